@@ -52,6 +52,15 @@ type ConnectionArgs struct {
 	// Cookie jar
 	CookieJar http.CookieJar
 
+	// OIDC tokens, used when AuthType is "oidc" (API extension: auth_oidc)
+	OIDCTokens *OIDCTokens
+
+	// Function called with the new OIDC tokens after they've been refreshed
+	OIDCTokensRefreshed func(*OIDCTokens)
+
+	// Secret of the API token, used when AuthType is "token" (API extension: auth_tokens)
+	AuthToken string
+
 	// Skip automatic GetServer request upon connection
 	SkipGetServer bool
 
@@ -237,6 +246,39 @@ func ConnectSimpleStreams(url string, args *ConnectionArgs) (ImageServer, error)
 	return &server, nil
 }
 
+// ConnectOCI lets you connect to a remote OCI registry (such as the Docker Hub) over HTTPs.
+//
+// Images from such a registry can only be retrieved by a LXD server which
+// converts them to LXD images, the returned ImageServer is mostly used to
+// pass the registry information along.
+func ConnectOCI(url string, args *ConnectionArgs) (ImageServer, error) {
+	logger.Debugf("Connecting to a remote OCI registry")
+
+	// Cleanup URL
+	url = strings.TrimSuffix(url, "/")
+
+	// Use empty args if not specified
+	if args == nil {
+		args = &ConnectionArgs{}
+	}
+
+	// Initialize the client struct
+	server := ProtocolOCI{
+		httpHost:        url,
+		httpUserAgent:   args.UserAgent,
+		httpCertificate: args.TLSServerCert,
+	}
+
+	// Setup the HTTP client
+	httpClient, err := tlsHTTPClient(args.HTTPClient, args.TLSClientCert, args.TLSClientKey, args.TLSCA, args.TLSServerCert, args.InsecureSkipVerify, args.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	server.http = httpClient
+
+	return &server, nil
+}
+
 // Internal function called by ConnectLXD and ConnectPublicLXD
 func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 	// Use empty args if not specified
@@ -258,6 +300,24 @@ func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 		server.RequireAuthenticated(true)
 	}
 
+	if args.AuthType == "token" {
+		server.authToken = args.AuthToken
+		server.RequireAuthenticated(true)
+	}
+
+	// The OIDC issuer is reached directly, without the client certificate meant for LXD
+	if args.AuthType == "oidc" && args.OIDCTokens != nil {
+		proxy := args.Proxy
+		if proxy == nil {
+			proxy = shared.ProxyFromEnvironment
+		}
+
+		server.oidcHTTP = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{Proxy: proxy}}
+		server.oidcTokens = args.OIDCTokens
+		server.oidcTokensRefreshed = args.OIDCTokensRefreshed
+		server.RequireAuthenticated(true)
+	}
+
 	// Setup the HTTP client
 	httpClient, err := tlsHTTPClient(args.HTTPClient, args.TLSClientCert, args.TLSClientKey, args.TLSCA, args.TLSServerCert, args.InsecureSkipVerify, args.Proxy)
 	if err != nil {
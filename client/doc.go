@@ -142,4 +142,34 @@
 //  if err != nil {
 //    return err
 //  }
+//
+// Example - cancellation
+//
+// This stops an instance, giving up (and cancelling the operation) after a minute
+//
+//  // Connect to LXD over the Unix socket
+//  c, err := lxd.ConnectLXDUnix("", nil)
+//  if err != nil {
+//    return err
+//  }
+//
+//  // Bind the requests to a context
+//  ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//  defer cancel()
+//
+//  req := api.InstanceStatePut{
+//    Action: "stop",
+//    Timeout: -1,
+//  }
+//
+//  op, err := c.UseContext(ctx).UpdateInstanceState("my-instance", req, "")
+//  if err != nil {
+//    return err
+//  }
+//
+//  // Wait for it to complete, or for the context to be done
+//  err = op.Wait()
+//  if err != nil {
+//    return err
+//  }
 package lxd
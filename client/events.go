@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/gorilla/websocket"
+
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -14,6 +17,9 @@ type EventListener struct {
 	disconnected bool
 	err          error
 
+	// Dedicated connection of the listeners returned by GetEventsWithArgs
+	conn *websocket.Conn
+
 	targets     []*EventTarget
 	targetsLock sync.Mutex
 }
@@ -69,12 +75,39 @@ func (e *EventListener) RemoveHandler(target *EventTarget) error {
 	return fmt.Errorf("Couldn't find this function and event types combination")
 }
 
+// dispatch calls the handlers interested in an event
+func (e *EventListener) dispatch(event api.Event) {
+	e.targetsLock.Lock()
+	defer e.targetsLock.Unlock()
+
+	for _, target := range e.targets {
+		if target.types != nil && !shared.StringInSlice(event.Type, target.types) {
+			continue
+		}
+
+		go target.function(event)
+	}
+}
+
 // Disconnect must be used once done listening for events
 func (e *EventListener) Disconnect() {
 	if e.disconnected {
 		return
 	}
 
+	if e.conn != nil {
+		e.targetsLock.Lock()
+		if !e.disconnected {
+			e.err = nil
+			e.disconnected = true
+			close(e.chActive)
+		}
+		e.targetsLock.Unlock()
+
+		e.conn.Close()
+		return
+	}
+
 	// Handle locking
 	e.r.eventListenersLock.Lock()
 	defer e.r.eventListenersLock.Unlock()
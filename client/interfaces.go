@@ -1,8 +1,10 @@
 package lxd
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -67,12 +69,29 @@ type InstanceServer interface {
 	// Server functions
 	GetServer() (server *api.Server, ETag string, err error)
 	GetServerResources() (resources *api.Resources, err error)
+	GetAuditEntries(limit int) (entries []api.AuditEntry, err error)
+	GetMetrics() (metrics string, err error)
+	CreateDatabaseBackup(backup api.DatabaseBackupsPost) (op Operation, err error)
 	UpdateServer(server api.ServerPut, ETag string) (err error)
 	HasExtension(extension string) (exists bool)
 	RequireAuthenticated(authenticated bool)
 	IsClustered() (clustered bool)
 	UseTarget(name string) (client InstanceServer)
 	UseProject(name string) (client InstanceServer)
+	UseContext(ctx context.Context) (client InstanceServer)
+
+	// Authorization group functions ("auth_builtin" API extension)
+	GetAuthGroups() (groups []api.AuthGroup, err error)
+	GetAuthGroup(name string) (group *api.AuthGroup, ETag string, err error)
+	CreateAuthGroup(group api.AuthGroupsPost) (err error)
+	UpdateAuthGroup(name string, group api.AuthGroupPut, ETag string) (err error)
+	RenameAuthGroup(name string, group api.AuthGroupPost) (err error)
+	DeleteAuthGroup(name string) (err error)
+
+	GetAuthTokens() (tokens []api.AuthToken, err error)
+	GetAuthToken(name string) (token *api.AuthToken, err error)
+	CreateAuthToken(token api.AuthTokensPost) (secret *api.AuthTokenSecret, err error)
+	DeleteAuthToken(name string) (err error)
 
 	// Certificate functions
 	GetCertificateFingerprints() (fingerprints []string, err error)
@@ -142,12 +161,14 @@ type InstanceServer interface {
 	// Instance functions.
 	GetInstanceNames(instanceType api.InstanceType) (names []string, err error)
 	GetInstances(instanceType api.InstanceType) (instances []api.Instance, err error)
+	GetInstancesWithFilter(instanceType api.InstanceType, args FilterArgs) (instances []api.Instance, err error)
 	GetInstancesFull(instanceType api.InstanceType) (instances []api.InstanceFull, err error)
 	GetInstance(name string) (instance *api.Instance, ETag string, err error)
 	CreateInstance(instance api.InstancesPost) (op Operation, err error)
 	CreateInstanceFromImage(source ImageServer, image api.Image, req api.InstancesPost) (op RemoteOperation, err error)
 	CopyInstance(source InstanceServer, instance api.Instance, args *InstanceCopyArgs) (op RemoteOperation, err error)
 	UpdateInstance(name string, instance api.InstancePut, ETag string) (op Operation, err error)
+	UpdateInstancesState(instanceType api.InstanceType, state api.InstancesStatePut, args *InstancesStateArgs) (op Operation, err error)
 	RenameInstance(name string, instance api.InstancePost) (op Operation, err error)
 	MigrateInstance(name string, instance api.InstancePost) (op Operation, err error)
 	DeleteInstance(name string) (op Operation, err error)
@@ -158,12 +179,16 @@ type InstanceServer interface {
 	DeleteInstanceConsoleLog(instanceName string, args *InstanceConsoleLogArgs) (err error)
 
 	GetInstanceFile(instanceName string, path string) (content io.ReadCloser, resp *InstanceFileResponse, err error)
+	GetInstanceFileIfChanged(instanceName string, path string, checksum string) (content io.ReadCloser, resp *InstanceFileResponse, err error)
 	CreateInstanceFile(instanceName string, path string, args InstanceFileArgs) (err error)
 	DeleteInstanceFile(instanceName string, path string) (err error)
 
 	GetInstanceSnapshotNames(instanceName string) (names []string, err error)
 	GetInstanceSnapshots(instanceName string) (snapshots []api.InstanceSnapshot, err error)
 	GetInstanceSnapshot(instanceName string, name string) (snapshot *api.InstanceSnapshot, ETag string, err error)
+	GetInstanceSnapshotDiff(instanceName string, name string, to string) (entries []api.InstanceSnapshotDiff, err error)
+	GetInstanceSnapshotGroups(instanceName string) (groups []api.InstanceSnapshotGroup, err error)
+	GetInstanceSnapshotGroup(instanceName string, name string) (group *api.InstanceSnapshotGroup, ETag string, err error)
 	CreateInstanceSnapshot(instanceName string, snapshot api.InstanceSnapshotsPost) (op Operation, err error)
 	CopyInstanceSnapshot(source InstanceServer, instanceName string, snapshot api.InstanceSnapshot, args *InstanceSnapshotCopyArgs) (op RemoteOperation, err error)
 	RenameInstanceSnapshot(instanceName string, name string, instance api.InstanceSnapshotPost) (op Operation, err error)
@@ -182,6 +207,9 @@ type InstanceServer interface {
 
 	GetInstanceState(name string) (state *api.InstanceState, ETag string, err error)
 	UpdateInstanceState(name string, state api.InstanceStatePut, ETag string) (op Operation, err error)
+	ResetInstance(name string, reset api.InstanceResetPost) (op Operation, err error)
+	GetInstanceUsage(name string, period string, resolution time.Duration) (usage *api.UsageHistory, err error)
+	GetInstanceNetworkDiagnostics(name string) (diagnostics []api.InstanceNetworkDiagnostic, err error)
 
 	GetInstanceLogfiles(name string) (logfiles []string, err error)
 	GetInstanceLogfile(name string, filename string) (content io.ReadCloser, err error)
@@ -198,13 +226,16 @@ type InstanceServer interface {
 
 	// Event handling functions
 	GetEvents() (listener *EventListener, err error)
+	GetEventsWithArgs(args *EventsArgs) (listener *EventListener, err error)
 
 	// Image functions
+	GetImagesWithFilter(args FilterArgs) (images []api.Image, err error)
 	CreateImage(image api.ImagesPost, args *ImageCreateArgs) (op Operation, err error)
 	CopyImage(source ImageServer, image api.Image, args *ImageCopyArgs) (op RemoteOperation, err error)
 	UpdateImage(fingerprint string, image api.ImagePut, ETag string) (err error)
 	DeleteImage(fingerprint string) (op Operation, err error)
 	RefreshImage(fingerprint string) (op Operation, err error)
+	ExportImage(fingerprint string, image api.ImageExportPost) (op Operation, err error)
 	CreateImageSecret(fingerprint string) (op Operation, err error)
 	CreateImageAlias(alias api.ImageAliasesPost) (err error)
 	UpdateImageAlias(name string, alias api.ImageAliasesEntryPut, ETag string) (err error)
@@ -222,9 +253,34 @@ type InstanceServer interface {
 	RenameNetwork(name string, network api.NetworkPost) (err error)
 	DeleteNetwork(name string) (err error)
 
+	// Network forward functions ("network_forward" API extension)
+	GetNetworkForwardAddresses(networkName string) ([]string, error)
+	GetNetworkForwards(networkName string) ([]api.NetworkForward, error)
+	GetNetworkForward(networkName string, listenAddress string) (forward *api.NetworkForward, ETag string, err error)
+	CreateNetworkForward(networkName string, forward api.NetworkForwardsPost) error
+	UpdateNetworkForward(networkName string, listenAddress string, forward api.NetworkForwardPut, ETag string) (err error)
+	DeleteNetworkForward(networkName string, listenAddress string) (err error)
+
+	// Network load balancer functions ("network_load_balancer" API extension)
+	GetNetworkLoadBalancerAddresses(networkName string) ([]string, error)
+	GetNetworkLoadBalancers(networkName string) ([]api.NetworkLoadBalancer, error)
+	GetNetworkLoadBalancer(networkName string, listenAddress string) (loadBalancer *api.NetworkLoadBalancer, ETag string, err error)
+	GetNetworkLoadBalancerState(networkName string, listenAddress string) (state *api.NetworkLoadBalancerState, err error)
+	CreateNetworkLoadBalancer(networkName string, loadBalancer api.NetworkLoadBalancersPost) error
+	UpdateNetworkLoadBalancer(networkName string, listenAddress string, loadBalancer api.NetworkLoadBalancerPut, ETag string) (err error)
+	DeleteNetworkLoadBalancer(networkName string, listenAddress string) (err error)
+
+	// Network DHCP reservation functions ("network_dhcp_reservations" API extension)
+	GetNetworkReservations(networkName string) ([]api.NetworkReservation, error)
+	GetNetworkReservation(networkName string, hwaddr string) (reservation *api.NetworkReservation, ETag string, err error)
+	CreateNetworkReservation(networkName string, reservation api.NetworkReservationsPost) error
+	UpdateNetworkReservation(networkName string, hwaddr string, reservation api.NetworkReservationPut, ETag string) (err error)
+	DeleteNetworkReservation(networkName string, hwaddr string) (err error)
+
 	// Operation functions
 	GetOperationUUIDs() (uuids []string, err error)
 	GetOperations() (operations []api.Operation, err error)
+	GetOperationsWithArgs(args *OperationsArgs) (operations []api.Operation, err error)
 	GetOperation(uuid string) (op *api.Operation, ETag string, err error)
 	GetOperationWait(uuid string, timeout int) (op *api.Operation, ETag string, err error)
 	GetOperationWebsocket(uuid string, secret string) (conn *websocket.Conn, err error)
@@ -243,16 +299,28 @@ type InstanceServer interface {
 	GetProjectNames() (names []string, err error)
 	GetProjects() (projects []api.Project, err error)
 	GetProject(name string) (project *api.Project, ETag string, err error)
+	GetProjectUsage(name string, start time.Time, end time.Time) (usage *api.ProjectUsage, err error)
 	CreateProject(project api.ProjectsPost) (err error)
 	UpdateProject(name string, project api.ProjectPut, ETag string) (err error)
 	RenameProject(name string, project api.ProjectPost) (op Operation, err error)
 	DeleteProject(name string) (err error)
 
+	// SR-IOV pool functions ("sriov_pools" API extension)
+	GetSRIOVPools() (pools []api.SRIOVPool, err error)
+	GetSRIOVPool(name string) (pool *api.SRIOVPool, ETag string, err error)
+	CreateSRIOVPool(pool api.SRIOVPoolsPost) (err error)
+	UpdateSRIOVPool(name string, pool api.SRIOVPoolPut, ETag string) (err error)
+	DeleteSRIOVPool(name string) (err error)
+
 	// Storage pool functions ("storage" API extension)
 	GetStoragePoolNames() (names []string, err error)
 	GetStoragePools() (pools []api.StoragePool, err error)
 	GetStoragePool(name string) (pool *api.StoragePool, ETag string, err error)
 	GetStoragePoolResources(name string) (resources *api.ResourcesStoragePool, err error)
+	GetStoragePoolUsage(name string, period string, resolution time.Duration) (usage *api.UsageHistory, err error)
+	GetStoragePoolTrash(name string) (entries []api.StoragePoolTrashEntry, err error)
+	RestoreStoragePoolTrashEntry(name string, id int64) (op Operation, err error)
+	DeleteStoragePoolTrashEntry(name string, id int64) (op Operation, err error)
 	CreateStoragePool(pool api.StoragePoolsPost) (err error)
 	UpdateStoragePool(name string, pool api.StoragePoolPut, ETag string) (err error)
 	DeleteStoragePool(name string) (err error)
@@ -260,6 +328,7 @@ type InstanceServer interface {
 	// Storage volume functions ("storage" API extension)
 	GetStoragePoolVolumeNames(pool string) (names []string, err error)
 	GetStoragePoolVolumes(pool string) (volumes []api.StorageVolume, err error)
+	GetStoragePoolVolumesWithFilter(pool string, args FilterArgs) (volumes []api.StorageVolume, err error)
 	GetStoragePoolVolume(pool string, volType string, name string) (volume *api.StorageVolume, ETag string, err error)
 	CreateStoragePoolVolume(pool string, volume api.StorageVolumesPost) (err error)
 	UpdateStoragePoolVolume(pool string, volType string, name string, volume api.StorageVolumePut, ETag string) (err error)
@@ -285,7 +354,19 @@ type InstanceServer interface {
 	GetClusterMemberNames() (names []string, err error)
 	GetClusterMembers() (members []api.ClusterMember, err error)
 	GetClusterMember(name string) (member *api.ClusterMember, ETag string, err error)
+	GetClusterMemberState(name string) (state *api.ClusterMemberState, ETag string, err error)
+	GetClusterState() (state *api.ClusterState, err error)
+	CreateClusterMember(member api.ClusterMembersPost) (op Operation, err error)
 	RenameClusterMember(name string, member api.ClusterMemberPost) (err error)
+	UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (op Operation, err error)
+	UpgradeCluster() (op Operation, err error)
+
+	// Cluster group functions ("clustering_groups" API extension)
+	GetClusterGroups() (groups []api.ClusterGroup, err error)
+	GetClusterGroup(name string) (group *api.ClusterGroup, ETag string, err error)
+	CreateClusterGroup(group api.ClusterGroupsPost) (err error)
+	UpdateClusterGroup(name string, group api.ClusterGroupPut, ETag string) (err error)
+	DeleteClusterGroup(name string) (err error)
 
 	// Internal functions (for internal use)
 	RawQuery(method string, path string, data interface{}, queryETag string) (resp *api.Response, ETag string, err error)
@@ -305,8 +386,8 @@ type ConnectionInfo struct {
 
 // The BackupFileRequest struct is used for a backup download request.
 type BackupFileRequest struct {
-	// Writer for the backup file
-	BackupFile io.WriteSeeker
+	// Writer for the backup file (doesn't need to be seekable, so can be a pipe)
+	BackupFile io.Writer
 
 	// Progress handler (called whenever some progress is made)
 	ProgressHandler func(progress ioprogress.ProgressData)
@@ -411,6 +492,63 @@ type StoragePoolVolumeCopyArgs struct {
 // during storage volume move.
 type StoragePoolVolumeMoveArgs struct {
 	StoragePoolVolumeCopyArgs
+
+	// API extension: clustering_volume_move
+	TargetMember string
+}
+
+// The OperationsArgs struct is used to filter the operations of a listing.
+type OperationsArgs struct {
+	// Include the operations of all projects
+	AllProjects bool
+
+	// Only include operations of this class (task, websocket or token)
+	Class string
+
+	// Only include operations with this status (e.g. running)
+	Status string
+
+	// Filter, sort and paginate the operations
+	Filter *FilterArgs
+}
+
+// The InstancesStateArgs struct is used to select the instances whose state is changed at once.
+type InstancesStateArgs struct {
+	// Include the instances of all projects
+	AllProjects bool
+
+	// Only include the instances matching the filter
+	Filter *FilterArgs
+}
+
+// The FilterArgs struct is used to filter, sort and paginate the listing of a collection.
+type FilterArgs struct {
+	// Filter expression (e.g. "status eq Running and location eq node1")
+	Filter string
+
+	// Fields to sort on, prefixed with "-" for descending order
+	Sort []string
+
+	// Maximum number of entries to return (0 for no limit)
+	Limit int
+
+	// Number of entries to skip
+	Offset int
+}
+
+// The EventsArgs struct is used to filter and replay the events of a dedicated event stream.
+type EventsArgs struct {
+	// Only receive these event types (defaults to all)
+	Types []string
+
+	// Only receive the lifecycle and operation events about resources matching these patterns
+	Resources []string
+
+	// Replay the recent events newer than this
+	Since time.Time
+
+	// Replay at most this many recent events
+	Replay int
 }
 
 // The InstanceBackupArgs struct is used when creating a instance from a backup.
@@ -439,6 +577,10 @@ type InstanceCopyArgs struct {
 	// API extension: container_incremental_copy
 	// Perform an incremental copy
 	Refresh bool
+
+	// API extension: instance_refresh_excludes
+	// Paths of the instance's root filesystem left out of an incremental copy
+	RefreshExcludes []string
 }
 
 // The InstanceSnapshotCopyArgs struct is used to pass additional options during instance copy.
@@ -527,4 +669,8 @@ type InstanceFileResponse struct {
 
 	// If a directory, the list of files inside it
 	Entries []string
+
+	// SHA-256 checksum of the file, when a checksum was provided to compare against
+	// API extension: instance_file_sync
+	Checksum string
 }
@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,8 +40,18 @@ type ProtocolLXD struct {
 	bakeryInteractor     []httpbakery.Interactor
 	requireAuthenticated bool
 
+	authToken string
+
+	oidcHTTP            *http.Client
+	oidcTokens          *OIDCTokens
+	oidcTokensRefreshed func(*OIDCTokens)
+	oidcLock            sync.Mutex
+
 	clusterTarget string
 	project       string
+
+	// Context bound to the requests, if any.
+	ctx context.Context
 }
 
 // Disconnect gets rid of any background goroutines
@@ -93,8 +104,23 @@ func (r *ProtocolLXD) GetHTTPClient() (*http.Client, error) {
 	return r.http, nil
 }
 
-// Do performs a Request, using macaroon authentication if set.
+// Do performs a Request, using macaroon, API token or OIDC authentication if set.
 func (r *ProtocolLXD) do(req *http.Request) (*http.Response, error) {
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+
+	if r.oidcTokens != nil {
+		err := r.addOIDCHeaders(req.Header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if r.bakeryClient != nil {
 		r.addMacaroonHeaders(req)
 		return r.bakeryClient.Do(req)
@@ -373,12 +399,38 @@ func (r *ProtocolLXD) rawWebsocket(url string) (*websocket.Conn, error) {
 		r.addMacaroonHeaders(req)
 	}
 
+	// Set the API token if needed
+	if r.authToken != "" {
+		headers.Set("Authorization", "Bearer "+r.authToken)
+	}
+
+	// Set the OIDC bearer token if needed
+	if r.oidcTokens != nil {
+		err := r.addOIDCHeaders(headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Establish the connection
-	conn, _, err := dialer.Dial(url, headers)
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, _, err := dialer.DialContext(ctx, url, headers)
 	if err != nil {
 		return nil, err
 	}
 
+	// Close the connection once the context is done
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			conn.Close()
+		}()
+	}
+
 	// Log the data
 	logger.Debugf("Connected to the websocket: %v", url)
 
@@ -0,0 +1,163 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Authorization group handling functions
+
+// GetAuthGroups returns the authorization groups
+func (r *ProtocolLXD) GetAuthGroups() ([]api.AuthGroup, error) {
+	if !r.HasExtension("auth_builtin") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	groups := []api.AuthGroup{}
+	_, err := r.queryStruct("GET", "/auth/groups?recursion=1", nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetAuthGroup returns information about the given authorization group
+func (r *ProtocolLXD) GetAuthGroup(name string) (*api.AuthGroup, string, error) {
+	if !r.HasExtension("auth_builtin") {
+		return nil, "", fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	group := api.AuthGroup{}
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
+// CreateAuthGroup defines a new authorization group using the provided struct
+func (r *ProtocolLXD) CreateAuthGroup(group api.AuthGroupsPost) error {
+	if !r.HasExtension("auth_builtin") {
+		return fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	_, _, err := r.query("POST", "/auth/groups", group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateAuthGroup updates the authorization group to match the provided struct
+func (r *ProtocolLXD) UpdateAuthGroup(name string, group api.AuthGroupPut, ETag string) error {
+	if !r.HasExtension("auth_builtin") {
+		return fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	_, _, err := r.query("PUT", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), group, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenameAuthGroup renames an existing authorization group
+func (r *ProtocolLXD) RenameAuthGroup(name string, group api.AuthGroupPost) error {
+	if !r.HasExtension("auth_builtin") {
+		return fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	_, _, err := r.query("POST", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAuthGroup deletes an existing authorization group
+func (r *ProtocolLXD) DeleteAuthGroup(name string) error {
+	if !r.HasExtension("auth_builtin") {
+		return fmt.Errorf("The server is missing the required \"auth_builtin\" API extension")
+	}
+
+	_, _, err := r.query("DELETE", fmt.Sprintf("/auth/groups/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// API token handling functions
+
+// GetAuthTokens returns the API tokens
+func (r *ProtocolLXD) GetAuthTokens() ([]api.AuthToken, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	tokens := []api.AuthToken{}
+	_, err := r.queryStruct("GET", "/auth/tokens?recursion=1", nil, "", &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetAuthToken returns information about the given API token
+func (r *ProtocolLXD) GetAuthToken(name string) (*api.AuthToken, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	token := api.AuthToken{}
+	_, err := r.queryStruct("GET", fmt.Sprintf("/auth/tokens/%s", url.PathEscape(name)), nil, "", &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// CreateAuthToken defines a new API token using the provided struct and returns its secret
+func (r *ProtocolLXD) CreateAuthToken(token api.AuthTokensPost) (*api.AuthTokenSecret, error) {
+	if !r.HasExtension("auth_tokens") {
+		return nil, fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	// Not using queryStruct as it logs the response, holding the secret
+	resp, _, err := r.query("POST", "/auth/tokens", token, "")
+	if err != nil {
+		return nil, err
+	}
+
+	secret := api.AuthTokenSecret{}
+	err = resp.MetadataAsStruct(&secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// DeleteAuthToken deletes an existing API token
+func (r *ProtocolLXD) DeleteAuthToken(name string) error {
+	if !r.HasExtension("auth_tokens") {
+		return fmt.Errorf("The server is missing the required \"auth_tokens\" API extension")
+	}
+
+	_, _, err := r.query("DELETE", fmt.Sprintf("/auth/tokens/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -58,6 +58,10 @@ func (r *ProtocolLXD) GetCertificate(fingerprint string) (*api.Certificate, stri
 
 // CreateCertificate adds a new certificate to the LXD trust store
 func (r *ProtocolLXD) CreateCertificate(certificate api.CertificatesPost) error {
+	if (certificate.Type != "client" || len(certificate.Projects) > 0) && !r.HasExtension("certificate_roles") {
+		return fmt.Errorf("The server is missing the required \"certificate_roles\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/certificates", certificate, "")
 	if err != nil {
@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/lxc/lxd/shared/api"
 )
@@ -108,6 +109,50 @@ func (r *ProtocolLXD) GetClusterMember(name string) (*api.ClusterMember, string,
 	return &member, etag, nil
 }
 
+// GetClusterMemberState returns the health and resource usage of a cluster member
+func (r *ProtocolLXD) GetClusterMemberState(name string) (*api.ClusterMemberState, string, error) {
+	if !r.HasExtension("clustering_member_state") {
+		return nil, "", fmt.Errorf("The server is missing the required \"clustering_member_state\" API extension")
+	}
+
+	state := api.ClusterMemberState{}
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/cluster/members/%s/state", name), nil, "", &state)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &state, etag, nil
+}
+
+// GetClusterState returns the state of the online cluster members along with their totals
+func (r *ProtocolLXD) GetClusterState() (*api.ClusterState, error) {
+	if !r.HasExtension("clustering_member_state") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_member_state\" API extension")
+	}
+
+	state := api.ClusterState{}
+	_, err := r.queryStruct("GET", "/cluster/state", nil, "", &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// CreateClusterMember generates a join token to add a cluster member
+func (r *ProtocolLXD) CreateClusterMember(member api.ClusterMembersPost) (Operation, error) {
+	if !r.HasExtension("clustering_join_token") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/members", member, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // RenameClusterMember changes the name of an existing member
 func (r *ProtocolLXD) RenameClusterMember(name string, member api.ClusterMemberPost) error {
 	if !r.HasExtension("clustering") {
@@ -121,3 +166,107 @@ func (r *ProtocolLXD) RenameClusterMember(name string, member api.ClusterMemberP
 
 	return nil
 }
+
+// UpdateClusterMemberState evacuates or restores a cluster member
+func (r *ProtocolLXD) UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (Operation, error) {
+	if !r.HasExtension("clustering_evacuation") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_evacuation\" API extension")
+	}
+
+	if state.Action == "maintenance" && !r.HasExtension("clustering_maintenance") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_maintenance\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/cluster/members/%s/state", name), state, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// UpgradeCluster starts a rolling upgrade of the cluster members
+func (r *ProtocolLXD) UpgradeCluster() (Operation, error) {
+	if !r.HasExtension("clustering_rolling_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_rolling_upgrade\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/upgrade", nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// GetClusterGroups returns the cluster groups
+func (r *ProtocolLXD) GetClusterGroups() ([]api.ClusterGroup, error) {
+	if !r.HasExtension("clustering_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	groups := []api.ClusterGroup{}
+	_, err := r.queryStruct("GET", "/cluster/groups?recursion=1", nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetClusterGroup returns information about the given cluster group
+func (r *ProtocolLXD) GetClusterGroup(name string) (*api.ClusterGroup, string, error) {
+	if !r.HasExtension("clustering_groups") {
+		return nil, "", fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	group := api.ClusterGroup{}
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
+// CreateClusterGroup defines a new cluster group using the provided struct
+func (r *ProtocolLXD) CreateClusterGroup(group api.ClusterGroupsPost) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("POST", "/cluster/groups", group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateClusterGroup updates the cluster group to match the provided struct
+func (r *ProtocolLXD) UpdateClusterGroup(name string, group api.ClusterGroupPut, ETag string) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("PUT", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), group, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteClusterGroup deletes an existing cluster group
+func (r *ProtocolLXD) DeleteClusterGroup(name string) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("DELETE", fmt.Sprintf("/cluster/groups/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
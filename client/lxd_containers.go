@@ -1892,14 +1892,34 @@ func (r *ProtocolLXD) GetContainerBackupFile(containerName string, name string,
 	// Handle the data
 	body := response.Body
 	if req.ProgressHandler != nil {
+		tracker := &ioprogress.ProgressTracker{
+			Length: response.ContentLength,
+		}
+
+		// Without a content length, the tracker reports the bytes instead of the percentage.
+		tracker.Handler = func(value int64, speed int64) {
+			if response.ContentLength <= 0 {
+				req.ProgressHandler(ioprogress.ProgressData{
+					Text:             fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(value, 2), units.GetByteSizeString(speed, 2)),
+					TransferredBytes: value,
+					Speed:            speed,
+				})
+
+				return
+			}
+
+			req.ProgressHandler(ioprogress.ProgressData{
+				Text:             fmt.Sprintf("%d%% (%s/s)", value, units.GetByteSizeString(speed, 2)),
+				Percentage:       int(value),
+				TransferredBytes: tracker.Processed(),
+				TotalBytes:       response.ContentLength,
+				Speed:            speed,
+			})
+		}
+
 		body = &ioprogress.ProgressReader{
 			ReadCloser: response.Body,
-			Tracker: &ioprogress.ProgressTracker{
-				Length: response.ContentLength,
-				Handler: func(percent int64, speed int64) {
-					req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
-				},
-			},
+			Tracker:    tracker,
 		}
 	}
 
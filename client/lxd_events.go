@@ -2,9 +2,12 @@ package lxd
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 )
 
@@ -107,17 +110,87 @@ func (r *ProtocolLXD) GetEvents() (*EventListener, error) {
 			// Send the message to all handlers
 			r.eventListenersLock.Lock()
 			for _, listener := range r.eventListeners {
-				listener.targetsLock.Lock()
-				for _, target := range listener.targets {
-					if target.types != nil && !shared.StringInSlice(event.Type, target.types) {
-						continue
-					}
+				listener.dispatch(event)
+			}
+			r.eventListenersLock.Unlock()
+		}
+	}()
+
+	return &listener, nil
+}
+
+// GetEventsWithArgs connects to the LXD monitoring interface on a dedicated connection, only
+// receiving the events matching the arguments, after a replay of the recent ones if requested.
+func (r *ProtocolLXD) GetEventsWithArgs(args *EventsArgs) (*EventListener, error) {
+	if !r.HasExtension("events_filter") {
+		return nil, fmt.Errorf("The server is missing the required \"events_filter\" API extension")
+	}
+
+	values := url.Values{}
+	if args != nil {
+		if len(args.Types) > 0 {
+			values.Set("type", strings.Join(args.Types, ","))
+		}
+
+		if len(args.Resources) > 0 {
+			values.Set("resource", strings.Join(args.Resources, ","))
+		}
+
+		if !args.Since.IsZero() {
+			values.Set("since", args.Since.Format(time.RFC3339Nano))
+		}
+
+		if args.Replay > 0 {
+			values.Set("replay", strconv.Itoa(args.Replay))
+		}
+	}
+
+	path := "/events"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	uri, err := r.setQueryAttributes(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.websocket(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := EventListener{
+		r:        r,
+		chActive: make(chan bool),
+		conn:     conn,
+	}
 
-					go target.function(event)
+	// Spawn the listener
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				listener.targetsLock.Lock()
+				if !listener.disconnected {
+					listener.err = err
+					listener.disconnected = true
+					close(listener.chActive)
 				}
 				listener.targetsLock.Unlock()
+
+				conn.Close()
+				return
 			}
-			r.eventListenersLock.Unlock()
+
+			// Attempt to unpack the message
+			event := api.Event{}
+			err = json.Unmarshal(data, &event)
+			if err != nil || event.Type == "" {
+				continue
+			}
+
+			listener.dispatch(event)
 		}
 	}()
 
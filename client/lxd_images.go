@@ -33,6 +33,26 @@ func (r *ProtocolLXD) GetImages() ([]api.Image, error) {
 	return images, nil
 }
 
+// GetImagesWithFilter returns the images matching the filter, in the requested order and page.
+func (r *ProtocolLXD) GetImagesWithFilter(args FilterArgs) ([]api.Image, error) {
+	if !r.HasExtension("collection_filter") {
+		return nil, fmt.Errorf("The server is missing the required \"collection_filter\" API extension")
+	}
+
+	images := []api.Image{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	setFilterValues(v, args)
+
+	_, err := r.queryStruct("GET", fmt.Sprintf("/images?%s", v.Encode()), nil, "", &images)
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
 // GetImageFingerprints returns a list of available image fingerprints
 func (r *ProtocolLXD) GetImageFingerprints() ([]string, error) {
 	urls := []string{}
@@ -356,6 +376,12 @@ func (r *ProtocolLXD) CreateImage(image api.ImagesPost, args *ImageCreateArgs) (
 		}
 	}
 
+	if image.Source != nil && image.Source.Type == "build" {
+		if !r.HasExtension("images_build") {
+			return nil, fmt.Errorf("The server is missing the required \"images_build\" API extension")
+		}
+	}
+
 	// Send the JSON based request
 	if args == nil {
 		op, _, err := r.queryOperation("POST", "/images", image, "")
@@ -625,6 +651,16 @@ func (r *ProtocolLXD) CopyImage(source ImageServer, image api.Image, args *Image
 		req.Source.ImageType = args.Type
 	}
 
+	if info.Protocol == "oci" {
+		if !r.HasExtension("oci_images") {
+			return nil, fmt.Errorf("The server is missing the required \"oci_images\" API extension")
+		}
+
+		// OCI images are always referenced by name
+		req.Source.Alias = image.Fingerprint
+		req.Source.Fingerprint = ""
+	}
+
 	// Generate secret token if needed
 	if !image.Public {
 		secret, err := source.GetImageSecret(image.Fingerprint)
@@ -689,6 +725,21 @@ func (r *ProtocolLXD) RefreshImage(fingerprint string) (Operation, error) {
 	return op, nil
 }
 
+// ExportImage requests that LXD pushes an image to another server
+func (r *ProtocolLXD) ExportImage(fingerprint string, image api.ImageExportPost) (Operation, error) {
+	if !r.HasExtension("images_oci_artifacts") {
+		return nil, fmt.Errorf("The server is missing the required \"images_oci_artifacts\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/images/%s/export", url.PathEscape(fingerprint)), image, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // CreateImageSecret requests that LXD issues a temporary image secret
 func (r *ProtocolLXD) CreateImageSecret(fingerprint string) (Operation, error) {
 	// Send the request
@@ -702,6 +753,10 @@ func (r *ProtocolLXD) CreateImageSecret(fingerprint string) (Operation, error) {
 
 // CreateImageAlias sets up a new image alias
 func (r *ProtocolLXD) CreateImageAlias(alias api.ImageAliasesPost) error {
+	if len(alias.Config) > 0 && !r.HasExtension("image_aliases_auto_update") {
+		return fmt.Errorf("The server is missing the required \"image_aliases_auto_update\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/images/aliases", alias, "")
 	if err != nil {
@@ -713,6 +768,10 @@ func (r *ProtocolLXD) CreateImageAlias(alias api.ImageAliasesPost) error {
 
 // UpdateImageAlias updates the image alias definition
 func (r *ProtocolLXD) UpdateImageAlias(name string, alias api.ImageAliasesEntryPut, ETag string) error {
+	if len(alias.Config) > 0 && !r.HasExtension("image_aliases_auto_update") {
+		return fmt.Errorf("The server is missing the required \"image_aliases_auto_update\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/images/aliases/%s", url.PathEscape(name)), alias, ETag)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -91,6 +92,31 @@ func (r *ProtocolLXD) GetInstances(instanceType api.InstanceType) ([]api.Instanc
 	return instances, nil
 }
 
+// GetInstancesWithFilter returns the instances matching the filter, in the requested order and page.
+func (r *ProtocolLXD) GetInstancesWithFilter(instanceType api.InstanceType, args FilterArgs) ([]api.Instance, error) {
+	if !r.HasExtension("collection_filter") {
+		return nil, fmt.Errorf("The server is missing the required \"collection_filter\" API extension")
+	}
+
+	instances := []api.Instance{}
+
+	path, v, err := r.instanceTypeToPath(instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Set("recursion", "1")
+	setFilterValues(v, args)
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s?%s", path, v.Encode()), nil, "", &instances)
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
 // GetInstancesFull returns a list of instances including snapshots, backups and state.
 func (r *ProtocolLXD) GetInstancesFull(instanceType api.InstanceType) ([]api.InstanceFull, error) {
 	instances := []api.InstanceFull{}
@@ -333,6 +359,16 @@ func (r *ProtocolLXD) CreateInstanceFromImage(source ImageServer, image api.Imag
 	req.Source.Protocol = info.Protocol
 	req.Source.Certificate = info.Certificate
 
+	if info.Protocol == "oci" {
+		if !r.HasExtension("oci_images") {
+			return nil, fmt.Errorf("The server is missing the required \"oci_images\" API extension")
+		}
+
+		// OCI images are always referenced by name
+		req.Source.Alias = image.Fingerprint
+		req.Source.Fingerprint = ""
+	}
+
 	// Generate secret token if needed
 	if !image.Public {
 		secret, err := source.GetImageSecret(image.Fingerprint)
@@ -393,6 +429,16 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 			}
 		}
 
+		if len(args.RefreshExcludes) > 0 {
+			if !args.Refresh {
+				return nil, fmt.Errorf("Path exclusions can only be used with incremental copies")
+			}
+
+			if !r.HasExtension("instance_refresh_excludes") {
+				return nil, fmt.Errorf("The target server is missing the required \"instance_refresh_excludes\" API extension")
+			}
+		}
+
 		// Allow overriding the target name
 		if args.Name != "" {
 			req.Name = args.Name
@@ -402,6 +448,7 @@ func (r *ProtocolLXD) CopyInstance(source InstanceServer, instance api.Instance,
 		req.Source.InstanceOnly = args.InstanceOnly
 		req.Source.ContainerOnly = args.InstanceOnly // For legacy servers.
 		req.Source.Refresh = args.Refresh
+		req.Source.RefreshExcludes = args.RefreshExcludes
 	}
 
 	if req.Source.Live {
@@ -676,6 +723,10 @@ func (r *ProtocolLXD) RenameInstance(name string, instance api.InstancePost) (Op
 		return nil, fmt.Errorf("Can't ask for a migration through RenameInstance")
 	}
 
+	if instance.Project != "" && !r.HasExtension("instance_project_move") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_project_move\" API extension")
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s", path, url.PathEscape(name)), instance, "")
 	if err != nil {
@@ -936,6 +987,20 @@ func (r *ProtocolLXD) ExecInstance(instanceName string, exec api.InstanceExecPos
 
 // GetInstanceFile retrieves the provided path from the instance.
 func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.ReadCloser, *InstanceFileResponse, error) {
+	return r.getInstanceFile(instanceName, filePath, "")
+}
+
+// GetInstanceFileIfChanged retrieves the provided path from the instance unless it's a file whose
+// SHA-256 checksum matches the provided one, in which case no content is returned.
+func (r *ProtocolLXD) GetInstanceFileIfChanged(instanceName string, filePath string, checksum string) (io.ReadCloser, *InstanceFileResponse, error) {
+	if !r.HasExtension("instance_file_sync") {
+		return nil, nil, fmt.Errorf("The server is missing the required \"instance_file_sync\" API extension")
+	}
+
+	return r.getInstanceFile(instanceName, filePath, checksum)
+}
+
+func (r *ProtocolLXD) getInstanceFile(instanceName string, filePath string, checksum string) (io.ReadCloser, *InstanceFileResponse, error) {
 	var err error
 	var requestURL string
 
@@ -975,6 +1040,11 @@ func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.
 		req.Header.Set("User-Agent", r.httpUserAgent)
 	}
 
+	// Only get the content if it doesn't match the checksum
+	if checksum != "" {
+		req.Header.Set("If-None-Match", checksum)
+	}
+
 	// Send the request
 	resp, err := r.do(req)
 	if err != nil {
@@ -982,7 +1052,7 @@ func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.
 	}
 
 	// Check the return value for a cleaner error
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
 		_, _, err := lxdParseResponse(resp)
 		if err != nil {
 			return nil, nil, err
@@ -992,10 +1062,16 @@ func (r *ProtocolLXD) GetInstanceFile(instanceName string, filePath string) (io.
 	// Parse the headers
 	uid, gid, mode, fileType, _ := shared.ParseLXDFileHeaders(resp.Header)
 	fileResp := InstanceFileResponse{
-		UID:  uid,
-		GID:  gid,
-		Mode: mode,
-		Type: fileType,
+		UID:      uid,
+		GID:      gid,
+		Mode:     mode,
+		Type:     fileType,
+		Checksum: resp.Header.Get("ETag"),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, &fileResp, nil
 	}
 
 	if fileResp.Type == "directory" {
@@ -1203,6 +1279,79 @@ func (r *ProtocolLXD) GetInstanceSnapshot(instanceName string, name string) (*ap
 	return &snapshot, etag, nil
 }
 
+// GetInstanceSnapshotDiff returns the files changed since the snapshot, up to the more recent
+// snapshot "to" or to the instance itself if empty.
+func (r *ProtocolLXD) GetInstanceSnapshotDiff(instanceName string, name string, to string) ([]api.InstanceSnapshotDiff, error) {
+	if !r.HasExtension("instance_snapshot_diff") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_snapshot_diff\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := fmt.Sprintf("%s/%s/snapshots/%s/diff", path, url.PathEscape(instanceName), url.PathEscape(name))
+	if to != "" {
+		uri = fmt.Sprintf("%s?to=%s", uri, url.QueryEscape(to))
+	}
+
+	entries := []api.InstanceSnapshotDiff{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", uri, nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetInstanceSnapshotGroups returns the snapshots of the instance taken together with its custom volumes
+func (r *ProtocolLXD) GetInstanceSnapshotGroups(instanceName string) ([]api.InstanceSnapshotGroup, error) {
+	if !r.HasExtension("snapshot_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"snapshot_groups\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []api.InstanceSnapshotGroup{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/snapshot-groups?recursion=1", path, url.PathEscape(instanceName)), nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetInstanceSnapshotGroup returns the instance snapshot with the given name along with the custom
+// volume snapshots taken together with it
+func (r *ProtocolLXD) GetInstanceSnapshotGroup(instanceName string, name string) (*api.InstanceSnapshotGroup, string, error) {
+	if !r.HasExtension("snapshot_groups") {
+		return nil, "", fmt.Errorf("The server is missing the required \"snapshot_groups\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, "", err
+	}
+
+	group := api.InstanceSnapshotGroup{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("%s/%s/snapshot-groups/%s", path, url.PathEscape(instanceName), url.PathEscape(name)), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
 // CreateInstanceSnapshot requests that LXD creates a new snapshot for the instance.
 func (r *ProtocolLXD) CreateInstanceSnapshot(instanceName string, snapshot api.InstanceSnapshotsPost) (Operation, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -1215,6 +1364,10 @@ func (r *ProtocolLXD) CreateInstanceSnapshot(instanceName string, snapshot api.I
 		return nil, fmt.Errorf("The server is missing the required \"snapshot_expiry_creation\" API extension")
 	}
 
+	if snapshot.Volumes && !r.HasExtension("snapshot_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"snapshot_groups\" API extension")
+	}
+
 	// Send the request
 	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/snapshots", path, url.PathEscape(instanceName)), snapshot, "")
 	if err != nil {
@@ -1604,6 +1757,106 @@ func (r *ProtocolLXD) UpdateInstanceState(name string, state api.InstanceStatePu
 	return op, nil
 }
 
+// UpdateInstancesState changes the state of all the instances of the project, or of all projects,
+// matching the arguments at once.
+func (r *ProtocolLXD) UpdateInstancesState(instanceType api.InstanceType, state api.InstancesStatePut, args *InstancesStateArgs) (Operation, error) {
+	if !r.HasExtension("instance_bulk_state_change") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_bulk_state_change\" API extension")
+	}
+
+	path, v, err := r.instanceTypeToPath(instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if args != nil {
+		if args.AllProjects {
+			v.Set("all-projects", "true")
+		}
+
+		if args.Filter != nil {
+			setFilterValues(v, *args.Filter)
+		}
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("PUT", fmt.Sprintf("%s?%s", path, v.Encode()), state, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// ResetInstance resets the instance's root filesystem to its image or to one of its snapshots.
+func (r *ProtocolLXD) ResetInstance(name string, reset api.InstanceResetPost) (Operation, error) {
+	if !r.HasExtension("instance_reset") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_reset\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("%s/%s/reset", path, url.PathEscape(name)), reset, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// GetInstanceUsage returns the resource usage of the instance over the last hour or day, averaged
+// over intervals of the given resolution.
+func (r *ProtocolLXD) GetInstanceUsage(name string, period string, resolution time.Duration) (*api.UsageHistory, error) {
+	if !r.HasExtension("usage_history") {
+		return nil, fmt.Errorf("The server is missing the required \"usage_history\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	values.Set("period", period)
+	values.Set("resolution", resolution.String())
+
+	usage := api.UsageHistory{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/usage?%s", path, url.PathEscape(name), values.Encode()), nil, "", &usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// GetInstanceNetworkDiagnostics runs the server side checks of the networking of the instance NICs.
+func (r *ProtocolLXD) GetInstanceNetworkDiagnostics(name string) ([]api.InstanceNetworkDiagnostic, error) {
+	if !r.HasExtension("instance_network_diagnostics") {
+		return nil, fmt.Errorf("The server is missing the required \"instance_network_diagnostics\" API extension")
+	}
+
+	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := []api.InstanceNetworkDiagnostic{}
+
+	// Fetch the raw value
+	_, err = r.queryStruct("GET", fmt.Sprintf("%s/%s/network-diagnostics", path, url.PathEscape(name)), nil, "", &diagnostics)
+	if err != nil {
+		return nil, err
+	}
+
+	return diagnostics, nil
+}
+
 // GetInstanceLogfiles returns a list of logfiles for the instance.
 func (r *ProtocolLXD) GetInstanceLogfiles(name string) ([]string, error) {
 	path, _, err := r.instanceTypeToPath(api.InstanceTypeAny)
@@ -2181,14 +2434,34 @@ func (r *ProtocolLXD) GetInstanceBackupFile(instanceName string, name string, re
 	// Handle the data
 	body := response.Body
 	if req.ProgressHandler != nil {
+		tracker := &ioprogress.ProgressTracker{
+			Length: response.ContentLength,
+		}
+
+		// Without a content length, the tracker reports the bytes instead of the percentage.
+		tracker.Handler = func(value int64, speed int64) {
+			if response.ContentLength <= 0 {
+				req.ProgressHandler(ioprogress.ProgressData{
+					Text:             fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(value, 2), units.GetByteSizeString(speed, 2)),
+					TransferredBytes: value,
+					Speed:            speed,
+				})
+
+				return
+			}
+
+			req.ProgressHandler(ioprogress.ProgressData{
+				Text:             fmt.Sprintf("%d%% (%s/s)", value, units.GetByteSizeString(speed, 2)),
+				Percentage:       int(value),
+				TransferredBytes: tracker.Processed(),
+				TotalBytes:       response.ContentLength,
+				Speed:            speed,
+			})
+		}
+
 		body = &ioprogress.ProgressReader{
 			ReadCloser: response.Body,
-			Tracker: &ioprogress.ProgressTracker{
-				Length: response.ContentLength,
-				Handler: func(percent int64, speed int64) {
-					req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
-				},
-			},
+			Tracker:    tracker,
 		}
 	}
 
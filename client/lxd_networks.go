@@ -159,3 +159,305 @@ func (r *ProtocolLXD) DeleteNetwork(name string) error {
 
 	return nil
 }
+
+// GetNetworkForwardAddresses returns a list of network forward listen addresses
+func (r *ProtocolLXD) GetNetworkForwardAddresses(networkName string) ([]string, error) {
+	if !r.HasExtension("network_forward") {
+		return nil, fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/forwards", url.PathEscape(networkName)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	addresses := []string{}
+	for _, uri := range urls {
+		fields := strings.Split(uri, "/forwards/")
+		addresses = append(addresses, fields[len(fields)-1])
+	}
+
+	return addresses, nil
+}
+
+// GetNetworkForwards returns a list of Network forward structs
+func (r *ProtocolLXD) GetNetworkForwards(networkName string) ([]api.NetworkForward, error) {
+	if !r.HasExtension("network_forward") {
+		return nil, fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	forwards := []api.NetworkForward{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/forwards?recursion=1", url.PathEscape(networkName)), nil, "", &forwards)
+	if err != nil {
+		return nil, err
+	}
+
+	return forwards, nil
+}
+
+// GetNetworkForward returns a Network forward entry for the provided network and listen address
+func (r *ProtocolLXD) GetNetworkForward(networkName string, listenAddress string) (*api.NetworkForward, string, error) {
+	if !r.HasExtension("network_forward") {
+		return nil, "", fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	forward := api.NetworkForward{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/forwards/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &forward)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &forward, etag, nil
+}
+
+// CreateNetworkForward defines a new network forward using the provided struct
+func (r *ProtocolLXD) CreateNetworkForward(networkName string, forward api.NetworkForwardsPost) error {
+	if !r.HasExtension("network_forward") {
+		return fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/forwards", url.PathEscape(networkName)), forward, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNetworkForward updates the network forward to match the provided struct
+func (r *ProtocolLXD) UpdateNetworkForward(networkName string, listenAddress string, forward api.NetworkForwardPut, ETag string) error {
+	if !r.HasExtension("network_forward") {
+		return fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/networks/%s/forwards/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), forward, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkForward deletes an existing network forward
+func (r *ProtocolLXD) DeleteNetworkForward(networkName string, listenAddress string) error {
+	if !r.HasExtension("network_forward") {
+		return fmt.Errorf("The server is missing the required \"network_forward\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/forwards/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetNetworkLoadBalancerAddresses returns a list of network load balancer listen addresses
+func (r *ProtocolLXD) GetNetworkLoadBalancerAddresses(networkName string) ([]string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	addresses := []string{}
+	for _, uri := range urls {
+		fields := strings.Split(uri, "/load-balancers/")
+		addresses = append(addresses, fields[len(fields)-1])
+	}
+
+	return addresses, nil
+}
+
+// GetNetworkLoadBalancers returns a list of Network load balancer structs
+func (r *ProtocolLXD) GetNetworkLoadBalancers(networkName string) ([]api.NetworkLoadBalancer, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancers := []api.NetworkLoadBalancer{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers?recursion=1", url.PathEscape(networkName)), nil, "", &loadBalancers)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancers, nil
+}
+
+// GetNetworkLoadBalancer returns a Network load balancer entry for the provided network and listen address
+func (r *ProtocolLXD) GetNetworkLoadBalancer(networkName string, listenAddress string) (*api.NetworkLoadBalancer, string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, "", fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancer := api.NetworkLoadBalancer{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &loadBalancer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &loadBalancer, etag, nil
+}
+
+// GetNetworkLoadBalancerState returns the health of the backends of a Network load balancer
+func (r *ProtocolLXD) GetNetworkLoadBalancerState(networkName string, listenAddress string) (*api.NetworkLoadBalancerState, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	state := api.NetworkLoadBalancerState{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers/%s/state", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// CreateNetworkLoadBalancer defines a new network load balancer using the provided struct
+func (r *ProtocolLXD) CreateNetworkLoadBalancer(networkName string, loadBalancer api.NetworkLoadBalancersPost) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), loadBalancer, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNetworkLoadBalancer updates the network load balancer to match the provided struct
+func (r *ProtocolLXD) UpdateNetworkLoadBalancer(networkName string, listenAddress string, loadBalancer api.NetworkLoadBalancerPut, ETag string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), loadBalancer, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkLoadBalancer deletes an existing network load balancer
+func (r *ProtocolLXD) DeleteNetworkLoadBalancer(networkName string, listenAddress string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetNetworkReservations returns a list of Network DHCP reservation structs
+func (r *ProtocolLXD) GetNetworkReservations(networkName string) ([]api.NetworkReservation, error) {
+	if !r.HasExtension("network_dhcp_reservations") {
+		return nil, fmt.Errorf("The server is missing the required \"network_dhcp_reservations\" API extension")
+	}
+
+	reservations := []api.NetworkReservation{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/reservations?recursion=1", url.PathEscape(networkName)), nil, "", &reservations)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// GetNetworkReservation returns a Network DHCP reservation entry for the provided network and MAC address
+func (r *ProtocolLXD) GetNetworkReservation(networkName string, hwaddr string) (*api.NetworkReservation, string, error) {
+	if !r.HasExtension("network_dhcp_reservations") {
+		return nil, "", fmt.Errorf("The server is missing the required \"network_dhcp_reservations\" API extension")
+	}
+
+	reservation := api.NetworkReservation{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/reservations/%s", url.PathEscape(networkName), url.PathEscape(hwaddr)), nil, "", &reservation)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &reservation, etag, nil
+}
+
+// CreateNetworkReservation defines a new network DHCP reservation using the provided struct
+func (r *ProtocolLXD) CreateNetworkReservation(networkName string, reservation api.NetworkReservationsPost) error {
+	if !r.HasExtension("network_dhcp_reservations") {
+		return fmt.Errorf("The server is missing the required \"network_dhcp_reservations\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/reservations", url.PathEscape(networkName)), reservation, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNetworkReservation updates the network DHCP reservation to match the provided struct
+func (r *ProtocolLXD) UpdateNetworkReservation(networkName string, hwaddr string, reservation api.NetworkReservationPut, ETag string) error {
+	if !r.HasExtension("network_dhcp_reservations") {
+		return fmt.Errorf("The server is missing the required \"network_dhcp_reservations\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/networks/%s/reservations/%s", url.PathEscape(networkName), url.PathEscape(hwaddr)), reservation, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkReservation deletes an existing network DHCP reservation
+func (r *ProtocolLXD) DeleteNetworkReservation(networkName string, hwaddr string) error {
+	if !r.HasExtension("network_dhcp_reservations") {
+		return fmt.Errorf("The server is missing the required \"network_dhcp_reservations\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/reservations/%s", url.PathEscape(networkName), url.PathEscape(hwaddr)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -51,6 +51,53 @@ func (r *ProtocolLXD) GetOperations() ([]api.Operation, error) {
 	return operations, nil
 }
 
+// GetOperationsWithArgs returns a list of Operation struct from all cluster members, filtered by
+// project, class and status
+func (r *ProtocolLXD) GetOperationsWithArgs(args *OperationsArgs) ([]api.Operation, error) {
+	if !r.HasExtension("operations_filter") {
+		return nil, fmt.Errorf("The server is missing the required \"operations_filter\" API extension")
+	}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+
+	if args.AllProjects {
+		v.Set("all-projects", "true")
+	}
+
+	if args.Class != "" {
+		v.Set("class", args.Class)
+	}
+
+	if args.Status != "" {
+		v.Set("status", args.Status)
+	}
+
+	if args.Filter != nil {
+		if !r.HasExtension("collection_filter") {
+			return nil, fmt.Errorf("The server is missing the required \"collection_filter\" API extension")
+		}
+
+		setFilterValues(v, *args.Filter)
+	}
+
+	apiOperations := map[string][]api.Operation{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/operations?%s", v.Encode()), nil, "", &apiOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Turn it into just a list of operations
+	operations := []api.Operation{}
+	for _, v := range apiOperations {
+		operations = append(operations, v...)
+	}
+
+	return operations, nil
+}
+
 // GetOperation returns an Operation entry for the provided uuid
 func (r *ProtocolLXD) GetOperation(uuid string) (*api.Operation, string, error) {
 	op := api.Operation{}
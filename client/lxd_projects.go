@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/shared/api"
 )
@@ -68,12 +69,37 @@ func (r *ProtocolLXD) GetProject(name string) (*api.Project, string, error) {
 	return &project, etag, nil
 }
 
+// GetProjectUsage returns the resources consumed by a project between the given times
+func (r *ProtocolLXD) GetProjectUsage(name string, start time.Time, end time.Time) (*api.ProjectUsage, error) {
+	if !r.HasExtension("projects_usage") {
+		return nil, fmt.Errorf("The server is missing the required \"projects_usage\" API extension")
+	}
+
+	values := url.Values{}
+	values.Set("start", start.Format(time.RFC3339))
+	values.Set("end", end.Format(time.RFC3339))
+
+	usage := api.ProjectUsage{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/projects/%s/usage?%s", url.PathEscape(name), values.Encode()), nil, "", &usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
 // CreateProject defines a new container project
 func (r *ProtocolLXD) CreateProject(project api.ProjectsPost) error {
 	if !r.HasExtension("projects") {
 		return fmt.Errorf("The server is missing the required \"projects\" API extension")
 	}
 
+	if project.Template != "" && !r.HasExtension("projects_templates") {
+		return fmt.Errorf("The server is missing the required \"projects_templates\" API extension")
+	}
+
 	// Send the request
 	_, _, err := r.query("POST", "/projects", project, "")
 	if err != nil {
@@ -1,7 +1,10 @@
 package lxd
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -89,6 +92,82 @@ func (r *ProtocolLXD) GetServerResources() (*api.Resources, error) {
 	return &resources, nil
 }
 
+// GetAuditEntries returns up to limit of the most recent audit entries of the server, newest first
+func (r *ProtocolLXD) GetAuditEntries(limit int) ([]api.AuditEntry, error) {
+	if !r.HasExtension("audit_log") {
+		return nil, fmt.Errorf("The server is missing the required \"audit_log\" API extension")
+	}
+
+	entries := []api.AuditEntry{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/audit?limit=%d", limit), nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetMetrics returns the metrics of the server (or of the targeted cluster member) in the Prometheus text format
+func (r *ProtocolLXD) GetMetrics() (string, error) {
+	if !r.HasExtension("instance_metrics") {
+		return "", fmt.Errorf("The server is missing the required \"instance_metrics\" API extension")
+	}
+
+	// Prepare the HTTP request
+	url, err := r.setQueryAttributes(fmt.Sprintf("%s/1.0/metrics", r.httpHost))
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Set the user agent
+	if r.httpUserAgent != "" {
+		req.Header.Set("User-Agent", r.httpUserAgent)
+	}
+
+	// Send the request
+	resp, err := r.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Check the return value for a cleaner error
+	if resp.StatusCode != http.StatusOK {
+		_, _, err := lxdParseResponse(resp)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// CreateDatabaseBackup takes a backup of the global and local databases and writes it to the requested target
+func (r *ProtocolLXD) CreateDatabaseBackup(backup api.DatabaseBackupsPost) (Operation, error) {
+	if !r.HasExtension("database_backup") {
+		return nil, fmt.Errorf("The server is missing the required \"database_backup\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/database/backups", backup, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // UseProject returns a client that will use a specific project.
 func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 	return &ProtocolLXD{
@@ -103,6 +182,7 @@ func (r *ProtocolLXD) UseProject(name string) InstanceServer {
 		requireAuthenticated: r.requireAuthenticated,
 		clusterTarget:        r.clusterTarget,
 		project:              name,
+		ctx:                  r.ctx,
 	}
 }
 
@@ -122,6 +202,31 @@ func (r *ProtocolLXD) UseTarget(name string) InstanceServer {
 		requireAuthenticated: r.requireAuthenticated,
 		project:              r.project,
 		clusterTarget:        name,
+		ctx:                  r.ctx,
+	}
+}
+
+// UseContext returns a client whose requests, websockets and operation waits are bound to the
+// provided context, so they get interrupted once it's canceled or expired.
+func (r *ProtocolLXD) UseContext(ctx context.Context) InstanceServer {
+	return &ProtocolLXD{
+		server:               r.server,
+		http:                 r.http,
+		httpCertificate:      r.httpCertificate,
+		httpHost:             r.httpHost,
+		httpUnixPath:         r.httpUnixPath,
+		httpProtocol:         r.httpProtocol,
+		httpUserAgent:        r.httpUserAgent,
+		bakeryClient:         r.bakeryClient,
+		bakeryInteractor:     r.bakeryInteractor,
+		requireAuthenticated: r.requireAuthenticated,
+		authToken:            r.authToken,
+		oidcHTTP:             r.oidcHTTP,
+		oidcTokens:           r.oidcTokens,
+		oidcTokensRefreshed:  r.oidcTokensRefreshed,
+		project:              r.project,
+		clusterTarget:        r.clusterTarget,
+		ctx:                  ctx,
 	}
 }
 
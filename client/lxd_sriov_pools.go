@@ -0,0 +1,89 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// SR-IOV pool handling functions
+
+// GetSRIOVPools returns a list of SR-IOV pool structs
+func (r *ProtocolLXD) GetSRIOVPools() ([]api.SRIOVPool, error) {
+	if !r.HasExtension("sriov_pools") {
+		return nil, fmt.Errorf("The server is missing the required \"sriov_pools\" API extension")
+	}
+
+	pools := []api.SRIOVPool{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", "/sriov-pools?recursion=1", nil, "", &pools)
+	if err != nil {
+		return nil, err
+	}
+
+	return pools, nil
+}
+
+// GetSRIOVPool returns a SR-IOV pool entry for the provided name
+func (r *ProtocolLXD) GetSRIOVPool(name string) (*api.SRIOVPool, string, error) {
+	if !r.HasExtension("sriov_pools") {
+		return nil, "", fmt.Errorf("The server is missing the required \"sriov_pools\" API extension")
+	}
+
+	pool := api.SRIOVPool{}
+
+	// Fetch the raw value
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/sriov-pools/%s", url.PathEscape(name)), nil, "", &pool)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &pool, etag, nil
+}
+
+// CreateSRIOVPool defines a new SR-IOV pool using the provided struct
+func (r *ProtocolLXD) CreateSRIOVPool(pool api.SRIOVPoolsPost) error {
+	if !r.HasExtension("sriov_pools") {
+		return fmt.Errorf("The server is missing the required \"sriov_pools\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("POST", "/sriov-pools", pool, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSRIOVPool updates the SR-IOV pool to match the provided struct
+func (r *ProtocolLXD) UpdateSRIOVPool(name string, pool api.SRIOVPoolPut, ETag string) error {
+	if !r.HasExtension("sriov_pools") {
+		return fmt.Errorf("The server is missing the required \"sriov_pools\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("PUT", fmt.Sprintf("/sriov-pools/%s", url.PathEscape(name)), pool, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSRIOVPool deletes an existing SR-IOV pool
+func (r *ProtocolLXD) DeleteSRIOVPool(name string) error {
+	if !r.HasExtension("sriov_pools") {
+		return fmt.Errorf("The server is missing the required \"sriov_pools\" API extension")
+	}
+
+	// Send the request
+	_, _, err := r.query("DELETE", fmt.Sprintf("/sriov-pools/%s", url.PathEscape(name)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/shared/api"
 )
@@ -133,3 +134,72 @@ func (r *ProtocolLXD) GetStoragePoolResources(name string) (*api.ResourcesStorag
 
 	return &res, nil
 }
+
+// GetStoragePoolUsage gets the space used by a given storage pool over the last hour or day,
+// averaged over intervals of the given resolution
+func (r *ProtocolLXD) GetStoragePoolUsage(name string, period string, resolution time.Duration) (*api.UsageHistory, error) {
+	if !r.HasExtension("usage_history") {
+		return nil, fmt.Errorf("The server is missing the required \"usage_history\" API extension")
+	}
+
+	values := url.Values{}
+	values.Set("period", period)
+	values.Set("resolution", resolution.String())
+
+	usage := api.UsageHistory{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/usage?%s", url.PathEscape(name), values.Encode()), nil, "", &usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// GetStoragePoolTrash returns the instances and custom volumes in the trash of a storage pool
+func (r *ProtocolLXD) GetStoragePoolTrash(name string) ([]api.StoragePoolTrashEntry, error) {
+	if !r.HasExtension("storage_trash") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_trash\" API extension")
+	}
+
+	entries := []api.StoragePoolTrashEntry{}
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/trash?recursion=1", url.PathEscape(name)), nil, "", &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// RestoreStoragePoolTrashEntry restores an instance or custom volume from the trash of a storage pool
+func (r *ProtocolLXD) RestoreStoragePoolTrashEntry(name string, id int64) (Operation, error) {
+	if !r.HasExtension("storage_trash") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_trash\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/storage-pools/%s/trash/%d", url.PathEscape(name), id), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// DeleteStoragePoolTrashEntry purges an instance or custom volume from the trash of a storage pool
+func (r *ProtocolLXD) DeleteStoragePoolTrashEntry(name string, id int64) (Operation, error) {
+	if !r.HasExtension("storage_trash") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_trash\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("DELETE", fmt.Sprintf("/storage-pools/%s/trash/%d", url.PathEscape(name), id), nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
@@ -51,6 +51,28 @@ func (r *ProtocolLXD) GetStoragePoolVolumes(pool string) ([]api.StorageVolume, e
 	return volumes, nil
 }
 
+// GetStoragePoolVolumesWithFilter returns the storage volumes of a pool matching the filter, in the
+// requested order and page.
+func (r *ProtocolLXD) GetStoragePoolVolumesWithFilter(pool string, args FilterArgs) ([]api.StorageVolume, error) {
+	if !r.HasExtension("collection_filter") {
+		return nil, fmt.Errorf("The server is missing the required \"collection_filter\" API extension")
+	}
+
+	volumes := []api.StorageVolume{}
+
+	v := url.Values{}
+	v.Set("recursion", "1")
+	setFilterValues(v, args)
+
+	// Fetch the raw value
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/volumes?%s", url.PathEscape(pool), v.Encode()), nil, "", &volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
 // GetStoragePoolVolume returns a StorageVolume entry for the provided pool and volume name
 func (r *ProtocolLXD) GetStoragePoolVolume(pool string, volType string, name string) (*api.StorageVolume, string, error) {
 	if !r.HasExtension("storage") {
@@ -530,9 +552,14 @@ func (r *ProtocolLXD) MoveStoragePoolVolume(pool string, source InstanceServer,
 		return nil, fmt.Errorf("Moving storage volumes between remotes is not implemented")
 	}
 
+	if args.TargetMember != "" && !r.HasExtension("clustering_volume_move") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_volume_move\" API extension")
+	}
+
 	req := api.StorageVolumePost{
-		Name: args.Name,
-		Pool: pool,
+		Name:         args.Name,
+		Pool:         pool,
+		TargetMember: args.TargetMember,
 	}
 
 	// Send the request
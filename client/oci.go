@@ -0,0 +1,43 @@
+package lxd
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ProtocolOCI implements a minimal OCI registry client.
+//
+// Images are referenced by name (and optional tag) and are retrieved and
+// converted by the LXD server itself, so only the connection information
+// is used on the client side.
+type ProtocolOCI struct {
+	http            *http.Client
+	httpHost        string
+	httpUserAgent   string
+	httpCertificate string
+}
+
+// Disconnect is a no-op for OCI registries
+func (r *ProtocolOCI) Disconnect() {
+	return
+}
+
+// GetConnectionInfo returns the basic connection information used to interact with the server
+func (r *ProtocolOCI) GetConnectionInfo() (*ConnectionInfo, error) {
+	info := ConnectionInfo{}
+	info.Addresses = []string{r.httpHost}
+	info.Certificate = r.httpCertificate
+	info.Protocol = "oci"
+	info.URL = r.httpHost
+
+	return &info, nil
+}
+
+// GetHTTPClient returns the http client used for the connection. This can be used to set custom http options.
+func (r *ProtocolOCI) GetHTTPClient() (*http.Client, error) {
+	if r.http == nil {
+		return nil, fmt.Errorf("HTTP client isn't set, bad connection")
+	}
+
+	return r.http, nil
+}
@@ -0,0 +1,86 @@
+package lxd
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Image handling functions
+
+// GetImages isn't supported by OCI registries
+func (r *ProtocolOCI) GetImages() ([]api.Image, error) {
+	return nil, fmt.Errorf("Listing images isn't supported by OCI registries")
+}
+
+// GetImageFingerprints isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageFingerprints() ([]string, error) {
+	return nil, fmt.Errorf("Listing images isn't supported by OCI registries")
+}
+
+// GetImage returns a placeholder Image struct for the provided image name.
+// The name is resolved to a manifest digest by the LXD server when the image is retrieved.
+func (r *ProtocolOCI) GetImage(name string) (*api.Image, string, error) {
+	image := api.Image{}
+	image.Fingerprint = name
+	image.Public = true
+	image.Type = "container"
+
+	return &image, "", nil
+}
+
+// GetImageFile isn't supported by OCI registries, the LXD server retrieves and converts the image
+func (r *ProtocolOCI) GetImageFile(name string, req ImageFileRequest) (*ImageFileResponse, error) {
+	return nil, fmt.Errorf("OCI images can only be retrieved by a LXD server")
+}
+
+// GetImageSecret isn't relevant for OCI registries
+func (r *ProtocolOCI) GetImageSecret(name string) (string, error) {
+	return "", fmt.Errorf("Private images aren't supported by OCI registries")
+}
+
+// GetPrivateImage isn't relevant for OCI registries
+func (r *ProtocolOCI) GetPrivateImage(name string, secret string) (*api.Image, string, error) {
+	return nil, "", fmt.Errorf("Private images aren't supported by OCI registries")
+}
+
+// GetPrivateImageFile isn't relevant for OCI registries
+func (r *ProtocolOCI) GetPrivateImageFile(name string, secret string, req ImageFileRequest) (*ImageFileResponse, error) {
+	return nil, fmt.Errorf("Private images aren't supported by OCI registries")
+}
+
+// GetImageAliases isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliases() ([]api.ImageAliasesEntry, error) {
+	return nil, fmt.Errorf("Listing image aliases isn't supported by OCI registries")
+}
+
+// GetImageAliasNames isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliasNames() ([]string, error) {
+	return nil, fmt.Errorf("Listing image aliases isn't supported by OCI registries")
+}
+
+// GetImageAlias returns an alias entry pointing to the image of the same name
+func (r *ProtocolOCI) GetImageAlias(name string) (*api.ImageAliasesEntry, string, error) {
+	return r.GetImageAliasType("container", name)
+}
+
+// GetImageAliasType returns an alias entry pointing to the image of the same name
+//
+// Virtual machine images can only come from LXD images pushed to the registry as artifacts.
+func (r *ProtocolOCI) GetImageAliasType(imageType string, name string) (*api.ImageAliasesEntry, string, error) {
+	if imageType == "" {
+		imageType = "container"
+	}
+
+	alias := api.ImageAliasesEntry{}
+	alias.Name = name
+	alias.Target = name
+	alias.Type = imageType
+
+	return &alias, "", nil
+}
+
+// GetImageAliasArchitectures returns a map of architectures / targets
+func (r *ProtocolOCI) GetImageAliasArchitectures(imageType string, name string) (map[string]*api.ImageAliasesEntry, error) {
+	return nil, fmt.Errorf("Listing image architectures isn't supported by OCI registries")
+}
@@ -0,0 +1,253 @@
+package lxd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OIDCTokens holds the tokens obtained from an OIDC issuer, along with what's needed to refresh them
+type OIDCTokens struct {
+	Issuer   string `json:"issuer" yaml:"issuer"`
+	ClientID string `json:"client_id" yaml:"client_id"`
+	Audience string `json:"audience,omitempty" yaml:"audience,omitempty"`
+
+	AccessToken  string    `json:"access_token" yaml:"access_token"`
+	IDToken      string    `json:"id_token" yaml:"id_token"`
+	RefreshToken string    `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry" yaml:"expiry"`
+}
+
+// token returns the token to send to LXD, which is the access token when an audience is
+// configured and the ID token otherwise.
+func (t *OIDCTokens) token() string {
+	if t.Audience != "" || t.IDToken == "" {
+		return t.AccessToken
+	}
+
+	return t.IDToken
+}
+
+type oidcDiscovery struct {
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OIDCDeviceLogin goes through the device authorization flow of an OIDC issuer.
+//
+// The prompt function is called with the URL the user must visit and the code to enter there,
+// then the issuer is polled until the user is done.
+func OIDCDeviceLogin(client *http.Client, issuer string, clientID string, audience string, prompt func(uri string, code string)) (*OIDCTokens, error) {
+	discovery, err := oidcDiscover(client, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	if discovery.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("The OIDC issuer doesn't support the device authorization flow")
+	}
+
+	values := url.Values{}
+	values.Set("client_id", clientID)
+	values.Set("scope", "openid email profile offline_access")
+	if audience != "" {
+		values.Set("audience", audience)
+	}
+
+	resp, err := client.PostForm(discovery.DeviceAuthorizationEndpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	device := struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int64  `json:"expires_in"`
+		Interval                int64  `json:"interval"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&device)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || device.DeviceCode == "" {
+		return nil, fmt.Errorf("Failed to start the device authorization: %s", resp.Status)
+	}
+
+	uri := device.VerificationURIComplete
+	if uri == "" {
+		uri = device.VerificationURI
+	}
+
+	prompt(uri, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	if device.ExpiresIn == 0 {
+		deadline = time.Now().Add(10 * time.Minute)
+	}
+
+	values = url.Values{}
+	values.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+	values.Set("device_code", device.DeviceCode)
+	values.Set("client_id", clientID)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		token, err := oidcTokenRequest(client, discovery.TokenEndpoint, values)
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Error {
+		case "":
+			return oidcTokensFromResponse(issuer, clientID, audience, token), nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return nil, fmt.Errorf("Device authorization failed: %s %s", token.Error, token.ErrorDescription)
+		}
+	}
+
+	return nil, fmt.Errorf("Device authorization timed out")
+}
+
+// oidcRefresh exchanges the refresh token for new tokens.
+func oidcRefresh(client *http.Client, tokens *OIDCTokens) (*OIDCTokens, error) {
+	if tokens.RefreshToken == "" {
+		return nil, fmt.Errorf("The OIDC tokens have expired and can't be refreshed, please add the remote again")
+	}
+
+	discovery, err := oidcDiscover(client, tokens.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", tokens.RefreshToken)
+	values.Set("client_id", tokens.ClientID)
+
+	token, err := oidcTokenRequest(client, discovery.TokenEndpoint, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("Failed to refresh the OIDC tokens: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	refreshed := oidcTokensFromResponse(tokens.Issuer, tokens.ClientID, tokens.Audience, token)
+
+	// Issuers don't have to rotate the refresh token.
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = tokens.RefreshToken
+	}
+
+	return refreshed, nil
+}
+
+func oidcDiscover(client *http.Client, issuer string) (*oidcDiscovery, error) {
+	resp, err := client.Get(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch the OIDC discovery document: %s", resp.Status)
+	}
+
+	discovery := oidcDiscovery{}
+	err = json.NewDecoder(resp.Body).Decode(&discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &discovery, nil
+}
+
+func oidcTokenRequest(client *http.Client, endpoint string, values url.Values) (*oidcTokenResponse, error) {
+	resp, err := client.PostForm(endpoint, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	token := oidcTokenResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK && token.Error == "" {
+		return nil, fmt.Errorf("OIDC token request failed: %s", resp.Status)
+	}
+
+	return &token, nil
+}
+
+func oidcTokensFromResponse(issuer string, clientID string, audience string, token *oidcTokenResponse) *OIDCTokens {
+	tokens := OIDCTokens{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		Audience:     audience,
+		AccessToken:  token.AccessToken,
+		IDToken:      token.IDToken,
+		RefreshToken: token.RefreshToken,
+	}
+
+	if token.ExpiresIn > 0 {
+		tokens.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	return &tokens
+}
+
+// addOIDCHeaders sets the bearer token of a request, refreshing the tokens first if they're about
+// to expire.
+func (r *ProtocolLXD) addOIDCHeaders(header http.Header) error {
+	r.oidcLock.Lock()
+	defer r.oidcLock.Unlock()
+
+	if !r.oidcTokens.Expiry.IsZero() && time.Now().Add(30*time.Second).After(r.oidcTokens.Expiry) {
+		tokens, err := oidcRefresh(r.oidcHTTP, r.oidcTokens)
+		if err != nil {
+			return err
+		}
+
+		r.oidcTokens = tokens
+		if r.oidcTokensRefreshed != nil {
+			r.oidcTokensRefreshed(tokens)
+		}
+	}
+
+	header.Set("Authorization", "Bearer "+r.oidcTokens.token())
+
+	return nil
+}
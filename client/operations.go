@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -113,7 +114,21 @@ func (op *operation) Wait() error {
 		return err
 	}
 
-	<-op.chActive
+	// Stop waiting (and cancel the operation if possible) once the client context is done
+	if op.r.ctx != nil {
+		select {
+		case <-op.chActive:
+		case <-op.r.ctx.Done():
+			// The client context is done, so cancel without it.
+			if op.MayCancel {
+				op.r.UseContext(context.Background()).DeleteOperation(op.ID)
+			}
+
+			return op.r.ctx.Err()
+		}
+	} else {
+		<-op.chActive
+	}
 
 	// We're done, parse the result
 	if op.Err != "" {
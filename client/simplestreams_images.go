@@ -12,6 +12,8 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
 )
 
 // Image handling functions
@@ -133,44 +135,29 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 					continue
 				}
 
-				// Create temporary file for the delta
-				deltaFile, err := ioutil.TempFile("", "lxc_image_")
+				size, err := r.applyDelta(download, srcPath, file, req.RootfsFile)
 				if err != nil {
-					return nil, err
-				}
-				defer deltaFile.Close()
-				defer os.Remove(deltaFile.Name())
-
-				// Download the delta
-				_, err = download(file.Path, "rootfs delta", file.Sha256, deltaFile)
-				if err != nil {
-					return nil, err
-				}
+					// Handle cancelation
+					if err.Error() == "net/http: request canceled" {
+						return nil, err
+					}
 
-				// Create temporary file for the delta
-				patchedFile, err := ioutil.TempFile("", "lxc_image_")
-				if err != nil {
-					return nil, err
-				}
-				defer patchedFile.Close()
-				defer os.Remove(patchedFile.Name())
+					// Fallback to the next delta or to the whole file
+					logger.Debugf("Failed to apply image delta from %q: %v", srcFingerprint, err)
 
-				// Apply it
-				_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
-				if err != nil {
-					return nil, err
-				}
+					_, err = req.RootfsFile.Seek(0, io.SeekStart)
+					if err != nil {
+						return nil, err
+					}
 
-				// Copy to the target
-				size, err := io.Copy(req.RootfsFile, patchedFile)
-				if err != nil {
-					return nil, err
+					continue
 				}
 
 				parts := strings.Split(rootfs.Path, "/")
 				resp.RootfsName = parts[len(parts)-1]
 				resp.RootfsSize = size
 				downloaded = true
+				break
 			}
 		}
 
@@ -190,6 +177,41 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 	return &resp, nil
 }
 
+// applyDelta downloads a delta against a local image file and writes the patched file to target,
+// returning its size.
+func (r *ProtocolSimpleStreams) applyDelta(download func(path string, filename string, hash string, target io.WriteSeeker) (int64, error), srcPath string, file simplestreams.DownloadableFile, target io.WriteSeeker) (int64, error) {
+	// Create temporary file for the delta
+	deltaFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer deltaFile.Close()
+	defer os.Remove(deltaFile.Name())
+
+	// Download the delta
+	_, err = download(file.Path, "rootfs delta", file.Sha256, deltaFile)
+	if err != nil {
+		return -1, err
+	}
+
+	// Create temporary file for the patched file
+	patchedFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer patchedFile.Close()
+	defer os.Remove(patchedFile.Name())
+
+	// Apply it
+	_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
+	if err != nil {
+		return -1, err
+	}
+
+	// Copy to the target
+	return io.Copy(target, patchedFile)
+}
+
 // GetImageSecret isn't relevant for the simplestreams protocol
 func (r *ProtocolSimpleStreams) GetImageSecret(fingerprint string) (string, error) {
 	return "", fmt.Errorf("Private images aren't supported by the simplestreams protocol")
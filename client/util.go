@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/lxc/lxd/shared"
@@ -186,3 +187,22 @@ func setQueryParam(uri, param, value string) (string, error) {
 
 	return fields.String(), nil
 }
+
+// setFilterValues adds the filter, sort and pagination of a listing to its query values.
+func setFilterValues(v url.Values, args FilterArgs) {
+	if args.Filter != "" {
+		v.Set("filter", args.Filter)
+	}
+
+	if len(args.Sort) > 0 {
+		v.Set("sort", strings.Join(args.Sort, ","))
+	}
+
+	if args.Limit > 0 {
+		v.Set("limit", strconv.Itoa(args.Limit))
+	}
+
+	if args.Offset > 0 {
+		v.Set("offset", strconv.Itoa(args.Offset))
+	}
+}
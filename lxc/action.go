@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/config"
 	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
@@ -97,18 +99,25 @@ func (c *cmdStop) Command() *cobra.Command {
 type cmdAction struct {
 	global *cmdGlobal
 
-	flagAll       bool
-	flagForce     bool
-	flagStateful  bool
-	flagStateless bool
-	flagTimeout   int
+	flagAll         bool
+	flagAllProjects bool
+	flagConcurrency int
+	flagFilter      string
+	flagForce       bool
+	flagStateful    bool
+	flagStateless   bool
+	flagTimeout     int
 }
 
 func (c *cmdAction) Command(action string) *cobra.Command {
 	cmd := &cobra.Command{}
+	cmd.ValidArgsFunction = cmpRepeat(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	cmd.Flags().BoolVar(&c.flagAll, "all", false, i18n.G("Run against all instances"))
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Run against the instances of all projects"))
+	cmd.Flags().StringVar(&c.flagFilter, "filter", "", i18n.G("Only run against the instances matching the filter (e.g. \"name match web-*\")")+"``")
+	cmd.Flags().IntVar(&c.flagConcurrency, "concurrency", 0, i18n.G("Number of instances to change at the same time on each server")+"``")
 
 	if action == "stop" {
 		cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Store the instance state"))
@@ -201,12 +210,88 @@ func (c *cmdAction) doAction(action string, conf *config.Config, nameArg string)
 	return nil
 }
 
+// doBulkAction has the server change the state of all the selected instances in one operation.
+func (c *cmdAction) doBulkAction(action string, d lxd.InstanceServer) error {
+	// Pause is called freeze
+	if action == "pause" {
+		action = "freeze"
+	}
+
+	req := api.InstancesStatePut{
+		State: api.InstanceStatePut{
+			Action:  action,
+			Timeout: c.flagTimeout,
+			Force:   c.flagForce,
+
+			// Store the state only if asked to and always restore it unless asked not to
+			Stateful: (action == "stop" && c.flagStateful) || (action == "start" && !c.flagStateless),
+		},
+		Concurrency: c.flagConcurrency,
+	}
+
+	args := &lxd.InstancesStateArgs{AllProjects: c.flagAllProjects}
+	if c.flagFilter != "" {
+		args.Filter = &lxd.FilterArgs{Filter: c.flagFilter}
+	}
+
+	op, err := d.UpdateInstancesState(api.InstanceTypeAny, req, args)
+	if err != nil {
+		return err
+	}
+
+	progress := utils.ProgressRenderer{
+		Quiet: c.global.flagQuiet,
+	}
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	progress.Done("")
+	if err == nil {
+		return nil
+	}
+
+	// Show which instances failed
+	results := []api.InstanceStateResult{}
+	data, jsonErr := json.Marshal(op.Get().Metadata["results"])
+	if jsonErr == nil {
+		jsonErr = json.Unmarshal(data, &results)
+	}
+
+	if jsonErr != nil || len(results) == 0 {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Status != "Failure" {
+			continue
+		}
+
+		name := result.Name
+		if c.flagAllProjects {
+			name = fmt.Sprintf("%s/%s", result.Project, result.Name)
+		}
+
+		msg := fmt.Sprintf(i18n.G("error: %v"), result.Error)
+		for _, line := range strings.Split(msg, "\n") {
+			fmt.Fprintln(os.Stderr, fmt.Sprintf("%s: %s", name, line))
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "")
+	return fmt.Errorf(i18n.G("Some instances failed to %s"), action)
+}
+
 func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 	conf := c.global.conf
 
 	var names []string
 	if len(args) == 0 {
-		if !c.flagAll {
+		if !c.flagAll && !c.flagAllProjects && c.flagFilter == "" {
 			cmd.Help()
 			return nil
 		}
@@ -216,6 +301,15 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		// Let the server change all the instances at once when it can.
+		if d.HasExtension("instance_bulk_state_change") {
+			return c.doBulkAction(cmd.Name(), d)
+		}
+
+		if c.flagAllProjects || c.flagFilter != "" {
+			return fmt.Errorf(i18n.G("The server doesn't support changing the state of instances in bulk"))
+		}
+
 		ctslist, err := d.GetInstances(api.InstanceTypeAny)
 		if err != nil {
 			return err
@@ -235,7 +329,7 @@ func (c *cmdAction) Run(cmd *cobra.Command, args []string) error {
 			names = append(names, ct.Name)
 		}
 	} else {
-		if c.flagAll {
+		if c.flagAll || c.flagAllProjects || c.flagFilter != "" {
 			return fmt.Errorf(i18n.G("Both --all and instance name given"))
 		}
 		names = args
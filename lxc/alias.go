@@ -89,7 +89,8 @@ type cmdAliasList struct {
 	global *cmdGlobal
 	alias  *cmdAlias
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdAliasList) Command() *cobra.Command {
@@ -99,7 +100,8 @@ func (c *cmdAliasList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List aliases")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List aliases`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -127,7 +129,7 @@ func (c *cmdAliasList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("TARGET"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, conf.Aliases)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, conf.Aliases)
 }
 
 // Rename
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdApply struct {
+	global *cmdGlobal
+
+	flagFile   string
+	flagDryRun bool
+}
+
+// applyManifest is the declared state of the resources of a server.
+type applyManifest struct {
+	Profiles       []api.ProfilesPost   `yaml:"profiles"`
+	Networks       []api.NetworksPost   `yaml:"networks"`
+	StorageVolumes []applyStorageVolume `yaml:"storage_volumes"`
+	Instances      []api.InstancesPost  `yaml:"instances"`
+}
+
+// applyStorageVolume is a storage volume declared in a manifest, along with its pool.
+type applyStorageVolume struct {
+	api.StorageVolumesPost `yaml:",inline"`
+
+	Pool string `yaml:"pool"`
+}
+
+// applyChange is what it takes to get a declared resource to its declared state.
+type applyChange struct {
+	kind string
+	name string
+
+	// Whether the resource gets created, and the description of the updates otherwise.
+	create  bool
+	updates []string
+
+	apply func() error
+}
+
+func (c *cmdApply) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("apply [<remote>:] -f <manifest>")
+	cmd.Short = i18n.G("Apply a manifest of declared resources")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Apply a manifest of declared resources
+
+The manifest is a YAML document listing profiles, networks, storage volumes
+and instances (with the fields of their creation requests) under the
+"profiles", "networks", "storage_volumes" and "instances" keys. Storage
+volumes also have their pool under the "pool" key.
+
+Missing resources get created and existing ones get updated to match the
+declaration. Declared config keys are set, others being left as they are
+(declare a key with an empty value to remove it). Declared devices replace
+the devices of the same name (declare an empty device to remove it).
+
+Resources are applied in the order of the keys above, so that instances can
+use the declared profiles, networks and volumes.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc apply -f manifest.yaml --dry-run
+    Show the changes needed to get to the state declared in manifest.yaml.
+
+lxc apply -f manifest.yaml
+    Apply the changes.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVarP(&c.flagFile, "file", "f", "", i18n.G("Manifest to apply (- for standard input)")+"``")
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, i18n.G("Only show the changes which would be applied"))
+
+	return cmd
+}
+
+func (c *cmdApply) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	if c.flagFile == "" {
+		return fmt.Errorf(i18n.G("A manifest must be provided with --file"))
+	}
+
+	// Parse the manifest
+	var content []byte
+	if c.flagFile == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(shared.HostPath(c.flagFile))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	manifest := applyManifest{}
+	err = yaml.UnmarshalStrict(content, &manifest)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Failed to parse the manifest: %v"), err)
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	remoteName, _, err := conf.ParseRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	d, err := conf.GetInstanceServer(remoteName)
+	if err != nil {
+		return err
+	}
+
+	// Work out the changes, failing before applying any if a declaration can't be applied
+	changes, err := c.plan(d, manifest)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, change := range changes {
+		if change.create {
+			fmt.Printf("+ %s %s\n", change.kind, change.name)
+		} else if len(change.updates) > 0 {
+			fmt.Printf("~ %s %s\n", change.kind, change.name)
+			for _, update := range change.updates {
+				fmt.Printf("    %s\n", update)
+			}
+		} else {
+			fmt.Printf("= %s %s\n", change.kind, change.name)
+			continue
+		}
+
+		count++
+		if c.flagDryRun {
+			continue
+		}
+
+		err := change.apply()
+		if err != nil {
+			return fmt.Errorf(i18n.G("Failed to apply %s %q: %v"), change.kind, change.name, err)
+		}
+	}
+
+	if c.flagDryRun {
+		fmt.Printf(i18n.G("%d changes to apply")+"\n", count)
+	} else {
+		fmt.Printf(i18n.G("%d changes applied")+"\n", count)
+	}
+
+	return nil
+}
+
+// plan returns the changes to apply to get the server to the state declared in the manifest.
+func (c *cmdApply) plan(d lxd.InstanceServer, manifest applyManifest) ([]applyChange, error) {
+	changes := []applyChange{}
+
+	// Profiles
+	profileNames, err := d.GetProfileNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, declared := range manifest.Profiles {
+		declared := declared
+		change := applyChange{kind: i18n.G("profile"), name: declared.Name}
+
+		if !shared.StringInSlice(declared.Name, profileNames) {
+			change.create = true
+			change.apply = func() error {
+				declared.Config, declared.Devices = applyCreateConfig(declared.Config, declared.Devices)
+				return d.CreateProfile(declared)
+			}
+		} else {
+			current, etag, err := d.GetProfile(declared.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			put := current.Writable()
+			put.Description, change.updates = applyDescription(put.Description, declared.Description, change.updates)
+			put.Config, change.updates = applyConfig(put.Config, declared.Config, change.updates)
+			put.Devices, change.updates = applyDevices(put.Devices, declared.Devices, change.updates)
+			change.apply = func() error {
+				return d.UpdateProfile(declared.Name, put, etag)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	// Networks
+	networkNames, err := d.GetNetworkNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, declared := range manifest.Networks {
+		declared := declared
+		change := applyChange{kind: i18n.G("network"), name: declared.Name}
+
+		if !shared.StringInSlice(declared.Name, networkNames) {
+			change.create = true
+			change.apply = func() error {
+				declared.Config, _ = applyCreateConfig(declared.Config, nil)
+				return d.CreateNetwork(declared)
+			}
+		} else {
+			current, etag, err := d.GetNetwork(declared.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if declared.Type != "" && declared.Type != current.Type {
+				return nil, fmt.Errorf(i18n.G("Network %q exists with type %q rather than %q"), declared.Name, current.Type, declared.Type)
+			}
+
+			put := current.Writable()
+			put.Description, change.updates = applyDescription(put.Description, declared.Description, change.updates)
+			put.Config, change.updates = applyConfig(put.Config, declared.Config, change.updates)
+			change.apply = func() error {
+				return d.UpdateNetwork(declared.Name, put, etag)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	// Storage volumes
+	volumeNames := map[string][]string{}
+	for _, declared := range manifest.StorageVolumes {
+		declared := declared
+		if declared.Pool == "" {
+			return nil, fmt.Errorf(i18n.G("Missing pool of storage volume %q"), declared.Name)
+		}
+
+		if declared.Type == "" {
+			declared.Type = "custom"
+		}
+
+		change := applyChange{kind: i18n.G("storage volume"), name: fmt.Sprintf("%s/%s/%s", declared.Pool, declared.Type, declared.Name)}
+
+		names, ok := volumeNames[declared.Pool]
+		if !ok {
+			names, err = d.GetStoragePoolVolumeNames(declared.Pool)
+			if err != nil {
+				return nil, err
+			}
+
+			volumeNames[declared.Pool] = names
+		}
+
+		if !shared.StringInSlice(fmt.Sprintf("%s/%s", declared.Type, declared.Name), names) {
+			change.create = true
+			change.apply = func() error {
+				declared.Config, _ = applyCreateConfig(declared.Config, nil)
+				return d.CreateStoragePoolVolume(declared.Pool, declared.StorageVolumesPost)
+			}
+		} else {
+			current, etag, err := d.GetStoragePoolVolume(declared.Pool, declared.Type, declared.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			put := current.Writable()
+			put.Description, change.updates = applyDescription(put.Description, declared.Description, change.updates)
+			put.Config, change.updates = applyConfig(put.Config, declared.Config, change.updates)
+			change.apply = func() error {
+				return d.UpdateStoragePoolVolume(declared.Pool, declared.Type, declared.Name, put, etag)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	// Instances
+	instanceNames, err := d.GetInstanceNames(api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, declared := range manifest.Instances {
+		declared := declared
+		change := applyChange{kind: i18n.G("instance"), name: declared.Name}
+
+		if !shared.StringInSlice(declared.Name, instanceNames) {
+			change.create = true
+			change.apply = func() error {
+				declared.Config, declared.Devices = applyCreateConfig(declared.Config, declared.Devices)
+				op, err := d.CreateInstance(declared)
+				if err != nil {
+					return err
+				}
+
+				return op.Wait()
+			}
+		} else {
+			current, etag, err := d.GetInstance(declared.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			put := current.Writable()
+			put.Description, change.updates = applyDescription(put.Description, declared.Description, change.updates)
+			put.Config, change.updates = applyConfig(put.Config, declared.Config, change.updates)
+			put.Devices, change.updates = applyDevices(put.Devices, declared.Devices, change.updates)
+
+			if declared.Profiles != nil && strings.Join(declared.Profiles, ",") != strings.Join(put.Profiles, ",") {
+				change.updates = append(change.updates, fmt.Sprintf("profiles: [%s] -> [%s]", strings.Join(put.Profiles, ", "), strings.Join(declared.Profiles, ", ")))
+				put.Profiles = declared.Profiles
+			}
+
+			change.apply = func() error {
+				op, err := d.UpdateInstance(declared.Name, put, etag)
+				if err != nil {
+					return err
+				}
+
+				return op.Wait()
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// applyDescription returns the description to set, adding its change to updates if any.
+func applyDescription(current string, declared string, updates []string) (string, []string) {
+	if declared == "" || declared == current {
+		return current, updates
+	}
+
+	return declared, append(updates, fmt.Sprintf("description: %q -> %q", current, declared))
+}
+
+// applyConfig returns the config resulting from setting the declared keys (removing the ones with
+// an empty value), adding the changes to updates.
+func applyConfig(current map[string]string, declared map[string]string, updates []string) (map[string]string, []string) {
+	config := map[string]string{}
+	for k, v := range current {
+		config[k] = v
+	}
+
+	keys := make([]string, 0, len(declared))
+	for k := range declared {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value, ok := config[k]
+		if declared[k] == "" {
+			if ok {
+				updates = append(updates, fmt.Sprintf("config.%s: %q -> removed", k, value))
+				delete(config, k)
+			}
+		} else if !ok {
+			updates = append(updates, fmt.Sprintf("config.%s: added %q", k, declared[k]))
+			config[k] = declared[k]
+		} else if value != declared[k] {
+			updates = append(updates, fmt.Sprintf("config.%s: %q -> %q", k, value, declared[k]))
+			config[k] = declared[k]
+		}
+	}
+
+	return config, updates
+}
+
+// applyDevices returns the devices resulting from replacing the declared ones (removing the empty
+// ones), adding the changes to updates.
+func applyDevices(current map[string]map[string]string, declared map[string]map[string]string, updates []string) (map[string]map[string]string, []string) {
+	devices := map[string]map[string]string{}
+	for k, v := range current {
+		devices[k] = v
+	}
+
+	names := make([]string, 0, len(declared))
+	for k := range declared {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		device, ok := devices[name]
+		if len(declared[name]) == 0 {
+			if ok {
+				updates = append(updates, fmt.Sprintf("devices.%s: removed", name))
+				delete(devices, name)
+			}
+		} else if !ok {
+			updates = append(updates, fmt.Sprintf("devices.%s: added", name))
+			devices[name] = declared[name]
+		} else if !applyDeviceEqual(device, declared[name]) {
+			updates = append(updates, fmt.Sprintf("devices.%s: changed", name))
+			devices[name] = declared[name]
+		}
+	}
+
+	return devices, updates
+}
+
+// applyDeviceEqual returns whether two devices have the same config.
+func applyDeviceEqual(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		value, ok := b[k]
+		if !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyCreateConfig returns the declared config and devices of a new resource, leaving out the
+// ones declared as removed.
+func applyCreateConfig(config map[string]string, devices map[string]map[string]string) (map[string]string, map[string]map[string]string) {
+	newConfig := map[string]string{}
+	for k, v := range config {
+		if v != "" {
+			newConfig[k] = v
+		}
+	}
+
+	newDevices := map[string]map[string]string{}
+	for k, v := range devices {
+		if len(v) > 0 {
+			newDevices[k] = v
+		}
+	}
+
+	return newConfig, newDevices
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyConfig(t *testing.T) {
+	current := map[string]string{
+		"limits.cpu":      "1",
+		"limits.memory":   "1GiB",
+		"user.comment":    "old",
+		"volatile.uuid":   "1234",
+		"security.nested": "true",
+	}
+
+	declared := map[string]string{
+		"limits.cpu":      "2",
+		"limits.memory":   "1GiB",
+		"user.comment":    "",
+		"user.owner":      "web",
+		"security.absent": "",
+	}
+
+	config, updates := applyConfig(current, declared, nil)
+	assert.Equal(t, map[string]string{
+		"limits.cpu":      "2",
+		"limits.memory":   "1GiB",
+		"user.owner":      "web",
+		"volatile.uuid":   "1234",
+		"security.nested": "true",
+	}, config)
+
+	assert.Equal(t, []string{
+		`config.limits.cpu: "1" -> "2"`,
+		`config.user.comment: "old" -> removed`,
+		`config.user.owner: added "web"`,
+	}, updates)
+
+	// The current config is left untouched.
+	assert.Equal(t, "1", current["limits.cpu"])
+}
+
+func TestApplyDevices(t *testing.T) {
+	current := map[string]map[string]string{
+		"eth0": {"type": "nic", "network": "lxdbr0"},
+		"root": {"type": "disk", "path": "/", "pool": "default"},
+		"data": {"type": "disk", "path": "/data", "source": "/srv"},
+	}
+
+	declared := map[string]map[string]string{
+		"eth0": {"type": "nic", "network": "lxdbr1"},
+		"root": {"type": "disk", "path": "/", "pool": "default"},
+		"data": {},
+		"gpu":  {"type": "gpu"},
+	}
+
+	devices, updates := applyDevices(current, declared, nil)
+	assert.Equal(t, map[string]map[string]string{
+		"eth0": {"type": "nic", "network": "lxdbr1"},
+		"root": {"type": "disk", "path": "/", "pool": "default"},
+		"gpu":  {"type": "gpu"},
+	}, devices)
+
+	assert.Equal(t, []string{
+		"devices.data: removed",
+		"devices.eth0: changed",
+		"devices.gpu: added",
+	}, updates)
+}
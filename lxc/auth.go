@@ -0,0 +1,1013 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdAuth struct {
+	global *cmdGlobal
+}
+
+func (c *cmdAuth) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("auth")
+	cmd.Short = i18n.G("Manage the built-in authorization")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage the built-in authorization
+
+Authorization groups are enforced once "auth.builtin" is set to true.`))
+
+	// Group
+	authGroupCmd := cmdAuthGroup{global: c.global, auth: c}
+	cmd.AddCommand(authGroupCmd.Command())
+
+	// Token
+	authTokenCmd := cmdAuthToken{global: c.global, auth: c}
+	cmd.AddCommand(authTokenCmd.Command())
+
+	return cmd
+}
+
+type cmdAuthGroup struct {
+	global *cmdGlobal
+	auth   *cmdAuth
+}
+
+func (c *cmdAuthGroup) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("group")
+	cmd.Short = i18n.G("Manage authorization groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage authorization groups
+
+Groups grant roles (auditor, user, operator or admin) to their identities, which are
+client certificate fingerprints or external usernames. Roles can be granted on the
+whole server, a project or a single instance of a project.`))
+
+	// Add
+	authGroupAddCmd := cmdAuthGroupAdd{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupAddCmd.Command())
+
+	// Create
+	authGroupCreateCmd := cmdAuthGroupCreate{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupCreateCmd.Command())
+
+	// Delete
+	authGroupDeleteCmd := cmdAuthGroupDelete{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupDeleteCmd.Command())
+
+	// Edit
+	authGroupEditCmd := cmdAuthGroupEdit{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupEditCmd.Command())
+
+	// Grant
+	authGroupGrantCmd := cmdAuthGroupGrant{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupGrantCmd.Command())
+
+	// List
+	authGroupListCmd := cmdAuthGroupList{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupListCmd.Command())
+
+	// Remove
+	authGroupRemoveCmd := cmdAuthGroupRemove{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupRemoveCmd.Command())
+
+	// Rename
+	authGroupRenameCmd := cmdAuthGroupRename{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupRenameCmd.Command())
+
+	// Revoke
+	authGroupRevokeCmd := cmdAuthGroupRevoke{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupRevokeCmd.Command())
+
+	// Show
+	authGroupShowCmd := cmdAuthGroupShow{global: c.global, authGroup: c}
+	cmd.AddCommand(authGroupShowCmd.Command())
+
+	return cmd
+}
+
+// parseGroup parses the group argument and checks a group name was provided.
+func (c *cmdAuthGroup) parseGroup(arg string) (remoteResource, error) {
+	resources, err := c.global.ParseServers(arg)
+	if err != nil {
+		return remoteResource{}, err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return remoteResource{}, fmt.Errorf(i18n.G("Missing authorization group name"))
+	}
+
+	return resource, nil
+}
+
+// parsePermission builds a permission from a role and an optional [<project>[/<instance>]] scope.
+func (c *cmdAuthGroup) parsePermission(args []string) api.AuthPermission {
+	permission := api.AuthPermission{Role: args[0]}
+	if len(args) > 1 {
+		fields := strings.SplitN(args[1], "/", 2)
+		permission.Project = fields[0]
+		if len(fields) > 1 {
+			permission.Instance = fields[1]
+		}
+	}
+
+	return permission
+}
+
+// formatPermission returns a permission in the format used by grant and revoke.
+func (c *cmdAuthGroup) formatPermission(permission api.AuthPermission) string {
+	if permission.Project == "" {
+		return permission.Role
+	}
+
+	if permission.Instance == "" {
+		return fmt.Sprintf("%s %s", permission.Role, permission.Project)
+	}
+
+	return fmt.Sprintf("%s %s/%s", permission.Role, permission.Project, permission.Instance)
+}
+
+// Add
+type cmdAuthGroupAdd struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<group> <identity>")
+	cmd.Short = i18n.G("Add an identity to an authorization group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add an identity to an authorization group
+
+The identity is a client certificate fingerprint or an external username.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(args[1], group.Identities) {
+		return fmt.Errorf(i18n.G("Identity %s is already in group %s"), args[1], resource.name)
+	}
+
+	group.Identities = append(group.Identities, args[1])
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Create
+type cmdAuthGroupCreate struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+
+	flagDescription string
+}
+
+func (c *cmdAuthGroupCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<group> [<identity>...]")
+	cmd.Short = i18n.G("Create authorization groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create authorization groups`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth group create developers alice bob
+    Create a group with two identities, then grant it roles with "lxc auth group grant".`))
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Authorization group description")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group := api.AuthGroupsPost{}
+	group.Name = resource.name
+	group.Description = c.flagDescription
+	group.Identities = args[1:]
+
+	err = resource.server.CreateAuthGroup(group)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Authorization group %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdAuthGroupDelete struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<group>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete authorization groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete authorization groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Authorization group %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdAuthGroupEdit struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<group>")
+	cmd.Short = i18n.G("Edit authorization groups as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit authorization groups as YAML`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth group edit <group> < group.yaml
+    Update an authorization group using the content of group.yaml`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the authorization group.
+### Any line starting with a '# will be ignored.
+###
+### An example would look like:
+### description: Developers
+### identities:
+### - 3ee64be3c3c7d617a7470e14f2d847081ad467c8c26e1caad841c8f67f7c7b09
+### permissions:
+### - role: operator
+###   project: dev
+### - role: user
+###   project: default
+###   instance: c1
+###
+### Note that the name is shown but cannot be changed`)
+}
+
+func (c *cmdAuthGroupEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.AuthGroupPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateAuthGroup(resource.name, newdata, "")
+	}
+
+	// Extract the current value
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.AuthGroupPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateAuthGroup(resource.name, newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Grant
+type cmdAuthGroupGrant struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupGrant) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("grant [<remote>:]<group> <role> [<project>[/<instance>]]")
+	cmd.Short = i18n.G("Grant a role to an authorization group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Grant a role to an authorization group
+
+Roles are granted on the whole server unless a project, or an instance of a project, is given.
+
+The roles are:
+ - auditor: read-only access
+ - user: the above, plus starting and stopping instances, exec, console, file transfer and snapshots
+ - operator: the above, plus creating, reconfiguring and deleting instances, images and profiles
+ - admin: the above, plus managing the project or, on the whole server, full access`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth group grant developers operator dev
+    Grant the operator role on the "dev" project.
+
+lxc auth group grant developers user default/c1
+    Grant the user role on instance "c1" of the "default" project.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupGrant) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	permission := c.authGroup.parsePermission(args[1:])
+	for _, p := range group.Permissions {
+		if p == permission {
+			return fmt.Errorf(i18n.G("Group %s already has %q"), resource.name, c.authGroup.formatPermission(permission))
+		}
+	}
+
+	group.Permissions = append(group.Permissions, permission)
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// List
+type cmdAuthGroupList struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdAuthGroupList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List authorization groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List authorization groups`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	groups, err := resources[0].server.GetAuthGroups()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, group := range groups {
+		permissions := []string{}
+		for _, permission := range group.Permissions {
+			permissions = append(permissions, c.authGroup.formatPermission(permission))
+		}
+
+		data = append(data, []string{group.Name, group.Description, strings.Join(group.Identities, "\n"), strings.Join(permissions, "\n")})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("IDENTITIES"),
+		i18n.G("PERMISSIONS"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, groups)
+}
+
+// Remove
+type cmdAuthGroupRemove struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<group> <identity>")
+	cmd.Short = i18n.G("Remove an identity from an authorization group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove an identity from an authorization group`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !shared.StringInSlice(args[1], group.Identities) {
+		return fmt.Errorf(i18n.G("Identity %s isn't in group %s"), args[1], resource.name)
+	}
+
+	identities := []string{}
+	for _, identity := range group.Identities {
+		if identity != args[1] {
+			identities = append(identities, identity)
+		}
+	}
+
+	group.Identities = identities
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Rename
+type cmdAuthGroupRename struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupRename) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("rename [<remote>:]<group> <new-name>")
+	cmd.Aliases = []string{"mv"}
+	cmd.Short = i18n.G("Rename authorization groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Rename authorization groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupRename) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.RenameAuthGroup(resource.name, api.AuthGroupPost{Name: args[1]})
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Authorization group %s renamed to %s")+"\n", resource.name, args[1])
+	}
+
+	return nil
+}
+
+// Revoke
+type cmdAuthGroupRevoke struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupRevoke) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke [<remote>:]<group> <role> [<project>[/<instance>]]")
+	cmd.Short = i18n.G("Revoke a role from an authorization group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke a role from an authorization group`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupRevoke) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	permission := c.authGroup.parsePermission(args[1:])
+
+	permissions := []api.AuthPermission{}
+	for _, p := range group.Permissions {
+		if p != permission {
+			permissions = append(permissions, p)
+		}
+	}
+
+	if len(permissions) == len(group.Permissions) {
+		return fmt.Errorf(i18n.G("Group %s doesn't have %q"), resource.name, c.authGroup.formatPermission(permission))
+	}
+
+	group.Permissions = permissions
+
+	return resource.server.UpdateAuthGroup(resource.name, group.Writable(), etag)
+}
+
+// Show
+type cmdAuthGroupShow struct {
+	global    *cmdGlobal
+	authGroup *cmdAuthGroup
+}
+
+func (c *cmdAuthGroupShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<group>")
+	cmd.Short = i18n.G("Show authorization group details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show authorization group details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthGroupShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, _, err := resource.server.GetAuthGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+type cmdAuthToken struct {
+	global *cmdGlobal
+	auth   *cmdAuth
+}
+
+func (c *cmdAuthToken) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("token")
+	cmd.Short = i18n.G("Manage API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage API tokens
+
+API tokens are an alternative to client certificates, restricted to some projects and
+capabilities, and which can expire.`))
+
+	// Create
+	authTokenCreateCmd := cmdAuthTokenCreate{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenCreateCmd.Command())
+
+	// Delete
+	authTokenDeleteCmd := cmdAuthTokenDelete{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenDeleteCmd.Command())
+
+	// List
+	authTokenListCmd := cmdAuthTokenList{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenListCmd.Command())
+
+	// Show
+	authTokenShowCmd := cmdAuthTokenShow{global: c.global, authToken: c}
+	cmd.AddCommand(authTokenShowCmd.Command())
+
+	return cmd
+}
+
+// parseToken parses the token argument and checks a token name was provided.
+func (c *cmdAuthToken) parseToken(arg string) (remoteResource, error) {
+	resources, err := c.global.ParseServers(arg)
+	if err != nil {
+		return remoteResource{}, err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return remoteResource{}, fmt.Errorf(i18n.G("Missing API token name"))
+	}
+
+	return resource, nil
+}
+
+// Create
+type cmdAuthTokenCreate struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+
+	flagCapability  string
+	flagDescription string
+	flagExpiry      string
+	flagProjects    []string
+}
+
+func (c *cmdAuthTokenCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<token>")
+	cmd.Short = i18n.G("Create API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create API tokens
+
+The capability of a token is one of:
+ - read-only: only GET requests
+ - exec-only: the above, plus running commands in instances
+ - full: all requests, limited to the projects of the token if any
+
+The secret of the token is only shown once, to be used with:
+  lxc remote add <name> <URL> --auth-type=token --token=<secret>`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc auth token create ci --capability=exec-only --projects=ci --expiry=30d
+    Create a token for the "ci" project which can run commands in instances and expires in 30 days.`))
+	cmd.Flags().StringVar(&c.flagCapability, "capability", "read-only", i18n.G("Capability of the token (read-only, exec-only or full)")+"``")
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("API token description")+"``")
+	cmd.Flags().StringVar(&c.flagExpiry, "expiry", "", i18n.G("Expiry of the token (e.g. 1H, 7d or 1y)")+"``")
+	cmd.Flags().StringSliceVar(&c.flagProjects, "projects", nil, i18n.G("Comma separated list of projects the token is restricted to")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authToken.parseToken(args[0])
+	if err != nil {
+		return err
+	}
+
+	token := api.AuthTokensPost{
+		Name:        resource.name,
+		Description: c.flagDescription,
+		Projects:    c.flagProjects,
+		Capability:  c.flagCapability,
+	}
+
+	if c.flagExpiry != "" {
+		token.ExpiresAt, err = shared.GetSnapshotExpiry(time.Now(), c.flagExpiry)
+		if err != nil {
+			return err
+		}
+	}
+
+	secret, err := resource.server.CreateAuthToken(token)
+	if err != nil {
+		return err
+	}
+
+	if c.global.flagQuiet {
+		fmt.Println(secret.Secret)
+		return nil
+	}
+
+	fmt.Printf(i18n.G("API token %s created, its secret is:")+"\n%s\n", resource.name, secret.Secret)
+
+	return nil
+}
+
+// Delete
+type cmdAuthTokenDelete struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+}
+
+func (c *cmdAuthTokenDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<token>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete API tokens`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authToken.parseToken(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteAuthToken(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("API token %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// List
+type cmdAuthTokenList struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdAuthTokenList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List API tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List API tokens`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := resources[0].server.GetAuthTokens()
+	if err != nil {
+		return err
+	}
+
+	const layout = "2006/01/02 15:04 MST"
+
+	data := [][]string{}
+	for _, token := range tokens {
+		projects := strings.Join(token.Projects, "\n")
+		if projects == "" {
+			projects = i18n.G("all")
+		}
+
+		expiry := i18n.G("never")
+		if !token.ExpiresAt.IsZero() {
+			expiry = token.ExpiresAt.Local().Format(layout)
+		}
+
+		data = append(data, []string{token.Name, token.Description, token.Capability, projects, expiry})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("CAPABILITY"),
+		i18n.G("PROJECTS"),
+		i18n.G("EXPIRES AT"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, tokens)
+}
+
+// Show
+type cmdAuthTokenShow struct {
+	global    *cmdGlobal
+	authToken *cmdAuthToken
+}
+
+func (c *cmdAuthTokenShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<token>")
+	cmd.Short = i18n.G("Show API token details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show API token details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdAuthTokenShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.authToken.parseToken(args[0])
+	if err != nil {
+		return err
+	}
+
+	token, err := resource.server.GetAuthToken(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -11,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 	yaml "gopkg.in/yaml.v2"
 
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -49,6 +51,38 @@ func (c *cmdCluster) Command() *cobra.Command {
 	clusterEnableCmd := cmdClusterEnable{global: c.global, cluster: c}
 	cmd.AddCommand(clusterEnableCmd.Command())
 
+	// Group
+	clusterGroupCmd := cmdClusterGroup{global: c.global, cluster: c}
+	cmd.AddCommand(clusterGroupCmd.Command())
+
+	// Evacuate
+	clusterEvacuateCmd := cmdClusterEvacuateAction{global: c.global, cluster: c, action: "evacuate"}
+	cmd.AddCommand(clusterEvacuateCmd.Command())
+
+	// Restore
+	clusterRestoreCmd := cmdClusterEvacuateAction{global: c.global, cluster: c, action: "restore"}
+	cmd.AddCommand(clusterRestoreCmd.Command())
+
+	// Maintenance
+	clusterMaintenanceCmd := cmdClusterEvacuateAction{global: c.global, cluster: c, action: "maintenance"}
+	cmd.AddCommand(clusterMaintenanceCmd.Command())
+
+	// Add token
+	clusterAddCmd := cmdClusterAdd{global: c.global, cluster: c}
+	cmd.AddCommand(clusterAddCmd.Command())
+
+	// List tokens
+	clusterListTokensCmd := cmdClusterListTokens{global: c.global, cluster: c}
+	cmd.AddCommand(clusterListTokensCmd.Command())
+
+	// Revoke tokens
+	clusterRevokeTokenCmd := cmdClusterRevokeToken{global: c.global, cluster: c}
+	cmd.AddCommand(clusterRevokeTokenCmd.Command())
+
+	// Upgrade
+	clusterUpgradeCmd := cmdClusterUpgrade{global: c.global, cluster: c}
+	cmd.AddCommand(clusterUpgradeCmd.Command())
+
 	return cmd
 }
 
@@ -57,7 +91,8 @@ type cmdClusterList struct {
 	global  *cmdGlobal
 	cluster *cmdCluster
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdClusterList) Command() *cobra.Command {
@@ -67,7 +102,8 @@ func (c *cmdClusterList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List all the cluster members")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List all the cluster members`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -131,7 +167,7 @@ func (c *cmdClusterList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("ARCHITECTURE"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, members)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, members)
 }
 
 // Show
@@ -317,6 +353,193 @@ func (c *cmdClusterRemove) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Evacuate, maintenance and restore
+type cmdClusterEvacuateAction struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+	action  string
+
+	flagForce bool
+}
+
+func (c *cmdClusterEvacuateAction) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = fmt.Sprintf("%s [<remote>:]<member>", c.action)
+
+	switch c.action {
+	case "evacuate":
+		cmd.Short = i18n.G("Evacuate cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Evacuate cluster member
+
+Instances are relocated to other members or stopped, according to their
+cluster.evacuate configuration, and no new instance is placed on the member.`))
+	case "maintenance":
+		cmd.Short = i18n.G("Put cluster member in maintenance mode")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Put cluster member in maintenance mode
+
+Instances keep running on the member, but no new instance is placed on it
+and images aren't replicated to it. Use "restore" to leave maintenance mode.`))
+	default:
+		cmd.Short = i18n.G("Restore cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Restore cluster member
+
+Instances evacuated from the member are moved back and started again, and
+the member leaves maintenance mode.`))
+	}
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Don't require user confirmation"))
+
+	return cmd
+}
+
+func (c *cmdClusterEvacuateAction) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Maintenance mode doesn't disrupt running instances.
+	if !c.flagForce && c.action != "maintenance" {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf(i18n.G("Are you sure you want to %s cluster member %q? (yes/no) [default=no]: "), c.action, resource.name)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSuffix(input, "\n")
+
+		if !shared.StringInSlice(strings.ToLower(input), []string{i18n.G("yes")}) {
+			return nil
+		}
+	}
+
+	op, err := resource.server.UpdateClusterMemberState(resource.name, api.ClusterMemberStatePost{Action: c.action})
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Failed to update cluster member state"))
+	}
+
+	var format string
+	switch c.action {
+	case "restore":
+		format = i18n.G("Restoring cluster member: %s")
+	case "maintenance":
+		format = i18n.G("Putting cluster member in maintenance mode: %s")
+	default:
+		format = i18n.G("Evacuating cluster member: %s")
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: format,
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+	return nil
+}
+
+// Upgrade
+type cmdClusterUpgrade struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagForce bool
+}
+
+func (c *cmdClusterUpgrade) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("upgrade [<remote>:]")
+	cmd.Short = i18n.G("Upgrade all the cluster members")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Upgrade all the cluster members
+
+Members are upgraded one failure domain at a time, members of the same cluster
+group sharing a domain, and the leader is upgraded last.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Don't require user confirmation"))
+
+	return cmd
+}
+
+func (c *cmdClusterUpgrade) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if !c.flagForce {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf(i18n.G("Are you sure you want to upgrade the cluster? (yes/no) [default=no]: "))
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSuffix(input, "\n")
+
+		if !shared.StringInSlice(strings.ToLower(input), []string{i18n.G("yes")}) {
+			return nil
+		}
+	}
+
+	op, err := resource.server.UpgradeCluster()
+	if err != nil {
+		return errors.Wrap(err, i18n.G("Failed to upgrade the cluster"))
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Upgrading cluster members: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done(i18n.G("The leader is being upgraded, which completes the cluster upgrade"))
+	return nil
+}
+
 // Enable
 type cmdClusterEnable struct {
 	global  *cmdGlobal
@@ -403,3 +626,233 @@ func (c *cmdClusterEnable) Run(cmd *cobra.Command, args []string) error {
 	fmt.Println(i18n.G("Clustering enabled"))
 	return nil
 }
+
+// clusterJoinTokens returns the pending join tokens of the cluster, along with the operations
+// holding them.
+func clusterJoinTokens(server lxd.InstanceServer) ([]api.Operation, []api.ClusterMemberJoinToken, error) {
+	ops, err := server.GetOperations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenOps := []api.Operation{}
+	tokens := []api.ClusterMemberJoinToken{}
+	for _, op := range ops {
+		if op.Class != "token" || op.StatusCode != api.Pending || op.Description != "Requesting to join the cluster" {
+			continue
+		}
+
+		data, err := json.Marshal(op.Metadata)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		token := api.ClusterMemberJoinToken{}
+		err = json.Unmarshal(data, &token)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tokenOps = append(tokenOps, op)
+		tokens = append(tokens, token)
+	}
+
+	return tokenOps, tokens, nil
+}
+
+// Add
+type cmdClusterAdd struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<name>")
+	cmd.Short = i18n.G("Request a join token for adding a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Request a join token for adding a cluster member
+
+The token is single-use and expires according to the cluster.join_token_expiry
+server configuration key. It is then provided to "lxd init" on the new member.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	// Request the join token
+	op, err := resource.server.CreateClusterMember(api.ClusterMembersPost{ServerName: resource.name})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(op.Get().Metadata)
+	if err != nil {
+		return err
+	}
+
+	token := api.ClusterMemberJoinToken{}
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Member %s join token:")+"\n", resource.name)
+	}
+
+	fmt.Println(token.String())
+
+	return nil
+}
+
+// List tokens
+type cmdClusterListTokens struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdClusterListTokens) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list-tokens [<remote>:]")
+	cmd.Short = i18n.G("List all active cluster member join tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List all active cluster member join tokens`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterListTokens) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	_, tokens, err := clusterJoinTokens(resource.server)
+	if err != nil {
+		return err
+	}
+
+	// Render the table
+	const layout = "2006/01/02 15:04 MST"
+
+	data := [][]string{}
+	for _, token := range tokens {
+		expiresAt := i18n.G("NEVER")
+		if !token.ExpiresAt.IsZero() {
+			expiresAt = token.ExpiresAt.Local().Format(layout)
+		}
+
+		data = append(data, []string{token.ServerName, token.String(), expiresAt})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("TOKEN"),
+		i18n.G("EXPIRES AT"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, tokens)
+}
+
+// Revoke token
+type cmdClusterRevokeToken struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterRevokeToken) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke-token [<remote>:]<name>")
+	cmd.Short = i18n.G("Revoke cluster member join token")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke cluster member join token`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterRevokeToken) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	ops, tokens, err := clusterJoinTokens(resource.server)
+	if err != nil {
+		return err
+	}
+
+	for i, token := range tokens {
+		if token.ServerName != resource.name {
+			continue
+		}
+
+		err = resource.server.DeleteOperation(ops[i].ID)
+		if err != nil {
+			return err
+		}
+
+		if !c.global.flagQuiet {
+			fmt.Printf(i18n.G("Cluster join token for %s deleted")+"\n", resource.name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(i18n.G("No cluster join token for member %s"), resource.name)
+}
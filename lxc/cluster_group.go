@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdClusterGroup struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterGroup) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("group")
+	cmd.Short = i18n.G("Manage cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage cluster groups
+
+Instances can be placed on the least busy member of a group with "--target @<group>".`))
+
+	// Assign
+	clusterGroupAssignCmd := cmdClusterGroupAssign{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupAssignCmd.Command())
+
+	// Create
+	clusterGroupCreateCmd := cmdClusterGroupCreate{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupCreateCmd.Command())
+
+	// Delete
+	clusterGroupDeleteCmd := cmdClusterGroupDelete{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupDeleteCmd.Command())
+
+	// List
+	clusterGroupListCmd := cmdClusterGroupList{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupListCmd.Command())
+
+	// Remove
+	clusterGroupRemoveCmd := cmdClusterGroupRemove{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupRemoveCmd.Command())
+
+	// Show
+	clusterGroupShowCmd := cmdClusterGroupShow{global: c.global, clusterGroup: c}
+	cmd.AddCommand(clusterGroupShowCmd.Command())
+
+	return cmd
+}
+
+// parseGroup parses the group argument and checks a group name was provided.
+func (c *cmdClusterGroup) parseGroup(arg string) (remoteResource, error) {
+	resources, err := c.global.ParseServers(arg)
+	if err != nil {
+		return remoteResource{}, err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return remoteResource{}, fmt.Errorf(i18n.G("Missing cluster group name"))
+	}
+
+	return resource, nil
+}
+
+// Assign
+type cmdClusterGroupAssign struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupAssign) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("assign [<remote>:]<group> <member>")
+	cmd.Short = i18n.G("Add a cluster member to a cluster group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add a cluster member to a cluster group`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupAssign) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.clusterGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if shared.StringInSlice(args[1], group.Members) {
+		return fmt.Errorf(i18n.G("Cluster member %s is already in group %s"), args[1], resource.name)
+	}
+
+	group.Members = append(group.Members, args[1])
+
+	return resource.server.UpdateClusterGroup(resource.name, group.Writable(), etag)
+}
+
+// Create
+type cmdClusterGroupCreate struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+
+	flagDescription string
+}
+
+func (c *cmdClusterGroupCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<group> [<member>...]")
+	cmd.Short = i18n.G("Create cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create cluster groups`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc cluster group create rack1 node1 node2
+    Create a group made of two cluster members.`))
+	cmd.Flags().StringVar(&c.flagDescription, "description", "", i18n.G("Cluster group description")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.clusterGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group := api.ClusterGroupsPost{}
+	group.Name = resource.name
+	group.Description = c.flagDescription
+	group.Members = args[1:]
+
+	err = resource.server.CreateClusterGroup(group)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Cluster group %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdClusterGroupDelete struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<group>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete cluster groups`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.clusterGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Cluster group %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// List
+type cmdClusterGroupList struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdClusterGroupList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List cluster groups")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List cluster groups`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	groups, err := resources[0].server.GetClusterGroups()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, group := range groups {
+		data = append(data, []string{group.Name, group.Description, strings.Join(group.Members, "\n")})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("MEMBERS"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, groups)
+}
+
+// Remove
+type cmdClusterGroupRemove struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<group> <member>")
+	cmd.Short = i18n.G("Remove a cluster member from a cluster group")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove a cluster member from a cluster group`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.clusterGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, etag, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !shared.StringInSlice(args[1], group.Members) {
+		return fmt.Errorf(i18n.G("Cluster member %s isn't in group %s"), args[1], resource.name)
+	}
+
+	members := []string{}
+	for _, member := range group.Members {
+		if member != args[1] {
+			members = append(members, member)
+		}
+	}
+
+	group.Members = members
+
+	return resource.server.UpdateClusterGroup(resource.name, group.Writable(), etag)
+}
+
+// Show
+type cmdClusterGroupShow struct {
+	global       *cmdGlobal
+	clusterGroup *cmdClusterGroup
+}
+
+func (c *cmdClusterGroupShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<group>")
+	cmd.Short = i18n.G("Show cluster group details")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show cluster group details`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterGroupShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.clusterGroup.parseGroup(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, _, err := resource.server.GetClusterGroup(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&group)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
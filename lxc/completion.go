@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/config"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+// completionCacheTTL is how long the names retrieved from a server are reused for completion.
+const completionCacheTTL = 30 * time.Second
+
+// completionFunc returns the completion candidates of an argument.
+type completionFunc func(toComplete string) ([]string, cobra.ShellCompDirective)
+
+type cmdCompletion struct {
+	global *cmdGlobal
+}
+
+func (c *cmdCompletion) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("completion <bash|zsh|fish>")
+	cmd.Short = i18n.G("Generate the shell completion script")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Generate the shell completion script
+
+The script completes the names of instances, images, storage pools, storage
+volumes and networks by querying the server, caching the names for a few
+seconds.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc completion bash > /etc/bash_completion.d/lxc
+    Install the bash completion script.
+
+source <(lxc completion zsh)
+    Load the zsh completion in the current shell.`))
+	cmd.ValidArgs = []string{"bash", "zsh", "fish"}
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdCompletion) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletion(os.Stdout)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(os.Stdout)
+	case "fish":
+		return cmd.Root().GenFishCompletion(os.Stdout, true)
+	}
+
+	return fmt.Errorf(i18n.G("Unsupported shell %q"), args[0])
+}
+
+// cmpArgs returns a completion function completing the positional arguments with the given
+// functions, in order. A nil function completes file names and arguments past the last function
+// aren't completed.
+func cmpArgs(funcs ...completionFunc) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(funcs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		if funcs[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		return funcs[len(args)](toComplete)
+	}
+}
+
+// cmpRepeat returns a completion function completing all the positional arguments with fn.
+func cmpRepeat(fn completionFunc) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return fn(toComplete)
+	}
+}
+
+// cmpInstances completes instance names.
+func (c *cmdGlobal) cmpInstances(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.cmpNames(toComplete, false, "instances", func(d lxd.ImageServer) ([]string, error) {
+		return d.(lxd.InstanceServer).GetInstanceNames(api.InstanceTypeAny)
+	})
+}
+
+// cmpImages completes image aliases, including the ones of public image servers.
+func (c *cmdGlobal) cmpImages(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.cmpNames(toComplete, true, "images", func(d lxd.ImageServer) ([]string, error) {
+		return d.GetImageAliasNames()
+	})
+}
+
+// cmpStoragePools completes storage pool names.
+func (c *cmdGlobal) cmpStoragePools(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.cmpNames(toComplete, false, "storage-pools", func(d lxd.ImageServer) ([]string, error) {
+		return d.(lxd.InstanceServer).GetStoragePoolNames()
+	})
+}
+
+// cmpNetworks completes network names.
+func (c *cmdGlobal) cmpNetworks(toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.cmpNames(toComplete, false, "networks", func(d lxd.ImageServer) ([]string, error) {
+		return d.(lxd.InstanceServer).GetNetworkNames()
+	})
+}
+
+// cmpStoragePoolVolumes completes the pool then the custom volume arguments of the storage volume
+// commands, the volume being looked up on the remote of the pool.
+func (c *cmdGlobal) cmpStoragePoolVolumes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return c.cmpStoragePools(toComplete)
+	}
+
+	if len(args) > 1 || strings.Contains(toComplete, ":") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	err := c.loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	remote, pool, err := c.conf.ParseRemote(args[0])
+	if err != nil || pool == "" {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	names, err := c.cmpCachedNames(remote, fmt.Sprintf("storage-pools_%s_volumes", pool), false, func(d lxd.ImageServer) ([]string, error) {
+		volumes, err := d.(lxd.InstanceServer).GetStoragePoolVolumeNames(pool)
+		if err != nil {
+			return nil, err
+		}
+
+		names := []string{}
+		for _, volume := range volumes {
+			if strings.HasPrefix(volume, "custom/") {
+				names = append(names, strings.TrimPrefix(volume, "custom/"))
+			}
+		}
+
+		return names, nil
+	})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	return cmpFilter(names, "", toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// cmpNames completes the names of a kind of resources of the remote given as part of toComplete,
+// or of the default remote. Without a remote, the matching remotes are suggested too.
+func (c *cmdGlobal) cmpNames(toComplete string, public bool, kind string, list func(d lxd.ImageServer) ([]string, error)) ([]string, cobra.ShellCompDirective) {
+	err := c.loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	remote, partial, err := c.conf.ParseRemote(toComplete)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	prefix := ""
+	if strings.Contains(toComplete, ":") {
+		prefix = remote + ":"
+	}
+
+	results := []string{}
+	names, err := c.cmpCachedNames(remote, kind, public, list)
+	if err == nil {
+		results = cmpFilter(names, prefix, partial)
+	}
+
+	if prefix != "" {
+		return results, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// Complete the remote names, without a trailing space to then type the resource name.
+	remotes := []string{}
+	for name, r := range c.conf.Remotes {
+		if !public && cmpImageOnly(r) {
+			continue
+		}
+
+		remotes = append(remotes, name+":")
+	}
+
+	remotes = cmpFilter(remotes, "", toComplete)
+	if len(results) == 0 && len(remotes) > 0 {
+		return remotes, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+
+	return append(results, remotes...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// cmpCachedNames returns the names of a kind of resources of the remote, reusing the ones cached
+// in the configuration directory when recent enough. Unless public is set, the list function gets
+// a lxd.InstanceServer and remotes only serving images have no names.
+func (c *cmdGlobal) cmpCachedNames(remote string, kind string, public bool, list func(d lxd.ImageServer) ([]string, error)) ([]string, error) {
+	r, ok := c.conf.Remotes[remote]
+	if !ok {
+		return nil, fmt.Errorf("The remote \"%s\" doesn't exist", remote)
+	}
+
+	imageOnly := cmpImageOnly(r)
+	if imageOnly && !public {
+		return nil, fmt.Errorf("The remote isn't a private LXD server")
+	}
+
+	project := c.conf.ProjectOverride
+	if project == "" {
+		project = r.Project
+	}
+
+	if project == "" {
+		project = "default"
+	}
+
+	cachePath := ""
+	if c.conf.ConfigDir != "" {
+		cachePath = c.conf.ConfigPath("cache", "completion", remote, project, fmt.Sprintf("%s.json", kind))
+	}
+
+	if cachePath != "" {
+		info, err := os.Stat(cachePath)
+		if err == nil && time.Since(info.ModTime()) < completionCacheTTL {
+			content, err := ioutil.ReadFile(cachePath)
+			if err == nil {
+				names := []string{}
+				err = json.Unmarshal(content, &names)
+				if err == nil {
+					return names, nil
+				}
+			}
+		}
+	}
+
+	var d lxd.ImageServer
+	var err error
+	if imageOnly {
+		d, err = c.conf.GetImageServer(remote)
+	} else {
+		d, err = c.conf.GetInstanceServer(remote)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := list(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Caching is best effort.
+	if cachePath != "" {
+		content, err := json.Marshal(names)
+		if err == nil && os.MkdirAll(filepath.Dir(cachePath), 0700) == nil {
+			ioutil.WriteFile(cachePath, content, 0600)
+		}
+	}
+
+	return names, nil
+}
+
+// cmpImageOnly returns whether the remote only serves images.
+func cmpImageOnly(r config.Remote) bool {
+	return r.Public || r.Protocol == "simplestreams" || r.Protocol == "oci"
+}
+
+// cmpFilter returns the sorted names starting with toComplete, with the prefix added.
+func cmpFilter(names []string, prefix string, toComplete string) []string {
+	results := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			results = append(results, prefix+name)
+		}
+	}
+
+	sort.Strings(results)
+	return results
+}
@@ -94,6 +94,7 @@ func (c *cmdConfigEdit) Command() *cobra.Command {
     Update the instance configuration from config.yaml.`))
 
 	cmd.Flags().StringVar(&c.config.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -374,6 +375,7 @@ func (c *cmdConfigGet) Command() *cobra.Command {
 		`Get values for instance or server configuration keys`))
 
 	cmd.Flags().StringVar(&c.config.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -467,6 +469,7 @@ lxc config set core.trust_password=blah
     Will set the server's trust password to blah.`))
 
 	cmd.Flags().StringVar(&c.config.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -582,6 +585,7 @@ func (c *cmdConfigShow) Command() *cobra.Command {
 
 	cmd.Flags().BoolVar(&c.flagExpanded, "expanded", false, i18n.G("Show the expanded configuration"))
 	cmd.Flags().StringVar(&c.config.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -700,6 +704,7 @@ func (c *cmdConfigUnset) Command() *cobra.Command {
 		`Unset instance or server configuration keys`))
 
 	cmd.Flags().StringVar(&c.config.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
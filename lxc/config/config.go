@@ -1,10 +1,15 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	"github.com/juju/persistent-cookiejar"
+
+	"github.com/lxc/lxd/client"
 )
 
 // Config holds settings to be used by a client or daemon
@@ -49,6 +54,31 @@ func (c *Config) CookiesPath(remote string) string {
 	return c.ConfigPath("jars", remote)
 }
 
+// OIDCTokensPath returns the path for the remote's OIDC tokens
+func (c *Config) OIDCTokensPath(remote string) string {
+	return c.ConfigPath("oidctokens", fmt.Sprintf("%s.json", remote))
+}
+
+// SaveOIDCTokens saves the remote's OIDC tokens to file
+func (c *Config) SaveOIDCTokens(remote string, tokens *lxd.OIDCTokens) error {
+	err := os.MkdirAll(c.ConfigPath("oidctokens"), 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.OIDCTokensPath(remote), data, 0600)
+}
+
+// AuthTokenPath returns the path for the secret of the remote's API token
+func (c *Config) AuthTokenPath(remote string) string {
+	return c.ConfigPath("tokens", remote)
+}
+
 // ServerCertPath returns the path for the remote's server certificate
 func (c *Config) ServerCertPath(remote string) string {
 	return c.ConfigPath("servercerts", fmt.Sprintf("%s.crt", remote))
@@ -2,6 +2,7 @@ package config
 
 import (
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
@@ -64,7 +65,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// Sanity checks
-	if remote.Public || remote.Protocol == "simplestreams" {
+	if remote.Public || remote.Protocol == "simplestreams" || remote.Protocol == "oci" {
 		return nil, fmt.Errorf("The remote isn't a private LXD server")
 	}
 
@@ -93,7 +94,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// HTTPs
-	if remote.AuthType != "candid" && (args.TLSClientCert == "" || args.TLSClientKey == "") {
+	if !shared.StringInSlice(remote.AuthType, []string{"candid", "oidc", "token"}) && (args.TLSClientCert == "" || args.TLSClientKey == "") {
 		return nil, fmt.Errorf("Missing TLS client certificate and key")
 	}
 
@@ -160,6 +161,16 @@ func (c *Config) GetImageServer(name string) (lxd.ImageServer, error) {
 		return d, nil
 	}
 
+	// HTTPs (OCI registry)
+	if remote.Protocol == "oci" {
+		d, err := lxd.ConnectOCI(remote.Addr, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return d, nil
+	}
+
 	// HTTPs (public LXD)
 	if remote.Public {
 		d, err := lxd.ConnectPublicLXD(remote.Addr, args)
@@ -244,6 +255,36 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 		args.CookieJar = c.cookieJars[name]
 	}
 
+	if args.AuthType == "token" && shared.PathExists(c.AuthTokenPath(name)) {
+		content, err := ioutil.ReadFile(c.AuthTokenPath(name))
+		if err != nil {
+			return nil, err
+		}
+
+		args.AuthToken = strings.TrimSpace(string(content))
+	}
+
+	if args.AuthType == "oidc" && shared.PathExists(c.OIDCTokensPath(name)) {
+		content, err := ioutil.ReadFile(c.OIDCTokensPath(name))
+		if err != nil {
+			return nil, err
+		}
+
+		tokens := lxd.OIDCTokens{}
+		err = json.Unmarshal(content, &tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		args.OIDCTokens = &tokens
+		args.OIDCTokensRefreshed = func(tokens *lxd.OIDCTokens) {
+			err := c.SaveOIDCTokens(name, tokens)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the OIDC tokens: %v\n", err)
+			}
+		}
+	}
+
 	// Stop here if no TLS involved
 	if strings.HasPrefix(remote.Addr, "unix:") {
 		return &args, nil
@@ -260,7 +301,7 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 	}
 
 	// Stop here if no client certificate involved
-	if remote.Protocol == "simplestreams" || remote.AuthType == "candid" {
+	if remote.Protocol == "simplestreams" || remote.Protocol == "oci" || shared.StringInSlice(remote.AuthType, []string{"candid", "oidc", "token"}) {
 		return &args, nil
 	}
 
@@ -2,11 +2,13 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/lxc/lxd/lxc/utils"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 )
@@ -251,6 +253,9 @@ type cmdConfigDeviceList struct {
 	config       *cmdConfig
 	configDevice *cmdConfigDevice
 	profile      *cmdProfile
+
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdConfigDeviceList) Command() *cobra.Command {
@@ -259,9 +264,13 @@ func (c *cmdConfigDeviceList) Command() *cobra.Command {
 	cmd.Aliases = []string{"ls"}
 	cmd.Short = i18n.G("List instance devices")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`List instance devices`))
+		`List instance devices
+
+Without --format, only the names of the devices are listed.`))
 
 	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagFormat, "format", "", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	return cmd
 }
@@ -286,30 +295,46 @@ func (c *cmdConfigDeviceList) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// List the devices
-	var devices []string
+	var devices map[string]map[string]string
 	if c.profile != nil {
 		profile, _, err := resource.server.GetProfile(resource.name)
 		if err != nil {
 			return err
 		}
 
-		for k := range profile.Devices {
-			devices = append(devices, k)
-		}
+		devices = profile.Devices
 	} else {
 		inst, _, err := resource.server.GetInstance(resource.name)
 		if err != nil {
 			return err
 		}
 
-		for k := range inst.Devices {
-			devices = append(devices, k)
+		devices = inst.Devices
+	}
+
+	if c.flagFormat == "" {
+		names := []string{}
+		for k := range devices {
+			names = append(names, k)
 		}
+
+		fmt.Printf("%s\n", strings.Join(names, "\n"))
+
+		return nil
 	}
 
-	fmt.Printf("%s\n", strings.Join(devices, "\n"))
+	data := [][]string{}
+	for k, device := range devices {
+		data = append(data, []string{k, device["type"]})
+	}
+	sort.Sort(byName(data))
 
-	return nil
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("TYPE"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, devices)
 }
 
 // Override
@@ -327,6 +352,7 @@ func (c *cmdConfigDeviceOverride) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Copy profile inherited devices and override configuration keys`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -227,7 +227,8 @@ type cmdConfigTemplateList struct {
 	config         *cmdConfig
 	configTemplate *cmdConfigTemplate
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdConfigTemplateList) Command() *cobra.Command {
@@ -236,7 +237,8 @@ func (c *cmdConfigTemplateList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List instance file templates")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List instance file templates`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -279,7 +281,7 @@ func (c *cmdConfigTemplateList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("FILENAME"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, templates)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, templates)
 }
 
 // Show
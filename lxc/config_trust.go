@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -49,6 +50,9 @@ type cmdConfigTrustAdd struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
+
+	flagType     string
+	flagProjects []string
 }
 
 func (c *cmdConfigTrustAdd) Command() *cobra.Command {
@@ -56,7 +60,18 @@ func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 	cmd.Use = i18n.G("add [<remote>:] <cert>")
 	cmd.Short = i18n.G("Add new trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Add new trusted clients`))
+		`Add new trusted clients
+
+The type of a client is one of:
+ - client: full access to the server
+ - viewer: only GET requests, within its projects if any
+ - operator: the above, plus changing the state of instances, running commands,
+   attaching to their console and taking snapshots`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc config trust add dashboard.crt --type=viewer --projects=web,db
+    Let the "dashboard" client view the "web" and "db" projects.`))
+	cmd.Flags().StringVar(&c.flagType, "type", "client", i18n.G("Type of the client (client, viewer or operator)")+"``")
+	cmd.Flags().StringSliceVar(&c.flagProjects, "projects", nil, i18n.G("Comma separated list of projects viewer and operator clients are restricted to")+"``")
 
 	cmd.RunE = c.Run
 
@@ -94,7 +109,8 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 	cert := api.CertificatesPost{}
 	cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
 	cert.Name = name
-	cert.Type = "client"
+	cert.Type = c.flagType
+	cert.Projects = c.flagProjects
 
 	return resource.server.CreateCertificate(cert)
 }
@@ -105,7 +121,8 @@ type cmdConfigTrustList struct {
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdConfigTrustList) Command() *cobra.Command {
@@ -115,7 +132,8 @@ func (c *cmdConfigTrustList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List trusted clients`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -151,6 +169,15 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 	data := [][]string{}
 	for _, cert := range trust {
 		fp := cert.Fingerprint[0:12]
+		certType := cert.Type
+
+		projects := strings.Join(cert.Projects, "\n")
+		if projects == "" {
+			projects = "-"
+			if certType != "client" {
+				projects = i18n.G("all")
+			}
+		}
 
 		certBlock, _ := pem.Decode([]byte(cert.Certificate))
 		if certBlock == nil {
@@ -165,18 +192,20 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 		const layout = "Jan 2, 2006 at 3:04pm (MST)"
 		issue := cert.NotBefore.Format(layout)
 		expiry := cert.NotAfter.Format(layout)
-		data = append(data, []string{fp, cert.Subject.CommonName, issue, expiry})
+		data = append(data, []string{fp, certType, cert.Subject.CommonName, projects, issue, expiry})
 	}
 	sort.Sort(stringList(data))
 
 	header := []string{
 		i18n.G("FINGERPRINT"),
+		i18n.G("TYPE"),
 		i18n.G("COMMON NAME"),
+		i18n.G("PROJECTS"),
 		i18n.G("ISSUE DATE"),
 		i18n.G("EXPIRY DATE"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, trust)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, trust)
 }
 
 // Remove
@@ -35,6 +35,7 @@ func (c *cmdConsole) Command() *cobra.Command {
 This command allows you to interact with the boot console of an instance
 as well as retrieve past log entries from it.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagShowLog, "show-log", false, i18n.G("Retrieve the instance's console log"))
 
@@ -18,19 +18,20 @@ import (
 type cmdCopy struct {
 	global *cmdGlobal
 
-	flagNoProfiles    bool
-	flagProfile       []string
-	flagConfig        []string
-	flagDevice        []string
-	flagEphemeral     bool
-	flagInstanceOnly  bool
-	flagContainerOnly bool
-	flagMode          string
-	flagStateless     bool
-	flagStorage       string
-	flagTarget        string
-	flagTargetProject string
-	flagRefresh       bool
+	flagNoProfiles     bool
+	flagProfile        []string
+	flagConfig         []string
+	flagDevice         []string
+	flagEphemeral      bool
+	flagInstanceOnly   bool
+	flagContainerOnly  bool
+	flagMode           string
+	flagStateless      bool
+	flagStorage        string
+	flagTarget         string
+	flagTargetProject  string
+	flagRefresh        bool
+	flagRefreshExclude []string
 }
 
 func (c *cmdCopy) Command() *cobra.Command {
@@ -41,6 +42,7 @@ func (c *cmdCopy) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Copy instances within or in between LXD servers`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the new instance")+"``")
 	cmd.Flags().StringArrayVarP(&c.flagDevice, "device", "d", nil, i18n.G("New key/value to apply to a specific device")+"``")
@@ -55,6 +57,7 @@ func (c *cmdCopy) Command() *cobra.Command {
 	cmd.Flags().StringVar(&c.flagTargetProject, "target-project", "", i18n.G("Copy to a project different from the source")+"``")
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagRefresh, "refresh", false, i18n.G("Perform an incremental copy"))
+	cmd.Flags().StringArrayVar(&c.flagRefreshExclude, "refresh-exclude", nil, i18n.G("Path of the instance to leave out of an incremental copy")+"``")
 
 	return cmd
 }
@@ -258,6 +261,14 @@ func (c *cmdCopy) copyInstance(conf *config.Config, sourceResource string, destR
 			Refresh:      c.flagRefresh,
 		}
 
+		if len(c.flagRefreshExclude) > 0 {
+			if sourceRemote != destRemote {
+				return fmt.Errorf(i18n.G("--refresh-exclude can only be used when copying within the same server"))
+			}
+
+			args.RefreshExcludes = c.flagRefreshExclude
+		}
+
 		// Copy of an instance into a new instance
 		entry, _, err := source.GetInstance(sourceName)
 		if err != nil {
@@ -468,6 +479,10 @@ func (c *cmdCopy) Run(cmd *cobra.Command, args []string) error {
 		mode = c.flagMode
 	}
 
+	if len(c.flagRefreshExclude) > 0 && !c.flagRefresh {
+		return fmt.Errorf(i18n.G("--refresh-exclude can only be used with --refresh"))
+	}
+
 	stateful := !c.flagStateless && !c.flagRefresh
 	keepVolatile := c.flagRefresh
 
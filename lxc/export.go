@@ -31,11 +31,17 @@ func (c *cmdExport) Command() *cobra.Command {
 	cmd.Use = i18n.G("export [<remote>:]<instance> [target] [--instance-only] [--optimized-storage]")
 	cmd.Short = i18n.G("Export instance backups")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Export instances as backup tarballs.`))
+		`Export instances as backup tarballs.
+
+The target can be "-" to write the tarball to standard output.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc export u1 backup0.tar.gz
-    Download a backup tarball of the u1 instance.`))
+    Download a backup tarball of the u1 instance.
+
+lxc export u1 - | ssh host lxc import -
+    Stream a backup tarball of the u1 instance to another host.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances, nil)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagContainerOnly, "container-only", false,
 		i18n.G("Whether or not to only backup the container (without snapshots), (deprecated, use instance-only)"))
@@ -109,26 +115,39 @@ func (c *cmdExport) Run(cmd *cobra.Command, args []string) error {
 		targetName = "backup.tar.gz"
 	}
 
-	target, err := os.Create(shared.HostPath(targetName))
-	if err != nil {
-		return err
+	// Stream to standard output, keeping it free of progress messages.
+	stream := targetName == "-"
+
+	var target io.Writer
+	if stream {
+		target = os.Stdout
+	} else {
+		file, err := os.Create(shared.HostPath(targetName))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		target = file
 	}
-	defer target.Close()
 
 	// Prepare the download request
 	progress := utils.ProgressRenderer{
 		Format: i18n.G("Exporting the backup: %s"),
-		Quiet:  c.global.flagQuiet,
+		Quiet:  c.global.flagQuiet || stream,
 	}
 	backupFileRequest := lxd.BackupFileRequest{
-		BackupFile:      io.WriteSeeker(target),
+		BackupFile:      target,
 		ProgressHandler: progress.UpdateProgress,
 	}
 
 	// Export tarball
 	_, err = d.GetInstanceBackupFile(name, backupName, &backupFileRequest)
 	if err != nil {
-		os.Remove(targetName)
+		if !stream {
+			os.Remove(shared.HostPath(targetName))
+		}
+
 		progress.Done("")
 		return errors.Wrap(err, "Fetch instance backup file")
 	}
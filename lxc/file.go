@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -84,6 +85,10 @@ func (c *cmdFile) Command() *cobra.Command {
 	filePushCmd := cmdFilePush{global: c.global, file: c}
 	cmd.AddCommand(filePushCmd.Command())
 
+	// Sync
+	fileSyncCmd := cmdFileSync{global: c.global, file: c}
+	cmd.AddCommand(fileSyncCmd.Command())
+
 	// Edit
 	fileEditCmd := cmdFileEdit{global: c.global, file: c, filePull: &filePullCmd, filePush: &filePushCmd}
 	cmd.AddCommand(fileEditCmd.Command())
@@ -648,6 +653,289 @@ func (c *cmdFilePush) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Sync
+type cmdFileSync struct {
+	global *cmdGlobal
+	file   *cmdFile
+
+	transferred int
+	unchanged   int
+}
+
+func (c *cmdFileSync) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("sync <source path> [<remote>:]<instance>/<path>")
+	cmd.Short = i18n.G("Synchronize files with instances")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Synchronize files with instances
+
+The content of the source is mirrored to the target, transferring only the files
+whose content differs. When the source exists locally, files are pushed into the
+instance, otherwise the source is taken as an instance path and files are pulled
+from it.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc file sync ./src foo/srv/app
+   To push the content of ./src into /srv/app in the instance "foo".
+
+lxc file sync foo/srv/app ./src
+   To pull the content of /srv/app in the instance "foo" into ./src.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdFileSync) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Figure out the direction
+	push := shared.PathExists(shared.HostPath(filepath.Clean(args[0])))
+	remoteArg := args[0]
+	localPath := shared.HostPath(filepath.Clean(args[1]))
+	if push {
+		remoteArg = args[1]
+		localPath = shared.HostPath(filepath.Clean(args[0]))
+	}
+
+	pathSpec := strings.SplitN(remoteArg, "/", 2)
+	if len(pathSpec) != 2 {
+		return fmt.Errorf(i18n.G("Invalid path %s"), remoteArg)
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(pathSpec[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	instancePath := path.Clean("/" + pathSpec[1])
+
+	if push {
+		err = c.push(resource.server, resource.name, localPath, instancePath)
+	} else {
+		err = c.pull(resource.server, resource.name, instancePath, localPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("%d files transferred, %d unchanged")+"\n", c.transferred, c.unchanged)
+	}
+
+	return nil
+}
+
+// push mirrors a local path to the instance.
+func (c *cmdFileSync) push(d lxd.InstanceServer, inst string, source string, target string) error {
+	sendFile := func(p string, fInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf(i18n.G("Failed to walk path for %s: %s"), p, err)
+		}
+
+		// Detect unsupported files
+		if !fInfo.Mode().IsRegular() && !fInfo.Mode().IsDir() && fInfo.Mode()&os.ModeSymlink != os.ModeSymlink {
+			return fmt.Errorf(i18n.G("'%s' isn't a supported file type"), p)
+		}
+
+		targetPath := path.Join(target, filepath.ToSlash(strings.TrimPrefix(p, source)))
+		mode, uid, gid := shared.GetOwnerMode(fInfo)
+		args := lxd.InstanceFileArgs{
+			UID:  int64(uid),
+			GID:  int64(gid),
+			Mode: int(mode.Perm()),
+		}
+
+		if fInfo.IsDir() {
+			args.Type = "directory"
+		} else if fInfo.Mode()&os.ModeSymlink == os.ModeSymlink {
+			symlinkTarget, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			args.Type = "symlink"
+			args.Content = bytes.NewReader([]byte(symlinkTarget))
+		} else {
+			// Skip files the instance already has
+			checksum, err := fileChecksum(p)
+			if err != nil {
+				return err
+			}
+
+			content, resp, err := d.GetInstanceFileIfChanged(inst, targetPath, checksum)
+			if err == nil {
+				if content != nil {
+					content.Close()
+				} else if resp.Type == "file" {
+					c.unchanged++
+					return nil
+				}
+			}
+
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			progress := utils.ProgressRenderer{
+				Format: fmt.Sprintf(i18n.G("Pushing %s to %s: %%s"), p, targetPath),
+				Quiet:  c.global.flagQuiet,
+			}
+
+			args.Type = "file"
+			args.Content = shared.NewReadSeeker(&ioprogress.ProgressReader{
+				ReadCloser: f,
+				Tracker: &ioprogress.ProgressTracker{
+					Length: fInfo.Size(),
+					Handler: func(percent int64, speed int64) {
+						progress.UpdateProgress(ioprogress.ProgressData{
+							Text: fmt.Sprintf("%d%% (%s/s)", percent,
+								units.GetByteSizeString(speed, 2))})
+					},
+				},
+			}, f)
+
+			logger.Infof("Pushing %s to %s (%s)", p, targetPath, args.Type)
+			err = d.CreateInstanceFile(inst, targetPath, args)
+			progress.Done("")
+			if err != nil {
+				return err
+			}
+
+			c.transferred++
+			return nil
+		}
+
+		logger.Infof("Pushing %s to %s (%s)", p, targetPath, args.Type)
+		return d.CreateInstanceFile(inst, targetPath, args)
+	}
+
+	return filepath.Walk(source, sendFile)
+}
+
+// pull mirrors an instance path locally.
+func (c *cmdFileSync) pull(d lxd.InstanceServer, inst string, source string, target string) error {
+	// Only get the content of files which differ from the local ones
+	checksum := ""
+	fInfo, err := os.Lstat(target)
+	if err == nil && fInfo.Mode().IsRegular() {
+		checksum, err = fileChecksum(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf, resp, err := d.GetInstanceFileIfChanged(inst, source, checksum)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Pulling %s from %s (%s)", target, source, resp.Type)
+
+	if resp.Type == "directory" {
+		err := os.MkdirAll(target, os.FileMode(resp.Mode))
+		if err != nil {
+			return err
+		}
+
+		for _, ent := range resp.Entries {
+			err := c.pull(d, inst, path.Join(source, ent), filepath.Join(target, ent))
+			if err != nil {
+				return err
+			}
+		}
+	} else if resp.Type == "file" {
+		if buf == nil {
+			c.unchanged++
+			return nil
+		}
+		defer buf.Close()
+
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		err = os.Chmod(target, os.FileMode(resp.Mode))
+		if err != nil {
+			return err
+		}
+
+		progress := utils.ProgressRenderer{
+			Format: fmt.Sprintf(i18n.G("Pulling %s from %s: %%s"), target, source),
+			Quiet:  c.global.flagQuiet,
+		}
+
+		writer := &ioprogress.ProgressWriter{
+			WriteCloser: f,
+			Tracker: &ioprogress.ProgressTracker{
+				Handler: func(bytesReceived int64, speed int64) {
+					progress.UpdateProgress(ioprogress.ProgressData{
+						Text: fmt.Sprintf("%s (%s/s)",
+							units.GetByteSizeString(bytesReceived, 2),
+							units.GetByteSizeString(speed, 2))})
+				},
+			},
+		}
+
+		_, err = io.Copy(writer, buf)
+		progress.Done("")
+		if err != nil {
+			return err
+		}
+
+		c.transferred++
+	} else if resp.Type == "symlink" {
+		linkTarget, err := ioutil.ReadAll(buf)
+		buf.Close()
+		if err != nil {
+			return err
+		}
+
+		// Replace the local symlink if it points elsewhere
+		newTarget := strings.TrimSpace(string(linkTarget))
+		oldTarget, err := os.Readlink(target)
+		if err == nil && oldTarget == newTarget {
+			return nil
+		}
+
+		os.Remove(target)
+		err = os.Symlink(newTarget, target)
+		if err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf(i18n.G("Unknown file type '%s'"), resp.Type)
+	}
+
+	return nil
+}
+
+// fileChecksum returns the SHA-256 checksum of a local file.
+func fileChecksum(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
 func (c *cmdFile) recursivePullFile(d lxd.InstanceServer, inst string, p string, targetDir string) error {
 	buf, resp, err := d.GetInstanceFile(inst, p)
 	if err != nil {
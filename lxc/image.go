@@ -56,6 +56,10 @@ hash or alias name (if one is set).`))
 	imageAliasCmd := cmdImageAlias{global: c.global, image: c}
 	cmd.AddCommand(imageAliasCmd.Command())
 
+	// Build
+	imageBuildCmd := cmdImageBuild{global: c.global, image: c}
+	cmd.AddCommand(imageBuildCmd.Command())
+
 	// Copy
 	imageCopyCmd := cmdImageCopy{global: c.global, image: c}
 	cmd.AddCommand(imageCopyCmd.Command())
@@ -84,6 +88,10 @@ hash or alias name (if one is set).`))
 	imageListCmd := cmdImageList{global: c.global, image: c}
 	cmd.AddCommand(imageListCmd.Command())
 
+	// Push
+	imagePushCmd := cmdImagePush{global: c.global, image: c}
+	cmd.AddCommand(imagePushCmd.Command())
+
 	// Refresh
 	imageRefreshCmd := cmdImageRefresh{global: c.global, image: c}
 	cmd.AddCommand(imageRefreshCmd.Command())
@@ -108,6 +116,132 @@ func (c *cmdImage) dereferenceAlias(d lxd.ImageServer, imageType string, inName
 	return result.Target
 }
 
+// Build
+type cmdImageBuild struct {
+	global *cmdGlobal
+	image  *cmdImage
+
+	flagAliases []string
+	flagPublic  bool
+}
+
+func (c *cmdImageBuild) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("build [<remote>:] <manifest> [key=value...]")
+	cmd.Short = i18n.G("Build images from a manifest")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Build images from a manifest
+
+The server creates a throwaway instance from the source image of the YAML
+manifest, writes its files and runs its actions in it, then publishes it as
+a new image. Existing aliases are moved to the new image.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image build manifest.yaml --alias web
+    Build an image from manifest.yaml, such as:
+
+    source:
+      type: image
+      alias: ubuntu/20.04
+      server: https://images.linuxcontainers.org
+      protocol: simplestreams
+    files:
+    - path: /etc/motd
+      content: Built by LXD
+    actions:
+    - apt-get update
+    - apt-get install -y nginx`))
+
+	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageBuild) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := conf.DefaultRemote
+	if strings.HasSuffix(args[0], ":") {
+		remote = strings.TrimSuffix(args[0], ":")
+		args = args[1:]
+		if len(args) == 0 {
+			return fmt.Errorf(i18n.G("Missing manifest"))
+		}
+	}
+
+	d, err := conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	// Parse the manifest
+	content, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	build := api.ImageBuild{}
+	err = yaml.Unmarshal(content, &build)
+	if err != nil {
+		return err
+	}
+
+	req := api.ImagesPost{
+		Source: &api.ImagesPostSource{
+			Type:  "build",
+			Build: &build,
+		},
+	}
+
+	req.Public = c.flagPublic
+	req.Properties, err = getConfig(args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range c.flagAliases {
+		req.Aliases = append(req.Aliases, api.ImageAlias{Name: entry})
+	}
+
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Building the image: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	op, err := d.CreateImage(req, nil)
+	if err != nil {
+		return err
+	}
+
+	// Register progress handler
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for the build to complete
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+	progress.Done("")
+
+	opAPI := op.Get()
+	fmt.Printf(i18n.G("Image built with fingerprint: %s")+"\n", opAPI.Metadata["fingerprint"])
+
+	return nil
+}
+
 // Copy
 type cmdImageCopy struct {
 	global *cmdGlobal
@@ -136,6 +270,7 @@ It requires the source to be an alias and for it to be public.`))
 	cmd.Flags().BoolVar(&c.flagAutoUpdate, "auto-update", false, i18n.G("Keep the image up to date after initial copy"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Copy virtual machine images"))
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -270,6 +405,7 @@ func (c *cmdImageDelete) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Delete images`))
 
+	cmd.ValidArgsFunction = cmpRepeat(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -327,6 +463,7 @@ func (c *cmdImageEdit) Command() *cobra.Command {
 lxc image edit <image> < image.yaml
     Load the image properties from a YAML file`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -449,6 +586,7 @@ func (c *cmdImageExport) Command() *cobra.Command {
 The output target is optional and defaults to the working directory.`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages, nil)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -594,8 +732,9 @@ type cmdImageImport struct {
 	global *cmdGlobal
 	image  *cmdImage
 
-	flagPublic  bool
-	flagAliases []string
+	flagPublic    bool
+	flagAliases   []string
+	flagSignature string
 }
 
 func (c *cmdImageImport) Command() *cobra.Command {
@@ -609,6 +748,7 @@ Directory import is only available on Linux and must be performed as root.`))
 
 	cmd.Flags().BoolVar(&c.flagPublic, "public", false, i18n.G("Make image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New aliases to add to the image")+"``")
+	cmd.Flags().StringVar(&c.flagSignature, "signature", "", i18n.G("File holding the armored detached signature of the image")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -721,6 +861,20 @@ func (c *cmdImageImport) Run(cmd *cobra.Command, args []string) error {
 		image.Properties[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
 	}
 
+	// Handle the signature
+	if c.flagSignature != "" {
+		signature, err := ioutil.ReadFile(c.flagSignature)
+		if err != nil {
+			return err
+		}
+
+		if image.Properties == nil {
+			image.Properties = map[string]string{}
+		}
+
+		image.Properties["signature"] = string(signature)
+	}
+
 	progress := utils.ProgressRenderer{
 		Format: i18n.G("Transferring image: %s"),
 		Quiet:  c.global.flagQuiet,
@@ -834,6 +988,7 @@ func (c *cmdImageInfo) Command() *cobra.Command {
 		`Show useful information about images`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -884,6 +1039,11 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 		autoUpdate = i18n.G("enabled")
 	}
 
+	pinned := i18n.G("no")
+	if info.Pinned {
+		pinned = i18n.G("yes")
+	}
+
 	imgType := "container"
 	if info.Type != "" {
 		imgType = info.Type
@@ -930,6 +1090,10 @@ func (c *cmdImageInfo) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf(i18n.G("Cached: %s")+"\n", cached)
+	fmt.Printf(i18n.G("Pinned: %s")+"\n", pinned)
+	if info.CacheExpiry > 0 {
+		fmt.Printf(i18n.G("Cache expiry: %d days")+"\n", info.CacheExpiry)
+	}
 	fmt.Printf(i18n.G("Auto update: %s")+"\n", autoUpdate)
 
 	if info.UpdateSource != nil {
@@ -973,7 +1137,8 @@ or part of the image hash or part of the image alias name.
 
 The -c option takes a (optionally comma-separated) list of arguments
 that control which image attributes to output when displaying in table
-or csv format.
+or csv format. Arguments are shorthand chars (see below) or column names
+(e.g. "fingerprint" or "upload_date").
 
 Default column layout is: lfpdasu
 
@@ -991,7 +1156,7 @@ Column shorthand chars:
     t - Type`))
 
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "lfpdatsu", i18n.G("Columns")+"``")
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1011,6 +1176,14 @@ func (c *cmdImageList) parseColumns() ([]imageColumn, error) {
 		't': {i18n.G("TYPE"), c.typeColumnData},
 	}
 
+	// Columns can also be selected by name, as for the other list commands.
+	columnsNameMap := map[string]imageColumn{}
+	for shorthand, column := range columnsShorthandMap {
+		if shorthand != 'F' {
+			columnsNameMap[utils.ColumnName(column.Name)] = column
+		}
+	}
+
 	columnList := strings.Split(c.flagColumns, ",")
 
 	columns := []imageColumn{}
@@ -1019,6 +1192,12 @@ func (c *cmdImageList) parseColumns() ([]imageColumn, error) {
 			return nil, fmt.Errorf(i18n.G("Empty column entry (redundant, leading or trailing command) in '%s'"), c.flagColumns)
 		}
 
+		column, ok := columnsNameMap[utils.ColumnName(columnEntry)]
+		if ok && len(columnEntry) > 1 {
+			columns = append(columns, column)
+			continue
+		}
+
 		for _, columnRune := range columnEntry {
 			if column, ok := columnsShorthandMap[columnRune]; ok {
 				columns = append(columns, column)
@@ -1253,6 +1432,100 @@ func (c *cmdImageList) Run(cmd *cobra.Command, args []string) error {
 	return utils.RenderTable(c.flagFormat, headers, data, rawData)
 }
 
+// Push
+type cmdImagePush struct {
+	global *cmdGlobal
+	image  *cmdImage
+}
+
+func (c *cmdImagePush) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("push [<remote>:]<image> <remote>:<name>[:<tag>]")
+	cmd.Short = i18n.G("Push images to OCI registries")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Push images to OCI registries
+
+The image is pushed by the LXD server as an OCI artifact, using the registry
+credentials configured on the server. The target remote must use the "oci" protocol.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image push ubuntu-web registry:lxd/ubuntu-web:20.04
+    Push the image with alias "ubuntu-web" to the "registry" remote as lxd/ubuntu-web:20.04.`))
+
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImagePush) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remotes
+	remoteName, name, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	targetRemote, targetName, err := conf.ParseRemote(args[1])
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf(i18n.G("Image identifier missing"))
+	}
+
+	if targetName == "" {
+		return fmt.Errorf(i18n.G("Target image name missing"))
+	}
+
+	remote, ok := conf.Remotes[targetRemote]
+	if !ok || remote.Protocol != "oci" {
+		return fmt.Errorf(i18n.G("The target remote must be an OCI registry"))
+	}
+
+	d, err := conf.GetInstanceServer(remoteName)
+	if err != nil {
+		return err
+	}
+
+	image := c.image.dereferenceAlias(d, "", name)
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Pushing the image: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	op, err := d.ExportImage(image, api.ImageExportPost{
+		Protocol: "oci",
+		Server:   remote.Addr,
+		Alias:    targetName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Register progress handler
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		return err
+	}
+
+	err = op.Wait()
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done(i18n.G("Image pushed successfully!"))
+	return nil
+}
+
 // Refresh
 type cmdImageRefresh struct {
 	global *cmdGlobal
@@ -1266,6 +1539,7 @@ func (c *cmdImageRefresh) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Refresh images`))
 
+	cmd.ValidArgsFunction = cmpRepeat(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1346,6 +1620,7 @@ func (c *cmdImageShow) Command() *cobra.Command {
 		`Show image properties`))
 
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Query virtual machine images"))
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -41,6 +41,14 @@ func (c *cmdImageAlias) Command() *cobra.Command {
 	imageAliasRenameCmd := cmdImageAliasRename{global: c.global, image: c.image, imageAlias: c}
 	cmd.AddCommand(imageAliasRenameCmd.Command())
 
+	// Set
+	imageAliasSetCmd := cmdImageAliasSet{global: c.global, image: c.image, imageAlias: c}
+	cmd.AddCommand(imageAliasSetCmd.Command())
+
+	// Unset
+	imageAliasUnsetCmd := cmdImageAliasUnset{global: c.global, image: c.image, imageAlias: c, imageAliasSet: &imageAliasSetCmd}
+	cmd.AddCommand(imageAliasUnsetCmd.Command())
+
 	return cmd
 }
 
@@ -105,6 +113,7 @@ func (c *cmdImageAliasDelete) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Delete image aliases`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -139,7 +148,8 @@ type cmdImageAliasList struct {
 	image      *cmdImage
 	imageAlias *cmdImageAlias
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdImageAliasList) Command() *cobra.Command {
@@ -152,7 +162,8 @@ func (c *cmdImageAliasList) Command() *cobra.Command {
 
 Filters may be part of the image hash or part of the image alias name.
 `))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -234,7 +245,7 @@ func (c *cmdImageAliasList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("DESCRIPTION"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, aliases)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, aliases)
 }
 
 // Rename
@@ -252,6 +263,7 @@ func (c *cmdImageAliasRename) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Rename aliases`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -279,3 +291,93 @@ func (c *cmdImageAliasRename) Run(cmd *cobra.Command, args []string) error {
 	// Rename the alias
 	return resource.server.RenameImageAlias(resource.name, api.ImageAliasesEntryPost{Name: args[1]})
 }
+
+// Set
+type cmdImageAliasSet struct {
+	global     *cmdGlobal
+	image      *cmdImage
+	imageAlias *cmdImageAlias
+}
+
+func (c *cmdImageAliasSet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("set [<remote>:]<alias> <key> <value>")
+	cmd.Short = i18n.G("Set image alias configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Set image alias configuration keys
+
+The configuration of an alias sets the auto-update policy of the image it points to.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc image alias set ubuntu auto_update.window 02:00-04:00
+    Only look for new versions of the image between 2 and 4 AM.`))
+
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasSet) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Alias name missing"))
+	}
+
+	alias, etag, err := resource.server.GetImageAlias(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if alias.Config == nil {
+		alias.Config = map[string]string{}
+	}
+
+	alias.Config[args[1]] = args[2]
+
+	return resource.server.UpdateImageAlias(resource.name, alias.ImageAliasesEntryPut, etag)
+}
+
+// Unset
+type cmdImageAliasUnset struct {
+	global        *cmdGlobal
+	image         *cmdImage
+	imageAlias    *cmdImageAlias
+	imageAliasSet *cmdImageAliasSet
+}
+
+func (c *cmdImageAliasUnset) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("unset [<remote>:]<alias> <key>")
+	cmd.Short = i18n.G("Unset image alias configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Unset image alias configuration keys`))
+
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdImageAliasUnset) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	args = append(args, "")
+	return c.imageAliasSet.Run(cmd, args)
+}
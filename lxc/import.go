@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -26,10 +27,15 @@ func (c *cmdImport) Command() *cobra.Command {
 	cmd.Use = i18n.G("import [<remote>:] <backup file>")
 	cmd.Short = i18n.G("Import instance backups")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Import backups of instances including their snapshots.`))
+		`Import backups of instances including their snapshots.
+
+The backup file can be "-" to read the tarball from standard input.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc import backup0.tar.gz
-    Create a new instance using backup0.tar.gz as the source.`))
+    Create a new instance using backup0.tar.gz as the source.
+
+ssh host lxc export u1 - | lxc import -
+    Create a new instance from a backup tarball streamed from another host.`))
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
@@ -57,15 +63,25 @@ func (c *cmdImport) Run(cmd *cobra.Command, args []string) error {
 
 	resource := resources[0]
 
-	file, err := os.Open(shared.HostPath(args[len(args)-1]))
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	fstat, err := file.Stat()
-	if err != nil {
-		return err
+	// The size of a stream isn't known, so no time left can be estimated.
+	var file io.ReadCloser
+	var size int64
+	if args[len(args)-1] == "-" {
+		file = os.Stdin
+	} else {
+		f, err := os.Open(shared.HostPath(args[len(args)-1]))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		fstat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		file = f
+		size = fstat.Size()
 	}
 
 	progress := utils.ProgressRenderer{
@@ -73,15 +89,23 @@ func (c *cmdImport) Run(cmd *cobra.Command, args []string) error {
 		Quiet:  c.global.flagQuiet,
 	}
 
+	tracker := &ioprogress.ProgressTracker{
+		Length: size,
+	}
+
+	tracker.Handler = func(value int64, speed int64) {
+		progress.UpdateProgress(ioprogress.ProgressData{
+			Text:             fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(tracker.Processed(), 2), units.GetByteSizeString(speed, 2)),
+			TransferredBytes: tracker.Processed(),
+			TotalBytes:       size,
+			Speed:            speed,
+		})
+	}
+
 	createArgs := lxd.InstanceBackupArgs{
 		BackupFile: &ioprogress.ProgressReader{
 			ReadCloser: file,
-			Tracker: &ioprogress.ProgressTracker{
-				Length: fstat.Size(),
-				Handler: func(percent int64, speed int64) {
-					progress.UpdateProgress(ioprogress.ProgressData{Text: fmt.Sprintf("%d%% (%s/s)", percent, units.GetByteSizeString(speed, 2))})
-				},
-			},
+			Tracker:    tracker,
 		},
 		PoolName: c.flagStorage,
 	}
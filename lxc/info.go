@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -39,6 +40,7 @@ func (c *cmdInfo) Command() *cobra.Command {
 lxc info [<remote>:] [--resources]
     For LXD server information.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagShowLog, "show-log", false, i18n.G("Show the instance's last 100 log lines?"))
 	cmd.Flags().BoolVar(&c.flagResources, "resources", false, i18n.G("Show the resources available to the server"))
@@ -130,6 +132,29 @@ func (c *cmdInfo) renderGPU(gpu api.ResourcesGPUCard, prefix string, initial boo
 		fmt.Printf(prefix+"  "+i18n.G("UUID: %v")+"\n", gpu.Nvidia.UUID)
 	}
 
+	if len(gpu.Mdev) > 0 {
+		fmt.Printf(prefix + i18n.G("Mdev profiles:") + "\n")
+
+		keys := make([]string, 0, len(gpu.Mdev))
+		for k := range gpu.Mdev {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, id := range keys {
+			mdev := gpu.Mdev[id]
+			if mdev.Name != "" {
+				fmt.Printf(prefix+"  - "+i18n.G("%s (%s) (%d available)")+"\n", id, mdev.Name, mdev.Available)
+			} else {
+				fmt.Printf(prefix+"  - "+i18n.G("%s (%d available)")+"\n", id, mdev.Available)
+			}
+
+			if mdev.Description != "" {
+				fmt.Printf(prefix+"      %s\n", mdev.Description)
+			}
+		}
+	}
+
 	if gpu.SRIOV != nil {
 		fmt.Printf(prefix + i18n.G("SR-IOV information:") + "\n")
 		fmt.Printf(prefix+"  "+i18n.G("Current number of VFs: %d")+"\n", gpu.SRIOV.CurrentVFs)
@@ -219,6 +244,7 @@ func (c *cmdInfo) renderNIC(nic api.ResourcesNetworkCard, prefix string, initial
 		fmt.Printf(prefix + i18n.G("SR-IOV information:") + "\n")
 		fmt.Printf(prefix+"  "+i18n.G("Current number of VFs: %d")+"\n", nic.SRIOV.CurrentVFs)
 		fmt.Printf(prefix+"  "+i18n.G("Maximum number of VFs: %d")+"\n", nic.SRIOV.MaximumVFs)
+		fmt.Printf(prefix+"  "+i18n.G("Available VFs: %d")+"\n", nic.SRIOV.AvailableVFs)
 		if len(nic.SRIOV.VFs) > 0 {
 			fmt.Printf(prefix+"  "+i18n.G("VFs: %d")+"\n", nic.SRIOV.MaximumVFs)
 			for _, vf := range nic.SRIOV.VFs {
@@ -255,6 +281,24 @@ func (c *cmdInfo) renderDisk(disk api.ResourcesStorageDisk, prefix string, initi
 	fmt.Printf(prefix+i18n.G("Read-Only: %v")+"\n", disk.ReadOnly)
 	fmt.Printf(prefix+i18n.G("Removable: %v")+"\n", disk.Removable)
 
+	if disk.NVMe != nil {
+		fmt.Printf(prefix + i18n.G("NVMe:") + "\n")
+		fmt.Printf(prefix+"  "+i18n.G("Namespace: %d")+"\n", disk.NVMe.Namespace)
+		fmt.Printf(prefix+"  "+i18n.G("Controller: %s (id: %d)")+"\n", disk.NVMe.Controller, disk.NVMe.ControllerID)
+
+		if disk.NVMe.Transport != "" {
+			fmt.Printf(prefix+"  "+i18n.G("Transport: %s")+"\n", disk.NVMe.Transport)
+		}
+
+		if disk.NVMe.Address != "" {
+			fmt.Printf(prefix+"  "+i18n.G("Address: %s")+"\n", disk.NVMe.Address)
+		}
+
+		if disk.NVMe.SubsystemNQN != "" {
+			fmt.Printf(prefix+"  "+i18n.G("Subsystem NQN: %s")+"\n", disk.NVMe.SubsystemNQN)
+		}
+	}
+
 	if len(disk.Partitions) != 0 {
 		fmt.Printf(prefix + i18n.G("Partitions:") + "\n")
 		for _, partition := range disk.Partitions {
@@ -267,6 +311,38 @@ func (c *cmdInfo) renderDisk(disk api.ResourcesStorageDisk, prefix string, initi
 	}
 }
 
+func (c *cmdInfo) renderUSB(usb api.ResourcesUSBDevice, prefix string) {
+	if usb.Vendor != "" {
+		fmt.Printf(prefix+i18n.G("Vendor: %v (%v)")+"\n", usb.Vendor, usb.VendorID)
+	} else {
+		fmt.Printf(prefix+i18n.G("Vendor ID: %v")+"\n", usb.VendorID)
+	}
+
+	if usb.Product != "" {
+		fmt.Printf(prefix+i18n.G("Product: %v (%v)")+"\n", usb.Product, usb.ProductID)
+	} else {
+		fmt.Printf(prefix+i18n.G("Product ID: %v")+"\n", usb.ProductID)
+	}
+
+	fmt.Printf(prefix+i18n.G("Bus Address: %v")+"\n", usb.BusAddress)
+	fmt.Printf(prefix+i18n.G("Device Address: %v")+"\n", usb.DeviceAddress)
+	fmt.Printf(prefix+i18n.G("Speed: %vMbit/s")+"\n", usb.Speed)
+
+	if usb.Ports > 0 {
+		fmt.Printf(prefix+i18n.G("Ports: %d")+"\n", usb.Ports)
+	}
+
+	if len(usb.Interfaces) > 0 {
+		fmt.Printf(prefix + i18n.G("Interfaces:") + "\n")
+		for _, iface := range usb.Interfaces {
+			fmt.Printf(prefix+"  - "+i18n.G("%d (class: %02x, subclass: %02x)")+"\n", iface.Number, iface.ClassID, iface.SubClassID)
+			if iface.Driver != "" {
+				fmt.Printf(prefix+"    "+i18n.G("Driver: %v (%v)")+"\n", iface.Driver, iface.DriverVersion)
+			}
+		}
+	}
+}
+
 func (c *cmdInfo) renderCPU(cpu api.ResourcesCPUSocket, prefix string) {
 	if cpu.Vendor != "" {
 		fmt.Printf(prefix+i18n.G("Vendor: %v")+"\n", cpu.Vendor)
@@ -357,6 +433,15 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 				fmt.Printf("      "+i18n.G("Free: %v")+"\n", units.GetByteSizeString(int64(node.Total-node.Used), 2))
 				fmt.Printf("      "+i18n.G("Used: %v")+"\n", units.GetByteSizeString(int64(node.Used), 2))
 				fmt.Printf("      "+i18n.G("Total: %v")+"\n", units.GetByteSizeString(int64(node.Total), 2))
+
+				if len(node.Distances) > 0 {
+					distances := make([]string, 0, len(node.Distances))
+					for _, distance := range node.Distances {
+						distances = append(distances, fmt.Sprintf("%d", distance))
+					}
+
+					fmt.Printf("      "+i18n.G("Distances: %s")+"\n", strings.Join(distances, " "))
+				}
 			}
 		}
 
@@ -400,6 +485,20 @@ func (c *cmdInfo) remoteInfo(d lxd.InstanceServer) error {
 			}
 		}
 
+		// USB devices
+		if len(resources.USB.Devices) > 0 {
+			fmt.Printf("\n" + i18n.G("USB devices:") + "\n")
+			for _, usb := range resources.USB.Devices {
+				if usb.Parent != "" {
+					fmt.Printf("  "+i18n.G("%s (on %s):")+"\n", usb.ID, usb.Parent)
+				} else {
+					fmt.Printf("  %s:\n", usb.ID)
+				}
+
+				c.renderUSB(usb, "    ")
+			}
+		}
+
 		return nil
 	}
 
@@ -556,6 +655,23 @@ func (c *cmdInfo) instanceInfo(d lxd.InstanceServer, remote config.Remote, name
 		}
 	}
 
+	// Accumulated network usage
+	if len(cs.NetworkUsage) > 0 {
+		devices := []string{}
+		for devName := range cs.NetworkUsage {
+			devices = append(devices, devName)
+		}
+		sort.Strings(devices)
+
+		fmt.Println(i18n.G("Network accounting:"))
+		for _, devName := range devices {
+			usage := cs.NetworkUsage[devName]
+			fmt.Printf("  %s:\n", devName)
+			fmt.Printf("    %s: %s\n", i18n.G("Bytes received"), units.GetByteSizeString(usage.BytesReceived, 2))
+			fmt.Printf("    %s: %s\n", i18n.G("Bytes sent"), units.GetByteSizeString(usage.BytesSent, 2))
+		}
+	}
+
 	// List snapshots
 	firstSnapshot := true
 	snaps, err := d.GetInstanceSnapshots(name)
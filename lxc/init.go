@@ -44,6 +44,7 @@ lxc init ubuntu:18.04 u1 < config.yaml
     Create the instance with configuration from config.yaml`))
 	cmd.Hidden = true
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the new instance")+"``")
 	cmd.Flags().StringArrayVarP(&c.flagProfile, "profile", "p", nil, i18n.G("Profile to apply to the new instance")+"``")
@@ -51,7 +52,7 @@ lxc init ubuntu:18.04 u1 < config.yaml
 	cmd.Flags().StringVarP(&c.flagNetwork, "network", "n", "", i18n.G("Network name")+"``")
 	cmd.Flags().StringVarP(&c.flagStorage, "storage", "s", "", i18n.G("Storage pool name")+"``")
 	cmd.Flags().StringVarP(&c.flagType, "type", "t", "", i18n.G("Instance type")+"``")
-	cmd.Flags().StringVar(&c.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVar(&c.flagTarget, "target", "", i18n.G("Cluster member name, or cluster group name prefixed with @")+"``")
 	cmd.Flags().BoolVar(&c.flagNoProfiles, "no-profiles", false, i18n.G("Create the instance with no profiles applied"))
 	cmd.Flags().BoolVar(&c.flagEmpty, "empty", false, i18n.G("Create an empty instance"))
 	cmd.Flags().BoolVar(&c.flagVM, "vm", false, i18n.G("Create a virtual machine"))
@@ -253,8 +254,8 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 			image = "default"
 		}
 
-		// Optimisation for simplestreams
-		if conf.Remotes[iremote].Protocol == "simplestreams" {
+		// Optimisation for simplestreams and OCI registries (resolved by the server)
+		if conf.Remotes[iremote].Protocol == "simplestreams" || conf.Remotes[iremote].Protocol == "oci" {
 			imgInfo = &api.Image{}
 			imgInfo.Fingerprint = image
 			imgInfo.Public = true
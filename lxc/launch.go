@@ -29,6 +29,7 @@ lxc launch ubuntu:18.04 u1 < config.yaml
     Create and start the instance with configuration from config.yaml`))
 	cmd.Hidden = false
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpImages)
 	cmd.RunE = c.Run
 
 	return cmd
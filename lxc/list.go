@@ -70,7 +70,7 @@ which instance attributes to output when displaying in table or csv
 format.
 
 Column arguments are either pre-defined shorthand chars (see below),
-or (extended) config keys.
+column names (e.g. "name" or "last_used_at") or (extended) config keys.
 
 Commas between consecutive shorthand chars are optional.
 
@@ -112,7 +112,7 @@ lxc list -c ns,user.comment:comment
 
 	cmd.RunE = c.Run
 	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", defaultColumns, i18n.G("Columns")+"``")
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
 	cmd.Flags().BoolVar(&c.flagFast, "fast", false, i18n.G("Fast mode (same as --columns=nsacPt)"))
 
 	return cmd
@@ -448,13 +448,32 @@ func (c *cmdList) parseColumns(clustered bool) ([]column, bool, error) {
 	if clustered {
 		columnsShorthandMap['L'] = column{
 			i18n.G("LOCATION"), c.locationColumnData, false, false}
-	} else {
-		if c.flagColumns != defaultColumns {
-			if strings.ContainsAny(c.flagColumns, "L") {
+	}
+
+	// Columns can also be selected by name, as for the other list commands.
+	columnsNameMap := map[string]column{}
+	for shorthand, column := range columnsShorthandMap {
+		if shorthand != 'F' {
+			columnsNameMap[utils.ColumnName(column.Name)] = column
+		}
+	}
+
+	if !clustered {
+		entries := strings.Split(c.flagColumns, ",")
+		for i, entry := range entries {
+			_, isName := columnsNameMap[utils.ColumnName(entry)]
+			if isName || strings.Contains(entry, ".") || !strings.Contains(entry, "L") {
+				continue
+			}
+
+			if c.flagColumns != defaultColumns {
 				return nil, false, fmt.Errorf(i18n.G("Can't specify column L when not clustered"))
 			}
+
+			entries[i] = strings.Replace(entry, "L", "", -1)
 		}
-		c.flagColumns = strings.Replace(c.flagColumns, "L", "", -1)
+
+		c.flagColumns = strings.Join(entries, ",")
 	}
 
 	columnList := strings.Split(c.flagColumns, ",")
@@ -469,6 +488,17 @@ func (c *cmdList) parseColumns(clustered bool) ([]column, bool, error) {
 		// Config keys always contain a period, parse anything without a
 		// period as a series of shorthand runes.
 		if !strings.Contains(columnEntry, ".") {
+			column, ok := columnsNameMap[utils.ColumnName(columnEntry)]
+			if ok && len(columnEntry) > 1 {
+				columns = append(columns, column)
+
+				if column.NeedsState || column.NeedsSnapshots {
+					needsData = true
+				}
+
+				continue
+			}
+
 			for _, columnRune := range columnEntry {
 				if column, ok := columnsShorthandMap[columnRune]; ok {
 					columns = append(columns, column)
@@ -77,10 +77,22 @@ For help with any of those, simply call them with --help.`))
 	aliasCmd := cmdAlias{global: &globalCmd}
 	app.AddCommand(aliasCmd.Command())
 
+	// apply sub-command
+	applyCmd := cmdApply{global: &globalCmd}
+	app.AddCommand(applyCmd.Command())
+
+	// auth sub-command
+	authCmd := cmdAuth{global: &globalCmd}
+	app.AddCommand(authCmd.Command())
+
 	// cluster sub-command
 	clusterCmd := cmdCluster{global: &globalCmd}
 	app.AddCommand(clusterCmd.Command())
 
+	// completion sub-command
+	completionCmd := cmdCompletion{global: &globalCmd}
+	app.AddCommand(completionCmd.Command())
+
 	// config sub-command
 	configCmd := cmdConfig{global: &globalCmd}
 	app.AddCommand(configCmd.Command())
@@ -193,6 +205,10 @@ For help with any of those, simply call them with --help.`))
 	snapshotCmd := cmdSnapshot{global: &globalCmd}
 	app.AddCommand(snapshotCmd.Command())
 
+	// sriov-pool sub-command
+	sriovPoolCmd := cmdSRIOVPool{global: &globalCmd}
+	app.AddCommand(sriovPoolCmd.Command())
+
 	// storage sub-command
 	storageCmd := cmdStorage{global: &globalCmd}
 	app.AddCommand(storageCmd.Command())
@@ -205,6 +221,10 @@ For help with any of those, simply call them with --help.`))
 	stopCmd := cmdStop{global: &globalCmd}
 	app.AddCommand(stopCmd.Command())
 
+	// top sub-command
+	topCmd := cmdTop{global: &globalCmd}
+	app.AddCommand(topCmd.Command())
+
 	// version sub-command
 	versionCmd := cmdVersion{global: &globalCmd}
 	app.AddCommand(versionCmd.Command())
@@ -264,48 +284,14 @@ To easily setup a local LXD server in a virtual machine, consider using: https:/
 func (c *cmdGlobal) PreRun(cmd *cobra.Command, args []string) error {
 	var err error
 
-	// If calling the help, skip pre-run
-	if cmd.Name() == "help" {
+	// If calling the help or the shell completion, skip pre-run
+	if shared.StringInSlice(cmd.Name(), []string{"help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}) {
 		return nil
 	}
 
-	// Figure out the config directory and config path
-	var configDir string
-	if os.Getenv("LXD_CONF") != "" {
-		configDir = os.Getenv("LXD_CONF")
-	} else if os.Getenv("HOME") != "" {
-		configDir = path.Join(os.Getenv("HOME"), ".config", "lxc")
-	} else {
-		user, err := user.Current()
-		if err != nil {
-			return err
-		}
-
-		configDir = path.Join(user.HomeDir, ".config", "lxc")
-	}
-
-	c.confPath = os.ExpandEnv(path.Join(configDir, "config.yml"))
-
-	// Load the configuration
-	if c.flagForceLocal {
-		c.conf = config.NewConfig("", true)
-	} else if shared.PathExists(c.confPath) {
-		c.conf, err = config.LoadConfig(c.confPath)
-		if err != nil {
-			return err
-		}
-	} else {
-		c.conf = config.NewConfig(filepath.Dir(c.confPath), true)
-	}
-
-	// Override the project
-	if c.flagProject != "" {
-		c.conf.ProjectOverride = c.flagProject
-	}
-
-	// Setup password helper
-	c.conf.PromptPassword = func(filename string) (string, error) {
-		return cli.AskPasswordOnce(fmt.Sprintf(i18n.G("Password for %s: "), filename)), nil
+	err = c.loadConfig()
+	if err != nil {
+		return err
 	}
 
 	// If the user is running a command that may attempt to connect to the local daemon
@@ -355,7 +341,7 @@ func (c *cmdGlobal) PreRun(cmd *cobra.Command, args []string) error {
 	c.conf.UserAgent = version.UserAgent
 
 	// Setup the logger
-	logger.Log, err = logging.GetLogger("", "", c.flagLogVerbose, c.flagLogDebug, nil)
+	logger.Log, err = logging.GetLogger("", "", "", c.flagLogVerbose, c.flagLogDebug, nil)
 	if err != nil {
 		return err
 	}
@@ -363,6 +349,53 @@ func (c *cmdGlobal) PreRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// loadConfig loads the client configuration, also used by the shell completion which doesn't go
+// through PreRun.
+func (c *cmdGlobal) loadConfig() error {
+	var err error
+
+	// Figure out the config directory and config path
+	var configDir string
+	if os.Getenv("LXD_CONF") != "" {
+		configDir = os.Getenv("LXD_CONF")
+	} else if os.Getenv("HOME") != "" {
+		configDir = path.Join(os.Getenv("HOME"), ".config", "lxc")
+	} else {
+		user, err := user.Current()
+		if err != nil {
+			return err
+		}
+
+		configDir = path.Join(user.HomeDir, ".config", "lxc")
+	}
+
+	c.confPath = os.ExpandEnv(path.Join(configDir, "config.yml"))
+
+	// Load the configuration
+	if c.flagForceLocal {
+		c.conf = config.NewConfig("", true)
+	} else if shared.PathExists(c.confPath) {
+		c.conf, err = config.LoadConfig(c.confPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.conf = config.NewConfig(filepath.Dir(c.confPath), true)
+	}
+
+	// Override the project
+	if c.flagProject != "" {
+		c.conf.ProjectOverride = c.flagProject
+	}
+
+	// Setup password helper
+	c.conf.PromptPassword = func(filename string) (string, error) {
+		return cli.AskPasswordOnce(fmt.Sprintf(i18n.G("Password for %s: "), filename)), nil
+	}
+
+	return nil
+}
+
 func (c *cmdGlobal) PostRun(cmd *cobra.Command, args []string) error {
 	// Macaroon teardown
 	if c.conf != nil && shared.PathExists(c.confPath) {
@@ -3,10 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
@@ -21,6 +23,9 @@ type cmdMonitor struct {
 	flagType     []string
 	flagPretty   bool
 	flagLogLevel string
+	flagResource []string
+	flagSince    string
+	flagReplay   int
 }
 
 func (c *cmdMonitor) Command() *cobra.Command {
@@ -39,13 +44,19 @@ lxc monitor --pretty --type=logging --loglevel=info
     Show a pretty log of messages with info level or higher.
 
 lxc monitor --type=lifecycle
-    Only show lifecycle events.`))
+    Only show lifecycle events.
+
+lxc monitor --type=lifecycle --resource=/1.0/instances/c1 --since=10m
+    Show the lifecycle events of instance c1 from the last 10 minutes onwards.`))
 	cmd.Hidden = true
 
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagPretty, "pretty", false, i18n.G("Pretty rendering"))
 	cmd.Flags().StringArrayVar(&c.flagType, "type", nil, i18n.G("Event type to listen for")+"``")
 	cmd.Flags().StringVar(&c.flagLogLevel, "loglevel", "", i18n.G("Minimum level for log messages")+"``")
+	cmd.Flags().StringArrayVar(&c.flagResource, "resource", nil, i18n.G("Only show events about resources matching this pattern")+"``")
+	cmd.Flags().StringVar(&c.flagSince, "since", "", i18n.G("Replay the recent events since this timestamp or duration ago")+"``")
+	cmd.Flags().IntVar(&c.flagReplay, "replay", 0, i18n.G("Replay up to this many recent events")+"``")
 
 	return cmd
 }
@@ -79,7 +90,25 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	listener, err := d.GetEvents()
+	var listener *lxd.EventListener
+	if len(c.flagResource) > 0 || c.flagSince != "" || c.flagReplay > 0 {
+		args := lxd.EventsArgs{
+			Types:     c.flagType,
+			Resources: c.flagResource,
+			Replay:    c.flagReplay,
+		}
+
+		if c.flagSince != "" {
+			args.Since, err = parseSince(c.flagSince)
+			if err != nil {
+				return err
+			}
+		}
+
+		listener, err = d.GetEventsWithArgs(&args)
+	} else {
+		listener, err = d.GetEvents()
+	}
 	if err != nil {
 		return err
 	}
@@ -168,3 +197,18 @@ func (c *cmdMonitor) Run(cmd *cobra.Command, args []string) error {
 
 	return <-chError
 }
+
+// parseSince accepts either a RFC3339 timestamp or a duration to go back from now.
+func parseSince(value string) (time.Time, error) {
+	duration, err := time.ParseDuration(value)
+	if err == nil {
+		return time.Now().Add(-duration), nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(i18n.G("Invalid since value %q, must be a timestamp or a duration"), value)
+	}
+
+	return since, nil
+}
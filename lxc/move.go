@@ -46,6 +46,7 @@ lxc move <old name> <new name> [--instance-only]
 lxc move <instance>/<old snapshot name> <instance>/<new snapshot name>
     Rename a snapshot.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the target instance")+"``")
 	cmd.Flags().StringArrayVarP(&c.flagDevice, "device", "d", nil, i18n.G("New key/value to apply to a specific device")+"``")
@@ -99,6 +100,25 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Moving an instance to another project of the same server is done in place when supported.
+	if sourceRemote == destRemote && c.flagTarget == "" && c.flagStorage == "" && c.flagTargetProject != "" && (destName == "" || destName == sourceName) && !shared.IsSnapshot(sourceName) {
+		if c.flagConfig == nil && c.flagDevice == nil && c.flagProfile == nil && !c.flagNoProfiles && !c.flagInstanceOnly && !c.flagContainerOnly {
+			source, err := conf.GetInstanceServer(sourceRemote)
+			if err != nil {
+				return err
+			}
+
+			if source.HasExtension("instance_project_move") {
+				op, err := source.RenameInstance(sourceName, api.InstancePost{Name: sourceName, Project: c.flagTargetProject})
+				if err != nil {
+					return err
+				}
+
+				return op.Wait()
+			}
+		}
+	}
+
 	// As an optimization, if the source an destination are the same, do
 	// this via a simple rename. This only works for instances that aren't
 	// running, instances that are running should be live migrated (of
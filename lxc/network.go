@@ -56,10 +56,18 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkDetachProfileCmd := cmdNetworkDetachProfile{global: c.global, network: c}
 	cmd.AddCommand(networkDetachProfileCmd.Command())
 
+	// Diagnose
+	networkDiagnoseCmd := cmdNetworkDiagnose{global: c.global, network: c}
+	cmd.AddCommand(networkDiagnoseCmd.Command())
+
 	// Edit
 	networkEditCmd := cmdNetworkEdit{global: c.global, network: c}
 	cmd.AddCommand(networkEditCmd.Command())
 
+	// Forward
+	networkForwardCmd := cmdNetworkForward{global: c.global}
+	cmd.AddCommand(networkForwardCmd.Command())
+
 	// Get
 	networkGetCmd := cmdNetworkGet{global: c.global, network: c}
 	cmd.AddCommand(networkGetCmd.Command())
@@ -76,6 +84,14 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkListLeasesCmd := cmdNetworkListLeases{global: c.global, network: c}
 	cmd.AddCommand(networkListLeasesCmd.Command())
 
+	// Load balancer
+	networkLoadBalancerCmd := cmdNetworkLoadBalancer{global: c.global}
+	cmd.AddCommand(networkLoadBalancerCmd.Command())
+
+	// Reservation
+	networkReservationCmd := cmdNetworkReservation{global: c.global}
+	cmd.AddCommand(networkReservationCmd.Command())
+
 	// Rename
 	networkRenameCmd := cmdNetworkRename{global: c.global, network: c}
 	cmd.AddCommand(networkRenameCmd.Command())
@@ -108,6 +124,7 @@ func (c *cmdNetworkAttach) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Attach new network interfaces to instances`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks, c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -181,6 +198,7 @@ func (c *cmdNetworkAttachProfile) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Attach network interfaces to profiles`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -326,6 +344,7 @@ func (c *cmdNetworkDelete) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Delete networks`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -376,6 +395,7 @@ func (c *cmdNetworkDetach) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Detach network interfaces from instances`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks, c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -461,6 +481,7 @@ func (c *cmdNetworkDetachProfile) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Detach network interfaces from profiles`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -533,6 +554,77 @@ func (c *cmdNetworkDetachProfile) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Diagnose
+type cmdNetworkDiagnose struct {
+	global  *cmdGlobal
+	network *cmdNetwork
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdNetworkDiagnose) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("diagnose [<remote>:]<instance>")
+	cmd.Short = i18n.G("Check the networking of instances")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Check the networking of instances
+
+Runs checks on the server for each network interface of the instance:
+ - bridge: the host interface is connected to the parent bridge
+ - dhcp: the interface has a DHCP lease on the managed network
+ - firewall: the firewall rules of the managed network are present
+ - ovn: the OVN logical port is bound (not supported by this server)
+ - uplink: the network is up and the host has a default route`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkDiagnose) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	// Run the checks
+	diagnostics, err := resource.server.GetInstanceNetworkDiagnostics(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, diagnostic := range diagnostics {
+		data = append(data, []string{diagnostic.Device, diagnostic.Check, strings.ToUpper(diagnostic.Status), diagnostic.Message})
+	}
+
+	header := []string{
+		i18n.G("DEVICE"),
+		i18n.G("CHECK"),
+		i18n.G("STATUS"),
+		i18n.G("MESSAGE"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, diagnostics)
+}
+
 // Edit
 type cmdNetworkEdit struct {
 	global  *cmdGlobal
@@ -546,6 +638,7 @@ func (c *cmdNetworkEdit) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Edit network configurations as YAML`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -670,6 +763,7 @@ func (c *cmdNetworkGet) Command() *cobra.Command {
 		`Get values for network configuration keys`))
 
 	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -728,6 +822,7 @@ func (c *cmdNetworkInfo) Command() *cobra.Command {
 		`Get runtime information on networks`))
 
 	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -796,7 +891,8 @@ type cmdNetworkList struct {
 	global  *cmdGlobal
 	network *cmdNetwork
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdNetworkList) Command() *cobra.Command {
@@ -808,7 +904,8 @@ func (c *cmdNetworkList) Command() *cobra.Command {
 		`List available networks`))
 
 	cmd.RunE = c.Run
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	return cmd
 }
@@ -874,7 +971,7 @@ func (c *cmdNetworkList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("STATE"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, networks)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, networks)
 }
 
 // List leases
@@ -882,7 +979,8 @@ type cmdNetworkListLeases struct {
 	global  *cmdGlobal
 	network *cmdNetwork
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdNetworkListLeases) Command() *cobra.Command {
@@ -891,8 +989,10 @@ func (c *cmdNetworkListLeases) Command() *cobra.Command {
 	cmd.Short = i18n.G("List DHCP leases")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List DHCP leases`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -944,7 +1044,7 @@ func (c *cmdNetworkListLeases) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, leases)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, leases)
 }
 
 // Rename
@@ -961,6 +1061,7 @@ func (c *cmdNetworkRename) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Rename networks`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1015,6 +1116,7 @@ For backward compatibility, a single configuration key may still be set with:
     lxc network set [<remote>:]<network> <key> <value>`))
 
 	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1082,6 +1184,7 @@ func (c *cmdNetworkShow) Command() *cobra.Command {
 		`Show network configurations`))
 
 	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1144,6 +1247,7 @@ func (c *cmdNetworkUnset) Command() *cobra.Command {
 		`Unset network configuration keys`))
 
 	cmd.Flags().StringVar(&c.network.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpNetworks)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkForward struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkForward) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("forward")
+	cmd.Short = i18n.G("Manage network address forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network address forwards`))
+
+	// Create
+	networkForwardCreateCmd := cmdNetworkForwardCreate{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardCreateCmd.Command())
+
+	// Delete
+	networkForwardDeleteCmd := cmdNetworkForwardDelete{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardDeleteCmd.Command())
+
+	// Edit
+	networkForwardEditCmd := cmdNetworkForwardEdit{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardEditCmd.Command())
+
+	// List
+	networkForwardListCmd := cmdNetworkForwardList{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardListCmd.Command())
+
+	// Port
+	networkForwardPortCmd := cmdNetworkForwardPort{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardPortCmd.Command())
+
+	// Show
+	networkForwardShowCmd := cmdNetworkForwardShow{global: c.global, networkForward: c}
+	cmd.AddCommand(networkForwardShowCmd.Command())
+
+	return cmd
+}
+
+// List
+type cmdNetworkForwardList struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdNetworkForwardList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<network>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network forwards`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	forwards, err := resource.server.GetNetworkForwards(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, forward := range forwards {
+		data = append(data, []string{forward.ListenAddress, forward.Description, forward.Config["target_address"], fmt.Sprintf("%d", len(forward.Ports))})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("LISTEN ADDRESS"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("DEFAULT TARGET ADDRESS"),
+		i18n.G("PORTS"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, forwards)
+}
+
+// Show
+type cmdNetworkForwardShow struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Show network forward configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network forward configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	forward, _, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&forward)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Create
+type cmdNetworkForwardCreate struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<network> <listen address> [key=value...]")
+	cmd.Short = i18n.G("Create new network forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network forwards`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network forward create lxdbr0 192.0.2.10 target_address=10.62.42.5
+    Forward traffic for 192.0.2.10 to 10.62.42.5 by default, ports are added with "lxc network forward port add".`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	forward := api.NetworkForwardsPost{}
+	forward.ListenAddress = args[1]
+	forward.Config = map[string]string{}
+	forward.Ports = []api.NetworkForwardPort{}
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		forward.Config[entry[0]] = entry[1]
+	}
+
+	err = resource.server.CreateNetworkForward(resource.name, forward)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network forward %s created")+"\n", forward.ListenAddress)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkForwardEdit struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Edit network forward configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network forward configurations as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the network forward.
+### Any line starting with a '# will be ignored.
+###
+### A network forward consists of a default target address and optional set of port forwards.
+###
+### An example would look like:
+### listen_address: 192.0.2.10
+### description: web server
+### config:
+###   target_address: 10.62.42.5
+### ports:
+### - description: https
+###   protocol: tcp
+###   listen_port: "443"
+###   target_port: "8443"
+###   target_address: 10.62.42.6
+###
+### Note that the listen_address cannot be changed.`)
+}
+
+func (c *cmdNetworkForwardEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkForwardPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkForward(resource.name, args[1], newdata, "")
+	}
+
+	// Extract the current value
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&forward)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkForwardPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkForward(resource.name, args[1], newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkForwardDelete struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+}
+
+func (c *cmdNetworkForwardDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<network> <listen address>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network forwards")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network forwards`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network forward %s deleted")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Port
+type cmdNetworkForwardPort struct {
+	global         *cmdGlobal
+	networkForward *cmdNetworkForward
+
+	flagRemoveForce bool
+}
+
+func (c *cmdNetworkForwardPort) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("port")
+	cmd.Short = i18n.G("Manage network forward ports")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network forward ports`))
+
+	// Add
+	cmdAdd := &cobra.Command{}
+	cmdAdd.Use = i18n.G("add [<remote>:]<network> <listen address> <protocol> <listen port(s)> [<target address>] [<target port>]")
+	cmdAdd.Short = i18n.G("Add ports to a forward")
+	cmdAdd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add ports to a forward
+
+The listen port can be a single port or a range (e.g. 8000-8010), a target port can only be set for a single port.
+Without a target address, the forward's default target address is used.`))
+	cmdAdd.RunE = c.RunAdd
+	cmd.AddCommand(cmdAdd)
+
+	// Remove
+	cmdRemove := &cobra.Command{}
+	cmdRemove.Use = i18n.G("remove [<remote>:]<network> <listen address> [<protocol>] [<listen port(s)>]")
+	cmdRemove.Short = i18n.G("Remove ports from a forward")
+	cmdRemove.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove ports from a forward`))
+	cmdRemove.Flags().BoolVar(&c.flagRemoveForce, "force", false, i18n.G("Remove all ports that match"))
+	cmdRemove.RunE = c.RunRemove
+	cmd.AddCommand(cmdRemove)
+
+	return cmd
+}
+
+func (c *cmdNetworkForwardPort) RunAdd(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 6)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	port := api.NetworkForwardPort{
+		Protocol:   args[2],
+		ListenPort: args[3],
+	}
+
+	if len(args) > 4 {
+		port.TargetAddress = args[4]
+	}
+
+	if len(args) > 5 {
+		port.TargetPort = args[5]
+	}
+
+	forward.Ports = append(forward.Ports, port)
+
+	return resource.server.UpdateNetworkForward(resource.name, forward.ListenAddress, forward.Writable(), etag)
+}
+
+func (c *cmdNetworkForwardPort) RunRemove(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 4)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	forward, etag, err := resource.server.GetNetworkForward(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	matches := func(port api.NetworkForwardPort) bool {
+		if len(args) > 2 && port.Protocol != args[2] {
+			return false
+		}
+
+		if len(args) > 3 && port.ListenPort != args[3] {
+			return false
+		}
+
+		return true
+	}
+
+	ports := []api.NetworkForwardPort{}
+	removed := 0
+	for _, port := range forward.Ports {
+		if matches(port) {
+			removed++
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf(i18n.G("No matching port(s) found"))
+	}
+
+	if removed > 1 && !c.flagRemoveForce {
+		return fmt.Errorf(i18n.G("Multiple ports match. Use --force to remove them all"))
+	}
+
+	forward.Ports = ports
+
+	return resource.server.UpdateNetworkForward(resource.name, forward.ListenAddress, forward.Writable(), etag)
+}
@@ -0,0 +1,686 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdNetworkLoadBalancer struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkLoadBalancer) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("load-balancer")
+	cmd.Short = i18n.G("Manage network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancers`))
+
+	// Backend
+	networkLoadBalancerBackendCmd := cmdNetworkLoadBalancerBackend{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerBackendCmd.Command())
+
+	// Create
+	networkLoadBalancerCreateCmd := cmdNetworkLoadBalancerCreate{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerCreateCmd.Command())
+
+	// Delete
+	networkLoadBalancerDeleteCmd := cmdNetworkLoadBalancerDelete{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerDeleteCmd.Command())
+
+	// Edit
+	networkLoadBalancerEditCmd := cmdNetworkLoadBalancerEdit{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerEditCmd.Command())
+
+	// Info
+	networkLoadBalancerInfoCmd := cmdNetworkLoadBalancerInfo{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerInfoCmd.Command())
+
+	// List
+	networkLoadBalancerListCmd := cmdNetworkLoadBalancerList{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerListCmd.Command())
+
+	// Port
+	networkLoadBalancerPortCmd := cmdNetworkLoadBalancerPort{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerPortCmd.Command())
+
+	// Show
+	networkLoadBalancerShowCmd := cmdNetworkLoadBalancerShow{global: c.global, networkLoadBalancer: c}
+	cmd.AddCommand(networkLoadBalancerShowCmd.Command())
+
+	return cmd
+}
+
+// List
+type cmdNetworkLoadBalancerList struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdNetworkLoadBalancerList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<network>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List available network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List available network load balancers`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancers, err := resource.server.GetNetworkLoadBalancers(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, loadBalancer := range loadBalancers {
+		data = append(data, []string{loadBalancer.ListenAddress, loadBalancer.Description, fmt.Sprintf("%d", len(loadBalancer.Backends)), fmt.Sprintf("%d", len(loadBalancer.Ports))})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("LISTEN ADDRESS"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("BACKENDS"),
+		i18n.G("PORTS"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, loadBalancers)
+}
+
+// Show
+type cmdNetworkLoadBalancerShow struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Show network load balancer configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network load balancer configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer, _, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Info
+type cmdNetworkLoadBalancerInfo struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+
+	flagTarget string
+}
+
+func (c *cmdNetworkLoadBalancerInfo) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("info [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Get the health of the network load balancer backends")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Get the health of the network load balancer backends`))
+	cmd.Flags().StringVar(&c.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerInfo) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	client := resource.server
+	if c.flagTarget != "" {
+		client = client.UseTarget(c.flagTarget)
+	}
+
+	state, err := client.GetNetworkLoadBalancerState(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range state.BackendHealth {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println(i18n.G("Backend health:"))
+	for _, name := range names {
+		health := state.BackendHealth[name]
+		fmt.Printf("  %s (%s): %s\n", name, health.Address, health.Status)
+	}
+
+	return nil
+}
+
+// Create
+type cmdNetworkLoadBalancerCreate struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<network> <listen address> [key=value...]")
+	cmd.Short = i18n.G("Create new network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new network load balancers`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network load-balancer create lxdbr0 192.0.2.10 healthcheck=true
+    Create a load balancer for 192.0.2.10 checking the health of its backends, those and the ports are added with "lxc network load-balancer backend add" and "lxc network load-balancer port add".`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer := api.NetworkLoadBalancersPost{}
+	loadBalancer.ListenAddress = args[1]
+	loadBalancer.Config = map[string]string{}
+	loadBalancer.Backends = []api.NetworkLoadBalancerBackend{}
+	loadBalancer.Ports = []api.NetworkLoadBalancerPort{}
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		loadBalancer.Config[entry[0]] = entry[1]
+	}
+
+	err = resource.server.CreateNetworkLoadBalancer(resource.name, loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network load balancer %s created")+"\n", loadBalancer.ListenAddress)
+	}
+
+	return nil
+}
+
+// Edit
+type cmdNetworkLoadBalancerEdit struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<network> <listen address>")
+	cmd.Short = i18n.G("Edit network load balancer configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit network load balancer configurations as YAML`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a yaml representation of the network load balancer.
+### Any line starting with a '# will be ignored.
+###
+### A network load balancer consists of a set of target backends and port forwards to them.
+###
+### An example would look like:
+### listen_address: 192.0.2.10
+### description: web servers
+### config:
+###   healthcheck: "true"
+### backends:
+### - name: web1
+###   description: first web server
+###   target_address: 10.62.42.5
+###   target_port: "8443"
+### - name: web2
+###   description: second web server
+###   target_address: 10.62.42.6
+###   target_port: "8443"
+### ports:
+### - description: https
+###   protocol: tcp
+###   listen_port: "443"
+###   target_backend:
+###   - web1
+###   - web2
+###
+### Note that the listen_address cannot be changed.`)
+}
+
+func (c *cmdNetworkLoadBalancerEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.NetworkLoadBalancerPut{}
+		err = yaml.Unmarshal(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return resource.server.UpdateNetworkLoadBalancer(resource.name, args[1], newdata, "")
+	}
+
+	// Extract the current value
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor
+		newdata := api.NetworkLoadBalancerPut{}
+		err = yaml.Unmarshal(content, &newdata)
+		if err == nil {
+			err = resource.server.UpdateNetworkLoadBalancer(resource.name, args[1], newdata, etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Delete
+type cmdNetworkLoadBalancerDelete struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<network> <listen address>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete network load balancers")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete network load balancers`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network load balancer %s deleted")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Backend
+type cmdNetworkLoadBalancerBackend struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+}
+
+func (c *cmdNetworkLoadBalancerBackend) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("backend")
+	cmd.Short = i18n.G("Manage network load balancer backends")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancer backends`))
+
+	// Add
+	cmdAdd := &cobra.Command{}
+	cmdAdd.Use = i18n.G("add [<remote>:]<network> <listen address> <backend name> <target address> [<target port>]")
+	cmdAdd.Short = i18n.G("Add backends to a load balancer")
+	cmdAdd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add backends to a load balancer
+
+Without a target port, the connections keep their listen port.`))
+	cmdAdd.RunE = c.RunAdd
+	cmd.AddCommand(cmdAdd)
+
+	// Remove
+	cmdRemove := &cobra.Command{}
+	cmdRemove.Use = i18n.G("remove [<remote>:]<network> <listen address> <backend name>")
+	cmdRemove.Short = i18n.G("Remove backends from a load balancer")
+	cmdRemove.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove backends from a load balancer
+
+The backend is also removed from the target backends of the ports.`))
+	cmdRemove.RunE = c.RunRemove
+	cmd.AddCommand(cmdRemove)
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerBackend) RunAdd(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 4, 5)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	backend := api.NetworkLoadBalancerBackend{
+		Name:          args[2],
+		TargetAddress: args[3],
+	}
+
+	if len(args) > 4 {
+		backend.TargetPort = args[4]
+	}
+
+	loadBalancer.Backends = append(loadBalancer.Backends, backend)
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+func (c *cmdNetworkLoadBalancerBackend) RunRemove(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	backends := []api.NetworkLoadBalancerBackend{}
+	for _, backend := range loadBalancer.Backends {
+		if backend.Name != args[2] {
+			backends = append(backends, backend)
+		}
+	}
+
+	if len(backends) == len(loadBalancer.Backends) {
+		return fmt.Errorf(i18n.G("No matching backend found"))
+	}
+
+	loadBalancer.Backends = backends
+
+	for i, port := range loadBalancer.Ports {
+		targets := []string{}
+		for _, name := range port.TargetBackend {
+			if name != args[2] {
+				targets = append(targets, name)
+			}
+		}
+
+		loadBalancer.Ports[i].TargetBackend = targets
+	}
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+// Port
+type cmdNetworkLoadBalancerPort struct {
+	global              *cmdGlobal
+	networkLoadBalancer *cmdNetworkLoadBalancer
+
+	flagRemoveForce bool
+}
+
+func (c *cmdNetworkLoadBalancerPort) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("port")
+	cmd.Short = i18n.G("Manage network load balancer ports")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network load balancer ports`))
+
+	// Add
+	cmdAdd := &cobra.Command{}
+	cmdAdd.Use = i18n.G("add [<remote>:]<network> <listen address> <protocol> <listen port(s)> <backend name>[,<backend name>...]")
+	cmdAdd.Short = i18n.G("Add ports to a load balancer")
+	cmdAdd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add ports to a load balancer
+
+The listen port can be a single port or a range (e.g. 8000-8010), the latter only targeting backends without a target port.`))
+	cmdAdd.RunE = c.RunAdd
+	cmd.AddCommand(cmdAdd)
+
+	// Remove
+	cmdRemove := &cobra.Command{}
+	cmdRemove.Use = i18n.G("remove [<remote>:]<network> <listen address> [<protocol>] [<listen port(s)>]")
+	cmdRemove.Short = i18n.G("Remove ports from a load balancer")
+	cmdRemove.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove ports from a load balancer`))
+	cmdRemove.Flags().BoolVar(&c.flagRemoveForce, "force", false, i18n.G("Remove all ports that match"))
+	cmdRemove.RunE = c.RunRemove
+	cmd.AddCommand(cmdRemove)
+
+	return cmd
+}
+
+func (c *cmdNetworkLoadBalancerPort) RunAdd(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 5, 5)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	port := api.NetworkLoadBalancerPort{
+		Protocol:      args[2],
+		ListenPort:    args[3],
+		TargetBackend: strings.Split(args[4], ","),
+	}
+
+	loadBalancer.Ports = append(loadBalancer.Ports, port)
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
+
+func (c *cmdNetworkLoadBalancerPort) RunRemove(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 4)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	loadBalancer, etag, err := resource.server.GetNetworkLoadBalancer(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	matches := func(port api.NetworkLoadBalancerPort) bool {
+		if len(args) > 2 && port.Protocol != args[2] {
+			return false
+		}
+
+		if len(args) > 3 && port.ListenPort != args[3] {
+			return false
+		}
+
+		return true
+	}
+
+	ports := []api.NetworkLoadBalancerPort{}
+	removed := 0
+	for _, port := range loadBalancer.Ports {
+		if matches(port) {
+			removed++
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf(i18n.G("No matching port(s) found"))
+	}
+
+	if removed > 1 && !c.flagRemoveForce {
+		return fmt.Errorf(i18n.G("Multiple ports match. Use --force to remove them all"))
+	}
+
+	loadBalancer.Ports = ports
+
+	return resource.server.UpdateNetworkLoadBalancer(resource.name, loadBalancer.ListenAddress, loadBalancer.Writable(), etag)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdNetworkReservation struct {
+	global *cmdGlobal
+}
+
+func (c *cmdNetworkReservation) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("reservation")
+	cmd.Short = i18n.G("Manage network DHCP reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage network DHCP reservations`))
+
+	// Add
+	networkReservationAddCmd := cmdNetworkReservationAdd{global: c.global, networkReservation: c}
+	cmd.AddCommand(networkReservationAddCmd.Command())
+
+	// List
+	networkReservationListCmd := cmdNetworkReservationList{global: c.global, networkReservation: c}
+	cmd.AddCommand(networkReservationListCmd.Command())
+
+	// Remove
+	networkReservationRemoveCmd := cmdNetworkReservationRemove{global: c.global, networkReservation: c}
+	cmd.AddCommand(networkReservationRemoveCmd.Command())
+
+	// Show
+	networkReservationShowCmd := cmdNetworkReservationShow{global: c.global, networkReservation: c}
+	cmd.AddCommand(networkReservationShowCmd.Command())
+
+	return cmd
+}
+
+// List
+type cmdNetworkReservationList struct {
+	global             *cmdGlobal
+	networkReservation *cmdNetworkReservation
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdNetworkReservationList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<network>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List network DHCP reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List network DHCP reservations`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkReservationList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	reservations, err := resource.server.GetNetworkReservations(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, reservation := range reservations {
+		data = append(data, []string{reservation.Hwaddr, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname, reservation.Description})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("MAC ADDRESS"),
+		i18n.G("IPV4"),
+		i18n.G("IPV6"),
+		i18n.G("HOSTNAME"),
+		i18n.G("DESCRIPTION"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, reservations)
+}
+
+// Show
+type cmdNetworkReservationShow struct {
+	global             *cmdGlobal
+	networkReservation *cmdNetworkReservation
+}
+
+func (c *cmdNetworkReservationShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<network> <MAC address>")
+	cmd.Short = i18n.G("Show network DHCP reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show network DHCP reservations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkReservationShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	reservation, _, err := resource.server.GetNetworkReservation(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&reservation)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Add
+type cmdNetworkReservationAdd struct {
+	global             *cmdGlobal
+	networkReservation *cmdNetworkReservation
+}
+
+func (c *cmdNetworkReservationAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [<remote>:]<network> <MAC address> [key=value...]")
+	cmd.Short = i18n.G("Add network DHCP reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Add network DHCP reservations
+
+Supported keys are ipv4_address, ipv6_address, hostname and description.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc network reservation add lxdbr0 00:16:3e:12:34:56 ipv4_address=10.62.42.50 hostname=printer
+    Always hand out 10.62.42.50 to the device with MAC address 00:16:3e:12:34:56.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkReservationAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	reservation := api.NetworkReservationsPost{}
+	reservation.Hwaddr = args[1]
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		switch entry[0] {
+		case "ipv4_address":
+			reservation.IPv4Address = entry[1]
+		case "ipv6_address":
+			reservation.IPv6Address = entry[1]
+		case "hostname":
+			reservation.Hostname = entry[1]
+		case "description":
+			reservation.Description = entry[1]
+		default:
+			return fmt.Errorf(i18n.G("Invalid key: %s"), entry[0])
+		}
+	}
+
+	err = resource.server.CreateNetworkReservation(resource.name, reservation)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network DHCP reservation for %s added")+"\n", reservation.Hwaddr)
+	}
+
+	return nil
+}
+
+// Remove
+type cmdNetworkReservationRemove struct {
+	global             *cmdGlobal
+	networkReservation *cmdNetworkReservation
+}
+
+func (c *cmdNetworkReservationRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("remove [<remote>:]<network> <MAC address>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Remove network DHCP reservations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Remove network DHCP reservations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdNetworkReservationRemove) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := parseNetwork(c.global, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteNetworkReservation(resource.name, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Network DHCP reservation for %s removed")+"\n", args[1])
+	}
+
+	return nil
+}
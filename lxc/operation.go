@@ -8,7 +8,9 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
 )
@@ -91,7 +93,11 @@ type cmdOperationList struct {
 	global    *cmdGlobal
 	operation *cmdOperation
 
-	flagFormat string
+	flagFormat      string
+	flagColumns     string
+	flagAllProjects bool
+	flagClass       string
+	flagStatus      string
 }
 
 func (c *cmdOperationList) Command() *cobra.Command {
@@ -101,7 +107,11 @@ func (c *cmdOperationList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List background operations")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List background operations`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("List operations from all projects"))
+	cmd.Flags().StringVar(&c.flagClass, "class", "", i18n.G("Only list operations of this class (task, websocket or token)")+"``")
+	cmd.Flags().StringVar(&c.flagStatus, "status", "", i18n.G("Only list operations with this status")+"``")
 
 	cmd.RunE = c.Run
 
@@ -132,7 +142,16 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get operations
-	operations, err := resource.server.GetOperations()
+	var operations []api.Operation
+	if c.flagAllProjects || c.flagClass != "" || c.flagStatus != "" {
+		operations, err = resource.server.GetOperationsWithArgs(&lxd.OperationsArgs{
+			AllProjects: c.flagAllProjects,
+			Class:       c.flagClass,
+			Status:      c.flagStatus,
+		})
+	} else {
+		operations, err = resource.server.GetOperations()
+	}
 	if err != nil {
 		return err
 	}
@@ -165,7 +184,7 @@ func (c *cmdOperationList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, operations)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, operations)
 }
 
 // Show
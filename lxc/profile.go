@@ -564,9 +564,10 @@ func (c *cmdProfileGet) Run(cmd *cobra.Command, args []string) error {
 
 // List
 type cmdProfileList struct {
-	global     *cmdGlobal
-	profile    *cmdProfile
-	flagFormat string
+	global      *cmdGlobal
+	profile     *cmdProfile
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdProfileList) Command() *cobra.Command {
@@ -578,7 +579,8 @@ func (c *cmdProfileList) Command() *cobra.Command {
 		`List profiles`))
 
 	cmd.RunE = c.Run
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	return cmd
 }
@@ -620,7 +622,7 @@ func (c *cmdProfileList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("NAME"),
 		i18n.G("USED BY")}
 
-	return utils.RenderTable(c.flagFormat, header, data, profiles)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, profiles)
 }
 
 // Remove
@@ -74,9 +74,10 @@ func (c *cmdProject) Command() *cobra.Command {
 
 // Create
 type cmdProjectCreate struct {
-	global     *cmdGlobal
-	project    *cmdProject
-	flagConfig []string
+	global       *cmdGlobal
+	project      *cmdProject
+	flagConfig   []string
+	flagTemplate string
 }
 
 func (c *cmdProjectCreate) Command() *cobra.Command {
@@ -86,6 +87,7 @@ func (c *cmdProjectCreate) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Create projects`))
 	cmd.Flags().StringArrayVarP(&c.flagConfig, "config", "c", nil, i18n.G("Config key/value to apply to the new project")+"``")
+	cmd.Flags().StringVar(&c.flagTemplate, "template", "", i18n.G("Project to copy the configuration and profiles from")+"``")
 
 	cmd.RunE = c.Run
 
@@ -114,6 +116,7 @@ func (c *cmdProjectCreate) Run(cmd *cobra.Command, args []string) error {
 	// Create the project
 	project := api.ProjectsPost{}
 	project.Name = resource.name
+	project.Template = c.flagTemplate
 
 	project.Config = map[string]string{}
 	for _, entry := range c.flagConfig {
@@ -373,7 +376,8 @@ type cmdProjectList struct {
 	global  *cmdGlobal
 	project *cmdProject
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdProjectList) Command() *cobra.Command {
@@ -383,7 +387,8 @@ func (c *cmdProjectList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List projects")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List projects`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -452,7 +457,7 @@ func (c *cmdProjectList) Run(cmd *cobra.Command, args []string) error {
 		i18n.G("USED BY"),
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, projects)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, projects)
 }
 
 // Rename
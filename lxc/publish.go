@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
@@ -22,6 +23,7 @@ type cmdPublish struct {
 	flagCompressionAlgorithm string
 	flagMakePublic           bool
 	flagForce                bool
+	flagSign                 string
 }
 
 func (c *cmdPublish) showByDefault() bool {
@@ -35,11 +37,13 @@ func (c *cmdPublish) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Publish instances as images`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagMakePublic, "public", false, i18n.G("Make the image public"))
 	cmd.Flags().StringArrayVar(&c.flagAliases, "alias", nil, i18n.G("New alias to define at target")+"``")
 	cmd.Flags().BoolVarP(&c.flagForce, "force", "f", false, i18n.G("Stop the instance if currently running"))
 	cmd.Flags().StringVar(&c.flagCompressionAlgorithm, "compression", "", i18n.G("Define a compression algorithm: for image or none")+"``")
+	cmd.Flags().StringVar(&c.flagSign, "sign", "", i18n.G("Sign the image with the given GPG key")+"``")
 
 	return cmd
 }
@@ -242,6 +246,14 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 	// Grab the fingerprint
 	fingerprint := opAPI.Metadata["fingerprint"].(string)
 
+	// Sign the image before it gets copied
+	if c.flagSign != "" {
+		err = c.sign(s, fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
 	// For remote publish, copy to target now
 	if cRemote != iRemote {
 		defer s.DeleteImage(fingerprint)
@@ -277,3 +289,27 @@ func (c *cmdPublish) Run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// sign sets the signature property of an image to the detached signature of its fingerprint made
+// with the GPG key.
+func (c *cmdPublish) sign(s lxd.InstanceServer, fingerprint string) error {
+	var signature bytes.Buffer
+	err := shared.RunCommandWithFds(strings.NewReader(fingerprint), &signature, "gpg", "--batch", "--armor", "--detach-sign", "--local-user", c.flagSign)
+	if err != nil {
+		return fmt.Errorf(i18n.G("Failed to sign the image with key %q: %v"), c.flagSign, err)
+	}
+
+	image, etag, err := s.GetImage(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	put := image.Writable()
+	if put.Properties == nil {
+		put.Properties = map[string]string{}
+	}
+
+	put.Properties["signature"] = signature.String()
+
+	return s.UpdateImage(fingerprint, put, etag)
+}
@@ -20,6 +20,7 @@ func (c *cmdRename) Command() *cobra.Command {
 	cmd.Short = i18n.G("Rename instances and snapshots")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Rename instances and snapshots`))
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 
 	return cmd
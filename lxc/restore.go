@@ -32,6 +32,7 @@ If --stateful is passed, then the running state will be restored too.`))
 lxc restore u1 snap0
     Restore the snapshot.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Whether or not to restore the instance's running state from snapshot (if available)"))
 
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 
+	"github.com/lxc/lxd/lxc/utils"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
-
-	"time"
+	"github.com/lxc/lxd/shared/units"
 )
 
 type cmdSnapshot struct {
@@ -15,6 +18,7 @@ type cmdSnapshot struct {
 
 	flagStateful bool
 	flagNoExpiry bool
+	flagVolumes  bool
 }
 
 func (c *cmdSnapshot) Command() *cobra.Command {
@@ -25,14 +29,23 @@ func (c *cmdSnapshot) Command() *cobra.Command {
 		`Create instance snapshots
 
 When --stateful is used, LXD attempts to checkpoint the instance's
-running state, including process memory state, TCP connections, ...`))
+running state, including process memory state, TCP connections, ...
+
+When --volumes is used, the custom volumes attached to the instance are
+snapshotted too, under the same name, and restored along with it.`))
 	cmd.Example = cli.FormatSection("", i18n.G(
 		`lxc snapshot u1 snap0
     Create a snapshot of "u1" called "snap0".`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
 	cmd.RunE = c.Run
 	cmd.Flags().BoolVar(&c.flagStateful, "stateful", false, i18n.G("Whether or not to snapshot the instance's running state"))
 	cmd.Flags().BoolVar(&c.flagNoExpiry, "no-expiry", false, i18n.G("Ignore any configured auto-expiry for the instance"))
+	cmd.Flags().BoolVar(&c.flagVolumes, "volumes", false, i18n.G("Also snapshot the custom volumes attached to the instance, at the same point in time"))
+
+	// Diff
+	snapshotDiffCmd := cmdSnapshotDiff{global: c.global}
+	cmd.AddCommand(snapshotDiffCmd.Command())
 
 	return cmd
 }
@@ -66,6 +79,7 @@ func (c *cmdSnapshot) Run(cmd *cobra.Command, args []string) error {
 	req := api.InstanceSnapshotsPost{
 		Name:     snapname,
 		Stateful: c.flagStateful,
+		Volumes:  c.flagVolumes,
 	}
 
 	if c.flagNoExpiry {
@@ -79,3 +93,82 @@ func (c *cmdSnapshot) Run(cmd *cobra.Command, args []string) error {
 
 	return op.Wait()
 }
+
+// Diff
+type cmdSnapshotDiff struct {
+	global *cmdGlobal
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdSnapshotDiff) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("diff [<remote>:]<instance> <snapshot> [<snapshot>]")
+	cmd.Short = i18n.G("Show the files changed since an instance snapshot")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the files changed since an instance snapshot
+
+Lists the files of the root filesystem added, modified or deleted since the
+snapshot, up to a more recent snapshot or to the current state of the instance.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc snapshot diff u1 snap0
+    Show the files changed in "u1" since its "snap0" snapshot.
+
+lxc snapshot diff u1 snap0 snap1
+    Show the files changed between the "snap0" and "snap1" snapshots of "u1".`))
+
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpInstances)
+	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	return cmd
+}
+
+func (c *cmdSnapshotDiff) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 3)
+	if exit {
+		return err
+	}
+
+	remote, name, err := conf.ParseRemote(args[0])
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf(i18n.G("Missing instance name"))
+	}
+
+	d, err := conf.GetInstanceServer(remote)
+	if err != nil {
+		return err
+	}
+
+	to := ""
+	if len(args) > 2 {
+		to = args[2]
+	}
+
+	entries, err := d.GetInstanceSnapshotDiff(name, args[1], to)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, entry := range entries {
+		data = append(data, []string{entry.Type, entry.Path, units.GetByteSizeString(entry.Size, 2)})
+	}
+
+	header := []string{
+		i18n.G("TYPE"),
+		i18n.G("PATH"),
+		i18n.G("SIZE"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, entries)
+}
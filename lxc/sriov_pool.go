@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdSRIOVPool struct {
+	global *cmdGlobal
+}
+
+func (c *cmdSRIOVPool) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("sriov-pool")
+	cmd.Short = i18n.G("Manage SR-IOV pools")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage SR-IOV pools`))
+
+	// Create
+	sriovPoolCreateCmd := cmdSRIOVPoolCreate{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolCreateCmd.Command())
+
+	// Delete
+	sriovPoolDeleteCmd := cmdSRIOVPoolDelete{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolDeleteCmd.Command())
+
+	// Get
+	sriovPoolGetCmd := cmdSRIOVPoolGet{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolGetCmd.Command())
+
+	// List
+	sriovPoolListCmd := cmdSRIOVPoolList{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolListCmd.Command())
+
+	// Set
+	sriovPoolSetCmd := cmdSRIOVPoolSet{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolSetCmd.Command())
+
+	// Show
+	sriovPoolShowCmd := cmdSRIOVPoolShow{global: c.global, sriovPool: c}
+	cmd.AddCommand(sriovPoolShowCmd.Command())
+
+	// Unset
+	sriovPoolUnsetCmd := cmdSRIOVPoolUnset{global: c.global, sriovPool: c, sriovPoolSet: &sriovPoolSetCmd}
+	cmd.AddCommand(sriovPoolUnsetCmd.Command())
+
+	return cmd
+}
+
+// parsePool parses the pool argument and checks a pool name was provided.
+func (c *cmdSRIOVPool) parsePool(arg string) (remoteResource, error) {
+	resources, err := c.global.ParseServers(arg)
+	if err != nil {
+		return remoteResource{}, err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return remoteResource{}, fmt.Errorf(i18n.G("Missing SR-IOV pool name"))
+	}
+
+	return resource, nil
+}
+
+// Create
+type cmdSRIOVPoolCreate struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+}
+
+func (c *cmdSRIOVPoolCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<pool> [key=value...]")
+	cmd.Short = i18n.G("Create SR-IOV pools")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create SR-IOV pools`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc sriov-pool create fast parent=enp5s0f0,enp5s0f1 vf_count=16 vlan=100
+    Create a pool allocating VFs from two physical functions, tagged on VLAN 100.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, -1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.sriovPool.parsePool(args[0])
+	if err != nil {
+		return err
+	}
+
+	pool := api.SRIOVPoolsPost{}
+	pool.Name = resource.name
+	pool.Config = map[string]string{}
+
+	for i := 1; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key/value pair: %s"), args[i])
+		}
+
+		pool.Config[entry[0]] = entry[1]
+	}
+
+	err = resource.server.CreateSRIOVPool(pool)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("SR-IOV pool %s created")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Delete
+type cmdSRIOVPoolDelete struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+}
+
+func (c *cmdSRIOVPoolDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<pool>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete SR-IOV pools")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete SR-IOV pools`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.sriovPool.parsePool(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = resource.server.DeleteSRIOVPool(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("SR-IOV pool %s deleted")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Get
+type cmdSRIOVPoolGet struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+}
+
+func (c *cmdSRIOVPoolGet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("get [<remote>:]<pool> <key>")
+	cmd.Short = i18n.G("Get values for SR-IOV pool configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Get values for SR-IOV pool configuration keys`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolGet) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	resource, err := c.sriovPool.parsePool(args[0])
+	if err != nil {
+		return err
+	}
+
+	pool, _, err := resource.server.GetSRIOVPool(resource.name)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range pool.Config {
+		if k == args[1] {
+			fmt.Printf("%s\n", v)
+		}
+	}
+
+	return nil
+}
+
+// List
+type cmdSRIOVPoolList struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+
+	flagFormat  string
+	flagColumns string
+}
+
+func (c *cmdSRIOVPoolList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List SR-IOV pools")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List SR-IOV pools`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	pools, err := resources[0].server.GetSRIOVPools()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, pool := range pools {
+		data = append(data, []string{pool.Name, pool.Config["parent"], pool.Config["vf_count"], pool.Description, fmt.Sprintf("%d", len(pool.UsedBy))})
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("PARENT"),
+		i18n.G("VF COUNT"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("USED BY"),
+	}
+
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, pools)
+}
+
+// Set
+type cmdSRIOVPoolSet struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+}
+
+func (c *cmdSRIOVPoolSet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("set [<remote>:]<pool> <key> <value>")
+	cmd.Short = i18n.G("Set SR-IOV pool configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Set SR-IOV pool configuration keys`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolSet) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	resource, err := c.sriovPool.parsePool(args[0])
+	if err != nil {
+		return err
+	}
+
+	pool, etag, err := resource.server.GetSRIOVPool(resource.name)
+	if err != nil {
+		return err
+	}
+
+	if pool.Config == nil {
+		pool.Config = map[string]string{}
+	}
+
+	pool.Config[args[1]] = args[2]
+
+	return resource.server.UpdateSRIOVPool(resource.name, pool.Writable(), etag)
+}
+
+// Show
+type cmdSRIOVPoolShow struct {
+	global    *cmdGlobal
+	sriovPool *cmdSRIOVPool
+}
+
+func (c *cmdSRIOVPoolShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<pool>")
+	cmd.Short = i18n.G("Show SR-IOV pool configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show SR-IOV pool configurations`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	resource, err := c.sriovPool.parsePool(args[0])
+	if err != nil {
+		return err
+	}
+
+	pool, _, err := resource.server.GetSRIOVPool(resource.name)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(pool.UsedBy)
+
+	data, err := yaml.Marshal(&pool)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Unset
+type cmdSRIOVPoolUnset struct {
+	global       *cmdGlobal
+	sriovPool    *cmdSRIOVPool
+	sriovPoolSet *cmdSRIOVPoolSet
+}
+
+func (c *cmdSRIOVPoolUnset) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("unset [<remote>:]<pool> <key>")
+	cmd.Short = i18n.G("Unset SR-IOV pool configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Unset SR-IOV pool configuration keys`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdSRIOVPoolUnset) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	args = append(args, "")
+	return c.sriovPoolSet.Run(cmd, args)
+}
@@ -163,6 +163,7 @@ func (c *cmdStorageDelete) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Delete storage pools`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -216,6 +217,7 @@ func (c *cmdStorageEdit) Command() *cobra.Command {
 		`lxc storage edit [<remote>:]<pool> < pool.yaml
     Update a storage pool using the content of pool.yaml.`))
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -334,6 +336,7 @@ func (c *cmdStorageGet) Command() *cobra.Command {
 		`Get values for storage pool configuration keys`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -390,6 +393,7 @@ func (c *cmdStorageInfo) Command() *cobra.Command {
 
 	cmd.Flags().BoolVar(&c.flagBytes, "bytes", false, i18n.G("Show the used and free space in bytes"))
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -497,7 +501,8 @@ type cmdStorageList struct {
 	global  *cmdGlobal
 	storage *cmdStorage
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdStorageList) Command() *cobra.Command {
@@ -507,7 +512,8 @@ func (c *cmdStorageList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List available storage pools")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List available storage pools`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
 	cmd.RunE = c.Run
 
@@ -566,7 +572,7 @@ func (c *cmdStorageList) Run(cmd *cobra.Command, args []string) error {
 	}
 	header = append(header, i18n.G("USED BY"))
 
-	return utils.RenderTable(c.flagFormat, header, data, pools)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, pools)
 }
 
 // Set
@@ -586,6 +592,7 @@ For backward compatibility, a single configuration key may still be set with:
     lxc storage set [<remote>:]<pool> <key> <value>`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -611,6 +618,15 @@ func (c *cmdStorageSet) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf(i18n.G("Missing pool name"))
 	}
 
+	// Targeting
+	if c.storage.flagTarget != "" {
+		if !resource.server.IsClustered() {
+			return fmt.Errorf(i18n.G("To use --target, the destination remote must be a cluster"))
+		}
+
+		resource.server = resource.server.UseTarget(c.storage.flagTarget)
+	}
+
 	// Get the pool entry
 	pool, etag, err := resource.server.GetStoragePool(resource.name)
 	if err != nil {
@@ -653,6 +669,7 @@ func (c *cmdStorageShow) Command() *cobra.Command {
 
 	cmd.Flags().BoolVar(&c.flagResources, "resources", false, i18n.G("Show the resources available to the storage pool"))
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -736,6 +753,7 @@ func (c *cmdStorageUnset) Command() *cobra.Command {
 		`Unset storage pool configuration keys`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -139,6 +139,7 @@ func (c *cmdStorageVolumeAttach) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Attach new storage volumes to instances`))
 
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -387,11 +388,22 @@ func (c *cmdStorageVolumeCopy) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if cmd.Name() == "move" && c.storage.flagTarget != "" {
+		if srcServer != dstServer || srcVolPool != dstVolPool {
+			return fmt.Errorf(i18n.G("Volumes can only be moved to another cluster member within the same remote and pool"))
+		}
+
+		if isSnapshot {
+			return fmt.Errorf(i18n.G("Storage volume snapshots can't be moved to another cluster member"))
+		}
+	}
+
 	if cmd.Name() == "move" && srcServer == dstServer {
 		args := &lxd.StoragePoolVolumeMoveArgs{}
 		args.Name = dstVolName
 		args.Mode = mode
 		args.VolumeOnly = false
+		args.TargetMember = c.storage.flagTarget
 
 		if isSnapshot {
 			srcVol.Name = srcVolName
@@ -540,6 +552,7 @@ func (c *cmdStorageVolumeDelete) Command() *cobra.Command {
 		`Delete storage volumes`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -615,6 +628,7 @@ func (c *cmdStorageVolumeDetach) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Detach storage volumes from instances`))
 
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -782,6 +796,7 @@ func (c *cmdStorageVolumeEdit) Command() *cobra.Command {
     Update a storage volume using the content of pool.yaml.`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -979,6 +994,7 @@ func (c *cmdStorageVolumeGet) Command() *cobra.Command {
 		`Get values for storage volume configuration keys`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1058,7 +1074,8 @@ type cmdStorageVolumeList struct {
 	storage       *cmdStorage
 	storageVolume *cmdStorageVolume
 
-	flagFormat string
+	flagFormat  string
+	flagColumns string
 }
 
 func (c *cmdStorageVolumeList) Command() *cobra.Command {
@@ -1068,8 +1085,10 @@ func (c *cmdStorageVolumeList) Command() *cobra.Command {
 	cmd.Short = i18n.G("List storage volumes")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`List storage volumes`))
-	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml|compact)")+"``")
+	cmd.Flags().StringVarP(&c.flagColumns, "columns", "c", "", i18n.G("Columns to show (comma-separated column names)")+"``")
 
+	cmd.ValidArgsFunction = cmpArgs(c.global.cmpStoragePools)
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1123,7 +1142,7 @@ func (c *cmdStorageVolumeList) Run(cmd *cobra.Command, args []string) error {
 		header = append(header, i18n.G("LOCATION"))
 	}
 
-	return utils.RenderTable(c.flagFormat, header, data, volumes)
+	return utils.RenderTableColumns(c.flagFormat, c.flagColumns, header, data, volumes)
 }
 
 // Move
@@ -1140,10 +1159,15 @@ func (c *cmdStorageVolumeMove) Command() *cobra.Command {
 	cmd.Aliases = []string{"mv"}
 	cmd.Short = i18n.G("Move storage volumes between pools")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Move storage volumes between pools`))
+		`Move storage volumes between pools
+
+Volumes of local storage pools can be moved to another cluster member with --target.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage volume move default/data default/data --target node2
+    Move the "data" custom volume of the "default" pool to cluster member "node2".`))
 
 	cmd.Flags().StringVar(&c.storageVolumeCopy.flagMode, "mode", "pull", i18n.G("Transfer mode, one of pull (default), push or relay")+"``")
-	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member to move the volume to")+"``")
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1174,6 +1198,7 @@ func (c *cmdStorageVolumeRename) Command() *cobra.Command {
 		`Rename storage volumes`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1290,6 +1315,7 @@ For backward compatibility, a single configuration key may still be set with:
     lxc storage volume set [<remote>:]<pool> <volume> <key> <value>`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1369,6 +1395,7 @@ lxc storage volume show default container/data
     Will show the properties of the filesystem for a container called "data" in the "default" pool.`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1462,6 +1489,7 @@ func (c *cmdStorageVolumeUnset) Command() *cobra.Command {
 		`Unset storage volume configuration keys`))
 
 	cmd.Flags().StringVar(&c.storage.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1492,6 +1520,7 @@ func (c *cmdStorageVolumeSnapshot) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Snapshot storage volumes`))
 
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -1563,6 +1592,7 @@ func (c *cmdStorageVolumeRestore) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Restore storage volume snapshots`))
 
+	cmd.ValidArgsFunction = c.global.cmpStoragePoolVolumes
 	cmd.RunE = c.Run
 
 	return cmd
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/units"
+)
+
+type cmdTop struct {
+	global *cmdGlobal
+
+	flagAllProjects bool
+	flagRefresh     int
+	flagSort        string
+}
+
+// topEntry is the usage of an instance in a sample of the metrics.
+type topEntry struct {
+	project  string
+	name     string
+	location string
+	running  bool
+
+	cpu     float64
+	memory  int64
+	disk    int64
+	network int64
+
+	// Rates since the previous sample, negative if unknown.
+	cpuRate     float64
+	networkRate float64
+}
+
+func (c *cmdTop) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("top [<remote>:]")
+	cmd.Short = i18n.G("Monitor the resource usage of instances")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Monitor the resource usage of instances
+
+Shows the CPU, memory, disk and network usage of the running instances of the
+project (or all projects), across all cluster members, refreshed periodically.
+
+Instances can be sorted by name, cpu, memory, disk or network.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc top --sort=memory
+    Show the instances of the current project, using the most memory first.
+
+lxc top --all-projects --refresh=10
+    Show the instances of all projects, refreshed every 10 seconds.`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().BoolVar(&c.flagAllProjects, "all-projects", false, i18n.G("Show instances from all projects"))
+	cmd.Flags().IntVar(&c.flagRefresh, "refresh", 5, i18n.G("Refresh interval in seconds")+"``")
+	cmd.Flags().StringVar(&c.flagSort, "sort", "cpu", i18n.G("Sort instances by name, cpu, memory, disk or network")+"``")
+
+	return cmd
+}
+
+func (c *cmdTop) Run(cmd *cobra.Command, args []string) error {
+	conf := c.global.conf
+
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	if c.flagRefresh < 1 {
+		return fmt.Errorf(i18n.G("The refresh interval must be at least one second"))
+	}
+
+	if !shared.StringInSlice(c.flagSort, []string{"name", "cpu", "memory", "disk", "network"}) {
+		return fmt.Errorf(i18n.G("Invalid sort order %q"), c.flagSort)
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	remoteName, _, err := conf.ParseRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	d, err := conf.GetInstanceServer(remoteName)
+	if err != nil {
+		return err
+	}
+
+	// Figure out the project to show
+	project := conf.Remotes[remoteName].Project
+	if conf.ProjectOverride != "" {
+		project = conf.ProjectOverride
+	}
+
+	if project == "" {
+		project = "default"
+	}
+
+	var previous map[string]*topEntry
+	var previousTime time.Time
+
+	for {
+		now := time.Now()
+		entries, err := c.sample(d)
+		if err != nil {
+			return err
+		}
+
+		// Compute the rates since the previous sample
+		elapsed := now.Sub(previousTime).Seconds()
+		for key, entry := range entries {
+			entry.cpuRate = -1
+			entry.networkRate = -1
+
+			prev, ok := previous[key]
+			if !ok || elapsed <= 0 {
+				continue
+			}
+
+			if entry.cpu >= prev.cpu {
+				entry.cpuRate = (entry.cpu - prev.cpu) / elapsed
+			}
+
+			if entry.network >= prev.network {
+				entry.networkRate = float64(entry.network-prev.network) / elapsed
+			}
+		}
+
+		rows := []*topEntry{}
+		for _, entry := range entries {
+			if !c.flagAllProjects && entry.project != project {
+				continue
+			}
+
+			rows = append(rows, entry)
+		}
+
+		c.sort(rows)
+
+		err = c.render(d, rows, now)
+		if err != nil {
+			return err
+		}
+
+		previous = entries
+		previousTime = now
+
+		time.Sleep(time.Duration(c.flagRefresh) * time.Second)
+	}
+}
+
+// sample retrieves the usage of the running instances from the metrics of all cluster members.
+func (c *cmdTop) sample(d lxd.InstanceServer) (map[string]*topEntry, error) {
+	entries := map[string]*topEntry{}
+
+	if !d.IsClustered() {
+		content, err := d.GetMetrics()
+		if err != nil {
+			return nil, err
+		}
+
+		topParseMetrics(content, "", entries)
+		return entries, nil
+	}
+
+	members, err := d.GetClusterMembers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		if member.Status != "Online" {
+			continue
+		}
+
+		content, err := d.UseTarget(member.ServerName).GetMetrics()
+		if err != nil {
+			return nil, err
+		}
+
+		topParseMetrics(content, member.ServerName, entries)
+	}
+
+	return entries, nil
+}
+
+func (c *cmdTop) sort(rows []*topEntry) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a := rows[i]
+		b := rows[j]
+
+		switch c.flagSort {
+		case "cpu":
+			if a.cpuRate != b.cpuRate {
+				return a.cpuRate > b.cpuRate
+			}
+		case "memory":
+			if a.memory != b.memory {
+				return a.memory > b.memory
+			}
+		case "disk":
+			if a.disk != b.disk {
+				return a.disk > b.disk
+			}
+		case "network":
+			if a.networkRate != b.networkRate {
+				return a.networkRate > b.networkRate
+			}
+		}
+
+		if a.project != b.project {
+			return a.project < b.project
+		}
+
+		return a.name < b.name
+	})
+}
+
+func (c *cmdTop) render(d lxd.InstanceServer, rows []*topEntry, now time.Time) error {
+	header := []string{i18n.G("NAME")}
+	if c.flagAllProjects {
+		header = append(header, i18n.G("PROJECT"))
+	}
+
+	if d.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	header = append(header, i18n.G("CPU"), i18n.G("MEMORY"), i18n.G("DISK"), i18n.G("NETWORK"))
+
+	data := [][]string{}
+	for _, entry := range rows {
+		line := []string{entry.name}
+		if c.flagAllProjects {
+			line = append(line, entry.project)
+		}
+
+		if d.IsClustered() {
+			line = append(line, entry.location)
+		}
+
+		cpu := "-"
+		if entry.cpuRate >= 0 {
+			cpu = fmt.Sprintf("%.1f%%", entry.cpuRate*100)
+		}
+
+		network := "-"
+		if entry.networkRate >= 0 {
+			network = fmt.Sprintf("%s/s", units.GetByteSizeString(int64(entry.networkRate), 2))
+		}
+
+		line = append(line, cpu, units.GetByteSizeString(entry.memory, 2), units.GetByteSizeString(entry.disk, 2), network)
+		data = append(data, line)
+	}
+
+	// Clear the screen
+	fmt.Print("\033[H\033[2J")
+	fmt.Printf(i18n.G("%d running instances, refreshed every %ds (%s)")+"\n", len(rows), c.flagRefresh, now.Format("15:04:05"))
+
+	return utils.RenderTable(utils.TableFormatTable, header, data, rows)
+}
+
+// topParseMetrics adds the instance usage found in metrics in the Prometheus text format to the
+// entries, keyed by project and name.
+func topParseMetrics(content string, location string, entries map[string]*topEntry) {
+	for _, line := range strings.Split(content, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		start := strings.Index(line, "{")
+		end := strings.LastIndex(line, "}")
+		if start < 0 || end < start {
+			continue
+		}
+
+		metric := line[:start]
+		if !shared.StringInSlice(metric, []string{"lxd_instance_cpu_seconds_total", "lxd_instance_memory_usage_bytes", "lxd_instance_disk_usage_bytes", "lxd_instance_network_receive_bytes_total", "lxd_instance_network_transmit_bytes_total"}) {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(line[end+1:]), 64)
+		if err != nil {
+			continue
+		}
+
+		labels := map[string]string{}
+		for _, label := range strings.Split(line[start+1:end], ",") {
+			fields := strings.SplitN(label, "=", 2)
+			if len(fields) != 2 {
+				continue
+			}
+
+			labels[fields[0]] = strings.Trim(fields[1], `"`)
+		}
+
+		key := fmt.Sprintf("%s/%s", labels["project"], labels["name"])
+
+		entry, ok := entries[key]
+		if !ok {
+			entry = &topEntry{project: labels["project"], name: labels["name"], location: location}
+			entries[key] = entry
+		}
+
+		switch metric {
+		case "lxd_instance_cpu_seconds_total":
+			entry.cpu = value
+			entry.running = true
+		case "lxd_instance_memory_usage_bytes":
+			entry.memory = int64(value)
+		case "lxd_instance_disk_usage_bytes":
+			entry.disk += int64(value)
+		default:
+			entry.network += int64(value)
+		}
+	}
+
+	// Stopped instances only report their accumulated network usage.
+	for key, entry := range entries {
+		if !entry.running {
+			delete(entries, key)
+		}
+	}
+}
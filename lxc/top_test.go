@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopParseMetrics(t *testing.T) {
+	content := `# HELP lxd_instance_network_receive_bytes_total Bytes received by the instance NIC.
+# TYPE lxd_instance_network_receive_bytes_total counter
+lxd_instance_network_receive_bytes_total{project="default",name="c1",device="eth0"} 1000
+lxd_instance_network_receive_bytes_total{project="default",name="c2",device="eth0"} 50
+lxd_instance_network_transmit_bytes_total{project="default",name="c1",device="eth0"} 500
+lxd_instance_cpu_seconds_total{project="default",name="c1"} 12.5
+lxd_instance_memory_usage_bytes{project="default",name="c1"} 2048
+lxd_instance_disk_usage_bytes{project="default",name="c1",device="root"} 4096
+lxd_db_queries_total{kind="query"} 10
+`
+
+	entries := map[string]*topEntry{}
+	topParseMetrics(content, "node1", entries)
+
+	// The stopped instance only has network usage and is left out.
+	assert.Len(t, entries, 1)
+
+	entry := entries["default/c1"]
+	assert.Equal(t, "node1", entry.location)
+	assert.Equal(t, 12.5, entry.cpu)
+	assert.Equal(t, int64(2048), entry.memory)
+	assert.Equal(t, int64(4096), entry.disk)
+	assert.Equal(t, int64(1500), entry.network)
+}
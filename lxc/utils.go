@@ -117,6 +117,21 @@ func runBatch(names []string, action func(name string) error) []batchResult {
 	return results
 }
 
+// parseNetwork parses the network argument and checks a network name was provided.
+func parseNetwork(global *cmdGlobal, arg string) (remoteResource, error) {
+	resources, err := global.ParseServers(arg)
+	if err != nil {
+		return remoteResource{}, err
+	}
+
+	resource := resources[0]
+	if resource.name == "" {
+		return remoteResource{}, fmt.Errorf(i18n.G("Missing network name"))
+	}
+
+	return resource, nil
+}
+
 // Add a device to an instance
 func instanceDeviceAdd(client lxd.InstanceServer, name string, devName string, dev map[string]string) error {
 	// Get the instance entry
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/termios"
+	"github.com/lxc/lxd/shared/units"
 )
 
 // ProgressRenderer tracks the progress information
@@ -22,6 +24,10 @@ type ProgressRenderer struct {
 	done      bool
 	lock      sync.Mutex
 	terminal  int
+
+	// Stage of the operation progress and when it started, to estimate the time left.
+	stage      string
+	stageStart time.Time
 }
 
 func (p *ProgressRenderer) truncate(msg string) string {
@@ -174,6 +180,11 @@ func (p *ProgressRenderer) Warn(status string, timeout time.Duration) {
 
 // UpdateProgress is a helper to update the status using an iopgress instance
 func (p *ProgressRenderer) UpdateProgress(progress ioprogress.ProgressData) {
+	if progress.TransferredBytes > 0 {
+		p.Update(progressBytes(progress.TransferredBytes, progress.TotalBytes, progress.Speed))
+		return
+	}
+
 	p.Update(progress.Text)
 }
 
@@ -183,6 +194,16 @@ func (p *ProgressRenderer) UpdateOp(op api.Operation) {
 		return
 	}
 
+	// Prefer the structured progress, giving the bytes processed.
+	progress, ok := op.Metadata["progress"].(map[string]interface{})
+	if ok {
+		msg := p.opProgress(op.Metadata, progress)
+		if msg != "" {
+			p.Update(msg)
+			return
+		}
+	}
+
 	for key, value := range op.Metadata {
 		if !strings.HasSuffix(key, "_progress") {
 			continue
@@ -192,3 +213,78 @@ func (p *ProgressRenderer) UpdateOp(op api.Operation) {
 		break
 	}
 }
+
+// opProgress renders the structured progress of an operation with the bytes processed, the rate
+// and the time left when known. It returns an empty string if no progress got reported yet.
+func (p *ProgressRenderer) opProgress(metadata map[string]interface{}, progress map[string]interface{}) string {
+	value := func(key string) int64 {
+		str, _ := progress[key].(string)
+		n, _ := strconv.ParseInt(str, 10, 64)
+		return n
+	}
+
+	processed := value("processed")
+	percent := value("percent")
+	speed := value("speed")
+	if processed <= 0 && percent <= 0 {
+		return ""
+	}
+
+	// The text progress starts with the description of the stage, like the volume name.
+	stage, _ := progress["stage"].(string)
+	description := ""
+	text, _ := metadata[stage+"_progress"].(string)
+	fields := strings.SplitN(text, ": ", 2)
+	if len(fields) == 2 {
+		description = fields[0]
+	}
+
+	// Restart the estimate of the time left for each stage and volume.
+	p.lock.Lock()
+	if p.stage != stage+"/"+description {
+		p.stage = stage + "/" + description
+		p.stageStart = time.Now()
+	}
+	elapsed := time.Since(p.stageStart)
+	p.lock.Unlock()
+
+	var msg string
+	if processed > 0 {
+		total := int64(0)
+		if percent > 0 {
+			total = processed * 100 / percent
+		}
+
+		msg = progressBytes(processed, total, speed)
+	} else {
+		msg = fmt.Sprintf("%d%% (%s/s", percent, units.GetByteSizeString(speed, 2))
+		if percent < 100 {
+			left := time.Duration(int64(elapsed) * (100 - percent) / percent)
+			msg += fmt.Sprintf(", %s left", left.Round(time.Second))
+		}
+
+		msg += ")"
+	}
+
+	if description != "" {
+		msg = fmt.Sprintf("%s: %s", description, msg)
+	}
+
+	return msg
+}
+
+// progressBytes returns the progress of a transfer as the bytes processed, out of the total if
+// known (non-zero), with the rate and the time left.
+func progressBytes(processed int64, total int64, speed int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("%s (%s/s)", units.GetByteSizeString(processed, 2), units.GetByteSizeString(speed, 2))
+	}
+
+	msg := fmt.Sprintf("%s/%s (%s/s", units.GetByteSizeString(processed, 2), units.GetByteSizeString(total, 2), units.GetByteSizeString(speed, 2))
+	if speed > 0 && total > processed {
+		left := time.Duration((total-processed)/speed) * time.Second
+		msg += fmt.Sprintf(", %s left", left)
+	}
+
+	return msg + ")"
+}
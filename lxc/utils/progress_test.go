@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBytes(t *testing.T) {
+	assert.Equal(t, "500.00MB (50.00MB/s)", progressBytes(500000000, 0, 50000000))
+	assert.Equal(t, "500.00MB/2.00GB (50.00MB/s, 30s left)", progressBytes(500000000, 2000000000, 50000000))
+	assert.Equal(t, "500.00MB/2.00GB (0B/s)", progressBytes(500000000, 2000000000, 0))
+}
+
+func TestProgressRendererOpProgress(t *testing.T) {
+	p := ProgressRenderer{}
+
+	metadata := map[string]interface{}{
+		"fs_progress": "c1: 500.00MB (50.00MB/s)",
+	}
+
+	// Nothing processed yet.
+	progress := map[string]interface{}{"stage": "fs", "speed": "0"}
+	assert.Equal(t, "", p.opProgress(metadata, progress))
+
+	progress = map[string]interface{}{"stage": "fs", "processed": "500000000", "speed": "50000000"}
+	assert.Equal(t, "c1: 500.00MB (50.00MB/s)", p.opProgress(metadata, progress))
+
+	progress["percent"] = "25"
+	assert.Equal(t, "c1: 500.00MB/2.00GB (50.00MB/s, 30s left)", p.opProgress(metadata, progress))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/olekukonko/tablewriter"
@@ -13,10 +14,11 @@ import (
 
 // Table list format
 const (
-	TableFormatCSV   = "csv"
-	TableFormatJSON  = "json"
-	TableFormatTable = "table"
-	TableFormatYAML  = "yaml"
+	TableFormatCSV     = "csv"
+	TableFormatJSON    = "json"
+	TableFormatTable   = "table"
+	TableFormatYAML    = "yaml"
+	TableFormatCompact = "compact"
 )
 
 // RenderTable renders tabular data in various formats.
@@ -30,6 +32,19 @@ func RenderTable(format string, header []string, data [][]string, raw interface{
 		table.SetHeader(header)
 		table.AppendBulk(data)
 		table.Render()
+	case TableFormatCompact:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetBorder(false)
+		table.SetHeaderLine(false)
+		table.SetColumnSeparator("")
+		table.SetCenterSeparator("")
+		table.SetRowSeparator("")
+		table.SetHeader(header)
+		table.AppendBulk(data)
+		table.Render()
 	case TableFormatCSV:
 		w := csv.NewWriter(os.Stdout)
 		w.WriteAll(data)
@@ -58,3 +73,72 @@ func RenderTable(format string, header []string, data [][]string, raw interface{
 
 	return nil
 }
+
+// RenderTableColumns renders tabular data like RenderTable, only keeping the columns selected by
+// columns (see SelectColumns). The JSON and YAML formats always render the full raw data.
+func RenderTableColumns(format string, columns string, header []string, data [][]string, raw interface{}) error {
+	header, data, err := SelectColumns(header, data, columns)
+	if err != nil {
+		return err
+	}
+
+	return RenderTable(format, header, data, raw)
+}
+
+// ColumnName returns the name used to select a column from its header, in lower case and with
+// underscores rather than spaces (e.g. "LAST USED AT" gives "last_used_at").
+func ColumnName(header string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(header)))
+}
+
+// SelectColumns returns the given columns of tabular data, in the given order. Columns are a
+// comma-separated list of column names (see ColumnName), dashes being accepted for underscores.
+// All the columns are returned if columns is empty.
+func SelectColumns(header []string, data [][]string, columns string) ([]string, [][]string, error) {
+	if columns == "" {
+		return header, data, nil
+	}
+
+	indexes := []int{}
+	for _, name := range strings.Split(columns, ",") {
+		found := -1
+		for i, column := range header {
+			if ColumnName(column) == ColumnName(name) {
+				found = i
+				break
+			}
+		}
+
+		if found < 0 {
+			names := make([]string, 0, len(header))
+			for _, column := range header {
+				names = append(names, ColumnName(column))
+			}
+
+			return nil, nil, fmt.Errorf(i18n.G("Unknown column %q (available: %s)"), name, strings.Join(names, ", "))
+		}
+
+		indexes = append(indexes, found)
+	}
+
+	newHeader := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		newHeader = append(newHeader, header[i])
+	}
+
+	newData := make([][]string, 0, len(data))
+	for _, row := range data {
+		newRow := make([]string, 0, len(indexes))
+		for _, i := range indexes {
+			if i < len(row) {
+				newRow = append(newRow, row[i])
+			} else {
+				newRow = append(newRow, "")
+			}
+		}
+
+		newData = append(newData, newRow)
+	}
+
+	return newHeader, newData, nil
+}
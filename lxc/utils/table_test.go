@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectColumns(t *testing.T) {
+	header := []string{"NAME", "LAST USED AT", "STATE"}
+	data := [][]string{
+		{"c1", "today", "RUNNING"},
+		{"c2", "yesterday", "STOPPED"},
+	}
+
+	newHeader, newData, err := SelectColumns(header, data, "")
+	require.NoError(t, err)
+	assert.Equal(t, header, newHeader)
+	assert.Equal(t, data, newData)
+
+	newHeader, newData, err = SelectColumns(header, data, "state,last-used-at,NAME")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"STATE", "LAST USED AT", "NAME"}, newHeader)
+	assert.Equal(t, [][]string{{"RUNNING", "today", "c1"}, {"STOPPED", "yesterday", "c2"}}, newData)
+
+	_, _, err = SelectColumns(header, data, "name,size")
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// quiesceHooksPath is the directory holding the executables run to quiesce applications, called
+// with "freeze" before the filesystems get frozen and with "thaw" after they got thawed.
+const quiesceHooksPath = "/etc/lxd-agent/quiesce.d"
+
+// quiesceDefaultTimeout is how long filesystems are kept frozen when the host doesn't say.
+const quiesceDefaultTimeout = 60 * time.Second
+
+// quiesceLock is used to access quiesceFrozen and quiesceTimer.
+var quiesceLock sync.Mutex
+
+// quiesceFrozen holds the frozen mount points, in the order they were frozen in.
+var quiesceFrozen []string
+
+// quiesceTimer thaws the filesystems if the host doesn't do it in time.
+var quiesceTimer *time.Timer
+
+// quiesceFreeze runs the freeze hooks then freezes the filesystems, until quiesceThaw is called or
+// the timeout expires.
+func quiesceFreeze(timeout time.Duration) error {
+	quiesceLock.Lock()
+	defer quiesceLock.Unlock()
+
+	if quiesceFrozen != nil {
+		return fmt.Errorf("The filesystems are already frozen")
+	}
+
+	hooks, err := quiesceHooks()
+	if err != nil {
+		return err
+	}
+
+	for i, hook := range hooks {
+		_, err := shared.RunCommand(hook, "freeze")
+		if err != nil {
+			// Let the applications which got quiesced resume.
+			for j := i - 1; j >= 0; j-- {
+				shared.RunCommand(hooks[j], "thaw")
+			}
+
+			return fmt.Errorf("Quiesce hook %q failed: %v", hook, err)
+		}
+	}
+
+	mountPoints, err := quiesceMountPoints()
+	if err != nil {
+		quiesceRunThawHooks(hooks)
+		return err
+	}
+
+	quiesceFrozen = []string{}
+	for _, mountPoint := range mountPoints {
+		_, err := shared.RunCommand("fsfreeze", "--freeze", mountPoint)
+		if err != nil {
+			quiesceThawFilesystems()
+			quiesceRunThawHooks(hooks)
+			return fmt.Errorf("Failed to freeze %q: %v", mountPoint, err)
+		}
+
+		quiesceFrozen = append(quiesceFrozen, mountPoint)
+	}
+
+	if timeout <= 0 {
+		timeout = quiesceDefaultTimeout
+	}
+
+	quiesceTimer = time.AfterFunc(timeout, func() {
+		logger.Warnf("Thawing the filesystems as they weren't thawed within %v", timeout)
+
+		err := quiesceThaw()
+		if err != nil {
+			logger.Errorf("Failed to thaw the filesystems: %v", err)
+		}
+	})
+
+	return nil
+}
+
+// quiesceThaw thaws the filesystems frozen by quiesceFreeze then runs the thaw hooks.
+func quiesceThaw() error {
+	quiesceLock.Lock()
+	defer quiesceLock.Unlock()
+
+	if quiesceFrozen == nil {
+		return nil
+	}
+
+	if quiesceTimer != nil {
+		quiesceTimer.Stop()
+		quiesceTimer = nil
+	}
+
+	err := quiesceThawFilesystems()
+
+	hooks, hooksErr := quiesceHooks()
+	if hooksErr == nil {
+		quiesceRunThawHooks(hooks)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return hooksErr
+}
+
+// quiesceThawFilesystems thaws the frozen filesystems in reverse order. Must be called with
+// quiesceLock held.
+func quiesceThawFilesystems() error {
+	var thawErr error
+	for i := len(quiesceFrozen) - 1; i >= 0; i-- {
+		_, err := shared.RunCommand("fsfreeze", "--unfreeze", quiesceFrozen[i])
+		if err != nil && thawErr == nil {
+			thawErr = fmt.Errorf("Failed to thaw %q: %v", quiesceFrozen[i], err)
+		}
+	}
+
+	quiesceFrozen = nil
+
+	return thawErr
+}
+
+// quiesceRunThawHooks runs the hooks with "thaw", in reverse order.
+func quiesceRunThawHooks(hooks []string) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		_, err := shared.RunCommand(hooks[i], "thaw")
+		if err != nil {
+			logger.Errorf("Quiesce hook %q failed to thaw: %v", hooks[i], err)
+		}
+	}
+}
+
+// quiesceHooks returns the executables of the quiesce hooks directory, sorted by name.
+func quiesceHooks() ([]string, error) {
+	entries, err := ioutil.ReadDir(quiesceHooksPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	hooks := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Mode()&0111 == 0 {
+			continue
+		}
+
+		hooks = append(hooks, filepath.Join(quiesceHooksPath, entry.Name()))
+	}
+
+	sort.Strings(hooks)
+
+	return hooks, nil
+}
+
+// quiesceMountPoints returns the mount points of the block-backed filesystems, the most recently
+// mounted first so that filesystems mounted inside others get frozen before them.
+func quiesceMountPoints() ([]string, error) {
+	content, err := ioutil.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoints := []string{}
+	seen := map[string]bool{}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+
+		// Read-only filesystems don't need freezing.
+		if shared.StringInSlice("ro", strings.Split(fields[3], ",")) {
+			continue
+		}
+
+		// The same filesystem can be mounted several times, it's frozen once.
+		if seen[fields[0]] {
+			continue
+		}
+
+		seen[fields[0]] = true
+		mountPoints = append(mountPoints, fields[1])
+	}
+
+	return mountPoints, nil
+}
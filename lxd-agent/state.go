@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared/api"
@@ -25,8 +27,29 @@ func stateGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, renderState())
 }
 
+// statePut quiesces the guest with the "freeze" action, freezing its filesystems until the "unfreeze"
+// action or the timeout (in seconds), so that the host can take consistent snapshots.
 func statePut(d *Daemon, r *http.Request) response.Response {
-	return response.NotImplemented(nil)
+	req := api.InstanceStatePut{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	switch req.Action {
+	case "freeze":
+		err = quiesceFreeze(time.Duration(req.Timeout) * time.Second)
+	case "unfreeze":
+		err = quiesceThaw()
+	default:
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+	}
+
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
 }
 
 func renderState() *api.InstanceState {
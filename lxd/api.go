@@ -10,6 +10,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared/logger"
 )
@@ -26,6 +27,8 @@ func RestServer(d *Daemon) *http.Server {
 		response.SyncResponse(true, []string{"/1.0"}).Render(w)
 	})
 
+	imageStreamsRoutes(d, mux)
+
 	for endpoint, f := range d.gateway.HandlerFuncs(d.NodeRefreshTask) {
 		mux.HandleFunc(endpoint, f)
 	}
@@ -129,6 +132,12 @@ func projectParam(request *http.Request) string {
 	return project
 }
 
+// Extract the filter, sort, limit and offset query parameters from the given
+// request, returning nil if none is set.
+func filterParam(request *http.Request) (*query.Filter, error) {
+	return query.ParseFilter(queryParam(request, "filter"), queryParam(request, "sort"), queryParam(request, "limit"), queryParam(request, "offset"))
+}
+
 // Extract the given query parameter directly from the URL, never from an
 // encoded body.
 func queryParam(request *http.Request, key string) string {
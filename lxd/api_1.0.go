@@ -12,12 +12,15 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/trace"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/logging"
 	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/version"
 	"github.com/pkg/errors"
@@ -32,11 +35,22 @@ var api10Cmd = APIEndpoint{
 var api10 = []APIEndpoint{
 	api10Cmd,
 	api10ResourcesCmd,
+	auditCmd,
+	authGroupCmd,
+	authGroupsCmd,
+	authTokenCmd,
+	authTokensCmd,
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
+	clusterGroupCmd,
+	clusterGroupsCmd,
 	clusterNodeCmd,
+	clusterNodeStateCmd,
 	clusterNodesCmd,
+	clusterStateCmd,
+	clusterUpgradeCmd,
+	databaseBackupsCmd,
 	instanceBackupCmd,
 	instanceBackupExportCmd,
 	instanceBackupsCmd,
@@ -48,10 +62,16 @@ var api10 = []APIEndpoint{
 	instanceLogsCmd,
 	instanceMetadataCmd,
 	instanceMetadataTemplatesCmd,
+	instanceNetworkDiagnosticsCmd,
+	instanceResetCmd,
 	instancesCmd,
 	instanceSnapshotCmd,
+	instanceSnapshotDiffCmd,
 	instanceSnapshotsCmd,
+	instanceSnapshotGroupsCmd,
+	instanceSnapshotGroupCmd,
 	instanceStateCmd,
+	instanceUsageCmd,
 	eventsCmd,
 	imageAliasCmd,
 	imageAliasesCmd,
@@ -60,8 +80,16 @@ var api10 = []APIEndpoint{
 	imageRefreshCmd,
 	imagesCmd,
 	imageSecretCmd,
+	metricsCmd,
 	networkCmd,
+	networkForwardCmd,
+	networkForwardsCmd,
 	networkLeasesCmd,
+	networkLoadBalancerCmd,
+	networkLoadBalancersCmd,
+	networkLoadBalancerStateCmd,
+	networkReservationCmd,
+	networkReservationsCmd,
 	networksCmd,
 	networkStateCmd,
 	operationCmd,
@@ -72,8 +100,14 @@ var api10 = []APIEndpoint{
 	profilesCmd,
 	projectCmd,
 	projectsCmd,
+	projectUsageCmd,
+	sriovPoolCmd,
+	sriovPoolsCmd,
 	storagePoolCmd,
 	storagePoolResourcesCmd,
+	storagePoolTrashCmd,
+	storagePoolTrashEntryCmd,
+	storagePoolUsageCmd,
 	storagePoolsCmd,
 	storagePoolVolumesCmd,
 	storagePoolVolumeSnapshotsTypeCmd,
@@ -86,7 +120,8 @@ var api10 = []APIEndpoint{
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
-	authMethods := []string{"tls"}
+	authMethods := []string{"tls", "token"}
+	var oidcIssuer, oidcClientID, oidcAudience string
 	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		config, err := cluster.ConfigLoad(tx)
 		if err != nil {
@@ -99,6 +134,11 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 			authMethods = append(authMethods, "candid")
 		}
 
+		oidcIssuer, oidcClientID, oidcAudience, _ = config.OIDCServer()
+		if oidcIssuer != "" {
+			authMethods = append(authMethods, "oidc")
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -111,6 +151,10 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 		Public:        false,
 		Auth:          "untrusted",
 		AuthMethods:   authMethods,
+
+		AuthOIDCIssuer:   oidcIssuer,
+		AuthOIDCClientID: oidcClientID,
+		AuthOIDCAudience: oidcAudience,
 	}
 
 	// If untrusted, return now
@@ -206,6 +250,7 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 	}
 
 	env.KernelFeatures = map[string]string{
+		"core_scheduling":           fmt.Sprintf("%v", d.os.CoreScheduling),
 		"netnsid_getifaddrs":        fmt.Sprintf("%v", d.os.NetnsGetifaddrs),
 		"uevent_injection":          fmt.Sprintf("%v", d.os.UeventInjection),
 		"unpriv_fscaps":             fmt.Sprintf("%v", d.os.VFS3Fscaps),
@@ -424,7 +469,16 @@ func doApi10Update(d *Daemon, req api.ServerPut, patch bool) response.Response {
 		} else {
 			clusterChanged, err = newClusterConfig.Replace(req.Config)
 		}
-		return err
+		if err != nil {
+			return err
+		}
+
+		// The image store can only be encrypted with a key provided out of the LXD data.
+		if newClusterConfig.ImagesEncryption() && newClusterConfig.ImagesEncryptionKeyFile() == "" {
+			return config.ErrorList{&config.Error{Name: "images.encryption", Value: true, Reason: "Requires images.encryption.key_file to be set"}}
+		}
+
+		return nil
 	})
 	if err != nil {
 		switch err.(type) {
@@ -497,6 +551,10 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			if !d.os.MockMode {
 				d.taskAutoUpdate.Reset()
 			}
+		case "cluster.rebalance.interval":
+			if d.taskClusterRebalance != nil {
+				d.taskClusterRebalance.Reset()
+			}
 		case "images.remote_cache_expiry":
 			if !d.os.MockMode {
 				d.taskPruneImages.Reset()
@@ -515,6 +573,71 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 			fallthrough
 		case "rbac.expiry":
 			rbacChanged = true
+		case "oidc.audience":
+			fallthrough
+		case "oidc.client.id":
+			fallthrough
+		case "oidc.groups.claim":
+			fallthrough
+		case "oidc.issuer":
+			d.setupOIDC(clusterConfig.OIDCServer())
+		case "auth.builtin":
+			d.setAuthBuiltin(clusterConfig.AuthBuiltin())
+		case "core.audit_sinks":
+			fallthrough
+		case "core.audit_webhook":
+			err := d.audit.Configure(clusterConfig.AuditSinks())
+			if err != nil {
+				return err
+			}
+		case "core.webhook_events":
+			fallthrough
+		case "core.webhook_secret":
+			fallthrough
+		case "core.webhook_urls":
+			err := d.webhooks.Configure(clusterConfig.Webhooks())
+			if err != nil {
+				return err
+			}
+		case "loki.api.url":
+			fallthrough
+		case "loki.auth.password":
+			fallthrough
+		case "loki.auth.username":
+			fallthrough
+		case "loki.labels":
+			fallthrough
+		case "loki.types":
+			fallthrough
+		case "syslog.address":
+			fallthrough
+		case "syslog.types":
+			err := d.logTargets.Configure(clusterConfig.LogTargets())
+			if err != nil {
+				return err
+			}
+		case "core.trace_endpoint":
+			err := trace.Configure(clusterConfig.TraceEndpoint())
+			if err != nil {
+				return err
+			}
+		case "core.bgp_asn":
+			err := networkBGPServerUpdate(s)
+			if err != nil {
+				return err
+			}
+		case "core.db_slow_query_threshold":
+			query.SetSlowThreshold(clusterConfig.SlowQueryThreshold())
+		case "core.rate_limit.burst":
+			fallthrough
+		case "core.rate_limit.concurrency":
+			fallthrough
+		case "core.rate_limit.requests":
+			requests, burst, concurrency := clusterConfig.RateLimits()
+			err := d.rateLimiter.Configure(float64(requests), int(burst), int(concurrency))
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -552,6 +675,34 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	_, ok = nodeChanged["core.dns_address"]
+	if ok {
+		err := networkDNSServerUpdate(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, ok = nodeChanged["core.bgp_address"]
+	_, routerIDChanged := nodeChanged["core.bgp_routerid"]
+	if ok || routerIDChanged {
+		err := networkBGPServerUpdate(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, value := range nodeChanged {
+		if !strings.HasPrefix(key, "core.log_level.") {
+			continue
+		}
+
+		err := logging.SetSubsystemLevel(strings.TrimPrefix(key, "core.log_level."), value)
+		if err != nil {
+			return err
+		}
+	}
+
 	value, ok = nodeChanged["storage.backups_volume"]
 	if ok {
 		err := daemonStorageMove(s, "backups", value)
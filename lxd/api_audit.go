@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var auditCmd = APIEndpoint{
+	Path: "audit",
+
+	Get: APIEndpointAction{Handler: auditGet},
+}
+
+// auditBodyLimit is the size above which request bodies and resource states aren't recorded.
+const auditBodyLimit = 64 * 1024
+
+// Return the most recent audit entries recorded by a cluster member.
+func auditGet(d *Daemon, r *http.Request) response.Response {
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	limit, err := shared.AtoiEmptyDefault(queryParam(r, "limit"), 0)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return response.SyncResponse(true, d.audit.Entries(queryParam(r, "project"), limit))
+}
+
+// auditRequest returns a new audit entry for the given request, or nil if it doesn't need to be
+// audited. Only mutating requests are, and those from other cluster members are left to the
+// member which forwarded them.
+func auditRequest(d *Daemon, r *http.Request, identity string, protocol string) *api.AuditEntry {
+	if shared.StringInSlice(r.Method, []string{"GET", "HEAD", "OPTIONS"}) || protocol == "cluster" {
+		return nil
+	}
+
+	if !d.audit.Enabled() && !d.logTargets.Enabled("audit") {
+		return nil
+	}
+
+	entry := &api.AuditEntry{
+		Timestamp:     time.Now().UTC(),
+		Identity:      identity,
+		Protocol:      protocol,
+		SourceAddress: r.RemoteAddr,
+		Method:        r.Method,
+		Resource:      r.URL.Path,
+		Project:       projectParam(r),
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		entry.Location, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to get member name for audit entry", log.Ctx{"err": err})
+	}
+
+	// Record the request body, leaving it in place for the handler.
+	if util.IsJSONRequest(r) && r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			logger.Warn("Failed to read request body for audit entry", log.Ctx{"err": err})
+		}
+
+		r.Body = shared.BytesReadCloser{Buf: bytes.NewBuffer(body)}
+
+		if len(body) <= auditBodyLimit {
+			var after interface{}
+			err = json.Unmarshal(body, &after)
+			if err == nil {
+				entry.After = after
+			}
+		}
+	}
+
+	return entry
+}
+
+// auditResourceState returns the current state of the resource targeted by a request, as
+// returned by the given GET handler of its endpoint.
+func auditResourceState(d *Daemon, r *http.Request, handler func(d *Daemon, r *http.Request) response.Response) interface{} {
+	req := r.WithContext(r.Context())
+	req.Method = "GET"
+	req.Body = ioutil.NopCloser(strings.NewReader(""))
+	req.ContentLength = 0
+
+	capture := &auditCaptureWriter{header: http.Header{}, status: http.StatusOK}
+	err := handler(d, req).Render(capture)
+	if err != nil || capture.status != http.StatusOK || capture.body.Len() > auditBodyLimit {
+		return nil
+	}
+
+	resp := struct {
+		Type     api.ResponseType `json:"type"`
+		Metadata interface{}      `json:"metadata"`
+	}{}
+
+	err = json.Unmarshal(capture.body.Bytes(), &resp)
+	if err != nil || resp.Type != api.SyncResponse {
+		return nil
+	}
+
+	return resp.Metadata
+}
+
+// auditFinish completes an audit entry with the outcome of its request and records it.
+func auditFinish(d *Daemon, entry *api.AuditEntry, w *auditResponseWriter) {
+	entry.StatusCode = w.status
+
+	location := w.Header().Get("Location")
+	if strings.HasPrefix(location, "/"+version.APIVersion+"/operations/") {
+		entry.Operation = location
+	}
+
+	d.audit.Record(*entry)
+}
+
+// auditResponseWriter records the status of the response to an audited request.
+type auditResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// auditCaptureWriter holds a rendered response in memory.
+type auditCaptureWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *auditCaptureWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *auditCaptureWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *auditCaptureWriter) WriteHeader(status int) {
+	w.status = status
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/auth"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var authGroupsCmd = APIEndpoint{
+	Path: "auth/groups",
+
+	Get:  APIEndpointAction{Handler: authGroupsGet},
+	Post: APIEndpointAction{Handler: authGroupsPost},
+}
+
+var authGroupCmd = APIEndpoint{
+	Path: "auth/groups/{name}",
+
+	Delete: APIEndpointAction{Handler: authGroupDelete},
+	Get:    APIEndpointAction{Handler: authGroupGet},
+	Post:   APIEndpointAction{Handler: authGroupPost},
+	Put:    APIEndpointAction{Handler: authGroupPut},
+}
+
+var authTokensCmd = APIEndpoint{
+	Path: "auth/tokens",
+
+	Get:  APIEndpointAction{Handler: authTokensGet},
+	Post: APIEndpointAction{Handler: authTokensPost},
+}
+
+var authTokenCmd = APIEndpoint{
+	Path: "auth/tokens/{name}",
+
+	Delete: APIEndpointAction{Handler: authTokenDelete},
+	Get:    APIEndpointAction{Handler: authTokenGet},
+}
+
+// API endpoints
+func authGroupsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	names, err := d.cluster.AuthGroups()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, name := range names {
+			urls = append(urls, fmt.Sprintf("/%s/auth/groups/%s", version.APIVersion, name))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	groups := []*api.AuthGroup{}
+	for _, name := range names {
+		_, group, err := d.cluster.AuthGroupGet(name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return response.SyncResponse(true, groups)
+}
+
+func authGroupsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.AuthGroupsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = authGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req.AuthGroupPut, err = authGroupValidate(req.AuthGroupPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.AuthGroupGet(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("The authorization group already exists"))
+	}
+
+	_, err = d.cluster.AuthGroupCreate(req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/auth/groups/%s", version.APIVersion, req.Name))
+}
+
+func authGroupGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	_, group, err := d.cluster.AuthGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{group.Name, group.Description, group.Identities, group.Permissions}
+
+	return response.SyncResponseETag(true, group, etag)
+}
+
+func authGroupPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, group, err := d.cluster.AuthGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{group.Name, group.Description, group.Identities, group.Permissions}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.AuthGroupPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req, err = authGroupValidate(req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.AuthGroupUpdate(id, req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authGroupPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	req := api.AuthGroupPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = authGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	id, _, err := d.cluster.AuthGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, _, err = d.cluster.AuthGroupGet(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("An authorization group named %q already exists", req.Name))
+	}
+
+	err = d.cluster.AuthGroupRename(id, req.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/auth/groups/%s", version.APIVersion, req.Name))
+}
+
+func authGroupDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, _, err := d.cluster.AuthGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.AuthGroupDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func authTokensGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	tokens, err := d.cluster.AuthTokens()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, token := range tokens {
+			urls = append(urls, fmt.Sprintf("/%s/auth/tokens/%s", version.APIVersion, token.Name))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, tokens)
+}
+
+func authTokensPost(d *Daemon, r *http.Request) response.Response {
+	req := api.AuthTokensPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = authGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = auth.ValidateToken(req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !req.ExpiresAt.IsZero() && req.ExpiresAt.Before(time.Now()) {
+		return response.BadRequest(fmt.Errorf("The expiry date is in the past"))
+	}
+
+	// Check that the projects the token is restricted to exist.
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		projects, err := tx.ProjectNames()
+		if err != nil {
+			return err
+		}
+
+		for _, project := range req.Projects {
+			if !shared.StringInSlice(project, projects) {
+				return fmt.Errorf("Project %q doesn't exist", project)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.AuthTokenGet(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("The API token already exists"))
+	}
+
+	secret, secretHash, err := auth.TokenGenerate()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	_, err = d.cluster.AuthTokenCreate(req, secretHash)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, api.AuthTokenSecret{Secret: secret}, fmt.Sprintf("/%s/auth/tokens/%s", version.APIVersion, req.Name))
+}
+
+func authTokenGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	_, token, err := d.cluster.AuthTokenGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, token)
+}
+
+func authTokenDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, _, err := d.cluster.AuthTokenGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.AuthTokenDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// authGroupValidateName checks that the name of an authorization group or API token can be used
+// in URLs.
+func authGroupValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("Names may not contain slashes")
+	}
+
+	return nil
+}
+
+// authGroupValidate checks the permissions of an authorization group and drops duplicated
+// identities and permissions.
+func authGroupValidate(group api.AuthGroupPut) (api.AuthGroupPut, error) {
+	identities := []string{}
+	seenIdentities := map[string]bool{}
+	for _, identity := range group.Identities {
+		if identity == "" || seenIdentities[identity] {
+			continue
+		}
+
+		seenIdentities[identity] = true
+		identities = append(identities, identity)
+	}
+
+	permissions := []api.AuthPermission{}
+	seenPermissions := map[api.AuthPermission]bool{}
+	for _, permission := range group.Permissions {
+		err := auth.ValidatePermission(permission)
+		if err != nil {
+			return group, err
+		}
+
+		if seenPermissions[permission] {
+			continue
+		}
+
+		seenPermissions[permission] = true
+		permissions = append(permissions, permission)
+	}
+
+	group.Identities = identities
+	group.Permissions = permissions
+
+	return group, nil
+}
+
+// userPermissions returns the permissions granted to the identity behind a request by the
+// built-in authorization groups. Nothing is granted if they can't be loaded.
+func (d *Daemon) userPermissions(r *http.Request) []api.AuthPermission {
+	identity := requestIdentity(r)
+	groups, _ := r.Context().Value("groups").([]string)
+	if identity == "" && len(groups) == 0 {
+		return nil
+	}
+
+	var permissions []api.AuthPermission
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		permissions, err = tx.AuthIdentityPermissions(identity, groups)
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to load permissions", log.Ctx{"identity": identity, "err": err})
+		return nil
+	}
+
+	return permissions
+}
+
+// requestToken returns the API token behind a request, if any.
+func requestToken(r *http.Request) *api.AuthToken {
+	token, _ := r.Context().Value("token").(*api.AuthToken)
+	return token
+}
+
+// requestCertificate returns the restricted client certificate behind a request, if any.
+func requestCertificate(r *http.Request) *api.Certificate {
+	cert, _ := r.Context().Value("certificate").(*api.Certificate)
+	return cert
+}
+
+// requestProjects returns the projects the restricted certificate or API token behind a request is
+// limited to, no projects meaning all of them.
+func requestProjects(r *http.Request) []string {
+	token := requestToken(r)
+	if token != nil {
+		return token.Projects
+	}
+
+	cert := requestCertificate(r)
+	if cert != nil {
+		return cert.Projects
+	}
+
+	return nil
+}
+
+// requestHasProject returns whether the restricted certificate or API token behind a request, if
+// any, grants access to a project. Handlers open to any authenticated client use it to leave out
+// the resources of the projects the client can't access.
+func requestHasProject(r *http.Request, project string) bool {
+	return auth.CertificateHasProject(requestProjects(r), project)
+}
+
+// pruneExpiredAuthTokensTask deletes the API tokens which have expired, every hour.
+func pruneExpiredAuthTokensTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		count, err := d.cluster.AuthTokensPruneExpired()
+		if err != nil {
+			logger.Error("Failed to prune expired API tokens", log.Ctx{"err": err})
+			return
+		}
+
+		if count > 0 {
+			logger.Info("Pruned expired API tokens", log.Ctx{"count": count})
+		}
+	}
+
+	return f, task.Every(time.Hour)
+}
+
+// requestIdentity returns the authenticated identity behind a request, if any.
+func requestIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value("username").(string)
+	return identity
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Test the projects clients restricted to some of them have access to
+func TestRequestHasProject(t *testing.T) {
+	r := httptest.NewRequest("GET", "/1.0/operations", nil)
+
+	// Other clients have access to all projects.
+	assert.True(t, requestHasProject(r, "default"))
+	assert.True(t, operationAllowed(r, "p2"))
+
+	// A viewer only has access to its projects, and to the operations not tied to a project.
+	cert := &api.Certificate{Type: "viewer", Projects: []string{"p1"}}
+	viewer := r.WithContext(context.WithValue(r.Context(), "certificate", cert))
+
+	assert.Equal(t, []string{"p1"}, requestProjects(viewer))
+	assert.True(t, requestHasProject(viewer, "p1"))
+	assert.False(t, requestHasProject(viewer, "default"))
+	assert.True(t, operationAllowed(viewer, "p1"))
+	assert.True(t, operationAllowed(viewer, ""))
+	assert.False(t, operationAllowed(viewer, "p2"))
+
+	// A viewer restricted to no projects has access to all of them.
+	cert = &api.Certificate{Type: "viewer"}
+	viewer = r.WithContext(context.WithValue(r.Context(), "certificate", cert))
+
+	assert.True(t, requestHasProject(viewer, "p2"))
+}
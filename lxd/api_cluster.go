@@ -39,7 +39,8 @@ var clusterCmd = APIEndpoint{
 var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
-	Get: APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterNodesPost},
 }
 
 var clusterNodeCmd = APIEndpoint{
@@ -596,6 +597,11 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 					return err
 				}
 
+				err = imageEncryptFiles(d, fingerprint)
+				if err != nil {
+					return err
+				}
+
 				for _, project := range projects {
 					err := d.cluster.ImageAssociateNode(project, fingerprint)
 					if err != nil {
@@ -867,6 +873,242 @@ func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, result)
 }
 
+// Issue a single-use join token for a new cluster member.
+func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterMembersPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.ServerName == "" {
+		return response.BadRequest(fmt.Errorf("No server name provided"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	resp := clusterUpgradeCheck(d)
+	if resp != nil {
+		return resp
+	}
+
+	var expiry string
+	var nameInUse bool
+	addresses := []string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.NodeByName(req.ServerName)
+		if err == nil {
+			nameInUse = true
+			return nil
+		}
+
+		if err != db.ErrNoSuchObject {
+			return err
+		}
+
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		expiry = config.JoinTokenExpiry()
+
+		// The new member can reach the cluster through any of the online members.
+		nodes, err := tx.Nodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.IsOffline(config.OfflineThreshold()) {
+				continue
+			}
+
+			addresses = append(addresses, node.Address)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if nameInUse {
+		return response.Conflict(fmt.Errorf("The cluster already has a member with name: %s", req.ServerName))
+	}
+
+	// Only one join token can be pending for a given member name.
+	tokens, err := clusterMemberJoinTokens(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, token := range tokens {
+		if token.ServerName == req.ServerName {
+			return response.Conflict(fmt.Errorf("A join token already exists for the name: %s", req.ServerName))
+		}
+	}
+
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	expiresAt, err := shared.GetSnapshotExpiry(time.Now(), expiry)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	meta := map[string]interface{}{
+		"server_name": req.ServerName,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+		"secret":      secret,
+		"expires_at":  expiresAt,
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassToken, db.OperationClusterJoinToken, nil, meta, nil, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterMemberJoinToken is a pending join token along with the ID of the operation holding it.
+type clusterMemberJoinToken struct {
+	api.ClusterMemberJoinToken
+
+	OperationID string
+}
+
+// clusterMemberJoinTokens returns the pending join tokens of the whole cluster, which are held by
+// token operations on the members which issued them.
+func clusterMemberJoinTokens(d *Daemon) ([]clusterMemberJoinToken, error) {
+	var dbOps []db.Operation
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		dbOps, err = tx.OperationsOfType(db.OperationClusterJoinToken)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []clusterMemberJoinToken{}
+	for _, dbOp := range dbOps {
+		var op *api.Operation
+
+		localOp, err := operations.OperationGetInternal(dbOp.UUID)
+		if err == nil {
+			_, op, err = localOp.Render()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			client, err := cluster.Connect(dbOp.NodeAddress, d.endpoints.NetworkCert(), false)
+			if err != nil {
+				return nil, err
+			}
+
+			op, _, err = client.GetOperation(dbOp.UUID)
+			if err != nil {
+				logger.Warnf("Failed to get join token %s from %s: %v", dbOp.UUID, dbOp.NodeAddress, err)
+				continue
+			}
+		}
+
+		if op.StatusCode != api.Pending {
+			continue
+		}
+
+		// Go through JSON, as the metadata of remote operations has been decoded into generic values.
+		data, err := json.Marshal(op.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		token := clusterMemberJoinToken{OperationID: op.ID}
+		err = json.Unmarshal(data, &token.ClusterMemberJoinToken)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// clusterMemberJoinTokenValid looks for a pending join token matching the given secret. Join tokens
+// are single-use, so the matching token gets revoked, and nil is returned if it has expired.
+func clusterMemberJoinTokenValid(d *Daemon, secret string) (*api.ClusterMemberJoinToken, error) {
+	tokens, err := clusterMemberJoinTokens(d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		if token.Secret != secret {
+			continue
+		}
+
+		err = clusterMemberJoinTokenRevoke(d, token.OperationID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+			logger.Warnf("Expired join token used for cluster member %s", token.ServerName)
+			return nil, nil
+		}
+
+		return &token.ClusterMemberJoinToken, nil
+	}
+
+	return nil, nil
+}
+
+// clusterMemberJoinTokenRevoke cancels the operation holding a join token, on whichever member
+// issued it.
+func clusterMemberJoinTokenRevoke(d *Daemon, id string) error {
+	op, err := operations.OperationGetInternal(id)
+	if err == nil {
+		_, err = op.Cancel()
+		return err
+	}
+
+	var address string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		operation, err := tx.OperationByUUID(id)
+		if err != nil {
+			return err
+		}
+
+		address = operation.NodeAddress
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteOperation(id)
+}
+
 func clusterNodeGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -909,6 +1151,11 @@ func clusterNodeDelete(d *Daemon, r *http.Request) response.Response {
 	d.clusterMembershipMutex.Lock()
 	defer d.clusterMembershipMutex.Unlock()
 
+	resp := clusterUpgradeCheck(d)
+	if resp != nil {
+		return resp
+	}
+
 	// Redirect all requests to the leader, which is the one with
 	// knowning what nodes are part of the raft cluster.
 	localAddress, err := node.ClusterAddress(d.db)
@@ -1013,6 +1260,11 @@ func internalClusterPostAccept(d *Daemon, r *http.Request) response.Response {
 	d.clusterMembershipMutex.Lock()
 	defer d.clusterMembershipMutex.Unlock()
 
+	resp := clusterUpgradeCheck(d)
+	if resp != nil {
+		return resp
+	}
+
 	req := internalClusterPostAcceptRequest{}
 
 	// Parse the request
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterNodeStateCmd = APIEndpoint{
+	Path: "cluster/members/{name}/state",
+
+	Get:  APIEndpointAction{Handler: clusterNodeStateGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterNodeStatePost},
+}
+
+// Evacuate or restore a cluster member. The request is handled by the member itself.
+func clusterNodeStatePost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	resp := clusterUpgradeCheck(d)
+	if resp != nil {
+		return resp
+	}
+
+	// Forward the request to the member if it isn't the local one.
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address != "" {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	req := api.ClusterMemberStatePost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var opType db.OperationType
+	var run func(op *operations.Operation) error
+
+	switch req.Action {
+	case "evacuate":
+		opType = db.OperationClusterMemberEvacuate
+		run = func(op *operations.Operation) error {
+			return clusterEvacuate(d, name)
+		}
+	case "maintenance":
+		opType = db.OperationClusterMemberMaintenance
+		run = func(op *operations.Operation) error {
+			return clusterMaintenance(d, name)
+		}
+	case "restore":
+		opType = db.OperationClusterMemberRestore
+		run = func(op *operations.Operation) error {
+			return clusterRestore(d, name)
+		}
+	default:
+		return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, opType, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// clusterEvacuate marks the local member as evacuated, so that no new instance is placed on it,
+// and relocates or stops its instances according to their cluster.evacuate policy.
+func clusterEvacuate(d *Daemon, name string) error {
+	client, err := clusterEvacuateClient(d)
+	if err != nil {
+		return err
+	}
+
+	err = clusterSetState(d, name, db.ClusterMemberStateEvacuated)
+	if err != nil {
+		return err
+	}
+
+	instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		policy := inst.ExpandedConfig()["cluster.evacuate"]
+		if policy == "" {
+			policy = "auto"
+		}
+
+		isRunning := inst.IsRunning()
+
+		// Instances which can't be relocated are stopped and started again on restore.
+		if policy == "stop" || (policy == "auto" && !clusterEvacuateMovable(inst)) {
+			if !isRunning {
+				continue
+			}
+
+			logger.Info("Stopping instance for evacuation", log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+			err = clusterEvacuateStop(inst)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to stop instance %q", inst.Name())
+			}
+
+			err = inst.VolatileSet(map[string]string{"volatile.evacuate.origin": name})
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var target string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			target, err = tx.NodeWithLeastContainers([]int{inst.Architecture()})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if target == "" {
+			return fmt.Errorf("No cluster member available to relocate instance %q to", inst.Name())
+		}
+
+		logger.Info("Relocating instance for evacuation", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "target": target})
+		if isRunning {
+			err = clusterEvacuateStop(inst)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to stop instance %q", inst.Name())
+			}
+		}
+
+		err = clusterEvacuateMove(client, inst.Project(), inst.Name(), target)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to relocate instance %q", inst.Name())
+		}
+
+		// Volatile keys aren't copied along with the instance, so record its origin afterwards.
+		err = clusterEvacuateSetOrigin(d, inst.Project(), inst.Name(), name)
+		if err != nil {
+			return err
+		}
+
+		if isRunning {
+			err = clusterEvacuateStart(client, inst.Project(), inst.Name())
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q", inst.Name())
+			}
+		}
+	}
+
+	return nil
+}
+
+// clusterMaintenance marks the local member as being in maintenance, so that no new instance or
+// image is placed on it while its instances keep running.
+func clusterMaintenance(d *Daemon, name string) error {
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		node, err := tx.NodeByName(name)
+		if err != nil {
+			return err
+		}
+
+		if node.State == db.ClusterMemberStateEvacuated {
+			return fmt.Errorf("The cluster member is evacuated, restore it first")
+		}
+
+		return tx.NodeUpdateState(node.ID, db.ClusterMemberStateMaintenance)
+	})
+}
+
+// clusterRestore brings back the instances evacuated from the local member, restarting the ones
+// which were stopped and moving back the ones which were relocated.
+func clusterRestore(d *Daemon, name string) error {
+	client, err := clusterEvacuateClient(d)
+	if err != nil {
+		return err
+	}
+
+	err = clusterSetState(d, name, db.ClusterMemberStateCreated)
+	if err != nil {
+		return err
+	}
+
+	var instances []db.Instance
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		instances, err = tx.InstanceList(db.InstanceFilter{Type: instancetype.Any})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dbInst := range instances {
+		if dbInst.Config["volatile.evacuate.origin"] != name {
+			continue
+		}
+
+		// Instance stopped in place.
+		if dbInst.Node == name {
+			inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+			if err != nil {
+				return err
+			}
+
+			err = inst.VolatileSet(map[string]string{"volatile.evacuate.origin": ""})
+			if err != nil {
+				return err
+			}
+
+			if inst.IsRunning() {
+				continue
+			}
+
+			logger.Info("Starting instance after evacuation", log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+			err = inst.Start(false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q", inst.Name())
+			}
+
+			continue
+		}
+
+		// Relocated instance.
+		state, _, err := client.UseProject(dbInst.Project).GetInstanceState(dbInst.Name)
+		if err != nil {
+			return err
+		}
+
+		isRunning := state.StatusCode == api.Running
+
+		logger.Info("Moving back instance after evacuation", log.Ctx{"project": dbInst.Project, "instance": dbInst.Name, "source": dbInst.Node})
+		if isRunning {
+			err = clusterEvacuateStopRemote(client, dbInst.Project, dbInst.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to stop instance %q", dbInst.Name)
+			}
+		}
+
+		err = clusterEvacuateMove(client, dbInst.Project, dbInst.Name, name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to move back instance %q", dbInst.Name)
+		}
+
+		if isRunning {
+			err = clusterEvacuateStart(client, dbInst.Project, dbInst.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q", dbInst.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// clusterSetState updates the state of the named cluster member.
+func clusterSetState(d *Daemon, name string, state int) error {
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		node, err := tx.NodeByName(name)
+		if err != nil {
+			return err
+		}
+
+		return tx.NodeUpdateState(node.ID, state)
+	})
+}
+
+// clusterEvacuateClient returns a client connected to the local member, used to move instances
+// through the regular API.
+func clusterEvacuateClient(d *Daemon) (lxd.InstanceServer, error) {
+	var address string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		address, err = tx.NodeAddress()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cluster.Connect(address, d.endpoints.NetworkCert(), true)
+}
+
+// clusterEvacuateMovable returns whether an instance can be relocated to another member, which
+// isn't the case when it uses devices specific to the local member.
+func clusterEvacuateMovable(inst instance.Instance) bool {
+	for _, dev := range inst.ExpandedDevices() {
+		switch dev["type"] {
+		case "unix-char", "unix-block", "usb", "gpu", "infiniband":
+			return false
+		case "nic":
+			if shared.StringInSlice(dev["nictype"], []string{"physical", "sriov"}) {
+				return false
+			}
+		case "disk":
+			if dev["path"] != "/" && dev["pool"] == "" {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// clusterEvacuateStop cleanly stops a local instance, forcing it if it doesn't shutdown in time.
+func clusterEvacuateStop(inst instance.Instance) error {
+	timeout := 30
+	value, ok := inst.ExpandedConfig()["boot.host_shutdown_timeout"]
+	if ok {
+		timeout, _ = strconv.Atoi(value)
+	}
+
+	err := inst.Shutdown(time.Second * time.Duration(timeout))
+	if err != nil {
+		return inst.Stop(false)
+	}
+
+	return nil
+}
+
+// clusterEvacuateStopRemote cleanly stops an instance through the API, forcing it if it doesn't
+// shutdown in time.
+func clusterEvacuateStopRemote(client lxd.InstanceServer, project string, name string) error {
+	op, err := client.UseProject(project).UpdateInstanceState(name, api.InstanceStatePut{Action: "stop", Timeout: 30}, "")
+	if err == nil {
+		err = op.Wait()
+	}
+
+	if err != nil {
+		op, err = client.UseProject(project).UpdateInstanceState(name, api.InstanceStatePut{Action: "stop", Force: true}, "")
+		if err != nil {
+			return err
+		}
+
+		return op.Wait()
+	}
+
+	return nil
+}
+
+// clusterEvacuateStart starts an instance through the API, wherever it's located.
+func clusterEvacuateStart(client lxd.InstanceServer, project string, name string) error {
+	op, err := client.UseProject(project).UpdateInstanceState(name, api.InstanceStatePut{Action: "start", Timeout: -1}, "")
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// clusterEvacuateMove moves a stopped instance to the given cluster member.
+func clusterEvacuateMove(client lxd.InstanceServer, project string, name string, target string) error {
+	op, err := client.UseProject(project).UseTarget(target).MigrateInstance(name, api.InstancePost{Name: name, Migration: true})
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
+// clusterEvacuateSetOrigin records the member an instance was evacuated from.
+func clusterEvacuateSetOrigin(d *Daemon, project string, name string, origin string) error {
+	id, err := d.cluster.ContainerID(project, name)
+	if err != nil {
+		return err
+	}
+
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.ContainerConfigUpdate(id, map[string]string{"volatile.evacuate.origin": origin})
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var clusterGroupsCmd = APIEndpoint{
+	Path: "cluster/groups",
+
+	Get:  APIEndpointAction{Handler: clusterGroupsGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: clusterGroupsPost},
+}
+
+var clusterGroupCmd = APIEndpoint{
+	Path: "cluster/groups/{name}",
+
+	Delete: APIEndpointAction{Handler: clusterGroupDelete},
+	Get:    APIEndpointAction{Handler: clusterGroupGet, AccessHandler: AllowAuthenticated},
+	Put:    APIEndpointAction{Handler: clusterGroupPut},
+}
+
+// API endpoints
+func clusterGroupsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	names, err := d.cluster.ClusterGroups()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, name := range names {
+			urls = append(urls, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, name))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	groups := []*api.ClusterGroup{}
+	for _, name := range names {
+		_, group, err := d.cluster.ClusterGroupGet(name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return response.SyncResponse(true, groups)
+}
+
+func clusterGroupsPost(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	req := api.ClusterGroupsPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") || strings.HasPrefix(req.Name, "@") {
+		return response.BadRequest(fmt.Errorf("Cluster group names may not contain slashes or start with '@'"))
+	}
+
+	_, _, err = d.cluster.ClusterGroupGet(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("The cluster group already exists"))
+	}
+
+	_, err = d.cluster.ClusterGroupCreate(req.Name, req.Description, req.Members)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, req.Name))
+}
+
+func clusterGroupGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	_, group, err := d.cluster.ClusterGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{group.Name, group.Description, group.Members}
+
+	return response.SyncResponseETag(true, group, etag)
+}
+
+func clusterGroupPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, group, err := d.cluster.ClusterGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{group.Name, group.Description, group.Members}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.ClusterGroupPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.ClusterGroupUpdate(id, req.Description, req.Members)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func clusterGroupDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, _, err := d.cluster.ClusterGroupGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Refuse to delete groups which projects are restricted to.
+	var projects []api.Project
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		projects, err = tx.ProjectList(db.ProjectFilter{})
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, project := range projects {
+		if shared.StringInSlice(name, projectConfigList(project.Config["restricted.cluster.groups"])) {
+			return response.BadRequest(fmt.Errorf("The cluster group is used by project %q", project.Name))
+		}
+	}
+
+	err = d.cluster.ClusterGroupDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/resources"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterStateCmd = APIEndpoint{
+	Path: "cluster/state",
+
+	Get: APIEndpointAction{Handler: clusterStateGet, AccessHandler: AllowAuthenticated},
+}
+
+// Return the state of a cluster member. The request is handled by the member itself.
+func clusterNodeStateGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// Forward the request to the member if it isn't the local one.
+	address, err := cluster.ResolveTarget(d.cluster, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address != "" {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	state, err := clusterMemberLocalState(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, state)
+}
+
+// Return the state of all the online cluster members and their totals.
+func clusterStateGet(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	var nodes []db.NodeInfo
+	var localAddress string
+	var offlineThreshold time.Duration
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		localAddress, err = tx.NodeAddress()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.NodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err = tx.Nodes()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	result := api.ClusterState{
+		Members: map[string]api.ClusterMemberState{},
+		SysInfo: api.ClusterMemberSysInfo{LoadAverages: make([]float64, 3)},
+	}
+
+	for _, member := range nodes {
+		if member.IsOffline(offlineThreshold) {
+			continue
+		}
+
+		var state *api.ClusterMemberState
+		if member.Address == localAddress {
+			state, err = clusterMemberLocalState(d)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		} else {
+			client, err := cluster.Connect(member.Address, d.endpoints.NetworkCert(), true)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			state, _, err = client.GetClusterMemberState(member.Name)
+			if err != nil {
+				logger.Warn("Failed to get cluster member state", log.Ctx{"member": member.Name, "err": err})
+				continue
+			}
+		}
+
+		result.Members[member.Name] = *state
+
+		for i := range result.SysInfo.LoadAverages {
+			if i < len(state.SysInfo.LoadAverages) {
+				result.SysInfo.LoadAverages[i] += state.SysInfo.LoadAverages[i]
+			}
+		}
+
+		result.SysInfo.CPUs += state.SysInfo.CPUs
+		result.SysInfo.TotalRAM += state.SysInfo.TotalRAM
+		result.SysInfo.UsedRAM += state.SysInfo.UsedRAM
+		result.RunningInstances += state.RunningInstances
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// clusterMemberLocalState computes the state of the local member from its load averages, memory
+// usage, storage pools and running instances.
+func clusterMemberLocalState(d *Daemon) (*api.ClusterMemberState, error) {
+	content, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("Invalid content of /proc/loadavg")
+	}
+
+	loadAverages := make([]float64, 3)
+	for i := range loadAverages {
+		loadAverages[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	memory, err := resources.GetMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	state := api.ClusterMemberState{
+		SysInfo: api.ClusterMemberSysInfo{
+			LoadAverages: loadAverages,
+			CPUs:         uint64(runtime.NumCPU()),
+			TotalRAM:     memory.Total,
+			UsedRAM:      memory.Used,
+		},
+		StoragePools: map[string]api.ResourcesStoragePool{},
+	}
+
+	pools, err := d.cluster.StoragePoolsNotPending()
+	if err != nil && err != db.ErrNoSuchObject {
+		return nil, err
+	}
+
+	for _, poolName := range pools {
+		res, err := storagePoolResources(d.State(), poolName)
+		if err != nil {
+			logger.Warn("Failed to get storage pool resources", log.Ctx{"pool": poolName, "err": err})
+			continue
+		}
+
+		state.StoragePools[poolName] = *res
+	}
+
+	instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inst := range instances {
+		if inst.IsRunning() {
+			state.RunningInstances++
+		}
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Post: APIEndpointAction{Handler: clusterUpgradePost},
+}
+
+var internalClusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Post: APIEndpointAction{Handler: internalClusterPostUpgrade},
+}
+
+// clusterUpgradeTimeout is how long the coordinator waits for the members of a failure domain to
+// come back with a new version.
+const clusterUpgradeTimeout = 30 * time.Minute
+
+// Start a rolling upgrade of the cluster, one failure domain at a time.
+func clusterUpgradePost(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// The upgrade is coordinated by the leader, which is upgraded last.
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	if localAddress != leader {
+		client, err := cluster.Connect(leader, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	resp := clusterUpgradeCheck(d)
+	if resp != nil {
+		return resp
+	}
+
+	var domains [][]db.NodeInfo
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		domains, err = clusterUpgradeDomains(tx, leader)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		return clusterUpgrade(d, op, domains, leader)
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterUpgrade, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// Upgrade the local member on behalf of the coordinator.
+func internalClusterPostUpgrade(d *Daemon, r *http.Request) response.Response {
+	err := cluster.TriggerUpdate()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterUpgradeDomains splits the members into the failure domains which get upgraded together.
+// Members of the same cluster group, or of the first one alphabetically if they are in several,
+// share a domain while the others are on their own. The leader always comes last, since it
+// coordinates the upgrade of the others.
+func clusterUpgradeDomains(tx *db.ClusterTx, leader string) ([][]db.NodeInfo, error) {
+	offlineThreshold, err := tx.NodeOfflineThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := tx.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := tx.NodesClusterGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	var last []db.NodeInfo
+	names := []string{}
+	members := map[string][]db.NodeInfo{}
+	for _, member := range nodes {
+		if member.IsOffline(offlineThreshold) {
+			return nil, fmt.Errorf("Cluster member %q is offline", member.Name)
+		}
+
+		if member.Address == leader {
+			last = []db.NodeInfo{member}
+			continue
+		}
+
+		// Cluster group names can't start with "@", so this can't clash with a group.
+		domain := "@" + member.Name
+		if len(groups[member.ID]) > 0 {
+			domain = groups[member.ID][0]
+		}
+
+		_, ok := members[domain]
+		if !ok {
+			names = append(names, domain)
+		}
+
+		members[domain] = append(members[domain], member)
+	}
+
+	sort.Strings(names)
+
+	domains := [][]db.NodeInfo{}
+	for _, name := range names {
+		domains = append(domains, members[name])
+	}
+
+	if last != nil {
+		domains = append(domains, last)
+	}
+
+	return domains, nil
+}
+
+// clusterUpgrade upgrades the failure domains in turn, waiting for all the members of a domain to
+// report a new version before moving to the next one. Upgraded members hold off serving the API
+// until the whole cluster runs the new version, so in the meantime the older members keep serving
+// it in a compatible mode.
+func clusterUpgrade(d *Daemon, op *operations.Operation, domains [][]db.NodeInfo, leader string) error {
+	status := map[string]string{}
+	for _, domain := range domains {
+		for _, member := range domain {
+			status[member.Name] = "pending"
+		}
+	}
+
+	updateMetadata := func(i int, names []string) error {
+		members := map[string]string{}
+		for name, value := range status {
+			members[name] = value
+		}
+
+		return op.UpdateMetadata(map[string]interface{}{
+			"upgrade_progress": fmt.Sprintf("%d/%d: %s", i+1, len(domains), strings.Join(names, ", ")),
+			"members":          members,
+		})
+	}
+
+	for i, domain := range domains {
+		names := []string{}
+		for _, member := range domain {
+			names = append(names, member.Name)
+			status[member.Name] = "upgrading"
+		}
+
+		err := updateMetadata(i, names)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Upgrading cluster members", log.Ctx{"members": names})
+
+		// The leader can't wait for itself to come back, its restart completes the upgrade.
+		if len(domain) == 1 && domain[0].Address == leader {
+			return cluster.TriggerUpdate()
+		}
+
+		err = clusterUpgradeDomain(d, domain)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range domain {
+			status[member.Name] = "upgraded"
+		}
+
+		err = updateMetadata(i, names)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterUpgradeDomain triggers the upgrade of the given members and waits for all of them to
+// record their new version in the database.
+func clusterUpgradeDomain(d *Daemon, domain []db.NodeInfo) error {
+	for _, member := range domain {
+		client, err := cluster.Connect(member.Address, d.endpoints.NetworkCert(), true)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = client.RawQuery("POST", "/internal/cluster/upgrade", nil, "")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to upgrade cluster member %q", member.Name)
+		}
+	}
+
+	deadline := time.Now().Add(clusterUpgradeTimeout)
+	for _, member := range domain {
+		for {
+			var current db.NodeInfo
+			err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				var err error
+				current, err = tx.NodeByName(member.Name)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if current.Version() != member.Version() {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("Cluster member %q wasn't upgraded within %s", member.Name, clusterUpgradeTimeout)
+			}
+
+			time.Sleep(5 * time.Second)
+		}
+	}
+
+	return nil
+}
+
+// clusterUpgradeInProgress returns whether a rolling upgrade of the cluster is in progress.
+func clusterUpgradeInProgress(d *Daemon) (bool, error) {
+	var ops []db.Operation
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		ops, err = tx.OperationsOfType(db.OperationClusterUpgrade)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(ops) > 0, nil
+}
+
+// clusterUpgradeCheck refuses changes to the cluster which members running an older version may
+// not cope with while a rolling upgrade is in progress.
+func clusterUpgradeCheck(d *Daemon) response.Response {
+	upgrading, err := clusterUpgradeInProgress(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if upgrading {
+		return response.Conflict(fmt.Errorf("A cluster upgrade is in progress"))
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/s3"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var databaseBackupsCmd = APIEndpoint{
+	Path: "database/backups",
+
+	Post: APIEndpointAction{Handler: databaseBackupsPost},
+}
+
+// databaseBackupFiles are the names of the database copies in a backup tarball.
+var databaseBackupFiles = []string{"global.db", "local.db"}
+
+// Take a backup of the global and local databases while running.
+func databaseBackupsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.DatabaseBackupsPost{}
+	err := shared.ReadToJSON(r.Body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	switch req.Target {
+	case "file":
+		if !filepath.IsAbs(req.Path) {
+			return response.BadRequest(fmt.Errorf("The backup path must be absolute"))
+		}
+
+		if shared.PathExists(req.Path) {
+			return response.BadRequest(fmt.Errorf("The backup path %q already exists", req.Path))
+		}
+	case "s3":
+		if req.S3 == nil || req.S3.Bucket == "" || req.S3.Key == "" {
+			return response.BadRequest(fmt.Errorf("The S3 bucket and key are required"))
+		}
+	default:
+		return response.BadRequest(fmt.Errorf("Invalid backup target %q, must be file or s3", req.Target))
+	}
+
+	run := func(op *operations.Operation) error {
+		return databaseBackup(d, req)
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationDatabaseBackup, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// databaseBackup writes consistent copies of the global and local databases to a tarball, and
+// then sends it to the requested target.
+func databaseBackup(d *Daemon, req api.DatabaseBackupsPost) error {
+	tmpDir, err := ioutil.TempDir(shared.VarPath("backups"), "lxd_database_backup_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = d.cluster.Backup(filepath.Join(tmpDir, "global.db"))
+	if err != nil {
+		return errors.Wrap(err, "Failed to back up the global database")
+	}
+
+	err = d.db.Backup(filepath.Join(tmpDir, "local.db"))
+	if err != nil {
+		return errors.Wrap(err, "Failed to back up the local database")
+	}
+
+	tarball := filepath.Join(tmpDir, "backup.tar.gz")
+	err = databaseBackupTarball(tmpDir, tarball)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create the backup tarball")
+	}
+
+	switch req.Target {
+	case "file":
+		err = shared.FileCopy(tarball, req.Path)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write the backup to %q", req.Path)
+		}
+
+		err = os.Chmod(req.Path, 0600)
+		if err != nil {
+			return err
+		}
+	case "s3":
+		client, err := util.HTTPClient("", d.proxy)
+		if err != nil {
+			return err
+		}
+
+		object := s3.Object{
+			Endpoint:  req.S3.Endpoint,
+			Bucket:    req.S3.Bucket,
+			Key:       req.S3.Key,
+			Region:    req.S3.Region,
+			AccessKey: req.S3.AccessKey,
+			SecretKey: req.S3.SecretKey,
+		}
+
+		err = s3.Upload(client, object, tarball)
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Backed up the databases", log.Ctx{"target": req.Target})
+	return nil
+}
+
+// databaseBackupTarball writes the database copies of a directory to a gzipped tarball.
+func databaseBackupTarball(dir string, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	for _, name := range databaseBackupFiles {
+		err := databaseBackupTarballAdd(tw, filepath.Join(dir, name), name)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = gw.Close()
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func databaseBackupTarballAdd(tw *tar.Writer, path string, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	hdr.Name = name
+
+	err = tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// databaseRestoreDir returns the directory backups to restore on the next start are staged in.
+func databaseRestoreDir(varDir string) string {
+	return filepath.Join(varDir, "database", "restore")
+}
+
+// databaseRestoreLocal replaces the local database with a staged backup, if any. It must be called
+// before the local database is opened.
+func databaseRestoreLocal(varDir string) error {
+	staged := filepath.Join(databaseRestoreDir(varDir), "local.db")
+	if !shared.PathExists(staged) {
+		return nil
+	}
+
+	logger.Info("Restoring local database from backup")
+
+	path := filepath.Join(varDir, "database", "local.db")
+	for _, suffix := range []string{"-wal", "-shm"} {
+		err := os.Remove(path + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(staged, path)
+}
+
+// databaseRestoreGlobal replaces the content of the global database with a staged backup, if any.
+func databaseRestoreGlobal(d *Daemon) error {
+	staged := filepath.Join(databaseRestoreDir(d.os.VarDir), "global.db")
+	if !shared.PathExists(staged) {
+		return nil
+	}
+
+	logger.Info("Restoring global database from backup")
+
+	err := d.cluster.Restore(staged)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(databaseRestoreDir(d.os.VarDir))
+}
@@ -45,11 +45,14 @@ var apiInternal = []APIEndpoint{
 	internalSQLCmd,
 	internalClusterAcceptCmd,
 	internalClusterRebalanceCmd,
+	internalClusterUpgradeCmd,
 	internalClusterAssignCmd,
 	internalClusterContainerMovedCmd,
 	internalGarbageCollectorCmd,
 	internalRAFTSnapshotCmd,
 	internalClusterHandoverCmd,
+	internalRecoverValidateCmd,
+	internalRecoverImportCmd,
 }
 
 var internalShutdownCmd = APIEndpoint{
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+var internalRecoverValidateCmd = APIEndpoint{
+	Path: "recover/validate",
+
+	Post: APIEndpointAction{Handler: internalRecoverValidate},
+}
+
+var internalRecoverImportCmd = APIEndpoint{
+	Path: "recover/import",
+
+	Post: APIEndpointAction{Handler: internalRecoverImport},
+}
+
+// internalRecoverValidatePost is used to list the volumes which can be recovered from the storage pools,
+// Pools being the pools which exist on disk but not in the database.
+type internalRecoverValidatePost struct {
+	Pools []api.StoragePoolsPost `json:"pools" yaml:"pools"`
+}
+
+// internalRecoverValidateVolume is a volume found on a storage pool without database record.
+type internalRecoverValidateVolume struct {
+	Name          string `json:"name" yaml:"name"`
+	Type          string `json:"type" yaml:"type"`
+	Pool          string `json:"pool" yaml:"pool"`
+	Project       string `json:"project" yaml:"project"`
+	SnapshotCount int    `json:"snapshot_count" yaml:"snapshot_count"`
+}
+
+// internalRecoverValidateResult lists the volumes which can be recovered, and the missing entities
+// preventing them to be.
+type internalRecoverValidateResult struct {
+	UnknownVolumes   []internalRecoverValidateVolume `json:"unknown_volumes" yaml:"unknown_volumes"`
+	DependencyErrors []string                        `json:"dependency_errors" yaml:"dependency_errors"`
+}
+
+// internalRecoverImportPost is used to recreate the database records of the volumes found on the
+// storage pools, Pools being the pools which exist on disk but not in the database.
+type internalRecoverImportPost struct {
+	Pools []api.StoragePoolsPost `json:"pools" yaml:"pools"`
+}
+
+// internalRecoverScan holds the volumes without database record found on the storage pools.
+type internalRecoverScan struct {
+	pools      map[string]storagePools.Pool
+	newPools   []api.StoragePoolsPost
+	instances  map[string]map[string][]*backup.InstanceConfig
+	customVols map[string][]string
+	result     internalRecoverValidateResult
+}
+
+func internalRecoverValidate(d *Daemon, r *http.Request) response.Response {
+	req := internalRecoverValidatePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	scan, cleanup, err := internalRecoverScanPools(d, req.Pools)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	defer cleanup()
+
+	return response.SyncResponse(true, scan.result)
+}
+
+func internalRecoverImport(d *Daemon, r *http.Request) response.Response {
+	req := internalRecoverImportPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	scan, cleanup, err := internalRecoverScanPools(d, req.Pools)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The supplied pools stay mounted once recovered.
+	revert := revert.New()
+	defer revert.Fail()
+	revert.Add(cleanup)
+
+	if len(scan.result.DependencyErrors) > 0 {
+		return response.BadRequest(fmt.Errorf("Missing dependencies: %v", scan.result.DependencyErrors))
+	}
+
+	// Create the database records of the pools which only exist on disk.
+	for _, poolInfo := range scan.newPools {
+		_, err := storagePoolDBCreate(d.State(), poolInfo.Name, poolInfo.Description, poolInfo.Driver, poolInfo.Config)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed creating storage pool %q database entry", poolInfo.Name))
+		}
+
+		poolName := poolInfo.Name
+		revert.Add(func() { dbStoragePoolDeleteAndUpdateCache(d.State(), poolName) })
+
+		pool, err := storagePools.GetPoolByName(d.State(), poolName)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		scan.pools[poolName] = pool
+	}
+
+	for poolName, volNames := range scan.customVols {
+		for _, volName := range volNames {
+			err := scan.pools[poolName].ImportCustomVolume(volName, nil, nil)
+			if err != nil {
+				return response.SmartError(errors.Wrapf(err, "Failed importing custom volume %q on pool %q", volName, poolName))
+			}
+		}
+	}
+
+	for poolName, projects := range scan.instances {
+		for projectName, configs := range projects {
+			for _, config := range configs {
+				pool := scan.pools[poolName]
+				err := internalRecoverImportInstance(d, pool, projectName, config, func(inst instance.Instance, volConfig map[string]string) error {
+					return pool.ImportInstance(inst, volConfig, nil)
+				})
+				if err != nil {
+					return response.SmartError(errors.Wrapf(err, "Failed importing instance %q in project %q", config.Container.Name, projectName))
+				}
+			}
+		}
+	}
+
+	revert.Success()
+	return response.EmptySyncResponse
+}
+
+// internalRecoverScanPools loads the storage pools known to the database and the supplied ones, and lists
+// the volumes they have without database record along with their missing dependencies. The returned
+// function needs to be called once done, to unmount the supplied pools which weren't recovered.
+func internalRecoverScanPools(d *Daemon, newPools []api.StoragePoolsPost) (*internalRecoverScan, func(), error) {
+	scan := &internalRecoverScan{
+		pools:      map[string]storagePools.Pool{},
+		newPools:   newPools,
+		instances:  map[string]map[string][]*backup.InstanceConfig{},
+		customVols: map[string][]string{},
+		result: internalRecoverValidateResult{
+			UnknownVolumes:   []internalRecoverValidateVolume{},
+			DependencyErrors: []string{},
+		},
+	}
+
+	poolNames, err := d.cluster.StoragePools()
+	if err != nil && err != db.ErrNoSuchObject {
+		return nil, nil, errors.Wrapf(err, "Failed getting existing storage pools")
+	}
+
+	for _, poolName := range poolNames {
+		pool, err := storagePools.GetPoolByName(d.State(), poolName)
+		if err != nil {
+			// Pools using the legacy storage drivers can't be scanned.
+			if err == storageDrivers.ErrUnknownDriver {
+				logger.Warn("Skipping storage pool with unsupported driver", log.Ctx{"pool": poolName})
+				continue
+			}
+
+			return nil, nil, errors.Wrapf(err, "Failed loading existing storage pool %q", poolName)
+		}
+
+		scan.pools[poolName] = pool
+	}
+
+	// Mount the supplied pools, to check they exist and to scan them.
+	revert := revert.New()
+	defer revert.Fail()
+
+	for i := range newPools {
+		poolInfo := newPools[i]
+
+		if shared.StringInSlice(poolInfo.Name, poolNames) {
+			return nil, nil, fmt.Errorf("Storage pool %q already exists", poolInfo.Name)
+		}
+
+		if poolInfo.Config["source"] == "" {
+			return nil, nil, fmt.Errorf("The source of storage pool %q is required", poolInfo.Name)
+		}
+
+		pool, err := storagePools.GetPoolByInfo(d.State(), &poolInfo)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed loading storage pool %q", poolInfo.Name)
+		}
+
+		err = os.MkdirAll(storageDrivers.GetPoolMountPath(poolInfo.Name), 0711)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ourMount, err := pool.Mount()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed mounting storage pool %q", poolInfo.Name)
+		}
+
+		if ourMount {
+			revert.Add(func() { pool.Unmount() })
+		}
+
+		scan.pools[poolInfo.Name] = pool
+	}
+
+	for poolName, pool := range scan.pools {
+		instances, customVols, err := pool.ListUnknownVolumes(nil)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed checking volumes on storage pool %q", poolName)
+		}
+
+		scan.instances[poolName] = instances
+		scan.customVols[poolName] = customVols
+
+		for projectName, configs := range instances {
+			for _, config := range configs {
+				instType, err := instancetype.New(config.Container.Type)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "Invalid type of instance %q", config.Container.Name)
+				}
+
+				scan.result.UnknownVolumes = append(scan.result.UnknownVolumes, internalRecoverValidateVolume{
+					Name:          config.Container.Name,
+					Type:          instType.String(),
+					Pool:          poolName,
+					Project:       projectName,
+					SnapshotCount: len(config.Snapshots),
+				})
+
+				scan.result.DependencyErrors = append(scan.result.DependencyErrors, internalRecoverDependencyErrors(d, projectName, config)...)
+			}
+		}
+
+		for _, volName := range customVols {
+			scan.result.UnknownVolumes = append(scan.result.UnknownVolumes, internalRecoverValidateVolume{
+				Name:    volName,
+				Type:    db.StoragePoolVolumeTypeNameCustom,
+				Pool:    poolName,
+				Project: "default",
+			})
+		}
+	}
+
+	sort.Slice(scan.result.UnknownVolumes, func(i, j int) bool {
+		a := scan.result.UnknownVolumes[i]
+		b := scan.result.UnknownVolumes[j]
+		return fmt.Sprintf("%s/%s/%s", a.Pool, a.Project, a.Name) < fmt.Sprintf("%s/%s/%s", b.Pool, b.Project, b.Name)
+	})
+
+	cleanup := revert.Clone().Fail
+	revert.Success()
+
+	return scan, cleanup, nil
+}
+
+// internalRecoverDependencyErrors returns the entities an instance depends on which don't exist.
+func internalRecoverDependencyErrors(d *Daemon, projectName string, config *backup.InstanceConfig) []string {
+	depErrors := []string{}
+
+	var projectExists bool
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		projectExists, err = tx.ProjectExists(projectName)
+		return err
+	})
+	if err != nil {
+		return append(depErrors, fmt.Sprintf("Failed checking project %q: %v", projectName, err))
+	}
+
+	if !projectExists {
+		return append(depErrors, fmt.Sprintf("Project %q of instance %q doesn't exist", projectName, config.Container.Name))
+	}
+
+	profiles, err := d.cluster.Profiles(projectName)
+	if err != nil {
+		return append(depErrors, fmt.Sprintf("Failed getting the profiles of project %q: %v", projectName, err))
+	}
+
+	for _, profile := range config.Container.Profiles {
+		if !shared.StringInSlice(profile, profiles) {
+			depErrors = append(depErrors, fmt.Sprintf("Profile %q of instance %q in project %q doesn't exist", profile, config.Container.Name, projectName))
+		}
+	}
+
+	_, err = d.cluster.ContainerID(projectName, config.Container.Name)
+	if err == nil {
+		depErrors = append(depErrors, fmt.Sprintf("Instance %q in project %q already exists on another storage pool", config.Container.Name, projectName))
+	}
+
+	return depErrors
+}
+
+// internalRecoverImportInstance recreates the database records of an instance and of its snapshots from
+// the backup file found in its volume.
+func internalRecoverImportInstance(d *Daemon, pool storagePools.Pool, projectName string, config *backup.InstanceConfig, importVolume func(inst instance.Instance, volConfig map[string]string) error) error {
+	instType, err := instancetype.New(config.Container.Type)
+	if err != nil {
+		return err
+	}
+
+	arch, err := osarch.ArchitectureId(config.Container.Architecture)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// The instance may have been found on a pool recovered under another name.
+	config.Container.Devices = internalRecoverRootDevice(config.Container.Devices, pool.Name())
+
+	inst, err := instanceCreateInternal(d.State(), db.InstanceArgs{
+		Project:      projectName,
+		Architecture: arch,
+		BaseImage:    config.Container.Config["volatile.base_image"],
+		Config:       config.Container.Config,
+		CreationDate: config.Container.CreatedAt,
+		Type:         instType,
+		Description:  config.Container.Description,
+		Devices:      deviceConfig.NewDevices(config.Container.Devices),
+		Ephemeral:    config.Container.Ephemeral,
+		LastUsedDate: config.Container.LastUsedAt,
+		Name:         config.Container.Name,
+		Profiles:     config.Container.Profiles,
+		Stateful:     config.Container.Stateful,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed creating instance record")
+	}
+
+	// Only remove the records on failure, the instance's volume being left untouched.
+	revert.Add(func() { internalRecoverRemoveInstanceRecords(d, pool, projectName, config) })
+
+	for _, snap := range config.Snapshots {
+		snapArch, err := osarch.ArchitectureId(snap.Architecture)
+		if err != nil {
+			return err
+		}
+
+		_, err = instanceCreateInternal(d.State(), db.InstanceArgs{
+			Project:      projectName,
+			Architecture: snapArch,
+			BaseImage:    snap.Config["volatile.base_image"],
+			Config:       snap.Config,
+			CreationDate: snap.CreatedAt,
+			Type:         instType,
+			Snapshot:     true,
+			Devices:      deviceConfig.NewDevices(internalRecoverRootDevice(snap.Devices, pool.Name())),
+			Ephemeral:    snap.Ephemeral,
+			LastUsedDate: snap.LastUsedAt,
+			Name:         fmt.Sprintf("%s%s%s", config.Container.Name, shared.SnapshotDelimiter, snap.Name),
+			Profiles:     snap.Profiles,
+			Stateful:     snap.Stateful,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed creating snapshot %q record", snap.Name)
+		}
+	}
+
+	var volConfig map[string]string
+	if config.Volume != nil {
+		volConfig = config.Volume.Config
+	}
+
+	err = importVolume(inst, volConfig)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// internalRecoverRemoveInstanceRecords removes the database records of an instance being recovered and of
+// its snapshots, leaving its volume untouched.
+func internalRecoverRemoveInstanceRecords(d *Daemon, pool storagePools.Pool, projectName string, config *backup.InstanceConfig) {
+	volDBType := db.StoragePoolVolumeTypeContainer
+	if config.Container.Type == string(api.InstanceTypeVM) {
+		volDBType = db.StoragePoolVolumeTypeVM
+	}
+
+	names := []string{config.Container.Name}
+	for _, snap := range config.Snapshots {
+		names = append(names, fmt.Sprintf("%s%s%s", config.Container.Name, shared.SnapshotDelimiter, snap.Name))
+	}
+
+	for _, name := range names {
+		d.cluster.InstanceRemove(projectName, name)
+		d.cluster.StoragePoolVolumeDelete(projectName, name, volDBType, pool.ID())
+	}
+}
+
+// internalRecoverRootDevice returns the devices with their root disk device using the pool, adding it if
+// missing.
+func internalRecoverRootDevice(devices map[string]map[string]string, poolName string) map[string]map[string]string {
+	if devices == nil {
+		devices = map[string]map[string]string{}
+	}
+
+	rootDevName, _, err := shared.GetRootDiskDevice(devices)
+	if err == nil {
+		devices[rootDevName]["pool"] = poolName
+		return devices
+	}
+
+	rootDevName = "root"
+	for i := 0; i < 100; i++ {
+		if devices[rootDevName] == nil {
+			break
+		}
+
+		rootDevName = fmt.Sprintf("root%d", i)
+	}
+
+	devices[rootDevName] = map[string]string{
+		"type": "disk",
+		"path": "/",
+		"pool": poolName,
+	}
+
+	return devices
+}
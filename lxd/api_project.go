@@ -12,11 +12,17 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/version"
 )
 
@@ -92,10 +98,36 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 	// Parse the request
 	project := api.ProjectsPost{}
 
-	// Set default features
+	err := json.NewDecoder(r.Body).Decode(&project)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	if project.Config == nil {
 		project.Config = map[string]string{}
 	}
+
+	// Start from the configuration of the template, if any
+	var template *api.Project
+	if project.Template != "" {
+		template, err = projectLoad(d.cluster, project.Template)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed to load template project %q", project.Template))
+		}
+
+		if project.Description == "" {
+			project.Description = template.Description
+		}
+
+		for key, value := range template.Config {
+			_, ok := project.Config[key]
+			if !ok {
+				project.Config[key] = value
+			}
+		}
+	}
+
+	// Set default features
 	for _, feature := range []string{"features.images", "features.profiles"} {
 		_, ok := project.Config[feature]
 		if !ok {
@@ -103,11 +135,6 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	err := json.NewDecoder(r.Body).Decode(&project)
-	if err != nil {
-		return response.BadRequest(err)
-	}
-
 	// Sanity checks
 	if project.Name == "" {
 		return response.BadRequest(fmt.Errorf("No name provided"))
@@ -139,9 +166,19 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		if project.Config["features.profiles"] == "true" {
-			err = projectCreateDefaultProfile(tx, project.Name)
-			if err != nil {
-				return err
+			copied := false
+			if template != nil && shared.IsTrue(template.Config["features.profiles"]) {
+				copied, err = projectCopyProfiles(tx, template.Name, project.Name)
+				if err != nil {
+					return err
+				}
+			}
+
+			if !copied {
+				err = projectCreateDefaultProfile(tx, project.Name)
+				if err != nil {
+					return err
+				}
 			}
 
 			if project.Config["features.images"] == "false" {
@@ -183,6 +220,33 @@ func projectCreateDefaultProfile(tx *db.ClusterTx, project string) error {
 	return nil
 }
 
+// Copy the profiles of a template project to a new project, returning whether it got a default
+// profile that way.
+func projectCopyProfiles(tx *db.ClusterTx, template string, project string) (bool, error) {
+	profiles, err := tx.ProfileList(db.ProfileFilter{Project: template})
+	if err != nil {
+		return false, errors.Wrap(err, "Load profiles of template project")
+	}
+
+	copiedDefault := false
+	for _, profile := range profiles {
+		profile.ID = 0
+		profile.Project = project
+		profile.UsedBy = nil
+
+		_, err := tx.ProfileCreate(profile)
+		if err != nil {
+			return false, errors.Wrapf(err, "Add profile %q to database", profile.Name)
+		}
+
+		if profile.Name == "default" {
+			copiedDefault = true
+		}
+	}
+
+	return copiedDefault, nil
+}
+
 func projectGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -333,12 +397,34 @@ func projectChange(d *Daemon, project *api.Project, req api.ProjectPut) response
 		return response.BadRequest(fmt.Errorf("Features can only be changed on empty projects"))
 	}
 
+	// Existing containers would keep their current range, so only allow this on empty projects.
+	if !projectIsEmpty(project) && shared.IsTrue(req.Config["security.idmap.isolated"]) != shared.IsTrue(project.Config["security.idmap.isolated"]) {
+		return response.BadRequest(fmt.Errorf("Idmap isolation can only be changed on empty projects"))
+	}
+
 	// Validate the configuration
 	err := projectValidateConfig(req.Config)
 	if err != nil {
 		return response.BadRequest(err)
 	}
 
+	// The volumes of the project must already fit in a new disk limit.
+	if req.Config["limits.disk"] != "" && req.Config["limits.disk"] != project.Config["limits.disk"] {
+		limit, err := units.ParseByteSizeString(req.Config["limits.disk"])
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		usage, err := storagePools.ProjectDiskUsage(d.State(), project.Name)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if usage > limit {
+			return response.BadRequest(fmt.Errorf("The project's volumes already use %s, more than limits.disk", units.GetByteSizeString(usage, 2)))
+		}
+	}
+
 	// Update the database entry
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		err := tx.ProjectUpdate(project.Name, req)
@@ -390,8 +476,24 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 
 	// Perform the rename
 	run := func(op *operations.Operation) error {
+		// The volumes of the instances are named after the project, so they must be stopped
+		// and kept from being started during the rename.
+		instances, err := instanceLoadByProject(d.State(), name)
+		if err != nil {
+			return err
+		}
+
+		locks, err := instanceMoveProjectLock(instances)
+		if err != nil {
+			return err
+		}
+		defer instanceMoveProjectUnlock(locks)
+
+		revert := revert.New()
+		defer revert.Fail()
+
 		var id int64
-		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			project, err := tx.ProjectGet(req.Name)
 			if err != nil && err != db.ErrNoSuchObject {
 				return errors.Wrapf(err, "Check if project %q exists", req.Name)
@@ -401,13 +503,15 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 				return fmt.Errorf("A project named '%s' already exists", req.Name)
 			}
 
-			project, err = tx.ProjectGet(name)
-			if err != nil {
-				return errors.Wrapf(err, "Fetch project %q", name)
-			}
+			if len(instances) > 0 {
+				count, err := tx.NodesCount()
+				if err != nil {
+					return errors.Wrap(err, "Failed to count cluster members")
+				}
 
-			if !projectIsEmpty(project) {
-				return fmt.Errorf("Only empty projects can be renamed")
+				if count > 1 {
+					return fmt.Errorf("Projects with instances can't be renamed in a cluster")
+				}
 			}
 
 			id, err = tx.ProjectID(name)
@@ -415,12 +519,66 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 				return errors.Wrapf(err, "Fetch project id %q", name)
 			}
 
-			return tx.ProjectRename(name, req.Name)
+			err = tx.ProjectRename(name, req.Name)
+			if err != nil {
+				return err
+			}
+
+			return tx.ProjectRenameReferences(name, req.Name)
 		})
 		if err != nil {
 			return err
 		}
 
+		// Rename the project back in the database first, then the files of the instances
+		// which got renamed.
+		moved := []string{}
+		revert.Add(func() {
+			err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				err := tx.ProjectRename(req.Name, name)
+				if err != nil {
+					return err
+				}
+
+				return tx.ProjectRenameReferences(req.Name, name)
+			})
+			if err != nil {
+				logger.Error("Failed to revert project rename", log.Ctx{"project": req.Name, "err": err})
+				return
+			}
+
+			for _, instName := range moved {
+				inst, err := instance.LoadByProjectAndName(d.State(), name, instName)
+				if err == nil {
+					err = instanceMoveProjectFiles(d.State(), inst, req.Name, op)
+				}
+
+				if err != nil {
+					logger.Error("Failed to revert instance project rename", log.Ctx{"project": name, "instance": instName, "err": err})
+				}
+			}
+		})
+
+		// Rename the volumes, backups and logs of the instances too.
+		for _, inst := range instances {
+			renamed, err := instance.LoadByProjectAndName(d.State(), req.Name, inst.Name())
+			if err != nil {
+				return err
+			}
+
+			// Extend the instance locks, as renaming the volumes may take a while.
+			for _, lock := range locks {
+				lock.Reset()
+			}
+
+			err = instanceMoveProjectFiles(d.State(), renamed, name, op)
+			if err != nil {
+				return err
+			}
+
+			moved = append(moved, inst.Name())
+		}
+
 		if d.rbac != nil {
 			err = d.rbac.RenameProject(id, req.Name)
 			if err != nil {
@@ -428,6 +586,12 @@ func projectPost(d *Daemon, r *http.Request) response.Response {
 			}
 		}
 
+		revert.Success()
+
+		if len(instances) > 0 {
+			networkUpdateStatic(d.State(), "")
+		}
+
 		return nil
 	}
 
@@ -493,8 +657,18 @@ func projectIsEmpty(project *api.Project) bool {
 
 // Validate the project configuration
 var projectConfigKeys = map[string]func(value string) error{
-	"features.profiles": shared.IsBool,
-	"features.images":   shared.IsBool,
+	"features.profiles":             shared.IsBool,
+	"features.images":               shared.IsBool,
+	"limits.disk":                   shared.IsSize,
+	"limits.networks":               shared.IsUint32,
+	"restricted":                    shared.IsBool,
+	"restricted.cluster.groups":     shared.IsAny,
+	"restricted.devices.nic":        projectValidNICTypes,
+	"restricted.networks.addresses": projectValidSubnets,
+	"restricted.networks.mirrors":   shared.IsAny,
+	"restricted.networks.subnets":   projectValidSubnets,
+	"restricted.networks.uplinks":   shared.IsAny,
+	"security.idmap.isolated":       shared.IsBool,
 }
 
 func projectValidateConfig(config map[string]string) error {
@@ -506,6 +680,16 @@ func projectValidateConfig(config map[string]string) error {
 			continue
 		}
 
+		// Volume defaults are storage volume keys
+		if strings.HasPrefix(key, "volume.") {
+			err := storagePools.ValidateVolumeDefault(strings.TrimPrefix(key, "volume."), v)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		// Then validate
 		validator, ok := projectConfigKeys[key]
 		if !ok {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+)
+
+// rateLimitKey returns the key the requests of a client are limited under. Trusted clients are
+// told apart by their identity, the others by their address.
+func rateLimitKey(r *http.Request, trusted bool, username string, protocol string) string {
+	if trusted {
+		return protocol + "/" + username
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip/" + host
+}
+
+// rateLimitLongLived returns whether the request is held open for a while, like event streams and
+// operation waits, and so doesn't count towards the concurrency limit.
+func rateLimitLongLived(r *http.Request, path string) bool {
+	if shared.StringInSlice(path, []string{"events", "operations/{id}/wait", "operations/{id}/websocket"}) {
+		return true
+	}
+
+	return r.Header.Get("Upgrade") == "websocket"
+}
+
+// rateLimitReject renders the response to a request over the rate limits, telling the client how
+// many seconds to wait before retrying.
+func rateLimitReject(w http.ResponseWriter, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	response.TooManyRequests(err).Render(w)
+}
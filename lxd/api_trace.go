@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/trace"
+)
+
+// traceRequest starts the span of an API request. It returns the request holding the span and a
+// response writer recording the status code, or nils when tracing is disabled.
+func traceRequest(w http.ResponseWriter, r *http.Request, route string) (*traceResponseWriter, *http.Request, *trace.Span) {
+	ctx, span := trace.StartServer(r.Context(), fmt.Sprintf("%s %s", r.Method, route), r.Header)
+	if span == nil {
+		return nil, r, nil
+	}
+
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("http.target", r.URL.RequestURI())
+	span.SetAttribute("net.peer.ip", r.RemoteAddr)
+
+	return &traceResponseWriter{ResponseWriter: w, status: http.StatusOK}, r.WithContext(ctx), span
+}
+
+// traceFinish ends the span of an API request, marking it as failed on server errors.
+func traceFinish(span *trace.Span, w *traceResponseWriter) {
+	span.SetAttribute("http.status_code", strconv.Itoa(w.status))
+
+	var err error
+	if w.status >= http.StatusInternalServerError {
+		err = fmt.Errorf("%s", http.StatusText(w.status))
+	}
+
+	span.End(err)
+}
+
+// traceOperation makes the operation started by an async response part of the trace of the
+// request.
+func traceOperation(ctx context.Context, resp response.Response) {
+	traced, ok := resp.(interface{ SetTraceContext(ctx context.Context) })
+	if ok {
+		traced.SetTraceContext(ctx)
+	}
+}
+
+// traceResponseWriter records the status code of a response.
+type traceResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *traceResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack lets websocket connections be upgraded through the writer.
+func (w *traceResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("The response writer doesn't support hijacking")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
@@ -600,6 +600,9 @@ func getAAProfileContent(c instance) string {
 		profile += strings.TrimLeft(profileUnprivileged, "\n")
 	}
 
+	// Append security.apparmor.allow/deny
+	profile += getRulesContent(c.ExpandedConfig())
+
 	// Append raw.apparmor
 	rawApparmor, ok := c.ExpandedConfig()["raw.apparmor"]
 	if ok {
@@ -0,0 +1,131 @@
+package apparmor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rulePermissions is the set of file permissions accepted in security.apparmor.allow/deny.
+const rulePermissions = "rwaklm"
+
+// ValidateRaw checks that a raw.apparmor value can't break out of the generated profile or leave it
+// unterminated, which is what its braces could do. Anything else is left to apparmor_parser, as
+// rules may span several lines.
+func ValidateRaw(raw string) error {
+	depth := 0
+	for _, line := range strings.Split(raw, "\n") {
+		// Ignore comments, including the ones following a rule. Includes look like
+		// comments but have no braces.
+		fields := strings.SplitN(line, "#", 2)
+		if len(fields) == 2 && (fields[0] == "" || strings.HasSuffix(fields[0], " ") || strings.HasSuffix(fields[0], "\t")) {
+			line = fields[0]
+		}
+
+		for _, r := range line {
+			switch r {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth < 0 {
+					return fmt.Errorf("Invalid raw.apparmor line, unbalanced braces: %s", strings.TrimSpace(line))
+				}
+			}
+		}
+	}
+
+	if depth != 0 {
+		return fmt.Errorf("Invalid raw.apparmor, unbalanced braces")
+	}
+
+	return nil
+}
+
+// ValidateRules validates a security.apparmor.allow or security.apparmor.deny value.
+func ValidateRules(value string) error {
+	_, err := parseRules(value)
+	return err
+}
+
+// parseRules parses a newline separated list of "<path> <permissions>" file rules.
+func parseRules(value string) ([][2]string, error) {
+	rules := [][2]string{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Invalid AppArmor rule %q, expected \"<path> <permissions>\"", line)
+		}
+
+		path := fields[0]
+		perms := fields[1]
+
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("Invalid AppArmor rule %q, path must be absolute", line)
+		}
+
+		if strings.ContainsAny(path, "\"#") {
+			return nil, fmt.Errorf("Invalid AppArmor rule %q, path contains invalid characters", line)
+		}
+
+		// Commas are only allowed as alternation separators within braces.
+		depth := 0
+		for _, r := range path {
+			switch r {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth < 0 {
+					return nil, fmt.Errorf("Invalid AppArmor rule %q, unbalanced braces in path", line)
+				}
+			case ',':
+				if depth == 0 {
+					return nil, fmt.Errorf("Invalid AppArmor rule %q, path contains invalid characters", line)
+				}
+			}
+		}
+
+		if depth != 0 {
+			return nil, fmt.Errorf("Invalid AppArmor rule %q, unbalanced braces in path", line)
+		}
+
+		for _, perm := range perms {
+			if !strings.ContainsRune(rulePermissions, perm) {
+				return nil, fmt.Errorf("Invalid AppArmor rule %q, permissions must be a combination of %q", line, rulePermissions)
+			}
+		}
+
+		rules = append(rules, [2]string{path, perms})
+	}
+
+	return rules, nil
+}
+
+// getRulesContent renders the security.apparmor.allow/deny rules of the instance.
+func getRulesContent(config map[string]string) string {
+	content := ""
+
+	// Invalid values are rejected when the config is set, skip them here rather than failing.
+	allow, err := parseRules(config["security.apparmor.allow"])
+	if err == nil && len(allow) > 0 {
+		content += "\n  ### Configuration: security.apparmor.allow\n"
+		for _, rule := range allow {
+			content += fmt.Sprintf("  %s %s,\n", rule[0], rule[1])
+		}
+	}
+
+	deny, err := parseRules(config["security.apparmor.deny"])
+	if err == nil && len(deny) > 0 {
+		content += "\n  ### Configuration: security.apparmor.deny\n"
+		for _, rule := range deny {
+			content += fmt.Sprintf("  deny %s %s,\n", rule[0], rule[1])
+		}
+	}
+
+	return content
+}
@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Sinks lists the destinations audit entries can be sent to.
+var Sinks = []string{"file", "syslog", "webhook"}
+
+// bufferSize is how many of the most recent entries are kept in memory to be queried.
+const bufferSize = 1000
+
+// Logger records audit entries to the configured sinks and keeps the most recent ones in memory.
+type Logger struct {
+	path string
+
+	sinks   []string
+	webhook string
+	file    *os.File
+	syslog  *syslog.Writer
+	client  *http.Client
+
+	entries []api.AuditEntry
+	next    int
+
+	// Called with every recorded entry, if set.
+	hook func(entry api.AuditEntry)
+
+	lock sync.Mutex
+}
+
+// NewLogger returns a new audit logger, which uses the given path for its file sink.
+func NewLogger(path string) *Logger {
+	return &Logger{
+		path:   path,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Configure sets the sinks entries are sent to and the URL of the webhook sink. Auditing is
+// disabled when no sink is set.
+func (l *Logger) Configure(sinks []string, webhook string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, sink := range sinks {
+		if !shared.StringInSlice(sink, Sinks) {
+			return fmt.Errorf("Invalid audit sink %q", sink)
+		}
+	}
+
+	if shared.StringInSlice("webhook", sinks) && webhook == "" {
+		return fmt.Errorf("The webhook audit sink requires a webhook URL")
+	}
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	if l.syslog != nil {
+		l.syslog.Close()
+		l.syslog = nil
+	}
+
+	if shared.StringInSlice("file", sinks) {
+		file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+
+		l.file = file
+	}
+
+	if shared.StringInSlice("syslog", sinks) {
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTHPRIV, "lxd-audit")
+		if err != nil {
+			return err
+		}
+
+		l.syslog = writer
+	}
+
+	l.sinks = sinks
+	l.webhook = webhook
+
+	return nil
+}
+
+// SetHook sets a function called with every recorded entry, once redacted.
+func (l *Logger) SetHook(hook func(entry api.AuditEntry)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.hook = hook
+}
+
+// Enabled returns whether requests should be audited.
+func (l *Logger) Enabled() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return len(l.sinks) > 0
+}
+
+// Record redacts the secrets of the given entry and sends it to the configured sinks.
+func (l *Logger) Record(entry api.AuditEntry) {
+	entry.Before = Redact(entry.Before)
+	entry.After = Redact(entry.After)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warn("Failed to encode audit entry", log.Ctx{"err": err})
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.entries) < bufferSize {
+		l.entries = append(l.entries, entry)
+	} else {
+		l.entries[l.next] = entry
+	}
+
+	l.next = (l.next + 1) % bufferSize
+
+	if l.file != nil {
+		_, err := l.file.Write(append(data, '\n'))
+		if err != nil {
+			logger.Warn("Failed to write audit entry", log.Ctx{"path": l.path, "err": err})
+		}
+	}
+
+	if l.syslog != nil {
+		err := l.syslog.Info(string(data))
+		if err != nil {
+			logger.Warn("Failed to send audit entry to syslog", log.Ctx{"err": err})
+		}
+	}
+
+	if shared.StringInSlice("webhook", l.sinks) {
+		go l.post(l.webhook, data)
+	}
+
+	if l.hook != nil {
+		l.hook(entry)
+	}
+}
+
+// post sends an entry to the webhook sink.
+func (l *Logger) post(url string, data []byte) {
+	resp, err := l.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		logger.Warn("Failed to send audit entry to webhook", log.Ctx{"url": url, "err": err})
+		return
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logger.Warn("Webhook rejected audit entry", log.Ctx{"url": url, "status": resp.Status})
+	}
+}
+
+// Entries returns up to limit of the most recent entries, newest first, optionally restricted to
+// a project. A limit of zero or less returns all the entries kept in memory.
+func (l *Logger) Entries(project string, limit int) []api.AuditEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entries := []api.AuditEntry{}
+	for i := 1; i <= len(l.entries); i++ {
+		entry := l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if project != "" && entry.Project != project {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+	}
+
+	return entries
+}
+
+// Redact returns a copy of the given decoded JSON value with the values of keys which may hold
+// secrets, such as passwords or private keys, replaced.
+func Redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			if isSecret(k) {
+				redacted[k] = "***"
+				continue
+			}
+
+			redacted[k] = Redact(v)
+		}
+
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, v := range value {
+			redacted[i] = Redact(v)
+		}
+
+		return redacted
+	default:
+		return value
+	}
+}
+
+// isSecret returns whether the given key may hold a secret.
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "private_key", "api.key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package audit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestLogger_Entries(t *testing.T) {
+	logger, cleanup := newLogger(t)
+	defer cleanup()
+
+	require.NoError(t, logger.Configure([]string{"file"}, ""))
+	assert.True(t, logger.Enabled())
+
+	logger.Record(api.AuditEntry{Method: "POST", Resource: "/1.0/instances", Project: "default"})
+	logger.Record(api.AuditEntry{Method: "PUT", Resource: "/1.0/instances/c1", Project: "p1"})
+	logger.Record(api.AuditEntry{Method: "DELETE", Resource: "/1.0/instances/c1", Project: "p1"})
+
+	entries := logger.Entries("", 2)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DELETE", entries[0].Method)
+	assert.Equal(t, "PUT", entries[1].Method)
+
+	entries = logger.Entries("default", 0)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "POST", entries[0].Method)
+}
+
+func TestLogger_ConfigureInvalid(t *testing.T) {
+	logger, cleanup := newLogger(t)
+	defer cleanup()
+
+	assert.Error(t, logger.Configure([]string{"stdout"}, ""))
+	assert.Error(t, logger.Configure([]string{"webhook"}, ""))
+	assert.False(t, logger.Enabled())
+}
+
+func TestRedact(t *testing.T) {
+	value := map[string]interface{}{
+		"password": "secret",
+		"config": map[string]interface{}{
+			"core.trust_password": "secret",
+			"core.https_address":  ":8443",
+		},
+	}
+
+	redacted := audit.Redact(value).(map[string]interface{})
+	assert.Equal(t, "***", redacted["password"])
+
+	config := redacted["config"].(map[string]interface{})
+	assert.Equal(t, "***", config["core.trust_password"])
+	assert.Equal(t, ":8443", config["core.https_address"])
+
+	// The original value is left untouched.
+	assert.Equal(t, "secret", value["password"])
+}
+
+// Return a new audit logger with its file sink in a temporary directory.
+func newLogger(t *testing.T) (*audit.Logger, func()) {
+	dir, err := ioutil.TempDir("", "lxd-audit-test-")
+	require.NoError(t, err)
+
+	return audit.NewLogger(filepath.Join(dir, "audit.log")), func() { os.RemoveAll(dir) }
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Roles lists the roles which can be granted, from the least to the most privileged.
+var Roles = []string{"auditor", "user", "operator", "admin"}
+
+// rolePermissions maps each role to the project permissions it grants, which are the same as
+// the ones of the external RBAC service.
+var rolePermissions = map[string][]string{
+	"auditor":  {"view"},
+	"user":     {"view", "operate-containers"},
+	"operator": {"view", "operate-containers", "manage-containers", "manage-images", "manage-profiles", "manage-networks"},
+	"admin":    {"view", "operate-containers", "manage-containers", "manage-images", "manage-profiles", "manage-networks", "manage-projects"},
+}
+
+// ValidatePermission checks that a permission grants a known role on a valid scope.
+func ValidatePermission(permission api.AuthPermission) error {
+	if !shared.StringInSlice(permission.Role, Roles) {
+		return fmt.Errorf("Invalid role %q, must be one of: auditor, user, operator, admin", permission.Role)
+	}
+
+	if permission.Instance != "" {
+		if permission.Project == "" {
+			return fmt.Errorf("Permissions on an instance require its project")
+		}
+
+		if permission.Role == "admin" {
+			return fmt.Errorf("The admin role can't be granted on an instance")
+		}
+	}
+
+	return nil
+}
+
+// IsAdmin returns whether the given permissions grant full access to the server.
+func IsAdmin(permissions []api.AuthPermission) bool {
+	for _, permission := range permissions {
+		if permission.Role == "admin" && permission.Project == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission returns whether the given permissions grant a project permission, such as
+// "view" or "manage-containers", on a project or, when instance isn't empty, on one of its
+// instances.
+func HasPermission(permissions []api.AuthPermission, project string, instance string, permission string) bool {
+	for _, p := range permissions {
+		if p.Project != "" && p.Project != project {
+			continue
+		}
+
+		if p.Instance != "" && p.Instance != instance {
+			continue
+		}
+
+		if shared.StringInSlice(permission, rolePermissions[p.Role]) {
+			return true
+		}
+	}
+
+	return false
+}
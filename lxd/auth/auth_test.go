@@ -0,0 +1,67 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/auth"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestValidatePermission(t *testing.T) {
+	assert.NoError(t, auth.ValidatePermission(api.AuthPermission{Role: "admin"}))
+	assert.NoError(t, auth.ValidatePermission(api.AuthPermission{Role: "user", Project: "p1", Instance: "c1"}))
+	assert.Error(t, auth.ValidatePermission(api.AuthPermission{Role: "root"}))
+	assert.Error(t, auth.ValidatePermission(api.AuthPermission{Role: "user", Instance: "c1"}))
+	assert.Error(t, auth.ValidatePermission(api.AuthPermission{Role: "admin", Project: "p1", Instance: "c1"}))
+}
+
+func TestHasPermission(t *testing.T) {
+	permissions := []api.AuthPermission{
+		{Role: "auditor"},
+		{Role: "operator", Project: "p1"},
+		{Role: "user", Project: "p2", Instance: "c1"},
+	}
+
+	assert.False(t, auth.IsAdmin(permissions))
+
+	// Server-wide roles apply to all projects.
+	assert.True(t, auth.HasPermission(permissions, "default", "", "view"))
+	assert.False(t, auth.HasPermission(permissions, "default", "", "manage-containers"))
+
+	// Project roles only apply to their project.
+	assert.True(t, auth.HasPermission(permissions, "p1", "", "manage-containers"))
+	assert.True(t, auth.HasPermission(permissions, "p1", "", "manage-networks"))
+	assert.False(t, auth.HasPermission(permissions, "p1", "", "manage-projects"))
+
+	// Instance roles only apply to their instance.
+	assert.True(t, auth.HasPermission(permissions, "p2", "c1", "operate-containers"))
+	assert.False(t, auth.HasPermission(permissions, "p2", "c2", "operate-containers"))
+	assert.False(t, auth.HasPermission(permissions, "p2", "", "operate-containers"))
+
+	assert.True(t, auth.IsAdmin(append(permissions, api.AuthPermission{Role: "admin"})))
+	assert.False(t, auth.IsAdmin([]api.AuthPermission{{Role: "admin", Project: "p1"}}))
+}
+
+func TestTokenAllows(t *testing.T) {
+	token := &api.AuthToken{}
+
+	token.Capability = "read-only"
+	assert.True(t, auth.TokenAllows(token, "GET", "instances/{name}"))
+	assert.False(t, auth.TokenAllows(token, "POST", "instances/{name}/exec"))
+
+	token.Capability = "exec-only"
+	assert.True(t, auth.TokenAllows(token, "POST", "containers/{name}/exec"))
+	assert.True(t, auth.TokenAllows(token, "DELETE", "operations/{id}"))
+	assert.False(t, auth.TokenAllows(token, "PUT", "instances/{name}/state"))
+
+	token.Capability = "full"
+	assert.True(t, auth.TokenAllows(token, "DELETE", "instances/{name}"))
+	assert.True(t, auth.TokenIsAdmin(token))
+
+	token.Projects = []string{"p1"}
+	assert.False(t, auth.TokenIsAdmin(token))
+	assert.True(t, auth.TokenHasProject(token, "p1"))
+	assert.False(t, auth.TokenHasProject(token, "default"))
+}
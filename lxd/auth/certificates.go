@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// CertificateTypes lists the types of trusted client certificates. Client certificates have full
+// access to the server, viewer and operator ones being restricted to the requests their role
+// allows within their projects.
+var CertificateTypes = []string{"client", "viewer", "operator"}
+
+// certificateOperatorActions lists the requests operator certificates can make on top of the GET
+// ones, as method and endpoint path.
+var certificateOperatorActions = [][2]string{
+	{"PUT", "instances"},
+	{"PUT", "instances/{name}/state"},
+	{"POST", "instances/{name}/exec"},
+	{"POST", "instances/{name}/console"},
+	{"POST", "instances/{name}/snapshots"},
+	{"DELETE", "operations/{id}"},
+}
+
+// certificateViewerExcludedReads lists the GET requests viewer certificates can't make, as they
+// expose the content of instances and images rather than their state. Operators can make them, as
+// they can run commands in instances anyway.
+var certificateViewerExcludedReads = []string{
+	"instances/{name}/files",
+	"instances/{name}/logs",
+	"instances/{name}/logs/{file}",
+	"instances/{name}/console",
+	"instances/{name}/backups/{backupName}/export",
+	"images/{fingerprint}/export",
+}
+
+// ValidateCertificate checks the type and projects of a trusted certificate.
+func ValidateCertificate(certType string, projects []string) error {
+	if !shared.StringInSlice(certType, CertificateTypes) {
+		return fmt.Errorf("Invalid certificate type %q, must be one of: %s", certType, strings.Join(CertificateTypes, ", "))
+	}
+
+	if certType == "client" && len(projects) > 0 {
+		return fmt.Errorf("Only viewer and operator certificates can be restricted to projects")
+	}
+
+	for _, project := range projects {
+		if project == "" {
+			return fmt.Errorf("Empty project name")
+		}
+	}
+
+	return nil
+}
+
+// CertificateIsRestricted returns whether a certificate type is restricted to the requests of
+// its role.
+func CertificateIsRestricted(certType string) bool {
+	return certType != "client"
+}
+
+// CertificateAllows returns whether a certificate type allows a request to the endpoint with the
+// given path. Viewers can only use GET requests, except the ones reading the files, logs, console
+// and backups of instances or exporting images, and operators can also make those, change the
+// state of instances, run commands, attach to their console, take snapshots and cancel operations.
+func CertificateAllows(certType string, method string, path string) bool {
+	if !CertificateIsRestricted(certType) {
+		return true
+	}
+
+	// The container and virtual machine endpoints are aliases of the instance ones.
+	for _, prefix := range []string{"containers", "virtual-machines"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			path = "instances" + strings.TrimPrefix(path, prefix)
+		}
+	}
+
+	if shared.StringInSlice(method, []string{"GET", "HEAD"}) {
+		return certType == "operator" || !shared.StringInSlice(path, certificateViewerExcludedReads)
+	}
+
+	if certType != "operator" {
+		return false
+	}
+
+	for _, action := range certificateOperatorActions {
+		if method == action[0] && path == action[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CertificateHasProject returns whether a certificate restricted to the given projects grants
+// access to a project, no projects meaning all of them.
+func CertificateHasProject(projects []string, project string) bool {
+	return len(projects) == 0 || shared.StringInSlice(project, projects)
+}
@@ -0,0 +1,43 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/auth"
+)
+
+func TestValidateCertificate(t *testing.T) {
+	assert.NoError(t, auth.ValidateCertificate("client", nil))
+	assert.NoError(t, auth.ValidateCertificate("viewer", []string{"p1", "p2"}))
+	assert.Error(t, auth.ValidateCertificate("admin", nil))
+	assert.Error(t, auth.ValidateCertificate("client", []string{"p1"}))
+	assert.Error(t, auth.ValidateCertificate("operator", []string{""}))
+}
+
+func TestCertificateAllows(t *testing.T) {
+	assert.True(t, auth.CertificateAllows("client", "DELETE", "instances/{name}"))
+
+	assert.True(t, auth.CertificateAllows("viewer", "GET", "instances/{name}"))
+	assert.False(t, auth.CertificateAllows("viewer", "PUT", "instances/{name}/state"))
+	assert.False(t, auth.CertificateAllows("viewer", "GET", "instances/{name}/files"))
+	assert.False(t, auth.CertificateAllows("viewer", "GET", "containers/{name}/logs/{file}"))
+	assert.False(t, auth.CertificateAllows("viewer", "GET", "virtual-machines/{name}/backups/{backupName}/export"))
+	assert.False(t, auth.CertificateAllows("viewer", "GET", "images/{fingerprint}/export"))
+	assert.True(t, auth.CertificateAllows("viewer", "GET", "images/{fingerprint}"))
+
+	assert.True(t, auth.CertificateAllows("operator", "PUT", "instances/{name}/state"))
+	assert.True(t, auth.CertificateAllows("operator", "POST", "containers/{name}/exec"))
+	assert.True(t, auth.CertificateAllows("operator", "PUT", "virtual-machines"))
+	assert.True(t, auth.CertificateAllows("operator", "GET", "instances/{name}/files"))
+	assert.True(t, auth.CertificateAllows("operator", "GET", "images/{fingerprint}/export"))
+	assert.False(t, auth.CertificateAllows("operator", "DELETE", "instances/{name}"))
+	assert.False(t, auth.CertificateAllows("operator", "POST", "storage-pools/{pool}/volumes/{type}/{name}/snapshots"))
+}
+
+func TestCertificateHasProject(t *testing.T) {
+	assert.True(t, auth.CertificateHasProject(nil, "default"))
+	assert.True(t, auth.CertificateHasProject([]string{"p1"}, "p1"))
+	assert.False(t, auth.CertificateHasProject([]string{"p1"}, "default"))
+}
@@ -0,0 +1,375 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// OIDCDiscovery holds the fields of the OIDC discovery document which LXD relies on.
+type OIDCDiscovery struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// OIDCIdentityPrefix prefixes the identities of OIDC users, so that they can't be mistaken for the
+// certificate names or usernames held by the authorization groups.
+const OIDCIdentityPrefix = "oidc:"
+
+// OIDCClaims holds the identity extracted from a verified token.
+type OIDCClaims struct {
+	// Verified email if the token has one, otherwise the subject, prefixed with OIDCIdentityPrefix.
+	Identity string
+
+	// Values of the groups claim, matched against the authorization group names.
+	Groups []string
+}
+
+// OIDCVerifier verifies the tokens issued to LXD clients by an OIDC issuer.
+type OIDCVerifier struct {
+	issuer      string
+	clientID    string
+	audience    string
+	groupsClaim string
+
+	client *http.Client
+
+	mu          sync.Mutex
+	jwksURI     string
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDCVerifier returns a verifier for the tokens of the given issuer. When audience is empty,
+// tokens must have been issued to clientID instead.
+func NewOIDCVerifier(issuer string, clientID string, audience string, groupsClaim string, proxy func(*http.Request) (*url.URL, error)) *OIDCVerifier {
+	return &OIDCVerifier{
+		issuer:      strings.TrimSuffix(issuer, "/"),
+		clientID:    clientID,
+		audience:    audience,
+		groupsClaim: groupsClaim,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{Proxy: proxy},
+		},
+	}
+}
+
+// OIDCDiscover fetches the discovery document of an OIDC issuer.
+func OIDCDiscover(client *http.Client, issuer string) (*OIDCDiscovery, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	discovery := OIDCDiscovery{}
+	err := oidcGetJSON(client, issuer+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the OIDC discovery document: %v", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch, got %q", discovery.Issuer)
+	}
+
+	return &discovery, nil
+}
+
+// Verify checks the signature, issuer, audience and validity period of a token and returns the
+// identity it holds.
+func (v *OIDCVerifier) Verify(token string) (*OIDCClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Malformed token")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+
+	err := oidcDecodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	err = oidcDecodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Malformed token signature: %v", err)
+	}
+
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = oidcVerifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.checkClaims(claims)
+}
+
+func (v *OIDCVerifier) checkClaims(claims map[string]interface{}) (*OIDCClaims, error) {
+	iss, _ := claims["iss"].(string)
+	if strings.TrimSuffix(iss, "/") != v.issuer {
+		return nil, fmt.Errorf("Token issued by %q", iss)
+	}
+
+	now := float64(time.Now().Unix())
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || exp < now {
+		return nil, fmt.Errorf("Token has expired")
+	}
+
+	nbf, ok := claims["nbf"].(float64)
+	if ok && nbf > now+60 {
+		return nil, fmt.Errorf("Token isn't valid yet")
+	}
+
+	audiences := oidcStrings(claims["aud"])
+	if v.audience != "" {
+		if !shared.StringInSlice(v.audience, audiences) {
+			return nil, fmt.Errorf("Token isn't intended for %q", v.audience)
+		}
+	} else {
+		azp, _ := claims["azp"].(string)
+		if azp != v.clientID && !shared.StringInSlice(v.clientID, audiences) {
+			return nil, fmt.Errorf("Token wasn't issued to client %q", v.clientID)
+		}
+	}
+
+	// Only trust the email once the issuer verified it, as users may be able to set any.
+	identity, _ := claims["email"].(string)
+	verified, _ := claims["email_verified"].(bool)
+	if identity == "" || !verified {
+		identity, _ = claims["sub"].(string)
+	}
+
+	if identity == "" {
+		return nil, fmt.Errorf("Token has no subject")
+	}
+
+	result := OIDCClaims{}
+	result.Identity = OIDCIdentityPrefix + identity
+
+	if v.groupsClaim != "" {
+		result.Groups = oidcStrings(claims[v.groupsClaim])
+	}
+
+	return &result, nil
+}
+
+// key returns the issuer key with the given ID, refreshing the keys when it's unknown.
+func (v *OIDCVerifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	if ok {
+		return key, nil
+	}
+
+	// Don't let unknown key IDs hammer the issuer.
+	if time.Since(v.keysFetched) < time.Minute {
+		return nil, fmt.Errorf("Unknown token signing key %q", kid)
+	}
+
+	v.keysFetched = time.Now()
+
+	if v.jwksURI == "" {
+		discovery, err := OIDCDiscover(v.client, v.issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		v.jwksURI = discovery.JWKSURI
+	}
+
+	keys, err := oidcFetchKeys(v.client, v.jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	v.keys = keys
+
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("Unknown token signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+func oidcFetchKeys(client *http.Client, uri string) (map[string]crypto.PublicKey, error) {
+	jwks := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}{}
+
+	err := oidcGetJSON(client, uri, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch the OIDC signing keys: %v", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch jwk.Kty {
+		case "RSA":
+			n, err := oidcDecodeInt(jwk.N)
+			if err != nil {
+				return nil, err
+			}
+
+			e, err := oidcDecodeInt(jwk.E)
+			if err != nil {
+				return nil, err
+			}
+
+			keys[jwk.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			if jwk.Crv != "P-256" {
+				continue
+			}
+
+			x, err := oidcDecodeInt(jwk.X)
+			if err != nil {
+				return nil, err
+			}
+
+			y, err := oidcDecodeInt(jwk.Y)
+			if err != nil {
+				return nil, err
+			}
+
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+
+	return keys, nil
+}
+
+func oidcVerifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Token signing key isn't an RSA key")
+		}
+
+		err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return fmt.Errorf("Invalid token signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Token signing key isn't an ECDSA key")
+		}
+
+		if len(signature) != 64 {
+			return fmt.Errorf("Invalid token signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("Invalid token signature")
+		}
+	default:
+		return fmt.Errorf("Unsupported token signing algorithm %q", alg)
+	}
+
+	return nil
+}
+
+func oidcGetJSON(client *http.Client, uri string, target interface{}) error {
+	resp, err := client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status code %d from %q", resp.StatusCode, uri)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+func oidcDecodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("Malformed token: %v", err)
+	}
+
+	err = json.Unmarshal(data, target)
+	if err != nil {
+		return fmt.Errorf("Malformed token: %v", err)
+	}
+
+	return nil
+}
+
+func oidcDecodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed OIDC signing key: %v", err)
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+// oidcStrings returns the values of a claim which may be a string or a list of strings.
+func oidcStrings(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			s, ok := v.(string)
+			if ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/auth"
+)
+
+// Return a fake OIDC issuer publishing the given key, along with its URL.
+func newTestIssuer(t *testing.T, key *rsa.PrivateKey) (*httptest.Server, string) {
+	var issuer string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(auth.OIDCDiscovery{Issuer: issuer, JWKSURI: issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	issuer = server.URL
+
+	return server, issuer
+}
+
+// Return a token with the given claims signed by the given key.
+func newTestToken(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerifier_Verify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server, issuer := newTestIssuer(t, key)
+	defer server.Close()
+
+	verifier := auth.NewOIDCVerifier(issuer, "lxd", "", "groups", nil)
+
+	claims := map[string]interface{}{
+		"iss":    issuer,
+		"aud":    "lxd",
+		"sub":    "1234",
+		"email":  "alice@example.com",
+		"groups": []string{"developers", "admins"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+
+	// Unverified emails aren't trusted.
+	result, err := verifier.Verify(newTestToken(t, key, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "oidc:1234", result.Identity)
+
+	claims["email_verified"] = true
+	result, err = verifier.Verify(newTestToken(t, key, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "oidc:alice@example.com", result.Identity)
+	assert.Equal(t, []string{"developers", "admins"}, result.Groups)
+
+	// Expired tokens are rejected.
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	_, err = verifier.Verify(newTestToken(t, key, claims))
+	assert.EqualError(t, err, "Token has expired")
+
+	// So are tokens issued to other clients.
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	claims["aud"] = "other"
+	_, err = verifier.Verify(newTestToken(t, key, claims))
+	assert.EqualError(t, err, `Token wasn't issued to client "lxd"`)
+
+	// And tokens signed by another key.
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	claims["aud"] = "lxd"
+	_, err = verifier.Verify(newTestToken(t, otherKey, claims))
+	assert.EqualError(t, err, "Invalid token signature")
+}
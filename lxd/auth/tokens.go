@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// TokenPrefix starts the secret of all API tokens, telling them apart from OIDC bearer tokens.
+const TokenPrefix = "lxdt_"
+
+// TokenCapabilities lists what API tokens can be restricted to.
+var TokenCapabilities = []string{"read-only", "exec-only", "full"}
+
+// TokenGenerate returns the secret of a new API token along with the hash stored in the database.
+func TokenGenerate() (string, string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret := TokenPrefix + hex.EncodeToString(buf)
+
+	return secret, TokenHash(secret), nil
+}
+
+// TokenHash returns the hash of the secret of an API token.
+func TokenHash(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// ValidateToken checks the capability and projects of a new API token.
+func ValidateToken(token api.AuthTokensPost) error {
+	if !shared.StringInSlice(token.Capability, TokenCapabilities) {
+		return fmt.Errorf("Invalid capability %q, must be one of: %s", token.Capability, strings.Join(TokenCapabilities, ", "))
+	}
+
+	for _, project := range token.Projects {
+		if project == "" {
+			return fmt.Errorf("Empty project name")
+		}
+	}
+
+	return nil
+}
+
+// TokenAllows returns whether the capability of an API token allows a request to the endpoint
+// with the given path. Read-only tokens can only use GET requests and exec-only tokens can also
+// run commands in instances, through the exec endpoint and its operation.
+func TokenAllows(token *api.AuthToken, method string, path string) bool {
+	if token.Capability == "full" {
+		return true
+	}
+
+	if shared.StringInSlice(method, []string{"GET", "HEAD"}) {
+		return true
+	}
+
+	if token.Capability != "exec-only" {
+		return false
+	}
+
+	if method == "POST" && strings.HasSuffix(path, "/{name}/exec") {
+		return true
+	}
+
+	return method == "DELETE" && path == "operations/{id}"
+}
+
+// TokenHasProject returns whether an API token grants access to a project.
+func TokenHasProject(token *api.AuthToken, project string) bool {
+	return len(token.Projects) == 0 || shared.StringInSlice(project, token.Projects)
+}
+
+// TokenIsAdmin returns whether an API token grants full access to the server.
+func TokenIsAdmin(token *api.AuthToken) bool {
+	return token.Capability == "full" && len(token.Projects) == 0
+}
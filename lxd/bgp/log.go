@@ -0,0 +1,45 @@
+package bgp
+
+import (
+	bgpLog "github.com/osrg/gobgp/v3/pkg/log"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// bgpLogger forwards the logs of the BGP speaker to the LXD logger, whose handlers filter them.
+type bgpLogger struct{}
+
+func (l *bgpLogger) Panic(msg string, fields bgpLog.Fields) {
+	logger.Crit(msg, log.Ctx(fields))
+	panic(msg)
+}
+
+func (l *bgpLogger) Fatal(msg string, fields bgpLog.Fields) {
+	logger.Crit(msg, log.Ctx(fields))
+	panic(msg)
+}
+
+func (l *bgpLogger) Error(msg string, fields bgpLog.Fields) {
+	logger.Error(msg, log.Ctx(fields))
+}
+
+func (l *bgpLogger) Warn(msg string, fields bgpLog.Fields) {
+	logger.Warn(msg, log.Ctx(fields))
+}
+
+func (l *bgpLogger) Info(msg string, fields bgpLog.Fields) {
+	logger.Info(msg, log.Ctx(fields))
+}
+
+func (l *bgpLogger) Debug(msg string, fields bgpLog.Fields) {
+	logger.Debug(msg, log.Ctx(fields))
+}
+
+func (l *bgpLogger) SetLevel(level bgpLog.LogLevel) {
+}
+
+// GetLevel makes the speaker emit all its logs, leaving the filtering to the LXD log handlers.
+func (l *bgpLogger) GetLevel() bgpLog.LogLevel {
+	return bgpLog.DebugLevel
+}
@@ -0,0 +1,313 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	bgpAPI "github.com/osrg/gobgp/v3/api"
+	bgpServer "github.com/osrg/gobgp/v3/pkg/server"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Peer represents a BGP router the routes are announced to.
+type Peer struct {
+	Address  net.IP
+	ASN      uint32
+	Password string
+}
+
+// Prefix represents a route announced to the peers.
+type Prefix struct {
+	Subnet net.IPNet
+
+	// Next hop of the route, the unspecified address meaning the local address of each session.
+	NextHop net.IP
+}
+
+// Server is a BGP speaker announcing the routes of the LXD networks to their peers. It never
+// installs the routes it learns.
+type Server struct {
+	bgp *bgpServer.BgpServer
+
+	address  string
+	asn      uint32
+	routerID net.IP
+
+	// Wanted peers and prefixes, by address and subnet/next hop.
+	peers    map[string]Peer
+	prefixes map[string]Prefix
+
+	// Peers and paths (by path UUID) currently added to the running speaker.
+	addedPeers map[string]Peer
+	addedPaths map[string][]byte
+
+	mu sync.Mutex
+}
+
+// NewServer returns a new, not yet started, BGP speaker.
+func NewServer() *Server {
+	s := &Server{
+		bgp:        bgpServer.NewBgpServer(bgpServer.LoggerOption(&bgpLogger{})),
+		peers:      map[string]Peer{},
+		prefixes:   map[string]Prefix{},
+		addedPeers: map[string]Peer{},
+		addedPaths: map[string][]byte{},
+	}
+
+	go s.bgp.Serve()
+
+	return s
+}
+
+// Start starts the BGP speaker on the given address (defaulting to port 179) with the given ASN
+// and router ID. If it's already running with the same configuration this is a no-op, otherwise
+// it's restarted and the current routes are announced to the current peers again.
+func (s *Server) Start(address string, asn uint32, routerID net.IP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if address == s.address && asn == s.asn && routerID.Equal(s.routerID) {
+		return nil
+	}
+
+	s.stop()
+
+	if address == "" {
+		return nil
+	}
+
+	if asn == 0 {
+		return fmt.Errorf("An ASN is required to start the BGP server")
+	}
+
+	if routerID.To4() == nil {
+		return fmt.Errorf("An IPv4 router ID is required to start the BGP server")
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+		portStr = "179"
+	}
+
+	port, err := strconv.ParseInt(portStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid BGP server port %q: %v", portStr, err)
+	}
+
+	global := &bgpAPI.Global{
+		Asn:        asn,
+		RouterId:   routerID.String(),
+		ListenPort: int32(port),
+	}
+
+	if host != "" {
+		global.ListenAddresses = []string{host}
+	}
+
+	err = s.bgp.StartBgp(context.Background(), &bgpAPI.StartBgpRequest{Global: global})
+	if err != nil {
+		return fmt.Errorf("Failed to start BGP server on %s: %v", address, err)
+	}
+
+	s.address = address
+	s.asn = asn
+	s.routerID = routerID
+	logger.Infof("Started BGP server on %s", address)
+
+	return s.sync()
+}
+
+// Stop stops the BGP speaker, withdrawing its routes.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop()
+}
+
+// Update sets the peers and the routes announced to them, only adding and removing the ones
+// which changed when the speaker is running.
+func (s *Server) Update(peers []Peer, prefixes []Prefix) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.peers = map[string]Peer{}
+	for _, peer := range peers {
+		s.peers[peer.Address.String()] = peer
+	}
+
+	s.prefixes = map[string]Prefix{}
+	for _, prefix := range prefixes {
+		s.prefixes[prefixKey(prefix)] = prefix
+	}
+
+	if s.address == "" {
+		return nil
+	}
+
+	return s.sync()
+}
+
+func (s *Server) stop() {
+	if s.address == "" {
+		return
+	}
+
+	// Stopping the speaker drops its peers and paths.
+	err := s.bgp.StopBgp(context.Background(), &bgpAPI.StopBgpRequest{})
+	if err != nil {
+		logger.Warnf("Failed to stop BGP server: %v", err)
+	}
+
+	s.address = ""
+	s.asn = 0
+	s.routerID = nil
+	s.addedPeers = map[string]Peer{}
+	s.addedPaths = map[string][]byte{}
+}
+
+// sync adds and removes the peers and paths of the running speaker to match the wanted ones.
+func (s *Server) sync() error {
+	for key, peer := range s.addedPeers {
+		wanted, ok := s.peers[key]
+		if ok && wanted.ASN == peer.ASN && wanted.Password == peer.Password {
+			continue
+		}
+
+		err := s.bgp.DeletePeer(context.Background(), &bgpAPI.DeletePeerRequest{Address: key})
+		if err != nil {
+			return fmt.Errorf("Failed to remove BGP peer %s: %v", key, err)
+		}
+
+		delete(s.addedPeers, key)
+	}
+
+	for key, peer := range s.peers {
+		_, ok := s.addedPeers[key]
+		if ok {
+			continue
+		}
+
+		err := s.addPeer(peer)
+		if err != nil {
+			return fmt.Errorf("Failed to add BGP peer %s: %v", key, err)
+		}
+
+		s.addedPeers[key] = peer
+	}
+
+	for key, uuid := range s.addedPaths {
+		_, ok := s.prefixes[key]
+		if ok {
+			continue
+		}
+
+		err := s.bgp.DeletePath(context.Background(), &bgpAPI.DeletePathRequest{
+			TableType: bgpAPI.TableType_GLOBAL,
+			Uuid:      uuid,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to withdraw BGP route %s: %v", key, err)
+		}
+
+		delete(s.addedPaths, key)
+	}
+
+	for key, prefix := range s.prefixes {
+		_, ok := s.addedPaths[key]
+		if ok {
+			continue
+		}
+
+		uuid, err := s.addPath(prefix)
+		if err != nil {
+			return fmt.Errorf("Failed to announce BGP route %s: %v", key, err)
+		}
+
+		s.addedPaths[key] = uuid
+	}
+
+	return nil
+}
+
+func (s *Server) addPeer(peer Peer) error {
+	// Announce both address families whatever the one of the session.
+	afiSafis := []*bgpAPI.AfiSafi{}
+	for _, afi := range []bgpAPI.Family_Afi{bgpAPI.Family_AFI_IP, bgpAPI.Family_AFI_IP6} {
+		afiSafis = append(afiSafis, &bgpAPI.AfiSafi{
+			Config: &bgpAPI.AfiSafiConfig{
+				Family:  &bgpAPI.Family{Afi: afi, Safi: bgpAPI.Family_SAFI_UNICAST},
+				Enabled: true,
+			},
+		})
+	}
+
+	return s.bgp.AddPeer(context.Background(), &bgpAPI.AddPeerRequest{
+		Peer: &bgpAPI.Peer{
+			Conf: &bgpAPI.PeerConf{
+				NeighborAddress: peer.Address.String(),
+				PeerAsn:         peer.ASN,
+				AuthPassword:    peer.Password,
+			},
+			AfiSafis: afiSafis,
+		},
+	})
+}
+
+func (s *Server) addPath(prefix Prefix) ([]byte, error) {
+	size, _ := prefix.Subnet.Mask.Size()
+	nlri, err := anypb.New(&bgpAPI.IPAddressPrefix{
+		Prefix:    prefix.Subnet.IP.String(),
+		PrefixLen: uint32(size),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	origin, err := anypb.New(&bgpAPI.OriginAttribute{Origin: 0})
+	if err != nil {
+		return nil, err
+	}
+
+	// IPv4 routes carry their next hop in a dedicated attribute, IPv6 ones along with the NLRI.
+	family := &bgpAPI.Family{Afi: bgpAPI.Family_AFI_IP, Safi: bgpAPI.Family_SAFI_UNICAST}
+	var nextHop *anypb.Any
+	if prefix.Subnet.IP.To4() != nil {
+		nextHop, err = anypb.New(&bgpAPI.NextHopAttribute{NextHop: prefix.NextHop.String()})
+	} else {
+		family = &bgpAPI.Family{Afi: bgpAPI.Family_AFI_IP6, Safi: bgpAPI.Family_SAFI_UNICAST}
+		nextHop, err = anypb.New(&bgpAPI.MpReachNLRIAttribute{
+			Family:   family,
+			NextHops: []string{prefix.NextHop.String()},
+			Nlris:    []*anypb.Any{nlri},
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.bgp.AddPath(context.Background(), &bgpAPI.AddPathRequest{
+		TableType: bgpAPI.TableType_GLOBAL,
+		Path: &bgpAPI.Path{
+			Family: family,
+			Nlri:   nlri,
+			Pattrs: []*anypb.Any{origin, nextHop},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Uuid, nil
+}
+
+func prefixKey(prefix Prefix) string {
+	return fmt.Sprintf("%s via %s", prefix.Subnet.String(), prefix.NextHop.String())
+}
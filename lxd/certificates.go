@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 
 	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/auth"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/response"
@@ -41,6 +42,24 @@ var certificateCmd = APIEndpoint{
 	Put:    APIEndpointAction{Handler: certificatePut},
 }
 
+// certificateTypes maps the types of certificates to their value in the database.
+var certificateTypes = map[string]int{
+	"client":   1,
+	"viewer":   2,
+	"operator": 3,
+}
+
+// certificateTypeName returns the type of a certificate from its value in the database.
+func certificateTypeName(certType int) string {
+	for name, value := range certificateTypes {
+		if value == certType {
+			return name
+		}
+	}
+
+	return "unknown"
+}
+
 func certificatesGet(d *Daemon, r *http.Request) response.Response {
 	recursion := util.IsRecursionRequest(r)
 
@@ -56,16 +75,16 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 			resp.Fingerprint = baseCert.Fingerprint
 			resp.Certificate = baseCert.Certificate
 			resp.Name = baseCert.Name
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
-			}
+			resp.Type = certificateTypeName(baseCert.Type)
+			resp.Projects = baseCert.Projects
 			certResponses = append(certResponses, resp)
 		}
 		return response.SyncResponse(true, certResponses)
 	}
 
+	d.clientLock.RLock()
+	defer d.clientLock.RUnlock()
+
 	body := []string{}
 	for _, cert := range d.clientCerts {
 		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
@@ -75,8 +94,18 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, body)
 }
 
+// readSavedClientCAList loads the trusted certificates, along with their type and projects, so
+// that requests are authenticated and authorized without looking them up in the database.
 func readSavedClientCAList(d *Daemon) {
-	d.clientCerts = map[string]x509.Certificate{}
+	clientCerts := map[string]x509.Certificate{}
+	clientRoles := map[string]api.Certificate{}
+
+	defer func() {
+		d.clientLock.Lock()
+		d.clientCerts = clientCerts
+		d.clientRoles = clientRoles
+		d.clientLock.Unlock()
+	}()
 
 	dbCerts, err := d.cluster.CertificatesGet()
 	if err != nil {
@@ -97,7 +126,16 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 
-		d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		fingerprint := shared.CertFingerprint(cert)
+		clientCerts[fingerprint] = *cert
+		clientRoles[fingerprint] = api.Certificate{
+			CertificatePut: api.CertificatePut{
+				Name:     dbCert.Name,
+				Type:     certificateTypeName(dbCert.Type),
+				Projects: dbCert.Projects,
+			},
+			Fingerprint: dbCert.Fingerprint,
+		}
 	}
 }
 
@@ -114,20 +152,30 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	trusted, _, protocol, err := d.Authenticate(r)
+	trusted, _, protocol, _, err := d.Authenticate(r)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
+	// New cluster members may provide the secret of a join token instead of the trust password.
+	var joinToken *api.ClusterMemberJoinToken
+	if !trusted && req.Password != "" && util.PasswordCheck(secret, req.Password) != nil {
+		joinToken, err = clusterMemberJoinTokenValid(d, req.Password)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	if (!trusted || (shared.StringInSlice(protocol, []string{"candid", "oidc", "token"}) && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil && joinToken == nil {
 		if req.Password != "" {
 			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
 		}
 		return response.Forbidden(nil)
 	}
 
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	err = auth.ValidateCertificate(req.Type, req.Projects)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
 	// Extract the certificate
@@ -160,20 +208,23 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Can't use TLS data on non-TLS link"))
 	}
 
-	fingerprint := shared.CertFingerprint(cert)
-
-	if d.clientCerts == nil {
-		d.clientCerts = map[string]x509.Certificate{}
+	if joinToken != nil {
+		name = joinToken.ServerName
 	}
 
+	fingerprint := shared.CertFingerprint(cert)
+
 	if !isClusterNotification(r) {
 		// Check if we already have the certificate
 		existingCert, _ := d.cluster.CertificateGet(fingerprint)
 		if existingCert != nil {
 			// Deal with the cache being potentially out of sync
+			d.clientLock.RLock()
 			_, ok := d.clientCerts[fingerprint]
+			d.clientLock.RUnlock()
+
 			if !ok {
-				d.clientCerts[fingerprint] = *cert
+				readSavedClientCAList(d)
 				return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 			}
 
@@ -183,9 +234,10 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		// Store the certificate in the cluster database
 		dbCert := db.CertInfo{
 			Fingerprint: shared.CertFingerprint(cert),
-			Type:        1,
+			Type:        certificateTypes[req.Type],
 			Name:        name,
 			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Projects:    req.Projects,
 		}
 
 		err = d.cluster.CertSave(&dbCert)
@@ -213,7 +265,8 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	// The certificate is in the database, whether added here or by the notifying member.
+	readSavedClientCAList(d)
 
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
@@ -240,11 +293,8 @@ func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, erro
 	resp.Fingerprint = dbCertInfo.Fingerprint
 	resp.Certificate = dbCertInfo.Certificate
 	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
-	}
+	resp.Type = certificateTypeName(dbCertInfo.Type)
+	resp.Projects = dbCertInfo.Projects
 
 	return resp, nil
 }
@@ -252,6 +302,12 @@ func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, erro
 func certificatePut(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// Other members only refresh their trusted certificates once updated.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
+	}
+
 	oldEntry, err := doCertificateGet(d.cluster, fingerprint)
 	if err != nil {
 		return response.SmartError(err)
@@ -274,6 +330,12 @@ func certificatePut(d *Daemon, r *http.Request) response.Response {
 func certificatePatch(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// Other members only refresh their trusted certificates once updated.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
+	}
+
 	oldEntry, err := doCertificateGet(d.cluster, fingerprint)
 	if err != nil {
 		return response.SmartError(err)
@@ -301,17 +363,57 @@ func certificatePatch(d *Daemon, r *http.Request) response.Response {
 	value, err = reqRaw.GetString("type")
 	if err == nil {
 		req.Type = value
+
+		// Client certificates aren't restricted to any project
+		if value == "client" {
+			req.Projects = nil
+		}
+	}
+
+	// Get projects
+	rawProjects, ok := reqRaw["projects"]
+	if ok {
+		projects, ok := rawProjects.([]interface{})
+		if !ok && rawProjects != nil {
+			return response.BadRequest(fmt.Errorf("Invalid projects"))
+		}
+
+		req.Projects = []string{}
+		for _, project := range projects {
+			name, ok := project.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Invalid project name"))
+			}
+
+			req.Projects = append(req.Projects, name)
+		}
 	}
 
 	return doCertificateUpdate(d, fingerprint, req.Writable())
 }
 
 func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) response.Response {
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	err := auth.ValidateCertificate(req.Type, req.Projects)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
-	err := d.cluster.CertUpdate(fingerprint, req.Name, 1)
+	err = d.cluster.CertUpdate(fingerprint, req.Name, certificateTypes[req.Type], req.Projects)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	readSavedClientCAList(d)
+
+	// Notify other members so that they refresh the type and projects of the certificate.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.UpdateCertificate(fingerprint, req, "")
+	})
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -322,6 +424,12 @@ func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut)
 func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// Other members only refresh their trusted certificates once deleted.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
+	}
+
 	certInfo, err := d.cluster.CertificateGet(fingerprint)
 	if err != nil {
 		return response.NotFound(err)
@@ -333,5 +441,18 @@ func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	}
 	readSavedClientCAList(d)
 
+	// Notify other members so that they stop trusting the certificate.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.DeleteCertificate(certInfo.Fingerprint)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
@@ -2,6 +2,7 @@ package cgroup
 
 import (
 	"fmt"
+	"strings"
 )
 
 // CGroup represents the main cgroup abstraction.
@@ -216,34 +217,60 @@ func (cg *CGroup) GetMemorySwapUsage() (string, error) {
 	return "", ErrUnknownVersion
 }
 
-// GetBlkioWeight returns the currently allowed range of weights
+// GetBlkioWeight returns the I/O weight of the group, the bfq one when it's the only one available.
 func (cg *CGroup) GetBlkioWeight() (string, error) {
-	// Confirm we have the controller
-	version := cgControllers["blkio"]
-	switch version {
-	case Unavailable:
+	key := "blkio.weight"
+	version, ok := cgControllers[key]
+	if !ok {
+		key = "blkio.bfq.weight"
+		version, ok = cgControllers[key]
+	}
+
+	if !ok {
 		return "", ErrControllerMissing
+	}
+
+	switch version {
 	case V1:
-		return cg.rw.Get(version, "blkio", "blkio.weight")
+		return cg.rw.Get(version, "blkio", key)
 	case V2:
-		return "", ErrControllerMissing
+		return cg.rw.Get(version, "io", strings.Replace(key, "blkio.", "io.", 1))
 	}
 	return "", ErrUnknownVersion
 }
 
-// SetBlkioWeight set the currently allowed range of weights
+// SetBlkioWeight sets the I/O weight of the group, between 10 and 1000, for all the available I/O
+// schedulers (cfq or iocost and bfq).
 func (cg *CGroup) SetBlkioWeight(value string) error {
-	version := cgControllers["blkio"]
-	switch version {
-	case Unavailable:
-		return ErrControllerMissing
-	case V1:
-		return cg.rw.Set(version, "blkio", "blkio.weight", value)
-	case V2:
+	applied := false
+	for _, key := range []string{"blkio.weight", "blkio.bfq.weight"} {
+		version, ok := cgControllers[key]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch version {
+		case V1:
+			err = cg.rw.Set(version, "blkio", key, value)
+		case V2:
+			err = cg.rw.Set(version, "io", strings.Replace(key, "blkio.", "io.", 1), value)
+		default:
+			err = ErrUnknownVersion
+		}
+
+		if err != nil {
+			return err
+		}
+
+		applied = true
+	}
+
+	if !applied {
 		return ErrControllerMissing
 	}
-	return ErrUnknownVersion
 
+	return nil
 }
 
 // SetCPUShare sets the weight of each group in the same hierarchy
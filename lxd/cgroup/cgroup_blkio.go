@@ -0,0 +1,30 @@
+package cgroup
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseDiskPriority parses a disk priority, between 0 and 10, returning the matching I/O weight.
+func ParseDiskPriority(diskPriority string) (int, error) {
+	priority := 5
+	if diskPriority != "" {
+		var err error
+		priority, err = strconv.Atoi(diskPriority)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	if priority < 0 || priority > 10 {
+		return -1, fmt.Errorf("Invalid disk priority: %d", priority)
+	}
+
+	// Minimum valid value is 10
+	weight := priority * 100
+	if weight == 0 {
+		weight = 10
+	}
+
+	return weight, nil
+}
@@ -129,8 +129,13 @@ func (info *Info) SupportsVersion(resource Resource) (Backend, bool) {
 		return Unavailable, false
 	case BlkioWeight:
 		val, ok := cgControllers["blkio.weight"]
-		if ok && val == V1 {
-			return V1, ok
+		if ok {
+			return val, ok
+		}
+
+		val, ok = cgControllers["blkio.bfq.weight"]
+		if ok {
+			return val, ok
 		}
 
 		return Unavailable, false
@@ -243,7 +248,7 @@ func (info *Info) Log() {
 	}
 
 	if !info.Supports(BlkioWeight, nil) {
-		logger.Warnf(" - Couldn't find the CGroup blkio.weight, I/O weight limits will be ignored")
+		logger.Warnf(" - Couldn't find the CGroup I/O weight, I/O priorities will be ignored")
 	}
 
 	if !info.Supports(CPU, nil) {
@@ -350,11 +355,29 @@ func init() {
 
 			scanControllers := bufio.NewScanner(controllers)
 			for scanControllers.Scan() {
-				line := strings.TrimSpace(scanSelfCg.Text())
-				unifiedControllers[line] = V2
+				for _, controller := range strings.Fields(scanControllers.Text()) {
+					unifiedControllers[controller] = V2
+				}
 			}
+			controllers.Close()
 			hasV2 = true
 
+			// Check which I/O weights are available, depending on the I/O schedulers.
+			unifiedPath := filepath.Dir(hybridPath)
+			if dedicatedPath != "" {
+				unifiedPath = filepath.Dir(dedicatedPath)
+			}
+
+			if unifiedControllers["io"] == V2 {
+				if shared.PathExists(filepath.Join(unifiedPath, "io.weight")) {
+					unifiedControllers["blkio.weight"] = V2
+				}
+
+				if shared.PathExists(filepath.Join(unifiedPath, "io.bfq.weight")) {
+					unifiedControllers["blkio.bfq.weight"] = V2
+				}
+			}
+
 			if dedicatedPath != "" {
 				cgControllers = unifiedControllers
 				break
@@ -368,13 +391,14 @@ func init() {
 
 	// Check for additional legacy cgroup features
 	val, ok := cgControllers["blkio"]
-	if ok && val == V1 && shared.PathExists("/sys/fs/cgroup/blkio/blkio.weight") {
-		cgControllers["blkio.weight"] = V1
-	} else {
-		val, ok := cgControllers["blkio"]
-		if ok && val == V1 && shared.PathExists("/sys/fs/cgroup/blkio/blkio.bfq.weight") {
+	if ok && val == V1 {
+		if shared.PathExists("/sys/fs/cgroup/blkio/blkio.weight") {
 			cgControllers["blkio.weight"] = V1
 		}
+
+		if shared.PathExists("/sys/fs/cgroup/blkio/blkio.bfq.weight") {
+			cgControllers["blkio.bfq.weight"] = V1
+		}
 	}
 
 	val, ok = cgControllers["memory"]
@@ -5,14 +5,22 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/scrypt"
 
+	"github.com/lxc/lxd/lxd/audit"
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/logtarget"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhook"
+	"github.com/lxc/lxd/shared"
 	"github.com/pkg/errors"
 )
 
@@ -95,6 +103,23 @@ func (c *Config) RemoteCacheExpiry() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// ImagesEncryption returns whether the image store is encrypted.
+func (c *Config) ImagesEncryption() bool {
+	return c.m.GetBool("images.encryption")
+}
+
+// ImagesEncryptionKeyFile returns the path to the file holding the key the image store is
+// encrypted with.
+func (c *Config) ImagesEncryptionKeyFile() string {
+	return c.m.GetString("images.encryption.key_file")
+}
+
+// ImagesTrustedKeys returns the armored OpenPGP public keys which images must be signed with, if
+// any.
+func (c *Config) ImagesTrustedKeys() string {
+	return c.m.GetString("images.trusted_keys")
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -125,11 +150,108 @@ func (c *Config) OfflineThreshold() time.Duration {
 	return time.Duration(n) * time.Second
 }
 
+// HeartbeatInterval returns the configured interval between two heartbeat
+// rounds of the leader.
+func (c *Config) HeartbeatInterval() time.Duration {
+	n := c.m.GetInt64("cluster.heartbeat_interval")
+	return time.Duration(n) * time.Second
+}
+
 // ImagesMinimalReplica returns the numbers of nodes for cluster images replication
 func (c *Config) ImagesMinimalReplica() int64 {
 	return c.m.GetInt64("cluster.images_minimal_replica")
 }
 
+// RebalanceInterval returns the configured interval between two checks of the
+// balance of the cluster, or zero if rebalancing is disabled.
+func (c *Config) RebalanceInterval() time.Duration {
+	n := c.m.GetInt64("cluster.rebalance.interval")
+	return time.Duration(n) * time.Minute
+}
+
+// RebalanceThreshold returns the difference of load, in percents, above which
+// instances get moved from the busiest to the least busy member.
+func (c *Config) RebalanceThreshold() int64 {
+	return c.m.GetInt64("cluster.rebalance.threshold")
+}
+
+// RebalanceAutomatic returns whether instances should be moved when the
+// cluster is unbalanced, rather than the move only being proposed.
+func (c *Config) RebalanceAutomatic() bool {
+	return c.m.GetBool("cluster.rebalance.automatic")
+}
+
+// JoinTokenExpiry returns the expiry expression of cluster join tokens.
+func (c *Config) JoinTokenExpiry() string {
+	return c.m.GetString("cluster.join_token_expiry")
+}
+
+// AuthBuiltin returns whether requests are authorized by the built-in authorization groups.
+func (c *Config) AuthBuiltin() bool {
+	return c.m.GetBool("auth.builtin")
+}
+
+// OIDCServer returns the OIDC issuer, client ID, expected audience and groups claim.
+func (c *Config) OIDCServer() (string, string, string, string) {
+	return c.m.GetString("oidc.issuer"),
+		c.m.GetString("oidc.client.id"),
+		c.m.GetString("oidc.audience"),
+		c.m.GetString("oidc.groups.claim")
+}
+
+// AuditSinks returns the sinks audit entries are sent to and the URL of the webhook sink.
+func (c *Config) AuditSinks() ([]string, string) {
+	return splitList(c.m.GetString("core.audit_sinks")), c.m.GetString("core.audit_webhook")
+}
+
+// BGPASN returns the ASN of the BGP servers of the cluster members, zero meaning unset.
+func (c *Config) BGPASN() int64 {
+	return c.m.GetInt64("core.bgp_asn")
+}
+
+// SlowQueryThreshold returns how long a database query can take before being logged, zero
+// meaning never.
+func (c *Config) SlowQueryThreshold() time.Duration {
+	return time.Duration(c.m.GetInt64("core.db_slow_query_threshold")) * time.Millisecond
+}
+
+// TraceEndpoint returns the OTLP/HTTP endpoint trace spans are exported to.
+func (c *Config) TraceEndpoint() string {
+	return c.m.GetString("core.trace_endpoint")
+}
+
+// Webhooks returns the URLs events are posted to, the events to post and the secret to sign them
+// with.
+func (c *Config) Webhooks() ([]string, []string, string) {
+	return splitList(c.m.GetString("core.webhook_urls")),
+		splitList(c.m.GetString("core.webhook_events")),
+		c.m.GetString("core.webhook_secret")
+}
+
+// LogTargets returns the remote log servers logs are forwarded to and which logs each of them gets.
+func (c *Config) LogTargets() logtarget.Config {
+	// The labels are checked when set.
+	labels, _ := logtarget.ParseLokiLabels(c.m.GetString("loki.labels"))
+
+	return logtarget.Config{
+		LokiURL:       c.m.GetString("loki.api.url"),
+		LokiUsername:  c.m.GetString("loki.auth.username"),
+		LokiPassword:  c.m.GetString("loki.auth.password"),
+		LokiLabels:    labels,
+		LokiTypes:     splitList(c.m.GetString("loki.types")),
+		SyslogAddress: c.m.GetString("syslog.address"),
+		SyslogTypes:   splitList(c.m.GetString("syslog.types")),
+	}
+}
+
+// RateLimits returns the number of requests per second each client can make, the size of its
+// bursts and how many of its requests can be handled at the same time, zero meaning no limit.
+func (c *Config) RateLimits() (int64, int64, int64) {
+	return c.m.GetInt64("core.rate_limit.requests"),
+		c.m.GetInt64("core.rate_limit.burst"),
+		c.m.GetInt64("core.rate_limit.concurrency")
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -160,6 +282,35 @@ func (c *Config) update(values map[string]interface{}) (map[string]string, error
 		return nil, err
 	}
 
+	// The offline threshold must leave room for at least one heartbeat round.
+	if c.OfflineThreshold() <= c.HeartbeatInterval() {
+		return nil, config.Error{
+			Name:   "cluster.offline_threshold",
+			Value:  c.m.GetInt64("cluster.offline_threshold"),
+			Reason: fmt.Sprintf("Value must be greater than '%d'", c.m.GetInt64("cluster.heartbeat_interval")),
+		}
+	}
+
+	// The webhook audit sink needs somewhere to send the entries to.
+	sinks, webhook := c.AuditSinks()
+	if shared.StringInSlice("webhook", sinks) && webhook == "" {
+		return nil, config.Error{
+			Name:   "core.audit_webhook",
+			Value:  webhook,
+			Reason: "Value is required by the webhook audit sink",
+		}
+	}
+
+	// Clients need a client ID to go through the device flow of the issuer.
+	issuer, clientID, _, _ := c.OIDCServer()
+	if issuer != "" && clientID == "" {
+		return nil, config.Error{
+			Name:   "oidc.client.id",
+			Value:  clientID,
+			Reason: "Value is required when oidc.issuer is set",
+		}
+	}
+
 	err = c.tx.UpdateConfig(changed)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot persist configuration changes: %v")
@@ -219,9 +370,19 @@ func configGet(cluster *db.Cluster) (*Config, error) {
 
 // ConfigSchema defines available server configuration keys.
 var ConfigSchema = config.Schema{
+	"auth.builtin":                   {Type: config.Bool},
 	"backups.compression_algorithm":  {Default: "gzip", Validator: validateCompression},
+	"cluster.heartbeat_interval":     {Type: config.Int64, Default: heartbeatIntervalDefaultString(), Validator: heartbeatIntervalValidator},
 	"cluster.offline_threshold":      {Type: config.Int64, Default: offlineThresholdDefault(), Validator: offlineThresholdValidator},
 	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
+	"cluster.join_token_expiry":      {Default: "3H", Validator: joinTokenExpiryValidator},
+	"cluster.rebalance.automatic":    {Type: config.Bool},
+	"cluster.rebalance.interval":     {Type: config.Int64, Default: "0"},
+	"cluster.rebalance.threshold":    {Type: config.Int64, Default: "20", Validator: rebalanceThresholdValidator},
+	"core.audit_sinks":               {Validator: auditSinksValidator},
+	"core.audit_webhook":             {},
+	"core.bgp_asn":                   {Type: config.Int64, Default: "0", Validator: bgpASNValidator},
+	"core.db_slow_query_threshold":   {Type: config.Int64, Default: "1000", Validator: slowQueryThresholdValidator},
 	"core.https_allowed_headers":     {},
 	"core.https_allowed_methods":     {},
 	"core.https_allowed_origin":      {},
@@ -229,7 +390,14 @@ var ConfigSchema = config.Schema{
 	"core.proxy_http":                {},
 	"core.proxy_https":               {},
 	"core.proxy_ignore_hosts":        {},
+	"core.rate_limit.burst":          {Type: config.Int64, Default: "0", Validator: rateLimitValidator},
+	"core.rate_limit.concurrency":    {Type: config.Int64, Default: "0", Validator: rateLimitValidator},
+	"core.rate_limit.requests":       {Type: config.Int64, Default: "0", Validator: rateLimitValidator},
+	"core.trace_endpoint":            {Validator: traceEndpointValidator},
 	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
+	"core.webhook_events":            {},
+	"core.webhook_secret":            {Hidden: true},
+	"core.webhook_urls":              {Validator: webhookURLsValidator},
 	"candid.api.key":                 {},
 	"candid.api.url":                 {},
 	"candid.domains":                 {},
@@ -237,9 +405,21 @@ var ConfigSchema = config.Schema{
 	"images.auto_update_cached":      {Type: config.Bool, Default: "true"},
 	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
 	"images.compression_algorithm":   {Default: "gzip", Validator: validateCompression},
+	"images.encryption":              {Type: config.Bool},
+	"images.encryption.key_file":     {Validator: util.ImageEncryptionKeyFileValidate},
 	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
+	"images.trusted_keys":            {Validator: validateTrustedKeys},
+	"loki.api.url":                   {Validator: lokiURLValidator},
+	"loki.auth.password":             {Hidden: true},
+	"loki.auth.username":             {},
+	"loki.labels":                    {Validator: lokiLabelsValidator},
+	"loki.types":                     {Default: "audit,console,lifecycle", Validator: logTypesValidator},
 	"maas.api.key":                   {},
 	"maas.api.url":                   {},
+	"oidc.audience":                  {},
+	"oidc.client.id":                 {},
+	"oidc.groups.claim":              {Default: "groups"},
+	"oidc.issuer":                    {Validator: oidcIssuerValidator},
 	"rbac.agent.url":                 {},
 	"rbac.agent.username":            {},
 	"rbac.agent.private_key":         {},
@@ -248,6 +428,8 @@ var ConfigSchema = config.Schema{
 	"rbac.api.key":                   {},
 	"rbac.api.url":                   {},
 	"rbac.expiry":                    {Type: config.Int64, Default: "3600"},
+	"syslog.address":                 {Validator: syslogAddressValidator},
+	"syslog.types":                   {Default: "audit,console,lifecycle", Validator: logTypesValidator},
 
 	// Keys deprecated since the implementation of the storage api.
 	"storage.lvm_fstype":           {Setter: deprecatedStorage, Default: "ext4"},
@@ -265,15 +447,28 @@ func offlineThresholdDefault() string {
 }
 
 func offlineThresholdValidator(value string) error {
-	// Ensure that the given value is greater than the heartbeat interval,
-	// which is the lower bound granularity of the offline check.
-	threshold, err := strconv.Atoi(value)
+	// Comparing with the heartbeat interval, which is the lower bound
+	// granularity of the offline check, happens once all keys are set.
+	_, err := strconv.Atoi(value)
 	if err != nil {
 		return fmt.Errorf("Offline threshold is not a number")
 	}
 
-	if threshold <= heartbeatInterval {
-		return fmt.Errorf("Value must be greater than '%d'", heartbeatInterval)
+	return nil
+}
+
+func heartbeatIntervalDefaultString() string {
+	return strconv.Itoa(heartbeatIntervalDefault)
+}
+
+func heartbeatIntervalValidator(value string) error {
+	interval, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Heartbeat interval is not a number")
+	}
+
+	if interval < heartbeatIntervalMinimum {
+		return fmt.Errorf("Value must be at least '%d'", heartbeatIntervalMinimum)
 	}
 
 	return nil
@@ -292,6 +487,158 @@ func imageMinimalReplicaValidator(value string) error {
 	return nil
 }
 
+func rebalanceThresholdValidator(value string) error {
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Rebalance threshold is not a number")
+	}
+
+	if threshold < 1 || threshold > 100 {
+		return fmt.Errorf("Value must be between 1 and 100")
+	}
+
+	return nil
+}
+
+func joinTokenExpiryValidator(value string) error {
+	_, err := shared.GetSnapshotExpiry(time.Now(), value)
+	if err != nil {
+		return fmt.Errorf("Invalid join token expiry")
+	}
+
+	return nil
+}
+
+func auditSinksValidator(value string) error {
+	for _, sink := range strings.Split(value, ",") {
+		sink = strings.TrimSpace(sink)
+		if sink != "" && !shared.StringInSlice(sink, audit.Sinks) {
+			return fmt.Errorf("Invalid audit sink %q, must be one of: %s", sink, strings.Join(audit.Sinks, ", "))
+		}
+	}
+
+	return nil
+}
+
+func oidcIssuerValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("The OIDC issuer must be an HTTPS URL")
+	}
+
+	return nil
+}
+
+func slowQueryThresholdValidator(value string) error {
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Slow query threshold is not a number")
+	}
+
+	if threshold < 0 {
+		return fmt.Errorf("Value must be zero or more")
+	}
+
+	return nil
+}
+
+func traceEndpointValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("The trace endpoint must be an HTTP or HTTPS URL")
+	}
+
+	return nil
+}
+
+func webhookURLsValidator(value string) error {
+	for _, entry := range splitList(value) {
+		err := webhook.ValidateURL(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func lokiURLValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	return logtarget.ValidateLokiURL(value)
+}
+
+func lokiLabelsValidator(value string) error {
+	_, err := logtarget.ParseLokiLabels(value)
+	return err
+}
+
+func syslogAddressValidator(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, _, err := logtarget.ParseSyslogAddress(value)
+	return err
+}
+
+func logTypesValidator(value string) error {
+	return logtarget.ValidateTypes(splitList(value))
+}
+
+func rateLimitValidator(value string) error {
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Rate limit is not a number")
+	}
+
+	if limit < 0 {
+		return fmt.Errorf("Value must be zero or more")
+	}
+
+	return nil
+}
+
+func bgpASNValidator(value string) error {
+	_, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid ASN: %s", value)
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated value, dropping the empty entries.
+func splitList(value string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
 func passwordSetter(value string) (string, error) {
 	// Nothing to do on unset
 	if value == "" {
@@ -330,6 +677,19 @@ func validateCompression(value string) error {
 	return err
 }
 
+func validateTrustedKeys(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, err := openpgp.ReadArmoredKeyRing(strings.NewReader(value))
+	if err != nil {
+		return errors.Wrap(err, "Invalid armored OpenPGP public keys")
+	}
+
+	return nil
+}
+
 func deprecatedStorage(value string) (string, error) {
 	if value == "" {
 		return "", nil
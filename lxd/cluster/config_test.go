@@ -2,6 +2,7 @@ package cluster_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
@@ -64,6 +65,25 @@ func TestConfigLoad_OfflineThresholdValidator(t *testing.T) {
 
 }
 
+// Heartbeat interval must be lower than the offline threshold.
+func TestConfigLoad_HeartbeatIntervalValidator(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]interface{}{"cluster.heartbeat_interval": "2"})
+	require.EqualError(t, err, "cannot set 'cluster.heartbeat_interval' to '2': Value must be at least '4'")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.heartbeat_interval": "30"})
+	require.EqualError(t, err, "cannot set 'cluster.offline_threshold' to '20': Value must be greater than '30'")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.heartbeat_interval": "5", "cluster.offline_threshold": "8"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.HeartbeatInterval())
+}
+
 // If some previously set values are missing from the ones passed to Replace(),
 // they are deleted from the configuration.
 func TestConfig_ReplaceDeleteValues(t *testing.T) {
@@ -108,6 +108,12 @@ type Gateway struct {
 	Cluster           *db.Cluster
 	HeartbeatNodeHook func(*APIHeartbeat)
 
+	// Run by the leader when a member changes state, along with its name.
+	HeartbeatMemberStateHook func(name string, state string)
+
+	// State of each member as of the last heartbeat round, if leader.
+	memberStates map[int64]string
+
 	// NodeStore wrapper.
 	store *dqliteNodeStore
 
@@ -110,14 +110,15 @@ func (hbState *APIHeartbeat) Update(fullStateList bool, raftNodes []db.RaftNode,
 }
 
 // Send sends heartbeat requests to the nodes supplied and updates heartbeat state.
-func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, localAddress string, nodes []db.NodeInfo, delay bool) {
+// If spread is non-zero, the requests are spread in time over that interval.
+func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, localAddress string, nodes []db.NodeInfo, spread time.Duration) {
 	heartbeatsWg := sync.WaitGroup{}
-	sendHeartbeat := func(nodeID int64, address string, delay bool, heartbeatData *APIHeartbeat) {
+	sendHeartbeat := func(nodeID int64, address string, spread time.Duration, heartbeatData *APIHeartbeat) {
 		defer heartbeatsWg.Done()
 
-		if delay {
+		if spread > 0 {
 			// Spread in time by waiting up to 3s less than the interval.
-			time.Sleep(time.Duration(rand.Intn((heartbeatInterval*1000)-3000)) * time.Millisecond)
+			time.Sleep(time.Duration(rand.Intn(int(spread/time.Millisecond)-3000)) * time.Millisecond)
 		}
 		logger.Debugf("Sending heartbeat to %s", address)
 
@@ -157,7 +158,7 @@ func (hbState *APIHeartbeat) Send(ctx context.Context, cert *shared.CertInfo, lo
 
 		// Parallelize the rest.
 		heartbeatsWg.Add(1)
-		go sendHeartbeat(node.ID, node.Address, delay, hbState)
+		go sendHeartbeat(node.ID, node.Address, spread, hbState)
 	}
 	heartbeatsWg.Wait()
 }
@@ -182,11 +183,37 @@ func HeartbeatTask(gateway *Gateway) (task.Func, task.Schedule) {
 		}
 	}
 
-	schedule := task.Every(time.Duration(heartbeatInterval) * time.Second)
+	// The interval is looked up at each round, so that changes of the configuration apply.
+	schedule := func() (time.Duration, error) {
+		return gateway.heartbeatInterval(), nil
+	}
 
 	return heartbeatWrapper, schedule
 }
 
+// heartbeatInterval returns the configured interval between two heartbeat rounds.
+func (g *Gateway) heartbeatInterval() time.Duration {
+	interval := time.Duration(heartbeatIntervalDefault) * time.Second
+	if g.Cluster == nil {
+		return interval
+	}
+
+	err := g.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		interval = config.HeartbeatInterval()
+		return nil
+	})
+	if err != nil {
+		logger.Warnf("Failed to get heartbeat interval: %v", err)
+	}
+
+	return interval
+}
+
 func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	if g.Cluster == nil || g.server == nil || g.memoryDial != nil {
 		// We're not a raft node or we're not clustered
@@ -195,6 +222,10 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 
 	raftNodes, err := g.currentRaftNodes()
 	if err == ErrNotLeader {
+		// Forget the member states, which may be stale by the time we're leader again.
+		g.lock.Lock()
+		g.memberStates = nil
+		g.lock.Unlock()
 		return
 	}
 
@@ -220,6 +251,7 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	var allNodes []db.NodeInfo
 	var localAddress string // Address of this node
 	var offlineThreshold time.Duration
+	var interval time.Duration
 	err = g.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 		allNodes, err = tx.Nodes()
@@ -232,11 +264,14 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 			return err
 		}
 
-		offlineThreshold, err = tx.NodeOfflineThreshold()
+		config, err := ConfigLoad(tx)
 		if err != nil {
 			return err
 		}
 
+		offlineThreshold = config.OfflineThreshold()
+		interval = config.HeartbeatInterval()
+
 		return nil
 	})
 	if err != nil {
@@ -261,14 +296,14 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	// Send stale set to all nodes in database to get a fresh set of active nodes.
 	if initialHeartbeat {
 		hbState.Update(false, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, 0)
 
 		// We have the latest set of node states now, lets send that state set to all nodes.
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, 0)
 	} else {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, allNodes, true)
+		hbState.Send(ctx, g.cert, localAddress, allNodes, interval)
 	}
 
 	// Look for any new node which appeared since sending last heartbeat.
@@ -307,7 +342,7 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	// If any new nodes found, send heartbeat to just them (with full node state).
 	if len(newNodes) > 0 {
 		hbState.Update(true, raftNodes, allNodes, offlineThreshold)
-		hbState.Send(ctx, g.cert, localAddress, newNodes, false)
+		hbState.Send(ctx, g.cert, localAddress, newNodes, 0)
 	}
 
 	// If the context has been cancelled, return immediately.
@@ -333,6 +368,9 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 		logger.Warnf("Failed to update heartbeat: %v", err)
 	}
 
+	// Report the members whose state changed since the previous round.
+	g.heartbeatMemberStates(hbState, allNodes, offlineThreshold)
+
 	// If full node state was sent and node refresh task is specified, run it async.
 	if g.HeartbeatNodeHook != nil {
 		go g.HeartbeatNodeHook(hbState)
@@ -342,8 +380,59 @@ func (g *Gateway) heartbeat(ctx context.Context, initialHeartbeat bool) {
 	logger.Debugf("Completed heartbeat round")
 }
 
-// heartbeatInterval Number of seconds to wait between to heartbeat rounds.
-const heartbeatInterval = 10
+// Possible states of a cluster member, as seen by the leader through heartbeats.
+const (
+	MemberStateOnline   = "online"
+	MemberStateDegraded = "degraded"
+	MemberStateOffline  = "offline"
+)
+
+// heartbeatMemberStates computes the state of each member after a heartbeat round and runs the
+// member state hook for those whose state differs from the previous round. A member whose last
+// heartbeat failed is degraded until the offline threshold is reached.
+func (g *Gateway) heartbeatMemberStates(hbState *APIHeartbeat, nodes []db.NodeInfo, offlineThreshold time.Duration) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	states := map[int64]string{}
+	for _, node := range nodes {
+		member, ok := hbState.Members[node.ID]
+		if !ok {
+			continue
+		}
+
+		state := MemberStateOnline
+		if !member.updated {
+			state = MemberStateDegraded
+			if member.LastHeartbeat.Before(time.Now().Add(-offlineThreshold)) {
+				state = MemberStateOffline
+			}
+		}
+
+		states[node.ID] = state
+
+		// Nothing to compare to for new members, or right after becoming leader.
+		previous, ok := g.memberStates[node.ID]
+		if !ok || previous == state {
+			continue
+		}
+
+		logger.Infof("Cluster member %s is now %s", node.Name, state)
+
+		if g.HeartbeatMemberStateHook != nil {
+			go g.HeartbeatMemberStateHook(node.Name, state)
+		}
+	}
+
+	g.memberStates = states
+}
+
+// heartbeatIntervalDefault Number of seconds to wait between to heartbeat rounds, by default.
+const heartbeatIntervalDefault = 10
+
+// heartbeatIntervalMinimum Minimum number of seconds between two heartbeat rounds, as
+// heartbeats get spread over the interval minus 3 seconds.
+const heartbeatIntervalMinimum = 4
 
 // HeartbeatNode performs a single heartbeat request against the node with the given address.
 func HeartbeatNode(taskCtx context.Context, address string, cert *shared.CertInfo, heartbeatData *APIHeartbeat) error {
@@ -913,6 +913,7 @@ func Purge(cluster *db.Cluster, name string) error {
 func List(state *state.State) ([]api.ClusterMember, error) {
 	var err error
 	var nodes []db.NodeInfo
+	var nodesGroups map[int64][]string
 	var offlineThreshold time.Duration
 
 	err = state.Cluster.Transaction(func(tx *db.ClusterTx) error {
@@ -921,6 +922,11 @@ func List(state *state.State) ([]api.ClusterMember, error) {
 			return err
 		}
 
+		nodesGroups, err = tx.NodesClusterGroups()
+		if err != nil {
+			return err
+		}
+
 		offlineThreshold, err = tx.NodeOfflineThreshold()
 		if err != nil {
 			return err
@@ -940,6 +946,7 @@ func List(state *state.State) ([]api.ClusterMember, error) {
 		result[i].URL = fmt.Sprintf("https://%s", node.Address)
 		result[i].Database = shared.StringInSlice(string(db.ClusterRoleDatabase), node.Roles)
 		result[i].Roles = node.Roles
+		result[i].Groups = nodesGroups[node.ID]
 		result[i].Architecture, err = osarch.ArchitectureName(node.Architecture)
 		if err != nil {
 			return nil, err
@@ -949,6 +956,12 @@ func List(state *state.State) ([]api.ClusterMember, error) {
 			result[i].Status = "Offline"
 			result[i].Message = fmt.Sprintf(
 				"no heartbeat since %s", now.Sub(node.Heartbeat))
+		} else if node.State == db.ClusterMemberStateEvacuated {
+			result[i].Status = "Evacuated"
+			result[i].Message = "unavailable due to maintenance"
+		} else if node.State == db.ClusterMemberStateMaintenance {
+			result[i].Status = "Maintenance"
+			result[i].Message = "not accepting new instances"
 		} else {
 			result[i].Status = "Online"
 			result[i].Message = "fully operational"
@@ -2,7 +2,10 @@ package cluster
 
 import (
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
+	"strings"
 
 	dqlite "github.com/canonical/go-dqlite"
 	"github.com/lxc/lxd/lxd/db"
@@ -31,40 +34,87 @@ func ListDatabaseNodes(database *db.Node) ([]string, error) {
 }
 
 func Recover(database *db.Node) error {
-	// Figure out if we actually act as dqlite node.
-	var info *db.RaftNode
-	err := database.Transaction(func(tx *db.NodeTx) error {
-		var err error
-		info, err = node.DetermineRaftNode(tx)
+	info, err := localRaftNode(database)
+	if err != nil {
 		return err
-	})
+	}
+
+	nodes := []db.RaftNode{
+		{ID: info.ID, Address: info.Address},
+	}
+
+	return Reconfigure(database, nodes)
+}
+
+// Reconfigure replaces the raft configuration of the local database node with
+// the given one, for example to change the address of members or to demote
+// the ones which were lost. The local node must be a voter of the new
+// configuration.
+//
+// Changed member addresses are also applied to the cluster database the next
+// time the daemon starts.
+func Reconfigure(database *db.Node, nodes []db.RaftNode) error {
+	info, err := localRaftNode(database)
 	if err != nil {
-		return errors.Wrap(err, "Failed to determine node role")
+		return err
 	}
 
-	// If we're not a database node, return an error.
-	if info == nil {
-		return fmt.Errorf("This LXD instance has no database role")
+	var local *db.RaftNode
+	ids := map[uint64]bool{}
+	addresses := map[string]bool{}
+	for i, node := range nodes {
+		if ids[node.ID] {
+			return fmt.Errorf("Duplicate raft node ID %d", node.ID)
+		}
+
+		_, _, err := net.SplitHostPort(node.Address)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid address for raft node %d", node.ID)
+		}
+
+		if addresses[node.Address] {
+			return fmt.Errorf("Duplicate raft node address %q", node.Address)
+		}
+
+		ids[node.ID] = true
+		addresses[node.Address] = true
+
+		if node.ID == info.ID {
+			local = &nodes[i]
+		}
 	}
 
-	// If this is a standalone node not exposed to the network, return an
-	// error.
-	if info.Address == "" {
-		return fmt.Errorf("This LXD instance is not clustered")
+	if local == nil {
+		return fmt.Errorf("The local raft node (ID %d) must be part of the configuration", info.ID)
+	}
+
+	if local.Role != db.RaftVoter {
+		return fmt.Errorf("The local raft node (ID %d) must be a voter", info.ID)
+	}
+
+	var current []db.RaftNode
+	err = database.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		current, err = tx.RaftNodes()
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get current database nodes")
 	}
 
 	dir := filepath.Join(database.Dir(), "global")
 	server, err := dqlite.New(
 		uint64(info.ID),
-		info.Address,
+		local.Address,
 		dir,
 	)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create dqlite server")
 	}
 
-	cluster := []dqlite.NodeInfo{
-		{ID: uint64(info.ID), Address: info.Address},
+	cluster := make([]dqlite.NodeInfo, len(nodes))
+	for i, node := range nodes {
+		cluster[i] = dqlite.NodeInfo{ID: node.ID, Address: node.Address, Role: node.Role}
 	}
 
 	err = server.Recover(cluster)
@@ -72,16 +122,86 @@ func Recover(database *db.Node) error {
 		return errors.Wrap(err, "Failed to recover database state")
 	}
 
-	// Update the list of raft nodes.
+	// Update the list of raft nodes, along with our own address if it
+	// changed.
 	err = database.Transaction(func(tx *db.NodeTx) error {
-		nodes := []db.RaftNode{
-			{ID: info.ID, Address: info.Address},
+		err := tx.RaftNodesReplace(nodes)
+		if err != nil {
+			return err
+		}
+
+		if local.Address == info.Address {
+			return nil
 		}
-		return tx.RaftNodesReplace(nodes)
+
+		config, err := node.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		_, err = config.Patch(map[string]interface{}{"cluster.https_address": local.Address})
+		return err
 	})
 	if err != nil {
 		return errors.Wrap(err, "Failed to update database nodes")
 	}
 
+	// Queue the address changes for the cluster database, which can't be
+	// opened without a working raft cluster.
+	patch := ""
+	for _, before := range current {
+		for _, after := range nodes {
+			if before.ID != after.ID || before.Address == after.Address {
+				continue
+			}
+
+			patch += fmt.Sprintf("UPDATE nodes SET address = '%s' WHERE address = '%s';\n",
+				strings.Replace(after.Address, "'", "''", -1), strings.Replace(before.Address, "'", "''", -1))
+		}
+	}
+
+	if patch == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(filepath.Join(database.Dir(), "patch.global.sql"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open cluster database patch")
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(patch)
+	if err != nil {
+		return errors.Wrap(err, "Failed to write cluster database patch")
+	}
+
 	return nil
 }
+
+// Return the raft node of the local database node, failing if it isn't part
+// of the raft cluster.
+func localRaftNode(database *db.Node) (*db.RaftNode, error) {
+	// Figure out if we actually act as dqlite node.
+	var info *db.RaftNode
+	err := database.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		info, err = node.DetermineRaftNode(tx)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to determine node role")
+	}
+
+	// If we're not a database node, return an error.
+	if info == nil {
+		return nil, fmt.Errorf("This LXD instance has no database role")
+	}
+
+	// If this is a standalone node not exposed to the network, return an
+	// error.
+	if info.Address == "" {
+		return nil, fmt.Errorf("This LXD instance is not clustered")
+	}
+
+	return info, nil
+}
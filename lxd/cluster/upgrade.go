@@ -95,6 +95,18 @@ func MaybeUpdate(state *state.State) error {
 	return triggerUpdate()
 }
 
+// TriggerUpdate runs the LXD_CLUSTER_UPDATE executable in the background, so that the local node
+// gets upgraded and restarted on behalf of the upgrade coordinator.
+func TriggerUpdate() error {
+	if os.Getenv("LXD_CLUSTER_UPDATE") == "" {
+		return fmt.Errorf("No LXD_CLUSTER_UPDATE variable set, this node can't be upgraded remotely")
+	}
+
+	go triggerUpdate()
+
+	return nil
+}
+
 func triggerUpdate() error {
 	logger.Infof("Node is out-of-date with respect to other cluster nodes")
 
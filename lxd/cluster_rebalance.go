@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// clusterMemberLoad is the resource pressure of a cluster member, as fractions of its capacity.
+type clusterMemberLoad struct {
+	CPU    float64
+	Memory float64
+}
+
+// clusterMemberLoadFromState computes the load of a member from its one minute load average and
+// its memory usage.
+func clusterMemberLoadFromState(state *api.ClusterMemberState) clusterMemberLoad {
+	load := clusterMemberLoad{}
+	if state.SysInfo.CPUs > 0 && len(state.SysInfo.LoadAverages) > 0 {
+		load.CPU = state.SysInfo.LoadAverages[0] / float64(state.SysInfo.CPUs)
+	}
+
+	if state.SysInfo.TotalRAM > 0 {
+		load.Memory = float64(state.SysInfo.UsedRAM) / float64(state.SysInfo.TotalRAM)
+	}
+
+	return load
+}
+
+// Score returns the overall pressure of the member in percents, which is driven by its most
+// constrained resource.
+func (l clusterMemberLoad) Score() int64 {
+	score := l.CPU
+	if l.Memory > score {
+		score = l.Memory
+	}
+
+	return int64(score * 100)
+}
+
+// clusterRebalanceMove is a relocation of an instance proposed or performed by the rebalancer.
+type clusterRebalanceMove struct {
+	Project string `json:"project" yaml:"project"`
+	Name    string `json:"name" yaml:"name"`
+	Source  string `json:"source" yaml:"source"`
+	Target  string `json:"target" yaml:"target"`
+}
+
+// clusterRebalanceTask periodically checks the balance of the cluster from the leader.
+func clusterRebalanceTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		// Only the leader looks after the balance of the cluster, so that instances don't get
+		// moved concurrently by several members.
+		localAddress, err := node.ClusterAddress(d.db)
+		if err != nil {
+			logger.Errorf("Failed to get current node address: %v", err)
+			return
+		}
+
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil {
+			logger.Errorf("Failed to get leader node address: %v", err)
+			return
+		}
+
+		if localAddress != leader {
+			logger.Debug("Skipping cluster rebalancing task since we're not leader")
+			return
+		}
+
+		// Instances stay put while members run different versions.
+		upgrading, err := clusterUpgradeInProgress(d)
+		if err != nil {
+			logger.Error("Failed to check for a cluster upgrade", log.Ctx{"err": err})
+			return
+		}
+
+		if upgrading {
+			logger.Debug("Skipping cluster rebalancing task since the cluster is being upgraded")
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			return clusterRebalance(d, op)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterInstancesRebalance, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start cluster rebalancing operation", log.Ctx{"err": err})
+			return
+		}
+
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to rebalance the cluster", log.Ctx{"err": err})
+		}
+	}
+
+	schedule := func() (time.Duration, error) {
+		var interval time.Duration
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return errors.Wrap(err, "failed to load cluster configuration")
+			}
+			interval = config.RebalanceInterval()
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+// clusterRebalance compares the load of the members and, when the difference between the busiest
+// and the least busy one exceeds the threshold, moves one instance from the former to the latter
+// or only proposes to do so.
+func clusterRebalance(d *Daemon, op *operations.Operation) error {
+	var nodes []db.NodeInfo
+	var config *cluster.Config
+	var offlineThreshold time.Duration
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		config, err = cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.NodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err = tx.Nodes()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Gather the load of the members which can receive instances.
+	loads := map[string]int64{}
+	members := []db.NodeInfo{}
+	for _, member := range nodes {
+		if member.IsOffline(offlineThreshold) || member.State == db.ClusterMemberStateEvacuated || member.State == db.ClusterMemberStateMaintenance {
+			continue
+		}
+
+		client, err := cluster.Connect(member.Address, d.endpoints.NetworkCert(), true)
+		if err != nil {
+			return err
+		}
+
+		state, _, err := client.GetClusterMemberState(member.Name)
+		if err != nil {
+			logger.Warn("Failed to get cluster member state", log.Ctx{"member": member.Name, "err": err})
+			continue
+		}
+
+		loads[member.Name] = clusterMemberLoadFromState(state).Score()
+		members = append(members, member)
+	}
+
+	if len(members) < 2 {
+		return nil
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return loads[members[i].Name] > loads[members[j].Name]
+	})
+
+	source := members[0]
+	if loads[source.Name]-loads[members[len(members)-1].Name] < config.RebalanceThreshold() {
+		return nil
+	}
+
+	client, err := clusterEvacuateClient(d)
+	if err != nil {
+		return err
+	}
+
+	move, err := clusterRebalanceCandidate(d, client, source, members[1:], loads)
+	if err != nil || move == nil {
+		return err
+	}
+
+	err = op.UpdateMetadata(map[string]interface{}{"move": move})
+	if err != nil {
+		return err
+	}
+
+	if !config.RebalanceAutomatic() {
+		logger.Info("Cluster rebalancing proposed", log.Ctx{"project": move.Project, "instance": move.Name, "source": move.Source, "target": move.Target})
+		return nil
+	}
+
+	logger.Info("Rebalancing cluster", log.Ctx{"project": move.Project, "instance": move.Name, "source": move.Source, "target": move.Target})
+
+	// Live migration between members isn't available, so the instance is restarted.
+	err = clusterEvacuateStopRemote(client, move.Project, move.Name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to stop instance %q", move.Name)
+	}
+
+	err = clusterEvacuateMove(client, move.Project, move.Name, move.Target)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move instance %q", move.Name)
+	}
+
+	err = clusterEvacuateStart(client, move.Project, move.Name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to start instance %q", move.Name)
+	}
+
+	return nil
+}
+
+// clusterRebalanceCandidate picks a running instance of the source member which can be moved,
+// along with the least busy member of a compatible architecture to move it to.
+func clusterRebalanceCandidate(d *Daemon, client lxd.InstanceServer, source db.NodeInfo, targets []db.NodeInfo, loads map[string]int64) (*clusterRebalanceMove, error) {
+	var instances []db.Instance
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		instances, err = tx.InstanceList(db.InstanceFilter{Node: source.Name, Type: instancetype.Any})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Project != instances[j].Project {
+			return instances[i].Project < instances[j].Project
+		}
+
+		return instances[i].Name < instances[j].Name
+	})
+
+	sort.Slice(targets, func(i, j int) bool {
+		return loads[targets[i].Name] < loads[targets[j].Name]
+	})
+
+	for _, dbInst := range instances {
+		inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		config := inst.ExpandedConfig()
+		if config["cluster.rebalance"] != "" && !shared.IsTrue(config["cluster.rebalance"]) {
+			continue
+		}
+
+		// Leave alone the instances which are expected to go back to an evacuated member.
+		if config["volatile.evacuate.origin"] != "" || !clusterEvacuateMovable(inst) {
+			continue
+		}
+
+		// Moving stopped instances wouldn't relieve the member.
+		state, _, err := client.UseProject(inst.Project()).GetInstanceState(inst.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if state.StatusCode != api.Running {
+			continue
+		}
+
+		for _, target := range targets {
+			if target.Architecture != inst.Architecture() {
+				continue
+			}
+
+			return &clusterRebalanceMove{
+				Project: inst.Project(),
+				Name:    inst.Name(),
+				Source:  source.Name,
+				Target:  target.Name,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -346,6 +346,11 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 			return nil, err
 		}
 
+		err = imageEncryptFiles(d, hash)
+		if err != nil {
+			return nil, err
+		}
+
 		err = d.cluster.ImageAssociateNode(args.Project, hash)
 		if err != nil {
 			return nil, err
@@ -377,7 +382,7 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 		inst.Delete()
 	}()
 
-	err = s.Cluster.ImageLastAccessUpdate(hash, time.Now().UTC())
+	err = s.Cluster.ImageLastAccessUpdate(args.Project, hash, time.Now().UTC())
 	if err != nil {
 		return nil, fmt.Errorf("Error updating image last use date: %s", err)
 	}
@@ -424,7 +429,7 @@ func instanceCreateFromImage(d *Daemon, args db.InstanceArgs, hash string, op *o
 	return inst, nil
 }
 
-func instanceCreateAsCopy(s *state.State, args db.InstanceArgs, sourceInst instance.Instance, instanceOnly bool, refresh bool, op *operations.Operation) (instance.Instance, error) {
+func instanceCreateAsCopy(s *state.State, args db.InstanceArgs, sourceInst instance.Instance, instanceOnly bool, refresh bool, refreshExcludes []string, op *operations.Operation) (instance.Instance, error) {
 	var inst, revertInst instance.Instance
 	var err error
 
@@ -557,7 +562,7 @@ func instanceCreateAsCopy(s *state.State, args db.InstanceArgs, sourceInst insta
 		}
 
 		if refresh {
-			err = pool.RefreshInstance(inst, sourceInst, snapshots, op)
+			err = pool.RefreshInstance(inst, sourceInst, snapshots, refreshExcludes, op)
 			if err != nil {
 				return nil, errors.Wrap(err, "Refresh instance")
 			}
@@ -571,6 +576,10 @@ func instanceCreateAsCopy(s *state.State, args db.InstanceArgs, sourceInst insta
 		ct := inst.(*containerLXC)
 
 		if refresh {
+			if len(refreshExcludes) > 0 {
+				return nil, fmt.Errorf("Path exclusions aren't supported by the instance's storage pool")
+			}
+
 			err = ct.Storage().ContainerRefresh(inst, sourceInst, snapshots)
 			if err != nil {
 				return nil, err
@@ -810,6 +819,14 @@ func instanceCreateInternal(s *state.State, args db.InstanceArgs) (instance.Inst
 		checkedProfiles = append(checkedProfiles, profile)
 	}
 
+	// Check the networking restrictions of the project.
+	if !args.Snapshot {
+		err = projectCheckInstanceNetworkDevices(s.Cluster, args.Project, args.Devices.CloneNative(), args.Profiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if args.CreationDate.IsZero() {
 		args.CreationDate = time.Now().UTC()
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -18,11 +20,14 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/logtarget"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/termios"
 )
@@ -458,3 +463,32 @@ func containerConsoleLogDelete(d *Daemon, r *http.Request) response.Response {
 
 	return response.SmartError(nil)
 }
+
+// forwardConsoleLogsTask forwards the lines added to the console logs of the local instances to
+// the log targets, every 10 seconds.
+func forwardConsoleLogsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		if !d.logTargets.Enabled("console") {
+			return
+		}
+
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for console log forwarding", log.Ctx{"err": err})
+			return
+		}
+
+		consoles := make([]logtarget.Console, 0, len(instances))
+		for _, inst := range instances {
+			consoles = append(consoles, logtarget.Console{
+				Project:  inst.Project(),
+				Instance: inst.Name(),
+				Path:     inst.ConsoleBufferLogPath(),
+			})
+		}
+
+		d.logTargets.ReadConsoles(consoles)
+	}
+
+	return f, task.Every(10 * time.Second)
+}
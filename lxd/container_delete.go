@@ -9,6 +9,8 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
 )
 
 func containerDelete(d *Daemon, r *http.Request) response.Response {
@@ -37,7 +39,25 @@ func containerDelete(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("container is running"))
 	}
 
+	// Move the instance's volume to the trash instead of deleting it when its pool has one.
+	var trashPool storagePools.Pool
+	pool, err := storagePools.GetPoolByInstance(d.State(), c)
+	if err == nil && pool.Driver().Config()["trash.expiry"] != "" {
+		if shared.IsTrue(c.ExpandedConfig()["security.protection.delete"]) {
+			return response.BadRequest(fmt.Errorf("Instance is protected"))
+		}
+
+		trashPool = pool
+	}
+
 	rmct := func(op *operations.Operation) error {
+		if trashPool != nil {
+			err := trashPool.TrashInstance(c, op)
+			if err != nil {
+				return err
+			}
+		}
+
 		return c.Delete()
 	}
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -82,6 +83,28 @@ func containerFileGet(c instance.Instance, path string, r *http.Request) respons
 		"X-LXD-type": type_,
 	}
 
+	if type_ == "file" && r.Header.Get("If-None-Match") != "" {
+		// Let clients skip transferring files they already have.
+		checksum, err := containerFileChecksum(temp.Name())
+		if err != nil {
+			os.Remove(temp.Name())
+			return response.InternalError(err)
+		}
+
+		headers["ETag"] = checksum
+		if checksum == r.Header.Get("If-None-Match") {
+			os.Remove(temp.Name())
+			return response.ManualResponse(func(w http.ResponseWriter) error {
+				for k, v := range headers {
+					w.Header().Set(k, v)
+				}
+
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			})
+		}
+	}
+
 	if type_ == "file" || type_ == "symlink" {
 		// Make a file response struct
 		files := make([]response.FileResponseEntry, 1)
@@ -99,6 +122,23 @@ func containerFileGet(c instance.Instance, path string, r *http.Request) respons
 	}
 }
 
+// containerFileChecksum returns the SHA-256 checksum of a file pulled from an instance.
+func containerFileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
 func containerFilePost(c instance.Instance, path string, r *http.Request) response.Response {
 	// Extract file ownership and mode from headers
 	uid, gid, mode, type_, write := shared.ParseLXDFileHeaders(r.Header)
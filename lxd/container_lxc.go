@@ -225,6 +225,14 @@ func containerLXCCreate(s *state.State, args db.InstanceArgs) (instance.Instance
 
 	// Fill in any default volume config
 	volumeConfig := map[string]string{}
+	if !c.IsSnapshot() {
+		err = storagePools.VolumeFillProjectDefault(s, args.Project, volumeConfig, dbPool)
+		if err != nil {
+			c.Delete()
+			return nil, err
+		}
+	}
+
 	err = storagePools.VolumeFillDefault(storagePool, volumeConfig, dbPool)
 	if err != nil {
 		c.Delete()
@@ -264,6 +272,7 @@ func containerLXCCreate(s *state.State, args db.InstanceArgs) (instance.Instance
 	if !c.IsPrivileged() {
 		idmap, base, err = findIdmap(
 			s,
+			args.Project,
 			args.Name,
 			c.expandedConfig["security.idmap.isolated"],
 			c.expandedConfig["security.idmap.base"],
@@ -500,7 +509,27 @@ func idmapSize(state *state.State, isolatedStr string, size string) (int64, erro
 
 var idmapLock sync.Mutex
 
-func findIdmap(state *state.State, cName string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
+// projectIdmapIsolated returns whether the project's containers should share an idmap range
+// distinct from that of other projects.
+func projectIdmapIsolated(state *state.State, projectName string) (bool, error) {
+	isolated := false
+	err := state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		project, err := tx.ProjectGet(projectName)
+		if err != nil {
+			return err
+		}
+
+		isolated = shared.IsTrue(project.Config["security.idmap.isolated"])
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to load project %q", projectName)
+	}
+
+	return isolated, nil
+}
+
+func findIdmap(state *state.State, projectName string, cName string, isolatedStr string, configBase string, configSize string, rawIdmap string) (*idmap.IdmapSet, int64, error) {
 	isolated := false
 	if shared.IsTrue(isolatedStr) {
 		isolated = true
@@ -511,7 +540,17 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return nil, 0, err
 	}
 
+	// Containers which aren't individually isolated share a range with the rest of their
+	// project when the project is isolated.
+	projectIsolated := false
 	if !isolated {
+		projectIsolated, err = projectIdmapIsolated(state, projectName)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if !isolated && !projectIsolated {
 		newIdmapset := idmap.IdmapSet{Idmap: make([]idmap.IdmapEntry, len(state.OS.IdmapSet.Idmap))}
 		copy(newIdmapset.Idmap, state.OS.IdmapSet.Idmap)
 
@@ -525,7 +564,12 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 		return &newIdmapset, 0, nil
 	}
 
-	size, err := idmapSize(state, isolatedStr, configSize)
+	// Project wide ranges use the default isolated size so all the members agree on it.
+	if projectIsolated {
+		configSize = ""
+	}
+
+	size, err := idmapSize(state, "true", configSize)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -576,10 +620,8 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			continue
 		}
 
-		name := container.Name()
-
 		/* Don't change our map Just Because. */
-		if name == cName {
+		if container.Project() == projectName && container.Name() == cName {
 			continue
 		}
 
@@ -587,9 +629,7 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			continue
 		}
 
-		if !shared.IsTrue(container.ExpandedConfig()["security.idmap.isolated"]) {
-			continue
-		}
+		cIsolated := shared.IsTrue(container.ExpandedConfig()["security.idmap.isolated"])
 
 		cBase := int64(0)
 		if container.ExpandedConfig()["volatile.idmap.base"] != "" {
@@ -599,9 +639,27 @@ func findIdmap(state *state.State, cName string, isolatedStr string, configBase
 			}
 		}
 
-		cSize, err := idmapSize(state, container.ExpandedConfig()["security.idmap.isolated"], container.ExpandedConfig()["security.idmap.size"])
-		if err != nil {
-			return nil, 0, err
+		// Non-isolated containers only hold a range of their own when part of an isolated project.
+		if !cIsolated && cBase == 0 {
+			continue
+		}
+
+		// Re-use the range already allocated to the project.
+		if projectIsolated && !cIsolated && container.Project() == projectName {
+			set, err := mkIdmap(cBase, size)
+			if err != nil && err == idmap.ErrHostIdIsSubId {
+				return nil, 0, err
+			}
+
+			return set, cBase, nil
+		}
+
+		cSize := size
+		if cIsolated {
+			cSize, err = idmapSize(state, "true", container.ExpandedConfig()["security.idmap.size"])
+			if err != nil {
+				return nil, 0, err
+			}
 		}
 
 		mapentries = append(mapentries, &idmap.IdmapEntry{Hostid: int64(cBase), Maprange: cSize})
@@ -1023,6 +1081,87 @@ func (c *containerLXC) initLXC(config bool) error {
 		}
 	}
 
+	// Setup OCI application containers (run the image's entrypoint instead of a system init)
+	if c.localConfig["image.type"] == "oci" && c.localConfig["image.oci.entrypoint"] != "" {
+		initCmd, err := ociInitCommand(c.localConfig["image.oci.entrypoint"])
+		if err != nil {
+			return err
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.init.cmd", initCmd)
+		if err != nil {
+			return err
+		}
+
+		if c.localConfig["image.oci.cwd"] != "" {
+			err = lxcSetConfigItem(cc, "lxc.init.cwd", c.localConfig["image.oci.cwd"])
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, key := range []string{"uid", "gid"} {
+			value := c.localConfig[fmt.Sprintf("image.oci.%s", key)]
+			if value != "" && value != "0" {
+				err = lxcSetConfigItem(cc, fmt.Sprintf("lxc.init.%s", key), value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// The image environment comes first so that environment.* keys override it.
+		if c.localConfig["image.oci.env"] != "" {
+			env := []string{}
+			err = json.Unmarshal([]byte(c.localConfig["image.oci.env"]), &env)
+			if err != nil {
+				return errors.Wrap(err, "Invalid OCI environment")
+			}
+
+			for _, entry := range env {
+				err = lxcSetConfigItem(cc, "lxc.environment", entry)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Applications expect SIGTERM rather than the SIGPWR used to stop a system init.
+		err = lxcSetConfigItem(cc, "lxc.signal.halt", "SIGTERM")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Setup time namespace offsets
+	for _, clock := range []string{"boot", "monotonic"} {
+		offset := c.expandedConfig[fmt.Sprintf("linux.time.offset.%s", clock)]
+		if offset == "" {
+			continue
+		}
+
+		if !c.state.OS.LXCFeatures["time_namespace"] || !shared.PathExists("/proc/self/ns/time") {
+			return fmt.Errorf("Time namespace offsets require support from both the kernel and liblxc")
+		}
+
+		err = lxcSetConfigItem(cc, fmt.Sprintf("lxc.time.offset.%s", clock), offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Setup core scheduling
+	if shared.IsTrue(c.expandedConfig["security.sched_core"]) {
+		if !c.state.OS.CoreScheduling || !c.state.OS.LXCFeatures["core_scheduling"] {
+			return fmt.Errorf("Core scheduling requires support from both the kernel and liblxc")
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.sched.core", "1")
+		if err != nil {
+			return err
+		}
+	}
+
 	// Setup environment
 	for k, v := range c.expandedConfig {
 		if strings.HasPrefix(k, "environment.") {
@@ -1200,6 +1339,24 @@ func (c *containerLXC) initLXC(config bool) error {
 		}
 	}
 
+	// Disk priority
+	diskPriority := c.expandedConfig["limits.disk.priority"]
+	if diskPriority != "" {
+		if !c.state.OS.CGInfo.Supports(cgroup.BlkioWeight, cg) {
+			return fmt.Errorf("Cannot apply limits.disk.priority as no I/O weight cgroup controller is available")
+		}
+
+		weight, err := cgroup.ParseDiskPriority(diskPriority)
+		if err != nil {
+			return err
+		}
+
+		err = cg.SetBlkioWeight(fmt.Sprintf("%d", weight))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Processes
 	if c.state.OS.CGInfo.Supports(cgroup.Pids, cg) {
 		processes := c.expandedConfig["limits.processes"]
@@ -1931,7 +2088,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 		logger.Debugf("Container idmap changed, remapping")
 		c.updateProgress("Remapping container filesystem")
 
-		ourStart, err = c.mount()
+		ourStart, err = c.holdMount()
 		if err != nil {
 			return "", postStartHooks, errors.Wrap(err, "Storage start")
 		}
@@ -1951,7 +2108,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 			}
 			if err != nil {
 				if ourStart {
-					c.unmount()
+					c.releaseMount()
 				}
 				return "", postStartHooks, err
 			}
@@ -1967,7 +2124,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 			}
 			if err != nil {
 				if ourStart {
-					c.unmount()
+					c.releaseMount()
 				}
 				return "", postStartHooks, err
 			}
@@ -2180,7 +2337,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 	}
 
 	// Storage is guaranteed to be mountable now (must be called after devices setup).
-	ourStart, err = c.mount()
+	ourStart, err = c.holdMount()
 	if err != nil {
 		return "", postStartHooks, err
 	}
@@ -2197,7 +2354,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 	currentIdmapset, err := c.CurrentIdmap()
 	if err != nil {
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return "", postStartHooks, err
 	}
@@ -2210,7 +2367,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 	err = os.Chown(c.Path(), int(uid), 0)
 	if err != nil {
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return "", postStartHooks, err
 	}
@@ -2219,7 +2376,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 	err = os.Chmod(c.Path(), 0100)
 	if err != nil {
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return "", postStartHooks, err
 	}
@@ -2228,7 +2385,7 @@ func (c *containerLXC) startCommon() (string, []func() error, error) {
 	err = c.UpdateBackupFile()
 	if err != nil {
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return "", postStartHooks, err
 	}
@@ -2425,7 +2582,7 @@ func (c *containerLXC) OnStart() error {
 	c.fromHook = true
 
 	// Start the storage for this container
-	ourStart, err := c.mount()
+	ourStart, err := c.holdMount()
 	if err != nil {
 		return err
 	}
@@ -2434,7 +2591,7 @@ func (c *containerLXC) OnStart() error {
 	err = apparmor.LoadProfile(c)
 	if err != nil {
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return err
 	}
@@ -2447,7 +2604,7 @@ func (c *containerLXC) OnStart() error {
 		if err != nil {
 			apparmor.Destroy(c)
 			if ourStart {
-				c.unmount()
+				c.releaseMount()
 			}
 			return err
 		}
@@ -2457,7 +2614,7 @@ func (c *containerLXC) OnStart() error {
 		if err != nil {
 			apparmor.Destroy(c)
 			if ourStart {
-				c.unmount()
+				c.releaseMount()
 			}
 			return err
 		}
@@ -2467,7 +2624,7 @@ func (c *containerLXC) OnStart() error {
 	if err != nil {
 		apparmor.Destroy(c)
 		if ourStart {
-			c.unmount()
+			c.releaseMount()
 		}
 		return err
 	}
@@ -2720,6 +2877,12 @@ func (c *containerLXC) OnStopNS(target string, netns string) error {
 		return fmt.Errorf("Invalid stop target: %s", target)
 	}
 
+	// Record the network usage while the host side interfaces are still around.
+	err := instance.NetworkUsageUpdate(c)
+	if err != nil {
+		logger.Warn("Failed to record network usage", log.Ctx{"project": c.project, "name": c.name, "err": err})
+	}
+
 	// Clean up devices.
 	c.cleanupDevices(netns)
 
@@ -2764,7 +2927,7 @@ func (c *containerLXC) OnStop(target string) error {
 	}
 
 	// Stop the storage for this container
-	_, err = c.unmount()
+	_, err = c.releaseMount()
 	if err != nil {
 		if op != nil {
 			op.Done(err)
@@ -3125,6 +3288,7 @@ func (c *containerLXC) RenderState() (*api.InstanceState, error) {
 		status.Processes = c.processesState()
 	}
 	status.Disk = c.diskState()
+	status.NetworkUsage = instance.NetworkUsage(c)
 
 	return &status, nil
 }
@@ -3838,6 +4002,67 @@ func (c *containerLXC) CGroupSet(key string, value string) error {
 	return nil
 }
 
+// kernelLimits maps the limits.kernel.* names to their resource identifier.
+var kernelLimits = map[string]int{
+	"as":         unix.RLIMIT_AS,
+	"core":       unix.RLIMIT_CORE,
+	"cpu":        unix.RLIMIT_CPU,
+	"data":       unix.RLIMIT_DATA,
+	"fsize":      unix.RLIMIT_FSIZE,
+	"locks":      unix.RLIMIT_LOCKS,
+	"memlock":    unix.RLIMIT_MEMLOCK,
+	"msgqueue":   unix.RLIMIT_MSGQUEUE,
+	"nice":       unix.RLIMIT_NICE,
+	"nofile":     unix.RLIMIT_NOFILE,
+	"nproc":      unix.RLIMIT_NPROC,
+	"rss":        unix.RLIMIT_RSS,
+	"rtprio":     unix.RLIMIT_RTPRIO,
+	"rttime":     unix.RLIMIT_RTTIME,
+	"sigpending": unix.RLIMIT_SIGPENDING,
+	"stack":      unix.RLIMIT_STACK,
+}
+
+// setKernelLimit applies a limits.kernel.* value ("unlimited", "<limit>" or "<soft>:<hard>") to
+// the init process of the running container, mirroring what lxc.prlimit does on startup.
+func (c *containerLXC) setKernelLimit(name string, value string) error {
+	resource, ok := kernelLimits[name]
+	if !ok {
+		// Let liblxc deal with limits we don't know about on next start.
+		logger.Info("Kernel limit will only be applied on next container start", log.Ctx{"project": c.project, "name": c.name, "limit": name})
+		return nil
+	}
+
+	parseLimit := func(limit string) (uint64, error) {
+		if limit == "unlimited" {
+			return unix.RLIM_INFINITY, nil
+		}
+
+		return strconv.ParseUint(limit, 10, 64)
+	}
+
+	fields := strings.SplitN(value, ":", 2)
+	soft, err := parseLimit(fields[0])
+	if err != nil {
+		return fmt.Errorf("Invalid value for limits.kernel.%s: %s", name, value)
+	}
+
+	hard := soft
+	if len(fields) == 2 {
+		hard, err = parseLimit(fields[1])
+		if err != nil {
+			return fmt.Errorf("Invalid value for limits.kernel.%s: %s", name, value)
+		}
+	}
+
+	limit := unix.Rlimit{Cur: soft, Max: hard}
+	err = unix.Prlimit(c.InitPID(), resource, &limit, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to set limits.kernel.%s: %v", name, err)
+	}
+
+	return nil
+}
+
 func (c *containerLXC) VolatileSet(changes map[string]string) error {
 	// Sanity check
 	for key := range changes {
@@ -4118,7 +4343,7 @@ func (c *containerLXC) Update(args db.InstanceArgs, userRequested bool) error {
 	}
 
 	// If apparmor changed, re-validate the apparmor profile
-	if shared.StringInSlice("raw.apparmor", changedConfig) || shared.StringInSlice("security.nesting", changedConfig) {
+	if shared.StringInSlice("raw.apparmor", changedConfig) || shared.StringInSlice("security.apparmor.allow", changedConfig) || shared.StringInSlice("security.apparmor.deny", changedConfig) || shared.StringInSlice("security.nesting", changedConfig) {
 		err = apparmor.ParseProfile(c)
 		if err != nil {
 			return errors.Wrap(err, "Parse AppArmor profile")
@@ -4132,6 +4357,7 @@ func (c *containerLXC) Update(args db.InstanceArgs, userRequested bool) error {
 			// update the idmap
 			idmap, base, err = findIdmap(
 				c.state,
+				c.Project(),
 				c.Name(),
 				c.expandedConfig["security.idmap.isolated"],
 				c.expandedConfig["security.idmap.base"],
@@ -4189,7 +4415,7 @@ func (c *containerLXC) Update(args db.InstanceArgs, userRequested bool) error {
 		for _, key := range changedConfig {
 			value := c.expandedConfig[key]
 
-			if key == "raw.apparmor" || key == "security.nesting" {
+			if shared.StringInSlice(key, []string{"raw.apparmor", "security.apparmor.allow", "security.apparmor.deny", "security.nesting"}) {
 				// Update the AppArmor profile
 				err = apparmor.LoadProfile(c)
 				if err != nil {
@@ -4212,6 +4438,53 @@ func (c *containerLXC) Update(args db.InstanceArgs, userRequested bool) error {
 						return err
 					}
 				}
+			} else if key == "raw.lxc" {
+				changes, restartKeys, err := instance.RawLXCLiveChanges(oldExpandedConfig["raw.lxc"], value)
+				if err != nil {
+					return err
+				}
+
+				// Only apply the changes if all of them can be, so that the running container
+				// doesn't end up with part of them.
+				if len(restartKeys) > 0 {
+					logger.Warn("The raw.lxc changes will only be applied on next container start", log.Ctx{"project": c.project, "name": c.name, "restartKeys": strings.Join(restartKeys, ", ")})
+					continue
+				}
+
+				// Restore the settings already changed if one of them fails.
+				oldValues := map[string]string{}
+				revertCGroups := func() {
+					for cgroupKey, oldValue := range oldValues {
+						c.CGroupSet(cgroupKey, oldValue)
+					}
+				}
+
+				for lxcKey, lxcValue := range changes {
+					cgroupKey := strings.TrimPrefix(strings.TrimPrefix(lxcKey, "lxc.cgroup2."), "lxc.cgroup.")
+					oldValue, err := c.CGroupGet(cgroupKey)
+					if err != nil {
+						revertCGroups()
+						return err
+					}
+
+					err = c.CGroupSet(cgroupKey, lxcValue)
+					if err != nil {
+						revertCGroups()
+						return err
+					}
+
+					oldValues[cgroupKey] = oldValue
+				}
+			} else if strings.HasPrefix(key, "limits.kernel.") {
+				// Removed limits can only be reset on next start.
+				if value == "" {
+					continue
+				}
+
+				err = c.setKernelLimit(strings.TrimPrefix(key, "limits.kernel."), value)
+				if err != nil {
+					return err
+				}
 			} else if key == "linux.kernel_modules" && value != "" {
 				for _, module := range strings.Split(value, ",") {
 					module = strings.TrimPrefix(module, " ")
@@ -4221,26 +4494,16 @@ func (c *containerLXC) Update(args db.InstanceArgs, userRequested bool) error {
 					}
 				}
 			} else if key == "limits.disk.priority" {
-				if !c.state.OS.CGInfo.Supports(cgroup.Blkio, cg) {
+				if !c.state.OS.CGInfo.Supports(cgroup.BlkioWeight, cg) {
 					continue
 				}
 
-				priorityInt := 5
-				diskPriority := c.expandedConfig["limits.disk.priority"]
-				if diskPriority != "" {
-					priorityInt, err = strconv.Atoi(diskPriority)
-					if err != nil {
-						return err
-					}
-				}
-
-				// Minimum valid value is 10
-				priority := priorityInt * 100
-				if priority == 0 {
-					priority = 10
+				weight, err := cgroup.ParseDiskPriority(c.expandedConfig["limits.disk.priority"])
+				if err != nil {
+					return err
 				}
 
-				cg.SetBlkioWeight(fmt.Sprintf("%d", priority))
+				err = cg.SetBlkioWeight(fmt.Sprintf("%d", weight))
 				if err != nil {
 					return err
 				}
@@ -6083,6 +6346,43 @@ func (c *containerLXC) mount() (bool, error) {
 	return isOurOperation, err
 }
 
+// holdMount mounts the instance's rootfs volume and keeps it mounted while the instance runs, even
+// once the operations which mount it in the meantime are done with it, until releaseMount is called.
+func (c *containerLXC) holdMount() (bool, error) {
+	// Check if we can load new storage layer for pool driver type.
+	pool, err := c.getStoragePool()
+	if err != storageDrivers.ErrUnknownDriver && err != storageDrivers.ErrNotImplemented {
+		if err != nil {
+			return false, err
+		}
+
+		err = pool.HoldInstanceMount(c, nil)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return c.mount()
+}
+
+// releaseMount releases the mount of the instance's rootfs volume held by holdMount, unmounting the
+// volume unless an operation is still using it.
+func (c *containerLXC) releaseMount() (bool, error) {
+	// Check if we can load new storage layer for pool driver type.
+	pool, err := c.getStoragePool()
+	if err != storageDrivers.ErrUnknownDriver && err != storageDrivers.ErrNotImplemented {
+		if err != nil {
+			return false, err
+		}
+
+		return pool.ReleaseInstanceMount(c, nil)
+	}
+
+	return c.unmount()
+}
+
 // StorageStop unmounts the instance's rootfs volume. Deprecated.
 func (c *containerLXC) StorageStop() (bool, error) {
 	return c.unmount()
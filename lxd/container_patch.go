@@ -120,6 +120,11 @@ func containerPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	err = projectCheckInstanceNetworkDevices(d.cluster, project, req.Devices, req.Profiles)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Update container configuration
 	args := db.InstanceArgs{
 		Architecture: architecture,
@@ -171,6 +171,42 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 		stateful = req.Live
 	}
 
+	// Move the instance to another project, under the same name.
+	if req.Project != "" && req.Project != project {
+		if req.Migration || targetNode != "" || (req.Name != "" && req.Name != name) {
+			return response.BadRequest(fmt.Errorf("Instances can't be renamed or migrated while being moved to another project"))
+		}
+
+		if !d.userHasPermission(r, req.Project, "manage-containers") {
+			return response.Forbidden(nil)
+		}
+
+		_, err = projectLoad(d.cluster, req.Project)
+		if err != nil {
+			return response.SmartError(errors.Wrapf(err, "Failed to load project %q", req.Project))
+		}
+
+		id, _ := d.cluster.ContainerID(req.Project, name)
+		if id > 0 {
+			return response.Conflict(fmt.Errorf("Name '%s' already in use in project %q", name, req.Project))
+		}
+
+		run := func(op *operations.Operation) error {
+			return instanceMoveProject(d.State(), inst, req.Project, op)
+		}
+
+		resources := map[string][]string{}
+		resources["instances"] = []string{name}
+		resources["containers"] = resources["instances"]
+
+		op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationInstanceProjectMove, resources, nil, run, nil, nil)
+		if err != nil {
+			return response.InternalError(err)
+		}
+
+		return operations.OperationResponse(op)
+	}
+
 	if req.Migration {
 		if targetNode != "" {
 			// Check whether the container is running.
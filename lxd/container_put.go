@@ -68,6 +68,11 @@ func containerPut(d *Daemon, r *http.Request) response.Response {
 	var do func(*operations.Operation) error
 	var opType db.OperationType
 	if configRaw.Restore == "" {
+		err = projectCheckInstanceNetworkDevices(d.cluster, project, configRaw.Devices, configRaw.Profiles)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		// Update container configuration
 		do = func(op *operations.Operation) error {
 			args := db.InstanceArgs{
@@ -131,10 +136,21 @@ func instanceSnapRestore(s *state.State, project, name, snap string, stateful bo
 		}
 	}
 
+	// Check the custom volumes snapshotted together with the instance can be restored.
+	volumes, err := instanceSnapshotGroupVolumes(s, inst, source)
+	if err != nil {
+		return err
+	}
+
 	err = inst.Restore(source, stateful)
 	if err != nil {
 		return err
 	}
 
+	err = instanceSnapshotGroupRestore(s, volumes)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Reset the root filesystem of an instance to its image or to one of its
+// snapshots, keeping its config, devices and attached volumes.
+func containerResetPost(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to a container on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	req := api.InstanceResetPost{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if inst.IsSnapshot() {
+		return response.BadRequest(fmt.Errorf("Snapshots can't be reset"))
+	}
+
+	// Stopping a running ephemeral instance would delete it.
+	if inst.IsEphemeral() && inst.IsRunning() {
+		return response.BadRequest(fmt.Errorf("Running ephemeral instances can't be reset"))
+	}
+
+	// Resolve the reset source up front so bad requests fail synchronously.
+	var source instance.Instance
+	fingerprint := ""
+	if req.Snapshot != "" {
+		snapName := req.Snapshot
+		if !shared.IsSnapshot(snapName) {
+			snapName = name + shared.SnapshotDelimiter + snapName
+		}
+
+		source, err = instance.LoadByProjectAndName(d.State(), project, snapName)
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				return response.BadRequest(fmt.Errorf("Snapshot %s does not exist", snapName))
+			}
+
+			return response.SmartError(err)
+		}
+	} else {
+		fingerprint = inst.LocalConfig()["volatile.base_image"]
+		if fingerprint == "" {
+			return response.BadRequest(fmt.Errorf("Instance wasn't created from an image, a snapshot must be specified"))
+		}
+
+		_, _, err = d.cluster.ImageGet(project, fingerprint, false, true)
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				return response.BadRequest(fmt.Errorf("Image %s the instance was created from no longer exists", fingerprint))
+			}
+
+			return response.SmartError(err)
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		return instanceReset(d, inst, source, fingerprint, op)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{name}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationInstanceReset, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceReset resets the instance's root volume to either the source snapshot or the image with
+// the given fingerprint. A running instance is stopped for the duration of the reset.
+func instanceReset(d *Daemon, inst instance.Instance, source instance.Instance, fingerprint string, op *operations.Operation) error {
+	s := d.State()
+
+	pool, err := storagePools.GetPoolByInstance(s, inst)
+	if err == storageDrivers.ErrUnknownDriver || err == storageDrivers.ErrNotImplemented {
+		return fmt.Errorf("Instance reset isn't supported by the instance's storage pool")
+	} else if err != nil {
+		return err
+	}
+
+	if fingerprint != "" {
+		err = instanceResetImageEnsure(d, inst.Project(), fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	wasRunning := inst.IsRunning()
+	if wasRunning {
+		err = inst.Stop(false)
+		if err != nil {
+			return err
+		}
+
+		// Bring the instance back up on its untouched volume if the reset fails.
+		revert.Add(func() { inst.Start(false) })
+	}
+
+	ctxMap := log.Ctx{"project": inst.Project(), "name": inst.Name()}
+	if source != nil {
+		ctxMap["source"] = source.Name()
+	} else {
+		ctxMap["image"] = fingerprint
+	}
+
+	logger.Info("Resetting instance", ctxMap)
+
+	if source != nil {
+		// Driver level restore, the instance config is intentionally not restored.
+		err = pool.RestoreInstanceSnapshot(inst, source, op)
+	} else {
+		err = pool.ResetInstance(inst, fingerprint, op)
+		if err == nil {
+			err = s.Cluster.ImageLastAccessUpdate(inst.Project(), fingerprint, time.Now().UTC())
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+
+	err = inst.UpdateBackupFile()
+	if err != nil {
+		return err
+	}
+
+	if wasRunning {
+		return inst.Start(false)
+	}
+
+	return nil
+}
+
+// instanceResetImageEnsure makes sure the image file is present on this node, fetching it from
+// another cluster member if needed.
+func instanceResetImageEnsure(d *Daemon, project string, fingerprint string) error {
+	nodeAddress, err := d.cluster.ImageLocate(fingerprint)
+	if err != nil {
+		return errors.Wrapf(err, "Locate image %s in the cluster", fingerprint)
+	}
+
+	if nodeAddress == "" {
+		return nil
+	}
+
+	logger.Debugf("Transferring image %s from node %s", fingerprint, nodeAddress)
+	client, err := cluster.Connect(nodeAddress, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	client = client.UseProject(project)
+
+	err = imageImportFromNode(filepath.Join(d.os.VarDir, "images"), client, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	err = imageEncryptFiles(d, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	return d.cluster.ImageAssociateNode(project, fingerprint)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -134,6 +135,10 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Snapshot names may not contain slashes"))
 	}
 
+	if req.Volumes && req.Stateful {
+		return response.BadRequest(fmt.Errorf("Stateful snapshots can't be taken together with custom volumes"))
+	}
+
 	fullName := name +
 		shared.SnapshotDelimiter +
 		req.Name
@@ -163,6 +168,10 @@ func containerSnapshotsPost(d *Daemon, r *http.Request) response.Response {
 			ExpiryDate:   expiry,
 		}
 
+		if req.Volumes {
+			return instanceCreateSnapshotGroup(d.State(), args, inst, op)
+		}
+
 		_, err := instanceCreateAsSnapshot(d.State(), args, inst, op)
 		if err != nil {
 			return err
@@ -228,6 +237,64 @@ func containerSnapshotHandler(d *Daemon, r *http.Request) response.Response {
 	}
 }
 
+// containerSnapshotDiffGet returns the files changed since a snapshot, up to the more recent
+// snapshot given by the "to" parameter or to the current state of the instance.
+func containerSnapshotDiffGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	containerName := mux.Vars(r)["name"]
+
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, containerName, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	snapshotName, err := url.QueryUnescape(mux.Vars(r)["snapshotName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), project, containerName+shared.SnapshotDelimiter+snapshotName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Compare to the instance itself unless given a more recent snapshot.
+	targetName := containerName
+	to := queryParam(r, "to")
+	if to != "" {
+		targetName = containerName + shared.SnapshotDelimiter + to
+	}
+
+	target, err := instance.LoadByProjectAndName(d.State(), project, targetName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if target.IsSnapshot() && !target.CreationDate().After(inst.CreationDate()) {
+		return response.BadRequest(fmt.Errorf("Snapshot %q isn't more recent than %q", to, snapshotName))
+	}
+
+	pool, err := storagePools.GetPoolByInstance(d.State(), inst)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	entries, err := pool.DiffInstanceSnapshot(inst, target, nil)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, entries)
+}
+
 func snapshotPut(d *Daemon, r *http.Request, sc instance.Instance, name string) response.Response {
 	// Validate the ETag
 	etag := []interface{}{sc.ExpiryDate()}
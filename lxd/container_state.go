@@ -83,6 +83,25 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	opType, do, err := instanceStateAction(d, c, raw)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{name}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, opType, resources, nil, do, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceStateAction returns the type of the operation changing the state of an instance as
+// requested and the function doing it.
+func instanceStateAction(d *Daemon, c instance.Instance, raw api.InstanceStatePut) (db.OperationType, func(op *operations.Operation) error, error) {
 	var opType db.OperationType
 	var do func(*operations.Operation) error
 	switch shared.InstanceAction(raw.Action) {
@@ -90,9 +109,11 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		opType = db.OperationContainerStart
 		do = func(op *operations.Operation) error {
 			c.SetOperation(op)
-			if err = c.Start(raw.Stateful); err != nil {
+			err := c.Start(raw.Stateful)
+			if err != nil {
 				return err
 			}
+
 			return nil
 		}
 	case shared.Stop:
@@ -110,7 +131,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		} else if raw.Timeout == 0 || raw.Force {
 			do = func(op *operations.Operation) error {
 				c.SetOperation(op)
-				err = c.Stop(false)
+				err := c.Stop(false)
 				if err != nil {
 					return err
 				}
@@ -127,7 +148,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 					}
 				}
 
-				err = c.Shutdown(time.Duration(raw.Timeout) * time.Second)
+				err := c.Shutdown(time.Duration(raw.Timeout) * time.Second)
 				if err != nil {
 					return err
 				}
@@ -168,7 +189,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 			}
 
 			if raw.Timeout == 0 || raw.Force {
-				err = c.Stop(false)
+				err := c.Stop(false)
 				if err != nil {
 					return err
 				}
@@ -177,13 +198,13 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 					return fmt.Errorf("container is not running")
 				}
 
-				err = c.Shutdown(time.Duration(raw.Timeout) * time.Second)
+				err := c.Shutdown(time.Duration(raw.Timeout) * time.Second)
 				if err != nil {
 					return err
 				}
 			}
 
-			err = c.Start(false)
+			err := c.Start(false)
 			if err != nil {
 				return err
 			}
@@ -192,7 +213,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		}
 	case shared.Freeze:
 		if !d.os.CGInfo.Supports(cgroup.Freezer, nil) {
-			return response.BadRequest(fmt.Errorf("This system doesn't support freezing containers"))
+			return db.OperationUnknown, nil, fmt.Errorf("This system doesn't support freezing containers")
 		}
 
 		opType = db.OperationContainerFreeze
@@ -202,7 +223,7 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 		}
 	case shared.Unfreeze:
 		if !d.os.CGInfo.Supports(cgroup.Freezer, nil) {
-			return response.BadRequest(fmt.Errorf("This system doesn't support unfreezing containers"))
+			return db.OperationUnknown, nil, fmt.Errorf("This system doesn't support unfreezing containers")
 		}
 
 		opType = db.OperationContainerUnfreeze
@@ -211,16 +232,8 @@ func containerStatePut(d *Daemon, r *http.Request) response.Response {
 			return c.Unfreeze()
 		}
 	default:
-		return response.BadRequest(fmt.Errorf("unknown action %s", raw.Action))
+		return db.OperationUnknown, nil, fmt.Errorf("unknown action %s", raw.Action)
 	}
 
-	resources := map[string][]string{}
-	resources["containers"] = []string{name}
-
-	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, opType, resources, nil, do, nil, nil)
-	if err != nil {
-		return response.InternalError(err)
-	}
-
-	return operations.OperationResponse(op)
+	return opType, do, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -26,6 +27,7 @@ var instancesCmd = APIEndpoint{
 
 	Get:  APIEndpointAction{Handler: containersGet, AccessHandler: AllowProjectPermission("containers", "view")},
 	Post: APIEndpointAction{Handler: containersPost, AccessHandler: AllowProjectPermission("containers", "manage-containers")},
+	Put:  APIEndpointAction{Handler: containersPut, AccessHandler: AllowProjectPermission("containers", "operate-containers")},
 }
 
 var instanceCmd = APIEndpoint{
@@ -55,6 +57,17 @@ var instanceStateCmd = APIEndpoint{
 	Put: APIEndpointAction{Handler: containerStatePut, AccessHandler: AllowProjectPermission("containers", "operate-containers")},
 }
 
+var instanceResetCmd = APIEndpoint{
+	Name: "instanceReset",
+	Path: "instances/{name}/reset",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerReset", Path: "containers/{name}/reset"},
+		{Name: "vmReset", Path: "virtual-machines/{name}/reset"},
+	},
+
+	Post: APIEndpointAction{Handler: containerResetPost, AccessHandler: AllowProjectPermission("containers", "manage-containers")},
+}
+
 var instanceFileCmd = APIEndpoint{
 	Name: "instanceFile",
 	Path: "instances/{name}/files",
@@ -94,6 +107,16 @@ var instanceSnapshotCmd = APIEndpoint{
 	Put:    APIEndpointAction{Handler: containerSnapshotHandler, AccessHandler: AllowProjectPermission("containers", "operate-containers")},
 }
 
+var instanceSnapshotDiffCmd = APIEndpoint{
+	Name: "instanceSnapshotDiff",
+	Path: "instances/{name}/snapshots/{snapshotName}/diff",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerSnapshotDiff", Path: "containers/{name}/snapshots/{snapshotName}/diff"},
+	},
+
+	Get: APIEndpointAction{Handler: containerSnapshotDiffGet, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
 var instanceConsoleCmd = APIEndpoint{
 	Name: "instanceConsole",
 	Path: "instances/{name}/console",
@@ -213,36 +236,83 @@ func containersRestart(s *state.State) error {
 	instances := []instance.Instance{}
 
 	for _, c := range result {
-		instances = append(instances, c)
+		config := c.ExpandedConfig()
+		lastState := config["volatile.last_state.power"]
+		autoStart := config["boot.autostart"]
+
+		if shared.IsTrue(autoStart) || (autoStart == "" && lastState == "RUNNING") {
+			instances = append(instances, c)
+		}
 	}
 
 	sort.Sort(containerAutostartList(instances))
 
-	// Restart the instances
+	concurrency, err := node.StartupConcurrency(s.Node)
+	if err != nil {
+		return err
+	}
+
+	// Instances of the same priority are started in parallel, bounded by
+	// the startup concurrency, and each priority only gets started once
+	// the higher ones are up. Instances with a delay are started on their
+	// own, the next instance only being started once the delay elapsed.
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+
+	var lastPriority int
+
+	if len(instances) != 0 {
+		lastPriority, _ = strconv.Atoi(instances[0].ExpandedConfig()["boot.autostart.priority"])
+	}
+
 	for _, c := range instances {
-		config := c.ExpandedConfig()
-		lastState := config["volatile.last_state.power"]
+		priority, _ := strconv.Atoi(c.ExpandedConfig()["boot.autostart.priority"])
 
-		autoStart := config["boot.autostart"]
-		autoStartDelay := config["boot.autostart.delay"]
+		// Enforce startup priority
+		if priority != lastPriority {
+			lastPriority = priority
 
-		if shared.IsTrue(autoStart) || (autoStart == "" && lastState == "RUNNING") {
-			if c.IsRunning() {
-				continue
-			}
+			// Wait for instances with higher priority to be started
+			wg.Wait()
+		}
+
+		if c.IsRunning() {
+			continue
+		}
+
+		autoStartDelay, err := strconv.Atoi(c.ExpandedConfig()["boot.autostart.delay"])
+		if err == nil && autoStartDelay > 0 {
+			// Wait for the instances already being started, then start
+			// this one and wait for its delay before starting the next.
+			wg.Wait()
 
 			err = c.Start(false)
 			if err != nil {
 				logger.Errorf("Failed to start container '%s': %v", c.Name(), err)
 			}
 
-			autoStartDelayInt, err := strconv.Atoi(autoStartDelay)
-			if err == nil {
-				time.Sleep(time.Duration(autoStartDelayInt) * time.Second)
-			}
+			time.Sleep(time.Duration(autoStartDelay) * time.Second)
+			continue
 		}
+
+		// Start the instance
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(c instance.Instance) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			err := c.Start(false)
+			if err != nil {
+				logger.Errorf("Failed to start container '%s': %v", c.Name(), err)
+			}
+		}(c)
 	}
 
+	wg.Wait()
+
 	return nil
 }
 
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,8 +45,13 @@ func urlInstanceTypeDetect(r *http.Request) (instancetype.Type, error) {
 }
 
 func containersGet(d *Daemon, r *http.Request) response.Response {
+	filter, err := filterParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	for i := 0; i < 100; i++ {
-		result, err := doContainersGet(d, r)
+		result, err := doContainersGet(d, r, filter)
 		if err == nil {
 			return response.SyncResponse(true, result)
 		}
@@ -63,7 +69,7 @@ func containersGet(d *Daemon, r *http.Request) response.Response {
 	return response.InternalError(fmt.Errorf("DB is locked"))
 }
 
-func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
+func doContainersGet(d *Daemon, r *http.Request, filter *query.Filter) (interface{}, error) {
 	resultString := []string{}
 	resultList := []*api.Instance{}
 	resultFullList := []*api.InstanceFull{}
@@ -88,6 +94,7 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	// Get the list and location of all containers
 	var result map[string][]string // Containers by node address
 	var nodes map[string]string    // Node names by container
+	var page map[string]int        // Position of the containers matching the filter
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 
@@ -101,12 +108,47 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 			return err
 		}
 
+		if filter != nil {
+			names, err := tx.InstanceNamesFiltered(project, instanceType, filter)
+			if err != nil {
+				return err
+			}
+
+			page = map[string]int{}
+			for i, name := range names {
+				page[name] = i
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
 		return []string{}, err
 	}
 
+	// Only keep the containers in the requested page
+	inPage := func(name string) bool {
+		if page == nil {
+			return true
+		}
+
+		_, ok := page[name]
+		return ok
+	}
+
+	if page != nil {
+		for address, containers := range result {
+			pageContainers := []string{}
+			for _, container := range containers {
+				if inPage(container) {
+					pageContainers = append(pageContainers, container)
+				}
+			}
+
+			result[address] = pageContainers
+		}
+	}
+
 	// Get the local instances
 	nodeCts := map[string]instance.Instance{}
 	if recursion > 0 {
@@ -122,6 +164,10 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 
 	// Append containers to list and handle errors
 	resultListAppend := func(name string, c api.Instance, err error) {
+		if !inPage(name) {
+			return
+		}
+
 		if err != nil {
 			c = api.Instance{
 				Name:       name,
@@ -136,6 +182,10 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	}
 
 	resultFullListAppend := func(name string, c api.InstanceFull, err error) {
+		if !inPage(name) {
+			return
+		}
+
 		if err != nil {
 			c = api.InstanceFull{Instance: api.Instance{
 				Name:       name,
@@ -275,22 +325,35 @@ func doContainersGet(d *Daemon, r *http.Request) (interface{}, error) {
 	}
 	wg.Wait()
 
+	// Sort the result list by name, or in the requested order.
+	less := func(a string, b string) bool {
+		if page != nil {
+			return page[a] < page[b]
+		}
+
+		return a < b
+	}
+
 	if recursion == 0 {
+		if page != nil {
+			sort.Slice(resultString, func(i, j int) bool {
+				return less(path.Base(resultString[i]), path.Base(resultString[j]))
+			})
+		}
+
 		return resultString, nil
 	}
 
 	if recursion == 1 {
-		// Sort the result list by name.
 		sort.Slice(resultList, func(i, j int) bool {
-			return resultList[i].Name < resultList[j].Name
+			return less(resultList[i].Name, resultList[j].Name)
 		})
 
 		return resultList, nil
 	}
 
-	// Sort the result list by name.
 	sort.Slice(resultFullList, func(i, j int) bool {
-		return resultFullList[i].Name < resultFullList[j].Name
+		return less(resultFullList[i].Name, resultFullList[j].Name)
 	})
 
 	return resultFullList, nil
@@ -153,6 +153,10 @@ func createFromMigration(d *Daemon, project string, req *api.InstancesPost) resp
 		return response.NotImplemented(fmt.Errorf("Mode '%s' not implemented", req.Source.Mode))
 	}
 
+	if len(req.Source.RefreshExcludes) > 0 {
+		return response.BadRequest(fmt.Errorf("Path exclusions are only supported when refreshing within the same server"))
+	}
+
 	// Parse the architecture name
 	architecture, err := osarch.ArchitectureId(req.Architecture)
 	if err != nil {
@@ -417,6 +421,15 @@ func createFromMigration(d *Daemon, project string, req *api.InstancesPost) resp
 		}
 	}
 
+	// The instance was created before the operation, so name it explicitly for it to be deleted
+	// if the migration is interrupted.
+	if !req.Source.Refresh {
+		err = op.SetResumeData(operationRollback{Instance: req.Name})
+		if err != nil {
+			logger.Warn("Failed to persist the instance migration", log.Ctx{"err": err, "operation": op.ID()})
+		}
+	}
+
 	revert = false
 	return operations.OperationResponse(op)
 }
@@ -426,6 +439,13 @@ func createFromCopy(d *Daemon, project string, req *api.InstancesPost) response.
 		return response.BadRequest(fmt.Errorf("must specify a source container"))
 	}
 
+	// The excluded paths must be within the instance's root filesystem.
+	for _, exclude := range req.Source.RefreshExcludes {
+		if exclude == "" || shared.StringInSlice("..", strings.Split(exclude, "/")) {
+			return response.BadRequest(fmt.Errorf("Invalid refresh exclusion path %q", exclude))
+		}
+	}
+
 	sourceProject := req.Source.Project
 	if sourceProject == "" {
 		sourceProject = project
@@ -570,7 +590,7 @@ func createFromCopy(d *Daemon, project string, req *api.InstancesPost) response.
 
 	run := func(op *operations.Operation) error {
 		instanceOnly := req.Source.InstanceOnly || req.Source.ContainerOnly
-		_, err := instanceCreateAsCopy(d.State(), args, source, instanceOnly, req.Source.Refresh, op)
+		_, err := instanceCreateAsCopy(d.State(), args, source, instanceOnly, req.Source.Refresh, req.Source.RefreshExcludes, op)
 		if err != nil {
 			return err
 		}
@@ -768,6 +788,18 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	targetNode := queryParam(r, "target")
+
+	// Check the target against the cluster groups the project is restricted to. A target
+	// starting with "@" refers to a cluster group rather than to a specific member.
+	targetGroups, err := projectCheckClusterTarget(d.cluster, project, targetNode)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if strings.HasPrefix(targetNode, "@") {
+		targetNode = ""
+	}
+
 	if targetNode == "" {
 		// If no target node was specified, pick the node with the
 		// least number of containers. If there's just one node, or if
@@ -780,12 +812,16 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 		}
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			var err error
-			targetNode, err = tx.NodeWithLeastContainers(architectures)
+			targetNode, err = tx.NodeWithLeastContainersInGroups(architectures, targetGroups)
 			return err
 		})
 		if err != nil {
 			return response.SmartError(err)
 		}
+
+		if targetNode == "" && len(targetGroups) > 0 {
+			return response.BadRequest(fmt.Errorf("No suitable cluster member found in cluster groups %s", strings.Join(targetGroups, ", ")))
+		}
 	}
 
 	if targetNode != "" {
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// instancesStateConcurrency is how many instances a member changes the state of at the same time
+// by default.
+const instancesStateConcurrency = 10
+
+// instanceStateTarget is an instance whose state is changed by a bulk request.
+type instanceStateTarget struct {
+	project  string
+	name     string
+	location string
+
+	// Address of the member the instance is on, empty for this one.
+	address string
+}
+
+// Change the state of the instances of a project, or of all of them, matching the filter. Each
+// member changes the state of its own instances, the results being gathered in the metadata of
+// the operation.
+func containersPut(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	allProjects := shared.IsTrue(queryParam(r, "all-projects"))
+
+	if allProjects && !d.userIsAdmin(r) {
+		return response.Forbidden(nil)
+	}
+
+	filter, err := filterParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req := api.InstancesStatePut{}
+
+	// We default to -1 (i.e. no timeout) here instead of 0 (instant
+	// timeout).
+	req.State.Timeout = -1
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !shared.StringInSlice(req.State.Action, []string{"start", "stop", "restart", "freeze", "unfreeze"}) {
+		return response.BadRequest(fmt.Errorf("unknown action %s", req.State.Action))
+	}
+
+	if req.Concurrency < 0 {
+		return response.BadRequest(fmt.Errorf("Concurrency can't be negative"))
+	}
+
+	if req.Concurrency == 0 {
+		req.Concurrency = instancesStateConcurrency
+	}
+
+	// Don't mess with instances while in setup mode
+	<-d.readyChan
+
+	targets := []instanceStateTarget{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		projects := []string{project}
+		if allProjects {
+			var err error
+			projects, err = tx.ProjectNames()
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, project := range projects {
+			names, err := tx.InstanceNamesFiltered(project, instanceType, filter)
+			if err != nil {
+				return err
+			}
+
+			addresses, err := tx.ContainersListByNodeAddress(project, instanceType)
+			if err != nil {
+				return err
+			}
+
+			locations, err := tx.ContainersByNodeName(project, instanceType)
+			if err != nil {
+				return err
+			}
+
+			instanceAddresses := map[string]string{}
+			for address, instances := range addresses {
+				for _, name := range instances {
+					instanceAddresses[name] = address
+				}
+			}
+
+			for _, name := range names {
+				target := instanceStateTarget{
+					project:  project,
+					name:     name,
+					location: locations[name],
+					address:  instanceAddresses[name],
+				}
+
+				// Requests from other members are only about the instances of this one.
+				if isClusterNotification(r) && target.address != "" {
+					continue
+				}
+
+				targets = append(targets, target)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	do := func(op *operations.Operation) error {
+		results := []api.InstanceStateResult{}
+		resultsLock := sync.Mutex{}
+
+		// Record the results as they come, for clients to follow the progress.
+		record := func(newResults ...api.InstanceStateResult) {
+			resultsLock.Lock()
+			defer resultsLock.Unlock()
+
+			results = append(results, newResults...)
+			op.UpdateMetadata(map[string]interface{}{"results": results})
+		}
+
+		// Split the instances between this member and the others.
+		local := []instanceStateTarget{}
+		remote := map[string][]instanceStateTarget{}
+		for _, target := range targets {
+			if target.address == "" {
+				local = append(local, target)
+			} else {
+				remote[target.address] = append(remote[target.address], target)
+			}
+		}
+
+		wg := sync.WaitGroup{}
+
+		for address, remoteTargets := range remote {
+			wg.Add(1)
+			go func(address string, remoteTargets []instanceStateTarget) {
+				defer wg.Done()
+				record(instancesStateRemote(d, r, address, remoteTargets, req)...)
+			}(address, remoteTargets)
+		}
+
+		slots := make(chan struct{}, req.Concurrency)
+		for _, target := range local {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(target instanceStateTarget) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				record(instanceStateLocal(d, op, target, req.State))
+			}(target)
+		}
+
+		wg.Wait()
+
+		failed := 0
+		for _, result := range results {
+			if result.Status == "Failure" {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("Failed to %s %d out of %d instances", req.State.Action, failed, len(results))
+		}
+
+		return nil
+	}
+
+	// Operations across projects don't belong to any.
+	resources := map[string][]string{}
+	opProject := ""
+	if !allProjects {
+		opProject = project
+		for _, target := range targets {
+			resources["instances"] = append(resources["instances"], target.name)
+		}
+	}
+
+	op, err := operations.OperationCreate(d.State(), opProject, operations.OperationClassTask, db.OperationInstancesStateUpdate, resources, nil, do, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// instanceStateLocal changes the state of an instance of this member, skipping those already in
+// the requested state.
+func instanceStateLocal(d *Daemon, op *operations.Operation, target instanceStateTarget, state api.InstanceStatePut) api.InstanceStateResult {
+	result := api.InstanceStateResult{
+		Name:     target.name,
+		Project:  target.project,
+		Location: target.location,
+		Status:   "Success",
+	}
+
+	fail := func(err error) api.InstanceStateResult {
+		result.Status = "Failure"
+		result.Error = err.Error()
+		return result
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), target.project, target.name)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch state.Action {
+	case "start":
+		// Starting a frozen instance unfreezes it, like "lxc start" does.
+		if inst.IsFrozen() {
+			state.Action = "unfreeze"
+		} else if inst.IsRunning() {
+			result.Status = "Skipped"
+			return result
+		}
+
+		// Only restore the state of the instances which have one.
+		state.Stateful = state.Stateful && inst.IsStateful()
+	case "stop", "restart", "freeze":
+		if !inst.IsRunning() || (state.Action == "freeze" && inst.IsFrozen()) {
+			result.Status = "Skipped"
+			return result
+		}
+	case "unfreeze":
+		if !inst.IsFrozen() {
+			result.Status = "Skipped"
+			return result
+		}
+	}
+
+	_, do, err := instanceStateAction(d, inst, state)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = do(op)
+	if err != nil {
+		return fail(err)
+	}
+
+	return result
+}
+
+// instancesStateRemote has another member change the state of its instances matching the request
+// and returns its results.
+func instancesStateRemote(d *Daemon, r *http.Request, address string, targets []instanceStateTarget, req api.InstancesStatePut) []api.InstanceStateResult {
+	// Mark all the instances of the member as failed when it can't be reached.
+	fail := func(err error) []api.InstanceStateResult {
+		results := make([]api.InstanceStateResult, 0, len(targets))
+		for _, target := range targets {
+			results = append(results, api.InstanceStateResult{
+				Name:     target.name,
+				Project:  target.project,
+				Location: target.location,
+				Status:   "Failure",
+				Error:    err.Error(),
+			})
+		}
+
+		return results
+	}
+
+	if address == "0.0.0.0" {
+		return fail(fmt.Errorf("Cluster member is offline"))
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), true)
+	if err != nil {
+		return fail(err)
+	}
+
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return fail(err)
+	}
+
+	args := &lxd.InstancesStateArgs{
+		AllProjects: shared.IsTrue(queryParam(r, "all-projects")),
+		Filter:      &lxd.FilterArgs{Filter: queryParam(r, "filter")},
+	}
+
+	// The sort and pagination were already checked by this member.
+	sort := queryParam(r, "sort")
+	if sort != "" {
+		args.Filter.Sort = strings.Split(sort, ",")
+	}
+
+	args.Filter.Limit, _ = strconv.Atoi(queryParam(r, "limit"))
+	args.Filter.Offset, _ = strconv.Atoi(queryParam(r, "offset"))
+
+	op, err := client.UseProject(projectParam(r)).UpdateInstancesState(api.InstanceType(instanceType.String()), req, args)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The operation fails when any instance does, the results tell which.
+	opErr := op.Wait()
+
+	results := []api.InstanceStateResult{}
+	data, err := json.Marshal(op.Get().Metadata["results"])
+	if err == nil {
+		err = json.Unmarshal(data, &results)
+	}
+
+	if err != nil || (opErr != nil && len(results) == 0) {
+		if opErr != nil {
+			return fail(opErr)
+		}
+
+		return fail(err)
+	}
+
+	return results
+}
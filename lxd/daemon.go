@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"database/sql"
 	sqldriver "database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,26 +29,38 @@ import (
 	"gopkg.in/macaroon-bakery.v2/bakery/identchecker"
 	"gopkg.in/macaroon-bakery.v2/httpbakery"
 
+	"github.com/lxc/lxd/lxd/audit"
+	"github.com/lxc/lxd/lxd/auth"
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/lxd/device"
+	"github.com/lxc/lxd/lxd/dns"
 	"github.com/lxc/lxd/lxd/endpoints"
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/firewall"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/logtarget"
 	"github.com/lxc/lxd/lxd/maas"
 	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/ratelimit"
 	"github.com/lxc/lxd/lxd/rbac"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/seccomp"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/sys"
 	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/trace"
+	"github.com/lxc/lxd/lxd/usage"
 	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/lxd/webhook"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/logging"
 	"github.com/lxc/lxd/shared/version"
 
 	log "github.com/lxc/lxd/shared/log15"
@@ -56,6 +69,8 @@ import (
 // A Daemon can respond to requests from a shared client.
 type Daemon struct {
 	clientCerts  map[string]x509.Certificate
+	clientRoles  map[string]api.Certificate // Type and projects of the trusted certificates
+	clientLock   sync.RWMutex               // Protects clientCerts and clientRoles
 	os           *sys.OS
 	db           *db.Node
 	firewall     firewall.Firewall
@@ -76,18 +91,43 @@ type Daemon struct {
 	clusterTasks task.Group
 
 	// Indexes of tasks that need to be reset when their execution interval changes
-	taskPruneImages *task.Task
-	taskAutoUpdate  *task.Task
+	taskPruneImages      *task.Task
+	taskAutoUpdate       *task.Task
+	taskClusterRebalance *task.Task
 
 	config    *DaemonConfig
 	endpoints *endpoints.Endpoints
 	gateway   *cluster.Gateway
 	seccomp   *seccomp.Server
+	dns       *dns.Server
+	bgp       *bgp.Server
 
 	proxy func(req *http.Request) (*url.URL, error)
 
 	externalAuth *externalAuth
 
+	// Verifies the tokens of OIDC clients, when an issuer is configured.
+	oidcVerifier *auth.OIDCVerifier
+
+	// Records mutating API requests to the configured audit sinks.
+	audit *audit.Logger
+
+	// Posts the selected events to the configured webhooks.
+	webhooks *webhook.Sender
+
+	// Forwards the audit entries, lifecycle events and console output to remote log servers.
+	logTargets *logtarget.Forwarder
+
+	// Resource usage of the instances and storage pools of this node over the last day.
+	usage *usage.Store
+
+	// Limits the rate and concurrency of the requests of each client.
+	rateLimiter *ratelimit.Limiter
+
+	// Whether requests are authorized by the built-in authorization groups.
+	authBuiltin     bool
+	authBuiltinLock sync.RWMutex // Protects authBuiltin
+
 	// Stores last heartbeat node information to detect node changes.
 	lastNodeList *cluster.APIHeartbeat
 
@@ -148,13 +188,18 @@ func NewDaemon(config *DaemonConfig, os *sys.OS) *Daemon {
 	devlxdEvents := events.NewServer(daemon.Debug, daemon.Verbose)
 
 	return &Daemon{
+		audit:        audit.NewLogger(shared.LogPath("audit.log")),
 		config:       config,
 		devlxdEvents: devlxdEvents,
 		events:       lxdEvents,
+		logTargets:   logtarget.NewForwarder(),
 		os:           os,
+		rateLimiter:  ratelimit.NewLimiter(),
 		setupChan:    make(chan struct{}),
 		readyChan:    make(chan struct{}),
 		shutdownChan: make(chan struct{}),
+		usage:        usage.NewStore(shared.VarPath("usage.json")),
+		webhooks:     webhook.NewSender(),
 	}
 }
 
@@ -214,9 +259,13 @@ func AllowProjectPermission(feature string, permission string) func(d *Daemon, r
 		// Get the project
 		project := projectParam(r)
 
-		// Validate whether the user has the needed permission
+		// Validate whether the user has the needed permission, either on the whole project or,
+		// for requests about a specific instance, on the instance alone
 		if !d.userHasPermission(r, project, permission) {
-			return response.Forbidden(nil)
+			instance := mux.Vars(r)["name"]
+			if feature != "containers" || instance == "" || !d.userHasInstancePermission(r, project, instance, permission) {
+				return response.Forbidden(nil)
+			}
 		}
 
 		return response.EmptySyncResponse
@@ -225,7 +274,7 @@ func AllowProjectPermission(feature string, permission string) func(d *Daemon, r
 
 // Convenience function around Authenticate
 func (d *Daemon) checkTrustedClient(r *http.Request) error {
-	trusted, _, _, err := d.Authenticate(r)
+	trusted, _, _, _, err := d.Authenticate(r)
 	if !trusted || err != nil {
 		if err != nil {
 			return err
@@ -239,10 +288,12 @@ func (d *Daemon) checkTrustedClient(r *http.Request) error {
 
 // Authenticate validates an incoming http Request
 // It will check over what protocol it came, what type of request it is and
-// will validate the TLS certificate or Macaroon.
+// will validate the TLS certificate, Macaroon, API token or OIDC token.
+//
+// Along with the username, it returns the groups of OIDC identities.
 //
 // This does not perform authorization, only validates authentication
-func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
+func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, []string, error) {
 	// Allow internal cluster traffic
 	if r.TLS != nil {
 		cert, _ := x509.ParseCertificate(d.endpoints.NetworkCert().KeyPair().Certificate[0])
@@ -250,29 +301,29 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		for i := range r.TLS.PeerCertificates {
 			trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], clusterCerts)
 			if trusted {
-				return true, "", "cluster", nil
+				return true, "", "cluster", nil, nil
 			}
 		}
 	}
 
 	// Local unix socket queries
 	if r.RemoteAddr == "@" {
-		return true, "", "unix", nil
+		return true, "", "unix", nil, nil
 	}
 
 	// Devlxd unix socket credentials on main API
 	if r.RemoteAddr == "@devlxd" {
-		return false, "", "", fmt.Errorf("Main API query can't come from /dev/lxd socket")
+		return false, "", "", nil, fmt.Errorf("Main API query can't come from /dev/lxd socket")
 	}
 
 	// Cluster notification with wrong certificate
 	if isClusterNotification(r) {
-		return false, "", "", fmt.Errorf("Cluster notification isn't using cluster certificate")
+		return false, "", "", nil, fmt.Errorf("Cluster notification isn't using cluster certificate")
 	}
 
 	// Bad query, no TLS found
 	if r.TLS == nil {
-		return false, "", "", fmt.Errorf("Bad/missing TLS on network query")
+		return false, "", "", nil, fmt.Errorf("Bad/missing TLS on network query")
 	}
 
 	if d.externalAuth != nil && r.Header.Get(httpbakery.BakeryProtocolHeader) != "" {
@@ -288,28 +339,53 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		info, err := authChecker.Allow(ctx, ops...)
 		if err != nil {
 			// Bad macaroon
-			return false, "", "", err
+			return false, "", "", nil, err
 		}
 
 		if info != nil && info.Identity != nil {
 			// Valid identity macaroon found
-			return true, info.Identity.Id(), "candid", nil
+			return true, info.Identity.Id(), "candid", nil, nil
 		}
 
 		// Valid macaroon with no identity information
-		return true, "", "candid", nil
+		return true, "", "candid", nil, nil
+	}
+
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+auth.TokenPrefix) {
+		name, err := d.cluster.AuthTokenAuthenticate(auth.TokenHash(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")))
+		if err != nil {
+			// Let the request through as untrusted, like an unknown certificate.
+			logger.Warn("Failed API token authentication", log.Ctx{"ip": r.RemoteAddr, "err": err})
+			return false, "", "", nil, nil
+		}
+
+		return true, name, "token", nil, nil
+	}
+
+	if d.oidcVerifier != nil && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		claims, err := d.oidcVerifier.Verify(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil {
+			// Let the request through as untrusted, like an unknown certificate.
+			logger.Warn("Failed OIDC authentication", log.Ctx{"ip": r.RemoteAddr, "err": err})
+			return false, "", "", nil, nil
+		}
+
+		return true, claims.Identity, "oidc", claims.Groups, nil
 	}
 
 	// Validate normal TLS access
+	d.clientLock.RLock()
+	defer d.clientLock.RUnlock()
+
 	for i := range r.TLS.PeerCertificates {
 		trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.clientCerts)
 		if trusted {
-			return true, username, "tls", nil
+			return true, username, "tls", nil, nil
 		}
 	}
 
 	// Reject unauthorized
-	return false, "", "", nil
+	return false, "", "", nil, nil
 }
 
 func writeMacaroonsRequiredResponse(b *identchecker.Bakery, r *http.Request, w http.ResponseWriter, derr *bakery.DischargeRequiredError, expiry int64) {
@@ -339,7 +415,7 @@ func writeMacaroonsRequiredResponse(b *identchecker.Bakery, r *http.Request, w h
 
 // State creates a new State instance linked to our internal db and os.
 func (d *Daemon) State() *state.State {
-	return state.NewState(d.db, d.cluster, d.maas, d.os, d.endpoints, d.events, d.devlxdEvents, d.firewall, d.proxy)
+	return state.NewState(d.db, d.cluster, d.maas, d.os, d.endpoints, d.events, d.devlxdEvents, d.firewall, d.dns, d.bgp, d.proxy)
 }
 
 // UnixSocket returns the full path to the unix.socket file that this daemon is
@@ -376,8 +452,15 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			}
 		}
 
+		// Trace the handling of the request
+		traceWriter, r, span := traceRequest(w, r, uri)
+		if span != nil {
+			w = traceWriter
+			defer traceFinish(span, traceWriter)
+		}
+
 		// Authentication
-		trusted, username, protocol, err := d.Authenticate(r)
+		trusted, username, protocol, groups, err := d.Authenticate(r)
 		if err != nil {
 			// If not a macaroon discharge request, return the error
 			_, ok := err.(*bakery.DischargeRequiredError)
@@ -400,7 +483,39 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 		untrustedOk := (r.Method == "GET" && c.Get.AllowUntrusted) || (r.Method == "POST" && c.Post.AllowUntrusted)
 		if trusted {
 			logger.Debug("Handling", log.Ctx{"method": r.Method, "url": r.URL.RequestURI(), "ip": r.RemoteAddr, "user": username})
+			span.SetAttribute("enduser.id", username)
 			r = r.WithContext(context.WithValue(r.Context(), "username", username))
+			r = r.WithContext(context.WithValue(r.Context(), "protocol", protocol))
+			r = r.WithContext(context.WithValue(r.Context(), "groups", groups))
+
+			// API tokens are restricted to the requests their capability allows
+			if protocol == "token" {
+				_, token, err := d.cluster.AuthTokenGet(username)
+				if err != nil || !auth.TokenAllows(token, r.Method, c.Path) {
+					logger.Warn("Rejecting request not allowed by API token", log.Ctx{"ip": r.RemoteAddr, "token": username})
+					response.Forbidden(nil).Render(w)
+					return
+				}
+
+				r = r.WithContext(context.WithValue(r.Context(), "token", token))
+			}
+
+			// Viewer and operator certificates are restricted to the requests their role allows
+			if protocol == "tls" {
+				d.clientLock.RLock()
+				cert, ok := d.clientRoles[username]
+				d.clientLock.RUnlock()
+
+				if !ok || !auth.CertificateAllows(cert.Type, r.Method, c.Path) {
+					logger.Warn("Rejecting request not allowed by certificate", log.Ctx{"ip": r.RemoteAddr, "fingerprint": username})
+					response.Forbidden(nil).Render(w)
+					return
+				}
+
+				if auth.CertificateIsRestricted(cert.Type) {
+					r = r.WithContext(context.WithValue(r.Context(), "certificate", &cert))
+				}
+			}
 		} else if untrustedOk && r.Header.Get("X-LXD-authenticated") == "" {
 			logger.Debug(fmt.Sprintf("Allowing untrusted %s", r.Method), log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
 		} else if derr, ok := err.(*bakery.DischargeRequiredError); ok {
@@ -412,6 +527,18 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			return
 		}
 
+		// Limit the requests of remote clients, local and cluster ones being exempt
+		if !shared.StringInSlice(protocol, []string{"unix", "cluster"}) {
+			release, wait, err := d.rateLimiter.Acquire(rateLimitKey(r, trusted, username, protocol), rateLimitLongLived(r, c.Path))
+			if err != nil {
+				logger.Debug("Rejecting request over the rate limits", log.Ctx{"ip": r.RemoteAddr, "user": username, "err": err})
+				rateLimitReject(w, wait, err)
+				return
+			}
+
+			defer release()
+		}
+
 		// Dump full request JSON when in debug mode
 		if daemon.Debug && r.Method != "GET" && util.IsJSONRequest(r) {
 			newBody := &bytes.Buffer{}
@@ -426,6 +553,14 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			shared.DebugJson(captured)
 		}
 
+		// Record mutating requests to the audit log once handled
+		auditEntry := auditRequest(d, r, username, protocol)
+		if auditEntry != nil {
+			auditWriter := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			w = auditWriter
+			defer auditFinish(d, auditEntry, auditWriter)
+		}
+
 		// Actually process the request
 		var resp response.Response
 		resp = response.NotImplemented(nil)
@@ -448,6 +583,11 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 				}
 			}
 
+			// Keep track of the state of the resource the request is about to change
+			if auditEntry != nil && c.Get.Handler != nil && shared.StringInSlice(r.Method, []string{"PUT", "PATCH", "DELETE"}) {
+				auditEntry.Before = auditResourceState(d, r, c.Get.Handler)
+			}
+
 			return action.Handler(d, r)
 		}
 
@@ -466,6 +606,9 @@ func (d *Daemon) createCmd(restAPI *mux.Router, version string, c APIEndpoint) {
 			resp = response.NotFound(fmt.Errorf("Method '%s' not found", r.Method))
 		}
 
+		// Make the operation started by the response part of the request's trace
+		traceOperation(r.Context(), resp)
+
 		// Handle errors
 		if err := resp.Render(w); err != nil {
 			err := response.InternalError(err).Render(w)
@@ -558,7 +701,7 @@ func (d *Daemon) init() error {
 	}
 
 	/* List of sub-systems to trace */
-	trace := d.config.Trace
+	traceSubsystems := d.config.Trace
 
 	/* Initialize the operating system facade */
 	err = d.os.Init()
@@ -612,6 +755,13 @@ func (d *Daemon) init() error {
 		logger.Infof(" - seccomp listener continue syscalls: no")
 	}
 
+	d.os.CoreScheduling = CanUseCoreScheduling()
+	if d.os.CoreScheduling {
+		logger.Infof(" - core scheduling: yes")
+	} else {
+		logger.Infof(" - core scheduling: no")
+	}
+
 	/*
 	 * During daemon startup we're the only thread that touches VFS3Fscaps
 	 * so we don't need to bother with atomic.StoreInt32() when touching
@@ -651,6 +801,8 @@ func (d *Daemon) init() error {
 		"network_phys_macvlan_mtu",
 		"network_veth_router",
 		"cgroup2",
+		"time_namespace",
+		"core_scheduling",
 	}
 	for _, extension := range lxcExtensions {
 		d.os.LXCFeatures[extension] = lxc.HasApiExtension(extension)
@@ -670,7 +822,7 @@ func (d *Daemon) init() error {
 
 	/* Setup dqlite */
 	clusterLogLevel := "ERROR"
-	if shared.StringInSlice("dqlite", trace) {
+	if shared.StringInSlice("dqlite", traceSubsystems) {
 		clusterLogLevel = "TRACE"
 	}
 	d.gateway, err = cluster.NewGateway(
@@ -682,6 +834,7 @@ func (d *Daemon) init() error {
 		return err
 	}
 	d.gateway.HeartbeatNodeHook = d.NodeRefreshTask
+	d.gateway.HeartbeatMemberStateHook = d.clusterMemberStateEvent
 
 	/* Setup some mounts (nice to have) */
 	if !d.os.MockMode {
@@ -782,7 +935,25 @@ func (d *Daemon) init() error {
 		return errors.Wrap(err, "failed to open cluster database")
 	}
 
-	d.firewall = firewall.New()
+	err = databaseRestoreGlobal(d)
+	if err != nil {
+		return errors.Wrap(err, "Failed to restore global database")
+	}
+
+	firewallDriver, err := node.FirewallDriver(d.db)
+	if err != nil {
+		return err
+	}
+
+	d.firewall, err = firewall.New(firewallDriver)
+	if err != nil {
+		logger.Warn("Firewall driver unavailable, falling back to xtables", log.Ctx{"driver": firewallDriver, "err": err})
+
+		d.firewall, err = firewall.New("xtables")
+		if err != nil {
+			return err
+		}
+	}
 
 	err = cluster.NotifyUpgradeCompleted(d.State(), certInfo)
 	if err != nil {
@@ -825,6 +996,12 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	// Restore the volume mount references of the running instances
+	err = storageMountRefsRestore(d.State())
+	if err != nil {
+		return err
+	}
+
 	/* Apply all patches */
 	err = patchesApplyAll(d)
 	if err != nil {
@@ -838,10 +1015,30 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	/* Setup the DNS server for the managed zones */
+	d.dns = dns.NewServer(func() ([]string, error) {
+		return networkDNSZoneNames(d.State())
+	}, func(name string) (*dns.Zone, error) {
+		return networkDNSZone(d.State(), name)
+	})
+
+	err = networkDNSServerUpdate(d.State())
+	if err != nil {
+		logger.Errorf("Failed to start DNS server: %v", err)
+	}
+
+	/* Setup the BGP server announcing the network routes */
+	d.bgp = bgp.NewServer()
+
+	err = networkBGPServerUpdate(d.State())
+	if err != nil {
+		logger.Errorf("Failed to start BGP server: %v", err)
+	}
+
 	// Cleanup leftover images
 	pruneLeftoverImages(d)
 
-	/* Setup the proxy handler, external authentication and MAAS */
+	/* Setup the proxy handler, external authentication, audit logging and MAAS */
 	candidAPIURL := ""
 	candidAPIKey := ""
 	candidDomains := ""
@@ -859,6 +1056,18 @@ func (d *Daemon) init() error {
 	maasAPIKey := ""
 	maasMachine := ""
 
+	var auditSinks []string
+	auditWebhook := ""
+	traceEndpoint := ""
+	var webhookURLs []string
+	var webhookEvents []string
+	webhookSecret := ""
+	var logTargets logtarget.Config
+	var rateRequests, rateBurst, rateConcurrency int64
+	var slowQueryThreshold time.Duration
+	serverName := ""
+	var logLevels map[string]string
+
 	err = d.db.Transaction(func(tx *db.NodeTx) error {
 		config, err := node.ConfigLoad(tx)
 		if err != nil {
@@ -866,12 +1075,20 @@ func (d *Daemon) init() error {
 		}
 
 		maasMachine = config.MAASMachine()
+		logLevels = config.LogLevels()
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	for subsystem, level := range logLevels {
+		err = logging.SetSubsystemLevel(subsystem, level)
+		if err != nil {
+			logger.Warn("Failed to set the log level of a subsystem", log.Ctx{"subsystem": subsystem, "err": err})
+		}
+	}
+
 	logger.Infof("Loading daemon configuration")
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		config, err := cluster.ConfigLoad(tx)
@@ -886,6 +1103,19 @@ func (d *Daemon) init() error {
 		candidAPIURL, candidAPIKey, candidExpiry, candidDomains = config.CandidServer()
 		maasAPIURL, maasAPIKey = config.MAASController()
 		rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey = config.RBACServer()
+		auditSinks, auditWebhook = config.AuditSinks()
+		traceEndpoint = config.TraceEndpoint()
+		webhookURLs, webhookEvents, webhookSecret = config.Webhooks()
+		logTargets = config.LogTargets()
+		rateRequests, rateBurst, rateConcurrency = config.RateLimits()
+		slowQueryThreshold = config.SlowQueryThreshold()
+		d.setAuthBuiltin(config.AuthBuiltin())
+		d.setupOIDC(config.OIDCServer())
+
+		serverName, err = tx.NodeName()
+		if err != nil {
+			return err
+		}
 
 		return nil
 	})
@@ -893,6 +1123,58 @@ func (d *Daemon) init() error {
 		return err
 	}
 
+	err = d.audit.Configure(auditSinks, auditWebhook)
+	if err != nil {
+		logger.Warn("Failed to configure audit logging", log.Ctx{"err": err})
+	}
+
+	err = trace.Configure(traceEndpoint)
+	if err != nil {
+		logger.Warn("Failed to configure tracing", log.Ctx{"err": err})
+	}
+
+	d.webhooks.SetLocation(serverName)
+	err = d.webhooks.Configure(webhookURLs, webhookEvents, webhookSecret)
+	if err != nil {
+		logger.Warn("Failed to configure webhooks", log.Ctx{"err": err})
+	}
+
+	d.logTargets.SetLocation(serverName)
+	err = d.logTargets.Configure(logTargets)
+	if err != nil {
+		logger.Warn("Failed to configure log forwarding", log.Ctx{"err": err})
+	}
+
+	query.SetSlowThreshold(slowQueryThreshold)
+
+	err = d.rateLimiter.Configure(float64(rateRequests), int(rateBurst), int(rateConcurrency))
+	if err != nil {
+		logger.Warn("Failed to configure rate limiting", log.Ctx{"err": err})
+	}
+
+	d.audit.SetHook(d.logTargets.SendAudit)
+	d.events.SetHook(func(group string, event api.Event) {
+		d.webhooks.Send(group, event)
+		d.logTargets.SendEvent(group, event)
+
+		// Instances and networks changing may change the records of the DNS zones.
+		if event.Type == "lifecycle" {
+			d.dns.Invalidate()
+
+			// Instances starting or stopping change the routes announced through BGP.
+			lifecycle := api.EventLifecycle{}
+			err := json.Unmarshal(event.Metadata, &lifecycle)
+			if err == nil && (strings.HasPrefix(lifecycle.Action, "container-") || strings.HasPrefix(lifecycle.Action, "virtual-machine-")) {
+				go func() {
+					err := networkBGPServerUpdate(d.State())
+					if err != nil {
+						logger.Warn("Failed to update the BGP routes", log.Ctx{"err": err})
+					}
+				}()
+			}
+		}
+	})
+
 	if rbacAPIURL != "" {
 		err = d.setupRBACServer(rbacAPIURL, rbacAPIKey, rbacExpiry, rbacAgentURL, rbacAgentUsername, rbacAgentPrivateKey, rbacAgentPublicKey)
 		if err != nil {
@@ -976,6 +1258,9 @@ func (d *Daemon) startClusterTasks() {
 	// Auto-sync images across the cluster (daily)
 	d.clusterTasks.Add(autoSyncImagesTask(d))
 
+	// Rebalance instances across the cluster (configurable)
+	d.taskClusterRebalance = d.clusterTasks.Add(clusterRebalanceTask(d))
+
 	// Start all background tasks
 	d.clusterTasks.Start()
 }
@@ -983,6 +1268,7 @@ func (d *Daemon) startClusterTasks() {
 func (d *Daemon) stopClusterTasks() {
 	d.clusterTasks.Stop(3 * time.Second)
 	d.clusterTasks = task.Group{}
+	d.taskClusterRebalance = nil
 }
 
 func (d *Daemon) Ready() error {
@@ -996,6 +1282,12 @@ func (d *Daemon) Ready() error {
 		d.startClusterTasks()
 	}
 
+	// Restore the resource usage history recorded before the restart
+	err = d.usage.Load()
+	if err != nil {
+		logger.Warn("Failed to load the resource usage history", log.Ctx{"err": err})
+	}
+
 	// FIXME: There's no hard reason for which we should not run these
 	//        tasks in mock mode. However it requires that we tweak them so
 	//        they exit gracefully without blocking (something we should do
@@ -1023,6 +1315,30 @@ func (d *Daemon) Ready() error {
 
 		// Remove expired container snapshots (minutely)
 		d.tasks.Add(pruneExpiredContainerSnapshotsTask(d))
+
+		// Record instance network usage (every 5 minutes)
+		d.tasks.Add(networkUsageTask(d))
+
+		// Remove expired API tokens (hourly)
+		d.tasks.Add(pruneExpiredAuthTokensTask(d))
+
+		// Forward instance console output (every 10 seconds)
+		d.tasks.Add(forwardConsoleLogsTask(d))
+
+		// Sample the resource usage of instances and storage pools (every minute)
+		d.tasks.Add(usageSampleTask(d))
+
+		// Record the resources consumed by projects (every 5 minutes)
+		d.tasks.Add(projectUsageTask(d))
+
+		// Purge the expired storage trash (hourly)
+		d.tasks.Add(storageTrashPurgeTask(d))
+
+		// Refresh the routes announced through BGP (every minute)
+		d.tasks.Add(networkBGPTask(d))
+
+		// Run the network load balancer health checks which are due (every second)
+		d.tasks.Add(networkLoadBalancerHealthTask(d))
 	}
 
 	// Start all background tasks
@@ -1031,6 +1347,9 @@ func (d *Daemon) Ready() error {
 	// Get daemon state struct
 	s := d.State()
 
+	// Resume or roll back the operations interrupted by the last shutdown
+	recoverInterruptedOperations(d)
+
 	// Restore containers
 	containersRestart(s)
 
@@ -1089,6 +1408,14 @@ func (d *Daemon) Stop() error {
 		trackError(d.endpoints.Down())
 	}
 
+	if d.dns != nil {
+		d.dns.Stop()
+	}
+
+	if d.bgp != nil {
+		d.bgp.Stop()
+	}
+
 	trackError(d.tasks.Stop(3 * time.Second))        // Give tasks a bit of time to cleanup.
 	trackError(d.clusterTasks.Stop(3 * time.Second)) // Give tasks a bit of time to cleanup.
 
@@ -1152,6 +1479,15 @@ func (d *Daemon) Stop() error {
 		trackError(d.seccomp.Stop())
 	}
 
+	// Export the queued trace spans
+	trackError(trace.Configure(""))
+
+	// Send the queued log entries
+	d.logTargets.Stop()
+
+	// Keep the resource usage history across restarts
+	trackError(d.usage.Save())
+
 	var err error
 	if n := len(errs); n > 0 {
 		format := "%v"
@@ -1300,19 +1636,112 @@ func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int6
 }
 
 func (d *Daemon) userIsAdmin(r *http.Request) bool {
-	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
+	if r.RemoteAddr == "@" || r.Context().Value("protocol") == "cluster" {
 		return true
 	}
 
-	return d.rbac.IsAdmin(r.Context().Value("username").(string))
+	token := requestToken(r)
+	if token != nil {
+		return auth.TokenIsAdmin(token)
+	}
+
+	if requestCertificate(r) != nil {
+		return false
+	}
+
+	if d.userAuthBuiltin(r) {
+		return auth.IsAdmin(d.userPermissions(r))
+	}
+
+	if d.externalAuth != nil && d.rbac != nil {
+		return d.rbac.IsAdmin(requestIdentity(r))
+	}
+
+	return true
 }
 
 func (d *Daemon) userHasPermission(r *http.Request, project string, permission string) bool {
-	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
+	if r.RemoteAddr == "@" || r.Context().Value("protocol") == "cluster" {
 		return true
 	}
 
-	return d.rbac.HasPermission(r.Context().Value("username").(string), project, permission)
+	token := requestToken(r)
+	if token != nil {
+		return auth.TokenHasProject(token, project)
+	}
+
+	cert := requestCertificate(r)
+	if cert != nil {
+		return auth.CertificateHasProject(cert.Projects, project)
+	}
+
+	if d.userAuthBuiltin(r) {
+		permissions := d.userPermissions(r)
+
+		return auth.IsAdmin(permissions) || auth.HasPermission(permissions, project, "", permission)
+	}
+
+	if d.externalAuth != nil && d.rbac != nil {
+		return d.rbac.HasPermission(requestIdentity(r), project, permission)
+	}
+
+	return true
+}
+
+// userHasInstancePermission returns whether the built-in authorization grants a permission on a
+// single instance. The external RBAC service only knows about projects.
+func (d *Daemon) userHasInstancePermission(r *http.Request, project string, instance string, permission string) bool {
+	token := requestToken(r)
+	if token != nil {
+		return auth.TokenHasProject(token, project)
+	}
+
+	cert := requestCertificate(r)
+	if cert != nil {
+		return auth.CertificateHasProject(cert.Projects, project)
+	}
+
+	if d.userAuthBuiltin(r) {
+		return auth.HasPermission(d.userPermissions(r), project, instance, permission)
+	}
+
+	return d.externalAuth == nil || d.rbac == nil
+}
+
+// userAuthBuiltin returns whether a request is authorized by the built-in authorization groups,
+// which is always the case of OIDC identities as they're unknown to the external RBAC service.
+func (d *Daemon) userAuthBuiltin(r *http.Request) bool {
+	if r.Context().Value("protocol") == "oidc" {
+		return true
+	}
+
+	d.authBuiltinLock.RLock()
+	authBuiltin := d.authBuiltin
+	d.authBuiltinLock.RUnlock()
+
+	return authBuiltin && (d.externalAuth == nil || d.rbac == nil)
+}
+
+// setAuthBuiltin sets whether requests are authorized by the built-in authorization groups, as
+// the setting can change while requests are being handled.
+func (d *Daemon) setAuthBuiltin(authBuiltin bool) {
+	d.authBuiltinLock.Lock()
+	d.authBuiltin = authBuiltin
+	d.authBuiltinLock.Unlock()
+}
+
+// Setup OIDC authentication, or disable it if no issuer is given.
+func (d *Daemon) setupOIDC(issuer string, clientID string, audience string, groupsClaim string) {
+	if issuer == "" {
+		d.oidcVerifier = nil
+		return
+	}
+
+	proxy := func(req *http.Request) (*url.URL, error) {
+		return d.proxy(req)
+	}
+
+	d.oidcVerifier = auth.NewOIDCVerifier(issuer, clientID, audience, groupsClaim, proxy)
 }
 
 // Setup MAAS
@@ -1347,6 +1776,11 @@ func (d *Daemon) setupMAASController(server string, key string, machine string)
 // Create a database connection and perform any updates needed.
 func initializeDbObject(d *Daemon) (*db.Dump, error) {
 	logger.Info("Initializing local database")
+	err := databaseRestoreLocal(d.os.VarDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to restore local database")
+	}
+
 	// Rename the old database name if needed.
 	if shared.PathExists(d.os.LegacyLocalDatabasePath()) {
 		if shared.PathExists(d.os.LocalDatabasePath()) {
@@ -1384,7 +1818,6 @@ func initializeDbObject(d *Daemon) (*db.Dump, error) {
 		}
 		return nil
 	}
-	var err error
 	var dump *db.Dump
 	d.db, dump, err = db.OpenNode(filepath.Join(d.os.VarDir, "database"), freshHook, legacy)
 	if err != nil {
@@ -1415,6 +1848,13 @@ func (d *Daemon) hasNodeListChanged(heartbeatData *cluster.APIHeartbeat) bool {
 	return false
 }
 
+// clusterMemberStateEvent broadcasts a lifecycle event when the leader detects that a cluster
+// member went online, degraded or offline.
+func (d *Daemon) clusterMemberStateEvent(name string, state string) {
+	d.events.SendLifecycle("", fmt.Sprintf("cluster-member-%s", state),
+		fmt.Sprintf("/%s/cluster/members/%s", version.APIVersion, name), map[string]interface{}{"state": state})
+}
+
 // NodeRefreshTask is run each time a fresh node is generated.
 // This can be used to trigger actions when the node list changes.
 func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
@@ -1466,6 +1906,12 @@ func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
 				return
 			}
 		}
+
+		// Nodes publish their WireGuard keys as they set up their networks, so always refresh.
+		err := networkUpdateWireGuardPeersTask(d.State())
+		if err != nil {
+			logger.Errorf("Error refreshing WireGuard peers: %v", err)
+		}
 	}
 
 	// Only update the node list if the task succeeded.
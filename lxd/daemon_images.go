@@ -36,6 +36,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 
 	var remote lxd.ImageServer
 	var info *api.Image
+	var ociImage *ociInfo
 
 	// Default protocol is LXD
 	if protocol == "" {
@@ -85,6 +86,17 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 
 			fp = info.Fingerprint
 		}
+	} else if protocol == "oci" {
+		// Resolve the tag to the current manifest digest
+		ociImage, err = ociImageInspect(server, alias)
+		if err != nil {
+			return nil, err
+		}
+
+		fp, err = ociImageFingerprint(d, project, ociImage)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// If auto-update is on and we're being given the image by
@@ -185,8 +197,17 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		// Wait until the download finishes (channel closes)
 		<-waitChannel
 
+		// Converted OCI images are found through their manifest digest.
+		imageFp := fp
+		if ociImage != nil {
+			imageFp, err = ociImageFingerprint(d, project, ociImage)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// Grab the database entry
-		_, imgInfo, err := d.cluster.ImageGet(project, fp, false, true)
+		_, imgInfo, err := d.cluster.ImageGet(project, imageFp, false, true)
 		if err != nil {
 			// Other download failed, lets try again
 			logger.Error("Other image download didn't succeed", log.Ctx{"image": fp})
@@ -198,17 +219,18 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		imagesDownloadingLock.Unlock()
 	}
 
-	// Add the download to the queue
+	// Add the download to the queue, under the fingerprint known before the download.
+	downloadFp := fp
 	imagesDownloadingLock.Lock()
-	imagesDownloading[fp] = make(chan bool)
+	imagesDownloading[downloadFp] = make(chan bool)
 	imagesDownloadingLock.Unlock()
 
 	// Unlock once this func ends.
 	defer func() {
 		imagesDownloadingLock.Lock()
-		if waitChannel, ok := imagesDownloading[fp]; ok {
+		if waitChannel, ok := imagesDownloading[downloadFp]; ok {
 			close(waitChannel)
-			delete(imagesDownloading, fp)
+			delete(imagesDownloading, downloadFp)
 		}
 		imagesDownloadingLock.Unlock()
 	}()
@@ -341,6 +363,15 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 				return nil, err
 			}
 		}
+	} else if protocol == "oci" {
+		// Fetch and convert the OCI image
+		info, err = ociImageDownload(server, alias, ociImage, destName, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		// The fingerprint of converted images is only known once generated.
+		fp = info.Fingerprint
 	} else if protocol == "direct" {
 		// Setup HTTP client
 		httpClient, err := util.HTTPClient(certificate, d.proxy)
@@ -427,6 +458,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		info.AutoUpdate = autoUpdate
 	}
 
+	// Check the image signature
+	err = imageVerifySignature(d, info.Fingerprint, info.Properties)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the database entry
 	err = d.cluster.ImageInsert(project, info.Fingerprint, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, info.Type)
 	if err != nil {
@@ -452,6 +489,11 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		}
 	}
 
+	err = imageEncryptFiles(d, fp)
+	if err != nil {
+		return nil, err
+	}
+
 	// Record the image source
 	if alias != fp {
 		id, _, err := d.cluster.ImageGet(project, fp, false, true)
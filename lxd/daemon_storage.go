@@ -162,13 +162,11 @@ func daemonStorageValidate(s *state.State, target string) error {
 		}
 
 		// Mount volume
-		ourMount, err := pool.MountCustomVolume(volumeName, nil)
+		_, err = pool.MountCustomVolume(volumeName, nil)
 		if err != nil {
 			return errors.Wrapf(err, "Failed to mount storage volume \"%s\"", target)
 		}
-		if ourMount {
-			defer pool.UnmountCustomVolume(volumeName, nil)
-		}
+		defer pool.UnmountCustomVolume(volumeName, nil)
 	} else {
 		volume, err := storageInit(s, "default", poolName, volumeName, storagePoolVolumeTypeCustom)
 		if err != nil {
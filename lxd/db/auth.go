@@ -0,0 +1,233 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// AuthGroups returns the names of all the authorization groups.
+func (c *Cluster) AuthGroups() ([]string, error) {
+	var names []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		names, err = query.SelectStrings(tx.tx, "SELECT name FROM auth_groups ORDER BY name")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// AuthGroupGet returns the authorization group with the given name.
+func (c *Cluster) AuthGroupGet(name string) (int64, *api.AuthGroup, error) {
+	id := int64(-1)
+	group := api.AuthGroup{
+		Name: name,
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		err := tx.tx.QueryRow("SELECT id, description FROM auth_groups WHERE name=?", name).Scan(&id, &group.Description)
+		if err != nil {
+			return err
+		}
+
+		group.Identities, err = query.SelectStrings(tx.tx, "SELECT identity FROM auth_groups_identities WHERE group_id=? ORDER BY identity", id)
+		if err != nil {
+			return err
+		}
+
+		group.Permissions, err = authPermissions(tx.tx, "auth_groups_permissions.group_id=?", id)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	return id, &group, nil
+}
+
+// AuthGroupCreate creates a new authorization group.
+func (c *Cluster) AuthGroupCreate(group api.AuthGroupsPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO auth_groups (name, description) VALUES (?, ?)", group.Name, group.Description)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return authGroupMembersAdd(tx.tx, id, group.AuthGroupPut)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// AuthGroupUpdate updates the description, identities and permissions of the authorization group
+// with the given ID.
+func (c *Cluster) AuthGroupUpdate(id int64, group api.AuthGroupPut) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE auth_groups SET description=? WHERE id=?", group.Description, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM auth_groups_identities WHERE group_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM auth_groups_permissions WHERE group_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return authGroupMembersAdd(tx.tx, id, group)
+	})
+}
+
+// AuthGroupRename renames the authorization group with the given ID.
+func (c *Cluster) AuthGroupRename(id int64, name string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE auth_groups SET name=? WHERE id=?", name, id)
+		return err
+	})
+}
+
+// AuthGroupDelete deletes the authorization group with the given ID.
+func (c *Cluster) AuthGroupDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "auth_groups", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+// AuthIdentityPermissions returns the permissions granted to an identity by all the
+// authorization groups it's a member of, along with the ones of the groups with the given
+// names, such as the groups of an OIDC token.
+func (c *ClusterTx) AuthIdentityPermissions(identity string, groups []string) ([]api.AuthPermission, error) {
+	filter := `auth_groups_permissions.group_id IN (
+    SELECT group_id FROM auth_groups_identities WHERE identity=?)`
+	args := []interface{}{identity}
+
+	if len(groups) > 0 {
+		filter += fmt.Sprintf(" OR auth_groups_permissions.group_id IN (SELECT id FROM auth_groups WHERE name IN %s)", query.Params(len(groups)))
+		for _, group := range groups {
+			args = append(args, group)
+		}
+	}
+
+	return authPermissions(c.tx, filter, args...)
+}
+
+// authPermissions returns the permissions matching the given filter.
+func authPermissions(tx *sql.Tx, filter string, args ...interface{}) ([]api.AuthPermission, error) {
+	stmt := fmt.Sprintf(`
+SELECT DISTINCT auth_groups_permissions.role, coalesce(projects.name, ''), coalesce(instances.name, '')
+  FROM auth_groups_permissions
+  LEFT JOIN projects ON projects.id = auth_groups_permissions.project_id
+  LEFT JOIN instances ON instances.id = auth_groups_permissions.instance_id
+  WHERE %s
+  ORDER BY auth_groups_permissions.id`, filter)
+
+	rows, err := tx.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []api.AuthPermission{}
+	for rows.Next() {
+		permission := api.AuthPermission{}
+
+		err := rows.Scan(&permission.Role, &permission.Project, &permission.Instance)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func authGroupMembersAdd(tx *sql.Tx, groupID int64, group api.AuthGroupPut) error {
+	for _, identity := range group.Identities {
+		_, err := tx.Exec("INSERT OR IGNORE INTO auth_groups_identities (group_id, identity) VALUES (?, ?)", groupID, identity)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, permission := range group.Permissions {
+		var projectID interface{}
+		var instanceID interface{}
+
+		if permission.Project != "" {
+			var id int64
+			err := tx.QueryRow("SELECT id FROM projects WHERE name=?", permission.Project).Scan(&id)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fmt.Errorf("Project %q doesn't exist", permission.Project)
+				}
+
+				return err
+			}
+
+			projectID = id
+		}
+
+		if permission.Instance != "" {
+			var id int64
+			err := tx.QueryRow("SELECT id FROM instances WHERE project_id=? AND name=?", projectID, permission.Instance).Scan(&id)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return fmt.Errorf("Instance %q doesn't exist in project %q", permission.Instance, permission.Project)
+				}
+
+				return err
+			}
+
+			instanceID = id
+		}
+
+		_, err := tx.Exec("INSERT INTO auth_groups_permissions (group_id, role, project_id, instance_id) VALUES (?, ?, ?, ?)", groupID, permission.Role, projectID, instanceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// AuthTokens returns all the API tokens.
+func (c *Cluster) AuthTokens() ([]api.AuthToken, error) {
+	var tokens []api.AuthToken
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		tokens, err = authTokens(tx.tx, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// AuthTokenGet returns the API token with the given name.
+func (c *Cluster) AuthTokenGet(name string) (int64, *api.AuthToken, error) {
+	var id int64
+
+	var tokens []api.AuthToken
+	err := c.Transaction(func(tx *ClusterTx) error {
+		err := tx.tx.QueryRow("SELECT id FROM auth_tokens WHERE name=?", name).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = authTokens(tx.tx, "WHERE auth_tokens.id=?", id)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	if len(tokens) != 1 {
+		return -1, nil, ErrNoSuchObject
+	}
+
+	return id, &tokens[0], nil
+}
+
+// AuthTokenAuthenticate returns the name of the unexpired API token with the given secret hash.
+func (c *Cluster) AuthTokenAuthenticate(secretHash string) (string, error) {
+	var name string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		return tx.tx.QueryRow("SELECT name FROM auth_tokens WHERE secret_hash=? AND (expires_at IS NULL OR expires_at > ?)", secretHash, time.Now().UTC()).Scan(&name)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNoSuchObject
+		}
+
+		return "", err
+	}
+
+	return name, nil
+}
+
+// AuthTokenCreate creates a new API token with the given secret hash.
+func (c *Cluster) AuthTokenCreate(token api.AuthTokensPost, secretHash string) (int64, error) {
+	var id int64
+
+	var expiresAt interface{}
+	if !token.ExpiresAt.IsZero() {
+		expiresAt = token.ExpiresAt.UTC()
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO auth_tokens (name, description, secret_hash, capability, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			token.Name, token.Description, secretHash, token.Capability, time.Now().UTC(), expiresAt)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for _, project := range token.Projects {
+			_, err := tx.tx.Exec("INSERT OR IGNORE INTO auth_tokens_projects (token_id, project) VALUES (?, ?)", id, project)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// AuthTokenDelete deletes the API token with the given ID.
+func (c *Cluster) AuthTokenDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "auth_tokens", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+// AuthTokensPruneExpired deletes the API tokens which have expired.
+func (c *Cluster) AuthTokensPruneExpired() (int64, error) {
+	var count int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("DELETE FROM auth_tokens WHERE expires_at IS NOT NULL AND expires_at <= ?", time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		count, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
+// authTokens returns the API tokens matching the given filter.
+func authTokens(tx *sql.Tx, filter string, args ...interface{}) ([]api.AuthToken, error) {
+	stmt := "SELECT id, name, description, capability, created_at, expires_at FROM auth_tokens " + filter + " ORDER BY name"
+
+	rows, err := tx.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	tokens := []api.AuthToken{}
+	for rows.Next() {
+		var id int64
+		var expiresAt *time.Time
+		token := api.AuthToken{}
+
+		err := rows.Scan(&id, &token.Name, &token.Description, &token.Capability, &token.CreatedAt, &expiresAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if expiresAt != nil {
+			token.ExpiresAt = *expiresAt
+		}
+
+		ids = append(ids, id)
+		tokens = append(tokens, token)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tokens {
+		tokens[i].Projects, err = query.SelectStrings(tx, "SELECT project FROM auth_tokens_projects WHERE token_id=? ORDER BY project", ids[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,108 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/cluster"
+	"github.com/lxc/lxd/lxd/db/node"
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/db/schema"
+	"github.com/lxc/lxd/shared"
+)
+
+// Backup writes a consistent copy of the global database to a new SQLite file at the given path,
+// while the database keeps being used.
+func (c *Cluster) Backup(path string) error {
+	return backupDatabase(c.db, cluster.Schema(), cluster.FreshSchema(), path)
+}
+
+// Restore replaces the content of the global database with the one of a backup taken with
+// Backup, whose schema is upgraded first if it's older.
+func (c *Cluster) Restore(path string) error {
+	return restoreDatabase(c.db, cluster.Schema(), cluster.FreshSchema(), path)
+}
+
+// Backup writes a consistent copy of the node-local database to a new SQLite file at the given
+// path, while the database keeps being used.
+func (n *Node) Backup(path string) error {
+	return backupDatabase(n.db, node.Schema(), node.FreshSchema(), path)
+}
+
+// Copy all the rows of a database with the given schema to a new SQLite file, within a single
+// read transaction.
+func backupDatabase(db *sql.DB, s *schema.Schema, fresh string, path string) error {
+	if shared.PathExists(path) {
+		return fmt.Errorf("Backup file %q already exists", path)
+	}
+
+	backup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create backup database")
+	}
+	defer backup.Close()
+
+	_, err = s.Ensure(backup)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create backup schema")
+	}
+
+	tables := append([]string{"schema"}, query.SchemaTables(fresh)...)
+
+	return query.Transaction(db, func(src *sql.Tx) error {
+		return query.Transaction(backup, func(dst *sql.Tx) error {
+			// The schema version row gets copied over from the database.
+			_, err := dst.Exec("DELETE FROM schema")
+			if err != nil {
+				return err
+			}
+
+			return query.CopyTables(src, dst, tables)
+		})
+	})
+}
+
+// Replace all the rows of a database with the given schema with the ones of a backup, within a
+// single transaction.
+func restoreDatabase(db *sql.DB, s *schema.Schema, fresh string, path string) error {
+	if !shared.PathExists(path) {
+		return fmt.Errorf("Backup file %q doesn't exist", path)
+	}
+
+	backup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open backup database")
+	}
+	defer backup.Close()
+
+	_, err = s.Ensure(backup)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update backup schema")
+	}
+
+	tables := query.SchemaTables(fresh)
+
+	return query.Transaction(backup, func(src *sql.Tx) error {
+		return query.Transaction(db, func(dst *sql.Tx) error {
+			// Rows can't be deleted and inserted in an order matching the foreign keys of
+			// all tables, so only check them when committing.
+			_, err := dst.Exec("PRAGMA defer_foreign_keys = ON")
+			if err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				_, err := dst.Exec(fmt.Sprintf("DELETE FROM %s", table))
+				if err != nil {
+					return errors.Wrapf(err, "Failed to empty table %s", table)
+				}
+			}
+
+			return query.CopyTables(src, dst, tables)
+		})
+	})
+}
@@ -4,6 +4,8 @@ package db
 
 import (
 	"database/sql"
+
+	"github.com/lxc/lxd/lxd/db/query"
 )
 
 // CertInfo is here to pass the certificates content
@@ -14,6 +16,9 @@ type CertInfo struct {
 	Type        int
 	Name        string
 	Certificate string
+
+	// Projects viewer and operator certificates are restricted to, all of them if empty.
+	Projects []string
 }
 
 // CertificatesGet returns all certificates from the DB as CertBaseInfo objects.
@@ -40,7 +45,19 @@ func (c *Cluster) CertificatesGet() (certs []*CertInfo, err error) {
 			certs = append(certs, cert)
 		}
 
-		return rows.Err()
+		err = rows.Err()
+		if err != nil {
+			return err
+		}
+
+		for _, cert := range certs {
+			cert.Projects, err = certificateProjects(tx.tx, cert.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		return certs, err
@@ -81,7 +98,15 @@ func (c *Cluster) CertificateGet(fingerprint string) (cert *CertInfo, err error)
 		return nil, err
 	}
 
-	return cert, err
+	err = c.Transaction(func(tx *ClusterTx) error {
+		cert.Projects, err = certificateProjects(tx.tx, cert.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cert, nil
 }
 
 // CertSave stores a CertBaseInfo object in the db,
@@ -100,7 +125,7 @@ func (c *Cluster) CertSave(cert *CertInfo) error {
 			return err
 		}
 		defer stmt.Close()
-		_, err = stmt.Exec(
+		result, err := stmt.Exec(
 			cert.Fingerprint,
 			cert.Type,
 			cert.Name,
@@ -109,7 +134,13 @@ func (c *Cluster) CertSave(cert *CertInfo) error {
 		if err != nil {
 			return err
 		}
-		return nil
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return certificateProjectsSet(tx.tx, id, cert.Projects)
 	})
 	return err
 }
@@ -125,10 +156,42 @@ func (c *Cluster) CertDelete(fingerprint string) error {
 }
 
 // CertUpdate updates the certificate with the given fingerprint.
-func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int) error {
+func (c *Cluster) CertUpdate(fingerprint string, certName string, certType int, projects []string) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
 		_, err := tx.tx.Exec("UPDATE certificates SET name=?, type=? WHERE fingerprint=?", certName, certType, fingerprint)
-		return err
+		if err != nil {
+			return err
+		}
+
+		var id int64
+		err = tx.tx.QueryRow("SELECT id FROM certificates WHERE fingerprint=?", fingerprint).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM certificates_projects WHERE certificate_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return certificateProjectsSet(tx.tx, id, projects)
 	})
 	return err
 }
+
+// certificateProjects returns the projects the certificate with the given ID is restricted to.
+func certificateProjects(tx *sql.Tx, id int) ([]string, error) {
+	return query.SelectStrings(tx, "SELECT project FROM certificates_projects WHERE certificate_id=? ORDER BY project", id)
+}
+
+// certificateProjectsSet restricts the certificate with the given ID to the given projects.
+func certificateProjectsSet(tx *sql.Tx, id int64, projects []string) error {
+	for _, project := range projects {
+		_, err := tx.Exec("INSERT OR IGNORE INTO certificates_projects (certificate_id, project) VALUES (?, ?)", id, project)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
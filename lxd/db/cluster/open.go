@@ -30,8 +30,9 @@ func Open(name string, store driver.NodeStore, options ...driver.Option) (*sql.D
 		return nil, errors.Wrap(err, "Failed to create dqlite driver")
 	}
 
+	// Keep statistics about the queries and log the slow ones.
 	driverName := dqliteDriverName()
-	sql.Register(driverName, driver)
+	sql.Register(driverName, query.Instrument(driver))
 
 	// Create the cluster db. This won't immediately establish any network
 	// connection, that will happen only when a db transaction is started
@@ -59,6 +59,324 @@ var updates = map[int]schema.Update{
 	21: updateFromV20,
 	22: updateFromV21,
 	23: updateFromV22,
+	24: updateFromV23,
+	25: updateFromV24,
+	26: updateFromV25,
+	27: updateFromV26,
+	28: updateFromV27,
+	29: updateFromV28,
+	30: updateFromV29,
+	31: updateFromV30,
+	32: updateFromV31,
+	33: updateFromV32,
+	34: updateFromV33,
+	35: updateFromV34,
+	36: updateFromV35,
+	37: updateFromV36,
+	38: updateFromV37,
+	39: updateFromV38,
+}
+
+// Add "networks_load_balancers" and "networks_load_balancers_config" tables
+func updateFromV38(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_load_balancers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	network_id INTEGER NOT NULL,
+	listen_address TEXT NOT NULL,
+	description TEXT NOT NULL,
+	backends TEXT NOT NULL,
+	ports TEXT NOT NULL,
+	UNIQUE (network_id, listen_address),
+	FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE
+);
+CREATE TABLE networks_load_balancers_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	network_load_balancer_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT,
+	UNIQUE (network_load_balancer_id, key),
+	FOREIGN KEY (network_load_balancer_id) REFERENCES networks_load_balancers (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "instances_snapshots_volumes" table, holding the custom volume snapshots taken together with
+// an instance snapshot
+func updateFromV37(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE instances_snapshots_volumes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	instance_snapshot_id INTEGER NOT NULL,
+	storage_volume_id INTEGER NOT NULL,
+	FOREIGN KEY (instance_snapshot_id) REFERENCES instances_snapshots (id) ON DELETE CASCADE,
+	FOREIGN KEY (storage_volume_id) REFERENCES storage_volumes (id) ON DELETE CASCADE,
+	UNIQUE (instance_snapshot_id, storage_volume_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "storage_trash" and "storage_trash_config" tables, holding the deleted instances and volumes
+// kept on their storage pool until they expire
+func updateFromV36(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE storage_trash (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	storage_pool_id INTEGER NOT NULL,
+	node_id INTEGER NOT NULL,
+	project TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type INTEGER NOT NULL,
+	description TEXT,
+	deleted_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (storage_pool_id) REFERENCES storage_pools (id) ON DELETE CASCADE,
+	FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE
+);
+CREATE TABLE storage_trash_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	storage_trash_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT,
+	FOREIGN KEY (storage_trash_id) REFERENCES storage_trash (id) ON DELETE CASCADE,
+	UNIQUE (storage_trash_id, key)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "pinned" and "cache_expiry" columns to "images", letting images be kept or expire on their own
+func updateFromV35(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE images ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE images ADD COLUMN cache_expiry INTEGER NOT NULL DEFAULT 0;
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "images_aliases_config" table, holding the auto-update policies of image aliases
+func updateFromV34(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE images_aliases_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	image_alias_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT,
+	FOREIGN KEY (image_alias_id) REFERENCES images_aliases (id) ON DELETE CASCADE,
+	UNIQUE (image_alias_id, key)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "projects_usage" table, accumulating the resources consumed by projects hour by hour
+func updateFromV33(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE projects_usage (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	project_id INTEGER NOT NULL,
+	hour DATETIME NOT NULL,
+	instance_seconds REAL NOT NULL DEFAULT 0,
+	cpu_seconds REAL NOT NULL DEFAULT 0,
+	memory_byte_seconds REAL NOT NULL DEFAULT 0,
+	storage_byte_seconds REAL NOT NULL DEFAULT 0,
+	network_received INTEGER NOT NULL DEFAULT 0,
+	network_sent INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+	UNIQUE (project_id, hour)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "certificates_projects" table, restricting viewer and operator certificates to some projects
+func updateFromV32(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE certificates_projects (
+	certificate_id INTEGER NOT NULL,
+	project TEXT NOT NULL,
+	FOREIGN KEY (certificate_id) REFERENCES certificates (id) ON DELETE CASCADE,
+	UNIQUE (certificate_id, project)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the "created_at", "resources" and "resume_data" columns to "operations", persisting the
+// operations which survive a daemon restart
+func updateFromV31(tx *sql.Tx) error {
+	stmts := `
+ALTER TABLE operations ADD COLUMN created_at DATETIME;
+ALTER TABLE operations ADD COLUMN resources TEXT NOT NULL DEFAULT '';
+ALTER TABLE operations ADD COLUMN resume_data TEXT NOT NULL DEFAULT '';
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "auth_tokens" and "auth_tokens_projects" tables
+func updateFromV30(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE auth_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	secret_hash TEXT NOT NULL,
+	capability TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME,
+	UNIQUE (name),
+	UNIQUE (secret_hash)
+);
+CREATE TABLE auth_tokens_projects (
+	token_id INTEGER NOT NULL,
+	project TEXT NOT NULL,
+	FOREIGN KEY (token_id) REFERENCES auth_tokens (id) ON DELETE CASCADE,
+	UNIQUE (token_id, project)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "auth_groups", "auth_groups_identities" and "auth_groups_permissions" tables
+func updateFromV29(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE auth_groups (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	UNIQUE (name)
+);
+CREATE TABLE auth_groups_identities (
+	group_id INTEGER NOT NULL,
+	identity TEXT NOT NULL,
+	FOREIGN KEY (group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+	UNIQUE (group_id, identity)
+);
+CREATE TABLE auth_groups_permissions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	group_id INTEGER NOT NULL,
+	role TEXT NOT NULL,
+	project_id INTEGER,
+	instance_id INTEGER,
+	FOREIGN KEY (group_id) REFERENCES auth_groups (id) ON DELETE CASCADE,
+	FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+	FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE,
+	UNIQUE (group_id, role, project_id, instance_id)
+);
+CREATE INDEX auth_groups_identities_identity_idx ON auth_groups_identities (identity);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "cluster_groups" and "nodes_cluster_groups" tables
+func updateFromV28(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE cluster_groups (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	UNIQUE (name)
+);
+CREATE TABLE nodes_cluster_groups (
+	node_id INTEGER NOT NULL,
+	group_id INTEGER NOT NULL,
+	FOREIGN KEY (node_id) REFERENCES nodes (id) ON DELETE CASCADE,
+	FOREIGN KEY (group_id) REFERENCES cluster_groups (id) ON DELETE CASCADE,
+	UNIQUE (node_id, group_id)
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the "state" column to "nodes", tracking evacuated members
+func updateFromV27(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE nodes ADD COLUMN state INTEGER NOT NULL DEFAULT 0;")
+	return err
+}
+
+// Add "sriov_pools" and "sriov_pools_config" tables
+func updateFromV26(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE sriov_pools (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL,
+	UNIQUE (name)
+);
+CREATE TABLE sriov_pools_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	sriov_pool_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT,
+	UNIQUE (sriov_pool_id, key),
+	FOREIGN KEY (sriov_pool_id) REFERENCES sriov_pools (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add the WireGuard public key of each node to "networks_nodes"
+func updateFromV25(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE networks_nodes ADD COLUMN wireguard_public_key TEXT NOT NULL DEFAULT '';")
+	return err
+}
+
+// Add "networks_reservations" table
+func updateFromV24(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_reservations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	network_id INTEGER NOT NULL,
+	hwaddr TEXT NOT NULL,
+	description TEXT NOT NULL,
+	ipv4_address TEXT NOT NULL,
+	ipv6_address TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	UNIQUE (network_id, hwaddr),
+	FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
+}
+
+// Add "networks_forwards" and "networks_forwards_config" tables
+func updateFromV23(tx *sql.Tx) error {
+	stmts := `
+CREATE TABLE networks_forwards (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	network_id INTEGER NOT NULL,
+	listen_address TEXT NOT NULL,
+	description TEXT NOT NULL,
+	ports TEXT NOT NULL,
+	UNIQUE (network_id, listen_address),
+	FOREIGN KEY (network_id) REFERENCES networks (id) ON DELETE CASCADE
+);
+CREATE TABLE networks_forwards_config (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	network_forward_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT,
+	UNIQUE (network_forward_id, key),
+	FOREIGN KEY (network_forward_id) REFERENCES networks_forwards (id) ON DELETE CASCADE
+);
+`
+	_, err := tx.Exec(stmts)
+	return err
 }
 
 // The zfs.pool_name config key is required for ZFS to function.
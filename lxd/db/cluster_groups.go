@@ -0,0 +1,187 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// ClusterGroups returns the names of all the cluster groups.
+func (c *Cluster) ClusterGroups() ([]string, error) {
+	var names []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		names, err = query.SelectStrings(tx.tx, "SELECT name FROM cluster_groups ORDER BY name")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// ClusterGroupGet returns the cluster group with the given name.
+func (c *Cluster) ClusterGroupGet(name string) (int64, *api.ClusterGroup, error) {
+	id := int64(-1)
+	group := api.ClusterGroup{
+		Name: name,
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		err := tx.tx.QueryRow("SELECT id, description FROM cluster_groups WHERE name=?", name).Scan(&id, &group.Description)
+		if err != nil {
+			return err
+		}
+
+		group.Members, err = tx.ClusterGroupsMembers([]string{name})
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	return id, &group, nil
+}
+
+// ClusterGroupCreate creates a new cluster group with the given members.
+func (c *Cluster) ClusterGroupCreate(name string, description string, members []string) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO cluster_groups (name, description) VALUES (?, ?)", name, description)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return clusterGroupMembersAdd(tx.tx, id, members)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// ClusterGroupUpdate updates the description and members of the cluster group with the given ID.
+func (c *Cluster) ClusterGroupUpdate(id int64, description string, members []string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE cluster_groups SET description=? WHERE id=?", description, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM nodes_cluster_groups WHERE group_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return clusterGroupMembersAdd(tx.tx, id, members)
+	})
+}
+
+// ClusterGroupDelete deletes the cluster group with the given ID.
+func (c *Cluster) ClusterGroupDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "cluster_groups", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+// ClusterGroupsMembers returns the names of the nodes which are members of at least one of the
+// given cluster groups.
+func (c *ClusterTx) ClusterGroupsMembers(groups []string) ([]string, error) {
+	if len(groups) == 0 {
+		return []string{}, nil
+	}
+
+	args := make([]interface{}, len(groups))
+	for i, group := range groups {
+		args[i] = group
+	}
+
+	stmt := fmt.Sprintf(`
+SELECT DISTINCT nodes.name FROM nodes
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.node_id = nodes.id
+  JOIN cluster_groups ON cluster_groups.id = nodes_cluster_groups.group_id
+  WHERE cluster_groups.name IN %s
+  ORDER BY nodes.name`, query.Params(len(groups)))
+
+	return query.SelectStrings(c.tx, stmt, args...)
+}
+
+// NodesClusterGroups returns the names of the cluster groups of each node, indexed by node ID.
+func (c *ClusterTx) NodesClusterGroups() (map[int64][]string, error) {
+	groups := map[int64][]string{}
+
+	rows, err := c.tx.Query(`
+SELECT nodes_cluster_groups.node_id, cluster_groups.name FROM nodes_cluster_groups
+  JOIN cluster_groups ON cluster_groups.id = nodes_cluster_groups.group_id
+  ORDER BY cluster_groups.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nodeID int64
+		var name string
+
+		err := rows.Scan(&nodeID, &name)
+		if err != nil {
+			return nil, err
+		}
+
+		groups[nodeID] = append(groups[nodeID], name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func clusterGroupMembersAdd(tx *sql.Tx, groupID int64, members []string) error {
+	for _, member := range members {
+		var nodeID int64
+		err := tx.QueryRow("SELECT id FROM nodes WHERE name=?", member).Scan(&nodeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("Cluster member %q doesn't exist", member)
+			}
+
+			return err
+		}
+
+		_, err = tx.Exec("INSERT OR IGNORE INTO nodes_cluster_groups (node_id, group_id) VALUES (?, ?)", nodeID, groupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -333,6 +333,56 @@ SELECT instances.name, nodes.id, nodes.address, nodes.heartbeat
 	return result, nil
 }
 
+// instanceFilterColumns maps the fields instances can be filtered and sorted on to their columns.
+var instanceFilterColumns = map[string]string{
+	"name":         "instances.name",
+	"type":         fmt.Sprintf("CASE instances.type WHEN %d THEN 'virtual-machine' ELSE 'container' END", instancetype.VM),
+	"description":  "instances.description",
+	"location":     "nodes.name",
+	"ephemeral":    "CASE WHEN instances.ephemeral THEN 'true' ELSE 'false' END",
+	"stateful":     "CASE WHEN instances.stateful THEN 'true' ELSE 'false' END",
+	"created_at":   "instances.creation_date",
+	"last_used_at": "instances.last_use_date",
+}
+
+// InstanceNamesFiltered returns the names of the instances of a project matching the filter, in
+// the requested order and page.
+func (c *ClusterTx) InstanceNamesFiltered(project string, instanceType instancetype.Type, filter *query.Filter) ([]string, error) {
+	args := []interface{}{project}
+	filters := "projects.name = ?"
+
+	if instanceType != instancetype.Any {
+		filters += " AND instances.type = ?"
+		args = append(args, instanceType)
+	}
+
+	where, whereArgs, err := filter.Where(instanceFilterColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	if where != "" {
+		filters += " AND " + where
+		args = append(args, whereArgs...)
+	}
+
+	order, err := filter.OrderBy(instanceFilterColumns, "ORDER BY instances.name")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := fmt.Sprintf(`
+SELECT instances.name
+  FROM instances
+  JOIN nodes ON nodes.id = instances.node_id
+  JOIN projects ON projects.id = instances.project_id
+  WHERE %s
+  %s %s
+`, filters, order, filter.LimitOffset())
+
+	return query.SelectStrings(c.tx, stmt, args...)
+}
+
 // ContainerListExpanded loads all containers across all projects and expands
 // their config and devices using the profiles they are associated to.
 func (c *ClusterTx) ContainerListExpanded() ([]Instance, error) {
@@ -533,6 +583,84 @@ func (c *ClusterTx) ContainerNodeMove(project, oldName, newName, newNode string)
 	return nil
 }
 
+// InstanceProjectMove moves the instance with the given name to another project, along with its
+// snapshots, backups and storage volumes. Its profiles are replaced by the ones of the other
+// project with the same names, which must exist.
+func (c *ClusterTx) InstanceProjectMove(project, name, newProject string) error {
+	instanceID, err := c.InstanceID(project, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance ID")
+	}
+
+	projectID, err := c.ProjectID(project)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get project ID")
+	}
+
+	newProjectID, err := c.ProjectID(newProject)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get new project ID")
+	}
+
+	profiles, err := query.SelectStrings(c.tx, `
+SELECT profiles.name
+  FROM instances_profiles
+  JOIN profiles ON profiles.id = instances_profiles.profile_id
+ WHERE instances_profiles.instance_id = ?
+ ORDER BY instances_profiles.apply_order`, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance profiles")
+	}
+
+	profilesProject := newProject
+	enabled, err := c.ProjectHasProfiles(newProject)
+	if err != nil {
+		return errors.Wrap(err, "Check if project has profiles")
+	}
+
+	if !enabled {
+		profilesProject = "default"
+	}
+
+	for _, profile := range profiles {
+		exists, err := c.ProfileExists(profilesProject, profile)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("Profile %q doesn't exist in project %q", profile, newProject)
+		}
+	}
+
+	_, err = c.tx.Exec("UPDATE instances SET project_id=? WHERE id=?", newProjectID, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance project")
+	}
+
+	_, err = c.tx.Exec("DELETE FROM instances_profiles WHERE instance_id=?", instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete instance profiles")
+	}
+
+	err = ContainerProfilesInsert(c.tx, int(instanceID), newProject, profiles)
+	if err != nil {
+		return errors.Wrap(err, "Failed to add instance profiles")
+	}
+
+	// The volumes of the instance and of its snapshots, on all nodes for remote pools.
+	stmt := `
+UPDATE storage_volumes SET project_id=?
+ WHERE project_id=? AND type IN (?, ?) AND (name=? OR name LIKE ?)
+`
+	_, err = c.tx.Exec(stmt, newProjectID, projectID, StoragePoolVolumeTypeContainer, StoragePoolVolumeTypeVM, name, name+"/%")
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance volumes project")
+	}
+
+	return nil
+}
+
 // ContainerNodeProjectList returns all container objects on the local node within the given project.
 func (c *ClusterTx) ContainerNodeProjectList(project string, instanceType instancetype.Type) ([]Instance, error) {
 	node, err := c.NodeName()
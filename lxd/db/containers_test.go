@@ -157,6 +157,50 @@ func TestInstanceList_ContainerWithSameNameInDifferentProjects(t *testing.T) {
 	assert.Equal(t, []string{"intranet"}, containers[1].Profiles)
 }
 
+func TestInstanceProjectMove(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	// Create a project with the profiles feature and a profile named like the one of the instance.
+	project := api.ProjectsPost{}
+	project.Name = "test"
+	project.Config = map[string]string{"features.profiles": "true"}
+	_, err := tx.ProjectCreate(project)
+	require.NoError(t, err)
+
+	c1 := db.Instance{
+		Project:      "default",
+		Name:         "c1",
+		Node:         "none",
+		Type:         instancetype.Container,
+		Architecture: 1,
+		Profiles:     []string{"intranet"},
+	}
+
+	_, err = tx.ProfileCreate(db.Profile{Project: "default", Name: "intranet"})
+	require.NoError(t, err)
+
+	_, err = tx.InstanceCreate(c1)
+	require.NoError(t, err)
+
+	// The profiles of the instance must exist in the other project.
+	err = tx.InstanceProjectMove("default", "c1", "test")
+	assert.EqualError(t, err, `Profile "intranet" doesn't exist in project "test"`)
+
+	_, err = tx.ProfileCreate(db.Profile{Project: "test", Name: "intranet"})
+	require.NoError(t, err)
+
+	err = tx.InstanceProjectMove("default", "c1", "test")
+	require.NoError(t, err)
+
+	containers, err := tx.InstanceList(db.InstanceFilter{Name: "c1"})
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+
+	assert.Equal(t, "test", containers[0].Project)
+	assert.Equal(t, []string{"intranet"}, containers[0].Profiles)
+}
+
 func TestInstanceListExpanded(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
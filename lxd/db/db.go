@@ -3,10 +3,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/db/node"
 	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/trace"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
 )
@@ -112,11 +115,17 @@ func (n *Node) Dir() string {
 // function returns no error, all database changes are committed to the
 // node-level database, otherwise they are rolled back.
 func (n *Node) Transaction(f func(*NodeTx) error) error {
+	span := startTransactionSpan("sqlite")
+
 	nodeTx := &NodeTx{}
-	return query.Transaction(n.db, func(tx *sql.Tx) error {
+	err := query.Transaction(n.db, func(tx *sql.Tx) error {
 		nodeTx.tx = tx
 		return f(nodeTx)
 	})
+
+	span.End(err)
+
+	return err
 }
 
 // Close the database facade.
@@ -342,9 +351,15 @@ func (c *Cluster) GetNodeID() int64 {
 // If EnterExclusive has been called before, calling Transaction will block
 // until ExitExclusive has been called as well to release the lock.
 func (c *Cluster) Transaction(f func(*ClusterTx) error) error {
+	span := startTransactionSpan("dqlite")
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.transaction(f)
+
+	err := c.transaction(f)
+	span.End(err)
+
+	return err
 }
 
 // EnterExclusive acquires a lock on the cluster db, so any successive call to
@@ -389,6 +404,24 @@ func (c *Cluster) transaction(f func(*ClusterTx) error) error {
 	})
 }
 
+// startTransactionSpan starts the trace span of a transaction, naming the function which runs it.
+func startTransactionSpan(system string) *trace.Span {
+	_, span := trace.Start(context.Background(), "db.transaction")
+	if span == nil {
+		return nil
+	}
+
+	span.SetAttribute("db.system", system)
+
+	// Skip this function and the Transaction method.
+	pc, _, _, ok := runtime.Caller(2)
+	if ok {
+		span.SetAttribute("code.function", runtime.FuncForPC(pc).Name())
+	}
+
+	return span
+}
+
 // NodeID sets the the node NodeID associated with this cluster instance. It's used for
 // backward-compatibility of all db-related APIs that were written before
 // clustering and don't accept a node NodeID, so in those cases we automatically
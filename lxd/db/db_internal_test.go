@@ -59,7 +59,7 @@ func (s *dbTestSuite) CreateTestDb() (*Cluster, func()) {
 
 	// Setup logging if main() hasn't been called/when testing
 	if logger.Log == nil {
-		logger.Log, err = logging.GetLogger("", "", true, true, nil)
+		logger.Log, err = logging.GetLogger("", "", "", true, true, nil)
 		s.Nil(err)
 	}
 
@@ -20,6 +20,7 @@ var ImageSourceProtocol = map[int]string{
 	0: "lxd",
 	1: "direct",
 	2: "simplestreams",
+	3: "oci",
 }
 
 // ImagesGetLocal returns the names of all local images.
@@ -48,6 +49,28 @@ SELECT images.fingerprint
 
 // ImagesGet returns the names of all images (optionally only the public ones).
 func (c *Cluster) ImagesGet(project string, public bool) ([]string, error) {
+	return c.ImagesGetFiltered(project, public, nil)
+}
+
+// imageFilterColumns maps the fields images can be filtered and sorted on to their columns.
+var imageFilterColumns = map[string]string{
+	"fingerprint":  "images.fingerprint",
+	"filename":     "images.filename",
+	"size":         "images.size",
+	"public":       "CASE WHEN images.public THEN 'true' ELSE 'false' END",
+	"auto_update":  "CASE WHEN images.auto_update THEN 'true' ELSE 'false' END",
+	"cached":       "CASE WHEN images.cached THEN 'true' ELSE 'false' END",
+	"pinned":       "CASE WHEN images.pinned THEN 'true' ELSE 'false' END",
+	"type":         fmt.Sprintf("CASE images.type WHEN %d THEN 'virtual-machine' ELSE 'container' END", instancetype.VM),
+	"created_at":   "images.creation_date",
+	"uploaded_at":  "images.upload_date",
+	"expires_at":   "images.expiry_date",
+	"last_used_at": "images.last_use_date",
+}
+
+// ImagesGetFiltered returns the names of the images of a project matching the filter, in the
+// requested order and page. If public is true, only public images are returned.
+func (c *Cluster) ImagesGetFiltered(project string, public bool, filter *query.Filter) ([]string, error) {
 	err := c.Transaction(func(tx *ClusterTx) error {
 		enabled, err := tx.ProjectHasImages(project)
 		if err != nil {
@@ -72,8 +95,26 @@ SELECT fingerprint
 		q += " AND public=1"
 	}
 
-	var fp string
 	inargs := []interface{}{project}
+
+	where, args, err := filter.Where(imageFilterColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	if where != "" {
+		q += " AND " + where
+		inargs = append(inargs, args...)
+	}
+
+	order, err := filter.OrderBy(imageFilterColumns, "ORDER BY images.id")
+	if err != nil {
+		return nil, err
+	}
+
+	q += " " + order + " " + filter.LimitOffset()
+
+	var fp string
 	outfmt := []interface{}{fp}
 	dbResults, err := queryScan(c.db, q, inargs, outfmt)
 	if err != nil {
@@ -88,43 +129,62 @@ SELECT fingerprint
 	return results, nil
 }
 
-// ImagesGetExpired returns the names of all images that have expired since the
-// given time.
-func (c *Cluster) ImagesGetExpired(expiry int64) ([]string, error) {
-	q := `SELECT fingerprint, last_use_date, upload_date FROM images WHERE cached=1`
+// ImageExpired identifies a cached image of a project which hasn't been used for longer than its
+// cache expiry.
+type ImageExpired struct {
+	Project     string
+	Fingerprint string
+}
+
+// ImagesGetExpired returns the cached images, other than the pinned ones, which weren't used in
+// their project for the given number of days, or for their own cache expiry if set.
+func (c *Cluster) ImagesGetExpired(expiry int64) ([]ImageExpired, error) {
+	q := `
+SELECT projects.name, fingerprint, last_use_date, upload_date, cache_expiry
+  FROM images
+  JOIN projects ON projects.id = images.project_id
+ WHERE cached=1 AND pinned=0
+`
 
+	var projectStr string
 	var fpStr string
 	var useStr string
 	var uploadStr string
+	var cacheExpiry int64
 
 	inargs := []interface{}{}
-	outfmt := []interface{}{fpStr, useStr, uploadStr}
+	outfmt := []interface{}{projectStr, fpStr, useStr, uploadStr, cacheExpiry}
 	dbResults, err := queryScan(c.db, q, inargs, outfmt)
 	if err != nil {
-		return []string{}, err
+		return []ImageExpired{}, err
 	}
 
-	results := []string{}
+	results := []ImageExpired{}
 	for _, r := range dbResults {
 		// Figure out the expiry
-		timestamp := r[2]
-		if r[1] != "" {
-			timestamp = r[1]
+		timestamp := r[3]
+		if r[2] != "" {
+			timestamp = r[2]
+		}
+
+		days := expiry
+		if r[4].(int64) > 0 {
+			days = r[4].(int64)
 		}
 
 		var imageExpiry time.Time
 		err = imageExpiry.UnmarshalText([]byte(timestamp.(string)))
 		if err != nil {
-			return []string{}, err
+			return []ImageExpired{}, err
 		}
-		imageExpiry = imageExpiry.Add(time.Duration(expiry*24) * time.Hour)
+		imageExpiry = imageExpiry.Add(time.Duration(days*24) * time.Hour)
 
 		// Check if expired
 		if imageExpiry.After(time.Now()) {
 			continue
 		}
 
-		results = append(results, r[0].(string))
+		results = append(results, ImageExpired{Project: r[0].(string), Fingerprint: r[1].(string)})
 	}
 
 	return results, nil
@@ -179,6 +239,49 @@ func (c *Cluster) ImageSourceGet(imageID int) (int, api.ImageSource, error) {
 
 }
 
+// ImageSourceGetVersions returns the fingerprints of the images of a project downloaded from the
+// given remote details (server, protocol and alias), the most recent first.
+func (c *Cluster) ImageSourceGetVersions(project string, server string, protocol string, alias string) ([]string, error) {
+	protocolInt := -1
+	for protoInt, protoString := range ImageSourceProtocol {
+		if protoString == protocol {
+			protocolInt = protoInt
+		}
+	}
+
+	if protocolInt == -1 {
+		return nil, fmt.Errorf("Invalid protocol: %s", protocol)
+	}
+
+	q := `
+SELECT images.fingerprint
+  FROM images_source
+  JOIN images ON images_source.image_id = images.id
+  JOIN projects ON images.project_id = projects.id
+ WHERE projects.name = ? AND images_source.server = ? AND images_source.protocol = ? AND images_source.alias = ?
+ ORDER BY images.creation_date DESC
+`
+	var fingerprints []string
+	err := c.Transaction(func(tx *ClusterTx) error {
+		enabled, err := tx.ProjectHasImages(project)
+		if err != nil {
+			return errors.Wrap(err, "Check if project has images")
+		}
+
+		if !enabled {
+			project = "default"
+		}
+
+		fingerprints, err = query.SelectStrings(tx.tx, q, project, server, protocolInt, alias)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fingerprints, nil
+}
+
 // ImageSourceGetCachedFingerprint tries to find a source entry of a locally
 // cached image that matches the given remote details (server, protocol and
 // alias). Return the fingerprint linked to the matching entry, if any.
@@ -332,13 +435,13 @@ func (c *Cluster) ImageGet(project, fingerprint string, public bool, strictMatch
 	// These two humongous things will be filled by the call to DbQueryRowScan
 	outfmt := []interface{}{&id, &image.Fingerprint, &image.Filename,
 		&image.Size, &image.Cached, &image.Public, &image.AutoUpdate, &arch,
-		&create, &expire, &used, &upload, &imageType}
+		&create, &expire, &used, &upload, &imageType, &image.Pinned, &image.CacheExpiry}
 
 	inargs := []interface{}{project}
 	query := `
         SELECT
             images.id, fingerprint, filename, size, cached, public, auto_update, architecture,
-            creation_date, expiry_date, last_use_date, upload_date, type
+            creation_date, expiry_date, last_use_date, upload_date, type, pinned, cache_expiry
         FROM images
         JOIN projects ON projects.id = images.project_id
        WHERE projects.name = ?`
@@ -412,13 +515,13 @@ func (c *Cluster) ImageGetFromAnyProject(fingerprint string) (int, *api.Image, e
 	// These two humongous things will be filled by the call to DbQueryRowScan
 	outfmt := []interface{}{&id, &image.Fingerprint, &image.Filename,
 		&image.Size, &image.Cached, &image.Public, &image.AutoUpdate, &arch,
-		&create, &expire, &used, &upload, &imageType}
+		&create, &expire, &used, &upload, &imageType, &image.Pinned, &image.CacheExpiry}
 
 	inargs := []interface{}{fingerprint}
 	query := `
         SELECT
             images.id, fingerprint, filename, size, cached, public, auto_update, architecture,
-            creation_date, expiry_date, last_use_date, upload_date, type
+            creation_date, expiry_date, last_use_date, upload_date, type, pinned, cache_expiry
         FROM images
         WHERE fingerprint = ?
         LIMIT 1`
@@ -722,9 +825,74 @@ func (c *Cluster) ImageAliasGet(project, name string, isTrustedClient bool) (int
 	entry.Description = description
 	entry.Type = instancetype.Type(imageType).String()
 
+	entry.Config, err = c.ImageAliasConfigGet(id)
+	if err != nil {
+		return -1, entry, err
+	}
+
 	return id, entry, nil
 }
 
+// ImageAliasConfigGet returns the configuration of the alias with the given ID.
+func (c *Cluster) ImageAliasConfigGet(id int) (map[string]string, error) {
+	var config map[string]string
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		config, err = query.SelectConfig(tx.tx, "images_aliases_config", "image_alias_id=?", id)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch image alias config")
+	}
+
+	return config, nil
+}
+
+// ImageAliasConfigUpdate replaces the configuration of the alias with the given ID.
+func (c *Cluster) ImageAliasConfigUpdate(id int, config map[string]string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM images_aliases_config WHERE image_alias_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.tx.Prepare("INSERT INTO images_aliases_config (image_alias_id, key, value) VALUES (?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for k, v := range config {
+			if v == "" {
+				continue
+			}
+
+			_, err = stmt.Exec(id, k, v)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ImageAliasesHaveConfig returns whether any image alias has a configuration key with the given
+// prefix.
+func (c *Cluster) ImageAliasesHaveConfig(prefix string) (bool, error) {
+	count := 0
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		count, err = query.Count(tx.tx, "images_aliases_config", "key LIKE ?", prefix+"%")
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 // ImageAliasRename renames the alias with the given ID.
 func (c *Cluster) ImageAliasRename(id int, name string) error {
 	err := exec(c.db, "UPDATE images_aliases SET name=? WHERE id=?", name, id)
@@ -817,10 +985,30 @@ func (c *Cluster) ImageCopyDefaultProfiles(id int, newID int) error {
 }
 
 // ImageLastAccessUpdate updates the last_use_date field of the image with the
-// given fingerprint.
-func (c *Cluster) ImageLastAccessUpdate(fingerprint string, date time.Time) error {
-	stmt := `UPDATE images SET last_use_date=? WHERE fingerprint=?`
-	err := exec(c.db, stmt, date, fingerprint)
+// given fingerprint in the given project.
+func (c *Cluster) ImageLastAccessUpdate(project, fingerprint string, date time.Time) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		enabled, err := tx.ProjectHasImages(project)
+		if err != nil {
+			return errors.Wrap(err, "Check if project has images")
+		}
+		if !enabled {
+			project = "default"
+		}
+
+		stmt := `
+UPDATE images SET last_use_date=?
+ WHERE fingerprint=? AND project_id = (SELECT id FROM projects WHERE name = ?)
+`
+		_, err = tx.tx.Exec(stmt, date, fingerprint, project)
+		return err
+	})
+}
+
+// ImageCacheUpdate sets whether the image with the given ID is pinned, never expiring from the
+// cache, and its own cache expiry in days (0 for images.remote_cache_expiry).
+func (c *Cluster) ImageCacheUpdate(id int, pinned bool, cacheExpiry int64) error {
+	err := exec(c.db, "UPDATE images SET pinned=?, cache_expiry=? WHERE id=?", pinned, cacheExpiry, id)
 	return err
 }
 
@@ -1114,15 +1302,16 @@ WHERE images.fingerprint = ?
 	return c.getNodesByImageFingerprint(q, fingerprint)
 }
 
-// ImageGetNodesWithoutImage returns the addresses of online nodes which don't have the image.
+// ImageGetNodesWithoutImage returns the addresses of online nodes which don't have the image,
+// leaving out the ones in maintenance.
 func (c *Cluster) ImageGetNodesWithoutImage(fingerprint string) ([]string, error) {
-	q := `
-SELECT DISTINCT nodes.address FROM nodes WHERE nodes.address NOT IN (
+	q := fmt.Sprintf(`
+SELECT DISTINCT nodes.address FROM nodes WHERE nodes.state != %d AND nodes.address NOT IN (
   SELECT DISTINCT nodes.address FROM nodes
     LEFT JOIN images_nodes ON images_nodes.node_id = nodes.id
     LEFT JOIN images ON images_nodes.image_id = images.id
   WHERE images.fingerprint = ?)
-`
+`, ClusterMemberStateMaintenance)
 	return c.getNodesByImageFingerprint(q, fingerprint)
 }
 
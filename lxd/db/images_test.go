@@ -39,3 +39,37 @@ func TestImageLocate(t *testing.T) {
 	require.Equal(t, "", address)
 	require.EqualError(t, err, "image not available on any online node")
 }
+
+func TestImagesGetExpired(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	for _, fingerprint := range []string{"abc", "def", "ghi"} {
+		err := cluster.ImageInsert(
+			"default", fingerprint, "x.gz", 16, false, false, "amd64", time.Now(), time.Now(), map[string]string{}, "container")
+		require.NoError(t, err)
+
+		err = cluster.ImageLastAccessInit(fingerprint)
+		require.NoError(t, err)
+
+		err = cluster.ImageLastAccessUpdate("default", fingerprint, time.Now().Add(-20*24*time.Hour))
+		require.NoError(t, err)
+	}
+
+	// Pin the first image and keep the second one for longer.
+	id, _, err := cluster.ImageGet("default", "abc", false, true)
+	require.NoError(t, err)
+
+	err = cluster.ImageCacheUpdate(id, true, 0)
+	require.NoError(t, err)
+
+	id, _, err = cluster.ImageGet("default", "def", false, true)
+	require.NoError(t, err)
+
+	err = cluster.ImageCacheUpdate(id, false, 30)
+	require.NoError(t, err)
+
+	images, err := cluster.ImagesGetExpired(10)
+	require.NoError(t, err)
+	assert.Equal(t, []db.ImageExpired{{Project: "default", Fingerprint: "ghi"}}, images)
+}
@@ -0,0 +1,190 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// NetworkForwards returns all the address forwards of the network with the given ID.
+func (c *Cluster) NetworkForwards(networkID int64) ([]api.NetworkForward, error) {
+	forwards := []api.NetworkForward{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		addresses, err := query.SelectStrings(tx.tx, "SELECT listen_address FROM networks_forwards WHERE network_id=? ORDER BY listen_address", networkID)
+		if err != nil {
+			return err
+		}
+
+		for _, address := range addresses {
+			_, forward, err := tx.networkForwardGet(networkID, address)
+			if err != nil {
+				return err
+			}
+
+			forwards = append(forwards, *forward)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return forwards, nil
+}
+
+// NetworkForwardGet returns the address forward of the network with the given ID for the given
+// listen address.
+func (c *Cluster) NetworkForwardGet(networkID int64, listenAddress string) (int64, *api.NetworkForward, error) {
+	var id int64
+	var forward *api.NetworkForward
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		id, forward, err = tx.networkForwardGet(networkID, listenAddress)
+		return err
+	})
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, forward, nil
+}
+
+func (c *ClusterTx) networkForwardGet(networkID int64, listenAddress string) (int64, *api.NetworkForward, error) {
+	var id int64
+	var ports string
+
+	forward := api.NetworkForward{
+		ListenAddress: listenAddress,
+	}
+
+	stmt := "SELECT id, description, ports FROM networks_forwards WHERE network_id=? AND listen_address=?"
+	err := c.tx.QueryRow(stmt, networkID, listenAddress).Scan(&id, &forward.Description, &ports)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	err = json.Unmarshal([]byte(ports), &forward.Ports)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed to parse ports of network forward %q: %v", listenAddress, err)
+	}
+
+	forward.Config, err = query.SelectConfig(c.tx, "networks_forwards_config", "network_forward_id=?", id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &forward, nil
+}
+
+// NetworkForwardCreate creates a new address forward on the network with the given ID.
+func (c *Cluster) NetworkForwardCreate(networkID int64, forward *api.NetworkForwardsPost) (int64, error) {
+	var id int64
+
+	ports, err := networkForwardPortsMarshal(forward.Ports)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO networks_forwards (network_id, listen_address, description, ports) VALUES (?, ?, ?, ?)", networkID, forward.ListenAddress, forward.Description, ports)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkForwardConfigAdd(tx.tx, id, forward.Config)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// NetworkForwardUpdate updates the address forward with the given ID.
+func (c *Cluster) NetworkForwardUpdate(id int64, forward *api.NetworkForwardPut) error {
+	ports, err := networkForwardPortsMarshal(forward.Ports)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_forwards SET description=?, ports=? WHERE id=?", forward.Description, ports, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_forwards_config WHERE network_forward_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkForwardConfigAdd(tx.tx, id, forward.Config)
+	})
+}
+
+// NetworkForwardDelete deletes the address forward with the given ID.
+func (c *Cluster) NetworkForwardDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "networks_forwards", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+func networkForwardPortsMarshal(ports []api.NetworkForwardPort) (string, error) {
+	if ports == nil {
+		ports = []api.NetworkForwardPort{}
+	}
+
+	data, err := json.Marshal(ports)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func networkForwardConfigAdd(tx *sql.Tx, forwardID int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_forwards_config (network_forward_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(forwardID, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
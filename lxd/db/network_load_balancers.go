@@ -0,0 +1,207 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// NetworkLoadBalancers returns all the load balancers of the network with the given ID.
+func (c *Cluster) NetworkLoadBalancers(networkID int64) ([]api.NetworkLoadBalancer, error) {
+	loadBalancers := []api.NetworkLoadBalancer{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		addresses, err := query.SelectStrings(tx.tx, "SELECT listen_address FROM networks_load_balancers WHERE network_id=? ORDER BY listen_address", networkID)
+		if err != nil {
+			return err
+		}
+
+		for _, address := range addresses {
+			_, loadBalancer, err := tx.networkLoadBalancerGet(networkID, address)
+			if err != nil {
+				return err
+			}
+
+			loadBalancers = append(loadBalancers, *loadBalancer)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancers, nil
+}
+
+// NetworkLoadBalancerGet returns the load balancer of the network with the given ID for the given
+// listen address.
+func (c *Cluster) NetworkLoadBalancerGet(networkID int64, listenAddress string) (int64, *api.NetworkLoadBalancer, error) {
+	var id int64
+	var loadBalancer *api.NetworkLoadBalancer
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		id, loadBalancer, err = tx.networkLoadBalancerGet(networkID, listenAddress)
+		return err
+	})
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, loadBalancer, nil
+}
+
+func (c *ClusterTx) networkLoadBalancerGet(networkID int64, listenAddress string) (int64, *api.NetworkLoadBalancer, error) {
+	var id int64
+	var backends string
+	var ports string
+
+	loadBalancer := api.NetworkLoadBalancer{
+		ListenAddress: listenAddress,
+	}
+
+	stmt := "SELECT id, description, backends, ports FROM networks_load_balancers WHERE network_id=? AND listen_address=?"
+	err := c.tx.QueryRow(stmt, networkID, listenAddress).Scan(&id, &loadBalancer.Description, &backends, &ports)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	err = json.Unmarshal([]byte(backends), &loadBalancer.Backends)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed to parse backends of network load balancer %q: %v", listenAddress, err)
+	}
+
+	err = json.Unmarshal([]byte(ports), &loadBalancer.Ports)
+	if err != nil {
+		return -1, nil, fmt.Errorf("Failed to parse ports of network load balancer %q: %v", listenAddress, err)
+	}
+
+	loadBalancer.Config, err = query.SelectConfig(c.tx, "networks_load_balancers_config", "network_load_balancer_id=?", id)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	return id, &loadBalancer, nil
+}
+
+// NetworkLoadBalancerCreate creates a new load balancer on the network with the given ID.
+func (c *Cluster) NetworkLoadBalancerCreate(networkID int64, loadBalancer *api.NetworkLoadBalancersPost) (int64, error) {
+	var id int64
+
+	backends, ports, err := networkLoadBalancerMarshal(&loadBalancer.NetworkLoadBalancerPut)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO networks_load_balancers (network_id, listen_address, description, backends, ports) VALUES (?, ?, ?, ?, ?)", networkID, loadBalancer.ListenAddress, loadBalancer.Description, backends, ports)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return networkLoadBalancerConfigAdd(tx.tx, id, loadBalancer.Config)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// NetworkLoadBalancerUpdate updates the load balancer with the given ID.
+func (c *Cluster) NetworkLoadBalancerUpdate(id int64, loadBalancer *api.NetworkLoadBalancerPut) error {
+	backends, ports, err := networkLoadBalancerMarshal(loadBalancer)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_load_balancers SET description=?, backends=?, ports=? WHERE id=?", loadBalancer.Description, backends, ports, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_load_balancers_config WHERE network_load_balancer_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return networkLoadBalancerConfigAdd(tx.tx, id, loadBalancer.Config)
+	})
+}
+
+// NetworkLoadBalancerDelete deletes the load balancer with the given ID.
+func (c *Cluster) NetworkLoadBalancerDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "networks_load_balancers", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+func networkLoadBalancerMarshal(loadBalancer *api.NetworkLoadBalancerPut) (string, string, error) {
+	backends := loadBalancer.Backends
+	if backends == nil {
+		backends = []api.NetworkLoadBalancerBackend{}
+	}
+
+	ports := loadBalancer.Ports
+	if ports == nil {
+		ports = []api.NetworkLoadBalancerPort{}
+	}
+
+	backendsData, err := json.Marshal(backends)
+	if err != nil {
+		return "", "", err
+	}
+
+	portsData, err := json.Marshal(ports)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(backendsData), string(portsData), nil
+}
+
+func networkLoadBalancerConfigAdd(tx *sql.Tx, loadBalancerID int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_load_balancers_config (network_load_balancer_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(loadBalancerID, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
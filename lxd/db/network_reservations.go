@@ -0,0 +1,108 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// NetworkReservations returns all the DHCP reservations of the network with the given ID.
+func (c *Cluster) NetworkReservations(networkID int64) ([]api.NetworkReservation, error) {
+	reservations := []api.NetworkReservation{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		stmt := "SELECT hwaddr, description, ipv4_address, ipv6_address, hostname FROM networks_reservations WHERE network_id=? ORDER BY hwaddr"
+		rows, err := tx.tx.Query(stmt, networkID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			reservation := api.NetworkReservation{}
+			err := rows.Scan(&reservation.Hwaddr, &reservation.Description, &reservation.IPv4Address, &reservation.IPv6Address, &reservation.Hostname)
+			if err != nil {
+				return err
+			}
+
+			reservations = append(reservations, reservation)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// NetworkReservationGet returns the DHCP reservation of the network with the given ID for the
+// given MAC address.
+func (c *Cluster) NetworkReservationGet(networkID int64, hwaddr string) (int64, *api.NetworkReservation, error) {
+	id := int64(-1)
+	reservation := api.NetworkReservation{
+		Hwaddr: hwaddr,
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		stmt := "SELECT id, description, ipv4_address, ipv6_address, hostname FROM networks_reservations WHERE network_id=? AND hwaddr=?"
+		return tx.tx.QueryRow(stmt, networkID, hwaddr).Scan(&id, &reservation.Description, &reservation.IPv4Address, &reservation.IPv6Address, &reservation.Hostname)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	return id, &reservation, nil
+}
+
+// NetworkReservationCreate creates a new DHCP reservation on the network with the given ID.
+func (c *Cluster) NetworkReservationCreate(networkID int64, reservation *api.NetworkReservationsPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO networks_reservations (network_id, hwaddr, description, ipv4_address, ipv6_address, hostname) VALUES (?, ?, ?, ?, ?, ?)", networkID, reservation.Hwaddr, reservation.Description, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// NetworkReservationUpdate updates the DHCP reservation with the given ID.
+func (c *Cluster) NetworkReservationUpdate(id int64, reservation *api.NetworkReservationPut) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_reservations SET description=?, ipv4_address=?, ipv6_address=?, hostname=? WHERE id=?", reservation.Description, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname, id)
+		return err
+	})
+}
+
+// NetworkReservationDelete deletes the DHCP reservation with the given ID.
+func (c *Cluster) NetworkReservationDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "networks_reservations", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
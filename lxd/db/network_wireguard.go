@@ -0,0 +1,58 @@
+// +build linux,cgo,!agent
+
+package db
+
+// NetworkWireGuardPeer holds the WireGuard details of a node on which a network is defined.
+type NetworkWireGuardPeer struct {
+	NodeID    int64
+	Address   string
+	PublicKey string
+	Local     bool
+}
+
+// NetworkWireGuardPublicKeySet records the WireGuard public key used by this node for the network
+// with the given ID.
+func (c *Cluster) NetworkWireGuardPublicKeySet(networkID int64, publicKey string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_nodes SET wireguard_public_key=? WHERE network_id=? AND node_id=?", publicKey, networkID, c.nodeID)
+		return err
+	})
+}
+
+// NetworkWireGuardPeers returns the WireGuard details of all the nodes on which the network with
+// the given ID is defined, including this one.
+func (c *Cluster) NetworkWireGuardPeers(networkID int64) ([]NetworkWireGuardPeer, error) {
+	peers := []NetworkWireGuardPeer{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		stmt := `
+SELECT nodes.id, nodes.address, networks_nodes.wireguard_public_key FROM networks_nodes
+  JOIN nodes ON nodes.id = networks_nodes.node_id
+WHERE networks_nodes.network_id = ?
+ORDER BY nodes.id
+`
+		rows, err := tx.tx.Query(stmt, networkID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			peer := NetworkWireGuardPeer{}
+			err := rows.Scan(&peer.NodeID, &peer.Address, &peer.PublicKey)
+			if err != nil {
+				return err
+			}
+
+			peer.Local = peer.NodeID == c.nodeID
+			peers = append(peers, peer)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
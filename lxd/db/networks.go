@@ -597,5 +597,7 @@ func (c *Cluster) NetworkRename(oldName string, newName string) error {
 
 // NetworkNodeConfigKeys lists all network config keys which are node-specific.
 var NetworkNodeConfigKeys = []string{
+	"bgp.ipv4.nexthop",
+	"bgp.ipv6.nexthop",
 	"bridge.external_interfaces",
 }
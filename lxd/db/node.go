@@ -28,6 +28,16 @@ var ClusterRoles = map[int]ClusterRole{
 	0: ClusterRoleDatabase,
 }
 
+// ClusterMemberStateCreated is the normal state of a cluster member.
+const ClusterMemberStateCreated = 0
+
+// ClusterMemberStateEvacuated is the state of a cluster member whose instances were evacuated.
+const ClusterMemberStateEvacuated = 1
+
+// ClusterMemberStateMaintenance is the state of a cluster member which keeps running its instances
+// but doesn't get new instances or images.
+const ClusterMemberStateMaintenance = 2
+
 // NodeInfo holds information about a single LXD instance in a cluster.
 type NodeInfo struct {
 	ID            int64     // Stable node identifier
@@ -39,6 +49,7 @@ type NodeInfo struct {
 	Heartbeat     time.Time // Timestamp of the last heartbeat
 	Roles         []string  // List of cluster roles
 	Architecture  int       // Node architecture
+	State         int       // Node state
 }
 
 // IsOffline returns true if the last successful heartbeat time of the node is
@@ -273,6 +284,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 			&nodes[i].APIExtensions,
 			&nodes[i].Heartbeat,
 			&nodes[i].Architecture,
+			&nodes[i].State,
 		}
 	}
 	if pending {
@@ -282,7 +294,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 	}
 
 	// Get the node entries
-	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch FROM nodes WHERE pending=?"
+	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch, state FROM nodes WHERE pending=?"
 	if where != "" {
 		sql += fmt.Sprintf("AND %s ", where)
 	}
@@ -366,6 +378,27 @@ func (c *ClusterTx) NodeUpdate(id int64, name string, address string) error {
 	return nil
 }
 
+// NodeUpdateState updates the state of the node with the given id.
+func (c *ClusterTx) NodeUpdateState(id int64, state int) error {
+	stmt := "UPDATE nodes SET state=? WHERE id=?"
+
+	result, err := c.tx.Exec(stmt, state, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update node state")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get affected rows")
+	}
+
+	if n != 1 {
+		return fmt.Errorf("Expected exactly one row to be updated")
+	}
+
+	return nil
+}
+
 // NodeAddRole adds a role to the node.
 func (c *ClusterTx) NodeAddRole(id int64, role ClusterRole) error {
 	// Translate role names to ids
@@ -579,11 +612,18 @@ func (c *ClusterTx) NodeOfflineThreshold() (time.Duration, error) {
 	return threshold, nil
 }
 
-// NodeWithLeastContainers returns the name of the non-offline node with with
-// the least number of containers (either already created or being created with
-// an operation). If archs is not empty, then return only nodes with an
-// architecture in that list.
+// NodeWithLeastContainers returns the name of the non-offline, non-evacuated
+// node with with the least number of containers (either already created or
+// being created with an operation). If archs is not empty, then return only
+// nodes with an architecture in that list.
 func (c *ClusterTx) NodeWithLeastContainers(archs []int) (string, error) {
+	return c.NodeWithLeastContainersInGroups(archs, nil)
+}
+
+// NodeWithLeastContainersInGroups works like NodeWithLeastContainers, but if
+// groups is not empty, then return only nodes which are members of at least
+// one of those cluster groups.
+func (c *ClusterTx) NodeWithLeastContainersInGroups(archs []int, groups []string) (string, error) {
 	threshold, err := c.NodeOfflineThreshold()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get offline threshold")
@@ -594,10 +634,22 @@ func (c *ClusterTx) NodeWithLeastContainers(archs []int) (string, error) {
 		return "", errors.Wrap(err, "failed to get current nodes")
 	}
 
+	var members []string
+	if len(groups) > 0 {
+		members, err = c.ClusterGroupsMembers(groups)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get cluster group members")
+		}
+	}
+
 	name := ""
 	containers := -1
 	for _, node := range nodes {
-		if node.IsOffline(threshold) {
+		if node.IsOffline(threshold) || node.State == ClusterMemberStateEvacuated || node.State == ClusterMemberStateMaintenance {
+			continue
+		}
+
+		if len(groups) > 0 && !shared.StringInSlice(node.Name, members) {
 			continue
 		}
 
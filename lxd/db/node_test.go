@@ -343,6 +343,61 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	assert.Equal(t, "buzz", name)
 }
 
+// If there are nodes, and one of them is evacuated, return the name of the
+// other node, even if the evacuated one has less containers.
+func TestNodeWithLeastContainers_EvacuatedNode(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	// Add a container to the default node (ID 1)
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	// Mark the newly created node as evacuated.
+	err = tx.NodeUpdateState(id, db.ClusterMemberStateEvacuated)
+	require.NoError(t, err)
+
+	name, err := tx.NodeWithLeastContainers(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+}
+
+// If a cluster group is given, only nodes which are members of it are
+// returned, even if another node has less containers.
+func TestNodeWithLeastContainersInGroups(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.NodeAdd("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	// Add a container to the default node (ID 1)
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	// Put the default node in a group of its own.
+	_, err = tx.Tx().Exec(`
+INSERT INTO cluster_groups (id, name) VALUES (1, 'rack1');
+INSERT INTO nodes_cluster_groups (node_id, group_id) VALUES (1, 1);
+`)
+	require.NoError(t, err)
+
+	name, err := tx.NodeWithLeastContainersInGroups(nil, []string{"rack1"})
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+
+	name, err = tx.NodeWithLeastContainers(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "buzz", name)
+}
+
 // If there are 2 online nodes, and a container is pending on one of them,
 // return the address of the other one number of containers.
 func TestNodeWithLeastContainers_Pending(t *testing.T) {
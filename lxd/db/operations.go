@@ -3,7 +3,9 @@
 package db
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/pkg/errors"
@@ -16,6 +18,7 @@ type Operation struct {
 	UUID        string        // User-visible identifier
 	NodeAddress string        // Address of the node the operation is running on
 	Type        OperationType // Type of the operation
+	Project     string        // Name of the project of the operation, if any
 }
 
 // Operations returns all operations associated with this node.
@@ -30,6 +33,11 @@ func (c *ClusterTx) OperationsUUIDs() ([]string, error) {
 	return query.SelectStrings(c.tx, stmt, c.nodeID)
 }
 
+// OperationsOfType returns all operations of the given type across the cluster.
+func (c *ClusterTx) OperationsOfType(typ OperationType) ([]Operation, error) {
+	return c.operations("type=?", typ)
+}
+
 // OperationNodes returns a list of nodes that have running operations
 func (c *ClusterTx) OperationNodes(project string) ([]string, error) {
 	stmt := `
@@ -42,6 +50,16 @@ SELECT DISTINCT nodes.address
 	return query.SelectStrings(c.tx, stmt, project)
 }
 
+// OperationNodesAllProjects returns a list of nodes that have running operations in any project.
+func (c *ClusterTx) OperationNodesAllProjects() ([]string, error) {
+	stmt := `
+SELECT DISTINCT nodes.address
+  FROM operations
+  JOIN nodes ON nodes.id = operations.node_id
+`
+	return query.SelectStrings(c.tx, stmt)
+}
+
 // OperationByUUID returns the operation with the given UUID.
 func (c *ClusterTx) OperationByUUID(uuid string) (Operation, error) {
 	null := Operation{}
@@ -94,9 +112,10 @@ func (c *ClusterTx) OperationRemove(uuid string) error {
 	return nil
 }
 
-// OperationFlush removes all operations for the given node.
+// OperationFlush removes all operations for the given node, except the persisted ones which are
+// left for the daemon to resume or roll back.
 func (c *ClusterTx) OperationFlush(nodeID int64) error {
-	_, err := c.tx.Exec("DELETE FROM operations WHERE node_id=?", nodeID)
+	_, err := c.tx.Exec("DELETE FROM operations WHERE node_id=? AND created_at IS NULL", nodeID)
 	if err != nil {
 		return err
 	}
@@ -104,6 +123,87 @@ func (c *ClusterTx) OperationFlush(nodeID int64) error {
 	return nil
 }
 
+// InterruptedOperation holds the persisted state of an operation which was still running when
+// the daemon stopped.
+type InterruptedOperation struct {
+	UUID       string
+	Type       OperationType
+	Project    string
+	CreatedAt  time.Time
+	Resources  map[string][]string
+	ResumeData string
+}
+
+// OperationPersist stores the creation date and resources of an operation, for it to be resumed
+// or rolled back if the daemon stops before it's done.
+func (c *ClusterTx) OperationPersist(uuid string, createdAt time.Time, resources map[string][]string) error {
+	encoded, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.tx.Exec("UPDATE operations SET created_at=?, resources=? WHERE uuid=?", createdAt.UTC(), string(encoded), uuid)
+	return err
+}
+
+// OperationUnpersist clears the persisted state of an operation which is done, so that it's
+// neither resumed nor rolled back should the daemon stop before the operation is removed.
+func (c *ClusterTx) OperationUnpersist(uuid string) error {
+	_, err := c.tx.Exec("UPDATE operations SET created_at=NULL, resources='', resume_data='' WHERE uuid=?", uuid)
+	return err
+}
+
+// OperationSetResumeData stores what's needed to resume or roll back an operation.
+func (c *ClusterTx) OperationSetResumeData(uuid string, data string) error {
+	_, err := c.tx.Exec("UPDATE operations SET resume_data=? WHERE uuid=?", data, uuid)
+	return err
+}
+
+// OperationsInterrupted returns the persistent operations of this node, which were interrupted
+// when the daemon last stopped.
+func (c *ClusterTx) OperationsInterrupted() ([]InterruptedOperation, error) {
+	stmt := `
+SELECT operations.uuid, operations.type, IFNULL(projects.name, ''), operations.created_at, operations.resources, operations.resume_data
+  FROM operations
+  LEFT OUTER JOIN projects ON projects.id = operations.project_id
+ WHERE operations.node_id = ? AND operations.created_at IS NOT NULL
+ ORDER BY operations.id
+`
+
+	rows, err := c.tx.Query(stmt, c.nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := []InterruptedOperation{}
+	for rows.Next() {
+		op := InterruptedOperation{}
+		var resources string
+
+		err := rows.Scan(&op.UUID, &op.Type, &op.Project, &op.CreatedAt, &resources, &op.ResumeData)
+		if err != nil {
+			return nil, err
+		}
+
+		if resources != "" {
+			err = json.Unmarshal([]byte(resources), &op.Resources)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse the resources of operation %s", op.UUID)
+			}
+		}
+
+		operations = append(operations, op)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
 // Operations returns all operations in the cluster, filtered by the given clause.
 func (c *ClusterTx) operations(where string, args ...interface{}) ([]Operation, error) {
 	operations := []Operation{}
@@ -114,10 +214,13 @@ func (c *ClusterTx) operations(where string, args ...interface{}) ([]Operation,
 			&operations[i].UUID,
 			&operations[i].NodeAddress,
 			&operations[i].Type,
+			&operations[i].Project,
 		}
 	}
 	sql := `
-SELECT operations.id, uuid, nodes.address, type FROM operations JOIN nodes ON nodes.id = node_id `
+SELECT operations.id, uuid, nodes.address, type, IFNULL(projects.name, '') FROM operations
+  JOIN nodes ON nodes.id = node_id
+  LEFT OUTER JOIN projects ON projects.id = operations.project_id `
 	if where != "" {
 		sql += fmt.Sprintf("WHERE %s ", where)
 	}
@@ -4,6 +4,7 @@ package db_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,7 @@ func TestOperation(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "default", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -58,6 +60,7 @@ func TestOperationNoProject(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, id, operation.ID)
 	assert.Equal(t, db.OperationContainerCreate, operation.Type)
+	assert.Equal(t, "", operation.Project)
 
 	uuids, err := tx.OperationsUUIDs()
 	require.NoError(t, err)
@@ -69,3 +72,41 @@ func TestOperationNoProject(t *testing.T) {
 	_, err = tx.OperationByUUID("abcd")
 	assert.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Persisted operations are kept by a flush and returned as interrupted.
+func TestOperationPersist(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.OperationAdd("default", "abcd", db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	_, err = tx.OperationAdd("default", "efgh", db.OperationContainerStart)
+	require.NoError(t, err)
+
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	resources := map[string][]string{"containers": {"/1.0/containers/c1"}}
+	require.NoError(t, tx.OperationPersist("abcd", createdAt, resources))
+	require.NoError(t, tx.OperationSetResumeData("abcd", `{"name":"c1"}`))
+
+	require.NoError(t, tx.OperationFlush(1))
+
+	uuids, err := tx.OperationsUUIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abcd"}, uuids)
+
+	operations, err := tx.OperationsInterrupted()
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	assert.Equal(t, "default", operations[0].Project)
+	assert.Equal(t, db.OperationContainerCreate, operations[0].Type)
+	assert.True(t, createdAt.Equal(operations[0].CreatedAt))
+	assert.Equal(t, resources, operations[0].Resources)
+	assert.Equal(t, `{"name":"c1"}`, operations[0].ResumeData)
+
+	require.NoError(t, tx.OperationUnpersist("abcd"))
+
+	operations, err = tx.OperationsInterrupted()
+	require.NoError(t, err)
+	assert.Len(t, operations, 0)
+}
@@ -55,6 +55,21 @@ const (
 	OperationInstanceTypesUpdate
 	OperationBackupsExpire
 	OperationSnapshotsExpire
+	OperationInstanceReset
+	OperationClusterMemberEvacuate
+	OperationClusterMemberRestore
+	OperationClusterInstancesRebalance
+	OperationClusterJoinToken
+	OperationClusterUpgrade
+	OperationClusterMemberMaintenance
+	OperationInstancesStateUpdate
+	OperationDatabaseBackup
+	OperationInstanceProjectMove
+	OperationImageBuild
+	OperationImagePush
+	OperationStorageTrashRestore
+	OperationStorageTrashPurge
+	OperationStorageTrashExpire
 )
 
 // Description return a human-readable description of the operation type.
@@ -150,6 +165,36 @@ func (t OperationType) Description() string {
 		return "Cleaning up expired backups"
 	case OperationSnapshotsExpire:
 		return "Cleaning up expired snapshots"
+	case OperationInstanceReset:
+		return "Resetting instance"
+	case OperationClusterMemberEvacuate:
+		return "Evacuating cluster member"
+	case OperationClusterMemberRestore:
+		return "Restoring cluster member"
+	case OperationClusterInstancesRebalance:
+		return "Rebalancing instances across the cluster"
+	case OperationClusterJoinToken:
+		return "Requesting to join the cluster"
+	case OperationClusterUpgrade:
+		return "Upgrading the cluster"
+	case OperationClusterMemberMaintenance:
+		return "Putting cluster member in maintenance mode"
+	case OperationInstancesStateUpdate:
+		return "Changing the state of instances"
+	case OperationDatabaseBackup:
+		return "Backing up database"
+	case OperationInstanceProjectMove:
+		return "Moving instance to another project"
+	case OperationImageBuild:
+		return "Building image"
+	case OperationImagePush:
+		return "Pushing image"
+	case OperationStorageTrashRestore:
+		return "Restoring from the storage trash"
+	case OperationStorageTrashPurge:
+		return "Purging from the storage trash"
+	case OperationStorageTrashExpire:
+		return "Cleaning up the expired storage trash"
 	default:
 		return "Executing operation"
 	}
@@ -178,6 +223,8 @@ func (t OperationType) Permission() string {
 		return "operate-containers"
 	case OperationContainerRestart:
 		return "operate-containers"
+	case OperationInstancesStateUpdate:
+		return "operate-containers"
 	case OperationCommandExec:
 		return "operate-containers"
 	case OperationSnapshotCreate:
@@ -205,6 +252,10 @@ func (t OperationType) Permission() string {
 		return "manage-containers"
 	case OperationSnapshotRestore:
 		return "manage-containers"
+	case OperationInstanceReset:
+		return "manage-containers"
+	case OperationInstanceProjectMove:
+		return "manage-containers"
 
 	case OperationImageDownload:
 		return "manage-images"
@@ -214,6 +265,10 @@ func (t OperationType) Permission() string {
 		return "manage-images"
 	case OperationImageRefresh:
 		return "manage-images"
+	case OperationImageBuild:
+		return "manage-images"
+	case OperationImagePush:
+		return "manage-images"
 	case OperationImagesUpdate:
 		return "manage-images"
 	case OperationImagesSynchronize:
@@ -222,3 +277,23 @@ func (t OperationType) Permission() string {
 
 	return ""
 }
+
+// PersistentOperationTypes lists the types of the operations which survive a daemon restart, being
+// resumed or rolled back on the next start instead of just forgotten.
+var PersistentOperationTypes = []OperationType{
+	OperationBackupCreate,
+	OperationContainerCreate,
+	OperationImageDownload,
+	OperationVolumeCopy,
+}
+
+// Persistent returns whether operations of this type survive a daemon restart.
+func (t OperationType) Persistent() bool {
+	for _, persistent := range PersistentOperationTypes {
+		if t == persistent {
+			return true
+		}
+	}
+
+	return false
+}
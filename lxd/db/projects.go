@@ -169,6 +169,25 @@ DELETE FROM projects_config WHERE projects_config.project_id = ?
 	return nil
 }
 
+// ProjectRenameReferences updates the references to a project by name after it got renamed, which
+// are the restrictions of API tokens and certificates and the ownership of networks.
+func (c *ClusterTx) ProjectRenameReferences(name string, to string) error {
+	stmts := []string{
+		"UPDATE auth_tokens_projects SET project=? WHERE project=?",
+		"UPDATE certificates_projects SET project=? WHERE project=?",
+		"UPDATE networks_config SET value=? WHERE key='volatile.project' AND value=?",
+	}
+
+	for _, stmt := range stmts {
+		_, err := c.tx.Exec(stmt, to, name)
+		if err != nil {
+			return errors.Wrap(err, "Update project references")
+		}
+	}
+
+	return nil
+}
+
 // ProjectLaunchWithoutImages updates the images_profiles table when a Project is created with features.images=false.
 func (c *ClusterTx) ProjectLaunchWithoutImages(project string) error {
 	defaultProfileID, err := c.ProfileID(project, "default")
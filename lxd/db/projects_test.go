@@ -4,6 +4,7 @@ package db_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/stretchr/testify/assert"
@@ -20,3 +21,30 @@ func TestProjectsList(t *testing.T) {
 	assert.Len(t, project.UsedBy, 1)
 	assert.Equal(t, "/1.0/profiles/default?project=default", project.UsedBy[0])
 }
+
+func TestProjectUsage(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	hour := time.Date(2020, 3, 2, 11, 0, 0, 0, time.UTC)
+
+	// Usage added during the same hour accumulates.
+	for _, minute := range []int{5, 10} {
+		err := tx.ProjectUsageAdd("default", hour.Add(time.Duration(minute)*time.Minute), db.ProjectUsage{InstanceSeconds: 300, NetworkSent: 10})
+		require.NoError(t, err)
+	}
+
+	err := tx.ProjectUsageAdd("default", hour.Add(time.Hour), db.ProjectUsage{InstanceSeconds: 60})
+	require.NoError(t, err)
+
+	usage, err := tx.ProjectUsageGet("default", hour, hour.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, db.ProjectUsage{InstanceSeconds: 600, NetworkSent: 20}, usage)
+
+	err = tx.ProjectUsagePrune(hour.Add(time.Hour))
+	require.NoError(t, err)
+
+	usage, err = tx.ProjectUsageGet("default", hour, hour.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(60), usage.InstanceSeconds)
+}
@@ -0,0 +1,92 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProjectUsage holds the resources consumed by a project. CPU and memory are the ones allocated
+// to its running instances.
+type ProjectUsage struct {
+	InstanceSeconds    float64
+	CPUSeconds         float64
+	MemoryByteSeconds  float64
+	StorageByteSeconds float64
+	NetworkReceived    int64
+	NetworkSent        int64
+}
+
+// ProjectUsageAdd adds to the resources consumed by a project during the hour starting at the
+// given time.
+func (c *ClusterTx) ProjectUsageAdd(project string, hour time.Time, usage ProjectUsage) error {
+	id, err := c.ProjectID(project)
+	if err != nil {
+		return err
+	}
+
+	hour = hour.UTC().Truncate(time.Hour)
+
+	stmt := `
+UPDATE projects_usage
+   SET instance_seconds = instance_seconds + ?,
+       cpu_seconds = cpu_seconds + ?,
+       memory_byte_seconds = memory_byte_seconds + ?,
+       storage_byte_seconds = storage_byte_seconds + ?,
+       network_received = network_received + ?,
+       network_sent = network_sent + ?
+ WHERE project_id = ? AND hour = ?
+`
+	result, err := c.tx.Exec(stmt, usage.InstanceSeconds, usage.CPUSeconds, usage.MemoryByteSeconds, usage.StorageByteSeconds, usage.NetworkReceived, usage.NetworkSent, id, hour)
+	if err != nil {
+		return errors.Wrap(err, "Update project usage")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	stmt = `
+INSERT INTO projects_usage (project_id, hour, instance_seconds, cpu_seconds, memory_byte_seconds, storage_byte_seconds, network_received, network_sent)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+	_, err = c.tx.Exec(stmt, id, hour, usage.InstanceSeconds, usage.CPUSeconds, usage.MemoryByteSeconds, usage.StorageByteSeconds, usage.NetworkReceived, usage.NetworkSent)
+	if err != nil {
+		return errors.Wrap(err, "Add project usage")
+	}
+
+	return nil
+}
+
+// ProjectUsageGet returns the resources consumed by a project during the hours starting between
+// the given times, the end being excluded.
+func (c *ClusterTx) ProjectUsageGet(project string, start time.Time, end time.Time) (ProjectUsage, error) {
+	usage := ProjectUsage{}
+
+	stmt := `
+SELECT coalesce(sum(instance_seconds), 0), coalesce(sum(cpu_seconds), 0), coalesce(sum(memory_byte_seconds), 0),
+       coalesce(sum(storage_byte_seconds), 0), coalesce(sum(network_received), 0), coalesce(sum(network_sent), 0)
+  FROM projects_usage
+  JOIN projects ON projects.id = projects_usage.project_id
+ WHERE projects.name = ? AND projects_usage.hour >= ? AND projects_usage.hour < ?
+`
+	err := c.tx.QueryRow(stmt, project, start.UTC(), end.UTC()).Scan(&usage.InstanceSeconds, &usage.CPUSeconds, &usage.MemoryByteSeconds, &usage.StorageByteSeconds, &usage.NetworkReceived, &usage.NetworkSent)
+	if err != nil {
+		return usage, errors.Wrap(err, "Fetch project usage")
+	}
+
+	return usage, nil
+}
+
+// ProjectUsagePrune deletes the usage recorded for the hours starting before the given time.
+func (c *ClusterTx) ProjectUsagePrune(before time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM projects_usage WHERE hour < ?", before.UTC())
+	return err
+}
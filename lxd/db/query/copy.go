@@ -0,0 +1,81 @@
+package query
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaTables returns the names of the tables created by a full schema SQL text generated with
+// schema.Schema.Dump(), sorted by name.
+func SchemaTables(schema string) []string {
+	tables := []string{}
+	for table := range dumpParseSchema(schema) {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+// CopyTables copies all rows of the given tables from a database to another one with the same
+// schema, the values being passed as is instead of being rendered as SQL text.
+func CopyTables(src *sql.Tx, dst *sql.Tx, tables []string) error {
+	for _, table := range tables {
+		err := copyTable(src, dst, table)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to copy table %s", table)
+		}
+	}
+
+	return nil
+}
+
+// Copy all rows of a single table.
+func copyTable(src *sql.Tx, dst *sql.Tx, table string) error {
+	rows, err := src.Query(fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", table))
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch rows")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get columns")
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = fmt.Sprintf("%q", column)
+	}
+
+	stmt, err := dst.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(quoted, ", "), Params(len(columns))))
+	if err != nil {
+		return errors.Wrap(err, "Failed to prepare insert")
+	}
+	defer stmt.Close()
+
+	for i := 0; rows.Next(); i++ {
+		values := make([]interface{}, len(columns))
+		row := make([]interface{}, len(columns))
+		for j := range values {
+			row[j] = &values[j]
+		}
+
+		err := rows.Scan(row...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to scan row %d", i)
+		}
+
+		_, err = stmt.Exec(values...)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to insert row %d", i)
+		}
+	}
+
+	return rows.Err()
+}
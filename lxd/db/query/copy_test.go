@@ -0,0 +1,43 @@
+package query_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+func TestSchemaTables(t *testing.T) {
+	assert.Equal(t, []string{"config", "patches", "raft_nodes"}, query.SchemaTables(schemas["local"]))
+}
+
+func TestCopyTables(t *testing.T) {
+	src := newTxForDump(t, "local")
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	_, err = db.Exec(schemas["local"])
+	require.NoError(t, err)
+
+	dst, err := db.Begin()
+	require.NoError(t, err)
+
+	// Values with quotes are copied as is.
+	_, err = src.Exec("INSERT INTO config (key, value) VALUES ('core.trust_password', 'it''s')")
+	require.NoError(t, err)
+
+	err = query.CopyTables(src, dst, query.SchemaTables(schemas["local"]))
+	require.NoError(t, err)
+
+	names, err := query.SelectStrings(dst, "SELECT name FROM patches ORDER BY id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invalid_profile_names", "leftover_profile_config"}, names)
+
+	values, err := query.SelectStrings(dst, "SELECT value FROM config WHERE key = 'core.trust_password'")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"it's"}, values)
+}
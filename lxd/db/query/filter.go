@@ -0,0 +1,426 @@
+package query
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidFilter is returned when a filter or sort refers to a field which the collection
+// can't be filtered or sorted on.
+var ErrInvalidFilter = fmt.Errorf("Invalid filter")
+
+// Filter holds the filter, sort and pagination parameters of a request on a collection.
+//
+// A filter combines comparisons like "name eq c1" with "and" and "or", "and" binding tighter.
+// The comparison operators are eq, ne, lt, le, gt, ge and match, the latter taking a shell
+// pattern. Values containing spaces can be single or double quoted.
+//
+// A sort is a comma separated list of fields, each prefixed with "-" for descending order.
+//
+// The same filter can either be turned into SQL, given the columns matching each field, or be
+// evaluated against the field values of objects which don't live in the database. All methods
+// can be called on a nil Filter, which matches everything.
+type Filter struct {
+	// Comparisons, as a disjunction of conjunctions.
+	clauses [][]Comparison
+
+	sorts []sortField
+
+	// Maximum number of objects to return, 0 for no limit.
+	Limit int
+
+	// Number of objects to skip.
+	Offset int
+}
+
+// Comparison is a single "<field> <operator> <value>" term of a filter.
+type Comparison struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+type sortField struct {
+	field      string
+	descending bool
+}
+
+var filterOperators = map[string]string{
+	"eq":    "=",
+	"ne":    "!=",
+	"lt":    "<",
+	"le":    "<=",
+	"gt":    ">",
+	"ge":    ">=",
+	"match": "GLOB",
+}
+
+// ParseFilter parses the filter, sort, limit and offset parameters of a request. It returns nil
+// if none of them is set.
+func ParseFilter(filter string, sortStr string, limit string, offset string) (*Filter, error) {
+	if filter == "" && sortStr == "" && limit == "" && offset == "" {
+		return nil, nil
+	}
+
+	f := &Filter{}
+
+	if filter != "" {
+		clauses, err := parseFilterClauses(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		f.clauses = clauses
+	}
+
+	if sortStr != "" {
+		for _, field := range strings.Split(sortStr, ",") {
+			field = strings.TrimSpace(field)
+
+			descending := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+
+			if !isFilterField(field) {
+				return nil, fmt.Errorf("Invalid sort field %q", field)
+			}
+
+			f.sorts = append(f.sorts, sortField{field: field, descending: descending})
+		}
+	}
+
+	var err error
+
+	f.Limit, err = parseFilterCount("limit", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Offset, err = parseFilterCount("offset", offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func parseFilterCount(name string, value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return -1, fmt.Errorf("Invalid %s %q", name, value)
+	}
+
+	return count, nil
+}
+
+func parseFilterClauses(filter string) ([][]Comparison, error) {
+	tokens, err := splitFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses := [][]Comparison{{}}
+	for i := 0; i < len(tokens); {
+		if len(tokens)-i < 3 {
+			return nil, fmt.Errorf("Incomplete filter comparison %q", strings.Join(tokens[i:], " "))
+		}
+
+		comparison := Comparison{
+			Field:    tokens[i],
+			Operator: strings.ToLower(tokens[i+1]),
+			Value:    tokens[i+2],
+		}
+
+		if !isFilterField(comparison.Field) {
+			return nil, fmt.Errorf("Invalid filter field %q", comparison.Field)
+		}
+
+		_, ok := filterOperators[comparison.Operator]
+		if !ok {
+			return nil, fmt.Errorf("Invalid filter operator %q", comparison.Operator)
+		}
+
+		last := len(clauses) - 1
+		clauses[last] = append(clauses[last], comparison)
+		i += 3
+
+		if i == len(tokens) {
+			break
+		}
+
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			clauses = append(clauses, []Comparison{})
+		default:
+			return nil, fmt.Errorf("Expected \"and\" or \"or\" in filter, got %q", tokens[i])
+		}
+
+		i++
+		if i == len(tokens) {
+			return nil, fmt.Errorf("Filter can't end with %q", tokens[i-1])
+		}
+	}
+
+	return clauses, nil
+}
+
+// splitFilter splits a filter on spaces, keeping quoted values together.
+func splitFilter(filter string) ([]string, error) {
+	tokens := []string{}
+
+	var token strings.Builder
+	var quote rune
+	inToken := false
+
+	for _, c := range filter {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			token.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inToken = true
+		case unicode.IsSpace(c):
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("Unterminated quote in filter")
+	}
+
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens, nil
+}
+
+func isFilterField(field string) bool {
+	if field == "" {
+		return false
+	}
+
+	for _, c := range field {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Where returns the SQL expression and arguments matching the filter, given the column
+// expression of each field which can be filtered on. It returns an empty expression if there's
+// nothing to filter.
+func (f *Filter) Where(columns map[string]string) (string, []interface{}, error) {
+	if f == nil || len(f.clauses) == 0 {
+		return "", nil, nil
+	}
+
+	args := []interface{}{}
+	disjunction := []string{}
+	for _, clause := range f.clauses {
+		conjunction := []string{}
+		for _, comparison := range clause {
+			column, ok := columns[comparison.Field]
+			if !ok {
+				return "", nil, errors.Wrapf(ErrInvalidFilter, "Can't filter on field %q", comparison.Field)
+			}
+
+			conjunction = append(conjunction, fmt.Sprintf("%s %s ?", column, filterOperators[comparison.Operator]))
+			args = append(args, comparison.Value)
+		}
+
+		disjunction = append(disjunction, "("+strings.Join(conjunction, " AND ")+")")
+	}
+
+	return "(" + strings.Join(disjunction, " OR ") + ")", args, nil
+}
+
+// OrderBy returns the SQL ORDER BY clause matching the sort, given the column expression of each
+// field which can be sorted on. The default clause is used when there's no sort, and to break ties
+// otherwise so that pages don't overlap.
+func (f *Filter) OrderBy(columns map[string]string, defaultOrder string) (string, error) {
+	if f == nil || len(f.sorts) == 0 {
+		return defaultOrder, nil
+	}
+
+	order := []string{}
+	for _, field := range f.sorts {
+		column, ok := columns[field.field]
+		if !ok {
+			return "", errors.Wrapf(ErrInvalidFilter, "Can't sort on field %q", field.field)
+		}
+
+		if field.descending {
+			column += " DESC"
+		}
+
+		order = append(order, column)
+	}
+
+	if defaultOrder != "" {
+		order = append(order, strings.TrimPrefix(defaultOrder, "ORDER BY "))
+	}
+
+	return "ORDER BY " + strings.Join(order, ", "), nil
+}
+
+// LimitOffset returns the SQL LIMIT clause matching the pagination, if any.
+func (f *Filter) LimitOffset() string {
+	if f == nil || (f.Limit == 0 && f.Offset == 0) {
+		return ""
+	}
+
+	limit := f.Limit
+	if limit == 0 {
+		limit = -1
+	}
+
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, f.Offset)
+}
+
+// Match returns whether an object with the given field values matches the filter.
+func (f *Filter) Match(values map[string]string) (bool, error) {
+	if f == nil || len(f.clauses) == 0 {
+		return true, nil
+	}
+
+	for _, clause := range f.clauses {
+		match := true
+		for _, comparison := range clause {
+			value, ok := values[comparison.Field]
+			if !ok {
+				return false, errors.Wrapf(ErrInvalidFilter, "Can't filter on field %q", comparison.Field)
+			}
+
+			if !compareFilterValue(value, comparison.Operator, comparison.Value) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Sort sorts objects, given their field values, and applies the pagination. The indexes of the
+// objects to return are given in order.
+func (f *Filter) Sort(values []map[string]string) ([]int, error) {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	if f == nil {
+		return indexes, nil
+	}
+
+	for _, field := range f.sorts {
+		for _, v := range values {
+			_, ok := v[field.field]
+			if !ok {
+				return nil, errors.Wrapf(ErrInvalidFilter, "Can't sort on field %q", field.field)
+			}
+		}
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a := values[indexes[i]]
+		b := values[indexes[j]]
+
+		for _, field := range f.sorts {
+			cmp := compareFilterValues(a[field.field], b[field.field])
+			if cmp == 0 {
+				continue
+			}
+
+			if field.descending {
+				return cmp > 0
+			}
+
+			return cmp < 0
+		}
+
+		return false
+	})
+
+	if f.Offset >= len(indexes) {
+		return []int{}, nil
+	}
+
+	indexes = indexes[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(indexes) {
+		indexes = indexes[:f.Limit]
+	}
+
+	return indexes, nil
+}
+
+// compareFilterValues compares two values numerically if they're both numbers, and as strings
+// otherwise.
+func compareFilterValues(a string, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareFilterValue(value string, operator string, expected string) bool {
+	switch operator {
+	case "eq":
+		return value == expected
+	case "ne":
+		return value != expected
+	case "match":
+		match, _ := path.Match(expected, value)
+		return match
+	}
+
+	cmp := compareFilterValues(value, expected)
+	switch operator {
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	}
+
+	return false
+}
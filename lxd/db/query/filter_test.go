@@ -0,0 +1,100 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+func TestParseFilter_Empty(t *testing.T) {
+	filter, err := query.ParseFilter("", "", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+}
+
+func TestParseFilter_Error(t *testing.T) {
+	cases := map[string]string{
+		"name":                    `Incomplete filter comparison "name"`,
+		"name is c1":              `Invalid filter operator "is"`,
+		"name eq c1 and":          `Filter can't end with "and"`,
+		"name eq c1 xor type eq":  `Expected "and" or "or" in filter, got "xor"`,
+		"name eq 'c1":             "Unterminated quote in filter",
+		"name; eq c1":             `Invalid filter field "name;"`,
+		"name eq c1 or type eq c": "",
+	}
+
+	for filter, message := range cases {
+		_, err := query.ParseFilter(filter, "", "", "")
+		if message == "" {
+			assert.NoError(t, err)
+			continue
+		}
+
+		assert.EqualError(t, err, message, filter)
+	}
+
+	_, err := query.ParseFilter("", "", "-1", "")
+	assert.EqualError(t, err, `Invalid limit "-1"`)
+}
+
+func TestFilter_SQL(t *testing.T) {
+	filter, err := query.ParseFilter(`name match "web-*" and type eq container or description eq 'a b'`, "-created_at,name", "10", "20")
+	require.NoError(t, err)
+
+	columns := map[string]string{
+		"name":        "instances.name",
+		"type":        "instances.type",
+		"description": "instances.description",
+		"created_at":  "instances.creation_date",
+	}
+
+	where, args, err := filter.Where(columns)
+	require.NoError(t, err)
+	assert.Equal(t, "((instances.name GLOB ? AND instances.type = ?) OR (instances.description = ?))", where)
+	assert.Equal(t, []interface{}{"web-*", "container", "a b"}, args)
+
+	order, err := filter.OrderBy(columns, "ORDER BY instances.id")
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER BY instances.creation_date DESC, instances.name, instances.id", order)
+
+	assert.Equal(t, "LIMIT 10 OFFSET 20", filter.LimitOffset())
+
+	_, _, err = filter.Where(map[string]string{"name": "name"})
+	assert.EqualError(t, err, `Can't filter on field "type": Invalid filter`)
+}
+
+func TestFilter_Match(t *testing.T) {
+	filter, err := query.ParseFilter("status eq Running and may_cancel eq true or class eq token", "", "", "")
+	require.NoError(t, err)
+
+	match, err := filter.Match(map[string]string{"status": "Running", "may_cancel": "true", "class": "task"})
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = filter.Match(map[string]string{"status": "Running", "may_cancel": "false", "class": "task"})
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = filter.Match(map[string]string{"status": "Success", "may_cancel": "false", "class": "token"})
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestFilter_Sort(t *testing.T) {
+	filter, err := query.ParseFilter("", "-size,name", "2", "1")
+	require.NoError(t, err)
+
+	values := []map[string]string{
+		{"name": "a", "size": "9"},
+		{"name": "b", "size": "10"},
+		{"name": "c", "size": "9"},
+		{"name": "d", "size": "1"},
+	}
+
+	indexes, err := filter.Sort(values)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, indexes)
+}
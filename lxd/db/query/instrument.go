@@ -0,0 +1,274 @@
+package query
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// StatsBuckets are the upper bounds, in seconds, of the buckets query durations are counted in.
+var StatsBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Stats holds the number and duration of the statements of a kind (exec or query) run against
+// an instrumented database.
+type Stats struct {
+	Count  uint64
+	Errors uint64
+
+	// Total duration of the statements, in seconds.
+	Sum float64
+
+	// Number of statements which took up to each of StatsBuckets, the last one counting the
+	// slower ones.
+	Buckets []uint64
+}
+
+var stats = map[string]*Stats{}
+var statsLock sync.Mutex
+
+// Statements taking longer than this are logged, unless zero.
+var slowThreshold int64
+
+// SetSlowThreshold sets how long a statement can take before being logged, zero disabling the
+// logging.
+func SetSlowThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowThreshold, int64(threshold))
+}
+
+// GetStats returns a copy of the statistics of the statements run so far, by kind.
+func GetStats() map[string]Stats {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+
+	result := make(map[string]Stats, len(stats))
+	for kind, s := range stats {
+		copied := *s
+		copied.Buckets = append([]uint64{}, s.Buckets...)
+		result[kind] = copied
+	}
+
+	return result
+}
+
+// observe accounts for a statement, logging it if slow.
+func observe(kind string, query string, duration time.Duration, err error) {
+	threshold := time.Duration(atomic.LoadInt64(&slowThreshold))
+	if threshold > 0 && duration > threshold {
+		logger.Warn("Slow database query", log.Ctx{"kind": kind, "query": strings.Join(strings.Fields(query), " "), "duration": duration, "err": err})
+	}
+
+	statsLock.Lock()
+	defer statsLock.Unlock()
+
+	s, ok := stats[kind]
+	if !ok {
+		s = &Stats{Buckets: make([]uint64, len(StatsBuckets)+1)}
+		stats[kind] = s
+	}
+
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+
+	seconds := duration.Seconds()
+	s.Sum += seconds
+
+	i := 0
+	for i < len(StatsBuckets) && seconds > StatsBuckets[i] {
+		i++
+	}
+
+	s.Buckets[i]++
+}
+
+// Instrument wraps a database driver, keeping statistics about the statements run through it
+// and logging the slow ones.
+func Instrument(d driver.Driver) driver.Driver {
+	return &instrumentedDriver{Driver: d}
+}
+
+type instrumentedDriver struct {
+	driver.Driver
+}
+
+// Open returns an instrumented connection.
+func (d *instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedConn{Conn: conn}, nil
+}
+
+// instrumentedConn times the statements run through a connection, passing through the optional
+// interfaces of the wrapped one.
+type instrumentedConn struct {
+	driver.Conn
+}
+
+// Prepare returns an instrumented statement.
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns an instrumented statement.
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+
+	preparer, ok := c.Conn.(driver.ConnPrepareContext)
+	if ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+// BeginTx starts a transaction.
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	beginner, ok := c.Conn.(driver.ConnBeginTx)
+	if ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+// ExecContext runs a statement without preparing it, if the wrapped connection supports it.
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		observe("exec", query, time.Since(start), err)
+	}
+
+	return result, err
+}
+
+// QueryContext runs a query without preparing it, if the wrapped connection supports it.
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		observe("query", query, time.Since(start), err)
+	}
+
+	return rows, err
+}
+
+// CheckNamedValue lets the wrapped connection convert the arguments, if it supports it.
+func (c *instrumentedConn) CheckNamedValue(value *driver.NamedValue) error {
+	checker, ok := c.Conn.(driver.NamedValueChecker)
+	if !ok {
+		return driver.ErrSkip
+	}
+
+	return checker.CheckNamedValue(value)
+}
+
+// ResetSession resets the wrapped connection, if it supports it.
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	resetter, ok := c.Conn.(driver.SessionResetter)
+	if !ok {
+		return nil
+	}
+
+	return resetter.ResetSession(ctx)
+}
+
+// IsValid returns whether the wrapped connection can be reused, if it tells.
+func (c *instrumentedConn) IsValid() bool {
+	validator, ok := c.Conn.(driver.Validator)
+	if !ok {
+		return true
+	}
+
+	return validator.IsValid()
+}
+
+// instrumentedStmt times the runs of a prepared statement.
+type instrumentedStmt struct {
+	driver.Stmt
+	conn  *instrumentedConn
+	query string
+}
+
+// ExecContext runs the statement.
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	var err error
+
+	start := time.Now()
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValuesToValues(args))
+	}
+
+	observe("exec", s.query, time.Since(start), err)
+
+	return result, err
+}
+
+// QueryContext runs the statement.
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	var err error
+
+	start := time.Now()
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValuesToValues(args))
+	}
+
+	observe("query", s.query, time.Since(start), err)
+
+	return rows, err
+}
+
+// CheckNamedValue lets the wrapped statement or connection convert the arguments, if they
+// support it.
+func (s *instrumentedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	checker, ok := s.Stmt.(driver.NamedValueChecker)
+	if !ok {
+		return s.conn.CheckNamedValue(value)
+	}
+
+	return checker.CheckNamedValue(value)
+}
+
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	return values
+}
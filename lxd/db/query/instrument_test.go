@@ -0,0 +1,46 @@
+package query_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// The statements run through an instrumented driver are counted by kind.
+func TestInstrument(t *testing.T) {
+	sql.Register("sqlite3_instrumented", query.Instrument(&sqlite3.SQLiteDriver{}))
+
+	db, err := sql.Open("sqlite3_instrumented", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := query.GetStats()
+
+	_, err = db.Exec("CREATE TABLE test (id INTEGER)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO test VALUES (?)", 1)
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO missing VALUES (1)")
+	assert.Error(t, err)
+
+	err = query.Transaction(db, func(tx *sql.Tx) error {
+		ids, err := query.SelectIntegers(tx, "SELECT id FROM test")
+		assert.Equal(t, []int{1}, ids)
+		return err
+	})
+	require.NoError(t, err)
+
+	after := query.GetStats()
+
+	assert.Equal(t, before["exec"].Count+3, after["exec"].Count)
+	assert.Equal(t, before["exec"].Errors+1, after["exec"].Errors)
+	assert.Equal(t, before["query"].Count+1, after["query"].Count)
+	assert.Len(t, after["query"].Buckets, len(query.StatsBuckets)+1)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/shared"
 )
 
@@ -125,3 +126,48 @@ func (c *Cluster) InstanceSnapshotID(project, instance, name string) (int, error
 	})
 	return int(id), err
 }
+
+// InstanceSnapshotVolume is a custom volume snapshot taken together with an instance snapshot.
+type InstanceSnapshotVolume struct {
+	ID   int64
+	Pool string
+	Name string
+}
+
+// InstanceSnapshotVolumeAdd records a custom volume snapshot as taken together with the instance
+// snapshot with the given ID.
+func (c *ClusterTx) InstanceSnapshotVolumeAdd(snapshotID int, volumeID int64) error {
+	_, err := c.tx.Exec("INSERT INTO instances_snapshots_volumes (instance_snapshot_id, storage_volume_id) VALUES (?, ?)", snapshotID, volumeID)
+	return err
+}
+
+// InstanceSnapshotVolumes returns the custom volume snapshots taken together with the instance
+// snapshot with the given ID.
+func (c *ClusterTx) InstanceSnapshotVolumes(snapshotID int) ([]InstanceSnapshotVolume, error) {
+	stmt := `
+SELECT storage_volumes.id, storage_pools.name, storage_volumes.name
+  FROM instances_snapshots_volumes
+  JOIN storage_volumes ON storage_volumes.id = instances_snapshots_volumes.storage_volume_id
+  JOIN storage_pools ON storage_pools.id = storage_volumes.storage_pool_id
+ WHERE instances_snapshots_volumes.instance_snapshot_id = ?
+ ORDER BY storage_pools.name, storage_volumes.name
+`
+	volumes := []InstanceSnapshotVolume{}
+	dest := func(i int) []interface{} {
+		volumes = append(volumes, InstanceSnapshotVolume{})
+		return []interface{}{&volumes[i].ID, &volumes[i].Pool, &volumes[i].Name}
+	}
+
+	sqlStmt, err := c.tx.Prepare(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer sqlStmt.Close()
+
+	err = query.SelectObjects(sqlStmt, dest, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes, nil
+}
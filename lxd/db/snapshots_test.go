@@ -155,6 +155,42 @@ func TestInstanceSnapshotList_SameNameInDifferentProjects(t *testing.T) {
 	assert.Equal(t, "s1", snapshot.Name)
 }
 
+// Custom volume snapshots can be recorded as taken together with an instance snapshot.
+func TestInstanceSnapshotVolumes(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	addContainer(t, tx, 1, "c1")
+	addInstanceSnapshot(t, tx, 1, "snap0")
+
+	poolID := addPool(t, tx, "pool1")
+	addVolume(t, tx, poolID, 1, "vol1/snap0")
+
+	snapshotID := int(getInstanceSnapshotID(t, tx, "c1", "snap0"))
+
+	var volumeID int64
+	err := tx.Tx().QueryRow("SELECT id FROM storage_volumes WHERE name=?", "vol1/snap0").Scan(&volumeID)
+	require.NoError(t, err)
+
+	volumes, err := tx.InstanceSnapshotVolumes(snapshotID)
+	require.NoError(t, err)
+	assert.Len(t, volumes, 0)
+
+	require.NoError(t, tx.InstanceSnapshotVolumeAdd(snapshotID, volumeID))
+
+	volumes, err = tx.InstanceSnapshotVolumes(snapshotID)
+	require.NoError(t, err)
+	require.Len(t, volumes, 1)
+	assert.Equal(t, "pool1", volumes[0].Pool)
+	assert.Equal(t, "vol1/snap0", volumes[0].Name)
+
+	require.NoError(t, tx.InstanceSnapshotDelete("default", "c1", "snap0"))
+
+	volumes, err = tx.InstanceSnapshotVolumes(snapshotID)
+	require.NoError(t, err)
+	assert.Len(t, volumes, 0)
+}
+
 func addInstanceSnapshot(t *testing.T, tx *db.ClusterTx, instanceID int64, name string) {
 	stmt := `
 INSERT INTO instances_snapshots(instance_id, name, creation_date) VALUES (?, ?, ?)
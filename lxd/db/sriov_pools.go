@@ -0,0 +1,131 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// SRIOVPools returns the names of all the SR-IOV pools.
+func (c *Cluster) SRIOVPools() ([]string, error) {
+	var names []string
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		names, err = query.SelectStrings(tx.tx, "SELECT name FROM sriov_pools ORDER BY name")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// SRIOVPoolGet returns the SR-IOV pool with the given name.
+func (c *Cluster) SRIOVPoolGet(name string) (int64, *api.SRIOVPool, error) {
+	id := int64(-1)
+	pool := api.SRIOVPool{
+		Name: name,
+	}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		err := tx.tx.QueryRow("SELECT id, description FROM sriov_pools WHERE name=?", name).Scan(&id, &pool.Description)
+		if err != nil {
+			return err
+		}
+
+		pool.Config, err = query.SelectConfig(tx.tx, "sriov_pools_config", "sriov_pool_id=?", id)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	return id, &pool, nil
+}
+
+// SRIOVPoolCreate creates a new SR-IOV pool.
+func (c *Cluster) SRIOVPoolCreate(name string, description string, config map[string]string) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec("INSERT INTO sriov_pools (name, description) VALUES (?, ?)", name, description)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return sriovPoolConfigAdd(tx.tx, id, config)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// SRIOVPoolUpdate updates the SR-IOV pool with the given ID.
+func (c *Cluster) SRIOVPoolUpdate(id int64, description string, config map[string]string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE sriov_pools SET description=? WHERE id=?", description, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM sriov_pools_config WHERE sriov_pool_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		return sriovPoolConfigAdd(tx.tx, id, config)
+	})
+}
+
+// SRIOVPoolDelete deletes the SR-IOV pool with the given ID.
+func (c *Cluster) SRIOVPoolDelete(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "sriov_pools", id)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+func sriovPoolConfigAdd(tx *sql.Tx, poolID int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO sriov_pools_config (sriov_pool_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(poolID, k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -750,6 +750,106 @@ func (c *Cluster) storagePoolVolumesGet(project string, poolID, nodeID int64, vo
 	return result, nil
 }
 
+// storageVolumeFilterColumns maps the fields storage volumes can be filtered and sorted on to
+// their columns.
+var storageVolumeFilterColumns = map[string]string{
+	"name": "storage_volumes.name",
+	"type": fmt.Sprintf("CASE storage_volumes.type WHEN %d THEN '%s' WHEN %d THEN '%s' WHEN %d THEN '%s' ELSE '%s' END",
+		StoragePoolVolumeTypeContainer, StoragePoolVolumeTypeNameContainer,
+		StoragePoolVolumeTypeImage, StoragePoolVolumeTypeNameImage,
+		StoragePoolVolumeTypeCustom, StoragePoolVolumeTypeNameCustom,
+		StoragePoolVolumeTypeNameVM),
+	"description": "storage_volumes.description",
+	"location":    "nodes.name",
+	"snapshot":    "CASE WHEN storage_volumes.snapshot THEN 'true' ELSE 'false' END",
+}
+
+// StoragePoolVolumesGetFiltered returns the storage volumes of the given types attached to a
+// storage pool and matching the filter, in the requested order and page. Image volumes are
+// always linked to the default project, so only the ones of the given images are included.
+func (c *Cluster) StoragePoolVolumesGetFiltered(project string, poolID int64, volumeTypes []int, images []string, filter *query.Filter) ([]*api.StorageVolume, error) {
+	type volumeKey struct {
+		name       string
+		volumeType int
+		nodeID     int64
+	}
+
+	keys := []volumeKey{}
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		args := []interface{}{poolID, project, StoragePoolVolumeTypeCustom}
+		for _, volumeType := range volumeTypes {
+			args = append(args, volumeType)
+		}
+
+		filters := fmt.Sprintf("(projects.name = ? OR storage_volumes.type = ?) AND storage_volumes.type IN %s", query.Params(len(volumeTypes)))
+		if len(images) > 0 {
+			filters = fmt.Sprintf("(%s) OR (projects.name = 'default' AND storage_volumes.type = ? AND storage_volumes.name IN %s)", filters, query.Params(len(images)))
+			args = append(args, StoragePoolVolumeTypeImage)
+			for _, image := range images {
+				args = append(args, image)
+			}
+		}
+
+		where, whereArgs, err := filter.Where(storageVolumeFilterColumns)
+		if err != nil {
+			return err
+		}
+
+		if where != "" {
+			filters = fmt.Sprintf("(%s) AND %s", filters, where)
+			args = append(args, whereArgs...)
+		}
+
+		order, err := filter.OrderBy(storageVolumeFilterColumns, "ORDER BY storage_volumes.id")
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`
+SELECT storage_volumes.name, storage_volumes.type, storage_volumes.node_id
+  FROM storage_volumes
+  JOIN nodes ON nodes.id = storage_volumes.node_id
+  JOIN projects ON projects.id = storage_volumes.project_id
+ WHERE storage_volumes.storage_pool_id = ? AND (%s)
+ %s %s
+`, filters, order, filter.LimitOffset())
+
+		dest := func(i int) []interface{} {
+			keys = append(keys, volumeKey{})
+			return []interface{}{&keys[i].name, &keys[i].volumeType, &keys[i].nodeID}
+		}
+
+		stmtObject, err := tx.tx.Prepare(stmt)
+		if err != nil {
+			return err
+		}
+		defer stmtObject.Close()
+
+		return query.SelectObjects(stmtObject, dest, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []*api.StorageVolume{}
+	for _, key := range keys {
+		volumeProject := project
+		if key.volumeType == StoragePoolVolumeTypeImage {
+			volumeProject = "default"
+		}
+
+		_, volume, err := c.StoragePoolVolumeGetType(volumeProject, key.name, key.volumeType, poolID, key.nodeID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch volume type")
+		}
+
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, nil
+}
+
 // StoragePoolVolumesGetType get all storage volumes attached to a given
 // storage pool of a given volume type, on the given node.
 func (c *Cluster) StoragePoolVolumesGetType(project string, volumeType int, poolID, nodeID int64) ([]string, error) {
@@ -0,0 +1,143 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+)
+
+// StorageTrashEntry is an instance or custom volume which was deleted while its storage pool had
+// trash.expiry set, and is kept on the pool under its trash name until it expires.
+type StorageTrashEntry struct {
+	ID          int64
+	PoolID      int64
+	Pool        string
+	Node        string
+	Project     string
+	Name        string
+	Type        int
+	Description string
+	Config      map[string]string
+	DeletedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// StorageTrashCreate adds an entry for a volume of this node moved to the trash of a pool.
+func (c *ClusterTx) StorageTrashCreate(entry StorageTrashEntry) (int64, error) {
+	stmt := `
+INSERT INTO storage_trash (storage_pool_id, node_id, project, name, type, description, deleted_at, expires_at)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+	result, err := c.tx.Exec(stmt, entry.PoolID, c.nodeID, entry.Project, entry.Name, entry.Type, entry.Description, entry.DeletedAt.UTC(), entry.ExpiresAt.UTC())
+	if err != nil {
+		return -1, errors.Wrap(err, "Add storage trash entry")
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, err
+	}
+
+	for key, value := range entry.Config {
+		_, err = c.tx.Exec("INSERT INTO storage_trash_config (storage_trash_id, key, value) VALUES (?, ?, ?)", id, key, value)
+		if err != nil {
+			return -1, errors.Wrap(err, "Add storage trash entry config")
+		}
+	}
+
+	return id, nil
+}
+
+// StorageTrashGet returns the trash entry with the given ID.
+func (c *ClusterTx) StorageTrashGet(id int64) (*StorageTrashEntry, error) {
+	entries, err := c.storageTrashList("storage_trash.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoSuchObject
+	}
+
+	return &entries[0], nil
+}
+
+// StorageTrashList returns the trash entries of a pool.
+func (c *ClusterTx) StorageTrashList(poolID int64) ([]StorageTrashEntry, error) {
+	return c.storageTrashList("storage_trash.storage_pool_id = ?", poolID)
+}
+
+// StorageTrashExpired returns the trash entries of this node which expired before the given time.
+func (c *ClusterTx) StorageTrashExpired(before time.Time) ([]StorageTrashEntry, error) {
+	return c.storageTrashList("storage_trash.node_id = ? AND storage_trash.expires_at < ?", c.nodeID, before.UTC())
+}
+
+// StorageTrashDelete removes a trash entry.
+func (c *ClusterTx) StorageTrashDelete(id int64) error {
+	result, err := c.tx.Exec("DELETE FROM storage_trash WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+func (c *ClusterTx) storageTrashList(where string, args ...interface{}) ([]StorageTrashEntry, error) {
+	stmt := `
+SELECT storage_trash.id, storage_trash.storage_pool_id, storage_pools.name, nodes.name, storage_trash.project,
+       storage_trash.name, storage_trash.type, coalesce(storage_trash.description, ''), storage_trash.deleted_at,
+       storage_trash.expires_at
+  FROM storage_trash
+  JOIN storage_pools ON storage_pools.id = storage_trash.storage_pool_id
+  JOIN nodes ON nodes.id = storage_trash.node_id
+ WHERE ` + where + `
+ ORDER BY storage_trash.id
+`
+	rows, err := c.tx.Query(stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fetch storage trash entries")
+	}
+
+	entries := []StorageTrashEntry{}
+	err = func(rows *sql.Rows) error {
+		defer rows.Close()
+
+		for rows.Next() {
+			entry := StorageTrashEntry{}
+			err := rows.Scan(&entry.ID, &entry.PoolID, &entry.Pool, &entry.Node, &entry.Project, &entry.Name, &entry.Type, &entry.Description, &entry.DeletedAt, &entry.ExpiresAt)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return rows.Err()
+	}(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].Config, err = query.SelectConfig(c.tx, "storage_trash_config", "storage_trash_id=?", entries[i].ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "Fetch storage trash entry config")
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,63 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Trash entries can be listed, expire and be removed.
+func TestStorageTrash(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	poolID := addPool(t, tx, "pool1")
+	now := time.Now()
+
+	id1, err := tx.StorageTrashCreate(db.StorageTrashEntry{
+		PoolID:    poolID,
+		Project:   "default",
+		Name:      "c1",
+		Type:      db.StoragePoolVolumeTypeContainer,
+		Config:    map[string]string{"size": "10GB"},
+		DeletedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = tx.StorageTrashCreate(db.StorageTrashEntry{
+		PoolID:    poolID,
+		Project:   "default",
+		Name:      "vol1",
+		Type:      db.StoragePoolVolumeTypeCustom,
+		DeletedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	entry, err := tx.StorageTrashGet(id1)
+	require.NoError(t, err)
+	assert.Equal(t, "pool1", entry.Pool)
+	assert.Equal(t, "c1", entry.Name)
+	assert.Equal(t, map[string]string{"size": "10GB"}, entry.Config)
+
+	entries, err := tx.StorageTrashList(poolID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	expired, err := tx.StorageTrashExpired(now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, id1, expired[0].ID)
+
+	require.NoError(t, tx.StorageTrashDelete(id1))
+
+	_, err = tx.StorageTrashGet(id1)
+	assert.Equal(t, db.ErrNoSuchObject, err)
+	assert.Equal(t, db.ErrNoSuchObject, tx.StorageTrashDelete(id1))
+}
@@ -85,6 +85,94 @@ SELECT nodes.id, nodes.address
 	return addresses, nil
 }
 
+// ProjectStorageVolume holds the details of a storage volume used to account for the disk space
+// of its project.
+type ProjectStorageVolume struct {
+	ID       int64
+	Pool     string
+	Node     string
+	Name     string
+	Type     int
+	Snapshot bool
+	Config   map[string]string
+}
+
+// ProjectStorageVolumes returns all the storage volumes of a project, including snapshots,
+// across all pools and nodes.
+func (c *ClusterTx) ProjectStorageVolumes(project string) ([]ProjectStorageVolume, error) {
+	volumes := []ProjectStorageVolume{}
+	dest := func(i int) []interface{} {
+		volumes = append(volumes, ProjectStorageVolume{Config: map[string]string{}})
+		return []interface{}{&volumes[i].ID, &volumes[i].Pool, &volumes[i].Node, &volumes[i].Name, &volumes[i].Type, &volumes[i].Snapshot}
+	}
+
+	sql := `
+SELECT storage_volumes.id, storage_pools.name, nodes.name, storage_volumes.name, storage_volumes.type, storage_volumes.snapshot
+  FROM storage_volumes
+  JOIN storage_pools ON storage_pools.id = storage_volumes.storage_pool_id
+  JOIN nodes ON nodes.id = storage_volumes.node_id
+  JOIN projects ON projects.id = storage_volumes.project_id
+ WHERE projects.name = ?
+ ORDER BY storage_volumes.id
+`
+	stmt, err := c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, project)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int64]int, len(volumes))
+	for i, volume := range volumes {
+		index[volume.ID] = i
+	}
+
+	configs := []struct {
+		volumeID int64
+		key      string
+		value    string
+	}{}
+	dest = func(i int) []interface{} {
+		configs = append(configs, struct {
+			volumeID int64
+			key      string
+			value    string
+		}{})
+		return []interface{}{&configs[i].volumeID, &configs[i].key, &configs[i].value}
+	}
+
+	sql = `
+SELECT storage_volumes_config.storage_volume_id, storage_volumes_config.key, coalesce(storage_volumes_config.value, '')
+  FROM storage_volumes_config
+  JOIN storage_volumes ON storage_volumes.id = storage_volumes_config.storage_volume_id
+  JOIN projects ON projects.id = storage_volumes.project_id
+ WHERE projects.name = ?
+`
+	stmt, err = c.tx.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, project)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		i, ok := index[config.volumeID]
+		if ok {
+			volumes[i].Config[config.key] = config.value
+		}
+	}
+
+	return volumes, nil
+}
+
 // StorageVolumeNodeGet returns the name of the node a storage volume is on.
 func (c *Cluster) StorageVolumeNodeGet(volumeID int64) (string, error) {
 	name := ""
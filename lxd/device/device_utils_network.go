@@ -14,7 +14,9 @@ import (
 	"sync"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/dhcpv6"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -299,6 +301,130 @@ func NetworkAttachInterface(netName string, devName string) error {
 	return nil
 }
 
+// networkSetupBridgePortVLANs configures the untagged VLAN and the tagged VLANs allowed on a port
+// of either a native bridge (which must have VLAN filtering enabled) or an Open vSwitch bridge.
+func networkSetupBridgePortVLANs(netName string, devName string, vlan string, vlanTagged string) error {
+	if vlan == "" && vlanTagged == "" {
+		return nil
+	}
+
+	tagged := []string{}
+	if vlanTagged != "" {
+		for _, id := range strings.Split(vlanTagged, ",") {
+			tagged = append(tagged, strings.TrimSpace(id))
+		}
+	}
+
+	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", netName)) {
+		content, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/bridge/vlan_filtering", netName))
+		if err != nil || strings.TrimSpace(string(content)) != "1" {
+			return fmt.Errorf("VLAN filtering isn't enabled on bridge %q", netName)
+		}
+
+		if vlan != "" {
+			// Replace the default untagged VLAN the bridge gave to the port, if it has one.
+			content, err = ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/bridge/default_pvid", netName))
+			if err != nil {
+				return err
+			}
+
+			defaultPVID := strings.TrimSpace(string(content))
+			if defaultPVID != "0" && defaultPVID != vlan {
+				_, err = shared.RunCommand("bridge", "vlan", "del", "dev", devName, "vid", defaultPVID, "master")
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = shared.RunCommand("bridge", "vlan", "add", "dev", devName, "vid", vlan, "pvid", "untagged", "master")
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, id := range tagged {
+			_, err = shared.RunCommand("bridge", "vlan", "add", "dev", devName, "vid", id, "master")
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	args := []string{"set", "port", devName}
+	if vlan != "" {
+		args = append(args, fmt.Sprintf("tag=%s", vlan))
+	}
+
+	if len(tagged) > 0 {
+		args = append(args, fmt.Sprintf("trunks=%s", strings.Join(tagged, ",")))
+	}
+
+	if vlan != "" && len(tagged) > 0 {
+		args = append(args, "vlan_mode=native-untagged")
+	} else if vlan != "" {
+		args = append(args, "vlan_mode=access")
+	} else {
+		args = append(args, "vlan_mode=trunk")
+	}
+
+	_, err := shared.RunCommand("ovs-vsctl", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// networkBridgePortMulticastRouter maps the values of the multicast.router NIC property to those
+// of the kernel bridge port setting.
+var networkBridgePortMulticastRouter = map[string]string{
+	"":          "1",
+	"auto":      "1",
+	"disabled":  "0",
+	"permanent": "2",
+}
+
+// networkSetupBridgePortMulticast configures the multicast flooding and router settings of a port
+// of either a native bridge or an Open vSwitch bridge. Settings removed since oldConfig are reset.
+func networkSetupBridgePortMulticast(netName string, devName string, m deviceConfig.Device, oldConfig deviceConfig.Device) error {
+	if m["multicast.flood"] == "" && m["multicast.router"] == "" && oldConfig["multicast.flood"] == "" && oldConfig["multicast.router"] == "" {
+		return nil
+	}
+
+	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/bridge", netName)) {
+		flood := "1"
+		if m["multicast.flood"] != "" && !shared.IsTrue(m["multicast.flood"]) {
+			flood = "0"
+		}
+
+		err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/brport/multicast_flood", devName), []byte(flood), 0)
+		if err != nil {
+			return fmt.Errorf("Failed to configure multicast flooding on %s: %v", devName, err)
+		}
+
+		err = ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/brport/multicast_router", devName), []byte(networkBridgePortMulticastRouter[m["multicast.router"]]), 0)
+		if err != nil {
+			return fmt.Errorf("Failed to configure multicast router on %s: %v", devName, err)
+		}
+
+		return nil
+	}
+
+	if m["multicast.router"] != "" {
+		return fmt.Errorf("The multicast.router property isn't supported on Open vSwitch bridges")
+	}
+
+	if m["multicast.flood"] == "" {
+		_, err := shared.RunCommand("ovs-vsctl", "remove", "port", devName, "other_config", "mcast-snooping-flood")
+		return err
+	}
+
+	_, err := shared.RunCommand("ovs-vsctl", "set", "port", devName, fmt.Sprintf("other_config:mcast-snooping-flood=%v", shared.IsTrue(m["multicast.flood"])))
+	return err
+}
+
 // networkCreateVethPair creates and configures a veth pair. It will set the hwaddr and mtu settings
 // in the supplied config to the newly created peer interface. If mtu is not specified, but parent
 // is supplied in config, then the MTU of the new peer interface will inherit the parent MTU.
@@ -363,11 +489,22 @@ func networkCreateVethPair(hostName string, m deviceConfig.Device) (string, erro
 		}
 	}
 
+	// Set the transmit queue length on both ends.
+	if m["queue.tx.length"] != "" {
+		for _, devName := range []string{peerName, hostName} {
+			err = networkSetDevTxQueueLength(devName, m["queue.tx.length"])
+			if err != nil {
+				NetworkRemoveInterface(peerName)
+				return "", err
+			}
+		}
+	}
+
 	return peerName, nil
 }
 
 // networkCreateTap creates and configures a TAP device.
-func networkCreateTap(hostName string) error {
+func networkCreateTap(hostName string, m deviceConfig.Device) error {
 	_, err := shared.RunCommand("ip", "tuntap", "add", "name", hostName, "mode", "tap")
 	if err != nil {
 		return fmt.Errorf("Failed to create the tap interfaces %s: %v", hostName, err)
@@ -379,6 +516,62 @@ func networkCreateTap(hostName string) error {
 		return fmt.Errorf("Failed to bring up the tap interface %s: %v", hostName, err)
 	}
 
+	if m["queue.tx.length"] != "" {
+		err = networkSetDevTxQueueLength(hostName, m["queue.tx.length"])
+		if err != nil {
+			NetworkRemoveInterface(hostName)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkDefaultTxQueueLength is the default transmit queue length of veth and TAP devices.
+const networkDefaultTxQueueLength = "1000"
+
+// networkSetDevTxQueueLength sets the transmit queue length of a network device, resetting it to
+// the default one if no length is given.
+func networkSetDevTxQueueLength(devName string, length string) error {
+	if length == "" {
+		length = networkDefaultTxQueueLength
+	}
+
+	_, err := shared.RunCommand("ip", "link", "set", "dev", devName, "txqueuelen", length)
+	if err != nil {
+		return fmt.Errorf("Failed to set the transmit queue length of %s: %v", devName, err)
+	}
+
+	return nil
+}
+
+// networkUpdateTxQueueLength applies a change of the transmit queue length of the NIC of a running
+// instance to its host side device and, for containers, to the device in their network namespace.
+// The device of VMs is emulated, so only the host side TAP device is changed for them.
+func networkUpdateTxQueueLength(s *state.State, inst instance.Instance, m deviceConfig.Device, oldM deviceConfig.Device, hostName string) error {
+	if m["queue.tx.length"] == oldM["queue.tx.length"] {
+		return nil
+	}
+
+	err := networkSetDevTxQueueLength(hostName, m["queue.tx.length"])
+	if err != nil {
+		return err
+	}
+
+	if inst.Type() != instancetype.Container {
+		return nil
+	}
+
+	length := m["queue.tx.length"]
+	if length == "" {
+		length = networkDefaultTxQueueLength
+	}
+
+	_, err = shared.RunCommand(s.OS.ExecPath, "forknet", "txqueuelen", fmt.Sprintf("/proc/%d/ns/net", inst.InitPID()), m["name"], length)
+	if err != nil {
+		return fmt.Errorf("Failed to set the transmit queue length of %s in the container: %v", m["name"], err)
+	}
+
 	return nil
 }
 
@@ -578,6 +771,93 @@ func networkSetVethLimits(m deviceConfig.Device) error {
 	return nil
 }
 
+// networkSetVethMirror mirrors the traffic of the veth device specified in the config to the target
+// interface. It must be run after networkSetVethLimits as that resets the tc qdiscs of the device.
+func networkSetVethMirror(s *state.State, projectName string, m deviceConfig.Device) error {
+	if m["mirror.target"] == "" {
+		return nil
+	}
+
+	veth := m["host_name"]
+	target, err := networkMirrorTarget(s, projectName, m["mirror.target"])
+	if err != nil {
+		return err
+	}
+
+	if target == veth {
+		return fmt.Errorf("Cannot mirror traffic of %s to itself", veth)
+	}
+
+	// Use continue so that the traffic still goes through the limit filters afterwards.
+	mirror := []string{"action", "mirred", "egress", "mirror", "dev", target, "continue"}
+
+	// Mirror the traffic sent to the instance (egress of the host side veth).
+	if m["limits.ingress"] == "" {
+		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", veth, "root", "handle", "1:0", "prio")
+		if err != nil {
+			return fmt.Errorf("Failed to create root tc qdisc: %s", out)
+		}
+	}
+
+	args := append([]string{"filter", "add", "dev", veth, "parent", "1:0", "prio", "1", "protocol", "all", "u32", "match", "u32", "0", "0"}, mirror...)
+	out, err := shared.RunCommand("tc", args...)
+	if err != nil {
+		return fmt.Errorf("Failed to create mirror tc filter: %s", out)
+	}
+
+	// Mirror the traffic sent by the instance (ingress of the host side veth).
+	if m["limits.egress"] == "" {
+		out, err := shared.RunCommand("tc", "qdisc", "add", "dev", veth, "handle", "ffff:0", "ingress")
+		if err != nil {
+			return fmt.Errorf("Failed to create ingress tc qdisc: %s", out)
+		}
+	}
+
+	args = append([]string{"filter", "add", "dev", veth, "parent", "ffff:0", "prio", "1", "protocol", "all", "u32", "match", "u32", "0", "0"}, mirror...)
+	out, err = shared.RunCommand("tc", args...)
+	if err != nil {
+		return fmt.Errorf("Failed to create mirror tc filter: %s", out)
+	}
+
+	return nil
+}
+
+// networkMirrorTarget resolves a mirror target, either a host interface or the NIC device of
+// another running instance of the project given as <instance>/<device>, to a host interface.
+func networkMirrorTarget(s *state.State, projectName string, value string) (string, error) {
+	target := value
+
+	fields := strings.SplitN(value, "/", 2)
+	if len(fields) == 2 {
+		inst, err := InstanceLoadByProjectAndName(s, projectName, fields[0])
+		if err != nil {
+			return "", err
+		}
+
+		target = inst.ExpandedConfig()[fmt.Sprintf("volatile.%s.host_name", fields[1])]
+		if target == "" {
+			return "", fmt.Errorf("Mirror target %q isn't a started NIC device", value)
+		}
+	}
+
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", target)) {
+		return "", fmt.Errorf("Mirror target interface %q doesn't exist", target)
+	}
+
+	return target, nil
+}
+
+// networkValidMirrorTarget validates a mirror target, a host interface or <instance>/<device>.
+func networkValidMirrorTarget(value string) error {
+	for _, field := range strings.SplitN(value, "/", 2) {
+		if field == "" || strings.ContainsAny(field, " /") {
+			return fmt.Errorf("Invalid mirror target: %s", value)
+		}
+	}
+
+	return nil
+}
+
 // networkValidMAC validates an ethernet MAC address. e.g. "32:47:ae:06:22:f9".
 func networkValidMAC(value string) error {
 	regexHwaddr, err := regexp.Compile("^([0-9a-fA-F]{2}:){5}[0-9a-fA-F]{2}$")
@@ -728,6 +1008,42 @@ func NetworkValidNetworkV6List(value string) error {
 	return nil
 }
 
+// NetworkValidVLAN validates a VLAN ID. If string is empty, returns valid.
+func NetworkValidVLAN(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	vlanID, err := strconv.Atoi(value)
+	if err != nil || vlanID < 0 || vlanID > 4094 {
+		return fmt.Errorf("Invalid VLAN ID: %s", value)
+	}
+
+	return nil
+}
+
+// networkValidVLANList validates a comma delimited list of VLAN IDs.
+func networkValidVLANList(value string) error {
+	for _, vlanID := range strings.Split(value, ",") {
+		vlanID = strings.TrimSpace(vlanID)
+		if vlanID == "" {
+			return fmt.Errorf("Invalid empty VLAN ID in list: %s", value)
+		}
+
+		err := NetworkValidVLAN(vlanID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkValidMulticastRouter validates the multicast router mode of a bridge port.
+func networkValidMulticastRouter(value string) error {
+	return shared.IsOneOf(value, []string{"auto", "disabled", "permanent"})
+}
+
 // networkParsePortRange validates a port range in the form n-n.
 func networkParsePortRange(r string) (int64, int64, error) {
 	entries := strings.Split(r, "-")
@@ -753,3 +1069,29 @@ func networkParsePortRange(r string) (int64, int64, error) {
 
 	return base, size, nil
 }
+
+// NetworkIPv6Address returns the IPv6 address of a managed bridge. Bridges numbered from a prefix
+// obtained through DHCPv6 prefix delegation use the first address of the prefix, or "none" until
+// one was obtained.
+func NetworkIPv6Address(network string, config map[string]string) string {
+	if config["ipv6.address"] != "delegated" {
+		return config["ipv6.address"]
+	}
+
+	prefix, err := dhcpv6.LoadClientLease(shared.VarPath("networks", network, "dhcpv6.uplink.lease"))
+	if err != nil || prefix == nil {
+		return "none"
+	}
+
+	// Use a /64 subnet when possible to allow SLAAC.
+	size, _ := prefix.Mask.Size()
+	if size < 64 {
+		size = 64
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, prefix.IP.To16())
+	ip[15] |= 1
+
+	return fmt.Sprintf("%s/%d", ip, size)
+}
@@ -82,6 +82,7 @@ func (d *disk) validateConfig() error {
 		"raw.mount.options": shared.IsAny,
 		"ceph.cluster_name": shared.IsAny,
 		"ceph.user_name":    shared.IsAny,
+		"boot.priority":     shared.IsUint32,
 	}
 
 	// VMs don't use the "path" property, but containers need it, so if we are validating a profile that can
@@ -459,8 +460,8 @@ func (d *disk) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		}
 	}
 
-	// Only apply IO limits if instance is running.
-	if isRunning {
+	// Only apply IO limits if container is running.
+	if isRunning && d.inst.Type() == instancetype.Container {
 		runConf := deviceConfig.RunConfig{}
 		err := d.generateLimits(&runConf)
 		if err != nil {
@@ -482,31 +483,6 @@ func (d *disk) applyQuota(newSize string) error {
 
 // generateLimits adds a set of cgroup rules to apply specified limits to the supplied RunConfig.
 func (d *disk) generateLimits(runConf *deviceConfig.RunConfig) error {
-	// Disk priority limits.
-	diskPriority := d.inst.ExpandedConfig()["limits.disk.priority"]
-	if diskPriority != "" {
-		if d.state.OS.CGInfo.Supports(cgroup.BlkioWeight, nil) {
-			priorityInt, err := strconv.Atoi(diskPriority)
-			if err != nil {
-				return err
-			}
-
-			priority := priorityInt * 100
-
-			// Minimum valid value is 10
-			if priority == 0 {
-				priority = 10
-			}
-
-			runConf.CGroups = append(runConf.CGroups, deviceConfig.RunConfigItem{
-				Key:   "blkio.weight",
-				Value: fmt.Sprintf("%d", priority),
-			})
-		} else {
-			return fmt.Errorf("Cannot apply limits.disk.priority as blkio.weight cgroup controller is missing")
-		}
-	}
-
 	// Disk throttle limits.
 	hasDiskLimits := false
 	for _, dev := range d.inst.ExpandedDevices() {
@@ -898,11 +874,10 @@ func (d *disk) getDiskLimits() (map[string]diskBlockLimit, error) {
 				// Straightforward entry (full block device)
 				blockStr = block
 			} else {
-				// Attempt to deal with a partition (guess its parent)
-				fields := strings.SplitN(block, ":", 2)
-				fields[1] = "0"
-				if shared.StringInSlice(fmt.Sprintf("%s:%s", fields[0], fields[1]), validBlocks) {
-					blockStr = fmt.Sprintf("%s:%s", fields[0], fields[1])
+				// Attempt to deal with a partition (resolve its parent)
+				parent, err := d.getPartitionParent(block)
+				if err == nil && shared.StringInSlice(parent, validBlocks) {
+					blockStr = parent
 				}
 			}
 
@@ -1117,11 +1092,17 @@ func (d *disk) getParentBlocks(path string) ([]string, error) {
 		if len(devices) == 0 {
 			return nil, fmt.Errorf("Unable to find backing block for zfs pool: %s", poolName)
 		}
-	} else if fs == "btrfs" && shared.PathExists(dev[1]) {
-		// Accessible btrfs filesystems
-		output, err := shared.RunCommand("btrfs", "filesystem", "show", dev[1])
+	} else if fs == "btrfs" {
+		// Accessible btrfs filesystems. Query through the mount point when
+		// the mount source isn't a usable path (e.g. subvolume bind-mounts).
+		btrfsPath := dev[1]
+		if !shared.PathExists(btrfsPath) {
+			btrfsPath = match
+		}
+
+		output, err := shared.RunCommand("btrfs", "filesystem", "show", btrfsPath)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to query btrfs filesystem information for %s: %v", dev[1], err)
+			return nil, fmt.Errorf("Failed to query btrfs filesystem information for %s: %v", btrfsPath, err)
 		}
 
 		for _, line := range strings.Split(output, "\n") {
@@ -1145,6 +1126,10 @@ func (d *disk) getParentBlocks(path string) ([]string, error) {
 		}
 
 		devices = append(devices, fmt.Sprintf("%d:%d", major, minor))
+	} else if match != "/" {
+		// Virtual filesystems (overlay, bind-mounted directories, ...) don't
+		// have a usable source, so fallback to the mount they're on top of.
+		return d.getParentBlocks(filepath.Dir(match))
 	} else {
 		return nil, fmt.Errorf("Invalid block device: %s", dev[1])
 	}
@@ -1152,6 +1137,27 @@ func (d *disk) getParentBlocks(path string) ([]string, error) {
 	return devices, nil
 }
 
+// getPartitionParent returns the major:minor of the block device holding the given partition.
+func (d *disk) getPartitionParent(block string) (string, error) {
+	sysPath := filepath.Join("/sys/dev/block", block)
+	if !shared.PathExists(filepath.Join(sysPath, "partition")) {
+		return "", fmt.Errorf("Block device %s isn't a partition", block)
+	}
+
+	// The partition's sysfs entry lives inside that of its parent device.
+	devPath, err := filepath.EvalSymlinks(sysPath)
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := ioutil.ReadFile(filepath.Join(filepath.Dir(devPath), "dev"))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(parent)), nil
+}
+
 // generateVMConfigDrive generates an ISO containing the cloud init config for a VM.
 // Returns the path to the ISO.
 func (d *disk) generateVMConfigDrive() (string, error) {
@@ -31,8 +31,10 @@ func nicValidationRules(requiredFields []string, optionalFields []string) map[st
 	defaultValidators := map[string]func(value string) error{
 		"name":                    shared.IsAny,
 		"parent":                  shared.IsAny,
+		"pool":                    shared.IsAny,
 		"mtu":                     shared.IsAny,
 		"vlan":                    shared.IsAny,
+		"vlan.tagged":             networkValidVLANList,
 		"hwaddr":                  networkValidMAC,
 		"host_name":               shared.IsAny,
 		"limits.ingress":          shared.IsAny,
@@ -41,6 +43,11 @@ func nicValidationRules(requiredFields []string, optionalFields []string) map[st
 		"security.mac_filtering":  shared.IsAny,
 		"security.ipv4_filtering": shared.IsAny,
 		"security.ipv6_filtering": shared.IsAny,
+		"multicast.flood":         shared.IsBool,
+		"multicast.router":        networkValidMulticastRouter,
+		"mirror.target":           networkValidMirrorTarget,
+		"queue.tx.length":         shared.IsUint32,
+		"boot.priority":           shared.IsUint32,
 		"maas.subnet.ipv4":        shared.IsAny,
 		"maas.subnet.ipv6":        shared.IsAny,
 		"ipv4.address":            NetworkValidAddressV4,
@@ -59,6 +59,8 @@ func (d *nicBridged) validateConfig() error {
 		"mtu",
 		"hwaddr",
 		"host_name",
+		"vlan",
+		"vlan.tagged",
 		"limits.ingress",
 		"limits.egress",
 		"limits.max",
@@ -69,10 +71,26 @@ func (d *nicBridged) validateConfig() error {
 		"security.mac_filtering",
 		"security.ipv4_filtering",
 		"security.ipv6_filtering",
+		"multicast.flood",
+		"multicast.router",
+		"mirror.target",
+		"queue.tx.length",
+		"boot.priority",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 	}
-	err := d.config.Validate(nicValidationRules(requiredFields, optionalFields))
+
+	// Bridged NICs pass the VLAN ID to the bridge, so it must be a valid one.
+	rules := nicValidationRules(requiredFields, optionalFields)
+	rules["vlan"] = func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		return NetworkValidVLAN(value)
+	}
+
+	err := d.config.Validate(rules)
 	if err != nil {
 		return err
 	}
@@ -96,7 +114,7 @@ func (d *nicBridged) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicBridged) CanHotPlug() (bool, []string) {
-	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering"}
+	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "multicast.flood", "multicast.router", "mirror.target", "queue.tx.length"}
 }
 
 // Add is run when a device is added to an instance whether or not the instance is running.
@@ -130,7 +148,7 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		peerName, err = networkCreateVethPair(saveData["host_name"], d.config)
 	} else if d.inst.Type() == instancetype.VM {
 		peerName = saveData["host_name"] // VMs use the host_name to link to the TAP FD.
-		err = networkCreateTap(saveData["host_name"])
+		err = networkCreateTap(saveData["host_name"], d.config)
 	}
 
 	if err != nil {
@@ -144,6 +162,13 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	// Mirror the traffic to the target interface (uses enriched host_name from networkSetupHostVethDevice).
+	err = networkSetVethMirror(d.state, d.inst.Project(), d.config)
+	if err != nil {
+		NetworkRemoveInterface(saveData["host_name"])
+		return nil, err
+	}
+
 	// Apply and host-side network filters (uses enriched host_name from networkSetupHostVethDevice).
 	err = d.setupHostFilters(nil)
 	if err != nil {
@@ -158,6 +183,20 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	// Configure the untagged and tagged VLANs of the bridge port.
+	err = networkSetupBridgePortVLANs(d.config["parent"], saveData["host_name"], d.config["vlan"], d.config["vlan.tagged"])
+	if err != nil {
+		NetworkRemoveInterface(saveData["host_name"])
+		return nil, err
+	}
+
+	// Configure multicast flooding and router discovery on the bridge port.
+	err = networkSetupBridgePortMulticast(d.config["parent"], saveData["host_name"], d.config, nil)
+	if err != nil {
+		NetworkRemoveInterface(saveData["host_name"])
+		return nil, err
+	}
+
 	// Attempt to disable router advertisement acceptance.
 	err = util.SysctlSet(fmt.Sprintf("net/ipv6/conf/%s/accept_ra", saveData["host_name"]), "0")
 	if err != nil && !os.IsNotExist(err) {
@@ -217,11 +256,26 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 			return err
 		}
 
+		err = networkSetVethMirror(d.state, d.inst.Project(), d.config)
+		if err != nil {
+			return err
+		}
+
 		// Apply and host-side network filters (uses enriched host_name from networkSetupHostVethDevice).
 		err = d.setupHostFilters(oldConfig)
 		if err != nil {
 			return err
 		}
+
+		err = networkSetupBridgePortMulticast(d.config["parent"], v["host_name"], d.config, oldConfig)
+		if err != nil {
+			return err
+		}
+
+		err = networkUpdateTxQueueLength(d.state, d.inst, d.config, oldConfig, v["host_name"])
+		if err != nil {
+			return err
+		}
 	}
 
 	// Rebuild dnsmasq entry if needed and reload.
@@ -335,6 +389,7 @@ func (d *nicBridged) rebuildDnsmasqEntry() error {
 	}
 
 	netConfig := dbInfo.Config
+	netConfig["ipv6.address"] = NetworkIPv6Address(d.config["parent"], netConfig)
 	ipv4Address := d.config["ipv4.address"]
 	ipv6Address := d.config["ipv6.address"]
 
@@ -562,6 +617,8 @@ func (d *nicBridged) setFilters() (err error) {
 
 	// If parent bridge is unmanaged we cannot allocate static IPs.
 	if netInfo != nil {
+		netInfo.Config["ipv6.address"] = NetworkIPv6Address(d.config["parent"], netInfo.Config)
+
 		// Retrieve existing IPs, or allocate new ones if needed.
 		IPv4, IPv6, err = d.allocateFilterIPs(netInfo.Config)
 		if err != nil {
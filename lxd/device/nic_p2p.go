@@ -28,6 +28,8 @@ func (d *nicP2P) validateConfig() error {
 		"limits.max",
 		"ipv4.routes",
 		"ipv6.routes",
+		"mirror.target",
+		"queue.tx.length",
 	}
 	err := d.config.Validate(nicValidationRules([]string{}, optionalFields))
 	if err != nil {
@@ -49,7 +51,7 @@ func (d *nicP2P) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicP2P) CanHotPlug() (bool, []string) {
-	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes"}
+	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "mirror.target", "queue.tx.length"}
 }
 
 // Start is run when the device is added to a running instance or instance is starting up.
@@ -78,6 +80,13 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
+	// Mirror the traffic to the target interface (uses enriched host_name from networkSetupHostVethDevice).
+	err = networkSetVethMirror(d.state, d.inst.Project(), d.config)
+	if err != nil {
+		NetworkRemoveInterface(saveData["host_name"])
+		return nil, err
+	}
+
 	err = d.volatileSet(saveData)
 	if err != nil {
 		return nil, err
@@ -115,6 +124,16 @@ func (d *nicP2P) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		return err
 	}
 
+	err = networkSetVethMirror(d.state, d.inst.Project(), d.config)
+	if err != nil {
+		return err
+	}
+
+	err = networkUpdateTxQueueLength(d.state, d.inst, d.config, oldConfig, v["host_name"])
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -20,6 +20,12 @@ import (
 
 type nicSRIOV struct {
 	deviceCommon
+
+	// vfParent is the parent device the VF was allocated from when using a SR-IOV pool.
+	vfParent string
+
+	// pool holds the configuration of the SR-IOV pool the VF is allocated from, if any.
+	pool map[string]string
 }
 
 // validateConfig checks the supplied config for correctness.
@@ -28,8 +34,10 @@ func (d *nicSRIOV) validateConfig() error {
 		return ErrUnsupportedDevType
 	}
 
-	requiredFields := []string{"parent"}
+	requiredFields := []string{}
 	optionalFields := []string{
+		"parent",
+		"pool",
 		"name",
 		"mtu",
 		"hwaddr",
@@ -43,6 +51,10 @@ func (d *nicSRIOV) validateConfig() error {
 		return err
 	}
 
+	if (d.config["parent"] == "") == (d.config["pool"] == "") {
+		return fmt.Errorf("Exactly one of the parent or pool properties must be set")
+	}
+
 	return nil
 }
 
@@ -52,6 +64,16 @@ func (d *nicSRIOV) validateEnvironment() error {
 		return fmt.Errorf("Requires name property to start")
 	}
 
+	if d.config["pool"] != "" {
+		_, pool, err := d.state.Cluster.SRIOVPoolGet(d.config["pool"])
+		if err != nil {
+			return fmt.Errorf("Failed to load SR-IOV pool '%s': %v", d.config["pool"], err)
+		}
+
+		d.pool = pool.Config
+		return nil
+	}
+
 	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", d.config["parent"])) {
 		return fmt.Errorf("Parent device '%s' doesn't exist", d.config["parent"])
 	}
@@ -59,6 +81,43 @@ func (d *nicSRIOV) validateEnvironment() error {
 	return nil
 }
 
+// parentDevice returns the parent device of the VF, either from the device config or, when using
+// a SR-IOV pool, the one it was allocated from.
+func (d *nicSRIOV) parentDevice() string {
+	if d.vfParent != "" {
+		return d.vfParent
+	}
+
+	if d.config["parent"] != "" {
+		return d.config["parent"]
+	}
+
+	return d.volatileGet()["last_state.vf.parent"]
+}
+
+// findPoolVirtualFunction looks for an unused virtual function on the parent devices of the pool.
+func (d *nicSRIOV) findPoolVirtualFunction(reservedDevices map[string]struct{}) (string, int, error) {
+	// The pool manages the number of VFs of its parents when vf_count is set.
+	bump := d.pool["vf_count"] == ""
+
+	for _, parent := range strings.Split(d.pool["parent"], ",") {
+		parent = strings.TrimSpace(parent)
+		if parent == "" || !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", parent)) {
+			continue
+		}
+
+		vfDev, vfID, err := d.findFreeVirtualFunction(parent, reservedDevices, bump)
+		if err != nil {
+			continue
+		}
+
+		d.vfParent = parent
+		return vfDev, vfID, nil
+	}
+
+	return "", 0, fmt.Errorf("No free virtual function found in SR-IOV pool '%s'", d.config["pool"])
+}
+
 // Start is run when the device is added to a running instance or instance is starting up.
 func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 	err := d.validateEnvironment()
@@ -73,7 +132,13 @@ func (d *nicSRIOV) Start() (*deviceConfig.RunConfig, error) {
 		return nil, err
 	}
 
-	vfDev, vfID, err := d.findFreeVirtualFunction(reservedDevices)
+	var vfDev string
+	var vfID int
+	if d.config["pool"] != "" {
+		vfDev, vfID, err = d.findPoolVirtualFunction(reservedDevices)
+	} else {
+		vfDev, vfID, err = d.findFreeVirtualFunction(d.config["parent"], reservedDevices, true)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -137,14 +202,16 @@ func (d *nicSRIOV) Stop() (*deviceConfig.RunConfig, error) {
 // postStop is run after the device is removed from the instance.
 func (d *nicSRIOV) postStop() error {
 	defer d.volatileSet(map[string]string{
-		"host_name":                "",
-		"last_state.hwaddr":        "",
-		"last_state.mtu":           "",
-		"last_state.created":       "",
-		"last_state.vf.id":         "",
-		"last_state.vf.hwaddr":     "",
-		"last_state.vf.vlan":       "",
-		"last_state.vf.spoofcheck": "",
+		"host_name":                 "",
+		"last_state.hwaddr":         "",
+		"last_state.mtu":            "",
+		"last_state.created":        "",
+		"last_state.vf.id":          "",
+		"last_state.vf.hwaddr":      "",
+		"last_state.vf.vlan":        "",
+		"last_state.vf.spoofcheck":  "",
+		"last_state.vf.parent":      "",
+		"last_state.vf.max_tx_rate": "",
 	})
 
 	v := d.volatileGet()
@@ -158,23 +225,24 @@ func (d *nicSRIOV) postStop() error {
 }
 
 // findFreeVirtualFunction looks on the specified parent device for an unused virtual function.
+// If bump is true, the number of VFs of the parent is raised to its maximum when all are in use.
 // Returns the name of the interface and virtual function index ID if found, error if not.
-func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{}) (string, int, error) {
-	sriovNumVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", d.config["parent"])
-	sriovTotalVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_totalvfs", d.config["parent"])
+func (d *nicSRIOV) findFreeVirtualFunction(parent string, reservedDevices map[string]struct{}, bump bool) (string, int, error) {
+	sriovNumVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", parent)
+	sriovTotalVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_totalvfs", parent)
 
 	// Verify that this is indeed a SR-IOV enabled device.
 	if !shared.PathExists(sriovTotalVFs) {
-		return "", 0, fmt.Errorf("Parent device '%s' doesn't support SR-IOV", d.config["parent"])
+		return "", 0, fmt.Errorf("Parent device '%s' doesn't support SR-IOV", parent)
 	}
 
 	// Get parent dev_port and dev_id values.
-	pfDevPort, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_port", d.config["parent"]))
+	pfDevPort, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_port", parent))
 	if err != nil {
 		return "", 0, err
 	}
 
-	pfDevID, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_id", d.config["parent"]))
+	pfDevID, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/dev_id", parent))
 	if err != nil {
 		return "", 0, err
 	}
@@ -202,7 +270,7 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 	}
 
 	// Ensure parent is up (needed for Intel at least).
-	_, err = shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "up")
+	_, err = shared.RunCommand("ip", "link", "set", "dev", parent, "up")
 	if err != nil {
 		return "", 0, err
 	}
@@ -211,12 +279,12 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 	nicName := ""
 	vfID := 0
 	for i := 0; i < sriovNum; i++ {
-		if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)) {
+		if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", parent, i)) {
 			continue
 		}
 
 		// Check if VF is already in use.
-		empty, err := shared.PathIsEmpty(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i))
+		empty, err := shared.PathIsEmpty(fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", parent, i))
 		if err != nil {
 			return "", 0, err
 		}
@@ -224,7 +292,7 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 			continue
 		}
 
-		vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)
+		vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", parent, i)
 		nicName, err = d.getFreeVFInterface(reservedDevices, vfListPath, pfDevID, pfDevPort)
 		if err != nil {
 			return "", 0, err
@@ -238,8 +306,8 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 	}
 
 	if nicName == "" {
-		if sriovNum == sriovTotal {
-			return "", 0, fmt.Errorf("All virtual functions of sriov device '%s' seem to be in use", d.config["parent"])
+		if sriovNum == sriovTotal || !bump {
+			return "", 0, fmt.Errorf("All virtual functions of sriov device '%s' seem to be in use", parent)
 		}
 
 		// Bump the number of VFs to the maximum.
@@ -250,7 +318,7 @@ func (d *nicSRIOV) findFreeVirtualFunction(reservedDevices map[string]struct{})
 
 		// Use next free VF index.
 		for i := sriovNum + 1; i < sriovTotal; i++ {
-			vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", d.config["parent"], i)
+			vfListPath := fmt.Sprintf("/sys/class/net/%s/device/virtfn%d/net", parent, i)
 			nicName, err = d.getFreeVFInterface(reservedDevices, vfListPath, pfDevID, pfDevPort)
 			if err != nil {
 				return "", 0, err
@@ -314,7 +382,7 @@ func (d *nicSRIOV) getFreeVFInterface(reservedDevices map[string]struct{}, vfLis
 // properties of the physical device into voltatile for restoration on detach.
 func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[string]string) error {
 	// Retrieve VF settings from parent device.
-	vfInfo, err := d.networkGetVirtFuncInfo(d.config["parent"], vfID)
+	vfInfo, err := d.networkGetVirtFuncInfo(d.parentDevice(), vfID)
 	if err != nil {
 		return err
 	}
@@ -325,6 +393,10 @@ func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[stri
 	volatile["last_state.vf.vlan"] = fmt.Sprintf("%d", vfInfo.vlan)
 	volatile["last_state.vf.spoofcheck"] = fmt.Sprintf("%t", vfInfo.spoofcheck)
 
+	if d.config["pool"] != "" {
+		volatile["last_state.vf.parent"] = d.parentDevice()
+	}
+
 	// Record the host interface we represents the VF device which we will move into instance.
 	volatile["host_name"] = vfDevice
 	volatile["last_state.created"] = "false" // Indicates don't delete device at stop time.
@@ -358,14 +430,29 @@ func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[stri
 	// The OS won't let an already bound device be bound again so is safe to call twice.
 	defer d.networkDeviceBind(vfPCISlot, vfDriverPath)
 
-	// Setup VF VLAN if specified.
-	if d.config["vlan"] != "" {
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "vlan", d.config["vlan"])
+	// Setup VF VLAN if specified, falling back to the pool's default.
+	vlan := d.config["vlan"]
+	if vlan == "" {
+		vlan = d.pool["vlan"]
+	}
+
+	if vlan != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "vlan", vlan)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Setup VF rate limit from the pool if specified.
+	if d.pool["limits.max_tx_rate"] != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "max_tx_rate", d.pool["limits.max_tx_rate"])
+		if err != nil {
+			return err
+		}
+
+		volatile["last_state.vf.max_tx_rate"] = "0"
+	}
+
 	// Setup VF MAC spoofing protection if specified.
 	// The ordering of this section is very important, as Intel cards require a very specific
 	// order of setup to allow LXD to set custom MACs when using spoof check mode.
@@ -377,25 +464,25 @@ func (d *nicSRIOV) setupSriovParent(vfDevice string, vfID int, volatile map[stri
 		}
 
 		// Set MAC on VF (this combined with spoof checking prevents any other MAC being used).
-		_, err = shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "mac", mac)
+		_, err = shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "mac", mac)
 		if err != nil {
 			return err
 		}
 
 		// Now that MAC is set on VF, we can enable spoof checking.
-		_, err = shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "spoofchk", "on")
+		_, err = shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "spoofchk", "on")
 		if err != nil {
 			return err
 		}
 	} else {
 		// Reset VF to ensure no previous MAC restriction exists.
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "mac", "00:00:00:00:00:00")
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "mac", "00:00:00:00:00:00")
 		if err != nil {
 			return err
 		}
 
 		// Ensure spoof checking is disabled if not enabled in instance.
-		_, err = shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "spoofchk", "off")
+		_, err = shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "spoofchk", "off")
 		if err != nil {
 			return err
 		}
@@ -482,7 +569,7 @@ func (d *nicSRIOV) networkGetVirtFuncInfo(devName string, vfID int) (vf virtFunc
 
 // networkGetVFDevicePCISlot returns the PCI slot name for a network virtual function device.
 func (d *nicSRIOV) networkGetVFDevicePCISlot(vfID string) (string, error) {
-	file, err := os.Open(fmt.Sprintf("/sys/class/net/%s/device/virtfn%s/uevent", d.config["parent"], vfID))
+	file, err := os.Open(fmt.Sprintf("/sys/class/net/%s/device/virtfn%s/uevent", d.parentDevice(), vfID))
 	if err != nil {
 		return "", err
 	}
@@ -507,7 +594,7 @@ func (d *nicSRIOV) networkGetVFDevicePCISlot(vfID string) (string, error) {
 
 // networkGetVFDeviceDriverPath returns the path to the network virtual function device driver in /sys.
 func (d *nicSRIOV) networkGetVFDeviceDriverPath(vfID string) (string, error) {
-	return filepath.EvalSymlinks(fmt.Sprintf("/sys/class/net/%s/device/virtfn%s/driver", d.config["parent"], vfID))
+	return filepath.EvalSymlinks(fmt.Sprintf("/sys/class/net/%s/device/virtfn%s/driver", d.parentDevice(), vfID))
 }
 
 // networkDeviceUnbind unbinds a network device from the OS using its PCI Slot Name and driver path.
@@ -537,7 +624,7 @@ func (d *nicSRIOV) networkDeviceBindWait(devName string) error {
 // volatile data that was stored when the device was first added with setupSriovParent().
 func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 	// Nothing to do if we don't know the original device name or the VF ID.
-	if volatile["host_name"] == "" || volatile["last_state.vf.id"] == "" || d.config["parent"] == "" {
+	if volatile["host_name"] == "" || volatile["last_state.vf.id"] == "" || d.parentDevice() == "" {
 		return nil
 	}
 
@@ -564,9 +651,17 @@ func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 	// The OS won't let an already bound device be bound again so is safe to call twice.
 	defer d.networkDeviceBind(vfPCISlot, vfDriverPath)
 
+	// Reset VF rate limit if specified
+	if volatile["last_state.vf.max_tx_rate"] != "" {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "max_tx_rate", volatile["last_state.vf.max_tx_rate"])
+		if err != nil {
+			return err
+		}
+	}
+
 	// Reset VF VLAN if specified
 	if volatile["last_state.vf.vlan"] != "" {
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "vlan", volatile["last_state.vf.vlan"])
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "vlan", volatile["last_state.vf.vlan"])
 		if err != nil {
 			return err
 		}
@@ -580,7 +675,7 @@ func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 			mode = "on"
 		}
 
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "spoofchk", mode)
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "spoofchk", mode)
 		if err != nil {
 			return err
 		}
@@ -588,7 +683,7 @@ func (d *nicSRIOV) restoreSriovParent(volatile map[string]string) error {
 
 	// Reset VF MAC specified if specified.
 	if volatile["last_state.vf.hwaddr"] != "" {
-		_, err := shared.RunCommand("ip", "link", "set", "dev", d.config["parent"], "vf", volatile["last_state.vf.id"], "mac", volatile["last_state.vf.hwaddr"])
+		_, err := shared.RunCommand("ip", "link", "set", "dev", d.parentDevice(), "vf", volatile["last_state.vf.id"], "mac", volatile["last_state.vf.hwaddr"])
 		if err != nil {
 			return err
 		}
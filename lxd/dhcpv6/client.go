@@ -0,0 +1,387 @@
+package dhcpv6
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// ClientLease is the prefix obtained by a client, as persisted on disk.
+type ClientLease struct {
+	Prefix string    `json:"prefix"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// LoadClientLease returns the prefix persisted by a client, or nil if it has none or it expired.
+func LoadClientLease(path string) (*net.IPNet, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	lease := ClientLease{}
+	err = json.Unmarshal(content, &lease)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(lease.Expiry) {
+		return nil, nil
+	}
+
+	_, prefix, err := net.ParseCIDR(lease.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return prefix, nil
+}
+
+// Client requests a delegated prefix from the DHCPv6 servers on an interface and keeps it renewed.
+type Client struct {
+	// Interface is the name of the interface the prefix is requested on.
+	Interface string
+
+	// IAID identifies the requested prefix among those of the other clients on the interface.
+	IAID uint32
+
+	// PrefixLength is the prefix length hinted to the server, 0 for no hint.
+	PrefixLength int
+
+	// LeasePath is the file the obtained prefix is persisted in.
+	LeasePath string
+
+	// OnPrefix is called when the obtained prefix changes, with nil when it's lost.
+	OnPrefix func(prefix *net.IPNet)
+
+	conn     *net.UDPConn
+	duid     []byte
+	serverID []byte
+	prefix   *net.IPNet
+	applied  string
+	t1       time.Time
+	t2       time.Time
+	expiry   time.Time
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Start starts requesting a prefix in the background.
+func (c *Client) Start() error {
+	iface, err := net.InterfaceByName(c.Interface)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return err
+	}
+
+	var local net.IP
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err == nil && ip.To4() == nil && ip.IsLinkLocalUnicast() {
+			local = ip
+			break
+		}
+	}
+
+	if local == nil {
+		return fmt.Errorf("Interface %s has no IPv6 link-local address", c.Interface)
+	}
+
+	c.conn, err = net.ListenUDP("udp6", &net.UDPAddr{IP: local, Port: ClientPort, Zone: c.Interface})
+	if err != nil {
+		return fmt.Errorf("Failed to listen for DHCPv6 replies on %s: %v", c.Interface, err)
+	}
+
+	c.duid = DUID(iface.HardwareAddr)
+	c.stop = make(chan struct{})
+
+	// Resume with the persisted prefix, it's already in use and will be used as hint.
+	c.prefix, _ = LoadClientLease(c.LeasePath)
+	if c.prefix != nil {
+		c.applied = c.prefix.String()
+	}
+
+	c.wg.Add(1)
+	go c.run()
+
+	return nil
+}
+
+// Stop stops the client, the obtained prefix isn't released.
+func (c *Client) Stop() {
+	close(c.stop)
+	c.conn.Close()
+	c.wg.Wait()
+}
+
+func (c *Client) run() {
+	defer c.wg.Done()
+
+	backoff := 10 * time.Second
+	for {
+		var wait time.Duration
+
+		now := time.Now()
+		switch {
+		case c.serverID == nil || now.After(c.expiry):
+			if c.serverID != nil {
+				logger.Warn("Delegated prefix expired", log.Ctx{"interface": c.Interface, "prefix": c.prefix.String()})
+				c.serverID = nil
+				c.setPrefix(nil, time.Time{})
+			}
+
+			err := c.solicit()
+			if err != nil {
+				logger.Debug("Failed to obtain a delegated prefix", log.Ctx{"interface": c.Interface, "err": err})
+				wait = backoff
+				if backoff < 2*time.Minute {
+					backoff *= 2
+				}
+			} else {
+				backoff = 10 * time.Second
+			}
+		case now.After(c.t2):
+			err := c.renew(MessageRebind)
+			if err != nil {
+				wait = 10 * time.Second
+			}
+		case now.After(c.t1):
+			err := c.renew(MessageRenew)
+			if err != nil {
+				wait = 10 * time.Second
+			}
+		default:
+			wait = c.t1.Sub(now)
+		}
+
+		if wait == 0 {
+			continue
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// solicit looks for a server delegating a prefix and requests it.
+func (c *Client) solicit() error {
+	advertise, err := c.exchange(MessageSolicit, nil, MessageAdvertise)
+	if err != nil {
+		return err
+	}
+
+	serverID := advertise.Get(OptionServerID)
+	prefix, _, err := c.parseReply(advertise)
+	if err != nil {
+		return err
+	}
+
+	c.prefix = prefix
+	reply, err := c.exchange(MessageRequest, serverID, MessageReply)
+	if err != nil {
+		return err
+	}
+
+	return c.bind(reply)
+}
+
+// renew extends the lifetime of the delegated prefix.
+func (c *Client) renew(msgType uint8) error {
+	serverID := c.serverID
+	if msgType == MessageRebind {
+		serverID = nil
+	}
+
+	reply, err := c.exchange(msgType, serverID, MessageReply)
+	if err != nil {
+		return err
+	}
+
+	err = c.bind(reply)
+	if err != nil {
+		// The binding is gone, start over.
+		c.expiry = time.Time{}
+		return err
+	}
+
+	return nil
+}
+
+// bind records the prefix from a server reply.
+func (c *Client) bind(reply *Message) error {
+	prefix, ia, err := c.parseReply(reply)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	valid := time.Duration(0)
+	for _, p := range ia.Prefixes {
+		if p.Prefix != nil && p.Prefix.IP.Mask(p.Prefix.Mask).Equal(prefix.IP) {
+			valid = time.Duration(p.Valid) * time.Second
+		}
+	}
+
+	t1 := time.Duration(ia.T1) * time.Second
+	if t1 == 0 {
+		t1 = valid / 2
+	}
+
+	t2 := time.Duration(ia.T2) * time.Second
+	if t2 == 0 {
+		t2 = valid / 5 * 4
+	}
+
+	c.serverID = reply.Get(OptionServerID)
+	c.t1 = now.Add(t1)
+	c.t2 = now.Add(t2)
+	c.setPrefix(prefix, now.Add(valid))
+
+	return nil
+}
+
+// setPrefix persists the prefix and reports it when it differs from the one in use.
+func (c *Client) setPrefix(prefix *net.IPNet, expiry time.Time) {
+	c.prefix = prefix
+	c.expiry = expiry
+
+	current := ""
+	if prefix == nil {
+		os.Remove(c.LeasePath)
+	} else {
+		current = prefix.String()
+
+		content, err := json.Marshal(ClientLease{Prefix: current, Expiry: expiry})
+		if err == nil {
+			err = ioutil.WriteFile(c.LeasePath, content, 0644)
+		}
+
+		if err != nil {
+			logger.Warn("Failed to save delegated prefix", log.Ctx{"interface": c.Interface, "err": err})
+		}
+	}
+
+	if current != c.applied {
+		c.applied = current
+		if c.OnPrefix != nil {
+			c.OnPrefix(prefix)
+		}
+	}
+}
+
+// parseReply returns the prefix delegated in a server message.
+func (c *Client) parseReply(msg *Message) (*net.IPNet, *IAPD, error) {
+	ias, err := msg.IAPDs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, ia := range ias {
+		if ia.IAID != c.IAID {
+			continue
+		}
+
+		if ia.Status != nil && *ia.Status != StatusSuccess {
+			return nil, nil, fmt.Errorf("Server refused the prefix delegation (status %d)", *ia.Status)
+		}
+
+		for _, p := range ia.Prefixes {
+			if p.Prefix != nil && p.Valid > 0 {
+				prefix := &net.IPNet{IP: p.Prefix.IP.Mask(p.Prefix.Mask), Mask: p.Prefix.Mask}
+				return prefix, &ia, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("No delegated prefix in server reply")
+}
+
+// exchange sends a message to the servers and waits for a reply of the expected type, retrying
+// a few times.
+func (c *Client) exchange(msgType uint8, serverID []byte, replyType uint8) (*Message, error) {
+	msg := &Message{Type: msgType}
+	_, err := rand.Read(msg.TransactionID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Add(OptionClientID, c.duid)
+	if serverID != nil {
+		msg.Add(OptionServerID, serverID)
+	}
+
+	msg.Add(OptionElapsedTime, []byte{0, 0})
+
+	ia := IAPD{IAID: c.IAID}
+	if c.prefix != nil {
+		ia.Prefixes = []IAPrefix{{Prefix: c.prefix}}
+	} else if c.PrefixLength > 0 {
+		ia.Prefixes = []IAPrefix{{Prefix: &net.IPNet{IP: net.IPv6unspecified, Mask: net.CIDRMask(c.PrefixLength, 128)}}}
+	}
+
+	msg.Add(OptionIAPD, ia.Encode())
+
+	dst := &net.UDPAddr{IP: AllServers, Port: ServerPort, Zone: c.Interface}
+	buf := make([]byte, 1500)
+	timeout := time.Second
+
+	for i := 0; i < 4; i++ {
+		_, err = c.conn.WriteToUDP(msg.Encode(), dst)
+		if err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(timeout)
+		for {
+			err = c.conn.SetReadDeadline(deadline)
+			if err != nil {
+				return nil, err
+			}
+
+			n, _, err := c.conn.ReadFromUDP(buf)
+			if err != nil {
+				netErr, ok := err.(net.Error)
+				if ok && netErr.Timeout() {
+					break
+				}
+
+				return nil, err
+			}
+
+			reply, err := Decode(buf[:n])
+			if err != nil || reply.Type != replyType || reply.TransactionID != msg.TransactionID {
+				continue
+			}
+
+			if !bytes.Equal(reply.Get(OptionClientID), c.duid) {
+				continue
+			}
+
+			return reply, nil
+		}
+
+		timeout *= 2
+	}
+
+	return nil, fmt.Errorf("No reply from DHCPv6 servers on %s", c.Interface)
+}
@@ -0,0 +1,120 @@
+package dhcpv6
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientParseReply(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("2001:db8:1::/56")
+	_, unmasked, _ := net.ParseCIDR("2001:db8:2::/56")
+	unmasked.IP[15] = 1
+	noPrefix := uint16(StatusNoPrefixAvail)
+
+	tests := []struct {
+		name   string
+		ias    []IAPD
+		prefix string
+		err    bool
+	}{
+		{
+			name:   "delegated prefix",
+			ias:    []IAPD{{IAID: 1, Prefixes: []IAPrefix{{Valid: 3600, Prefix: prefix}}}},
+			prefix: "2001:db8:1::/56",
+		},
+		{
+			name:   "prefix with host bits",
+			ias:    []IAPD{{IAID: 1, Prefixes: []IAPrefix{{Valid: 3600, Prefix: unmasked}}}},
+			prefix: "2001:db8:2::/56",
+		},
+		{
+			name:   "other IAID",
+			ias:    []IAPD{{IAID: 2, Prefixes: []IAPrefix{{Valid: 3600, Prefix: unmasked}}}, {IAID: 1, Prefixes: []IAPrefix{{Valid: 3600, Prefix: prefix}}}},
+			prefix: "2001:db8:1::/56",
+		},
+		{
+			name: "expired prefix",
+			ias:  []IAPD{{IAID: 1, Prefixes: []IAPrefix{{Valid: 0, Prefix: prefix}}}},
+			err:  true,
+		},
+		{
+			name: "refused",
+			ias:  []IAPD{{IAID: 1, Status: &noPrefix}},
+			err:  true,
+		},
+		{
+			name: "no IA_PD",
+			err:  true,
+		},
+	}
+
+	c := &Client{IAID: 1}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := &Message{Type: MessageReply}
+			for _, ia := range test.ias {
+				msg.Add(OptionIAPD, ia.Encode())
+			}
+
+			prefix, _, err := c.parseReply(msg)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.prefix, prefix.String())
+		})
+	}
+}
+
+func TestClientBind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-dhcpv6-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	prefixes := []*net.IPNet{}
+	c := &Client{
+		IAID:      1,
+		LeasePath: filepath.Join(dir, "lease"),
+		OnPrefix:  func(prefix *net.IPNet) { prefixes = append(prefixes, prefix) },
+	}
+
+	_, prefix, _ := net.ParseCIDR("2001:db8:1::/56")
+	reply := &Message{Type: MessageReply}
+	reply.Add(OptionServerID, []byte{1})
+	ia := IAPD{IAID: 1, Prefixes: []IAPrefix{{Valid: 3600, Prefix: prefix}}}
+	reply.Add(OptionIAPD, ia.Encode())
+
+	// Missing T1 and T2 default to a half and 80% of the lifetime.
+	before := time.Now()
+	require.NoError(t, c.bind(reply))
+	assert.Equal(t, []byte{1}, c.serverID)
+	assert.WithinDuration(t, before.Add(30*time.Minute), c.t1, time.Minute)
+	assert.WithinDuration(t, before.Add(48*time.Minute), c.t2, time.Minute)
+	assert.WithinDuration(t, before.Add(time.Hour), c.expiry, time.Minute)
+
+	// The prefix is reported once and persisted.
+	require.NoError(t, c.bind(reply))
+	assert.Len(t, prefixes, 1)
+
+	persisted, err := LoadClientLease(c.LeasePath)
+	require.NoError(t, err)
+	assert.Equal(t, prefix.String(), persisted.String())
+
+	// Losing the prefix removes it.
+	c.setPrefix(nil, time.Time{})
+	assert.Len(t, prefixes, 2)
+	assert.Nil(t, prefixes[1])
+
+	persisted, err = LoadClientLease(c.LeasePath)
+	require.NoError(t, err)
+	assert.Nil(t, persisted)
+}
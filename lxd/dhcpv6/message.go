@@ -0,0 +1,266 @@
+package dhcpv6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Message types (RFC 8415).
+const (
+	MessageSolicit   = 1
+	MessageAdvertise = 2
+	MessageRequest   = 3
+	MessageRenew     = 5
+	MessageRebind    = 6
+	MessageReply     = 7
+	MessageRelease   = 8
+)
+
+// Option codes (RFC 8415).
+const (
+	OptionClientID    = 1
+	OptionServerID    = 2
+	OptionPreference  = 7
+	OptionElapsedTime = 8
+	OptionStatusCode  = 13
+	OptionRapidCommit = 14
+	OptionIAPD        = 25
+	OptionIAPrefix    = 26
+)
+
+// Status codes (RFC 8415).
+const (
+	StatusSuccess       = 0
+	StatusNoBinding     = 3
+	StatusNoPrefixAvail = 6
+)
+
+// Ports used by clients and servers.
+const (
+	ClientPort = 546
+	ServerPort = 547
+)
+
+// AllServers is the link-local multicast address of all DHCPv6 relay agents and servers.
+var AllServers = net.ParseIP("ff02::1:2")
+
+// Option is a DHCPv6 option.
+type Option struct {
+	Code uint16
+	Data []byte
+}
+
+// Message is a DHCPv6 client/server message.
+type Message struct {
+	Type          uint8
+	TransactionID [3]byte
+	Options       []Option
+}
+
+// Decode parses a DHCPv6 client/server message.
+func Decode(data []byte) (*Message, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("Message too short")
+	}
+
+	m := &Message{Type: data[0]}
+	copy(m.TransactionID[:], data[1:4])
+
+	options, err := decodeOptions(data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	m.Options = options
+	return m, nil
+}
+
+// Encode serializes the message.
+func (m *Message) Encode() []byte {
+	data := []byte{m.Type, m.TransactionID[0], m.TransactionID[1], m.TransactionID[2]}
+	return append(data, encodeOptions(m.Options)...)
+}
+
+// Add appends an option to the message.
+func (m *Message) Add(code uint16, data []byte) {
+	m.Options = append(m.Options, Option{Code: code, Data: data})
+}
+
+// Get returns the data of the first option with the given code, or nil if missing.
+func (m *Message) Get(code uint16) []byte {
+	for _, option := range m.Options {
+		if option.Code == code {
+			return option.Data
+		}
+	}
+
+	return nil
+}
+
+// IAPDs returns the IA_PD options of the message.
+func (m *Message) IAPDs() ([]IAPD, error) {
+	ias := []IAPD{}
+
+	for _, option := range m.Options {
+		if option.Code != OptionIAPD {
+			continue
+		}
+
+		ia, err := DecodeIAPD(option.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		ias = append(ias, *ia)
+	}
+
+	return ias, nil
+}
+
+// IAPD is an identity association for prefix delegation.
+type IAPD struct {
+	IAID     uint32
+	T1       uint32
+	T2       uint32
+	Prefixes []IAPrefix
+
+	// Status is only set when the IA_PD carries a status code option.
+	Status *uint16
+}
+
+// IAPrefix is a prefix delegated as part of an IA_PD.
+type IAPrefix struct {
+	Preferred uint32
+	Valid     uint32
+	Prefix    *net.IPNet
+}
+
+// DecodeIAPD parses the data of an IA_PD option.
+func DecodeIAPD(data []byte) (*IAPD, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("IA_PD option too short")
+	}
+
+	ia := &IAPD{
+		IAID: binary.BigEndian.Uint32(data[0:4]),
+		T1:   binary.BigEndian.Uint32(data[4:8]),
+		T2:   binary.BigEndian.Uint32(data[8:12]),
+	}
+
+	options, err := decodeOptions(data[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		switch option.Code {
+		case OptionIAPrefix:
+			if len(option.Data) < 25 {
+				return nil, fmt.Errorf("IA Prefix option too short")
+			}
+
+			length := int(option.Data[8])
+			if length > 128 {
+				return nil, fmt.Errorf("Invalid prefix length %d", length)
+			}
+
+			prefix := &net.IPNet{
+				IP:   net.IP(append([]byte{}, option.Data[9:25]...)),
+				Mask: net.CIDRMask(length, 128),
+			}
+
+			ia.Prefixes = append(ia.Prefixes, IAPrefix{
+				Preferred: binary.BigEndian.Uint32(option.Data[0:4]),
+				Valid:     binary.BigEndian.Uint32(option.Data[4:8]),
+				Prefix:    prefix,
+			})
+		case OptionStatusCode:
+			if len(option.Data) < 2 {
+				return nil, fmt.Errorf("Status code option too short")
+			}
+
+			status := binary.BigEndian.Uint16(option.Data[0:2])
+			ia.Status = &status
+		}
+	}
+
+	return ia, nil
+}
+
+// Encode serializes the IA_PD into option data.
+func (ia *IAPD) Encode() []byte {
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data[0:4], ia.IAID)
+	binary.BigEndian.PutUint32(data[4:8], ia.T1)
+	binary.BigEndian.PutUint32(data[8:12], ia.T2)
+
+	options := []Option{}
+	for _, prefix := range ia.Prefixes {
+		prefixData := make([]byte, 25)
+		binary.BigEndian.PutUint32(prefixData[0:4], prefix.Preferred)
+		binary.BigEndian.PutUint32(prefixData[4:8], prefix.Valid)
+
+		if prefix.Prefix != nil {
+			length, _ := prefix.Prefix.Mask.Size()
+			prefixData[8] = byte(length)
+			copy(prefixData[9:25], prefix.Prefix.IP.To16())
+		}
+
+		options = append(options, Option{Code: OptionIAPrefix, Data: prefixData})
+	}
+
+	if ia.Status != nil {
+		options = append(options, Option{Code: OptionStatusCode, Data: StatusCode(*ia.Status, "")})
+	}
+
+	return append(data, encodeOptions(options)...)
+}
+
+// StatusCode returns the data of a status code option.
+func StatusCode(code uint16, message string) []byte {
+	data := make([]byte, 2)
+	binary.BigEndian.PutUint16(data, code)
+	return append(data, []byte(message)...)
+}
+
+// DUID returns a link-layer address based DUID (DUID-LL) for the given Ethernet address.
+func DUID(hwaddr net.HardwareAddr) []byte {
+	return append([]byte{0, 3, 0, 1}, hwaddr...)
+}
+
+func decodeOptions(data []byte) ([]Option, error) {
+	options := []Option{}
+
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("Truncated option header")
+		}
+
+		code := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			return nil, fmt.Errorf("Truncated option %d", code)
+		}
+
+		options = append(options, Option{Code: code, Data: data[4 : 4+length]})
+		data = data[4+length:]
+	}
+
+	return options, nil
+}
+
+func encodeOptions(options []Option) []byte {
+	data := []byte{}
+
+	for _, option := range options {
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint16(header[0:2], option.Code)
+		binary.BigEndian.PutUint16(header[2:4], uint16(len(option.Data)))
+
+		data = append(data, header...)
+		data = append(data, option.Data...)
+	}
+
+	return data
+}
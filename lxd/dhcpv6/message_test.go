@@ -0,0 +1,157 @@
+package dhcpv6
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		msgType uint8
+		options []Option
+		err     bool
+	}{
+		{
+			name: "too short",
+			data: []byte{1, 2, 3},
+			err:  true,
+		},
+		{
+			name:    "no options",
+			data:    []byte{MessageSolicit, 0xaa, 0xbb, 0xcc},
+			msgType: MessageSolicit,
+			options: []Option{},
+		},
+		{
+			name:    "options",
+			data:    []byte{MessageRequest, 0xaa, 0xbb, 0xcc, 0, 1, 0, 2, 0xde, 0xad, 0, 14, 0, 0},
+			msgType: MessageRequest,
+			options: []Option{{Code: OptionClientID, Data: []byte{0xde, 0xad}}, {Code: OptionRapidCommit, Data: []byte{}}},
+		},
+		{
+			name: "truncated option header",
+			data: []byte{MessageRequest, 0xaa, 0xbb, 0xcc, 0, 1, 0},
+			err:  true,
+		},
+		{
+			name: "truncated option data",
+			data: []byte{MessageRequest, 0xaa, 0xbb, 0xcc, 0, 1, 0, 4, 0xde, 0xad},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := Decode(test.data)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.msgType, msg.Type)
+			assert.Equal(t, [3]byte{0xaa, 0xbb, 0xcc}, msg.TransactionID)
+			assert.Equal(t, test.options, msg.Options)
+			assert.Equal(t, test.data, msg.Encode())
+		})
+	}
+}
+
+func TestMessageGet(t *testing.T) {
+	msg := &Message{Type: MessageSolicit}
+	msg.Add(OptionClientID, []byte{1})
+	msg.Add(OptionClientID, []byte{2})
+	msg.Add(OptionElapsedTime, []byte{0, 0})
+
+	assert.Equal(t, []byte{1}, msg.Get(OptionClientID))
+	assert.Equal(t, []byte{0, 0}, msg.Get(OptionElapsedTime))
+	assert.Nil(t, msg.Get(OptionServerID))
+}
+
+func TestIAPD(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("2001:db8:1::/56")
+	noBinding := uint16(StatusNoBinding)
+
+	tests := []struct {
+		name string
+		ia   IAPD
+	}{
+		{
+			name: "empty",
+			ia:   IAPD{IAID: 1},
+		},
+		{
+			name: "prefix",
+			ia:   IAPD{IAID: 2, T1: 1800, T2: 2880, Prefixes: []IAPrefix{{Preferred: 3600, Valid: 3600, Prefix: prefix}}},
+		},
+		{
+			name: "status",
+			ia:   IAPD{IAID: 3, Status: &noBinding},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := &Message{Type: MessageReply}
+			msg.Add(OptionIAPD, test.ia.Encode())
+			msg.Add(OptionServerID, []byte{1})
+
+			ias, err := msg.IAPDs()
+			require.NoError(t, err)
+			require.Len(t, ias, 1)
+
+			ia := ias[0]
+			assert.Equal(t, test.ia.IAID, ia.IAID)
+			assert.Equal(t, test.ia.T1, ia.T1)
+			assert.Equal(t, test.ia.T2, ia.T2)
+			assert.Equal(t, test.ia.Status, ia.Status)
+			require.Len(t, ia.Prefixes, len(test.ia.Prefixes))
+			for i, p := range test.ia.Prefixes {
+				assert.Equal(t, p.Preferred, ia.Prefixes[i].Preferred)
+				assert.Equal(t, p.Valid, ia.Prefixes[i].Valid)
+				assert.Equal(t, p.Prefix.String(), ia.Prefixes[i].Prefix.String())
+			}
+		})
+	}
+}
+
+func TestDecodeIAPDInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "too short",
+			data: []byte{0, 0, 0, 1, 0, 0, 0, 0},
+		},
+		{
+			name: "prefix option too short",
+			data: append(make([]byte, 12), 0, 26, 0, 2, 0, 0),
+		},
+		{
+			name: "prefix length too long",
+			data: append(append(make([]byte, 12), 0, 26, 0, 25), append(make([]byte, 8), append([]byte{129}, make([]byte, 16)...)...)...),
+		},
+		{
+			name: "status code too short",
+			data: append(make([]byte, 12), 0, 13, 0, 1, 0),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeIAPD(test.data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDUID(t *testing.T) {
+	hwaddr, _ := net.ParseMAC("00:16:3e:00:00:01")
+	assert.Equal(t, []byte{0, 3, 0, 1, 0x00, 0x16, 0x3e, 0x00, 0x00, 0x01}, DUID(hwaddr))
+}
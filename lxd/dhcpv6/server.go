@@ -0,0 +1,465 @@
+package dhcpv6
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// maxCandidates limits how many prefixes of the pool are considered when allocating a new one.
+const maxCandidates = 65536
+
+// Lease is a prefix delegated to a client.
+type Lease struct {
+	DUID   string    `json:"duid"`
+	IAID   uint32    `json:"iaid"`
+	Prefix string    `json:"prefix"`
+	Router string    `json:"router"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// Server is a DHCPv6 server only handling prefix delegation.
+type Server struct {
+	// Interface is the name of the interface the server listens on.
+	Interface string
+
+	// Pool is the prefix delegated prefixes are allocated from.
+	Pool *net.IPNet
+
+	// PrefixLength is the length of the delegated prefixes.
+	PrefixLength int
+
+	// Lifetime is the valid lifetime of the delegated prefixes.
+	Lifetime time.Duration
+
+	// LeasesPath is the file the leases are persisted in.
+	LeasesPath string
+
+	// ClientLimit is the maximum number of prefixes delegated to a client, identified by its
+	// link-local address as it can use any number of DUIDs. Zero means no limit.
+	ClientLimit int
+
+	// OnBind is called when a prefix is delegated to a client, with the client's link-local address.
+	OnBind func(prefix *net.IPNet, router net.IP) error
+
+	// OnUnbind is called when a delegated prefix is released or has expired.
+	OnUnbind func(prefix *net.IPNet) error
+
+	conn   *net.UDPConn
+	duid   []byte
+	leases map[string]*Lease
+	mu     sync.Mutex
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Start loads the persisted leases and starts serving requests.
+func (s *Server) Start() error {
+	iface, err := net.InterfaceByName(s.Interface)
+	if err != nil {
+		return err
+	}
+
+	s.duid = DUID(iface.HardwareAddr)
+	s.leases = map[string]*Lease{}
+	s.stop = make(chan struct{})
+
+	err = s.load()
+	if err != nil {
+		return err
+	}
+
+	s.conn, err = net.ListenMulticastUDP("udp6", iface, &net.UDPAddr{IP: AllServers, Port: ServerPort})
+	if err != nil {
+		return fmt.Errorf("Failed to listen for DHCPv6 requests on %s: %v", s.Interface, err)
+	}
+
+	s.wg.Add(2)
+	go s.serve()
+	go s.expire()
+
+	return nil
+}
+
+// Stop stops serving requests, leaving the delegated prefixes in place.
+func (s *Server) Stop() {
+	close(s.stop)
+	s.conn.Close()
+	s.wg.Wait()
+}
+
+// Leases returns the current leases.
+func (s *Server) Leases() []Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leases := []Lease{}
+	for _, lease := range s.leases {
+		leases = append(leases, *lease)
+	}
+
+	return leases
+}
+
+// load reads the persisted leases and calls OnBind for those still valid.
+func (s *Server) load() error {
+	content, err := ioutil.ReadFile(s.LeasesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	leases := []Lease{}
+	err = json.Unmarshal(content, &leases)
+	if err != nil {
+		return err
+	}
+
+	for i := range leases {
+		lease := leases[i]
+
+		_, prefix, err := net.ParseCIDR(lease.Prefix)
+		if err != nil || time.Now().After(lease.Expiry) || !s.inPool(prefix) {
+			continue
+		}
+
+		err = s.OnBind(prefix, net.ParseIP(lease.Router))
+		if err != nil {
+			logger.Warn("Failed to restore delegated prefix", log.Ctx{"interface": s.Interface, "prefix": lease.Prefix, "err": err})
+			continue
+		}
+
+		s.leases[leaseKey(lease.DUID, lease.IAID)] = &lease
+	}
+
+	return nil
+}
+
+// save persists the leases. Must be called with the lock held.
+func (s *Server) save() error {
+	leases := []Lease{}
+	for _, lease := range s.leases {
+		leases = append(leases, *lease)
+	}
+
+	content, err := json.Marshal(leases)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.LeasesPath, content, 0644)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, 1500)
+	for {
+		n, src, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+
+			logger.Warn("Failed to read DHCPv6 request", log.Ctx{"interface": s.Interface, "err": err})
+			continue
+		}
+
+		// Requests from other interfaces are handled by their own server.
+		if src.Zone != s.Interface {
+			continue
+		}
+
+		msg, err := Decode(buf[:n])
+		if err != nil {
+			logger.Debug("Invalid DHCPv6 message", log.Ctx{"interface": s.Interface, "source": src.String(), "err": err})
+			continue
+		}
+
+		reply := s.handle(msg, src.IP)
+		if reply == nil {
+			continue
+		}
+
+		_, err = s.conn.WriteToUDP(reply.Encode(), src)
+		if err != nil {
+			logger.Warn("Failed to send DHCPv6 reply", log.Ctx{"interface": s.Interface, "destination": src.String(), "err": err})
+		}
+	}
+}
+
+// expire removes the leases which weren't renewed in time.
+func (s *Server) expire() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.expireLeases(time.Now())
+	}
+}
+
+// expireLeases removes the leases which expired at the given time along with their routes.
+func (s *Server) expireLeases(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for key, lease := range s.leases {
+		if now.Before(lease.Expiry) {
+			continue
+		}
+
+		s.unbind(lease)
+		delete(s.leases, key)
+		changed = true
+	}
+
+	if changed {
+		err := s.save()
+		if err != nil {
+			logger.Warn("Failed to save DHCPv6 leases", log.Ctx{"interface": s.Interface, "err": err})
+		}
+	}
+}
+
+// handle builds the reply to a client message, or returns nil if it shouldn't be answered.
+func (s *Server) handle(msg *Message, router net.IP) *Message {
+	// Prefixes are routed through the client's link-local address, which clients must send from.
+	if !router.IsLinkLocalUnicast() || router.To4() != nil {
+		return nil
+	}
+
+	clientID := msg.Get(OptionClientID)
+	if clientID == nil {
+		return nil
+	}
+
+	serverID := msg.Get(OptionServerID)
+	switch msg.Type {
+	case MessageSolicit, MessageRebind:
+		if serverID != nil {
+			return nil
+		}
+	case MessageRequest, MessageRenew, MessageRelease:
+		if !bytes.Equal(serverID, s.duid) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	ias, err := msg.IAPDs()
+	if err != nil || len(ias) == 0 {
+		return nil
+	}
+
+	reply := &Message{Type: MessageReply, TransactionID: msg.TransactionID}
+	reply.Add(OptionServerID, s.duid)
+	reply.Add(OptionClientID, clientID)
+
+	commit := msg.Type != MessageSolicit
+	if msg.Type == MessageSolicit {
+		if msg.Get(OptionRapidCommit) != nil {
+			commit = true
+			reply.Add(OptionRapidCommit, []byte{})
+		} else {
+			reply.Type = MessageAdvertise
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	duid := hex.EncodeToString(clientID)
+	for _, ia := range ias {
+		replyIA := IAPD{IAID: ia.IAID}
+
+		var prefix *net.IPNet
+		switch msg.Type {
+		case MessageSolicit, MessageRequest:
+			prefix = s.allocate(duid, ia, router)
+		case MessageRenew, MessageRebind:
+			lease := s.leases[leaseKey(duid, ia.IAID)]
+			if lease != nil {
+				_, prefix, _ = net.ParseCIDR(lease.Prefix)
+			}
+		case MessageRelease:
+			lease := s.leases[leaseKey(duid, ia.IAID)]
+			if lease != nil {
+				s.unbind(lease)
+				delete(s.leases, leaseKey(duid, ia.IAID))
+			}
+		}
+
+		if msg.Type == MessageRelease {
+			status := uint16(StatusSuccess)
+			replyIA.Status = &status
+		} else if prefix == nil {
+			// Rebinds for unknown bindings are left to other servers.
+			if msg.Type == MessageRebind {
+				return nil
+			}
+
+			status := uint16(StatusNoPrefixAvail)
+			if msg.Type == MessageRenew {
+				status = StatusNoBinding
+			}
+
+			replyIA.Status = &status
+		} else {
+			lifetime := uint32(s.Lifetime.Seconds())
+			replyIA.T1 = lifetime / 2
+			replyIA.T2 = lifetime / 5 * 4
+			replyIA.Prefixes = []IAPrefix{{Preferred: lifetime, Valid: lifetime, Prefix: prefix}}
+
+			if commit {
+				err := s.bind(duid, ia.IAID, prefix, router)
+				if err != nil {
+					logger.Warn("Failed to delegate prefix", log.Ctx{"interface": s.Interface, "prefix": prefix.String(), "err": err})
+					status := uint16(StatusNoPrefixAvail)
+					replyIA = IAPD{IAID: ia.IAID, Status: &status}
+				}
+			}
+		}
+
+		reply.Add(OptionIAPD, replyIA.Encode())
+	}
+
+	if commit {
+		err := s.save()
+		if err != nil {
+			logger.Warn("Failed to save DHCPv6 leases", log.Ctx{"interface": s.Interface, "err": err})
+		}
+	}
+
+	return reply
+}
+
+// allocate returns the prefix to delegate to the client, preferring its existing lease, then its
+// hint, or nil if none is available or the client reached its limit. Must be called with the lock
+// held.
+func (s *Server) allocate(duid string, ia IAPD, router net.IP) *net.IPNet {
+	lease := s.leases[leaseKey(duid, ia.IAID)]
+	if lease != nil {
+		_, prefix, err := net.ParseCIDR(lease.Prefix)
+		if err == nil {
+			return prefix
+		}
+	}
+
+	// Expired leases still count until they're removed along with their routes.
+	used := map[string]bool{}
+	clientLeases := 0
+	for _, lease := range s.leases {
+		used[lease.Prefix] = true
+
+		if lease.Router == router.String() {
+			clientLeases++
+		}
+	}
+
+	if s.ClientLimit > 0 && clientLeases >= s.ClientLimit {
+		return nil
+	}
+
+	for _, hint := range ia.Prefixes {
+		if hint.Prefix == nil || hint.Prefix.IP.IsUnspecified() {
+			continue
+		}
+
+		candidate := &net.IPNet{IP: hint.Prefix.IP.Mask(hint.Prefix.Mask), Mask: hint.Prefix.Mask}
+		if s.inPool(candidate) && !used[candidate.String()] {
+			return candidate
+		}
+	}
+
+	poolLength, _ := s.Pool.Mask.Size()
+	count := new(big.Int).Lsh(big.NewInt(1), uint(s.PrefixLength-poolLength))
+	if count.Cmp(big.NewInt(maxCandidates)) > 0 {
+		count = big.NewInt(maxCandidates)
+	}
+
+	base := new(big.Int).SetBytes(s.Pool.IP.To16())
+	for i := int64(0); i < count.Int64(); i++ {
+		offset := new(big.Int).Lsh(big.NewInt(i), uint(128-s.PrefixLength))
+		ip := new(big.Int).Add(base, offset).Bytes()
+
+		candidate := &net.IPNet{IP: make(net.IP, net.IPv6len), Mask: net.CIDRMask(s.PrefixLength, 128)}
+		copy(candidate.IP[net.IPv6len-len(ip):], ip)
+
+		if !used[candidate.String()] {
+			return candidate
+		}
+	}
+
+	return nil
+}
+
+// bind records the lease and routes the prefix to the client. Must be called with the lock held.
+func (s *Server) bind(duid string, iaid uint32, prefix *net.IPNet, router net.IP) error {
+	key := leaseKey(duid, iaid)
+
+	lease := s.leases[key]
+	if lease == nil || lease.Prefix != prefix.String() || lease.Router != router.String() {
+		err := s.OnBind(prefix, router)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.leases[key] = &Lease{
+		DUID:   duid,
+		IAID:   iaid,
+		Prefix: prefix.String(),
+		Router: router.String(),
+		Expiry: time.Now().Add(s.Lifetime),
+	}
+
+	return nil
+}
+
+// unbind removes the route to a delegated prefix. Must be called with the lock held.
+func (s *Server) unbind(lease *Lease) {
+	_, prefix, err := net.ParseCIDR(lease.Prefix)
+	if err != nil {
+		return
+	}
+
+	err = s.OnUnbind(prefix)
+	if err != nil {
+		logger.Warn("Failed to remove delegated prefix", log.Ctx{"interface": s.Interface, "prefix": lease.Prefix, "err": err})
+	}
+}
+
+// inPool checks whether the prefix has the delegated length and is part of the pool.
+func (s *Server) inPool(prefix *net.IPNet) bool {
+	length, _ := prefix.Mask.Size()
+	return length == s.PrefixLength && s.Pool.Contains(prefix.IP)
+}
+
+func leaseKey(duid string, iaid uint32) string {
+	return fmt.Sprintf("%s/%d", duid, iaid)
+}
@@ -0,0 +1,271 @@
+package dhcpv6
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer returns a server delegating /56 prefixes out of 2001:db8::/48 which records the routes
+// it sets up.
+func testServer(t *testing.T, clientLimit int) (*Server, map[string]string, func()) {
+	dir, err := ioutil.TempDir("", "lxd-dhcpv6-")
+	require.NoError(t, err)
+
+	_, pool, _ := net.ParseCIDR("2001:db8::/48")
+	routes := map[string]string{}
+
+	s := &Server{
+		Interface:    "lxdbr0",
+		Pool:         pool,
+		PrefixLength: 56,
+		Lifetime:     time.Hour,
+		LeasesPath:   filepath.Join(dir, "leases"),
+		ClientLimit:  clientLimit,
+		OnBind: func(prefix *net.IPNet, router net.IP) error {
+			routes[prefix.String()] = router.String()
+			return nil
+		},
+		OnUnbind: func(prefix *net.IPNet) error {
+			delete(routes, prefix.String())
+			return nil
+		},
+		duid:   []byte{0, 3, 0, 1, 0, 0x16, 0x3e, 0, 0, 1},
+		leases: map[string]*Lease{},
+	}
+
+	return s, routes, func() { os.RemoveAll(dir) }
+}
+
+// testRequest returns a client message requesting prefixes for the given IAIDs.
+func testRequest(msgType uint8, clientID []byte, serverID []byte, iaids ...uint32) *Message {
+	msg := &Message{Type: msgType, TransactionID: [3]byte{1, 2, 3}}
+	msg.Add(OptionClientID, clientID)
+	if serverID != nil {
+		msg.Add(OptionServerID, serverID)
+	}
+
+	for _, iaid := range iaids {
+		ia := IAPD{IAID: iaid}
+		msg.Add(OptionIAPD, ia.Encode())
+	}
+
+	return msg
+}
+
+// testReplyStatus returns the prefix and status of the IA_PDs of a reply.
+func testReplyStatus(t *testing.T, reply *Message) ([]string, []uint16) {
+	ias, err := reply.IAPDs()
+	require.NoError(t, err)
+
+	prefixes := []string{}
+	statuses := []uint16{}
+	for _, ia := range ias {
+		status := uint16(StatusSuccess)
+		if ia.Status != nil {
+			status = *ia.Status
+		}
+
+		statuses = append(statuses, status)
+		for _, p := range ia.Prefixes {
+			prefixes = append(prefixes, p.Prefix.String())
+		}
+	}
+
+	return prefixes, statuses
+}
+
+func TestServerHandle(t *testing.T) {
+	router := net.ParseIP("fe80::216:3eff:fe00:2")
+	client := []byte{0, 3, 0, 1, 0, 0x16, 0x3e, 0, 0, 2}
+
+	tests := []struct {
+		name     string
+		msg      func(s *Server) *Message
+		source   net.IP
+		reply    uint8
+		prefixes []string
+		statuses []uint16
+		routes   map[string]string
+	}{
+		{
+			name:   "solicit without rapid commit",
+			msg:    func(s *Server) *Message { return testRequest(MessageSolicit, client, nil, 1) },
+			source: router,
+			reply:  MessageAdvertise,
+			// Advertised but not bound yet.
+			prefixes: []string{"2001:db8::/56"},
+			statuses: []uint16{StatusSuccess},
+			routes:   map[string]string{},
+		},
+		{
+			name: "solicit with rapid commit",
+			msg: func(s *Server) *Message {
+				msg := testRequest(MessageSolicit, client, nil, 1)
+				msg.Add(OptionRapidCommit, []byte{})
+				return msg
+			},
+			source:   router,
+			reply:    MessageReply,
+			prefixes: []string{"2001:db8::/56"},
+			statuses: []uint16{StatusSuccess},
+			routes:   map[string]string{"2001:db8::/56": router.String()},
+		},
+		{
+			name:     "request",
+			msg:      func(s *Server) *Message { return testRequest(MessageRequest, client, s.duid, 1) },
+			source:   router,
+			reply:    MessageReply,
+			prefixes: []string{"2001:db8::/56"},
+			statuses: []uint16{StatusSuccess},
+			routes:   map[string]string{"2001:db8::/56": router.String()},
+		},
+		{
+			name:     "request beyond the client limit",
+			msg:      func(s *Server) *Message { return testRequest(MessageRequest, client, s.duid, 1, 2) },
+			source:   router,
+			reply:    MessageReply,
+			prefixes: []string{"2001:db8::/56"},
+			statuses: []uint16{StatusSuccess, StatusNoPrefixAvail},
+			routes:   map[string]string{"2001:db8::/56": router.String()},
+		},
+		{
+			name:     "request for another server",
+			msg:      func(s *Server) *Message { return testRequest(MessageRequest, client, []byte{1}, 1) },
+			source:   router,
+			statuses: nil,
+		},
+		{
+			name:     "request from a global address",
+			msg:      func(s *Server) *Message { return testRequest(MessageRequest, client, s.duid, 1) },
+			source:   net.ParseIP("2001:db8:ffff::2"),
+			statuses: nil,
+		},
+		{
+			name:     "renew without binding",
+			msg:      func(s *Server) *Message { return testRequest(MessageRenew, client, s.duid, 1) },
+			source:   router,
+			reply:    MessageReply,
+			prefixes: []string{},
+			statuses: []uint16{StatusNoBinding},
+			routes:   map[string]string{},
+		},
+		{
+			name:     "rebind without binding",
+			msg:      func(s *Server) *Message { return testRequest(MessageRebind, client, nil, 1) },
+			source:   router,
+			statuses: nil,
+		},
+		{
+			name:     "message without IA_PD",
+			msg:      func(s *Server) *Message { return testRequest(MessageSolicit, client, nil) },
+			source:   router,
+			statuses: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, routes, cleanup := testServer(t, 1)
+			defer cleanup()
+
+			reply := s.handle(test.msg(s), test.source)
+			if test.statuses == nil {
+				assert.Nil(t, reply)
+				return
+			}
+
+			require.NotNil(t, reply)
+			assert.Equal(t, test.reply, reply.Type)
+			assert.Equal(t, [3]byte{1, 2, 3}, reply.TransactionID)
+			assert.Equal(t, client, reply.Get(OptionClientID))
+			assert.Equal(t, s.duid, reply.Get(OptionServerID))
+
+			prefixes, statuses := testReplyStatus(t, reply)
+			assert.Equal(t, test.prefixes, prefixes)
+			assert.Equal(t, test.statuses, statuses)
+			assert.Equal(t, test.routes, routes)
+		})
+	}
+}
+
+func TestServerClientLimit(t *testing.T) {
+	s, routes, cleanup := testServer(t, 2)
+	defer cleanup()
+
+	router := net.ParseIP("fe80::2")
+	other := net.ParseIP("fe80::3")
+
+	// New DUIDs from the same link-local address count towards its limit.
+	for i, status := range []uint16{StatusSuccess, StatusSuccess, StatusNoPrefixAvail} {
+		reply := s.handle(testRequest(MessageRequest, []byte{byte(i)}, s.duid, 1), router)
+		require.NotNil(t, reply)
+
+		_, statuses := testReplyStatus(t, reply)
+		assert.Equal(t, []uint16{status}, statuses)
+	}
+
+	// Renewing an existing binding isn't affected.
+	reply := s.handle(testRequest(MessageRenew, []byte{0}, s.duid, 1), router)
+	_, statuses := testReplyStatus(t, reply)
+	assert.Equal(t, []uint16{StatusSuccess}, statuses)
+
+	// Other clients have their own limit.
+	reply = s.handle(testRequest(MessageRequest, []byte{9}, s.duid, 1), other)
+	_, statuses = testReplyStatus(t, reply)
+	assert.Equal(t, []uint16{StatusSuccess}, statuses)
+
+	assert.Len(t, routes, 3)
+}
+
+func TestServerReleaseAndExpiry(t *testing.T) {
+	s, routes, cleanup := testServer(t, 0)
+	defer cleanup()
+
+	router := net.ParseIP("fe80::2")
+	s.handle(testRequest(MessageRequest, []byte{1}, s.duid, 1), router)
+	s.handle(testRequest(MessageRequest, []byte{2}, s.duid, 1), router)
+	require.Len(t, routes, 2)
+
+	// Releasing a prefix removes its route.
+	reply := s.handle(testRequest(MessageRelease, []byte{1}, s.duid, 1), router)
+	_, statuses := testReplyStatus(t, reply)
+	assert.Equal(t, []uint16{StatusSuccess}, statuses)
+	assert.Equal(t, map[string]string{"2001:db8:0:100::/56": router.String()}, routes)
+
+	// Leases which aren't renewed expire along with their route.
+	s.expireLeases(time.Now())
+	assert.Len(t, routes, 1)
+
+	s.expireLeases(time.Now().Add(2 * time.Hour))
+	assert.Len(t, routes, 0)
+	assert.Len(t, s.Leases(), 0)
+}
+
+func TestServerLoad(t *testing.T) {
+	s, routes, cleanup := testServer(t, 0)
+	defer cleanup()
+
+	router := net.ParseIP("fe80::2")
+	s.handle(testRequest(MessageRequest, []byte{1}, s.duid, 1), router)
+	require.Len(t, s.Leases(), 1)
+
+	// A new server restores the persisted leases and their routes.
+	s2, routes2, cleanup2 := testServer(t, 0)
+	defer cleanup2()
+
+	s2.LeasesPath = s.LeasesPath
+	require.NoError(t, s2.load())
+	assert.Equal(t, routes, routes2)
+
+	leases := s2.Leases()
+	require.Len(t, leases, 1)
+	assert.Equal(t, "2001:db8::/56", leases[0].Prefix)
+	assert.Equal(t, router.String(), leases[0].Router)
+}
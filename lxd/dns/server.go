@@ -0,0 +1,321 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Peer represents a DNS server allowed to transfer a zone.
+type Peer struct {
+	Address string
+
+	// Name and base64 secret of the TSIG key (hmac-sha256) required from the peer, if any.
+	KeyName string
+	Key     string
+}
+
+// Zone represents an authoritative DNS zone served by LXD. Its records must start with the SOA,
+// whose serial is set by the server.
+type Zone struct {
+	Name    string
+	Records []dns.RR
+	Peers   []Peer
+
+	// Subnets allowed to query the zone, on top of the loopback and its peers.
+	Allowed []*net.IPNet
+}
+
+// ZoneLister returns the fully qualified names of the zones LXD is authoritative for.
+type ZoneLister func() ([]string, error)
+
+// ZoneRetriever returns the zone with the given fully qualified name, or nil if LXD isn't
+// authoritative for it.
+type ZoneRetriever func(name string) (*Zone, error)
+
+// Server is an authoritative DNS server for the LXD managed zones.
+type Server struct {
+	zones *zoneCache
+
+	address string
+	keys    map[string]string
+	servers []*dns.Server
+
+	mu sync.Mutex
+}
+
+// NewServer returns a new, not yet started, DNS server using the given zone lister and retriever.
+func NewServer(lister ZoneLister, retriever ZoneRetriever) *Server {
+	return &Server{zones: newZoneCache(lister, retriever)}
+}
+
+// Invalidate drops the cached zones, so that they're rebuilt on the next query. It must be called
+// whenever the zones or their records may have changed.
+func (s *Server) Invalidate() {
+	s.zones.invalidate()
+}
+
+// Start starts the DNS server on the given address (both UDP and TCP) using the given TSIG keys
+// (fully qualified key name to base64 secret). If the server is already running with the same
+// configuration this is a no-op, otherwise it's restarted.
+func (s *Server) Start(address string, keys map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.servers != nil && address == s.address && keysEqual(keys, s.keys) {
+		return nil
+	}
+
+	s.stop()
+
+	if address == "" {
+		return nil
+	}
+
+	// Default to the standard DNS port.
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "53")
+	}
+
+	for _, proto := range []string{"udp", "tcp"} {
+		srv := &dns.Server{
+			Addr:       address,
+			Net:        proto,
+			Handler:    &handler{zones: s.zones},
+			TsigSecret: keys,
+		}
+
+		started := make(chan error, 1)
+		srv.NotifyStartedFunc = func() { started <- nil }
+
+		go func() {
+			err := srv.ListenAndServe()
+			if err != nil {
+				started <- err
+			}
+		}()
+
+		err := <-started
+		if err != nil {
+			s.stop()
+			return fmt.Errorf("Failed to start DNS server on %s/%s: %v", address, proto, err)
+		}
+
+		s.servers = append(s.servers, srv)
+	}
+
+	s.address = address
+	s.keys = keys
+	logger.Infof("Started DNS server on %s", address)
+
+	return nil
+}
+
+// Stop stops the DNS server.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stop()
+}
+
+func (s *Server) stop() {
+	for _, srv := range s.servers {
+		err := srv.Shutdown()
+		if err != nil {
+			logger.Warnf("Failed to stop DNS server: %v", err)
+		}
+	}
+
+	s.servers = nil
+	s.address = ""
+	s.keys = nil
+}
+
+func keysEqual(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+type handler struct {
+	zones *zoneCache
+}
+
+// ServeDNS answers queries for the records of LXD managed zones and zone transfers to allowed peers.
+func (h *handler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	msg := dns.Msg{}
+	msg.SetReply(r)
+	msg.Authoritative = true
+
+	// We only support single questions.
+	if len(r.Question) != 1 {
+		msg.SetRcode(r, dns.RcodeFormatError)
+		w.WriteMsg(&msg)
+		return
+	}
+
+	question := r.Question[0]
+	zone, err := h.zones.zoneFor(question.Name)
+	if err != nil {
+		logger.Errorf("Failed to load DNS zone for %s: %v", question.Name, err)
+		msg.SetRcode(r, dns.RcodeServerFailure)
+		w.WriteMsg(&msg)
+		return
+	}
+
+	if zone == nil {
+		msg.Authoritative = false
+		msg.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(&msg)
+		return
+	}
+
+	if question.Qtype == dns.TypeAXFR || question.Qtype == dns.TypeIXFR {
+		h.transfer(w, r, zone)
+		return
+	}
+
+	if !queryAllowed(w.RemoteAddr(), zone) {
+		logger.Debugf("Refused DNS query for %s from %s", question.Name, w.RemoteAddr())
+		msg.Authoritative = false
+		msg.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(&msg)
+		return
+	}
+
+	found := false
+	for _, rr := range zone.Records {
+		if !strings.EqualFold(rr.Header().Name, question.Name) {
+			continue
+		}
+
+		found = true
+		if question.Qtype == dns.TypeANY || rr.Header().Rrtype == question.Qtype {
+			msg.Answer = append(msg.Answer, rr)
+		}
+	}
+
+	if !found {
+		msg.SetRcode(r, dns.RcodeNameError)
+	}
+
+	// Include the SOA for negative answers.
+	if len(msg.Answer) == 0 && len(zone.Records) > 0 {
+		msg.Ns = append(msg.Ns, zone.Records[0])
+	}
+
+	w.WriteMsg(&msg)
+}
+
+// queryAllowed returns whether the given client may query the records of a zone, which is the case
+// of the loopback, the peers of the zone and the subnets it allows.
+func queryAllowed(addr net.Addr, zone *Zone) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	remote := net.ParseIP(host)
+	if remote == nil {
+		return false
+	}
+
+	if remote.IsLoopback() {
+		return true
+	}
+
+	for _, peer := range zone.Peers {
+		if remote.Equal(net.ParseIP(peer.Address)) {
+			return true
+		}
+	}
+
+	for _, subnet := range zone.Allowed {
+		if subnet.Contains(remote) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transfer sends the whole zone to an allowed peer.
+func (h *handler) transfer(w dns.ResponseWriter, r *dns.Msg, zone *Zone) {
+	refuse := func(reason string) {
+		logger.Warnf("Refused DNS zone transfer of %s to %s: %s", zone.Name, w.RemoteAddr(), reason)
+		msg := dns.Msg{}
+		msg.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(&msg)
+	}
+
+	if w.RemoteAddr().Network() != "tcp" {
+		refuse("transfers require TCP")
+		return
+	}
+
+	host, _, err := net.SplitHostPort(w.RemoteAddr().String())
+	if err != nil {
+		refuse(err.Error())
+		return
+	}
+
+	remote := net.ParseIP(host)
+	var peer *Peer
+	for i := range zone.Peers {
+		if remote != nil && remote.Equal(net.ParseIP(zone.Peers[i].Address)) {
+			peer = &zone.Peers[i]
+			break
+		}
+	}
+
+	if peer == nil {
+		refuse("not a peer of the zone")
+		return
+	}
+
+	if peer.Key != "" {
+		tsig := r.IsTsig()
+		if tsig == nil || !strings.EqualFold(tsig.Hdr.Name, dns.Fqdn(peer.KeyName)) || w.TsigStatus() != nil {
+			refuse("invalid or missing TSIG signature")
+			return
+		}
+	}
+
+	records := append([]dns.RR{}, zone.Records...)
+	if len(records) > 0 {
+		// Transfers end with the SOA record.
+		records = append(records, records[0])
+	}
+
+	ch := make(chan *dns.Envelope)
+	tr := new(dns.Transfer)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tr.Out(w, r, ch)
+	}()
+
+	ch <- &dns.Envelope{RR: records}
+	close(ch)
+
+	err = <-errCh
+	if err != nil {
+		logger.Errorf("Failed DNS zone transfer of %s to %s: %v", zone.Name, w.RemoteAddr(), err)
+	}
+
+	w.Hijack()
+}
@@ -0,0 +1,142 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// zoneCacheExpiry bounds how long cached zones are served without being rebuilt, so that the
+// changes LXD isn't told about, such as dynamic leases or instances of other cluster members, are
+// eventually published.
+const zoneCacheExpiry = time.Minute
+
+// cachedZone is a zone built by the retriever along with the serial of its SOA.
+type cachedZone struct {
+	zone   *Zone
+	serial uint32
+	loaded time.Time
+	stale  bool
+}
+
+// zoneCache caches the zones served by the DNS server, rebuilding them once invalidated or expired.
+type zoneCache struct {
+	lister    ZoneLister
+	retriever ZoneRetriever
+
+	names       map[string]bool
+	namesLoaded time.Time
+	zones       map[string]*cachedZone
+
+	mu sync.Mutex
+}
+
+func newZoneCache(lister ZoneLister, retriever ZoneRetriever) *zoneCache {
+	return &zoneCache{
+		lister:    lister,
+		retriever: retriever,
+		zones:     map[string]*cachedZone{},
+	}
+}
+
+// invalidate marks all the cached zones as stale. They keep their serial, which is only bumped if
+// their records changed once rebuilt.
+func (c *zoneCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = nil
+	for _, entry := range c.zones {
+		entry.stale = true
+	}
+}
+
+// zoneFor returns the most specific zone containing the given name.
+func (c *zoneCache) zoneFor(name string) (*Zone, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.names == nil || time.Since(c.namesLoaded) > zoneCacheExpiry {
+		names, err := c.lister()
+		if err != nil {
+			return nil, err
+		}
+
+		c.names = map[string]bool{}
+		for _, name := range names {
+			c.names[strings.ToLower(dns.Fqdn(name))] = true
+		}
+
+		c.namesLoaded = time.Now()
+	}
+
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	for i := range labels {
+		zoneName := dns.Fqdn(strings.Join(labels[i:], "."))
+		if c.names[zoneName] {
+			return c.zone(zoneName)
+		}
+	}
+
+	return nil, nil
+}
+
+// zone returns the zone with the given name, rebuilding it if needed.
+func (c *zoneCache) zone(name string) (*Zone, error) {
+	entry := c.zones[name]
+	if entry != nil && !entry.stale && time.Since(entry.loaded) <= zoneCacheExpiry {
+		return entry.zone, nil
+	}
+
+	zone, err := c.retriever(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if zone == nil {
+		delete(c.zones, name)
+		return nil, nil
+	}
+
+	// The serial is a change counter, bumped whenever the records of the zone change. It
+	// starts from the current time so that it keeps increasing across restarts.
+	serial := uint32(time.Now().Unix())
+	if entry != nil {
+		serial = entry.serial
+		if !recordsEqual(entry.zone.Records, zone.Records) {
+			serial++
+		}
+	}
+
+	if len(zone.Records) > 0 {
+		soa, ok := zone.Records[0].(*dns.SOA)
+		if ok {
+			soa.Serial = serial
+		}
+	}
+
+	c.zones[name] = &cachedZone{zone: zone, serial: serial, loaded: time.Now()}
+
+	return zone, nil
+}
+
+// recordsEqual returns whether two versions of a zone have the same records, besides their SOA.
+func recordsEqual(a []dns.RR, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if i == 0 {
+			continue
+		}
+
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+
+	return true
+}
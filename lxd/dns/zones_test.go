@@ -0,0 +1,86 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneCache(t *testing.T) {
+	address := "10.0.0.2"
+	builds := 0
+
+	cache := newZoneCache(func() ([]string, error) {
+		return []string{"lxd.example.net."}, nil
+	}, func(name string) (*Zone, error) {
+		builds++
+
+		header := func(rrtype uint16) dns.RR_Header {
+			return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: 300}
+		}
+
+		return &Zone{Name: name, Records: []dns.RR{
+			&dns.SOA{Hdr: header(dns.TypeSOA), Ns: "ns.", Mbox: "hostmaster.", Refresh: 120, Retry: 60, Expire: 86400, Minttl: 30},
+			&dns.A{Hdr: header(dns.TypeA), A: net.ParseIP(address)},
+		}}, nil
+	})
+
+	serial := func(zone *Zone) uint32 {
+		return zone.Records[0].(*dns.SOA).Serial
+	}
+
+	// Names within the zone are served from the cache.
+	zone, err := cache.zoneFor("c1.lxd.example.net.")
+	require.NoError(t, err)
+	require.NotNil(t, zone)
+	first := serial(zone)
+
+	zone, err = cache.zoneFor("C2.LXD.example.net.")
+	require.NoError(t, err)
+	require.NotNil(t, zone)
+	assert.Equal(t, 1, builds)
+
+	zone, err = cache.zoneFor("example.net.")
+	require.NoError(t, err)
+	assert.Nil(t, zone)
+
+	// Rebuilding an unchanged zone keeps its serial.
+	cache.invalidate()
+	zone, err = cache.zoneFor("lxd.example.net.")
+	require.NoError(t, err)
+	assert.Equal(t, 2, builds)
+	assert.Equal(t, first, serial(zone))
+
+	// While changed records bump it.
+	address = "10.0.0.3"
+	cache.invalidate()
+	zone, err = cache.zoneFor("lxd.example.net.")
+	require.NoError(t, err)
+	assert.Equal(t, first+1, serial(zone))
+}
+
+func TestQueryAllowed(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/24")
+	require.NoError(t, err)
+
+	zone := &Zone{
+		Peers:   []Peer{{Address: "192.0.2.53"}},
+		Allowed: []*net.IPNet{subnet},
+	}
+
+	for address, allowed := range map[string]bool{
+		"127.0.0.1":    true,
+		"[::1]":        true,
+		"192.0.2.53":   true,
+		"10.0.0.42":    true,
+		"192.0.2.54":   false,
+		"198.51.100.1": false,
+	} {
+		addr, err := net.ResolveUDPAddr("udp", address+":5353")
+		require.NoError(t, err)
+		assert.Equal(t, allowed, queryAllowed(addr, zone), address)
+	}
+}
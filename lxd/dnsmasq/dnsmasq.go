@@ -31,6 +31,16 @@ var ConfigMutex sync.Mutex
 
 // UpdateStaticEntry writes a single dhcp-host line for a network/instance combination.
 func UpdateStaticEntry(network string, projectName string, instanceName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string) error {
+	return writeStaticEntry(network, project.Prefix(projectName, instanceName), netConfig, hwaddr, ipv4Address, ipv6Address, instanceName)
+}
+
+// UpdateReservationEntry writes a single dhcp-host line for a network DHCP reservation.
+func UpdateReservationEntry(network string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string, hostname string) error {
+	fileName := fmt.Sprintf("reservation.%s", strings.Replace(strings.ToLower(hwaddr), ":", "-", -1))
+	return writeStaticEntry(network, fileName, netConfig, hwaddr, ipv4Address, ipv6Address, hostname)
+}
+
+func writeStaticEntry(network string, fileName string, netConfig map[string]string, hwaddr string, ipv4Address string, ipv6Address string, hostname string) error {
 	hwaddr = strings.ToLower(hwaddr)
 	line := hwaddr
 
@@ -43,15 +53,15 @@ func UpdateStaticEntry(network string, projectName string, instanceName string,
 		line += fmt.Sprintf(",[%s]", ipv6Address)
 	}
 
-	if netConfig["dns.mode"] == "" || netConfig["dns.mode"] == "managed" {
-		line += fmt.Sprintf(",%s", instanceName)
+	if hostname != "" && (netConfig["dns.mode"] == "" || netConfig["dns.mode"] == "managed") {
+		line += fmt.Sprintf(",%s", hostname)
 	}
 
 	if line == hwaddr {
 		return nil
 	}
 
-	err := ioutil.WriteFile(shared.VarPath("networks", network, "dnsmasq.hosts", project.Prefix(projectName, instanceName)), []byte(line+"\n"), 0644)
+	err := ioutil.WriteFile(shared.VarPath("networks", network, "dnsmasq.hosts", fileName), []byte(line+"\n"), 0644)
 	if err != nil {
 		return err
 	}
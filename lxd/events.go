@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -18,25 +23,37 @@ var eventsCmd = APIEndpoint{
 }
 
 type eventsServe struct {
-	req *http.Request
-	d   *Daemon
+	req    *http.Request
+	d      *Daemon
+	filter *events.ListenerFilter
 }
 
 func (r *eventsServe) Render(w http.ResponseWriter) error {
-	return eventsSocket(r.d, r.req, w)
+	return eventsSocket(r.d, r.req, w, r.filter)
 }
 
 func (r *eventsServe) String() string {
 	return "event handler"
 }
 
-func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
+func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter, filter *events.ListenerFilter) error {
 	project := projectParam(r)
 	typeStr := r.FormValue("type")
 	if typeStr == "" {
 		typeStr = "logging,operation,lifecycle"
 	}
 
+	// Clients restricted to projects don't get the log messages of the server, which aren't
+	// tied to a project.
+	types := []string{}
+	for _, eventType := range strings.Split(typeStr, ",") {
+		if eventType == "logging" && len(requestProjects(r)) > 0 {
+			continue
+		}
+
+		types = append(types, eventType)
+	}
+
 	// Upgrade the connection to websocket
 	c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -59,7 +76,7 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
 	// local events.
-	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r))
+	listener, err := d.events.AddListener(project, c, types, serverName, isClusterNotification(r), filter)
 	if err != nil {
 		return err
 	}
@@ -94,5 +111,57 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 }
 
 func eventsGet(d *Daemon, r *http.Request) response.Response {
-	return &eventsServe{req: r, d: d}
+	if !requestHasProject(r, projectParam(r)) {
+		return response.Forbidden(nil)
+	}
+
+	filter, err := eventsFilter(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return &eventsServe{req: r, d: d, filter: filter}
+}
+
+// eventsFilter parses the resource, since and replay query parameters.
+func eventsFilter(r *http.Request) (*events.ListenerFilter, error) {
+	filter := events.ListenerFilter{}
+
+	resourceStr := r.FormValue("resource")
+	if resourceStr != "" {
+		filter.Resources = strings.Split(resourceStr, ",")
+
+		for _, pattern := range filter.Resources {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return nil, fmt.Errorf("Invalid resource pattern %q: %v", pattern, err)
+			}
+		}
+	}
+
+	sinceStr := r.FormValue("since")
+	if sinceStr != "" {
+		since, err := time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid since timestamp %q: %v", sinceStr, err)
+		}
+
+		filter.Since = since
+	}
+
+	replayStr := r.FormValue("replay")
+	if replayStr != "" {
+		replay, err := strconv.Atoi(replayStr)
+		if err != nil || replay < 0 {
+			return nil, fmt.Errorf("Invalid replay count %q", replayStr)
+		}
+
+		if replay > events.HistorySize {
+			replay = events.HistorySize
+		}
+
+		filter.Replay = replay
+	}
+
+	return &filter, nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +17,9 @@ import (
 	"github.com/lxc/lxd/shared/logger"
 )
 
+// HistorySize is the number of recent events kept around to be replayed to new listeners.
+const HistorySize = 1000
+
 // Server represents an instance of an event server.
 type Server struct {
 	debug   bool
@@ -22,6 +27,37 @@ type Server struct {
 
 	listeners map[string]*Listener
 	lock      sync.Mutex
+
+	// Ring buffer of the recent non-logging events, oldest at historyNext once full.
+	history     []historyEntry
+	historyNext int
+
+	// Called with every local event, if set.
+	hook func(group string, event api.Event)
+}
+
+type historyEntry struct {
+	group     string
+	event     api.Event
+	isForward bool
+}
+
+// ListenerFilter restricts the events sent to a listener and requests a replay of the recent ones.
+type ListenerFilter struct {
+	// Patterns matched against the lifecycle event source or the operation resources, as
+	// understood by path.Match. Logging events are dropped when set.
+	Resources []string
+
+	// Replay the recent events newer than this.
+	Since time.Time
+
+	// Replay at most this many recent events.
+	Replay int
+}
+
+// replays returns whether the filter requests a replay.
+func (f *ListenerFilter) replays() bool {
+	return f != nil && (!f.Since.IsZero() || f.Replay > 0)
 }
 
 // NewServer returns a new event server.
@@ -35,8 +71,20 @@ func NewServer(debug bool, verbose bool) *Server {
 	return server
 }
 
+// SetHook sets a function called with every event broadcast by this server, excluding those
+// forwarded from other cluster members.
+func (s *Server) SetHook(hook func(group string, event api.Event)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.hook = hook
+}
+
 // AddListener creates and returns a new event listener.
-func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+//
+// When the filter requests a replay, the matching recent events are sent to the listener before
+// any new one.
+func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool, filter *ListenerFilter) (*Listener, error) {
 	listener := &Listener{
 		group:        group,
 		connection:   connection,
@@ -47,18 +95,83 @@ func (s *Server) AddListener(group string, connection *websocket.Conn, messageTy
 		id:           uuid.NewRandom().String(),
 	}
 
+	if filter != nil {
+		listener.resources = filter.Resources
+	}
+
 	s.lock.Lock()
-	defer s.lock.Unlock()
 
 	if s.listeners[listener.id] != nil {
+		s.lock.Unlock()
 		return nil, fmt.Errorf("A listener with id '%s' already exists", listener.id)
 	}
 
+	replay := []api.Event{}
+	if filter.replays() {
+		replay = s.replay(listener, filter)
+
+		// Hold back the new events until the replay is done.
+		listener.lock.Lock()
+	}
+
 	s.listeners[listener.id] = listener
+	s.lock.Unlock()
+
+	if filter.replays() {
+		defer listener.lock.Unlock()
+
+		for _, event := range replay {
+			if !s.send(listener, event) {
+				break
+			}
+		}
+	}
 
 	return listener, nil
 }
 
+// replay returns the recent events matching the listener and filter, oldest first. It must be
+// called with the server lock held.
+func (s *Server) replay(listener *Listener, filter *ListenerFilter) []api.Event {
+	history := append(append([]historyEntry{}, s.history[s.historyNext:]...), s.history[:s.historyNext]...)
+
+	events := []api.Event{}
+	for _, entry := range history {
+		if !filter.Since.IsZero() && !entry.event.Timestamp.After(filter.Since) {
+			continue
+		}
+
+		if !listener.matches(entry.group, entry.event, entry.isForward) {
+			continue
+		}
+
+		events = append(events, entry.event)
+	}
+
+	if filter.Replay > 0 && len(events) > filter.Replay {
+		events = events[len(events)-filter.Replay:]
+	}
+
+	return events
+}
+
+// record adds an event to the history. It must be called with the server lock held.
+func (s *Server) record(group string, event api.Event, isForward bool) {
+	// Log messages would quickly push everything else out.
+	if event.Type == "logging" {
+		return
+	}
+
+	entry := historyEntry{group: group, event: event, isForward: isForward}
+	if len(s.history) < HistorySize {
+		s.history = append(s.history, entry)
+		return
+	}
+
+	s.history[s.historyNext] = entry
+	s.historyNext = (s.historyNext + 1) % HistorySize
+}
+
 // SendLifecycle broadcasts a lifecycle event.
 func (s *Server) SendLifecycle(group, action, source string,
 	context map[string]interface{}) error {
@@ -111,17 +224,11 @@ func (s *Server) Forward(id int64, event api.Event) {
 
 func (s *Server) broadcast(group string, event api.Event, isForward bool) error {
 	s.lock.Lock()
+	s.record(group, event, isForward)
+
 	listeners := s.listeners
 	for _, listener := range listeners {
-		if group != "" && listener.group != "*" && group != listener.group {
-			continue
-		}
-
-		if isForward && listener.noForward {
-			continue
-		}
-
-		if !shared.StringInSlice(event.Type, listener.messageTypes) {
+		if !listener.matches(group, event, isForward) {
 			continue
 		}
 
@@ -135,46 +242,62 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 			listener.lock.Lock()
 			defer listener.lock.Unlock()
 
-			// Make sure we're not done already
-			if listener.done {
-				return
-			}
+			s.send(listener, event)
+		}(listener, event)
+	}
 
-			// Set the Location to the expected serverName
-			if event.Location == "" {
-				eventCopy := api.Event{}
-				err := shared.DeepCopy(&event, &eventCopy)
-				if err != nil {
-					return
-				}
-				eventCopy.Location = listener.location
+	hook := s.hook
+	s.lock.Unlock()
 
-				event = eventCopy
-			}
+	if hook != nil && !isForward {
+		hook(group, event)
+	}
 
-			body, err := json.Marshal(event)
-			if err != nil {
-				return
-			}
+	return nil
+}
 
-			err = listener.connection.WriteMessage(websocket.TextMessage, body)
-			if err != nil {
-				// Remove the listener from the list
-				s.lock.Lock()
-				delete(s.listeners, listener.id)
-				s.lock.Unlock()
-
-				// Disconnect the listener
-				listener.connection.Close()
-				listener.active <- false
-				listener.done = true
-				logger.Debugf("Disconnected event listener: %s", listener.id)
-			}
-		}(listener, event)
+// send writes an event to a listener, disconnecting it on failure. It must be called with the
+// listener lock held and returns whether the listener is still connected.
+func (s *Server) send(listener *Listener, event api.Event) bool {
+	// Make sure we're not done already
+	if listener.done {
+		return false
 	}
-	s.lock.Unlock()
 
-	return nil
+	// Set the Location to the expected serverName
+	if event.Location == "" {
+		eventCopy := api.Event{}
+		err := shared.DeepCopy(&event, &eventCopy)
+		if err != nil {
+			return true
+		}
+		eventCopy.Location = listener.location
+
+		event = eventCopy
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return true
+	}
+
+	err = listener.connection.WriteMessage(websocket.TextMessage, body)
+	if err != nil {
+		// Remove the listener from the list
+		s.lock.Lock()
+		delete(s.listeners, listener.id)
+		s.lock.Unlock()
+
+		// Disconnect the listener
+		listener.connection.Close()
+		listener.active <- false
+		listener.done = true
+		logger.Debugf("Disconnected event listener: %s", listener.id)
+
+		return false
+	}
+
+	return true
 }
 
 // Listener describes an event listener.
@@ -192,6 +315,71 @@ type Listener struct {
 	// nodes. It only used by listeners created internally by LXD nodes
 	// connecting to other LXD nodes to get their local events only.
 	noForward bool
+
+	// Patterns the resources of the events must match, if any.
+	resources []string
+}
+
+// matches returns whether an event should be sent to the listener.
+func (e *Listener) matches(group string, event api.Event, isForward bool) bool {
+	if group != "" && e.group != "*" && group != e.group {
+		return false
+	}
+
+	if isForward && e.noForward {
+		return false
+	}
+
+	if !shared.StringInSlice(event.Type, e.messageTypes) {
+		return false
+	}
+
+	if len(e.resources) == 0 {
+		return true
+	}
+
+	for _, resource := range eventResources(event) {
+		for _, pattern := range e.resources {
+			match, _ := path.Match(pattern, resource)
+			if match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// eventResources returns the URLs of the resources an event is about, without query string.
+func eventResources(event api.Event) []string {
+	resources := []string{}
+
+	switch event.Type {
+	case "lifecycle":
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil {
+			return nil
+		}
+
+		resources = append(resources, lifecycle.Source)
+	case "operation":
+		op := api.Operation{}
+		err := json.Unmarshal(event.Metadata, &op)
+		if err != nil {
+			return nil
+		}
+
+		for _, urls := range op.Resources {
+			resources = append(resources, urls...)
+		}
+	}
+
+	for i, resource := range resources {
+		resources[i] = strings.SplitN(resource, "?", 2)[0]
+	}
+
+	return resources
 }
 
 // MessageTypes returns a list of message types the listener will be notified of.
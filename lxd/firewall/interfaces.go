@@ -1,11 +1,15 @@
 package firewall
 
 import (
+	"fmt"
 	"net"
+	"os/exec"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
 	"github.com/lxc/lxd/lxd/iptables"
+	"github.com/lxc/lxd/lxd/nftables"
+	"github.com/lxc/lxd/shared"
 )
 
 // Firewall represents an LXD firewall.
@@ -24,15 +28,49 @@ type Firewall interface {
 
 	// Network Functions
 	NetworkSetupAllowForwarding(family firewallConsts.Family, name string, actionType firewallConsts.Action) error
-	NetworkSetupNAT(family firewallConsts.Family, name string, location firewallConsts.Location, args ...string) error
+	NetworkSetupNAT(family firewallConsts.Family, name string, location firewallConsts.Location, subnet *net.IPNet, srcIP net.IP, exclude []*net.IPNet) error
 	NetworkSetupIPv4DNSOverrides(name string) error
 	NetworkSetupIPv4DHCPWorkaround(name string) error
 	NetworkSetupIPv6DNSOverrides(name string) error
 	NetworkSetupTunnelNAT(name string, location firewallConsts.Location, overlaySubnet net.IPNet) error
+	NetworkSetupForwardNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddress net.IP, targetPort string) error
+	NetworkClearForwards(family firewallConsts.Family, name string) error
+	NetworkSetupLoadBalancerNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddresses []net.IP, targetPorts []string, sessionAffinity bool) error
+	NetworkClearLoadBalancers(family firewallConsts.Family, name string) error
+	NetworkHasRules(family firewallConsts.Family, name string) (bool, error)
 }
 
-// New returns an appropriate firewall implementation.
-func New() Firewall {
-	// TODO: Issue #6223: add startup logic to choose xtables or nftables
-	return iptables.XTables{}
+// New returns the firewall implementation for the given driver name. An empty name or "auto"
+// prefers xtables when the iptables tools are present and falls back to nftables otherwise.
+func New(driver string) (Firewall, error) {
+	switch driver {
+	case "", "auto":
+		_, err := exec.LookPath("iptables")
+		if err != nil && nftables.Available() == nil {
+			return nftables.NFTables{}, nil
+		}
+
+		return iptables.XTables{}, nil
+	case "xtables":
+		return iptables.XTables{}, nil
+	case "nftables":
+		err := nftables.Available()
+		if err != nil {
+			return nil, fmt.Errorf("The nftables firewall driver isn't available: %v", err)
+		}
+
+		return nftables.NFTables{}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown firewall driver %q (must be one of auto, xtables or nftables)", driver)
+}
+
+// ValidateDriver checks that the given firewall driver name is known. Whether the driver can be
+// used on the system is only checked when loading it.
+func ValidateDriver(driver string) error {
+	if !shared.StringInSlice(driver, []string{"", "auto", "xtables", "nftables"}) {
+		return fmt.Errorf("Unknown firewall driver %q (must be one of auto, xtables or nftables)", driver)
+	}
+
+	return nil
 }
@@ -28,6 +28,7 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/node"
@@ -67,7 +68,8 @@ var imageCmd = APIEndpoint{
 var imageExportCmd = APIEndpoint{
 	Path: "images/{fingerprint}/export",
 
-	Get: APIEndpointAction{Handler: imageExport, AllowUntrusted: true},
+	Get:  APIEndpointAction{Handler: imageExport, AllowUntrusted: true},
+	Post: APIEndpointAction{Handler: imageExportPost, AccessHandler: AllowProjectPermission("images", "manage-images")},
 }
 
 var imageSecretCmd = APIEndpoint{
@@ -226,7 +228,7 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 
 				if totalSize > 0 {
 					percent = value
-					processed = totalSize * (percent / 100.0)
+					processed = totalSize * percent / 100
 				} else {
 					processed = value
 				}
@@ -288,6 +290,36 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 		return nil, err
 	}
 	info.Size = fi.Size()
+
+	// Virtual machine images carry their root disk as a separate qcow2 file.
+	var rootfsFile string
+	if c.Type() == instancetype.VM {
+		info.Type = "virtual-machine"
+
+		rootfsFile = imageFile.Name() + ".rootfs"
+		defer os.Remove(rootfsFile)
+
+		shared.SetProgressMetadata(metadata, "create_image_from_container_pack", "Image root disk", 0, 0, 0)
+		op.UpdateMetadata(metadata)
+
+		err = imageExportRootDisk(d, c, compress, rootfsFile, op)
+		if err != nil {
+			return nil, err
+		}
+
+		rootfs, err := os.Open(rootfsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := io.Copy(sha256, rootfs)
+		rootfs.Close()
+		if err != nil {
+			return nil, err
+		}
+		info.Size += size
+	}
+
 	info.Fingerprint = fmt.Sprintf("%x", sha256.Sum(nil))
 
 	_, _, err = d.cluster.ImageGet(project, info.Fingerprint, false, true)
@@ -306,6 +338,18 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 		return nil, err
 	}
 
+	if rootfsFile != "" {
+		err = shared.FileMove(rootfsFile, finalName+".rootfs")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = imageEncryptFiles(d, info.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
 	info.Architecture, _ = osarch.ArchitectureName(c.Architecture())
 	info.Properties = req.Properties
 
@@ -318,6 +362,57 @@ func imgPostContInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operati
 	return &info, nil
 }
 
+// imageExportRootDisk converts the root disk of a virtual machine to a qcow2 file, compressed with
+// zstd (or qcow2's default zlib for other algorithms) unless compression is "none". Zeroed blocks
+// are left out of the file.
+func imageExportRootDisk(d *Daemon, inst instance.Instance, compress string, target string, op *operations.Operation) error {
+	pool, err := storagePools.GetPoolByInstance(d.State(), inst)
+	if err != nil {
+		return err
+	}
+
+	if inst.IsSnapshot() {
+		ourMount, err := pool.MountInstanceSnapshot(inst, op)
+		if err != nil {
+			return err
+		}
+
+		if ourMount {
+			defer pool.UnmountInstanceSnapshot(inst, op)
+		}
+	} else {
+		ourMount, err := pool.MountInstance(inst, op)
+		if err != nil {
+			return err
+		}
+
+		if ourMount {
+			defer pool.UnmountInstance(inst, op)
+		}
+	}
+
+	diskPath, err := pool.GetInstanceDisk(inst)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"convert", "-O", "qcow2", "-S", "4096"}
+	if compress != "none" {
+		args = append(args, "-c")
+		if compress == "zstd" {
+			args = append(args, "-o", "compression_type=zstd")
+		}
+	}
+	args = append(args, diskPath, target)
+
+	_, err = shared.RunCommand("qemu-img", args...)
+	if err != nil {
+		return errors.Wrap(err, "Failed converting the root disk to qcow2")
+	}
+
+	return nil
+}
+
 func imgPostRemoteInfo(d *Daemon, req api.ImagesPost, op *operations.Operation, project string) (*api.Image, error) {
 	var err error
 	var hash string
@@ -576,6 +671,11 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 		}
 	}
 
+	err = imageEncryptFiles(d, info.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
 	info.Architecture = imageMeta.Architecture
 	info.CreatedAt = time.Unix(imageMeta.CreationDate, 0)
 	info.ExpiresAt = time.Unix(imageMeta.ExpiryDate, 0)
@@ -608,6 +708,12 @@ func getImgPostInfo(d *Daemon, r *http.Request, builddir string, project string,
 			return &info, fmt.Errorf("Image with same fingerprint already exists")
 		}
 	} else {
+		// Check the image signature
+		err = imageVerifySignature(d, info.Fingerprint, info.Properties)
+		if err != nil {
+			return nil, err
+		}
+
 		// Create the database entry
 		err = d.cluster.ImageInsert(project, info.Fingerprint, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, info.Type)
 		if err != nil {
@@ -711,7 +817,7 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 		imageUpload = true
 	}
 
-	if !imageUpload && !shared.StringInSlice(req.Source.Type, []string{"container", "snapshot", "image", "url"}) {
+	if !imageUpload && !shared.StringInSlice(req.Source.Type, []string{"container", "snapshot", "image", "url", "build"}) {
 		cleanup(builddir, post)
 		return response.InternalError(fmt.Errorf("Invalid images JSON"))
 	}
@@ -753,6 +859,9 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			} else if req.Source.Type == "url" {
 				/* Processing image copy from URL */
 				info, err = imgPostURLInfo(d, req, op, project)
+			} else if req.Source.Type == "build" {
+				/* Processing image build */
+				info, err = imgPostBuildInfo(d, r, req, op, builddir)
 			} else {
 				/* Processing image creation from container */
 				imagePublishLock.Lock()
@@ -771,44 +880,99 @@ func imagesPost(d *Daemon, r *http.Request) response.Response {
 			return err
 		}
 
-		// Apply any provided alias
-		for _, alias := range req.Aliases {
-			_, _, err := d.cluster.ImageAliasGet(project, alias.Name, true)
-			if err != db.ErrNoSuchObject {
-				if err != nil {
-					return errors.Wrapf(err, "Fetch image alias %q", alias.Name)
-				}
+		return imagesPostFinish(d, project, req.Aliases, info.Fingerprint)
+	}
 
-				return fmt.Errorf("Alias already exists: %s", alias.Name)
-			}
+	opType := db.OperationImageDownload
+	if !imageUpload && req.Source.Type == "build" {
+		opType = db.OperationImageBuild
+	}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, opType, nil, nil, run, nil, nil)
+	if err != nil {
+		cleanup(builddir, post)
+		return response.InternalError(err)
+	}
+
+	// Downloads from a remote server or URL are resumed if interrupted by a daemon restart.
+	if !imageUpload && shared.StringInSlice(req.Source.Type, []string{"image", "url"}) {
+		err = op.SetResumeData(req)
+		if err != nil {
+			logger.Warn("Failed to persist the image download", log.Ctx{"err": err, "operation": op.ID()})
+		}
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// imagesPostResume starts again an image download which was interrupted by a daemon restart.
+func imagesPostResume(d *Daemon, project string, req api.ImagesPost) error {
+	run := func(op *operations.Operation) error {
+		var err error
+		var info *api.Image
+
+		if req.Source.Type == "image" {
+			info, err = imgPostRemoteInfo(d, req, op, project)
+		} else {
+			info, err = imgPostURLInfo(d, req, op, project)
+		}
+		if err != nil {
+			return err
+		}
+
+		return imagesPostFinish(d, project, req.Aliases, info.Fingerprint)
+	}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDownload, nil, nil, run, nil, nil)
+	if err != nil {
+		return err
+	}
 
-			id, _, err := d.cluster.ImageGet(project, info.Fingerprint, false, false)
+	err = op.SetResumeData(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = op.Run()
+	return err
+}
+
+// imagesPostFinish adds the aliases of a new image and syncs it between the cluster members.
+func imagesPostFinish(d *Daemon, project string, aliases []api.ImageAlias, fingerprint string) error {
+	// Apply any provided alias
+	for _, alias := range aliases {
+		_, entry, err := d.cluster.ImageAliasGet(project, alias.Name, true)
+		if err != db.ErrNoSuchObject {
 			if err != nil {
-				return errors.Wrapf(err, "Fetch image %q", info.Fingerprint)
+				return errors.Wrapf(err, "Fetch image alias %q", alias.Name)
 			}
 
-			err = d.cluster.ImageAliasAdd(project, alias.Name, id, alias.Description)
-			if err != nil {
-				return errors.Wrapf(err, "Add new image alias to the database")
+			// Image builds move existing aliases to the new image.
+			if entry.Target == fingerprint {
+				continue
 			}
+
+			return fmt.Errorf("Alias already exists: %s", alias.Name)
 		}
 
-		// Sync the images between each node in the cluster on demand
-		err = imageSyncBetweenNodes(d, project, info.Fingerprint)
+		id, _, err := d.cluster.ImageGet(project, fingerprint, false, false)
 		if err != nil {
-			return errors.Wrapf(err, "Image sync between nodes")
+			return errors.Wrapf(err, "Fetch image %q", fingerprint)
 		}
 
-		return nil
+		err = d.cluster.ImageAliasAdd(project, alias.Name, id, alias.Description)
+		if err != nil {
+			return errors.Wrapf(err, "Add new image alias to the database")
+		}
 	}
 
-	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImageDownload, nil, nil, run, nil, nil)
+	// Sync the images between each node in the cluster on demand
+	err := imageSyncBetweenNodes(d, project, fingerprint)
 	if err != nil {
-		cleanup(builddir, post)
-		return response.InternalError(err)
+		return errors.Wrapf(err, "Image sync between nodes")
 	}
 
-	return operations.OperationResponse(op)
+	return nil
 }
 
 func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
@@ -916,8 +1080,8 @@ func getImageMetadata(fname string) (*api.ImageMetadata, string, error) {
 	return &result, imageType, nil
 }
 
-func doImagesGet(d *Daemon, recursion bool, project string, public bool) (interface{}, error) {
-	results, err := d.cluster.ImagesGet(project, public)
+func doImagesGet(d *Daemon, recursion bool, project string, public bool, filter *query.Filter) (interface{}, error) {
+	results, err := d.cluster.ImagesGetFiltered(project, public, filter)
 	if err != nil {
 		return []string{}, err
 	}
@@ -951,7 +1115,12 @@ func imagesGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	public := d.checkTrustedClient(r) != nil || AllowProjectPermission("images", "view")(d, r) != response.EmptySyncResponse
 
-	result, err := doImagesGet(d, util.IsRecursionRequest(r), project, public)
+	filter, err := filterParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	result, err := doImagesGet(d, util.IsRecursionRequest(r), project, public, filter)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -991,6 +1160,17 @@ func autoUpdateImagesTask(d *Daemon) (task.Func, task.Schedule) {
 		if err != nil {
 			return 0, err
 		}
+
+		// Image aliases with their own policy need more frequent checks.
+		policies, err := d.cluster.ImageAliasesHaveConfig("auto_update.")
+		if err != nil {
+			return 0, err
+		}
+
+		if policies && (interval == 0 || interval > imageAutoUpdatePolicyInterval) {
+			interval = imageAutoUpdatePolicyInterval
+		}
+
 		return interval, nil
 	}
 	return f, schedule
@@ -1041,6 +1221,22 @@ func autoUpdateImagesInProject(ctx context.Context, d *Daemon, project string) e
 			continue
 		}
 
+		_, source, err := d.cluster.ImageSourceGet(id)
+		if err != nil {
+			logger.Error("Error getting source image", log.Ctx{"err": err, "fp": fingerprint, "project": project})
+			continue
+		}
+
+		policy, err := imageAutoUpdatePolicyGet(d, project, info)
+		if err != nil {
+			logger.Error("Error loading image auto-update policy", log.Ctx{"err": err, "fp": fingerprint, "project": project})
+			continue
+		}
+
+		if !imageAutoUpdateDue(project, source, policy, time.Now()) {
+			continue
+		}
+
 		// FIXME: since our APIs around image downloading don't support
 		//        cancelling, we run the function in a different
 		//        goroutine and simply abort when the context expires.
@@ -1101,6 +1297,16 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		op.UpdateMetadata(metadata)
 	}
 
+	// Previous versions may be kept as set by the aliases of the image.
+	policy, err := imageAutoUpdatePolicyGet(d, project, info)
+	if err != nil {
+		logger.Error("Error loading image auto-update policy", log.Ctx{"err": err, "fp": fingerprint})
+		return err
+	}
+
+	// Pinned images are never removed, only no longer auto-updated.
+	keepPrevious := policy.keep > 1 || info.Pinned
+
 	// Update the image on each pool where it currently exists.
 	hash := fingerprint
 
@@ -1132,12 +1338,18 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 			}
 		}
 
-		err = d.cluster.ImageLastAccessUpdate(hash, info.LastUsedAt)
+		err = d.cluster.ImageLastAccessUpdate(project, hash, info.LastUsedAt)
 		if err != nil {
 			logger.Error("Error setting last use date", log.Ctx{"err": err, "fp": hash})
 			continue
 		}
 
+		err = d.cluster.ImageCacheUpdate(newId, info.Pinned, info.CacheExpiry)
+		if err != nil {
+			logger.Error("Error setting cache policy", log.Ctx{"err": err, "fp": hash})
+			continue
+		}
+
 		err = d.cluster.ImageAliasesMove(id, newId)
 		if err != nil {
 			logger.Error("Error moving aliases", log.Ctx{"err": err, "fp": hash})
@@ -1151,7 +1363,7 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 
 		// If we do have optimized pools, make sure we remove
 		// the volumes associated with the image.
-		if poolName != "" {
+		if poolName != "" && !keepPrevious {
 			err = doDeleteImageFromPool(d.State(), fingerprint, poolName)
 			if err != nil {
 				logger.Error("Error deleting image from pool", log.Ctx{"err": err, "fp": fingerprint})
@@ -1165,6 +1377,29 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		return nil
 	}
 
+	for _, alias := range info.Aliases {
+		d.events.SendLifecycle(project, "image-alias-updated", fmt.Sprintf("/1.0/images/aliases/%s", alias.Name), map[string]interface{}{
+			"old_target": fingerprint,
+			"target":     hash,
+		})
+	}
+
+	// Keep the previous version, without updating it anymore, and prune the older ones.
+	if keepPrevious {
+		err = d.cluster.ImageUpdate(id, info.Filename, info.Size, info.Public, false, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, "", nil)
+		if err != nil {
+			logger.Error("Error disabling auto-update of the previous image", log.Ctx{"err": err, "fp": fingerprint})
+		}
+
+		err = autoUpdateImagePrune(d, project, source, policy.keep)
+		if err != nil {
+			logger.Error("Error pruning previous image versions", log.Ctx{"err": err, "alias": source.Alias})
+		}
+
+		setRefreshResult(true)
+		return nil
+	}
+
 	// Remove main image file.
 	fname := filepath.Join(d.os.VarDir, "images", fingerprint)
 	if shared.PathExists(fname) {
@@ -1192,6 +1427,62 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 	return nil
 }
 
+// autoUpdateImagePrune deletes the versions of an image downloaded from the given source beyond
+// the given number of most recent ones.
+func autoUpdateImagePrune(d *Daemon, project string, source api.ImageSource, keep int) error {
+	fingerprints, err := d.cluster.ImageSourceGetVersions(project, source.Server, source.Protocol, source.Alias)
+	if err != nil {
+		return err
+	}
+
+	if len(fingerprints) <= keep {
+		return nil
+	}
+
+	for _, fp := range fingerprints[keep:] {
+		id, info, err := d.cluster.ImageGet(project, fp, false, true)
+		if err != nil {
+			return err
+		}
+
+		if info.Pinned {
+			continue
+		}
+
+		err = d.cluster.ImageDelete(id)
+		if err != nil {
+			return errors.Wrapf(err, "Error deleting image %s from database", fp)
+		}
+
+		// Other projects may still use the image.
+		_, _, err = d.cluster.ImageGetFromAnyProject(fp)
+		if err != db.ErrNoSuchObject {
+			continue
+		}
+
+		poolIDs, err := d.cluster.ImageGetPools(fp)
+		if err != nil {
+			return err
+		}
+
+		poolNames, err := d.cluster.ImageGetPoolNamesFromIDs(poolIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, pool := range poolNames {
+			err := doDeleteImageFromPool(d.State(), fp, pool)
+			if err != nil {
+				return errors.Wrapf(err, "Error deleting image %s from storage pool %s", fp, pool)
+			}
+		}
+
+		imageDeleteFromDisk(fp)
+	}
+
+	return nil
+}
+
 func pruneExpiredImagesTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		opRun := func(op *operations.Operation) error {
@@ -1283,7 +1574,14 @@ func pruneLeftoverImages(d *Daemon) {
 	}
 
 	logger.Infof("Pruning leftover image files")
-	_, err = op.Run()
+	chanRun, err := op.Run()
+	if err != nil {
+		logger.Error("Failed to prune leftover image files", log.Ctx{"err": err})
+		return
+	}
+
+	// Wait for the pruning to be done, so it can't race with resumed image downloads.
+	err = <-chanRun
 	if err != nil {
 		logger.Error("Failed to prune leftover image files", log.Ctx{"err": err})
 		return
@@ -1304,7 +1602,7 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 	}
 
 	// Delete them
-	for _, fp := range images {
+	for _, image := range images {
 		// At each iteration we check if we got cancelled in the
 		// meantime. It is safe to abort here since anything not
 		// expired now will be expired at the next run.
@@ -1314,6 +1612,28 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 		default:
 		}
 
+		fp := image.Fingerprint
+
+		imgID, _, err := d.cluster.ImageGet(image.Project, fp, false, true)
+		if err != nil {
+			return errors.Wrapf(err, "Error retrieving image info %s", fp)
+		}
+
+		// Remove the database entry for the image.
+		if err = d.cluster.ImageDelete(imgID); err != nil {
+			return errors.Wrapf(err, "Error deleting image %s from database", fp)
+		}
+
+		// The image may still be used in other projects.
+		_, _, err = d.cluster.ImageGetFromAnyProject(fp)
+		if err == nil {
+			continue
+		}
+
+		if err != db.ErrNoSuchObject {
+			return errors.Wrapf(err, "Error retrieving image info %s", fp)
+		}
+
 		// Get the IDs of all storage pools on which a storage volume
 		// for the requested image currently exists.
 		poolIDs, err := d.cluster.ImageGetPools(fp)
@@ -1351,16 +1671,6 @@ func pruneExpiredImages(ctx context.Context, d *Daemon) error {
 				return errors.Wrapf(err, "Error deleting image file %s", fname)
 			}
 		}
-
-		imgID, _, err := d.cluster.ImageGet("default", fp, false, false)
-		if err != nil {
-			return errors.Wrapf(err, "Error retrieving image info %s", fp)
-		}
-
-		// Remove the database entry for the image.
-		if err = d.cluster.ImageDelete(imgID); err != nil {
-			return errors.Wrapf(err, "Error deleting image %s from database", fp)
-		}
 	}
 
 	return nil
@@ -1600,6 +1910,10 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		info.ExpiresAt = req.ExpiresAt
 	}
 
+	if req.CacheExpiry < 0 {
+		return response.BadRequest(fmt.Errorf("Invalid cache expiry %d", req.CacheExpiry))
+	}
+
 	// Get profile ids
 	if req.Profiles == nil {
 		req.Profiles = []string{"default"}
@@ -1620,6 +1934,11 @@ func imagePut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageCacheUpdate(id, req.Pinned, req.CacheExpiry)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1682,11 +2001,32 @@ func imagePatch(d *Daemon, r *http.Request) response.Response {
 		info.Properties = properties
 	}
 
+	// Get Pinned
+	pinned, err := reqRaw.GetBool("pinned")
+	if err == nil {
+		info.Pinned = pinned
+	}
+
+	// Get CacheExpiry
+	_, ok = reqRaw["cache_expiry"]
+	if ok {
+		if req.CacheExpiry < 0 {
+			return response.BadRequest(fmt.Errorf("Invalid cache expiry %d", req.CacheExpiry))
+		}
+
+		info.CacheExpiry = req.CacheExpiry
+	}
+
 	err = d.cluster.ImageUpdate(id, info.Filename, info.Size, info.Public, info.AutoUpdate, info.Architecture, info.CreatedAt, info.ExpiresAt, info.Properties, "", nil)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageCacheUpdate(id, info.Pinned, info.CacheExpiry)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1701,8 +2041,13 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("name and target are required"))
 	}
 
+	err := imageAliasValidateConfig(req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// This is just to see if the alias name already exists.
-	_, _, err := d.cluster.ImageAliasGet(project, req.Name, true)
+	_, _, err = d.cluster.ImageAliasGet(project, req.Name, true)
 	if err != db.ErrNoSuchObject {
 		if err != nil {
 			return response.InternalError(err)
@@ -1721,6 +2066,18 @@ func imageAliasesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	if len(req.Config) > 0 {
+		aliasID, _, err := d.cluster.ImageAliasGet(project, req.Name, true)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = d.cluster.ImageAliasConfigUpdate(aliasID, req.Config)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/images/aliases/%s", version.APIVersion, req.Name))
 }
 
@@ -1808,6 +2165,11 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("The target field is required"))
 	}
 
+	err = imageAliasValidateConfig(req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	imageId, _, err := d.cluster.ImageGet(project, req.Target, false, false)
 	if err != nil {
 		return response.SmartError(err)
@@ -1818,6 +2180,11 @@ func imageAliasPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageAliasConfigUpdate(id, req.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1861,6 +2228,32 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		alias.Description = description
 	}
 
+	_, ok = req["config"]
+	if ok {
+		config, err := req.GetMap("config")
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if alias.Config == nil {
+			alias.Config = map[string]string{}
+		}
+
+		for k, v := range config {
+			value, ok := v.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Value of image alias configuration key %q must be a string", k))
+			}
+
+			alias.Config[k] = value
+		}
+
+		err = imageAliasValidateConfig(alias.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	imageId, _, err := d.cluster.ImageGet(project, alias.Target, false, false)
 	if err != nil {
 		return response.SmartError(err)
@@ -1871,6 +2264,11 @@ func imageAliasPatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = d.cluster.ImageAliasConfigUpdate(id, alias.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -1951,13 +2349,27 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	imagePath := shared.VarPath("images", imgInfo.Fingerprint)
 	rootfsPath := imagePath + ".rootfs"
 
+	// Encrypted image files are served from their content decrypted in memory.
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	paths, cleanup, err := imageFilesDecrypted(key, imagePath, rootfsPath)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	imagePath = paths[0]
+	rootfsPath = paths[1]
+
 	_, ext, _, err := shared.DetectCompression(imagePath)
 	if err != nil {
 		ext = ""
 	}
 	filename := fmt.Sprintf("%s%s", imgInfo.Fingerprint, ext)
 
-	if shared.PathExists(rootfsPath) {
+	if rootfsPath != "" && shared.PathExists(rootfsPath) {
 		files := make([]response.FileResponseEntry, 2)
 
 		files[0].Identifier = "metadata"
@@ -1980,7 +2392,7 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 		files[1].Path = rootfsPath
 		files[1].Filename = filename
 
-		return response.FileResponse(r, files, nil, false)
+		return &imageDecryptedResponse{response.FileResponse(r, files, nil, false), cleanup}
 	}
 
 	files := make([]response.FileResponseEntry, 1)
@@ -1988,7 +2400,57 @@ func imageExport(d *Daemon, r *http.Request) response.Response {
 	files[0].Path = imagePath
 	files[0].Filename = filename
 
-	return response.FileResponse(r, files, nil, false)
+	return &imageDecryptedResponse{response.FileResponse(r, files, nil, false), cleanup}
+}
+
+func imageExportPost(d *Daemon, r *http.Request) response.Response {
+	project := projectParam(r)
+	fingerprint := mux.Vars(r)["fingerprint"]
+
+	_, imgInfo, err := d.cluster.ImageGet(project, fingerprint, false, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.ImageExportPost{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Protocol != "oci" {
+		return response.BadRequest(fmt.Errorf("Unsupported protocol: %q", req.Protocol))
+	}
+
+	if req.Server == "" || req.Alias == "" {
+		return response.BadRequest(fmt.Errorf("The target server and alias are required"))
+	}
+
+	// Check if the image is only available on another node.
+	address, err := d.cluster.ImageLocate(imgInfo.Fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if address != "" {
+		// Forward the request to the other node
+		cert := d.endpoints.NetworkCert()
+		client, err := cluster.Connect(address, cert, false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	run := func(op *operations.Operation) error {
+		return ociArtifactPush(d, req.Server, req.Alias, imgInfo)
+	}
+
+	op, err := operations.OperationCreate(d.State(), project, operations.OperationClassTask, db.OperationImagePush, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
 }
 
 func imageSecret(d *Daemon, r *http.Request) response.Response {
@@ -2257,7 +2719,19 @@ func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error
 	imageMetaPath := shared.VarPath("images", fingerprint)
 	imageRootfsPath := shared.VarPath("images", fingerprint+".rootfs")
 
-	metaFile, err := os.Open(imageMetaPath)
+	// Send the image files decrypted in memory, encrypted again by the other member if configured.
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return err
+	}
+
+	paths, cleanup, err := imageFilesDecrypted(key, imageMetaPath, imageRootfsPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	metaFile, err := os.Open(paths[0])
 	if err != nil {
 		return err
 	}
@@ -2266,8 +2740,8 @@ func imageSyncBetweenNodes(d *Daemon, project string, fingerprint string) error
 	createArgs.MetaFile = metaFile
 	createArgs.MetaName = filepath.Base(imageMetaPath)
 
-	if shared.PathExists(imageRootfsPath) {
-		rootfsFile, err := os.Open(imageRootfsPath)
+	if paths[1] != "" {
+		rootfsFile, err := os.Open(paths[1])
 		if err != nil {
 			return err
 		}
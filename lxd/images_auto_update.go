@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// imageAutoUpdatePolicyInterval is how often the auto-update task runs when image aliases have
+// their own auto-update policy, for their windows to be honoured.
+const imageAutoUpdatePolicyInterval = 15 * time.Minute
+
+// imageAliasConfigKeys lists the configuration keys of image aliases, which set the auto-update
+// policy of the image they target.
+var imageAliasConfigKeys = map[string]func(value string) error{
+	// Hours between the checks for a new version (0 disables auto-update)
+	"auto_update.interval": shared.IsUint32,
+
+	// Daily time window during which the checks happen, as HH:MM-HH:MM in server time
+	"auto_update.window": func(value string) error {
+		_, _, err := imageAutoUpdateWindowParse(value)
+		return err
+	},
+
+	// Number of versions kept, the previous ones not being auto-updated anymore
+	"auto_update.keep": func(value string) error {
+		keep, err := strconv.Atoi(value)
+		if err != nil || keep < 1 {
+			return fmt.Errorf("Invalid number of versions %q", value)
+		}
+
+		return nil
+	},
+}
+
+// imageAliasValidateConfig validates the configuration of an image alias.
+func imageAliasValidateConfig(config map[string]string) error {
+	for k, v := range config {
+		validator, ok := imageAliasConfigKeys[k]
+		if !ok {
+			return fmt.Errorf("Invalid image alias configuration key %q", k)
+		}
+
+		if v == "" {
+			continue
+		}
+
+		err := validator(v)
+		if err != nil {
+			return fmt.Errorf("Invalid value for image alias configuration key %q: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+// imageAutoUpdateWindowParse parses a HH:MM-HH:MM time window into its start and end, as
+// durations since midnight.
+func imageAutoUpdateWindowParse(value string) (time.Duration, time.Duration, error) {
+	fields := strings.Split(value, "-")
+	if len(fields) != 2 {
+		return -1, -1, fmt.Errorf("Invalid time window %q", value)
+	}
+
+	bounds := make([]time.Duration, 2)
+	for i, field := range fields {
+		t, err := time.Parse("15:04", strings.TrimSpace(field))
+		if err != nil {
+			return -1, -1, fmt.Errorf("Invalid time window %q", value)
+		}
+
+		bounds[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// imageAutoUpdatePolicy is how an image gets auto-updated, as set by the configuration of the
+// aliases targeting it.
+type imageAutoUpdatePolicy struct {
+	interval time.Duration
+	window   string
+	keep     int
+}
+
+// imageAutoUpdatePolicyGet returns the auto-update policy of an image, taking the configuration
+// of its first alias which has some, and the global interval otherwise.
+func imageAutoUpdatePolicyGet(d *Daemon, project string, info *api.Image) (imageAutoUpdatePolicy, error) {
+	policy := imageAutoUpdatePolicy{keep: 1}
+
+	interval, err := cluster.ConfigGetInt64(d.cluster, "images.auto_update_interval")
+	if err != nil {
+		return policy, err
+	}
+
+	policy.interval = time.Duration(interval) * time.Hour
+
+	for _, alias := range info.Aliases {
+		_, entry, err := d.cluster.ImageAliasGet(project, alias.Name, true)
+		if err == db.ErrNoSuchObject {
+			continue
+		}
+
+		if err != nil {
+			return policy, err
+		}
+
+		if len(entry.Config) == 0 {
+			continue
+		}
+
+		if entry.Config["auto_update.interval"] != "" {
+			hours, err := strconv.Atoi(entry.Config["auto_update.interval"])
+			if err != nil {
+				return policy, err
+			}
+
+			policy.interval = time.Duration(hours) * time.Hour
+		}
+
+		if entry.Config["auto_update.keep"] != "" {
+			policy.keep, err = strconv.Atoi(entry.Config["auto_update.keep"])
+			if err != nil {
+				return policy, err
+			}
+		}
+
+		policy.window = entry.Config["auto_update.window"]
+
+		break
+	}
+
+	return policy, nil
+}
+
+// inWindow returns whether the given time is within the time window of the policy, if any.
+func (p imageAutoUpdatePolicy) inWindow(now time.Time) bool {
+	if p.window == "" {
+		return true
+	}
+
+	start, end, err := imageAutoUpdateWindowParse(p.window)
+	if err != nil {
+		return false
+	}
+
+	hour, min, _ := now.Clock()
+	current := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute
+
+	// Windows can go past midnight.
+	if start <= end {
+		return current >= start && current < end
+	}
+
+	return current >= start || current < end
+}
+
+// Time of the last check for a new version of the images, by source, as images get a new
+// fingerprint when updated.
+var imageAutoUpdateChecks = map[string]time.Time{}
+var imageAutoUpdateChecksLock sync.Mutex
+
+// imageAutoUpdateDue returns whether the image with the given source is due a check for a new
+// version according to the policy, recording the check if so.
+func imageAutoUpdateDue(project string, source api.ImageSource, policy imageAutoUpdatePolicy, now time.Time) bool {
+	if policy.interval <= 0 || !policy.inWindow(now) {
+		return false
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s", project, source.Server, source.Protocol, source.Alias)
+
+	imageAutoUpdateChecksLock.Lock()
+	defer imageAutoUpdateChecksLock.Unlock()
+
+	// The task runs at the smallest interval, give it some slack.
+	last, ok := imageAutoUpdateChecks[key]
+	if ok && now.Sub(last) < policy.interval-time.Minute {
+		return false
+	}
+
+	imageAutoUpdateChecks[key] = now
+
+	return true
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// imageBuildAgentTimeout is how long to wait for the build instance to run commands once started.
+const imageBuildAgentTimeout = 5 * time.Minute
+
+// imageBuildShutdownTimeout is how long the build instance gets to shut down cleanly.
+const imageBuildShutdownTimeout = 5 * time.Minute
+
+// imgPostBuildInfo builds an image by creating a throwaway instance from the image of the build
+// manifest, writing its files and running its actions, then publishing the instance. The aliases
+// of the request which already exist are moved to the new image, so that rebuilds replace the
+// previous image.
+func imgPostBuildInfo(d *Daemon, r *http.Request, req api.ImagesPost, op *operations.Operation, builddir string) (*api.Image, error) {
+	project := projectParam(r)
+	build := req.Source.Build
+	if build == nil {
+		return nil, fmt.Errorf("No build manifest provided")
+	}
+
+	if build.Type == "" {
+		build.Type = "container"
+	}
+
+	dbType, err := instancetype.New(build.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the image the build starts from.
+	hash, err := instance.ResolveImage(d.State(), project, build.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	var source *api.Image
+	if build.Source.Server != "" {
+		source, err = d.ImageDownload(op, build.Source.Server, build.Source.Protocol, build.Source.Certificate, build.Source.Secret, hash, build.Type, true, false, "", true, project)
+	} else {
+		_, source, err = d.cluster.ImageGet(project, hash, false, false)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get the build source image")
+	}
+
+	architecture, err := osarch.ArchitectureId(source.Architecture)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the throwaway instance.
+	args := db.InstanceArgs{
+		Project:      project,
+		Name:         fmt.Sprintf("lxd-build-%s", strings.Split(op.ID(), "-")[0]),
+		Type:         dbType,
+		Architecture: architecture,
+		Config:       build.Config,
+		Profiles:     build.Profiles,
+	}
+
+	inst, err := instanceCreateFromImage(d, args, source.Fingerprint, op)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create the build instance")
+	}
+
+	defer func() {
+		if inst.IsRunning() {
+			inst.Stop(false)
+		}
+
+		err := inst.Delete()
+		if err != nil {
+			logger.Warn("Failed to delete the build instance", log.Ctx{"project": project, "instance": inst.Name(), "err": err})
+		}
+	}()
+
+	err = op.UpdateMetadata(map[string]interface{}{"build_instance": inst.Name()})
+	if err != nil {
+		return nil, err
+	}
+
+	err = inst.Start(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to start the build instance")
+	}
+
+	// Virtual machines only run commands once their agent is up.
+	deadline := time.Now().Add(imageBuildAgentTimeout)
+	for {
+		err = imageBuildExec(inst, builddir, []string{"true"})
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Wrap(err, "The build instance isn't running commands")
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+
+	for _, file := range build.Files {
+		err = imageBuildFile(inst, builddir, file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to write %q", file.Path)
+		}
+	}
+
+	for _, action := range build.Actions {
+		err = imageBuildExec(inst, builddir, []string{"/bin/sh", "-c", action})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = inst.Shutdown(imageBuildShutdownTimeout)
+	if err != nil {
+		err = inst.Stop(false)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to stop the build instance")
+		}
+	}
+
+	// Publish the instance.
+	publish := req
+	publish.Source = &api.ImagesPostSource{Type: "container", Name: inst.Name()}
+
+	imagePublishLock.Lock()
+	info, err := imgPostContInfo(d, r, publish, op, builddir)
+	imagePublishLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	imageID, _, err := d.cluster.ImageGet(project, info.Fingerprint, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alias := range req.Aliases {
+		aliasID, _, err := d.cluster.ImageAliasGet(project, alias.Name, true)
+		if err == db.ErrNoSuchObject {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fetch image alias %q", alias.Name)
+		}
+
+		err = d.cluster.ImageAliasUpdate(aliasID, imageID, alias.Description)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Move image alias %q", alias.Name)
+		}
+	}
+
+	return info, nil
+}
+
+// imageBuildExec runs a command as root in the build instance, failing with its output if it
+// doesn't succeed.
+func imageBuildExec(inst instance.Instance, builddir string, command []string) error {
+	output, err := ioutil.TempFile(builddir, "lxd_build_output_")
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	env := map[string]string{
+		"PATH": "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME": "/root",
+		"USER": "root",
+		"LANG": "C.UTF-8",
+	}
+
+	cmd, err := inst.Exec(command, env, nil, output, output, "", 0, 0)
+	if err != nil {
+		return err
+	}
+
+	exitCode, err := cmd.Wait()
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		content, _ := ioutil.ReadFile(output.Name())
+		return fmt.Errorf("Command %q failed with exit code %d: %s", strings.Join(command, " "), exitCode, strings.TrimSpace(string(content)))
+	}
+
+	return nil
+}
+
+// imageBuildFile writes a file of the build manifest to the build instance, creating its parent
+// directories.
+func imageBuildFile(inst instance.Instance, builddir string, file api.ImageBuildFile) error {
+	if !filepath.IsAbs(file.Path) {
+		return fmt.Errorf("The path must be absolute")
+	}
+
+	mode := int64(0644)
+	if file.Mode != "" {
+		var err error
+		mode, err = strconv.ParseInt(file.Mode, 8, 0)
+		if err != nil {
+			return fmt.Errorf("Invalid mode %q", file.Mode)
+		}
+	}
+
+	err := imageBuildExec(inst, builddir, []string{"mkdir", "-p", filepath.Dir(file.Path)})
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.TempFile(builddir, "lxd_build_file_")
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	_, err = content.WriteString(file.Content)
+	if err != nil {
+		return err
+	}
+
+	return inst.FilePush("file", content.Name(), file.Path, file.UID, file.GID, int(mode), "overwrite")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+)
+
+// imageEncryptFiles encrypts the files of an image in the image store with the key of
+// images.encryption.key_file, when images.encryption is set.
+func imageEncryptFiles(d *Daemon, fingerprint string) error {
+	encrypted, err := cluster.ConfigGetBool(d.cluster, "images.encryption")
+	if err != nil {
+		return errors.Wrap(err, "Failed to load cluster configuration")
+	}
+
+	if !encrypted {
+		return nil
+	}
+
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return err
+	}
+
+	for _, path := range []string{shared.VarPath("images", fingerprint), shared.VarPath("images", fingerprint+".rootfs")} {
+		if !shared.PathExists(path) {
+			continue
+		}
+
+		err := util.ImageEncrypt(path, key)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to encrypt image file %q", path)
+		}
+	}
+
+	return nil
+}
+
+// imageFilesDecrypted returns the paths to the content of the given image files, the encrypted
+// ones being decrypted in memory with the given key. Missing files get an empty path. The returned
+// function releases the decrypted content, which can't be read anymore then.
+func imageFilesDecrypted(key []byte, paths ...string) ([]string, func(), error) {
+	files := []*os.File{}
+	cleanup := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	result := make([]string, len(paths))
+	for i, path := range paths {
+		if !shared.PathExists(path) {
+			continue
+		}
+
+		if !util.ImageIsEncrypted(path) {
+			result[i] = path
+			continue
+		}
+
+		f, err := util.ImageDecryptMemfd(path, key)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		files = append(files, f)
+		result[i] = util.ImageMemfdPath(f)
+	}
+
+	return result, cleanup, nil
+}
+
+// imageDecryptedResponse wraps a response serving image files decrypted by imageFilesDecrypted,
+// releasing them once rendered.
+type imageDecryptedResponse struct {
+	response.Response
+	cleanup func()
+}
+
+func (r *imageDecryptedResponse) Render(w http.ResponseWriter) error {
+	defer r.cleanup()
+
+	return r.Response.Render(w)
+}
+
+// errImageHeaderRead stops the decryption of an image file once its header got read.
+var errImageHeaderRead = fmt.Errorf("Image header read")
+
+// imageHeaderWriter keeps the header of a decrypted image file, used to detect its compression.
+type imageHeaderWriter struct {
+	buf []byte
+}
+
+func (w *imageHeaderWriter) Write(p []byte) (int, error) {
+	n := 263 - len(w.buf)
+	if n > len(p) {
+		n = len(p)
+	}
+
+	w.buf = append(w.buf, p[:n]...)
+	if len(w.buf) >= 263 {
+		return n, errImageHeaderRead
+	}
+
+	return len(p), nil
+}
+
+// imageDetectCompression returns the compression of an image file as an extension, looking at its
+// decrypted content if encrypted.
+func imageDetectCompression(path string, key []byte) (string, error) {
+	if !util.ImageIsEncrypted(path) {
+		_, ext, _, err := shared.DetectCompression(path)
+		return ext, err
+	}
+
+	header := &imageHeaderWriter{}
+	_, err := util.ImageDecrypt(path, key, header)
+	if err != nil && err != errImageHeaderRead {
+		return "", err
+	}
+
+	_, ext, _, err := shared.DetectCompressionFile(bytes.NewReader(header.buf))
+	return ext, err
+}
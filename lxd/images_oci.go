@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/ioprogress"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// ociInfo is the subset of "skopeo inspect" output used by LXD.
+type ociInfo struct {
+	Name         string            `json:"Name"`
+	Digest       string            `json:"Digest"`
+	Created      time.Time         `json:"Created"`
+	Architecture string            `json:"Architecture"`
+	Labels       map[string]string `json:"Labels"`
+
+	// Fingerprint of the LXD image, for LXD images pushed as artifacts.
+	Fingerprint string `json:"-"`
+}
+
+// ociRuntimeConfig is the subset of the OCI runtime spec (config.json) generated by umoci.
+type ociRuntimeConfig struct {
+	Process struct {
+		Args []string `json:"args"`
+		Env  []string `json:"env"`
+		Cwd  string   `json:"cwd"`
+		User struct {
+			UID uint32 `json:"uid"`
+			GID uint32 `json:"gid"`
+		} `json:"user"`
+	} `json:"process"`
+}
+
+// ociRegistryReference returns the reference of an image alias on the given registry.
+func ociRegistryReference(server string, alias string) string {
+	registry := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	registry = strings.TrimSuffix(registry, "/")
+
+	return fmt.Sprintf("%s/%s", registry, alias)
+}
+
+// ociImageReference returns the skopeo reference for an image alias on the given registry.
+func ociImageReference(server string, alias string) string {
+	return fmt.Sprintf("docker://%s", ociRegistryReference(server, alias))
+}
+
+// ociImageName returns the repository name of an image alias, stripping any tag.
+func ociImageName(alias string) string {
+	idx := strings.LastIndex(alias, ":")
+	if idx > strings.LastIndex(alias, "/") {
+		return alias[:idx]
+	}
+
+	return alias
+}
+
+// ociImageInspect retrieves the manifest information of an image from an OCI registry.
+func ociImageInspect(server string, alias string) (*ociInfo, error) {
+	_, err := exec.LookPath("skopeo")
+	if err != nil {
+		return nil, fmt.Errorf("OCI images require skopeo to be installed")
+	}
+
+	// LXD images pushed as artifacts are retrieved as-is.
+	artifact, err := ociArtifactInspect(server, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if artifact != nil {
+		return artifact, nil
+	}
+
+	output, err := shared.RunCommand("skopeo", "--insecure-policy", "inspect", ociImageReference(server, alias))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to inspect OCI image %q", alias)
+	}
+
+	info := ociInfo{}
+	err = json.Unmarshal([]byte(output), &info)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse OCI image information for %q", alias)
+	}
+
+	if !strings.HasPrefix(info.Digest, "sha256:") {
+		return nil, fmt.Errorf("Unsupported OCI image digest %q", info.Digest)
+	}
+
+	return &info, nil
+}
+
+// ociImageFingerprint returns the fingerprint of the local image of the project converted from the
+// given OCI image, matched on its manifest digest, or the fingerprint of the LXD image for artifacts.
+// As the fingerprint of converted images is the hash of the generated tarball, the manifest digest
+// is returned when the image wasn't converted yet, only identifying the download.
+func ociImageFingerprint(d *Daemon, project string, info *ociInfo) (string, error) {
+	if info.Fingerprint != "" {
+		return info.Fingerprint, nil
+	}
+
+	fingerprints, err := d.cluster.ImagesGet(project, false)
+	if err != nil {
+		return "", err
+	}
+
+	for _, fingerprint := range fingerprints {
+		_, image, err := d.cluster.ImageGet(project, fingerprint, false, true)
+		if err != nil {
+			return "", err
+		}
+
+		if image.Properties["oci.digest"] == info.Digest {
+			return image.Fingerprint, nil
+		}
+	}
+
+	return strings.TrimPrefix(info.Digest, "sha256:"), nil
+}
+
+// ociImageDownload fetches an image from an OCI registry and converts it into a unified LXD image
+// tarball at destName. The image's entrypoint, working directory and environment are recorded as
+// image properties so that the container can be started without a system init.
+func ociImageDownload(server string, alias string, info *ociInfo, destName string, progress func(ioprogress.ProgressData)) (*api.Image, error) {
+	if info.Fingerprint != "" {
+		return ociArtifactDownload(server, alias, info, destName, progress)
+	}
+
+	_, err := exec.LookPath("umoci")
+	if err != nil {
+		return nil, fmt.Errorf("OCI images require umoci to be installed")
+	}
+
+	tmpDir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_oci_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Fetch the layers.
+	progress(ioprogress.ProgressData{Text: "Retrieving OCI image layers"})
+	ociPath := filepath.Join(tmpDir, "oci")
+	// Pin the copy to the inspected digest so the fingerprint matches the content.
+	srcRef := fmt.Sprintf("%s@%s", ociImageReference(server, ociImageName(alias)), info.Digest)
+	_, err = shared.RunCommand("skopeo", "--insecure-policy", "copy", srcRef, fmt.Sprintf("oci:%s:latest", ociPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to retrieve OCI image %q", alias)
+	}
+
+	// Unpack the layers into a rootfs.
+	progress(ioprogress.ProgressData{Text: "Unpacking OCI image"})
+	bundlePath := filepath.Join(tmpDir, "bundle")
+	_, err = shared.RunCommand("umoci", "unpack", "--keep-dirlinks", "--image", fmt.Sprintf("%s:latest", ociPath), bundlePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to unpack OCI image %q", alias)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(bundlePath, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	config := ociRuntimeConfig{}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse OCI runtime config")
+	}
+
+	if len(config.Process.Args) == 0 {
+		return nil, fmt.Errorf("OCI image %q doesn't define an entrypoint or command", alias)
+	}
+
+	// Application images usually lack the mount points LXC expects.
+	rootfsPath := filepath.Join(bundlePath, "rootfs")
+	for _, dir := range []string{"dev", "proc", "sys", "tmp", "etc"} {
+		err = os.MkdirAll(filepath.Join(rootfsPath, dir), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	architecture := info.Architecture
+	archID, err := osarch.ArchitectureId(architecture)
+	if err == nil {
+		architecture, _ = osarch.ArchitectureName(archID)
+	}
+
+	entrypoint, err := json.Marshal(config.Process.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := json.Marshal(config.Process.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]string{
+		"type":           "oci",
+		"description":    fmt.Sprintf("OCI image %s", alias),
+		"oci.entrypoint": string(entrypoint),
+		"oci.env":        string(env),
+		"oci.cwd":        config.Process.Cwd,
+		"oci.uid":        fmt.Sprintf("%d", config.Process.User.UID),
+		"oci.gid":        fmt.Sprintf("%d", config.Process.User.GID),
+		"oci.digest":     info.Digest,
+	}
+
+	metadata := api.ImageMetadata{
+		Architecture: architecture,
+		CreationDate: info.Created.Unix(),
+		Properties:   properties,
+	}
+
+	data, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(bundlePath, "metadata.yaml"), data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate a unified image tarball.
+	progress(ioprogress.ProgressData{Text: "Generating image"})
+	_, err = shared.RunCommand("tar", "-C", bundlePath, "--numeric-owner", "--xattrs", "-zcf", destName, "metadata.yaml", "rootfs")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate image tarball")
+	}
+
+	// The fingerprint is the hash of the tarball, as for any other image.
+	f, err := os.Open(destName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+
+	image := api.Image{}
+	image.Fingerprint = fmt.Sprintf("%x", hash.Sum(nil))
+	image.Filename = fmt.Sprintf("%s.tar.gz", strings.Replace(alias, "/", "_", -1))
+	image.Size = size
+	image.Architecture = architecture
+	image.CreatedAt = info.Created
+	image.Properties = properties
+	image.Type = "container"
+
+	return &image, nil
+}
+
+// ociInitCommand builds the LXC init command for an application container created from an OCI
+// image, quoting its arguments as a POSIX shell would.
+func ociInitCommand(entrypoint string) (string, error) {
+	args := []string{}
+	err := json.Unmarshal([]byte(entrypoint), &args)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid OCI entrypoint")
+	}
+
+	if len(args) == 0 {
+		return "", fmt.Errorf("Empty OCI entrypoint")
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, ociShellQuote(arg))
+	}
+
+	return strings.Join(quoted, " "), nil
+}
+
+// ociShellQuote returns the argument single-quoted when it contains characters other than the ones
+// a shell never interprets, closing the quotes around its own single quotes.
+func ociShellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%_+=:,./-") == "" {
+		return arg
+	}
+
+	return fmt.Sprintf("'%s'", strings.Replace(arg, "'", `'"'"'`, -1))
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/ioprogress"
+)
+
+// Media types and annotations of LXD images stored as OCI artifacts.
+const (
+	ociArtifactType          = "application/vnd.linuxcontainers.lxd.image.v1"
+	ociArtifactMetadataType  = "application/vnd.linuxcontainers.lxd.image.metadata.v1"
+	ociArtifactRootfsType    = "application/vnd.linuxcontainers.lxd.image.rootfs.v1"
+	ociArtifactFingerprint   = "org.linuxcontainers.lxd.image.fingerprint"
+	ociArtifactMetadataTitle = "metadata"
+)
+
+// ociArtifactManifest is the subset of an OCI manifest used to recognize LXD image artifacts.
+type ociArtifactManifest struct {
+	ArtifactType string `json:"artifactType"`
+	Config       struct {
+		MediaType string `json:"mediaType"`
+	} `json:"config"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ociArtifactRootfsTitle returns the name of the root filesystem file of an image artifact, which
+// tells the image type as for split image uploads.
+func ociArtifactRootfsTitle(imageType string) string {
+	if imageType == instancetype.VM.String() {
+		return "rootfs.img"
+	}
+
+	return "rootfs"
+}
+
+// ociArtifactInspect returns the information of an image stored as a LXD artifact in an OCI
+// registry, or nil if the image isn't such an artifact.
+func ociArtifactInspect(server string, alias string) (*ociInfo, error) {
+	output, err := shared.RunCommand("skopeo", "--insecure-policy", "inspect", "--raw", ociImageReference(server, alias))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to inspect OCI image %q", alias)
+	}
+
+	manifest := ociArtifactManifest{}
+	err = json.Unmarshal([]byte(output), &manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse OCI manifest of %q", alias)
+	}
+
+	if manifest.ArtifactType != ociArtifactType && manifest.Config.MediaType != ociArtifactType {
+		return nil, nil
+	}
+
+	fingerprint := manifest.Annotations[ociArtifactFingerprint]
+	if len(fingerprint) != 64 || strings.Trim(fingerprint, "0123456789abcdef") != "" {
+		return nil, fmt.Errorf("Invalid LXD image fingerprint in OCI artifact %q", alias)
+	}
+
+	return &ociInfo{Name: alias, Fingerprint: fingerprint}, nil
+}
+
+// ociArtifactDownload fetches a LXD image stored as an artifact in an OCI registry to destName
+// (and destName.rootfs for split images), checking its content against the fingerprint.
+func ociArtifactDownload(server string, alias string, info *ociInfo, destName string, progress func(ioprogress.ProgressData)) (*api.Image, error) {
+	_, err := exec.LookPath("oras")
+	if err != nil {
+		return nil, fmt.Errorf("LXD images stored in OCI registries require oras to be installed")
+	}
+
+	tmpDir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_oci_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	progress(ioprogress.ProgressData{Text: "Retrieving OCI artifact"})
+	_, err = shared.RunCommand("oras", "pull", "--output", tmpDir, ociRegistryReference(server, alias))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to retrieve OCI artifact %q", alias)
+	}
+
+	metaPath := filepath.Join(tmpDir, ociArtifactMetadataTitle)
+	if !shared.PathExists(metaPath) {
+		return nil, fmt.Errorf("OCI artifact %q is missing the image metadata", alias)
+	}
+
+	imageType := ""
+	rootfsPath := ""
+	for _, t := range []string{instancetype.Container.String(), instancetype.VM.String()} {
+		path := filepath.Join(tmpDir, ociArtifactRootfsTitle(t))
+		if shared.PathExists(path) {
+			imageType = t
+			rootfsPath = path
+			break
+		}
+	}
+
+	// Validate the content against the fingerprint.
+	hash := sha256.New()
+	size := int64(0)
+	for _, path := range []string{metaPath, rootfsPath} {
+		if path == "" {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		size += n
+	}
+
+	result := fmt.Sprintf("%x", hash.Sum(nil))
+	if result != info.Fingerprint {
+		return nil, fmt.Errorf("Hash mismatch for OCI artifact %q: %s != %s", alias, result, info.Fingerprint)
+	}
+
+	imageMeta, metaType, err := getImageMetadata(metaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if imageType == "" {
+		imageType = metaType
+	}
+
+	err = shared.FileMove(metaPath, destName)
+	if err != nil {
+		return nil, err
+	}
+
+	if rootfsPath != "" {
+		err = shared.FileMove(rootfsPath, destName+".rootfs")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	image := api.Image{}
+	image.Fingerprint = info.Fingerprint
+	image.Filename = fmt.Sprintf("%s.tar", strings.Replace(ociImageName(alias), "/", "_", -1))
+	image.Size = size
+	image.Architecture = imageMeta.Architecture
+	image.CreatedAt = time.Unix(imageMeta.CreationDate, 0)
+	image.ExpiresAt = time.Unix(imageMeta.ExpiryDate, 0)
+	image.Properties = imageMeta.Properties
+	image.Type = imageType
+
+	return &image, nil
+}
+
+// ociArtifactPush pushes an image of the local image store to an OCI registry as a LXD artifact.
+// Registry credentials are the ones of the oras configuration of the LXD server.
+func ociArtifactPush(d *Daemon, server string, alias string, info *api.Image) error {
+	_, err := exec.LookPath("oras")
+	if err != nil {
+		return fmt.Errorf("Pushing images to OCI registries requires oras to be installed")
+	}
+
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return err
+	}
+
+	imagePath := shared.VarPath("images", info.Fingerprint)
+	paths, cleanup, err := imageFilesDecrypted(key, imagePath, imagePath+".rootfs")
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// The artifact files are named after their role, so link them into a directory of their own.
+	// Symlinks are used as decrypted files are only reachable through /proc.
+	tmpDir, err := ioutil.TempDir(shared.VarPath("images"), "lxd_oci_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := []string{fmt.Sprintf("%s:%s", ociArtifactMetadataTitle, ociArtifactMetadataType)}
+	err = os.Symlink(paths[0], filepath.Join(tmpDir, ociArtifactMetadataTitle))
+	if err != nil {
+		return err
+	}
+
+	if paths[1] != "" {
+		rootfsTitle := ociArtifactRootfsTitle(info.Type)
+		files = append(files, fmt.Sprintf("%s:%s", rootfsTitle, ociArtifactRootfsType))
+
+		err = os.Symlink(paths[1], filepath.Join(tmpDir, rootfsTitle))
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []string{"push", "--artifact-type", ociArtifactType, "--annotation", fmt.Sprintf("%s=%s", ociArtifactFingerprint, info.Fingerprint), ociRegistryReference(server, alias)}
+	args = append(args, files...)
+
+	cmd := exec.Command("oras", args...)
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to push image to %q: %s", alias, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOCIInitCommand(t *testing.T) {
+	cmd, err := ociInitCommand(`["/docker-entrypoint.sh","nginx","-g","daemon off;"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `/docker-entrypoint.sh nginx -g 'daemon off;'`, cmd)
+
+	cmd, err = ociInitCommand(`["sh","-c","echo \"hello world\""]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `sh -c 'echo "hello world"'`, cmd)
+
+	cmd, err = ociInitCommand(`["echo","it's","$HOME",""]`)
+	assert.NoError(t, err)
+	assert.Equal(t, `echo 'it'"'"'s' '$HOME' ''`, cmd)
+
+	_, err = ociInitCommand(`[]`)
+	assert.Error(t, err)
+}
+
+func TestOCIImageName(t *testing.T) {
+	assert.Equal(t, "nginx", ociImageName("nginx:1.19"))
+	assert.Equal(t, "library/nginx", ociImageName("library/nginx"))
+	assert.Equal(t, "localhost:5000/app", ociImageName("localhost:5000/app:latest"))
+	assert.Equal(t, "localhost:5000/app", ociImageName("localhost:5000/app"))
+}
+
+func TestOCIRegistryReference(t *testing.T) {
+	assert.Equal(t, "registry.example.com/lxd/web:1.0", ociRegistryReference("https://registry.example.com/", "lxd/web:1.0"))
+	assert.Equal(t, "docker://docker.io/nginx", ociImageReference("https://docker.io", "nginx"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// imageSignatureProperty is the image property holding the armored OpenPGP detached signature of
+// the image fingerprint.
+const imageSignatureProperty = "signature"
+
+// imageVerifySignature checks that an image is signed with one of the keys of images.trusted_keys,
+// when set.
+func imageVerifySignature(d *Daemon, fingerprint string, properties map[string]string) error {
+	var keys string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return errors.Wrap(err, "failed to load cluster configuration")
+		}
+
+		keys = config.ImagesTrustedKeys()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if keys == "" {
+		return nil
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(keys))
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse the trusted image keys")
+	}
+
+	signature := properties[imageSignatureProperty]
+	if signature == "" {
+		return fmt.Errorf("Image %q isn't signed, which this server requires", fingerprint)
+	}
+
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, strings.NewReader(fingerprint), strings.NewReader(signature))
+	if err != nil {
+		return fmt.Errorf("Signature of image %q doesn't verify against the trusted keys: %v", fingerprint, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// imageStreamsFile is the hash and size of an image file listed in the simplestreams products.
+type imageStreamsFile struct {
+	sha256 string
+	size   int64
+}
+
+// Image files never change, so their hashes are only computed once.
+var imageStreamsFiles = map[string]imageStreamsFile{}
+var imageStreamsFilesLock sync.Mutex
+
+// imageStreamsRoutes adds the handlers serving the public images of the default project as a
+// simplestreams server, which other LXD servers and clients can use as a remote over plain HTTPS.
+// The index and products are generated on each request, so they follow the images and aliases
+// being added and deleted.
+func imageStreamsRoutes(d *Daemon, router *mux.Router) {
+	router.HandleFunc("/streams/v1/index.json", func(w http.ResponseWriter, r *http.Request) {
+		products, err := imageStreamsProducts(d)
+		if err != nil {
+			imageStreamsError(w, err)
+			return
+		}
+
+		names := make([]string, 0, len(products.Products))
+		for name := range products.Products {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		stream := simplestreams.Stream{
+			Format: "index:1.0",
+			Index: map[string]simplestreams.StreamIndex{
+				"images": {
+					DataType: "image-downloads",
+					Path:     "streams/v1/images.json",
+					Format:   "products:1.0",
+					Products: names,
+				},
+			},
+		}
+
+		imageStreamsRender(w, stream)
+	})
+
+	router.HandleFunc("/streams/v1/images.json", func(w http.ResponseWriter, r *http.Request) {
+		products, err := imageStreamsProducts(d)
+		if err != nil {
+			imageStreamsError(w, err)
+			return
+		}
+
+		imageStreamsRender(w, products)
+	})
+
+	router.HandleFunc("/streams/images/{fingerprint}/{file}", func(w http.ResponseWriter, r *http.Request) {
+		imageStreamsFileGet(d, r).Render(w)
+	})
+}
+
+// imageStreamsRender writes a simplestreams JSON document.
+func imageStreamsRender(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		logger.Debug("Failed to write simplestreams document", log.Ctx{"err": err})
+	}
+}
+
+// imageStreamsError writes an error in place of a simplestreams document.
+func imageStreamsError(w http.ResponseWriter, err error) {
+	logger.Error("Failed to generate simplestreams products", log.Ctx{"err": err})
+	w.Header().Set("Content-Type", "application/json")
+	response.SmartError(err).Render(w)
+}
+
+// imageStreamsFileInfo returns the hash and size of an image file, decrypted with the given key if
+// encrypted.
+func imageStreamsFileInfo(path string, key []byte) (imageStreamsFile, error) {
+	imageStreamsFilesLock.Lock()
+	defer imageStreamsFilesLock.Unlock()
+
+	info, ok := imageStreamsFiles[path]
+	if ok {
+		return info, nil
+	}
+
+	var err error
+
+	// Encrypted image files are listed with the hash of their content.
+	hash := sha256.New()
+	var size int64
+	if util.ImageIsEncrypted(path) {
+		size, err = util.ImageDecrypt(path, key, hash)
+		if err != nil {
+			return info, err
+		}
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return info, err
+		}
+		defer f.Close()
+
+		size, err = io.Copy(hash, f)
+		if err != nil {
+			return info, err
+		}
+	}
+
+	info = imageStreamsFile{sha256: fmt.Sprintf("%x", hash.Sum(nil)), size: size}
+	imageStreamsFiles[path] = info
+
+	return info, nil
+}
+
+// imageStreamsProducts returns the simplestreams products of the public images of the default
+// project stored on this member, one per image.
+func imageStreamsProducts(d *Daemon) (*simplestreams.Products, error) {
+	result, err := doImagesGet(d, true, "default", true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	products := &simplestreams.Products{
+		ContentID: "images",
+		DataType:  "image-downloads",
+		Format:    "products:1.0",
+		Products:  map[string]simplestreams.Product{},
+	}
+
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range result.([]*api.Image) {
+		if image == nil {
+			continue
+		}
+
+		items, err := imageStreamsItems(image, key)
+		if err != nil {
+			logger.Debug("Skipping image from the simplestreams products", log.Ctx{"fingerprint": image.Fingerprint, "err": err})
+			continue
+		}
+
+		aliases := make([]string, 0, len(image.Aliases))
+		for _, alias := range image.Aliases {
+			aliases = append(aliases, alias.Name)
+		}
+
+		products.Products[fmt.Sprintf("lxd:%s", image.Fingerprint)] = simplestreams.Product{
+			Aliases:         strings.Join(aliases, ","),
+			Architecture:    image.Architecture,
+			OperatingSystem: image.Properties["os"],
+			Release:         image.Properties["release"],
+			ReleaseTitle:    image.Properties["release"],
+			Version:         image.Properties["version"],
+			Versions: map[string]simplestreams.ProductVersion{
+				image.CreatedAt.UTC().Format("20060102_1504"): {
+					Items: items,
+					Label: image.Properties["label"],
+				},
+			},
+		}
+	}
+
+	return products, nil
+}
+
+// imageStreamsItems returns the simplestreams items of an image, whose files must be stored on
+// this member and are decrypted with the given key if encrypted.
+func imageStreamsItems(image *api.Image, key []byte) (map[string]simplestreams.ProductVersionItem, error) {
+	metaPath := shared.VarPath("images", image.Fingerprint)
+	if !shared.PathExists(metaPath) {
+		return nil, fmt.Errorf("Image files aren't stored on this member")
+	}
+
+	meta, err := imageStreamsFileInfo(metaPath, key)
+	if err != nil {
+		return nil, err
+	}
+
+	metaItem := simplestreams.ProductVersionItem{
+		Path:       fmt.Sprintf("streams/images/%s/meta", image.Fingerprint),
+		HashSha256: meta.sha256,
+		Size:       meta.size,
+	}
+
+	// Unified images.
+	rootfsPath := metaPath + ".rootfs"
+	if !shared.PathExists(rootfsPath) {
+		metaItem.FileType = "lxd_combined.tar.gz"
+		return map[string]simplestreams.ProductVersionItem{metaItem.FileType: metaItem}, nil
+	}
+
+	rootfs, err := imageStreamsFileInfo(rootfsPath, key)
+	if err != nil {
+		return nil, err
+	}
+
+	metaItem.FileType = "lxd.tar.xz"
+	rootfsItem := simplestreams.ProductVersionItem{
+		Path:       fmt.Sprintf("streams/images/%s/rootfs", image.Fingerprint),
+		HashSha256: rootfs.sha256,
+		Size:       rootfs.size,
+	}
+
+	// The combined hash of the metadata with the root file is the image fingerprint.
+	ext, _ := imageDetectCompression(rootfsPath, key)
+	if image.Type == "virtual-machine" {
+		rootfsItem.FileType = "disk-kvm.img"
+		metaItem.LXDHashSha256DiskKvmImg = image.Fingerprint
+	} else if ext == ".squashfs" {
+		rootfsItem.FileType = "squashfs"
+		metaItem.LXDHashSha256SquashFs = image.Fingerprint
+	} else {
+		rootfsItem.FileType = "root.tar.xz"
+		metaItem.LXDHashSha256RootXz = image.Fingerprint
+	}
+
+	return map[string]simplestreams.ProductVersionItem{
+		metaItem.FileType:   metaItem,
+		rootfsItem.FileType: rootfsItem,
+	}, nil
+}
+
+// imageStreamsFileGet serves the metadata or root file of a public image of the default project.
+func imageStreamsFileGet(d *Daemon, r *http.Request) response.Response {
+	fingerprint := mux.Vars(r)["fingerprint"]
+	file := mux.Vars(r)["file"]
+
+	_, image, err := d.cluster.ImageGet("default", fingerprint, true, true)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	path := shared.VarPath("images", image.Fingerprint)
+	switch file {
+	case "meta":
+	case "rootfs":
+		path += ".rootfs"
+	default:
+		return response.NotFound(nil)
+	}
+
+	// Check if the image is only available on another member.
+	address, err := d.cluster.ImageLocate(image.Fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address != "" {
+		client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.ForwardedResponse(client, r)
+	}
+
+	if !shared.PathExists(path) {
+		return response.NotFound(nil)
+	}
+
+	// Encrypted image files are served from their content decrypted in memory.
+	key, err := storagePools.ImageEncryptionKey(d.State())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	paths, cleanup, err := imageFilesDecrypted(key, path)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	files := []response.FileResponseEntry{{
+		Identifier: file,
+		Path:       paths[0],
+		Filename:   fmt.Sprintf("%s.%s", image.Fingerprint, file),
+	}}
+
+	return &imageDecryptedResponse{response.FileResponse(r, files, nil, false), cleanup}
+}
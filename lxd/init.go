@@ -1,11 +1,15 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +22,9 @@ type initDataNode struct {
 
 type initDataCluster struct {
 	api.ClusterPut `yaml:",inline"`
+
+	// Join token of the new member, from which the fields needed to join are derived.
+	ClusterToken string `json:"cluster_token" yaml:"cluster_token"`
 }
 
 // Helper to initialize node-specific entities on a LXD instance using the
@@ -366,3 +373,44 @@ func initDataClusterApply(d lxd.InstanceServer, config *initDataCluster) error {
 
 	return nil
 }
+
+// Helper to fill the fields needed to join a cluster from a join token.
+//
+// The first cluster member listed in the token which presents the expected
+// certificate is used as the join target.
+func initDataClusterJoinToken(config *initDataCluster, encoded string) error {
+	joinTokenJSON, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	joinToken := api.ClusterMemberJoinToken{}
+	err = json.Unmarshal(joinTokenJSON, &joinToken)
+	if err != nil {
+		return err
+	}
+
+	if joinToken.ServerName == "" || joinToken.Secret == "" || joinToken.Fingerprint == "" || len(joinToken.Addresses) == 0 {
+		return fmt.Errorf("Join token is missing required fields")
+	}
+
+	for _, address := range joinToken.Addresses {
+		cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", address), version.UserAgent)
+		if err != nil {
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != joinToken.Fingerprint {
+			return fmt.Errorf("Certificate fingerprint mismatch between join token and cluster member %q", address)
+		}
+
+		config.ServerName = joinToken.ServerName
+		config.ClusterAddress = address
+		config.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		config.ClusterPassword = joinToken.Secret
+
+		return nil
+	}
+
+	return fmt.Errorf("Unable to connect to any of the cluster members specified in the join token")
+}
@@ -109,3 +109,11 @@ type Instance interface {
 	DeferTemplateApply(trigger string) error
 	DaemonState() *state.State
 }
+
+// Quiescer is implemented by the instances able to quiesce their applications and filesystems, so
+// that snapshots taken while they're running are consistent.
+type Quiescer interface {
+	// Quiesce freezes the filesystems of the instance until Thaw is called or the timeout expires.
+	Quiesce(timeout time.Duration) error
+	Thaw() error
+}
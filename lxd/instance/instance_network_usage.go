@@ -0,0 +1,146 @@
+package instance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// networkUsageNICTypes are the NIC types whose host side interface stays on the host, as opposed to
+// the ones whose interface is moved into the instance.
+var networkUsageNICTypes = []string{"bridged", "p2p", "routed"}
+
+// networkUsageCounters returns the live counters of the host side interface backing the given NIC
+// device (the veth peer or tap device recorded in its volatile host_name), along with the index of
+// that interface. The counters are swapped to be seen from the instance, so that what the host side
+// interface transmits is what the instance receives. NICs without a host side interface aren't
+// accounted, as anything reported from within the instance can't be trusted.
+func networkUsageCounters(inst Instance, devName string) (api.InstanceStateNetworkCounters, int64, bool) {
+	if !shared.StringInSlice(inst.ExpandedDevices()[devName]["nictype"], networkUsageNICTypes) {
+		return api.InstanceStateNetworkCounters{}, -1, false
+	}
+
+	hostName := inst.LocalConfig()[fmt.Sprintf("volatile.%s.host_name", devName)]
+	if hostName == "" || strings.Contains(hostName, "/") {
+		return api.InstanceStateNetworkCounters{}, -1, false
+	}
+
+	read := func(name string) (int64, error) {
+		content, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/%s", hostName, name))
+		if err != nil {
+			return -1, err
+		}
+
+		return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	}
+
+	index, err := read("ifindex")
+	if err != nil {
+		return api.InstanceStateNetworkCounters{}, -1, false
+	}
+
+	hostReceived, err := read("statistics/rx_bytes")
+	if err != nil {
+		return api.InstanceStateNetworkCounters{}, -1, false
+	}
+
+	hostSent, err := read("statistics/tx_bytes")
+	if err != nil {
+		return api.InstanceStateNetworkCounters{}, -1, false
+	}
+
+	counters := api.InstanceStateNetworkCounters{
+		BytesReceived: hostSent,
+		BytesSent:     hostReceived,
+	}
+
+	return counters, index, true
+}
+
+// networkUsageCheckpoint parses the checkpoint recorded for a NIC device, returning the counters
+// it holds if it was taken on the current host side interface of the device.
+func networkUsageCheckpoint(inst Instance, devName string, index int64) (int64, int64) {
+	fields := strings.Split(inst.LocalConfig()[fmt.Sprintf("volatile.%s.usage.checkpoint", devName)], ":")
+	if len(fields) != 3 || fields[0] != fmt.Sprintf("%d", index) {
+		return 0, 0
+	}
+
+	received, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	sent, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	return received, sent
+}
+
+// networkUsageDelta returns the traffic since the checkpoint, treating a counter lower than the
+// checkpoint as having been reset.
+func networkUsageDelta(current int64, checkpoint int64) int64 {
+	if current < checkpoint {
+		return current
+	}
+
+	return current - checkpoint
+}
+
+// NetworkUsage returns the accumulated traffic of each NIC device of the instance, combining the
+// usage persisted in its volatile config with the traffic since the last checkpoint.
+func NetworkUsage(inst Instance) map[string]api.InstanceStateNetworkUsage {
+	usage := map[string]api.InstanceStateNetworkUsage{}
+	config := inst.LocalConfig()
+
+	for devName, dev := range inst.ExpandedDevices() {
+		if dev["type"] != "nic" {
+			continue
+		}
+
+		devUsage := api.InstanceStateNetworkUsage{}
+		devUsage.BytesReceived, _ = strconv.ParseInt(config[fmt.Sprintf("volatile.%s.usage.bytes_received", devName)], 10, 64)
+		devUsage.BytesSent, _ = strconv.ParseInt(config[fmt.Sprintf("volatile.%s.usage.bytes_sent", devName)], 10, 64)
+
+		counters, index, ok := networkUsageCounters(inst, devName)
+		if ok {
+			received, sent := networkUsageCheckpoint(inst, devName, index)
+			devUsage.BytesReceived += networkUsageDelta(counters.BytesReceived, received)
+			devUsage.BytesSent += networkUsageDelta(counters.BytesSent, sent)
+		}
+
+		usage[devName] = devUsage
+	}
+
+	return usage
+}
+
+// NetworkUsageUpdate persists the accumulated traffic of each NIC device of the instance and
+// records a new checkpoint of the counters of their host side interfaces. It must be called before
+// those interfaces are removed when the instance stops.
+func NetworkUsageUpdate(inst Instance) error {
+	usage := NetworkUsage(inst)
+	changes := map[string]string{}
+
+	for devName, devUsage := range usage {
+		counters, index, ok := networkUsageCounters(inst, devName)
+		if !ok {
+			continue
+		}
+
+		changes[fmt.Sprintf("volatile.%s.usage.bytes_received", devName)] = fmt.Sprintf("%d", devUsage.BytesReceived)
+		changes[fmt.Sprintf("volatile.%s.usage.bytes_sent", devName)] = fmt.Sprintf("%d", devUsage.BytesSent)
+		changes[fmt.Sprintf("volatile.%s.usage.checkpoint", devName)] = fmt.Sprintf("%d:%d:%d", index, counters.BytesReceived, counters.BytesSent)
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return inst.VolatileSet(changes)
+}
@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +16,7 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/apparmor"
 	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
@@ -182,6 +184,12 @@ func validConfigKey(os *sys.OS, key string, value string) error {
 	if key == "raw.lxc" {
 		return lxcValidConfig(value)
 	}
+	if key == "raw.apparmor" {
+		return apparmor.ValidateRaw(value)
+	}
+	if key == "security.apparmor.allow" || key == "security.apparmor.deny" {
+		return apparmor.ValidateRules(value)
+	}
 	if key == "security.syscalls.blacklist_compat" {
 		for _, arch := range os.Architectures {
 			if arch == osarch.ARCH_64BIT_INTEL_X86 ||
@@ -220,6 +228,66 @@ func lxcParseRawLXC(line string) (string, string, error) {
 	return key, val, nil
 }
 
+// RawLXCLiveChanges compares two raw.lxc values and returns the cgroup settings which were added or
+// modified, along with the keys whose changes can't be applied to a running container, if any.
+func RawLXCLiveChanges(oldRawLxc string, newRawLxc string) (map[string]string, []string, error) {
+	parse := func(rawLxc string) (map[string][]string, error) {
+		config := map[string][]string{}
+		for _, line := range strings.Split(rawLxc, "\n") {
+			key, val, err := lxcParseRawLXC(line)
+			if err != nil {
+				return nil, err
+			}
+
+			if key == "" {
+				continue
+			}
+
+			config[key] = append(config[key], val)
+		}
+
+		return config, nil
+	}
+
+	oldConfig, err := parse(oldRawLxc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newConfig, err := parse(newRawLxc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := map[string]string{}
+	restartKeys := []string{}
+	for key, values := range newConfig {
+		if strings.Join(values, "\n") == strings.Join(oldConfig[key], "\n") {
+			continue
+		}
+
+		// Only single-valued cgroup settings can be changed on a running container.
+		if len(values) != 1 || (!strings.HasPrefix(key, "lxc.cgroup.") && !strings.HasPrefix(key, "lxc.cgroup2.")) {
+			restartKeys = append(restartKeys, key)
+			continue
+		}
+
+		changes[key] = values[0]
+	}
+
+	// Removed keys can't be reverted to their previous value live.
+	for key := range oldConfig {
+		_, ok := newConfig[key]
+		if !ok {
+			restartKeys = append(restartKeys, key)
+		}
+	}
+
+	sort.Strings(restartKeys)
+
+	return changes, restartKeys, nil
+}
+
 func lxcValidConfig(rawLxc string) error {
 	for _, line := range strings.Split(rawLxc, "\n") {
 		key, _, err := lxcParseRawLXC(line)
@@ -28,6 +28,10 @@ type Monitor struct {
 	disconnected bool
 	chDisconnect chan struct{}
 	eventHandler func(name string, data map[string]interface{})
+
+	// deviceDeleted holds the channels waiting for the removal of a device, by device ID.
+	deviceDeleted     map[string]chan struct{}
+	deviceDeletedLock sync.Mutex
 }
 
 // Connect creates or retrieves an existing QMP monitor for the path.
@@ -59,6 +63,7 @@ func Connect(path string, eventHandler func(name string, data map[string]interfa
 	monitor.qmp = qmpConn
 	monitor.chDisconnect = make(chan struct{}, 1)
 	monitor.eventHandler = eventHandler
+	monitor.deviceDeleted = map[string]chan struct{}{}
 
 	// Spawn goroutines.
 	err = monitor.run()
@@ -131,6 +136,18 @@ func (m *Monitor) run() error {
 					continue
 				}
 
+				if e.Event == "DEVICE_DELETED" {
+					id, _ := e.Data["device"].(string)
+
+					m.deviceDeletedLock.Lock()
+					ch, ok := m.deviceDeleted[id]
+					if ok {
+						close(ch)
+						delete(m.deviceDeleted, id)
+					}
+					m.deviceDeletedLock.Unlock()
+				}
+
 				if m.eventHandler != nil {
 					m.eventHandler(e.Event, e.Data)
 				}
@@ -282,3 +299,67 @@ func (m *Monitor) Quit() error {
 func (m *Monitor) AgentReady() bool {
 	return m.agentReady
 }
+
+// runCmdArgs runs a command with arguments, returning the error reported by QEMU if it fails.
+func (m *Monitor) runCmdArgs(cmd string, args interface{}) error {
+	// Check if disconnected
+	if m.disconnected {
+		return ErrMonitorDisconnect
+	}
+
+	req, err := json.Marshal(map[string]interface{}{"execute": cmd, "arguments": args})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.qmp.Run(req)
+	if err != nil {
+		return fmt.Errorf("Failed to run %s: %v", cmd, err)
+	}
+
+	return nil
+}
+
+// NetdevAdd adds a network backend.
+func (m *Monitor) NetdevAdd(args map[string]string) error {
+	return m.runCmdArgs("netdev_add", args)
+}
+
+// NetdevDel removes a network backend.
+func (m *Monitor) NetdevDel(id string) error {
+	return m.runCmdArgs("netdev_del", map[string]string{"id": id})
+}
+
+// DeviceAdd hot plugs a device.
+func (m *Monitor) DeviceAdd(args map[string]string) error {
+	return m.runCmdArgs("device_add", args)
+}
+
+// DeviceDel requests the removal of a device and waits for the guest to release it.
+func (m *Monitor) DeviceDel(id string, timeout time.Duration) error {
+	ch := make(chan struct{})
+
+	m.deviceDeletedLock.Lock()
+	m.deviceDeleted[id] = ch
+	m.deviceDeletedLock.Unlock()
+
+	defer func() {
+		m.deviceDeletedLock.Lock()
+		delete(m.deviceDeleted, id)
+		m.deviceDeletedLock.Unlock()
+	}()
+
+	err := m.runCmdArgs("device_del", map[string]string{"id": id})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-m.chDisconnect:
+		return ErrMonitorDisconnect
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out waiting for the guest to release device %q", id)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +21,7 @@ import (
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
+	yaml "gopkg.in/yaml.v2"
 
 	lxdClient "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/backup"
@@ -30,6 +32,7 @@ import (
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/instance/operationlock"
 	"github.com/lxc/lxd/lxd/instance/qemu/qmp"
 	"github.com/lxc/lxd/lxd/maas"
 	"github.com/lxc/lxd/lxd/operations"
@@ -41,6 +44,7 @@ import (
 	"github.com/lxc/lxd/lxd/vsock"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/containerwriter"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
@@ -210,6 +214,13 @@ func Create(s *state.State, args db.InstanceArgs) (instance.Instance, error) {
 
 	// Fill in any default volume config.
 	volumeConfig := map[string]string{}
+	if !vm.IsSnapshot() {
+		err = storagePools.VolumeFillProjectDefault(s, args.Project, volumeConfig, pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = storagePools.VolumeFillDefault(storagePool, volumeConfig, pool)
 	if err != nil {
 		return nil, err
@@ -385,6 +396,28 @@ func (vm *Qemu) unmount() (bool, error) {
 	return unmounted, nil
 }
 
+// holdMount mounts the instance's config volume and keeps it mounted while the instance runs, even
+// once the operations which mount it in the meantime are done with it, until releaseMount is called.
+func (vm *Qemu) holdMount() error {
+	pool, err := vm.getStoragePool()
+	if err != nil {
+		return err
+	}
+
+	return pool.HoldInstanceMount(vm, nil)
+}
+
+// releaseMount releases the mount of the instance's config volume held by holdMount, unmounting the
+// volume unless an operation is still using it.
+func (vm *Qemu) releaseMount() (bool, error) {
+	pool, err := vm.getStoragePool()
+	if err != nil {
+		return false, err
+	}
+
+	return pool.ReleaseInstanceMount(vm, nil)
+}
+
 // generateAgentCert creates the necessary server key and certificate if needed.
 func (vm *Qemu) generateAgentCert() (string, string, string, string, error) {
 	// Mount the instance's config volume if needed.
@@ -457,13 +490,19 @@ func (vm *Qemu) Freeze() error {
 
 // OnStop is run when the instance stops.
 func (vm *Qemu) OnStop(target string) error {
+	// Record the network usage while the tap devices are still around.
+	err := instance.NetworkUsageUpdate(vm)
+	if err != nil {
+		logger.Warn("Failed to record network usage", log.Ctx{"project": vm.Project(), "instance": vm.Name(), "err": err})
+	}
+
 	vm.cleanupDevices()
 	os.Remove(vm.pidFilePath())
 	os.Remove(vm.getMonitorPath())
-	vm.unmount()
+	vm.releaseMount()
 
 	// Record power state
-	err := vm.state.Cluster.ContainerSetState(vm.id, "STOPPED")
+	err = vm.state.Cluster.ContainerSetState(vm.id, "STOPPED")
 	if err != nil {
 		return err
 	}
@@ -538,12 +577,19 @@ func (vm *Qemu) Start(stateful bool) error {
 		return err
 	}
 
+	// Setup a new operation
+	op, err := operationlock.Create(vm.id, "start", false, false)
+	if err != nil {
+		return errors.Wrap(err, "Create instance start operation")
+	}
+	defer op.Done(nil)
+
 	if vm.IsRunning() {
 		return fmt.Errorf("The instance is already running")
 	}
 
-	// Mount the instance's config volume.
-	_, err = vm.mount()
+	// Mount the instance's config volume and keep it mounted while the instance runs.
+	err = vm.holdMount()
 	if err != nil {
 		return err
 	}
@@ -677,6 +723,14 @@ func (vm *Qemu) Start(stateful bool) error {
 		return err
 	}
 
+	if vm.expandedConfig["limits.disk.priority"] != "" {
+		err = vm.setDiskPriority()
+		if err != nil {
+			vm.Stop(false)
+			return err
+		}
+	}
+
 	// Start QMP monitoring.
 	monitor, err := qmp.Connect(vm.getMonitorPath(), vm.getMonitorEventHandler())
 	if err != nil {
@@ -712,6 +766,35 @@ func (vm *Qemu) Start(stateful bool) error {
 	return nil
 }
 
+// setDiskPriority sets the I/O priority of the qemu process from limits.disk.priority, mapping the
+// disk priority to a best-effort I/O scheduling level.
+func (vm *Qemu) setDiskPriority() error {
+	priority := 5
+	if vm.expandedConfig["limits.disk.priority"] != "" {
+		var err error
+		priority, err = strconv.Atoi(vm.expandedConfig["limits.disk.priority"])
+		if err != nil {
+			return err
+		}
+	}
+
+	pid, err := vm.pid()
+	if err != nil {
+		return err
+	}
+
+	if pid <= 0 {
+		return fmt.Errorf("Failed to find the qemu process")
+	}
+
+	err = util.SetIOPriority(pid, 7-priority*7/10)
+	if err != nil {
+		return errors.Wrap(err, "Failed to set the I/O priority")
+	}
+
+	return nil
+}
+
 func (vm *Qemu) setupNvram() error {
 	srcOvmfFile := filepath.Join(vm.ovmfPath(), "OVMF_VARS.fd")
 	if vm.expandedConfig["security.secureboot"] == "" || shared.IsTrue(vm.expandedConfig["security.secureboot"]) {
@@ -1176,12 +1259,14 @@ backend = "pty"
 	vm.addMonitorConfig(sb)
 	vm.addConfDriveConfig(sb)
 
+	bootIndexes := vm.bootIndexes()
+
 	nicIndex := 0
 	driveIndex := 0
 	for _, runConf := range devConfs {
 		// Add root drive device.
 		if runConf.RootFS.Path != "" {
-			err = vm.addRootDriveConfig(sb)
+			err = vm.addRootDriveConfig(sb, bootIndexes)
 			if err != nil {
 				return "", err
 			}
@@ -1192,17 +1277,19 @@ backend = "pty"
 			for _, drive := range runConf.Mounts {
 				// Increment first so index starts at 1, as root drive uses index 0.
 				driveIndex++
-				vm.addDriveConfig(sb, driveIndex, drive)
+				vm.addDriveConfig(sb, bootIndexes, driveIndex, drive)
 			}
 		}
 
 		// Add network device.
 		if len(runConf.NetworkInterface) > 0 {
-			vm.addNetDevConfig(sb, nicIndex, runConf.NetworkInterface)
+			vm.addNetDevConfig(sb, bootIndexes, nicIndex, runConf.NetworkInterface)
 			nicIndex++
 		}
 	}
 
+	vm.addHotplugConfig(sb)
+
 	// Write the config file to disk.
 	configPath := filepath.Join(vm.LogPath(), "qemu.conf")
 	return configPath, ioutil.WriteFile(configPath, []byte(sb.String()), 0640)
@@ -1342,8 +1429,60 @@ mount_tag = "config"
 	return
 }
 
+// bootIndexes returns the qemu boot index of the disk and NIC devices, keyed by the name used in their
+// qemu IDs. Devices are ordered by their boot.priority (highest first), then the root disk followed
+// by the NICs. Disks other than the root one are only bootable if they have a boot.priority.
+func (vm *Qemu) bootIndexes() map[string]int {
+	type bootDevice struct {
+		name     string
+		priority int
+	}
+
+	devices := []bootDevice{}
+	for _, dev := range vm.expandedDevices.Sorted() {
+		var name string
+		if dev.Config["type"] == "disk" && shared.IsRootDiskDevice(dev.Config) {
+			name = "root"
+		} else if dev.Config["type"] == "disk" && dev.Config["boot.priority"] != "" {
+			name = dev.Name
+		} else if dev.Config["type"] == "nic" {
+			name = dev.Config["name"]
+		} else {
+			continue
+		}
+
+		priority, _ := strconv.Atoi(dev.Config["boot.priority"])
+		devices = append(devices, bootDevice{name: name, priority: priority})
+	}
+
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].priority != devices[j].priority {
+			return devices[i].priority > devices[j].priority
+		}
+
+		return devices[i].name == "root" && devices[j].name != "root"
+	})
+
+	bootIndexes := map[string]int{}
+	for i, dev := range devices {
+		bootIndexes[dev.name] = i + 1
+	}
+
+	return bootIndexes
+}
+
+// addBootIndexConfig adds the boot index of the named device, if it is bootable.
+func (vm *Qemu) addBootIndexConfig(sb *strings.Builder, bootIndexes map[string]int, name string) {
+	bootIndex, ok := bootIndexes[name]
+	if !ok {
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("bootindex = \"%d\"\n", bootIndex))
+}
+
 // addRootDriveConfig adds the qemu config required for adding the root drive.
-func (vm *Qemu) addRootDriveConfig(sb *strings.Builder) error {
+func (vm *Qemu) addRootDriveConfig(sb *strings.Builder, bootIndexes map[string]int) error {
 	pool, err := vm.getStoragePool()
 	if err != nil {
 		return err
@@ -1371,14 +1510,15 @@ channel = "0"
 scsi-id = "0"
 lun = "1"
 drive = "lxd_root"
-bootindex = "1"
 `, rootDrivePath))
 
+	vm.addBootIndexConfig(sb, bootIndexes, "root")
+
 	return nil
 }
 
 // addDriveConfig adds the qemu config required for adding a supplementary drive.
-func (vm *Qemu) addDriveConfig(sb *strings.Builder, driveIndex int, driveConf deviceConfig.MountEntryItem) {
+func (vm *Qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int, driveIndex int, driveConf deviceConfig.MountEntryItem) {
 	driveName := fmt.Sprintf(driveConf.TargetPath)
 
 	// Devices use "lxd_" prefix indicating that this is a user named device.
@@ -1400,11 +1540,13 @@ lun = "1"
 drive = "lxd_%s"
 `, driveName, driveName, driveConf.DevPath, driveName, driveIndex, driveName))
 
+	vm.addBootIndexConfig(sb, bootIndexes, driveName)
+
 	return
 }
 
 // addNetDevConfig adds the qemu config required for adding a network device.
-func (vm *Qemu) addNetDevConfig(sb *strings.Builder, nicIndex int, nicConfig []deviceConfig.RunConfigItem) {
+func (vm *Qemu) addNetDevConfig(sb *strings.Builder, bootIndexes map[string]int, nicIndex int, nicConfig []deviceConfig.RunConfigItem) {
 	var devName, devTap, devHwaddr string
 	for _, nicItem := range nicConfig {
 		if nicItem.Key == "name" {
@@ -1438,12 +1580,105 @@ netdev = "lxd_%s"
 mac = "%s"
 bus = "qemu_pcie%d"
 addr = "0x0"
-bootindex = "%d""
-`, devName, devName, devTap, 5+nicIndex, 14+nicIndex, 5+nicIndex, 4+nicIndex, devName, devName, devHwaddr, 5+nicIndex, 2+nicIndex))
+`, devName, devName, devTap, 5+nicIndex, 14+nicIndex, 5+nicIndex, 4+nicIndex, devName, devName, devHwaddr, 5+nicIndex))
+
+	vm.addBootIndexConfig(sb, bootIndexes, devName)
 
 	return
 }
 
+// qemuHotplugPorts is the number of spare PCIe root ports devices can be hot plugged into.
+const qemuHotplugPorts = 8
+
+// addHotplugConfig adds the spare PCIe root ports used to hot plug devices into the running VM.
+func (vm *Qemu) addHotplugConfig(sb *strings.Builder) {
+	sb.WriteString(`
+# Hotplug ports`)
+
+	for i := 0; i < qemuHotplugPorts; i++ {
+		multifunction := ""
+		if i == 0 {
+			multifunction = "\nmultifunction = \"on\""
+		}
+
+		sb.WriteString(fmt.Sprintf(`
+[device "qemu_hotplug%d"]
+driver = "pcie-root-port"
+port = "0x2%d"
+chassis = "%d"
+bus = "pcie.0"%s
+addr = "0x3.0x%d"
+`, i, i, 20+i, multifunction, i))
+	}
+
+	return
+}
+
+// deviceAttachNIC hot plugs the tap interface of a started NIC device into the running VM.
+func (vm *Qemu) deviceAttachNIC(nicConfig []deviceConfig.RunConfigItem) error {
+	var devName, devTap, devHwaddr string
+	for _, nicItem := range nicConfig {
+		if nicItem.Key == "name" {
+			devName = nicItem.Value
+		} else if nicItem.Key == "link" {
+			devTap = nicItem.Value
+		} else if nicItem.Key == "hwaddr" {
+			devHwaddr = nicItem.Value
+		}
+	}
+
+	monitor, err := qmp.Connect(vm.getMonitorPath(), vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	// Use the same IDs as the NICs added at startup (see addNetDevConfig).
+	netdevID := fmt.Sprintf("lxd_%s", devName)
+	err = monitor.NetdevAdd(map[string]string{
+		"type":       "tap",
+		"id":         netdevID,
+		"ifname":     devTap,
+		"script":     "no",
+		"downscript": "no",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Use the first spare port, QEMU refuses the ones which are already in use.
+	for i := 0; i < qemuHotplugPorts; i++ {
+		err = monitor.DeviceAdd(map[string]string{
+			"driver": "virtio-net-pci",
+			"id":     fmt.Sprintf("dev-lxd_%s", devName),
+			"netdev": netdevID,
+			"mac":    devHwaddr,
+			"bus":    fmt.Sprintf("qemu_hotplug%d", i),
+			"addr":   "0x0",
+		})
+		if err == nil {
+			return nil
+		}
+	}
+
+	monitor.NetdevDel(netdevID)
+	return errors.Wrap(err, "No spare port to hot plug the device")
+}
+
+// deviceDetachNIC hot unplugs a NIC device from the running VM, waiting for the guest to release it.
+func (vm *Qemu) deviceDetachNIC(devName string) error {
+	monitor, err := qmp.Connect(vm.getMonitorPath(), vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	err = monitor.DeviceDel(fmt.Sprintf("dev-lxd_%s", devName), 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	return monitor.NetdevDel(fmt.Sprintf("lxd_%s", devName))
+}
+
 // pidFilePath returns the path where the qemu process should write its PID.
 func (vm *Qemu) pidFilePath() string {
 	return filepath.Join(vm.LogPath(), "qemu.pid")
@@ -1555,9 +1790,7 @@ func (vm *Qemu) Rename(newName string) error {
 
 // Update the instance config.
 func (vm *Qemu) Update(args db.InstanceArgs, userRequested bool) error {
-	if vm.IsRunning() {
-		return fmt.Errorf("Update whilst running not supported")
-	}
+	isRunning := vm.IsRunning()
 
 	// Set sane defaults for unset keys.
 	if args.Project == "" {
@@ -1764,6 +1997,23 @@ func (vm *Qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		return updateFields
 	})
 
+	// Only NIC devices can be hot plugged into a running VM.
+	if isRunning {
+		for _, key := range changedConfig {
+			if key != "limits.disk.priority" {
+				return fmt.Errorf("Only NIC devices and limits.disk.priority can be changed whilst the VM is running")
+			}
+		}
+
+		for _, devices := range []deviceConfig.Devices{removeDevices, addDevices, updateDevices} {
+			for devName, dev := range devices {
+				if dev["type"] != "nic" {
+					return fmt.Errorf("Device '%s' cannot be changed whilst the VM is running", devName)
+				}
+			}
+		}
+	}
+
 	// Do some validation of the config diff.
 	err = instance.ValidConfig(vm.state.OS, vm.expandedConfig, false, true)
 	if err != nil {
@@ -1782,6 +2032,13 @@ func (vm *Qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		return err
 	}
 
+	if isRunning && shared.StringInSlice("limits.disk.priority", changedConfig) {
+		err = vm.setDiskPriority()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Update MAAS (must run after the MAC addresses have been generated).
 	updateMAAS := false
 	for _, key := range []string{"maas.subnet.ipv4", "maas.subnet.ipv6", "ipv4.address", "ipv6.address"} {
@@ -1889,6 +2146,13 @@ func (vm *Qemu) updateDevices(removeDevices deviceConfig.Devices, addDevices dev
 	// Remove devices in reverse order to how they were added.
 	for _, dev := range removeDevices.Reversed() {
 		if isRunning {
+			if dev.Config["type"] == "nic" {
+				err := vm.deviceDetachNIC(dev.Config["name"])
+				if err != nil {
+					return errors.Wrapf(err, "Failed to detach device '%s'", dev.Name)
+				}
+			}
+
 			err := vm.deviceStop(dev.Name, dev.Config)
 			if err == device.ErrUnsupportedDevType {
 				continue // No point in trying to remove device below.
@@ -1921,10 +2185,18 @@ func (vm *Qemu) updateDevices(removeDevices deviceConfig.Devices, addDevices dev
 		}
 
 		if isRunning {
-			_, err := vm.deviceStart(dev.Name, dev.Config, isRunning)
+			runConf, err := vm.deviceStart(dev.Name, dev.Config, isRunning)
 			if err != nil && err != device.ErrUnsupportedDevType {
 				return errors.Wrapf(err, "Failed to start device '%s'", dev.Name)
 			}
+
+			if runConf != nil && len(runConf.NetworkInterface) > 0 {
+				err = vm.deviceAttachNIC(runConf.NetworkInterface)
+				if err != nil {
+					vm.deviceStop(dev.Name, dev.Config)
+					return errors.Wrapf(err, "Failed to attach device '%s'", dev.Name)
+				}
+			}
 		}
 	}
 
@@ -2256,9 +2528,132 @@ func (vm *Qemu) deviceRemove(deviceName string, rawConfig deviceConfig.Device) e
 	return d.Remove()
 }
 
-// Export publishes the instance.
+// Export writes the metadata tarball of the instance (metadata.yaml and templates). The root disk
+// isn't included as virtual machine images carry it as a separate qcow2 file.
 func (vm *Qemu) Export(w io.Writer, properties map[string]string) error {
-	return fmt.Errorf("Export Not implemented")
+	ctxMap := log.Ctx{
+		"project":   vm.project,
+		"name":      vm.name,
+		"created":   vm.creationDate,
+		"ephemeral": vm.ephemeral,
+		"used":      vm.lastUsedDate}
+
+	if vm.IsRunning() {
+		return fmt.Errorf("Cannot export a running instance as an image")
+	}
+
+	logger.Info("Exporting instance", ctxMap)
+
+	err := vm.export(w, properties)
+	if err != nil {
+		logger.Error("Failed exporting instance", ctxMap)
+		return err
+	}
+
+	logger.Info("Exported instance", ctxMap)
+	return nil
+}
+
+func (vm *Qemu) export(w io.Writer, properties map[string]string) error {
+	// Mount the instance's config volume.
+	ourMount, err := vm.mount()
+	if err != nil {
+		return err
+	}
+
+	if ourMount {
+		defer vm.unmount()
+	}
+
+	// Load the existing metadata.yaml or generate a new one.
+	meta := api.ImageMetadata{}
+	fnam := filepath.Join(vm.Path(), "metadata.yaml")
+	if shared.PathExists(fnam) {
+		content, err := ioutil.ReadFile(fnam)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.Unmarshal(content, &meta)
+		if err != nil {
+			return err
+		}
+	} else {
+		arch := vm.architecture
+		if vm.IsSnapshot() {
+			parentName, _, _ := shared.InstanceGetParentAndSnapshotName(vm.name)
+			parent, err := instance.LoadByProjectAndName(vm.state, vm.project, parentName)
+			if err != nil {
+				return err
+			}
+
+			arch = parent.Architecture()
+		}
+
+		meta.Architecture, _ = osarch.ArchitectureName(arch)
+		if meta.Architecture == "" {
+			meta.Architecture, err = osarch.ArchitectureName(vm.state.OS.Architectures[0])
+			if err != nil {
+				return err
+			}
+		}
+
+		meta.CreationDate = time.Now().UTC().Unix()
+	}
+
+	if properties != nil {
+		meta.Properties = properties
+	}
+
+	data, err := yaml.Marshal(&meta)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := ioutil.TempDir("", "lxd_lxd_metadata_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	fnam = filepath.Join(tempDir, "metadata.yaml")
+	err = ioutil.WriteFile(fnam, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Create the tarball.
+	ctw := containerwriter.NewContainerTarWriter(w, nil)
+
+	fi, err := os.Lstat(fnam)
+	if err != nil {
+		ctw.Close()
+		return err
+	}
+
+	err = ctw.WriteFile(len(tempDir)+1, fnam, fi)
+	if err != nil {
+		ctw.Close()
+		return err
+	}
+
+	// Include all the templates.
+	offset := len(vm.Path()) + 1
+	if shared.PathExists(vm.TemplatesPath()) {
+		err = filepath.Walk(vm.TemplatesPath(), func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			return ctw.WriteFile(offset, path, fi)
+		})
+		if err != nil {
+			ctw.Close()
+			return err
+		}
+	}
+
+	return ctw.Close()
 }
 
 // CGroupGet is not implemented for VMs.
@@ -2786,15 +3181,18 @@ func (vm *Qemu) RenderState() (*api.InstanceState, error) {
 			logger.Warn("Error getting disk usage", log.Ctx{"project": vm.Project(), "instance": vm.Name(), "err": err})
 		}
 
+		status.NetworkUsage = instance.NetworkUsage(vm)
+
 		return status, nil
 	}
 
 	// At least return the Status and StatusCode if we couldn't get any
 	// information for the VM agent.
 	return &api.InstanceState{
-		Pid:        int64(pid),
-		Status:     statusCode.String(),
-		StatusCode: statusCode,
+		Pid:          int64(pid),
+		Status:       statusCode.String(),
+		StatusCode:   statusCode,
+		NetworkUsage: instance.NetworkUsage(vm),
 	}, nil
 }
 
@@ -2853,6 +3251,48 @@ func (vm *Qemu) agentGetState() (*api.InstanceState, error) {
 	return status, nil
 }
 
+// Quiesce asks the agent inside of the VM to run its quiesce hooks and to freeze its filesystems,
+// until Thaw is called or the timeout expires.
+func (vm *Qemu) Quiesce(timeout time.Duration) error {
+	return vm.agentSetState(api.InstanceStatePut{Action: "freeze", Timeout: int(timeout / time.Second)})
+}
+
+// Thaw asks the agent inside of the VM to thaw the filesystems frozen by Quiesce.
+func (vm *Qemu) Thaw() error {
+	return vm.agentSetState(api.InstanceStatePut{Action: "unfreeze"})
+}
+
+// agentSetState connects to the agent inside of the VM and does an API call to change its state.
+func (vm *Qemu) agentSetState(state api.InstanceStatePut) error {
+	// Check if the agent is running.
+	monitor, err := qmp.Connect(vm.getMonitorPath(), vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	if !monitor.AgentReady() {
+		return errQemuAgentOffline
+	}
+
+	client, err := vm.getAgentClient()
+	if err != nil {
+		return err
+	}
+
+	agent, err := lxdClient.ConnectLXDHTTP(nil, client)
+	if err != nil {
+		return err
+	}
+	defer agent.Disconnect()
+
+	_, _, err = agent.RawQuery("PUT", "/1.0/state", state, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // IsRunning returns whether or not the instance is running.
 func (vm *Qemu) IsRunning() bool {
 	state := vm.State()
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/operationlock"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// instanceMoveProject moves a stopped instance, with its snapshots, backups and volumes, to another
+// project of this server under the same name.
+func instanceMoveProject(s *state.State, inst instance.Instance, newProject string, op *operations.Operation) error {
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Snapshots can't be moved to another project on their own")
+	}
+
+	oldProject := inst.Project()
+	name := inst.Name()
+
+	err := projectCheckInstanceNetworkDevices(s.Cluster, newProject, inst.LocalDevices().CloneNative(), inst.Profiles())
+	if err != nil {
+		return err
+	}
+
+	// Keep the instance from being started while its volumes are renamed.
+	locks, err := instanceMoveProjectLock([]instance.Instance{inst})
+	if err != nil {
+		return err
+	}
+	defer instanceMoveProjectUnlock(locks)
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.InstanceProjectMove(oldProject, name, newProject)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move instance %q to project %q", name, newProject)
+	}
+
+	revert.Add(func() {
+		s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.InstanceProjectMove(newProject, name, oldProject)
+		})
+	})
+
+	moved, err := instance.LoadByProjectAndName(s, newProject, name)
+	if err != nil {
+		return err
+	}
+
+	err = instanceMoveProjectFiles(s, moved, oldProject, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+
+	err = moved.UpdateBackupFile()
+	if err != nil {
+		logger.Warn("Failed to update instance backup file", log.Ctx{"project": newProject, "instance": name, "err": err})
+	}
+
+	// Update lease files.
+	networkUpdateStatic(s, "")
+
+	s.Events.SendLifecycle(oldProject, "container-renamed", fmt.Sprintf("/1.0/containers/%s", name), map[string]interface{}{
+		"new_project": newProject,
+	})
+
+	return nil
+}
+
+// instanceMoveProjectLock takes the operation lock of the given instances, failing if any of them is
+// running or busy, so that they can't be started while they're moved to another project. The locks
+// must be released with instanceMoveProjectUnlock.
+func instanceMoveProjectLock(instances []instance.Instance) ([]*operationlock.InstanceOperation, error) {
+	locks := []*operationlock.InstanceOperation{}
+
+	for _, inst := range instances {
+		lock, err := operationlock.Create(inst.ID(), "move", true, false)
+		if err != nil {
+			instanceMoveProjectUnlock(locks)
+			return nil, errors.Wrapf(err, "Failed to lock instance %q", inst.Name())
+		}
+
+		locks = append(locks, lock)
+
+		if inst.IsRunning() {
+			instanceMoveProjectUnlock(locks)
+			return nil, fmt.Errorf("Instance %q must be stopped to be moved to another project", inst.Name())
+		}
+	}
+
+	return locks, nil
+}
+
+// instanceMoveProjectUnlock releases the locks taken by instanceMoveProjectLock.
+func instanceMoveProjectUnlock(locks []*operationlock.InstanceOperation) {
+	for _, lock := range locks {
+		lock.Done(nil)
+	}
+}
+
+// instanceMoveProjectFiles renames the volumes, backups and logs of an instance, which are named
+// after its project, once it got moved from another project in the database. On failure, nothing
+// is left renamed. Moving the files back once the database is reverted is done by calling it again
+// with the projects swapped.
+func instanceMoveProjectFiles(s *state.State, inst instance.Instance, oldProject string, op *operations.Operation) error {
+	pool, err := storagePools.GetPoolByInstance(s, inst)
+	if err == storageDrivers.ErrUnknownDriver || err == storageDrivers.ErrNotImplemented {
+		return fmt.Errorf("The storage pool of instance %q doesn't support moving it to another project", inst.Name())
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Load instance storage pool")
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Rename the backups and logs first, as they're trivially renamed back, while the volumes
+	// can only be once the instance is back in its old project in the database.
+	for _, dir := range []string{shared.VarPath("backups"), shared.LogPath()} {
+		oldPath := filepath.Join(dir, project.Prefix(oldProject, inst.Name()))
+		if !shared.PathExists(oldPath) {
+			continue
+		}
+
+		newPath := filepath.Join(dir, project.Prefix(inst.Project(), inst.Name()))
+		err := os.Rename(oldPath, newPath)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { os.Rename(newPath, oldPath) })
+	}
+
+	// MoveInstanceProject reverts its own changes on failure.
+	err = pool.MoveInstanceProject(inst, oldProject, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move the volumes of instance %q", inst.Name())
+	}
+
+	revert.Success()
+	return nil
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var instanceSnapshotGroupsCmd = APIEndpoint{
+	Name: "instanceSnapshotGroups",
+	Path: "instances/{name}/snapshot-groups",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerSnapshotGroups", Path: "containers/{name}/snapshot-groups"},
+		{Name: "vmSnapshotGroups", Path: "virtual-machines/{name}/snapshot-groups"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceSnapshotGroupsGet, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
+var instanceSnapshotGroupCmd = APIEndpoint{
+	Name: "instanceSnapshotGroup",
+	Path: "instances/{name}/snapshot-groups/{snapshotName}",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerSnapshotGroup", Path: "containers/{name}/snapshot-groups/{snapshotName}"},
+		{Name: "vmSnapshotGroup", Path: "virtual-machines/{name}/snapshot-groups/{snapshotName}"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceSnapshotGroupGet, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
+// instanceCustomVolume is a custom volume attached to an instance.
+type instanceCustomVolume struct {
+	pool string
+	name string
+}
+
+// instanceAttachedCustomVolumes returns the custom volumes attached to an instance.
+func instanceAttachedCustomVolumes(inst instance.Instance) []instanceCustomVolume {
+	volumes := []instanceCustomVolume{}
+	for _, dev := range inst.ExpandedDevices().Sorted() {
+		if dev.Config["type"] != "disk" || dev.Config["path"] == "/" {
+			continue
+		}
+
+		if dev.Config["pool"] == "" || dev.Config["source"] == "" {
+			continue
+		}
+
+		vol := instanceCustomVolume{pool: dev.Config["pool"], name: filepath.Clean(dev.Config["source"])}
+
+		duplicate := false
+		for _, other := range volumes {
+			if other == vol {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			volumes = append(volumes, vol)
+		}
+	}
+
+	return volumes
+}
+
+// instanceCreateSnapshotGroup snapshots an instance together with the custom volumes attached to
+// it, freezing the running instances using any of them for the time of the snapshots so that they
+// are all taken at the same point in time.
+func instanceCreateSnapshotGroup(s *state.State, args db.InstanceArgs, inst instance.Instance, op *operations.Operation) error {
+	_, snapName, _ := shared.InstanceGetParentAndSnapshotName(args.Name)
+
+	volumes := instanceAttachedCustomVolumes(inst)
+	pools := map[string]storagePools.Pool{}
+	for _, vol := range volumes {
+		if pools[vol.pool] != nil {
+			continue
+		}
+
+		pool, err := storagePools.GetPoolByName(s, vol.pool)
+		if err == storageDrivers.ErrUnknownDriver || err == storageDrivers.ErrNotImplemented {
+			return fmt.Errorf("Storage pool %q doesn't support snapshot groups", vol.pool)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		pools[vol.pool] = pool
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	frozen, err := instanceSnapshotGroupFreeze(s, inst, volumes)
+	if err != nil {
+		return err
+	}
+
+	thaw := func() {
+		for _, frozenInst := range frozen {
+			err := frozenInst.Unfreeze()
+			if err != nil {
+				logger.Error("Failed to unfreeze instance after snapshot", log.Ctx{"project": frozenInst.Project(), "instance": frozenInst.Name(), "err": err})
+			}
+		}
+
+		frozen = nil
+	}
+	defer thaw()
+
+	snapInst, err := instanceCreateAsSnapshot(s, args, inst, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { snapInst.Delete() })
+
+	volumeIDs := []int64{}
+	for _, vol := range volumes {
+		pool := pools[vol.pool]
+
+		err = pool.CreateCustomVolumeSnapshot(vol.name, snapName, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to snapshot custom volume %q of storage pool %q", vol.name, vol.pool)
+		}
+
+		volSnapName := vol.name + shared.SnapshotDelimiter + snapName
+		revert.Add(func() { pool.DeleteCustomVolumeSnapshot(volSnapName, op) })
+
+		volumeID, _, err := s.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", volSnapName, db.StoragePoolVolumeTypeCustom, pool.ID())
+		if err != nil {
+			return err
+		}
+
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+
+	thaw()
+
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, volumeID := range volumeIDs {
+			err := tx.InstanceSnapshotVolumeAdd(snapInst.ID(), volumeID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to record the snapshot group")
+	}
+
+	revert.Success()
+	return nil
+}
+
+// instanceSnapshotGroupFreeze freezes the running instances of this member which use any of the
+// volumes, the instance itself included, and returns them.
+func instanceSnapshotGroupFreeze(s *state.State, inst instance.Instance, volumes []instanceCustomVolume) ([]instance.Instance, error) {
+	insts, err := instanceLoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	frozen := []instance.Instance{}
+	for _, other := range insts {
+		if !other.IsRunning() || other.IsFrozen() {
+			continue
+		}
+
+		using := other.Project() == inst.Project() && other.Name() == inst.Name()
+		for _, otherVol := range instanceAttachedCustomVolumes(other) {
+			for _, vol := range volumes {
+				if otherVol == vol {
+					using = true
+				}
+			}
+		}
+
+		if !using {
+			continue
+		}
+
+		err := other.Freeze()
+		if err != nil {
+			for _, frozenInst := range frozen {
+				frozenInst.Unfreeze()
+			}
+
+			return nil, errors.Wrapf(err, "Failed to freeze instance %q", other.Name())
+		}
+
+		frozen = append(frozen, other)
+	}
+
+	return frozen, nil
+}
+
+// instanceSnapshotGroupVolumes returns the custom volumes snapshotted together with an instance
+// snapshot, checking that they can be restored, which requires the instance to be stopped.
+func instanceSnapshotGroupVolumes(s *state.State, inst instance.Instance, snapInst instance.Instance) ([]db.InstanceSnapshotVolume, error) {
+	var volumes []db.InstanceSnapshotVolume
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		volumes, err = tx.InstanceSnapshotVolumes(snapInst.ID())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+
+	if inst.IsRunning() {
+		return nil, fmt.Errorf("The instance must be stopped to restore a snapshot taken with its volumes")
+	}
+
+	for _, vol := range volumes {
+		_, err := storagePools.GetPoolByName(s, vol.Pool)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return volumes, nil
+}
+
+// instanceSnapshotGroupRestore restores the custom volumes of a snapshot group. It's called once the
+// instance itself has been restored so that a failure there leaves the volumes untouched.
+func instanceSnapshotGroupRestore(s *state.State, volumes []db.InstanceSnapshotVolume) error {
+	for _, vol := range volumes {
+		pool, err := storagePools.GetPoolByName(s, vol.Pool)
+		if err != nil {
+			return err
+		}
+
+		volName, snapName, _ := shared.InstanceGetParentAndSnapshotName(vol.Name)
+		err = pool.RestoreCustomVolume(volName, snapName, nil)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to restore custom volume %q of storage pool %q", volName, vol.Pool)
+		}
+	}
+
+	return nil
+}
+
+// instanceSnapshotGroupToAPI returns the snapshot group of an instance snapshot, or nil if no custom
+// volume was snapshotted together with it.
+func instanceSnapshotGroupToAPI(s *state.State, snapInst instance.Instance) (*api.InstanceSnapshotGroup, error) {
+	var volumes []db.InstanceSnapshotVolume
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		volumes, err = tx.InstanceSnapshotVolumes(snapInst.ID())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+
+	_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapInst.Name())
+	group := api.InstanceSnapshotGroup{
+		Name:      snapName,
+		CreatedAt: snapInst.CreationDate(),
+		Volumes:   []api.InstanceSnapshotGroupVolume{},
+	}
+
+	for _, vol := range volumes {
+		volName, volSnapName, _ := shared.InstanceGetParentAndSnapshotName(vol.Name)
+		group.Volumes = append(group.Volumes, api.InstanceSnapshotGroupVolume{
+			Pool:     vol.Pool,
+			Volume:   volName,
+			Snapshot: volSnapName,
+		})
+	}
+
+	return &group, nil
+}
+
+// /1.0/instances/{name}/snapshot-groups
+// List the snapshots of an instance taken together with its custom volumes
+func instanceSnapshotGroupsGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+	recursion := util.IsRecursionRequest(r)
+
+	// Handle requests targeted to an instance on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	snaps, err := inst.Snapshots()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	urls := []string{}
+	groups := []api.InstanceSnapshotGroup{}
+	for _, snap := range snaps {
+		group, err := instanceSnapshotGroupToAPI(d.State(), snap)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if group == nil {
+			continue
+		}
+
+		urls = append(urls, fmt.Sprintf("/%s/instances/%s/snapshot-groups/%s", version.APIVersion, name, group.Name))
+		groups = append(groups, *group)
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, groups)
+}
+
+// /1.0/instances/{name}/snapshot-groups/{snapshotName}
+// Get a snapshot of an instance taken together with its custom volumes
+func instanceSnapshotGroupGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	snapshotName, err := url.QueryUnescape(mux.Vars(r)["snapshotName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	snapInst, err := instance.LoadByProjectAndName(d.State(), project, name+shared.SnapshotDelimiter+snapshotName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	group, err := instanceSnapshotGroupToAPI(d.State(), snapInst)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if group == nil {
+		return response.NotFound(fmt.Errorf("Snapshot %q wasn't taken together with custom volumes", snapshotName))
+	}
+
+	return response.SyncResponseETag(true, group, group)
+}
@@ -103,6 +103,37 @@ func iptablesClear(protocol string, comment string, table string) error {
 	return nil
 }
 
+// iptablesHasRules returns whether rules with the comment are present in any of the tables.
+func iptablesHasRules(protocol string, comment string) (bool, error) {
+	// Detect kernels that lack IPv6 support
+	if !shared.PathExists("/proc/sys/net/ipv6") && protocol == "ipv6" {
+		return false, nil
+	}
+
+	cmd := "iptables"
+	if protocol == "ipv6" {
+		cmd = "ip6tables"
+	}
+
+	_, err := exec.LookPath(cmd)
+	if err != nil {
+		return false, nil
+	}
+
+	for _, table := range []string{"filter", "nat", "mangle"} {
+		output, err := shared.TryRunCommand(cmd, "-w", "-t", table, "-S")
+		if err != nil {
+			return false, fmt.Errorf("Failed to list %s rules for %s (table %s)", protocol, comment, table)
+		}
+
+		if strings.Contains(output, fmt.Sprintf("generated for %s\"", comment)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // NetworkAppend adds a network rule at end of ruleset.
 func NetworkAppend(protocol string, comment string, table string, chain string,
 	rule ...string) error {
@@ -123,6 +154,11 @@ func NetworkClear(protocol string, comment string, table string) error {
 		table)
 }
 
+// NetworkHasRules returns whether network rules are present.
+func NetworkHasRules(protocol string, comment string) (bool, error) {
+	return iptablesHasRules(protocol, fmt.Sprintf("LXD network %s", comment))
+}
+
 // ContainerPrepend adds container rule at start of ruleset.
 func ContainerPrepend(protocol string, comment string, table string,
 	chain string, rule ...string) error {
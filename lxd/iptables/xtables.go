@@ -165,16 +165,36 @@ func (xt XTables) NetworkSetupAllowForwarding(family firewallConsts.Family, name
 }
 
 // NetworkSetupNAT configures NAT
-func (xt XTables) NetworkSetupNAT(family firewallConsts.Family, name string, location firewallConsts.Location, args ...string) error {
-	if location == firewallConsts.LocationPrepend {
-		err := NetworkPrepend(fmt.Sprintf("%s", family), name, "nat", "POSTROUTING", args...)
-		if err != nil {
-			return err
+func (xt XTables) NetworkSetupNAT(family firewallConsts.Family, name string, location firewallConsts.Location, subnet *net.IPNet, srcIP net.IP, exclude []*net.IPNet) error {
+	// If a SNAT source address is specified, use that, otherwise default to using MASQUERADE mode.
+	args := []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "MASQUERADE"}
+	if srcIP != nil {
+		args = []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "SNAT", "--to", srcIP.String()}
+	}
+
+	// Excluded destinations return before reaching the NAT rule, so they are added after it when
+	// prepending and before it when appending.
+	rules := [][]string{args}
+	for _, dest := range exclude {
+		rule := []string{"-s", subnet.String(), "-d", dest.String(), "-j", "RETURN"}
+		if location == firewallConsts.LocationPrepend {
+			rules = append(rules, rule)
+		} else {
+			rules = append([][]string{rule}, rules...)
 		}
-	} else if location == firewallConsts.LocationAppend {
-		err := NetworkAppend(fmt.Sprintf("%s", family), name, "nat", "POSTROUTING", args...)
-		if err != nil {
-			return err
+	}
+
+	for _, rule := range rules {
+		if location == firewallConsts.LocationPrepend {
+			err := NetworkPrepend(fmt.Sprintf("%s", family), name, "nat", "POSTROUTING", rule...)
+			if err != nil {
+				return err
+			}
+		} else if location == firewallConsts.LocationAppend {
+			err := NetworkAppend(fmt.Sprintf("%s", family), name, "nat", "POSTROUTING", rule...)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -243,6 +263,112 @@ func (xt XTables) NetworkSetupTunnelNAT(name string, location firewallConsts.Loc
 	return nil
 }
 
+// NetworkSetupForwardNAT adds the DNAT rules for a network address forward. If targetPort is empty
+// the destination port is kept as is.
+func (xt XTables) NetworkSetupForwardNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddress net.IP, targetPort string) error {
+	comment := fmt.Sprintf("%s forwards", name)
+	dport := strings.Replace(listenPort, "-", ":", -1)
+
+	toDest := targetAddress.String()
+	if targetPort != "" {
+		toDest = fmt.Sprintf("%s:%s", targetAddress, targetPort)
+		if family == firewallConsts.FamilyIPv6 {
+			toDest = fmt.Sprintf("[%s]:%s", targetAddress, targetPort)
+		}
+	}
+
+	// outbound <-> instance
+	err := NetworkPrepend(fmt.Sprintf("%s", family), comment, "nat", "PREROUTING", "-p", protocol, "--destination", listenAddress.String(), "--dport", dport, "-j", "DNAT", "--to-destination", toDest)
+	if err != nil {
+		return err
+	}
+
+	// host <-> instance
+	err = NetworkPrepend(fmt.Sprintf("%s", family), comment, "nat", "OUTPUT", "-p", protocol, "--destination", listenAddress.String(), "--dport", dport, "-j", "DNAT", "--to-destination", toDest)
+	if err != nil {
+		return err
+	}
+
+	// instance <-> instance on the same network (hairpin)
+	hairpinPort := dport
+	if targetPort != "" {
+		hairpinPort = targetPort
+	}
+
+	return NetworkPrepend(fmt.Sprintf("%s", family), comment, "nat", "POSTROUTING", "-p", protocol, "--source", targetAddress.String(), "--destination", targetAddress.String(), "--dport", hairpinPort, "-j", "MASQUERADE")
+}
+
+// NetworkClearForwards removes the rules of all the address forwards of a network.
+func (xt XTables) NetworkClearForwards(family firewallConsts.Family, name string) error {
+	return NetworkClear(fmt.Sprintf("%s", family), fmt.Sprintf("%s forwards", name), "nat")
+}
+
+// NetworkSetupLoadBalancerNAT adds the DNAT rules spreading the connections to a network load
+// balancer randomly across its targets, targetPorts[i] being the port of targetAddresses[i] or
+// empty to keep the destination port. Session affinity isn't supported.
+func (xt XTables) NetworkSetupLoadBalancerNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddresses []net.IP, targetPorts []string, sessionAffinity bool) error {
+	if sessionAffinity {
+		return fmt.Errorf("Load balancer session affinity requires the nftables firewall driver")
+	}
+
+	comment := fmt.Sprintf("%s load-balancers", name)
+	dport := strings.Replace(listenPort, "-", ":", -1)
+	count := len(targetAddresses)
+
+	// Rules are prepended, so the last target goes first.
+	for i := count - 1; i >= 0; i-- {
+		targetAddress := targetAddresses[i]
+		targetPort := targetPorts[i]
+
+		toDest := targetAddress.String()
+		if targetPort != "" {
+			toDest = fmt.Sprintf("%s:%s", targetAddress, targetPort)
+			if family == firewallConsts.FamilyIPv6 {
+				toDest = fmt.Sprintf("[%s]:%s", targetAddress, targetPort)
+			}
+		}
+
+		rule := []string{"-p", protocol, "--destination", listenAddress.String(), "--dport", dport}
+		if i < count-1 {
+			// Each target gets an even share of the connections left over by the previous ones.
+			rule = append(rule, "-m", "statistic", "--mode", "random", "--probability", fmt.Sprintf("%.5f", 1/float64(count-i)))
+		}
+
+		rule = append(rule, "-j", "DNAT", "--to-destination", toDest)
+
+		// outbound <-> instance and host <-> instance
+		for _, chain := range []string{"PREROUTING", "OUTPUT"} {
+			err := NetworkPrepend(fmt.Sprintf("%s", family), comment, "nat", chain, rule...)
+			if err != nil {
+				return err
+			}
+		}
+
+		// instance <-> instance on the same network (hairpin)
+		hairpinPort := dport
+		if targetPort != "" {
+			hairpinPort = targetPort
+		}
+
+		err := NetworkPrepend(fmt.Sprintf("%s", family), comment, "nat", "POSTROUTING", "-p", protocol, "--source", targetAddress.String(), "--destination", targetAddress.String(), "--dport", hairpinPort, "-j", "MASQUERADE")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NetworkClearLoadBalancers removes the rules of all the load balancers of a network.
+func (xt XTables) NetworkClearLoadBalancers(family firewallConsts.Family, name string) error {
+	return NetworkClear(fmt.Sprintf("%s", family), fmt.Sprintf("%s load-balancers", name), "nat")
+}
+
+// NetworkHasRules returns whether any rules of the network are present.
+func (xt XTables) NetworkHasRules(family firewallConsts.Family, name string) (bool, error) {
+	return NetworkHasRules(fmt.Sprintf("%s", family), name)
+}
+
 // Helper Functions
 
 // generateFilterEbtablesRules returns a customised set of ebtables filter rules based on the device.
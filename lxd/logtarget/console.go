@@ -0,0 +1,107 @@
+package logtarget
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// consoleReadMax is how much of a console log is read at once.
+const consoleReadMax = 1024 * 1024
+
+// Console is the console log of an instance.
+type Console struct {
+	Project  string
+	Instance string
+	Path     string
+}
+
+// ReadConsoles forwards the lines added to the given console logs since they were last read.
+// Console logs seen for the first time are forwarded from their current end, and those which
+// aren't given anymore are forgotten.
+func (f *Forwarder) ReadConsoles(consoles []Console) {
+	f.lock.Lock()
+	offsets := f.consoleOffsets
+	f.lock.Unlock()
+
+	next := make(map[string]int64, len(consoles))
+	for _, console := range consoles {
+		offset, ok := offsets[console.Path]
+		if !ok {
+			offset = -1
+		}
+
+		next[console.Path] = f.readConsole(console, offset)
+	}
+
+	f.lock.Lock()
+	f.consoleOffsets = next
+	f.lock.Unlock()
+}
+
+// readConsole forwards the complete lines of a console log past the given offset, or none if the
+// offset is negative, and returns the offset of the first line left to forward.
+func (f *Forwarder) readConsole(console Console, offset int64) int64 {
+	file, err := os.Open(console.Path)
+	if err != nil {
+		return 0
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+
+	// Start from the end of logs seen for the first time and from the start of truncated ones.
+	if offset < 0 {
+		return info.Size()
+	}
+
+	if info.Size() < offset {
+		offset = 0
+	}
+
+	if info.Size() == offset {
+		return offset
+	}
+
+	buf := make([]byte, consoleReadMax)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		logger.Warn("Failed to read console log", log.Ctx{"path": console.Path, "err": err})
+		return offset
+	}
+
+	buf = buf[:n]
+
+	// Leave the last line until it's complete, unless it fills the whole buffer.
+	end := bytes.LastIndexByte(buf, '\n') + 1
+	if end == 0 && n == consoleReadMax {
+		end = n
+	}
+
+	now := time.Now()
+	for _, line := range strings.Split(string(buf[:end]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		f.Send(Entry{
+			Timestamp: now,
+			Type:      "console",
+			Project:   console.Project,
+			Instance:  console.Instance,
+			Message:   line,
+		})
+	}
+
+	return offset + int64(end)
+}
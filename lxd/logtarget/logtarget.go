@@ -0,0 +1,297 @@
+package logtarget
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// Types lists the kinds of logs which can be forwarded.
+var Types = []string{"audit", "console", "lifecycle"}
+
+// Entries are sent in batches of up to batchSize, at least every batchInterval. Entries are
+// dropped rather than blocking when more than queueSize are waiting to be sent to a target.
+const (
+	batchSize     = 100
+	batchInterval = time.Second
+	queueSize     = 10000
+)
+
+// Entry is a log line forwarded to the targets.
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Project   string    `json:"project,omitempty"`
+	Instance  string    `json:"instance,omitempty"`
+	Location  string    `json:"location,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// Config holds the targets logs are forwarded to and which logs each of them gets.
+type Config struct {
+	LokiURL      string
+	LokiUsername string
+	LokiPassword string
+	LokiLabels   map[string]string
+	LokiTypes    []string
+
+	SyslogAddress string
+	SyslogTypes   []string
+}
+
+// target sends batches of entries to a remote log server.
+type target interface {
+	send(entries []Entry) error
+	close()
+}
+
+// Forwarder sends the audit entries, lifecycle events and instance console output to the
+// configured log targets.
+type Forwarder struct {
+	location string
+	workers  []*worker
+
+	// Offsets up to which the console logs have been forwarded, by path.
+	consoleOffsets map[string]int64
+
+	lock sync.Mutex
+}
+
+// NewForwarder returns a new forwarder, with no target configured.
+func NewForwarder() *Forwarder {
+	return &Forwarder{
+		consoleOffsets: map[string]int64{},
+	}
+}
+
+// Configure replaces the targets logs are forwarded to, sending the entries queued for the
+// previous ones first.
+func (f *Forwarder) Configure(config Config) error {
+	workers := []*worker{}
+
+	if config.LokiURL != "" {
+		loki, err := newLoki(config.LokiURL, config.LokiUsername, config.LokiPassword, config.LokiLabels)
+		if err != nil {
+			return err
+		}
+
+		workers = append(workers, newWorker("loki", loki, config.LokiTypes))
+	}
+
+	if config.SyslogAddress != "" {
+		syslog, err := newSyslog(config.SyslogAddress)
+		if err != nil {
+			for _, w := range workers {
+				w.stop()
+			}
+
+			return err
+		}
+
+		workers = append(workers, newWorker("syslog", syslog, config.SyslogTypes))
+	}
+
+	f.lock.Lock()
+	old := f.workers
+	f.workers = workers
+	f.lock.Unlock()
+
+	for _, w := range old {
+		w.stop()
+	}
+
+	return nil
+}
+
+// Stop sends the queued entries and disconnects from the targets.
+func (f *Forwarder) Stop() {
+	err := f.Configure(Config{})
+	if err != nil {
+		logger.Warn("Failed to stop log forwarding", log.Ctx{"err": err})
+	}
+}
+
+// SetLocation sets the name of the cluster member the entries are forwarded from.
+func (f *Forwarder) SetLocation(location string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.location = location
+}
+
+// Enabled returns whether logs of the given type are forwarded to any target.
+func (f *Forwarder) Enabled(logType string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, w := range f.workers {
+		if shared.StringInSlice(logType, w.types) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Send queues an entry for the targets which get its type.
+func (f *Forwarder) Send(entry Entry) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if entry.Location == "" {
+		entry.Location = f.location
+	}
+
+	for _, w := range f.workers {
+		if shared.StringInSlice(entry.Type, w.types) {
+			w.queue(entry)
+		}
+	}
+}
+
+// SendEvent forwards a lifecycle event. Other events are ignored.
+func (f *Forwarder) SendEvent(group string, event api.Event) {
+	if event.Type != "lifecycle" || !f.Enabled("lifecycle") {
+		return
+	}
+
+	lifecycle := api.EventLifecycle{}
+	err := json.Unmarshal(event.Metadata, &lifecycle)
+	if err != nil {
+		return
+	}
+
+	instance := ""
+	source := strings.SplitN(lifecycle.Source, "?", 2)[0]
+	for _, prefix := range []string{"/1.0/instances/", "/1.0/containers/", "/1.0/virtual-machines/"} {
+		if strings.HasPrefix(source, prefix) {
+			instance = strings.SplitN(strings.TrimPrefix(source, prefix), "/", 2)[0]
+			break
+		}
+	}
+
+	f.Send(Entry{
+		Timestamp: event.Timestamp,
+		Type:      "lifecycle",
+		Project:   group,
+		Instance:  instance,
+		Location:  event.Location,
+		Message:   string(event.Metadata),
+	})
+}
+
+// SendAudit forwards an audit entry.
+func (f *Forwarder) SendAudit(entry api.AuditEntry) {
+	if !f.Enabled("audit") {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warn("Failed to encode audit entry for forwarding", log.Ctx{"err": err})
+		return
+	}
+
+	f.Send(Entry{
+		Timestamp: entry.Timestamp,
+		Type:      "audit",
+		Project:   entry.Project,
+		Location:  entry.Location,
+		Message:   string(data),
+	})
+}
+
+// worker batches the entries sent to a target.
+type worker struct {
+	name   string
+	target target
+	types  []string
+
+	entries chan Entry
+	done    chan struct{}
+}
+
+func newWorker(name string, target target, types []string) *worker {
+	w := &worker{
+		name:    name,
+		target:  target,
+		types:   types,
+		entries: make(chan Entry, queueSize),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// queue queues an entry, dropping it if the queue is full.
+func (w *worker) queue(entry Entry) {
+	select {
+	case w.entries <- entry:
+	default:
+	}
+}
+
+// stop sends the queued entries and disconnects from the target.
+func (w *worker) stop() {
+	close(w.entries)
+	<-w.done
+}
+
+func (w *worker) run() {
+	defer close(w.done)
+	defer w.target.close()
+
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := []Entry{}
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.send(batch)
+				return
+			}
+
+			batch = append(batch, entry)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		w.send(batch)
+		batch = []Entry{}
+	}
+}
+
+func (w *worker) send(batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := w.target.send(batch)
+	if err != nil {
+		logger.Warn("Failed to forward logs", log.Ctx{"target": w.name, "entries": len(batch), "err": err})
+	}
+}
+
+// ValidateTypes checks that the given log types can be forwarded.
+func ValidateTypes(types []string) error {
+	for _, logType := range types {
+		if !shared.StringInSlice(logType, Types) {
+			return fmt.Errorf("Invalid log type %q, must be one of: %s", logType, strings.Join(Types, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package logtarget_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/logtarget"
+	"github.com/lxc/lxd/shared/api"
+)
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func TestForwarder_Loki(t *testing.T) {
+	var lock sync.Mutex
+	streams := []lokiStream{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "lxd", username)
+		assert.Equal(t, "secret", password)
+
+		request := struct {
+			Streams []lokiStream `json:"streams"`
+		}{}
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		streams = append(streams, request.Streams...)
+	}))
+	defer server.Close()
+
+	forwarder := logtarget.NewForwarder()
+	forwarder.SetLocation("node1")
+
+	err := forwarder.Configure(logtarget.Config{
+		LokiURL:      server.URL,
+		LokiUsername: "lxd",
+		LokiPassword: "secret",
+		LokiLabels:   map[string]string{"env": "prod"},
+		LokiTypes:    []string{"audit", "lifecycle"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, forwarder.Enabled("lifecycle"))
+	assert.False(t, forwarder.Enabled("console"))
+
+	metadata, err := json.Marshal(api.EventLifecycle{Action: "instance-started", Source: "/1.0/instances/c1?project=p1"})
+	require.NoError(t, err)
+
+	forwarder.SendEvent("p1", api.Event{Type: "lifecycle", Timestamp: time.Now(), Metadata: metadata})
+	forwarder.SendEvent("p1", api.Event{Type: "operation", Timestamp: time.Now(), Metadata: []byte("{}")})
+	forwarder.SendAudit(api.AuditEntry{Timestamp: time.Now(), Method: "POST", Resource: "/1.0/instances", Project: "default"})
+	forwarder.Send(logtarget.Entry{Timestamp: time.Now(), Type: "console", Message: "Dropped"})
+
+	// Stopping sends the queued entries.
+	forwarder.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	require.Len(t, streams, 2)
+
+	assert.Equal(t, map[string]string{
+		"app":      "lxd",
+		"env":      "prod",
+		"type":     "lifecycle",
+		"project":  "p1",
+		"instance": "c1",
+		"location": "node1",
+	}, streams[0].Stream)
+	assert.Equal(t, string(metadata), streams[0].Values[0][1])
+
+	assert.Equal(t, "audit", streams[1].Stream["type"])
+	assert.Equal(t, "default", streams[1].Stream["project"])
+}
+
+func TestForwarder_ReadConsoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-logtarget-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	lines := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			Streams []lokiStream `json:"streams"`
+		}{}
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err)
+
+		for _, stream := range request.Streams {
+			for _, value := range stream.Values {
+				lines <- stream.Stream["instance"] + ": " + value[1]
+			}
+		}
+	}))
+	defer server.Close()
+
+	forwarder := logtarget.NewForwarder()
+	require.NoError(t, forwarder.Configure(logtarget.Config{LokiURL: server.URL, LokiTypes: []string{"console"}}))
+
+	path := filepath.Join(dir, "console.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("Before forwarding\n"), 0600))
+
+	consoles := []logtarget.Console{{Project: "default", Instance: "c1", Path: path}}
+
+	// The existing content is skipped.
+	forwarder.ReadConsoles(consoles)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString("Booting\r\nReady\nLogin: ")
+	require.NoError(t, err)
+	file.Close()
+
+	// The incomplete last line is left for later.
+	forwarder.ReadConsoles(consoles)
+	forwarder.Stop()
+
+	close(lines)
+
+	forwarded := []string{}
+	for line := range lines {
+		forwarded = append(forwarded, line)
+	}
+
+	assert.Equal(t, []string{"c1: Booting", "c1: Ready"}, forwarded)
+}
+
+func TestParseSyslogAddress(t *testing.T) {
+	network, address, err := logtarget.ParseSyslogAddress("syslog.example.com:514")
+	require.NoError(t, err)
+	assert.Equal(t, "udp", network)
+	assert.Equal(t, "syslog.example.com:514", address)
+
+	network, _, err = logtarget.ParseSyslogAddress("tcp://syslog.example.com:514")
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", network)
+
+	_, _, err = logtarget.ParseSyslogAddress("tls://syslog.example.com:6514")
+	assert.Error(t, err)
+
+	_, _, err = logtarget.ParseSyslogAddress("syslog.example.com")
+	assert.Error(t, err)
+}
+
+func TestParseLokiLabels(t *testing.T) {
+	labels, err := logtarget.ParseLokiLabels("env=prod, region=eu-west")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "region": "eu-west"}, labels)
+
+	_, err = logtarget.ParseLokiLabels("env")
+	assert.Error(t, err)
+
+	_, err = logtarget.ParseLokiLabels("my-env=prod")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package logtarget
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lokiLabelName matches the valid names of Loki labels.
+var lokiLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// loki pushes entries to the API of a Loki server, labelled with their type, project, instance
+// and location on top of the configured labels.
+type loki struct {
+	url      string
+	username string
+	password string
+	labels   map[string]string
+
+	client *http.Client
+}
+
+func newLoki(rawURL string, username string, password string, labels map[string]string) (*loki, error) {
+	err := ValidateLokiURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loki{
+		url:      strings.TrimSuffix(rawURL, "/") + "/loki/api/v1/push",
+		username: username,
+		password: password,
+		labels:   labels,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type lokiRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (l *loki) send(entries []Entry) error {
+	data, err := json.Marshal(l.encode(entries))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", l.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Loki returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (l *loki) close() {
+}
+
+// encode groups the entries into streams by labels.
+func (l *loki) encode(entries []Entry) lokiRequest {
+	streams := map[string]*lokiStream{}
+	keys := []string{}
+
+	for _, entry := range entries {
+		labels := map[string]string{"app": "lxd", "type": entry.Type}
+		for name, value := range l.labels {
+			labels[name] = value
+		}
+
+		for name, value := range map[string]string{"project": entry.Project, "instance": entry.Instance, "location": entry.Location} {
+			if value != "" {
+				labels[name] = value
+			}
+		}
+
+		key := lokiStreamKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels, Values: [][2]string{}}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Message})
+	}
+
+	request := lokiRequest{Streams: make([]lokiStream, 0, len(keys))}
+	for _, key := range keys {
+		request.Streams = append(request.Streams, *streams[key])
+	}
+
+	return request
+}
+
+// lokiStreamKey returns a string identifying a set of labels.
+func lokiStreamKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, value))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// ValidateLokiURL checks that the URL of a Loki server is an HTTP or HTTPS URL.
+func ValidateLokiURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid Loki URL %q, must be an HTTP or HTTPS URL", value)
+	}
+
+	return nil
+}
+
+// ParseLokiLabels parses a comma separated list of label=value pairs.
+func ParseLokiLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		fields := strings.SplitN(pair, "=", 2)
+		if len(fields) != 2 || !lokiLabelName.MatchString(fields[0]) {
+			return nil, fmt.Errorf("Invalid Loki label %q, must be NAME=VALUE", pair)
+		}
+
+		labels[fields[0]] = fields[1]
+	}
+
+	return labels, nil
+}
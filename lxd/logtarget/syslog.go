@@ -0,0 +1,71 @@
+package logtarget
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net"
+	"strings"
+)
+
+// remoteSyslog sends entries to a remote syslog server, one JSON encoded message each.
+type remoteSyslog struct {
+	writer *syslog.Writer
+}
+
+func newSyslog(address string) (*remoteSyslog, error) {
+	network, addr, err := ParseSyslogAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, "lxd")
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteSyslog{writer: writer}, nil
+}
+
+func (s *remoteSyslog) send(entries []Entry) error {
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		err = s.writer.Info(string(data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *remoteSyslog) close() {
+	s.writer.Close()
+}
+
+// ParseSyslogAddress returns the network and address of a syslog server, given as HOST:PORT,
+// udp://HOST:PORT or tcp://HOST:PORT. UDP is used when no scheme is given.
+func ParseSyslogAddress(address string) (string, string, error) {
+	network := "udp"
+
+	fields := strings.SplitN(address, "://", 2)
+	if len(fields) == 2 {
+		network = fields[0]
+		address = fields[1]
+	}
+
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("Invalid syslog protocol %q, must be udp or tcp", network)
+	}
+
+	_, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid syslog address %q: %v", address, err)
+	}
+
+	return network, address, nil
+}
@@ -29,6 +29,7 @@ type cmdGlobal struct {
 	flagVersion bool
 
 	flagLogFile    string
+	flagLogFormat  string
 	flagLogDebug   bool
 	flagLogSyslog  bool
 	flagLogTrace   []string
@@ -62,7 +63,7 @@ func (c *cmdGlobal) Run(cmd *cobra.Command, args []string) error {
 		syslog = "lxd"
 	}
 
-	log, err := logging.GetLogger(syslog, c.flagLogFile, c.flagLogVerbose, c.flagLogDebug, events.NewEventHandler())
+	log, err := logging.GetLogger(syslog, c.flagLogFile, c.flagLogFormat, c.flagLogVerbose, c.flagLogDebug, events.NewEventHandler())
 	if err != nil {
 		return err
 	}
@@ -87,6 +88,7 @@ func main() {
 	app.PersistentFlags().BoolVar(&globalCmd.flagVersion, "version", false, "Print version number")
 	app.PersistentFlags().BoolVarP(&globalCmd.flagHelp, "help", "h", false, "Print help")
 	app.PersistentFlags().StringVar(&globalCmd.flagLogFile, "logfile", "", "Path to the log file"+"``")
+	app.PersistentFlags().StringVar(&globalCmd.flagLogFormat, "logformat", "text", "Format of the log messages (text or json)"+"``")
 	app.PersistentFlags().BoolVar(&globalCmd.flagLogSyslog, "syslog", false, "Log to syslog")
 	app.PersistentFlags().StringArrayVar(&globalCmd.flagLogTrace, "trace", []string{}, "Log tracing targets"+"``")
 	app.PersistentFlags().BoolVarP(&globalCmd.flagLogDebug, "debug", "d", false, "Show all debug messages")
@@ -172,6 +174,10 @@ func main() {
 	netcatCmd := cmdNetcat{global: &globalCmd}
 	app.AddCommand(netcatCmd.Command())
 
+	// recover sub-command
+	recoverCmd := cmdRecover{global: &globalCmd}
+	app.AddCommand(recoverCmd.Command())
+
 	// shutdown sub-command
 	shutdownCmd := cmdShutdown{global: &globalCmd}
 	app.AddCommand(shutdownCmd.Command())
@@ -192,6 +198,10 @@ func main() {
 	clusterCmd := cmdCluster{global: &globalCmd}
 	app.AddCommand(clusterCmd.Command())
 
+	// database sub-command
+	databaseCmd := cmdDatabase{global: &globalCmd}
+	app.AddCommand(databaseCmd.Command())
+
 	// Run the main command and handle errors
 	err := app.Execute()
 	if err != nil {
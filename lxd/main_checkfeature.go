@@ -1,6 +1,10 @@
 package main
 
 import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -324,3 +328,15 @@ func CanUseSeccompListener() bool {
 func CanUseSeccompListenerContinue() bool {
 	return bool(C.seccomp_notify_aware == 2)
 }
+
+// CanUseCoreScheduling checks whether the kernel supports core scheduling cookies (PR_SCHED_CORE).
+func CanUseCoreScheduling() bool {
+	// Values from linux/prctl.h, not yet exposed by x/sys/unix.
+	const prSchedCore = 62
+	const prSchedCoreGet = 0
+	const pidTypePID = 0
+
+	var cookie uint64
+	err := unix.Prctl(prSchedCore, prSchedCoreGet, 0, pidTypePID, uintptr(unsafe.Pointer(&cookie)))
+	return err == nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,8 +14,10 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/sys"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/termios"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 type cmdCluster struct {
@@ -36,6 +39,14 @@ func (c *cmdCluster) Command() *cobra.Command {
 	recover := cmdClusterRecoverFromQuorumLoss{global: c.global}
 	cmd.AddCommand(recover.Command())
 
+	// Show
+	show := cmdClusterShow{global: c.global}
+	cmd.AddCommand(show.Command())
+
+	// Guided recovery
+	guidedRecover := cmdClusterRecover{global: c.global}
+	cmd.AddCommand(guidedRecover.Command())
+
 	return cmd
 }
 
@@ -147,3 +158,238 @@ Do you want to proceed? (yes/no): `)
 	}
 	return nil
 }
+
+// clusterRaftMember is a member of the raft configuration, as shown and edited
+// by the recovery commands.
+type clusterRaftMember struct {
+	ID      uint64 `yaml:"id"`
+	Address string `yaml:"address"`
+	Role    string `yaml:"role"`
+}
+
+// clusterRaftRoles maps the raft roles to their names in the configuration.
+var clusterRaftRoles = map[db.RaftRole]string{
+	db.RaftVoter:   "voter",
+	db.RaftStandBy: "stand-by",
+	db.RaftSpare:   "spare",
+}
+
+// clusterRaftConfig returns the last known raft configuration of the local
+// member.
+func clusterRaftConfig(database *db.Node) ([]clusterRaftMember, error) {
+	nodes := []db.RaftNode{}
+	err := database.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		nodes, err = tx.RaftNodes()
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get database nodes")
+	}
+
+	members := make([]clusterRaftMember, len(nodes))
+	for i, node := range nodes {
+		members[i] = clusterRaftMember{ID: node.ID, Address: node.Address, Role: clusterRaftRoles[node.Role]}
+	}
+
+	return members, nil
+}
+
+// clusterRaftNodes parses an edited raft configuration.
+func clusterRaftNodes(content []byte) ([]db.RaftNode, error) {
+	members := []clusterRaftMember{}
+	err := yaml.Unmarshal(content, &members)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]db.RaftRole{}
+	for role, name := range clusterRaftRoles {
+		roles[name] = role
+	}
+
+	nodes := make([]db.RaftNode, len(members))
+	for i, member := range members {
+		role, ok := roles[member.Role]
+		if !ok {
+			return nil, fmt.Errorf("Invalid role %q for raft node %d", member.Role, member.ID)
+		}
+
+		nodes[i] = db.RaftNode{ID: member.ID, Address: member.Address, Role: role}
+	}
+
+	return nodes, nil
+}
+
+type cmdClusterShow struct {
+	global *cmdGlobal
+}
+
+func (c *cmdClusterShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "show"
+	cmd.Short = "Show the last known raft configuration of this cluster member"
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterShow) Run(cmd *cobra.Command, args []string) error {
+	os := sys.DefaultOS()
+
+	db, _, err := db.OpenNode(filepath.Join(os.VarDir, "database"), nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open local database")
+	}
+
+	members, err := clusterRaftConfig(db)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&members)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+type cmdClusterRecover struct {
+	global *cmdGlobal
+}
+
+func (c *cmdClusterRecover) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "recover"
+	cmd.Short = "Recover the database of a cluster which has lost quorum"
+	cmd.Long = `Description:
+  Recover the database of a cluster which has lost quorum
+
+  The last known raft configuration of this member is shown and can be edited,
+  for example to change the address of members or to demote lost members to
+  spares. The database of this member is then reconstituted from it.
+
+  If the configuration is provided on standard input, it's applied without
+  any prompt. This lets the same configuration, as printed by "lxd cluster show"
+  and edited, be applied on all the surviving database members.
+`
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterRecover) Run(cmd *cobra.Command, args []string) error {
+	// Make sure that the daemon is not running.
+	_, err := lxd.ConnectLXDUnix("", nil)
+	if err == nil {
+		return fmt.Errorf("The LXD daemon is running, please stop it first.")
+	}
+
+	database, _, err := db.OpenNode(filepath.Join(sys.DefaultOS().VarDir, "database"), nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open local database")
+	}
+
+	// If stdin isn't a terminal, read the configuration from it.
+	if !termios.IsTerminal(int(os.Stdin.Fd())) {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		nodes, err := clusterRaftNodes(content)
+		if err != nil {
+			return err
+		}
+
+		return cluster.Reconfigure(database, nodes)
+	}
+
+	members, err := clusterRaftConfig(database)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&members)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Last known raft configuration of this member:\n\n%s\n", data)
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("Do you want to edit it? If not, this member will become the only database member (yes/no) [default=no]: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSuffix(input, "\n")
+
+	if !shared.StringInSlice(strings.ToLower(input), []string{"yes"}) {
+		err = c.promptConfirmation()
+		if err != nil {
+			return err
+		}
+
+		return cluster.Recover(database)
+	}
+
+	content, err := shared.TextEditor("", data)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var nodes []db.RaftNode
+		nodes, err = clusterRaftNodes(content)
+		if err == nil {
+			err = c.promptConfirmation()
+			if err != nil {
+				return err
+			}
+
+			err = cluster.Reconfigure(database, nodes)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration: %s\n", err)
+			fmt.Println("Press enter to start the editor again")
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+func (c *cmdClusterRecover) promptConfirmation() error {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf(`You should run this command only if the cluster has lost quorum, with the LXD
+daemon of all the surviving database members stopped. The same configuration
+must be applied on each of them before starting them again.
+
+Members left out of the configuration won't be able to access the database
+anymore, but all information about them will be preserved in the database.
+
+Do you want to proceed? (yes/no): `)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSuffix(input, "\n")
+
+	if !shared.StringInSlice(strings.ToLower(input), []string{"yes"}) {
+		return fmt.Errorf("Recover operation aborted")
+	}
+	return nil
+}
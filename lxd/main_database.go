@@ -0,0 +1,155 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/sys"
+	"github.com/lxc/lxd/shared"
+)
+
+type cmdDatabase struct {
+	global *cmdGlobal
+}
+
+func (c *cmdDatabase) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "database"
+	cmd.Short = "Low-level database administration commands"
+	cmd.Long = `Description:
+  Low level administration tools for the LXD databases.
+`
+	// Restore
+	restore := cmdDatabaseRestore{global: c.global}
+	cmd.AddCommand(restore.Command())
+
+	return cmd
+}
+
+type cmdDatabaseRestore struct {
+	global    *cmdGlobal
+	flagLocal bool
+}
+
+func (c *cmdDatabaseRestore) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "restore <backup>"
+	cmd.Short = "Restore the databases from a backup on the next start"
+	cmd.Long = `Description:
+  Restore the databases from a backup on the next start
+
+  The backup is a tarball created through the /1.0/database/backups API.
+  Its global database replaces the content of the cluster database when the
+  daemon next starts, and with --local its local database replaces the one
+  of this member too.
+
+  The daemon must be stopped. In a cluster, only stage the backup on one
+  member, since the restored content gets replicated to all of them.
+`
+	cmd.RunE = c.Run
+
+	cmd.Flags().BoolVar(&c.flagLocal, "local", false, "Also restore the local database of this member")
+
+	return cmd
+}
+
+func (c *cmdDatabaseRestore) Run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		cmd.Help()
+
+		if len(args) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("Missing required arguments")
+	}
+
+	// Make sure that the daemon is not running.
+	_, err := lxd.ConnectLXDUnix("", nil)
+	if err == nil {
+		return fmt.Errorf("The LXD daemon is running, please stop it first.")
+	}
+
+	names := []string{"global.db"}
+	if c.flagLocal {
+		names = append(names, "local.db")
+	}
+
+	dir := databaseRestoreDir(sys.DefaultOS().VarDir)
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	err = c.extract(args[0], dir, names)
+	if err != nil {
+		os.RemoveAll(dir)
+		return errors.Wrapf(err, "Failed to extract backup %q", args[0])
+	}
+
+	fmt.Println("The backup will be restored when LXD next starts")
+	return nil
+}
+
+// extract writes the given files of a backup tarball to a directory.
+func (c *cmdDatabaseRestore) extract(path string, dir string, names []string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !shared.StringInSlice(hdr.Name, names) {
+			continue
+		}
+
+		out, err := os.OpenFile(filepath.Join(dir, hdr.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		if !shared.PathExists(filepath.Join(dir, name)) {
+			return fmt.Errorf("The backup doesn't contain %s", name)
+		}
+	}
+
+	return nil
+}
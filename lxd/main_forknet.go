@@ -74,7 +74,8 @@ void forknet(void)
 		forkdonetinfo(pid);
 	}
 
-	if (strcmp(command, "detach") == 0)
+	// Both enter the network namespace given as a file.
+	if (strcmp(command, "detach") == 0 || strcmp(command, "txqueuelen") == 0)
 		forkdonetdetach(cur);
 }
 */
@@ -112,6 +113,13 @@ func (c *cmdForknet) Command() *cobra.Command {
 	cmdDetach.RunE = c.RunDetach
 	cmd.AddCommand(cmdDetach)
 
+	// txqueuelen
+	cmdTxQueueLen := &cobra.Command{}
+	cmdTxQueueLen.Use = "txqueuelen <netns file> <ifname> <length>"
+	cmdTxQueueLen.Args = cobra.ExactArgs(3)
+	cmdTxQueueLen.RunE = c.RunTxQueueLen
+	cmd.AddCommand(cmdTxQueueLen)
+
 	return cmd
 }
 
@@ -163,3 +171,20 @@ func (c *cmdForknet) RunDetach(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func (c *cmdForknet) RunTxQueueLen(cmd *cobra.Command, args []string) error {
+	ifName := args[1]
+	length := args[2]
+
+	if ifName == "" {
+		return fmt.Errorf("ifname argument is required")
+	}
+
+	// Set the transmit queue length of the interface in the container's network namespace.
+	_, err := shared.RunCommand("ip", "link", "set", "dev", ifName, "txqueuelen", length)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -135,6 +135,14 @@ func (c *cmdInit) Run(cmd *cobra.Command, args []string) error {
 		config.Node.Config["cluster.https_address"] = config.Node.Config["core.https_address"]
 	}
 
+	// Derive the fields needed to join a cluster from the join token, if one was provided.
+	if config.Cluster != nil && config.Cluster.ClusterToken != "" {
+		err = initDataClusterJoinToken(config.Cluster, config.Cluster.ClusterToken)
+		if err != nil {
+			return errors.Wrap(err, "Invalid join token")
+		}
+	}
+
 	// Detect if the user has chosen to join a cluster using the new
 	// cluster join API format, and use the dedicated API if so.
 	if config.Cluster != nil && config.Cluster.ClusterAddress != "" && config.Cluster.ServerAddress != "" {
@@ -167,7 +175,7 @@ func (c *cmdInit) availableStorageDrivers(poolType string) []string {
 	}
 
 	// Get info for new drivers.
-	s := state.NewState(nil, nil, nil, sys.DefaultOS(), nil, nil, nil, nil, nil)
+	s := state.NewState(nil, nil, nil, sys.DefaultOS(), nil, nil, nil, nil, nil, nil, nil)
 	info := storageDrivers.SupportedDrivers(s)
 	availableDrivers := []string{}
 	for _, entry := range info {
@@ -122,33 +122,48 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		if cli.AskBool("Are you joining an existing cluster? (yes/no) [default=no]: ", "no") {
 			// Existing cluster
 			config.Cluster.ServerAddress = serverAddress
-			for {
-				// Cluster URL
-				clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
-				_, _, err := net.SplitHostPort(clusterAddress)
-				if err != nil {
-					clusterAddress = fmt.Sprintf("%s:8443", clusterAddress)
-				}
-				config.Cluster.ClusterAddress = clusterAddress
 
-				// Cluster certificate
-				cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", config.Cluster.ClusterAddress), version.UserAgent)
-				if err != nil {
-					fmt.Printf("Error connecting to existing cluster node: %v\n", err)
-					continue
-				}
+			joinToken := cli.AskBool("Do you have a join token? (yes/no) [default=no]: ", "no")
+			if joinToken {
+				for {
+					err := initDataClusterJoinToken(config.Cluster, cli.AskString("Please provide join token: ", "", nil))
+					if err != nil {
+						fmt.Printf("Invalid join token: %v\n", err)
+						continue
+					}
 
-				certDigest := shared.CertFingerprint(cert)
-				fmt.Printf("Cluster fingerprint: %s\n", certDigest)
-				fmt.Printf("You can validate this fingerprint by running \"lxc info\" locally on an existing node.\n")
-				if !cli.AskBool("Is this the correct fingerprint? (yes/no) [default=no]: ", "no") {
-					return fmt.Errorf("User aborted configuration")
+					fmt.Printf("Joining the cluster as %q through %s\n", config.Cluster.ServerName, config.Cluster.ClusterAddress)
+					break
 				}
-				config.Cluster.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+			} else {
+				for {
+					// Cluster URL
+					clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
+					_, _, err := net.SplitHostPort(clusterAddress)
+					if err != nil {
+						clusterAddress = fmt.Sprintf("%s:8443", clusterAddress)
+					}
+					config.Cluster.ClusterAddress = clusterAddress
 
-				// Cluster password
-				config.Cluster.ClusterPassword = cli.AskPasswordOnce("Cluster trust password: ")
-				break
+					// Cluster certificate
+					cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", config.Cluster.ClusterAddress), version.UserAgent)
+					if err != nil {
+						fmt.Printf("Error connecting to existing cluster node: %v\n", err)
+						continue
+					}
+
+					certDigest := shared.CertFingerprint(cert)
+					fmt.Printf("Cluster fingerprint: %s\n", certDigest)
+					fmt.Printf("You can validate this fingerprint by running \"lxc info\" locally on an existing node.\n")
+					if !cli.AskBool("Is this the correct fingerprint? (yes/no) [default=no]: ", "no") {
+						return fmt.Errorf("User aborted configuration")
+					}
+					config.Cluster.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+					// Cluster password
+					config.Cluster.ClusterPassword = cli.AskPasswordOnce("Cluster trust password: ")
+					break
+				}
 			}
 
 			// Root is required to access the certificate files
@@ -174,6 +189,11 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 				return errors.Wrap(err, "Failed to setup trust relationship with cluster")
 			}
 
+			// Join tokens are single-use, so the trust relationship can't be setup again.
+			if joinToken {
+				config.Cluster.ClusterPassword = ""
+			}
+
 			// Client parameters to connect to the target cluster node.
 			args := &lxd.ConnectionArgs{
 				TLSClientCert: string(cert.PublicKey()),
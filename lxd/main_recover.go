@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+)
+
+type cmdRecover struct {
+	global *cmdGlobal
+}
+
+func (c *cmdRecover) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "recover"
+	cmd.Short = "Recover missing instances and volumes from existing and unknown storage pools"
+	cmd.Long = `Description:
+  Recover missing instances and volumes from existing and unknown storage pools
+
+  This command is mostly used for disaster recovery. It scans the storage
+  pools known to LXD, as well as pools which exist on disk but are unknown
+  to LXD, for instance and custom volumes missing from the database.
+
+  The volumes found are listed along with any missing dependency, such as
+  projects and profiles, which need to be recreated first. Once confirmed,
+  the database entries of the storage pools, instances, snapshots and
+  custom volumes are recreated.
+`
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdRecover) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	if len(args) > 0 {
+		return fmt.Errorf("Invalid arguments")
+	}
+
+	// Only root should run this
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("This must be run as root")
+	}
+
+	d, err := lxd.ConnectLXDUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	existingPools, err := d.GetStoragePools()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("This LXD server currently has the following storage pools:")
+	for _, pool := range existingPools {
+		fmt.Printf(" - %s (backend=%q, source=%q)\n", pool.Name, pool.Driver, pool.Config["source"])
+	}
+
+	// Gather the pools which exist on disk but are unknown to LXD.
+	unknownPools := []api.StoragePoolsPost{}
+	for cli.AskBool("Would you like to recover another storage pool? (yes/no) [default=no]: ", "no") {
+		pool := api.StoragePoolsPost{
+			StoragePoolPut: api.StoragePoolPut{
+				Config: map[string]string{},
+			},
+		}
+
+		pool.Name = cli.AskString("Name of the storage pool: ", "", nil)
+		pool.Driver = cli.AskChoice("Name of the storage backend (btrfs, cephfs, dir, lvm, zfs): ", []string{"btrfs", "cephfs", "dir", "lvm", "zfs"}, "")
+		pool.Config["source"] = cli.AskString("Source of the storage pool (block device, volume group, dataset, path, ... as applicable): ", "", nil)
+
+		for {
+			configKey := cli.AskString("Additional storage pool configuration property (KEY=VALUE, empty when done): ", "", func(value string) error {
+				if value != "" && !strings.Contains(value, "=") {
+					return fmt.Errorf("Invalid property, expected KEY=VALUE")
+				}
+
+				return nil
+			})
+			if configKey == "" {
+				break
+			}
+
+			fields := strings.SplitN(configKey, "=", 2)
+			pool.Config[fields[0]] = fields[1]
+		}
+
+		unknownPools = append(unknownPools, pool)
+	}
+
+	fmt.Println("The recovery process will be scanning the following storage pools:")
+	for _, pool := range existingPools {
+		fmt.Printf(" - EXISTING: %q (backend=%q, source=%q)\n", pool.Name, pool.Driver, pool.Config["source"])
+	}
+
+	for _, pool := range unknownPools {
+		fmt.Printf(" - NEW: %q (backend=%q, source=%q)\n", pool.Name, pool.Driver, pool.Config["source"])
+	}
+
+	if !cli.AskBool("Would you like to continue with scanning for lost volumes? (yes/no) [default=yes]: ", "yes") {
+		return nil
+	}
+
+	fmt.Println("Scanning for unknown volumes...")
+
+	// Check the pools for unknown volumes and their missing dependencies.
+	for {
+		resp, _, err := d.RawQuery("POST", "/internal/recover/validate", internalRecoverValidatePost{Pools: unknownPools}, "")
+		if err != nil {
+			return fmt.Errorf("Failed validation request: %v", err)
+		}
+
+		res := internalRecoverValidateResult{}
+		err = resp.MetadataAsStruct(&res)
+		if err != nil {
+			return fmt.Errorf("Failed parsing validation response: %v", err)
+		}
+
+		if len(res.UnknownVolumes) == 0 {
+			fmt.Println("No unknown volumes found. Nothing to do.")
+			return nil
+		}
+
+		fmt.Println("The following unknown volumes have been found:")
+		for _, vol := range res.UnknownVolumes {
+			fmt.Printf(" - %s %q on pool %q in project %q (includes %d snapshots)\n", strings.Title(vol.Type), vol.Name, vol.Pool, vol.Project, vol.SnapshotCount)
+		}
+
+		if len(res.DependencyErrors) == 0 {
+			break
+		}
+
+		fmt.Println("You are currently missing the following:")
+		for _, depErr := range res.DependencyErrors {
+			fmt.Printf(" - %s\n", depErr)
+		}
+
+		cli.AskString("Please create those missing entries and then hit ENTER: ", "", func(string) error { return nil })
+	}
+
+	if !cli.AskBool("Would you like those to be recovered? (yes/no) [default=no]: ", "no") {
+		return nil
+	}
+
+	fmt.Println("Starting recovery...")
+
+	_, _, err = d.RawQuery("POST", "/internal/recover/import", internalRecoverImportPost{Pools: unknownPools}, "")
+	if err != nil {
+		return fmt.Errorf("Failed import request: %v", err)
+	}
+
+	return nil
+}
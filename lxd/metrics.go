@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var metricsCmd = APIEndpoint{
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet},
+}
+
+// metricsGet exposes the accumulated network usage of the instances of this node, along with the
+// CPU, memory and disk usage of the running ones, in the Prometheus text format.
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	// Handle requests targeted to another node
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Project() != instances[j].Project() {
+			return instances[i].Project() < instances[j].Project()
+		}
+
+		return instances[i].Name() < instances[j].Name()
+	})
+
+	var received bytes.Buffer
+	var sent bytes.Buffer
+	var cpu bytes.Buffer
+	var memory bytes.Buffer
+	var disk bytes.Buffer
+
+	for _, inst := range instances {
+		var usage map[string]api.InstanceStateNetworkUsage
+
+		if inst.IsRunning() {
+			state, err := inst.RenderState()
+			if err != nil {
+				logger.Warn("Failed to get instance state", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				continue
+			}
+
+			usage = state.NetworkUsage
+
+			labels := fmt.Sprintf(`project="%s",name="%s"`, inst.Project(), inst.Name())
+			fmt.Fprintf(&cpu, "lxd_instance_cpu_seconds_total{%s} %g\n", labels, float64(state.CPU.Usage)/1e9)
+			fmt.Fprintf(&memory, "lxd_instance_memory_usage_bytes{%s} %d\n", labels, state.Memory.Usage)
+
+			devices := []string{}
+			for devName := range state.Disk {
+				devices = append(devices, devName)
+			}
+			sort.Strings(devices)
+
+			for _, devName := range devices {
+				fmt.Fprintf(&disk, "lxd_instance_disk_usage_bytes{%s,device=\"%s\"} %d\n", labels, devName, state.Disk[devName].Usage)
+			}
+		} else {
+			usage = instance.NetworkUsage(inst)
+		}
+
+		devices := []string{}
+		for devName := range usage {
+			devices = append(devices, devName)
+		}
+		sort.Strings(devices)
+
+		for _, devName := range devices {
+			labels := fmt.Sprintf(`project="%s",name="%s",device="%s"`, inst.Project(), inst.Name(), devName)
+			fmt.Fprintf(&received, "lxd_instance_network_receive_bytes_total{%s} %d\n", labels, usage[devName].BytesReceived)
+			fmt.Fprintf(&sent, "lxd_instance_network_transmit_bytes_total{%s} %d\n", labels, usage[devName].BytesSent)
+		}
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "# HELP lxd_instance_network_receive_bytes_total Bytes received by the instance NIC.\n")
+		fmt.Fprintf(w, "# TYPE lxd_instance_network_receive_bytes_total counter\n")
+		_, err := w.Write(received.Bytes())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "# HELP lxd_instance_network_transmit_bytes_total Bytes sent by the instance NIC.\n")
+		fmt.Fprintf(w, "# TYPE lxd_instance_network_transmit_bytes_total counter\n")
+		_, err = w.Write(sent.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "# HELP lxd_instance_cpu_seconds_total CPU time used by the running instance.\n")
+		fmt.Fprintf(w, "# TYPE lxd_instance_cpu_seconds_total counter\n")
+		_, err = w.Write(cpu.Bytes())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "# HELP lxd_instance_memory_usage_bytes Memory used by the running instance.\n")
+		fmt.Fprintf(w, "# TYPE lxd_instance_memory_usage_bytes gauge\n")
+		_, err = w.Write(memory.Bytes())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "# HELP lxd_instance_disk_usage_bytes Disk space used by the running instance disk.\n")
+		fmt.Fprintf(w, "# TYPE lxd_instance_disk_usage_bytes gauge\n")
+		_, err = w.Write(disk.Bytes())
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(metricsDatabase())
+		return err
+	})
+}
+
+// metricsDatabase returns the number, failures and durations of the queries this node ran against
+// the cluster database, by kind (exec or query).
+func metricsDatabase() []byte {
+	stats := query.GetStats()
+
+	kinds := []string{}
+	for kind := range stats {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# HELP lxd_db_queries_total Queries run against the cluster database.\n")
+	fmt.Fprintf(&buf, "# TYPE lxd_db_queries_total counter\n")
+	for _, kind := range kinds {
+		fmt.Fprintf(&buf, "lxd_db_queries_total{kind=\"%s\"} %d\n", kind, stats[kind].Count)
+	}
+
+	fmt.Fprintf(&buf, "# HELP lxd_db_query_errors_total Queries run against the cluster database which failed.\n")
+	fmt.Fprintf(&buf, "# TYPE lxd_db_query_errors_total counter\n")
+	for _, kind := range kinds {
+		fmt.Fprintf(&buf, "lxd_db_query_errors_total{kind=\"%s\"} %d\n", kind, stats[kind].Errors)
+	}
+
+	fmt.Fprintf(&buf, "# HELP lxd_db_query_duration_seconds Duration of the queries run against the cluster database.\n")
+	fmt.Fprintf(&buf, "# TYPE lxd_db_query_duration_seconds histogram\n")
+	for _, kind := range kinds {
+		s := stats[kind]
+
+		var count uint64
+		for i, bound := range query.StatsBuckets {
+			count += s.Buckets[i]
+			fmt.Fprintf(&buf, "lxd_db_query_duration_seconds_bucket{kind=\"%s\",le=\"%g\"} %d\n", kind, bound, count)
+		}
+
+		fmt.Fprintf(&buf, "lxd_db_query_duration_seconds_bucket{kind=\"%s\",le=\"+Inf\"} %d\n", kind, s.Count)
+		fmt.Fprintf(&buf, "lxd_db_query_duration_seconds_sum{kind=\"%s\"} %g\n", kind, s.Sum)
+		fmt.Fprintf(&buf, "lxd_db_query_duration_seconds_count{kind=\"%s\"} %d\n", kind, s.Count)
+	}
+
+	return buf.Bytes()
+}
+
+// networkUsageTask periodically persists the network usage of the running instances so it
+// survives them being stopped from within.
+func networkUsageTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for network usage", log.Ctx{"err": err})
+			return
+		}
+
+		for _, inst := range instances {
+			if !inst.IsRunning() {
+				continue
+			}
+
+			err = instance.NetworkUsageUpdate(inst)
+			if err != nil {
+				logger.Warn("Failed to record network usage", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			}
+		}
+	}
+
+	return f, task.Every(5 * time.Minute)
+}
@@ -3,6 +3,7 @@ package migration
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/shared"
@@ -171,10 +172,14 @@ func progressWrapperRender(op *operations.Operation, key string, description str
 		progress = fmt.Sprintf("%s: %s (%s/s)", description, units.GetByteSizeString(progressInt, 2), units.GetByteSizeString(speedInt, 2))
 	}
 
-	if meta[key] != progress {
-		meta[key] = progress
-		op.UpdateMetadata(meta)
+	if meta[key] == progress {
+		return
 	}
+
+	// Also report the bytes transferred through the structured progress.
+	shared.SetProgressMetadata(meta, strings.TrimSuffix(key, "_progress"), description, 0, progressInt, speedInt)
+	meta[key] = progress
+	op.UpdateMetadata(meta)
 }
 
 // ProgressReader reports the read progress.
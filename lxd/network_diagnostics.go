@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var instanceNetworkDiagnosticsCmd = APIEndpoint{
+	Name: "instanceNetworkDiagnostics",
+	Path: "instances/{name}/network-diagnostics",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerNetworkDiagnostics", Path: "containers/{name}/network-diagnostics"},
+		{Name: "vmNetworkDiagnostics", Path: "virtual-machines/{name}/network-diagnostics"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceNetworkDiagnosticsGet, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
+// Results of the network checks.
+const (
+	networkDiagnosticOK      = "ok"
+	networkDiagnosticFailed  = "failed"
+	networkDiagnosticSkipped = "skipped"
+)
+
+// /1.0/instances/{name}/network-diagnostics
+// Check the networking of the NIC devices of an instance
+func instanceNetworkDiagnosticsGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	devices := inst.ExpandedDevices()
+	devNames := []string{}
+	for devName, m := range devices {
+		if m["type"] == "nic" {
+			devNames = append(devNames, devName)
+		}
+	}
+	sort.Strings(devNames)
+
+	diagnostics := []api.InstanceNetworkDiagnostic{}
+	for _, devName := range devNames {
+		results, err := networkDiagnoseNIC(d.State(), inst, devName, devices[devName])
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		diagnostics = append(diagnostics, results...)
+	}
+
+	return response.SyncResponse(true, diagnostics)
+}
+
+// networkDiagnoseNIC runs the checks of a NIC device of an instance, in order: bridge membership,
+// DHCP lease, firewall rules, OVN port binding and uplink reachability.
+func networkDiagnoseNIC(s *state.State, inst instance.Instance, devName string, m map[string]string) ([]api.InstanceNetworkDiagnostic, error) {
+	result := func(check string, status string, format string, args ...interface{}) api.InstanceNetworkDiagnostic {
+		return api.InstanceNetworkDiagnostic{Device: devName, Check: check, Status: status, Message: fmt.Sprintf(format, args...)}
+	}
+
+	// OVN isn't supported by this server, so there are never ports to check.
+	ovn := result("ovn", networkDiagnosticSkipped, "OVN networks aren't supported by this server")
+
+	if m["nictype"] != "bridged" {
+		return []api.InstanceNetworkDiagnostic{
+			result("bridge", networkDiagnosticSkipped, "Only bridged NICs are checked (NIC type %q)", m["nictype"]),
+			result("dhcp", networkDiagnosticSkipped, "Only bridged NICs are checked (NIC type %q)", m["nictype"]),
+			result("firewall", networkDiagnosticSkipped, "Only bridged NICs are checked (NIC type %q)", m["nictype"]),
+			ovn,
+			networkDiagnoseUplink(nil, result),
+		}, nil
+	}
+
+	parent := m["parent"]
+	volatile := inst.LocalConfig()
+
+	// Only LXD managed networks have leases and firewall rules to check.
+	n, err := networkLoadByName(s, parent)
+	if err != nil && err != db.ErrNoSuchObject {
+		return nil, err
+	}
+
+	diagnostics := []api.InstanceNetworkDiagnostic{}
+
+	// Bridge membership
+	hostName := m["host_name"]
+	if hostName == "" {
+		hostName = volatile[fmt.Sprintf("volatile.%s.host_name", devName)]
+	}
+
+	if !inst.IsRunning() {
+		diagnostics = append(diagnostics, result("bridge", networkDiagnosticSkipped, "The instance isn't running"))
+	} else if hostName == "" || !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", hostName)) {
+		diagnostics = append(diagnostics, result("bridge", networkDiagnosticFailed, "The host interface of the NIC can't be found"))
+	} else {
+		bridge := networkDiagnoseBridgeOf(hostName)
+		if bridge == parent {
+			diagnostics = append(diagnostics, result("bridge", networkDiagnosticOK, "Host interface %q is connected to %q", hostName, parent))
+		} else if bridge == "" {
+			diagnostics = append(diagnostics, result("bridge", networkDiagnosticFailed, "Host interface %q isn't connected to any bridge", hostName))
+		} else {
+			diagnostics = append(diagnostics, result("bridge", networkDiagnosticFailed, "Host interface %q is connected to %q instead of %q", hostName, bridge, parent))
+		}
+	}
+
+	// DHCP lease
+	hwaddr := m["hwaddr"]
+	if hwaddr == "" {
+		hwaddr = volatile[fmt.Sprintf("volatile.%s.hwaddr", devName)]
+	}
+
+	if n == nil {
+		diagnostics = append(diagnostics, result("dhcp", networkDiagnosticSkipped, "%q isn't a LXD managed network", parent))
+	} else if !networkDiagnoseDHCPEnabled(n.Config()) {
+		diagnostics = append(diagnostics, result("dhcp", networkDiagnosticSkipped, "DHCP is disabled on %q", parent))
+	} else if hwaddr == "" {
+		diagnostics = append(diagnostics, result("dhcp", networkDiagnosticFailed, "The NIC has no MAC address yet"))
+	} else {
+		addresses, err := networkGetLeaseAddresses(s, parent, hwaddr)
+		if err != nil {
+			diagnostics = append(diagnostics, result("dhcp", networkDiagnosticFailed, "Failed to read the leases of %q: %v", parent, err))
+		} else if len(addresses) == 0 {
+			diagnostics = append(diagnostics, result("dhcp", networkDiagnosticFailed, "No DHCP lease for %s on %q", hwaddr, parent))
+		} else {
+			leases := []string{}
+			for _, addr := range addresses {
+				leases = append(leases, addr.Address)
+			}
+
+			diagnostics = append(diagnostics, result("dhcp", networkDiagnosticOK, "Leased %s", strings.Join(leases, ", ")))
+		}
+	}
+
+	// Firewall rules
+	if n == nil {
+		diagnostics = append(diagnostics, result("firewall", networkDiagnosticSkipped, "%q isn't a LXD managed network", parent))
+	} else {
+		diagnostics = append(diagnostics, networkDiagnoseFirewall(s, n, result))
+	}
+
+	diagnostics = append(diagnostics, ovn)
+	diagnostics = append(diagnostics, networkDiagnoseUplink(n, result))
+
+	return diagnostics, nil
+}
+
+// networkDiagnoseBridgeOf returns the name of the bridge the host interface is connected to, if any.
+func networkDiagnoseBridgeOf(hostName string) string {
+	link, err := os.Readlink(fmt.Sprintf("/sys/class/net/%s/master", hostName))
+	if err != nil {
+		return ""
+	}
+
+	bridge := filepath.Base(link)
+
+	// Ports of openvswitch bridges all have the datapath as master.
+	if bridge == "ovs-system" {
+		output, err := shared.RunCommand("ovs-vsctl", "port-to-br", hostName)
+		if err != nil {
+			return ""
+		}
+
+		return strings.TrimSpace(output)
+	}
+
+	return bridge
+}
+
+// networkDiagnoseDHCPEnabled returns whether dnsmasq hands out leases on the network.
+func networkDiagnoseDHCPEnabled(config map[string]string) bool {
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if shared.StringInSlice(config[fmt.Sprintf("%s.address", family)], []string{"", "none"}) {
+			continue
+		}
+
+		if config[fmt.Sprintf("%s.dhcp", family)] == "" || shared.IsTrue(config[fmt.Sprintf("%s.dhcp", family)]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// networkDiagnoseFirewall checks that the firewall rules LXD sets up for the network are present.
+func networkDiagnoseFirewall(s *state.State, n *network, result func(string, string, string, ...interface{}) api.InstanceNetworkDiagnostic) api.InstanceNetworkDiagnostic {
+	config := n.Config()
+	families := []struct {
+		key    string
+		label  string
+		family firewallConsts.Family
+	}{
+		{"ipv4", "IPv4", firewallConsts.FamilyIPv4},
+		{"ipv6", "IPv6", firewallConsts.FamilyIPv6},
+	}
+
+	checked := []string{}
+	for _, f := range families {
+		key := f.key
+		enabled := !shared.StringInSlice(config[fmt.Sprintf("%s.address", key)], []string{"", "none"})
+		if key == "ipv4" && config["bridge.mode"] == "fan" {
+			enabled = true
+		}
+
+		if !enabled {
+			continue
+		}
+
+		// Rules only get added for NAT or when the firewall isn't disabled.
+		firewall := config[fmt.Sprintf("%s.firewall", key)]
+		if !shared.IsTrue(config[fmt.Sprintf("%s.nat", key)]) && firewall != "" && !shared.IsTrue(firewall) {
+			continue
+		}
+
+		found, err := s.Firewall.NetworkHasRules(f.family, n.name)
+		if err != nil {
+			return result("firewall", networkDiagnosticFailed, "Failed to list the firewall rules: %v", err)
+		}
+
+		if !found {
+			return result("firewall", networkDiagnosticFailed, "Missing %s firewall rules of %q", f.label, n.name)
+		}
+
+		checked = append(checked, f.label)
+	}
+
+	if len(checked) == 0 {
+		return result("firewall", networkDiagnosticSkipped, "No firewall rules expected for %q", n.name)
+	}
+
+	return result("firewall", networkDiagnosticOK, "Found %s firewall rules of %q", strings.Join(checked, " and "), n.name)
+}
+
+// networkDiagnoseUplink checks that the network is up and that the host has a default route to
+// forward the instance traffic to. The network is nil if not managed by LXD.
+func networkDiagnoseUplink(n *network, result func(string, string, string, ...interface{}) api.InstanceNetworkDiagnostic) api.InstanceNetworkDiagnostic {
+	if n != nil {
+		if !n.IsRunning() {
+			return result("uplink", networkDiagnosticFailed, "Network %q isn't running", n.name)
+		}
+
+		routing := n.Config()["ipv4.routing"]
+		if !shared.StringInSlice(n.Config()["ipv4.address"], []string{"", "none"}) && (routing == "" || shared.IsTrue(routing)) {
+			content, err := ioutil.ReadFile("/proc/sys/net/ipv4/ip_forward")
+			if err == nil && strings.TrimSpace(string(content)) != "1" {
+				return result("uplink", networkDiagnosticFailed, "IPv4 forwarding is disabled on the host")
+			}
+		}
+	}
+
+	devices := networkDiagnoseDefaultRoutes()
+	if len(devices) == 0 {
+		return result("uplink", networkDiagnosticFailed, "The host has no default route")
+	}
+
+	return result("uplink", networkDiagnosticOK, "Default route through %s", strings.Join(devices, ", "))
+}
+
+// networkDiagnoseDefaultRoutes returns the host interfaces having an IPv4 or IPv6 default route.
+func networkDiagnoseDefaultRoutes() []string {
+	devices := []string{}
+
+	add := func(dev string) {
+		if !shared.StringInSlice(dev, devices) {
+			devices = append(devices, dev)
+		}
+	}
+
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+	content, err := ioutil.ReadFile("/proc/net/route")
+	if err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+				continue
+			}
+
+			add(fields[0])
+		}
+	}
+
+	// Destination PrefixLen Source SrcPrefixLen NextHop Metric RefCnt Use Flags Iface
+	content, err = ioutil.ReadFile("/proc/net/ipv6_route")
+	if err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[0] != strings.Repeat("0", 32) || fields[1] != "00" || fields[9] == "lo" {
+				continue
+			}
+
+			add(fields[9])
+		}
+	}
+
+	return devices
+}
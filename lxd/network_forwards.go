@@ -0,0 +1,570 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkForwardsCmd = APIEndpoint{
+	Path: "networks/{name}/forwards",
+
+	Get:  APIEndpointAction{Handler: networkForwardsGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: networkForwardsPost, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+}
+
+var networkForwardCmd = APIEndpoint{
+	Path: "networks/{name}/forwards/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkForwardDelete, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Get:    APIEndpointAction{Handler: networkForwardGet, AccessHandler: AllowAuthenticated},
+	Patch:  APIEndpointAction{Handler: networkForwardPatch, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Put:    APIEndpointAction{Handler: networkForwardPut, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+}
+
+// API endpoints
+func networkForwardsGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	recursion := util.IsRecursionRequest(r)
+
+	networkID, network, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Clients restricted to projects only see the networks of those.
+	if !requestHasProject(r, projectNetworkOwner(network.Config)) {
+		return response.Forbidden(nil)
+	}
+
+	forwards, err := d.cluster.NetworkForwards(networkID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, forward := range forwards {
+			urls = append(urls, fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, name, forward.ListenAddress))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, forwards)
+}
+
+func networkForwardsPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	req := api.NetworkForwardsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to apply the forward which was added to the database.
+	if isClusterNotification(r) {
+		err = n.forwardsApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	listenAddress := net.ParseIP(req.ListenAddress)
+	if listenAddress == nil {
+		return response.BadRequest(fmt.Errorf("Invalid listen address %q", req.ListenAddress))
+	}
+	req.ListenAddress = listenAddress.String()
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkForward(d.cluster, project, n.config, req.ListenAddress)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = networkForwardValidate(n, listenAddress, &req.NetworkForwardPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.NetworkForwardGet(n.id, req.ListenAddress)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("A forward for %q already exists", req.ListenAddress))
+	}
+
+	_, _, err = d.cluster.NetworkLoadBalancerGet(n.id, req.ListenAddress)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("A load balancer for %q already exists", req.ListenAddress))
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	id, err := d.cluster.NetworkForwardCreate(n.id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		d.cluster.NetworkForwardDelete(id)
+		networkForwardsRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.DeleteNetworkForward(name, req.ListenAddress)
+		})
+	})
+
+	err = n.forwardsApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.CreateNetworkForward(name, req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, name, req.ListenAddress))
+}
+
+func networkForwardGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	networkID, network, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Clients restricted to projects only see the networks of those.
+	if !requestHasProject(r, projectNetworkOwner(network.Config)) {
+		return response.Forbidden(nil)
+	}
+
+	_, forward, err := d.cluster.NetworkForwardGet(networkID, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{forward.ListenAddress, forward.Description, forward.Config, forward.Ports}
+
+	return response.SyncResponseETag(true, forward, etag)
+}
+
+func networkForwardPut(d *Daemon, r *http.Request) response.Response {
+	return doNetworkForwardUpdate(d, r, false)
+}
+
+func networkForwardPatch(d *Daemon, r *http.Request) response.Response {
+	return doNetworkForwardUpdate(d, r, true)
+}
+
+func doNetworkForwardUpdate(d *Daemon, r *http.Request, patch bool) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to apply the forward which was updated in the database.
+	if isClusterNotification(r) {
+		err = n.forwardsApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	id, forward, err := d.cluster.NetworkForwardGet(n.id, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkForward(d.cluster, project, n.config, forward.ListenAddress)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{forward.ListenAddress, forward.Description, forward.Config, forward.Ports}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkForwardPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if patch {
+		// Config stacking
+		if req.Config == nil {
+			req.Config = map[string]string{}
+		}
+
+		for k, v := range forward.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				req.Config[k] = v
+			}
+		}
+
+		if req.Ports == nil {
+			req.Ports = forward.Ports
+		}
+	}
+
+	err = networkForwardValidate(n, net.ParseIP(forward.ListenAddress), &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = d.cluster.NetworkForwardUpdate(id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		d.cluster.NetworkForwardUpdate(id, &forward.NetworkForwardPut)
+		networkForwardsRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.UpdateNetworkForward(name, listenAddress, forward.NetworkForwardPut, "")
+		})
+	})
+
+	err = n.forwardsApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.UpdateNetworkForward(name, listenAddress, req, "")
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.EmptySyncResponse
+}
+
+func networkForwardDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to remove the forward which was deleted from the database.
+	if isClusterNotification(r) {
+		err = n.forwardsApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	id, forward, err := d.cluster.NetworkForwardGet(n.id, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkConfig(d.cluster, project, n.config, nil)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = d.cluster.NetworkForwardDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		req := api.NetworkForwardsPost{NetworkForwardPut: forward.NetworkForwardPut, ListenAddress: forward.ListenAddress}
+		d.cluster.NetworkForwardCreate(n.id, &req)
+		networkForwardsRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.CreateNetworkForward(name, req)
+		})
+	})
+
+	err = n.forwardsApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.DeleteNetworkForward(name, listenAddress)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.EmptySyncResponse
+}
+
+// networkListenAddressFromRequest returns the listen address of the request URL in its canonical
+// form, as stored in the database.
+func networkListenAddressFromRequest(r *http.Request) string {
+	listenAddress := mux.Vars(r)["listenAddress"]
+
+	ip := net.ParseIP(listenAddress)
+	if ip == nil {
+		return listenAddress
+	}
+
+	return ip.String()
+}
+
+// networkForwardsNotify notifies all other nodes of a change to a network's forwards.
+func networkForwardsNotify(d *Daemon, hook func(client lxd.InstanceServer) error) error {
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
+	if err != nil {
+		return err
+	}
+
+	return notifier(hook)
+}
+
+// networkForwardsRevert re-applies the forwards of a network on all nodes once a failed change got
+// reverted in the database. The notified nodes apply the forwards from the database, whatever the
+// hook is.
+func networkForwardsRevert(d *Daemon, n *network, hook func(client lxd.InstanceServer) error) {
+	err := n.forwardsApply()
+	if err != nil {
+		logger.Error("Failed to re-apply network forwards", log.Ctx{"network": n.name, "err": err})
+	}
+
+	err = networkForwardsNotify(d, hook)
+	if err != nil {
+		logger.Error("Failed to notify other nodes of reverted network forwards", log.Ctx{"network": n.name, "err": err})
+	}
+}
+
+// networkForwardValidate checks the configuration and ports of a forward on the given network.
+func networkForwardValidate(n *network, listenAddress net.IP, forward *api.NetworkForwardPut) error {
+	isIPv4 := listenAddress.To4() != nil
+
+	for k, v := range forward.Config {
+		switch k {
+		case "target_address":
+			err := networkForwardValidTarget(n, isIPv4, v)
+			if err != nil {
+				return err
+			}
+		default:
+			if !strings.HasPrefix(k, "user.") {
+				return fmt.Errorf("Invalid network forward configuration key %q", k)
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	for i, port := range forward.Ports {
+		if !shared.StringInSlice(port.Protocol, []string{"tcp", "udp"}) {
+			return fmt.Errorf("Invalid protocol %q for port %d", port.Protocol, i)
+		}
+
+		start, end, err := networkForwardPortRange(port.ListenPort)
+		if err != nil {
+			return fmt.Errorf("Invalid listen port %q for port %d", port.ListenPort, i)
+		}
+
+		if port.TargetPort != "" {
+			if start != end {
+				return fmt.Errorf("A target port can't be used with a listen port range for port %d", i)
+			}
+
+			err = networkValidPort(port.TargetPort)
+			if err != nil {
+				return fmt.Errorf("Invalid target port %q for port %d", port.TargetPort, i)
+			}
+		}
+
+		target := port.TargetAddress
+		if target == "" {
+			target = forward.Config["target_address"]
+		}
+
+		if target == "" {
+			return fmt.Errorf("No target address for port %d and no default target address", i)
+		}
+
+		err = networkForwardValidTarget(n, isIPv4, target)
+		if err != nil {
+			return err
+		}
+
+		for p := start; p <= end; p++ {
+			key := fmt.Sprintf("%s/%d", port.Protocol, p)
+			if used[key] {
+				return fmt.Errorf("Listen port %s/%d is used more than once", port.Protocol, p)
+			}
+
+			used[key] = true
+		}
+	}
+
+	return nil
+}
+
+// networkForwardValidTarget checks that a target address is within the network's subnet of the
+// listen address family.
+func networkForwardValidTarget(n *network, isIPv4 bool, value string) error {
+	target := net.ParseIP(value)
+	if target == nil {
+		return fmt.Errorf("Invalid target address %q", value)
+	}
+
+	if (target.To4() != nil) != isIPv4 {
+		return fmt.Errorf("Target address %q doesn't match the listen address family", value)
+	}
+
+	key := "ipv6.address"
+	if isIPv4 {
+		key = "ipv4.address"
+	}
+
+	_, subnet, err := net.ParseCIDR(n.config[key])
+	if err != nil || !subnet.Contains(target) {
+		return fmt.Errorf("Target address %q isn't within the network's subnet", value)
+	}
+
+	return nil
+}
+
+// networkForwardPortRange parses a single port or a "<start>-<end>" port range.
+func networkForwardPortRange(value string) (int64, int64, error) {
+	fields := strings.SplitN(value, "-", 2)
+
+	start, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || start < 1 || start > 65535 {
+		return -1, -1, fmt.Errorf("Invalid port %q", fields[0])
+	}
+
+	end := start
+	if len(fields) == 2 {
+		end, err = strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || end < start || end > 65535 {
+			return -1, -1, fmt.Errorf("Invalid port range %q", value)
+		}
+	}
+
+	return start, end, nil
+}
+
+// forwardsApply refreshes the firewall rules of the network's forwards if the network is running.
+func (n *network) forwardsApply() error {
+	if !n.IsRunning() {
+		return nil
+	}
+
+	return n.forwardsSetup()
+}
+
+// forwardsSetup (re)creates the firewall rules for all the address forwards of the network.
+func (n *network) forwardsSetup() error {
+	err := n.forwardsClear()
+	if err != nil {
+		return err
+	}
+
+	forwards, err := n.state.Cluster.NetworkForwards(n.id)
+	if err != nil {
+		return err
+	}
+
+	for _, forward := range forwards {
+		listenAddress := net.ParseIP(forward.ListenAddress)
+		if listenAddress == nil {
+			continue
+		}
+
+		family := firewallConsts.Family(firewallConsts.FamilyIPv6)
+		if listenAddress.To4() != nil {
+			family = firewallConsts.FamilyIPv4
+		}
+
+		for _, port := range forward.Ports {
+			target := port.TargetAddress
+			if target == "" {
+				target = forward.Config["target_address"]
+			}
+
+			err = n.state.Firewall.NetworkSetupForwardNAT(family, n.name, port.Protocol, listenAddress, port.ListenPort, net.ParseIP(target), port.TargetPort)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// forwardsClear removes the firewall rules of the network's address forwards.
+func (n *network) forwardsClear() error {
+	for _, family := range []firewallConsts.Family{firewallConsts.FamilyIPv4, firewallConsts.FamilyIPv6} {
+		err := n.state.Firewall.NetworkClearForwards(family, n.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,903 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkLoadBalancersCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers",
+
+	Get:  APIEndpointAction{Handler: networkLoadBalancersGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: networkLoadBalancersPost, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+}
+
+var networkLoadBalancerCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkLoadBalancerDelete, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Get:    APIEndpointAction{Handler: networkLoadBalancerGet, AccessHandler: AllowAuthenticated},
+	Patch:  APIEndpointAction{Handler: networkLoadBalancerPatch, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Put:    APIEndpointAction{Handler: networkLoadBalancerPut, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+}
+
+var networkLoadBalancerStateCmd = APIEndpoint{
+	Path: "networks/{name}/load-balancers/{listenAddress}/state",
+
+	Get: APIEndpointAction{Handler: networkLoadBalancerStateGet, AccessHandler: AllowAuthenticated},
+}
+
+// networkLoadBalancerConfigKeys maps the configuration keys of a load balancer to their validators.
+var networkLoadBalancerConfigKeys = map[string]func(value string) error{
+	"session_affinity": func(value string) error {
+		return shared.IsOneOf(value, []string{"none", "client_ip"})
+	},
+	"healthcheck":               shared.IsBool,
+	"healthcheck.interval":      networkValidLoadBalancerCount,
+	"healthcheck.timeout":       networkValidLoadBalancerCount,
+	"healthcheck.failure_count": networkValidLoadBalancerCount,
+	"healthcheck.success_count": networkValidLoadBalancerCount,
+	"healthcheck.port":          networkValidPort,
+}
+
+// API endpoints
+func networkLoadBalancersGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	recursion := util.IsRecursionRequest(r)
+
+	networkID, network, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Clients restricted to projects only see the networks of those.
+	if !requestHasProject(r, projectNetworkOwner(network.Config)) {
+		return response.Forbidden(nil)
+	}
+
+	loadBalancers, err := d.cluster.NetworkLoadBalancers(networkID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, loadBalancer := range loadBalancers {
+			urls = append(urls, fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, name, loadBalancer.ListenAddress))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, loadBalancers)
+}
+
+func networkLoadBalancersPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	req := api.NetworkLoadBalancersPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to apply the load balancer which was added to the database.
+	if isClusterNotification(r) {
+		err = n.loadBalancersApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	listenAddress := net.ParseIP(req.ListenAddress)
+	if listenAddress == nil {
+		return response.BadRequest(fmt.Errorf("Invalid listen address %q", req.ListenAddress))
+	}
+	req.ListenAddress = listenAddress.String()
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkForward(d.cluster, project, n.config, req.ListenAddress)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = networkLoadBalancerValidate(n, listenAddress, &req.NetworkLoadBalancerPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.NetworkLoadBalancerGet(n.id, req.ListenAddress)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("A load balancer for %q already exists", req.ListenAddress))
+	}
+
+	_, _, err = d.cluster.NetworkForwardGet(n.id, req.ListenAddress)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("A forward for %q already exists", req.ListenAddress))
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	id, err := d.cluster.NetworkLoadBalancerCreate(n.id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		d.cluster.NetworkLoadBalancerDelete(id)
+		networkLoadBalancersRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.DeleteNetworkLoadBalancer(name, req.ListenAddress)
+		})
+	})
+
+	err = n.loadBalancersApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.CreateNetworkLoadBalancer(name, req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, name, req.ListenAddress))
+}
+
+func networkLoadBalancerGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	networkID, network, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Clients restricted to projects only see the networks of those.
+	if !requestHasProject(r, projectNetworkOwner(network.Config)) {
+		return response.Forbidden(nil)
+	}
+
+	_, loadBalancer, err := d.cluster.NetworkLoadBalancerGet(networkID, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{loadBalancer.ListenAddress, loadBalancer.Description, loadBalancer.Config, loadBalancer.Backends, loadBalancer.Ports}
+
+	return response.SyncResponseETag(true, loadBalancer, etag)
+}
+
+func networkLoadBalancerPut(d *Daemon, r *http.Request) response.Response {
+	return doNetworkLoadBalancerUpdate(d, r, false)
+}
+
+func networkLoadBalancerPatch(d *Daemon, r *http.Request) response.Response {
+	return doNetworkLoadBalancerUpdate(d, r, true)
+}
+
+func doNetworkLoadBalancerUpdate(d *Daemon, r *http.Request, patch bool) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to apply the load balancer which was updated in the database.
+	if isClusterNotification(r) {
+		err = n.loadBalancersApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	id, loadBalancer, err := d.cluster.NetworkLoadBalancerGet(n.id, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkForward(d.cluster, project, n.config, loadBalancer.ListenAddress)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{loadBalancer.ListenAddress, loadBalancer.Description, loadBalancer.Config, loadBalancer.Backends, loadBalancer.Ports}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkLoadBalancerPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if patch {
+		// Config stacking
+		if req.Config == nil {
+			req.Config = map[string]string{}
+		}
+
+		for k, v := range loadBalancer.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				req.Config[k] = v
+			}
+		}
+
+		if req.Backends == nil {
+			req.Backends = loadBalancer.Backends
+		}
+
+		if req.Ports == nil {
+			req.Ports = loadBalancer.Ports
+		}
+	}
+
+	err = networkLoadBalancerValidate(n, net.ParseIP(loadBalancer.ListenAddress), &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = d.cluster.NetworkLoadBalancerUpdate(id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		d.cluster.NetworkLoadBalancerUpdate(id, &loadBalancer.NetworkLoadBalancerPut)
+		networkLoadBalancersRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.UpdateNetworkLoadBalancer(name, listenAddress, loadBalancer.NetworkLoadBalancerPut, "")
+		})
+	})
+
+	err = n.loadBalancersApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.UpdateNetworkLoadBalancer(name, listenAddress, req, "")
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.EmptySyncResponse
+}
+
+func networkLoadBalancerDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Other nodes only need to remove the load balancer which was deleted from the database.
+	if isClusterNotification(r) {
+		err = n.loadBalancersApply()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	id, loadBalancer, err := d.cluster.NetworkLoadBalancerGet(n.id, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkConfig(d.cluster, project, n.config, nil)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = d.cluster.NetworkLoadBalancerDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() {
+		req := api.NetworkLoadBalancersPost{NetworkLoadBalancerPut: loadBalancer.NetworkLoadBalancerPut, ListenAddress: loadBalancer.ListenAddress}
+		d.cluster.NetworkLoadBalancerCreate(n.id, &req)
+		networkLoadBalancersRevert(d, n, func(client lxd.InstanceServer) error {
+			return client.CreateNetworkLoadBalancer(name, req)
+		})
+	})
+
+	err = n.loadBalancersApply()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkForwardsNotify(d, func(client lxd.InstanceServer) error {
+		return client.DeleteNetworkLoadBalancer(name, listenAddress)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+
+	return response.EmptySyncResponse
+}
+
+func networkLoadBalancerStateGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	listenAddress := networkListenAddressFromRequest(r)
+
+	networkID, network, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Clients restricted to projects only see the networks of those.
+	if !requestHasProject(r, projectNetworkOwner(network.Config)) {
+		return response.Forbidden(nil)
+	}
+
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	_, loadBalancer, err := d.cluster.NetworkLoadBalancerGet(networkID, listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	state := api.NetworkLoadBalancerState{
+		BackendHealth: map[string]api.NetworkLoadBalancerStateBackendHealth{},
+	}
+
+	for _, backend := range loadBalancer.Backends {
+		status := "unknown"
+		if shared.IsTrue(loadBalancer.Config["healthcheck"]) {
+			online, checked := networkLoadBalancerBackendOnline(name, loadBalancer.ListenAddress, backend.Name)
+			if checked && online {
+				status = "online"
+			} else if checked {
+				status = "offline"
+			}
+		}
+
+		state.BackendHealth[backend.Name] = api.NetworkLoadBalancerStateBackendHealth{
+			Address: backend.TargetAddress,
+			Status:  status,
+		}
+	}
+
+	return response.SyncResponse(true, state)
+}
+
+// networkLoadBalancersRevert re-applies the load balancers of a network on all nodes once a failed
+// change got reverted in the database. The notified nodes apply the load balancers from the
+// database, whatever the hook is.
+func networkLoadBalancersRevert(d *Daemon, n *network, hook func(client lxd.InstanceServer) error) {
+	err := n.loadBalancersApply()
+	if err != nil {
+		logger.Error("Failed to re-apply network load balancers", log.Ctx{"network": n.name, "err": err})
+	}
+
+	err = networkForwardsNotify(d, hook)
+	if err != nil {
+		logger.Error("Failed to notify other nodes of reverted network load balancers", log.Ctx{"network": n.name, "err": err})
+	}
+}
+
+// networkValidLoadBalancerCount checks that the value is a strictly positive integer.
+func networkValidLoadBalancerCount(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || count < 1 {
+		return fmt.Errorf("Invalid value for a positive integer: %s", value)
+	}
+
+	return nil
+}
+
+// networkLoadBalancerValidate checks the configuration, backends and ports of a load balancer on
+// the given network.
+func networkLoadBalancerValidate(n *network, listenAddress net.IP, loadBalancer *api.NetworkLoadBalancerPut) error {
+	isIPv4 := listenAddress.To4() != nil
+
+	for k, v := range loadBalancer.Config {
+		if strings.HasPrefix(k, "user.") {
+			continue
+		}
+
+		validator, ok := networkLoadBalancerConfigKeys[k]
+		if !ok {
+			return fmt.Errorf("Invalid network load balancer configuration key %q", k)
+		}
+
+		err := validator(v)
+		if err != nil {
+			return fmt.Errorf("Invalid value for network load balancer configuration key %q: %v", k, err)
+		}
+	}
+
+	backends := map[string]api.NetworkLoadBalancerBackend{}
+	for i, backend := range loadBalancer.Backends {
+		if backend.Name == "" || strings.ContainsAny(backend.Name, " /,") {
+			return fmt.Errorf("Invalid name %q for backend %d", backend.Name, i)
+		}
+
+		_, ok := backends[backend.Name]
+		if ok {
+			return fmt.Errorf("Backend name %q is used more than once", backend.Name)
+		}
+
+		err := networkForwardValidTarget(n, isIPv4, backend.TargetAddress)
+		if err != nil {
+			return err
+		}
+
+		if backend.TargetPort != "" {
+			err = networkValidPort(backend.TargetPort)
+			if err != nil {
+				return fmt.Errorf("Invalid target port %q for backend %q", backend.TargetPort, backend.Name)
+			}
+		}
+
+		backends[backend.Name] = backend
+	}
+
+	used := map[string]bool{}
+	for i, port := range loadBalancer.Ports {
+		if !shared.StringInSlice(port.Protocol, []string{"tcp", "udp"}) {
+			return fmt.Errorf("Invalid protocol %q for port %d", port.Protocol, i)
+		}
+
+		start, end, err := networkForwardPortRange(port.ListenPort)
+		if err != nil {
+			return fmt.Errorf("Invalid listen port %q for port %d", port.ListenPort, i)
+		}
+
+		if len(port.TargetBackend) == 0 {
+			return fmt.Errorf("No target backend for port %d", i)
+		}
+
+		for _, name := range port.TargetBackend {
+			backend, ok := backends[name]
+			if !ok {
+				return fmt.Errorf("Unknown target backend %q for port %d", name, i)
+			}
+
+			if start != end && backend.TargetPort != "" {
+				return fmt.Errorf("Backend %q with a target port can't be used with a listen port range for port %d", name, i)
+			}
+		}
+
+		for p := start; p <= end; p++ {
+			key := fmt.Sprintf("%s/%d", port.Protocol, p)
+			if used[key] {
+				return fmt.Errorf("Listen port %s/%d is used more than once", port.Protocol, p)
+			}
+
+			used[key] = true
+		}
+	}
+
+	return nil
+}
+
+// loadBalancersApply refreshes the firewall rules of the network's load balancers if the network
+// is running.
+func (n *network) loadBalancersApply() error {
+	if !n.IsRunning() {
+		return nil
+	}
+
+	return n.loadBalancersSetup()
+}
+
+// loadBalancersSetup (re)creates the firewall rules for all the load balancers of the network,
+// only targeting the backends which aren't known to be offline, and registers their health checks.
+func (n *network) loadBalancersSetup() error {
+	err := n.loadBalancersClearRules()
+	if err != nil {
+		return err
+	}
+
+	loadBalancers, err := n.state.Cluster.NetworkLoadBalancers(n.id)
+	if err != nil {
+		return err
+	}
+
+	networkLoadBalancerHealthRegister(n.name, loadBalancers)
+
+	for _, loadBalancer := range loadBalancers {
+		listenAddress := net.ParseIP(loadBalancer.ListenAddress)
+		if listenAddress == nil {
+			continue
+		}
+
+		family := firewallConsts.Family(firewallConsts.FamilyIPv6)
+		if listenAddress.To4() != nil {
+			family = firewallConsts.FamilyIPv4
+		}
+
+		backends := map[string]api.NetworkLoadBalancerBackend{}
+		for _, backend := range loadBalancer.Backends {
+			backends[backend.Name] = backend
+		}
+
+		healthCheck := shared.IsTrue(loadBalancer.Config["healthcheck"])
+		sessionAffinity := loadBalancer.Config["session_affinity"] == "client_ip"
+
+		for _, port := range loadBalancer.Ports {
+			targetAddresses := []net.IP{}
+			targetPorts := []string{}
+			for _, name := range port.TargetBackend {
+				backend, ok := backends[name]
+				if !ok {
+					continue
+				}
+
+				if healthCheck {
+					online, _ := networkLoadBalancerBackendOnline(n.name, loadBalancer.ListenAddress, name)
+					if !online {
+						continue
+					}
+				}
+
+				targetAddresses = append(targetAddresses, net.ParseIP(backend.TargetAddress))
+				targetPorts = append(targetPorts, backend.TargetPort)
+			}
+
+			// Leave the port alone when none of its backends is online.
+			if len(targetAddresses) == 0 {
+				continue
+			}
+
+			err = n.state.Firewall.NetworkSetupLoadBalancerNAT(family, n.name, port.Protocol, listenAddress, port.ListenPort, targetAddresses, targetPorts, sessionAffinity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadBalancersClear removes the firewall rules of the network's load balancers and stops their
+// health checks.
+func (n *network) loadBalancersClear() error {
+	networkLoadBalancerHealthRegister(n.name, nil)
+
+	return n.loadBalancersClearRules()
+}
+
+func (n *network) loadBalancersClearRules() error {
+	for _, family := range []firewallConsts.Family{firewallConsts.FamilyIPv4, firewallConsts.FamilyIPv6} {
+		err := n.state.Firewall.NetworkClearLoadBalancers(family, n.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkLoadBalancerCheck represents the health check of the backends of a load balancer.
+type networkLoadBalancerCheck struct {
+	network       string
+	listenAddress string
+
+	interval     time.Duration
+	timeout      time.Duration
+	failureCount int
+	successCount int
+
+	// Addresses checked and health of the backends, by backend name.
+	targets  map[string]string
+	backends map[string]*networkLoadBalancerBackendHealth
+
+	nextRun time.Time
+	running bool
+}
+
+// networkLoadBalancerBackendHealth represents the health of a backend. Backends are considered
+// online until they fail enough checks in a row.
+type networkLoadBalancerBackendHealth struct {
+	online    bool
+	failures  int
+	successes int
+}
+
+// networkLoadBalancerChecks holds the health checks of the load balancers of the networks running
+// on this node, by network name and listen address.
+var networkLoadBalancerChecks = map[string]*networkLoadBalancerCheck{}
+var networkLoadBalancerChecksLock sync.Mutex
+
+// networkLoadBalancerHealthRegister replaces the health checks of a network with the ones of the
+// given load balancers, keeping the health of the backends whose checked address didn't change.
+func networkLoadBalancerHealthRegister(network string, loadBalancers []api.NetworkLoadBalancer) {
+	networkLoadBalancerChecksLock.Lock()
+	defer networkLoadBalancerChecksLock.Unlock()
+
+	previous := map[string]*networkLoadBalancerCheck{}
+	for key, check := range networkLoadBalancerChecks {
+		if check.network == network {
+			previous[key] = check
+			delete(networkLoadBalancerChecks, key)
+		}
+	}
+
+	for _, loadBalancer := range loadBalancers {
+		if !shared.IsTrue(loadBalancer.Config["healthcheck"]) {
+			continue
+		}
+
+		check := &networkLoadBalancerCheck{
+			network:       network,
+			listenAddress: loadBalancer.ListenAddress,
+			interval:      networkLoadBalancerDuration(loadBalancer.Config["healthcheck.interval"], 10),
+			timeout:       networkLoadBalancerDuration(loadBalancer.Config["healthcheck.timeout"], 5),
+			failureCount:  networkLoadBalancerCount(loadBalancer.Config["healthcheck.failure_count"], 3),
+			successCount:  networkLoadBalancerCount(loadBalancer.Config["healthcheck.success_count"], 2),
+			targets:       map[string]string{},
+			backends:      map[string]*networkLoadBalancerBackendHealth{},
+		}
+
+		key := fmt.Sprintf("%s/%s", network, loadBalancer.ListenAddress)
+		old := previous[key]
+		if old != nil {
+			check.nextRun = old.nextRun
+			check.running = old.running
+		}
+
+		for _, backend := range loadBalancer.Backends {
+			port := networkLoadBalancerCheckPort(loadBalancer, backend)
+			if port == "" {
+				continue
+			}
+
+			target := net.JoinHostPort(backend.TargetAddress, port)
+			check.targets[backend.Name] = target
+
+			if old != nil && old.targets[backend.Name] == target {
+				check.backends[backend.Name] = old.backends[backend.Name]
+				continue
+			}
+
+			check.backends[backend.Name] = &networkLoadBalancerBackendHealth{online: true}
+		}
+
+		networkLoadBalancerChecks[key] = check
+	}
+}
+
+// networkLoadBalancerCheckPort returns the port the health check of a backend connects to, that is
+// the health check port, the backend's target port or the first port it's the target of.
+func networkLoadBalancerCheckPort(loadBalancer api.NetworkLoadBalancer, backend api.NetworkLoadBalancerBackend) string {
+	if loadBalancer.Config["healthcheck.port"] != "" {
+		return loadBalancer.Config["healthcheck.port"]
+	}
+
+	if backend.TargetPort != "" {
+		return backend.TargetPort
+	}
+
+	for _, port := range loadBalancer.Ports {
+		if shared.StringInSlice(backend.Name, port.TargetBackend) {
+			start, _, err := networkForwardPortRange(port.ListenPort)
+			if err == nil {
+				return fmt.Sprintf("%d", start)
+			}
+		}
+	}
+
+	return ""
+}
+
+// networkLoadBalancerBackendOnline returns whether a backend is online and whether it's being
+// checked at all.
+func networkLoadBalancerBackendOnline(network string, listenAddress string, backend string) (bool, bool) {
+	networkLoadBalancerChecksLock.Lock()
+	defer networkLoadBalancerChecksLock.Unlock()
+
+	check, ok := networkLoadBalancerChecks[fmt.Sprintf("%s/%s", network, listenAddress)]
+	if !ok {
+		return true, false
+	}
+
+	health, ok := check.backends[backend]
+	if !ok {
+		return true, false
+	}
+
+	return health.online, true
+}
+
+// networkLoadBalancerHealthTask runs the load balancer health checks which are due.
+func networkLoadBalancerHealthTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		networkLoadBalancerHealthCheck(d.State())
+	}
+
+	return f, task.Every(time.Second)
+}
+
+func networkLoadBalancerHealthCheck(s *state.State) {
+	networkLoadBalancerChecksLock.Lock()
+	defer networkLoadBalancerChecksLock.Unlock()
+
+	now := time.Now()
+	for key, check := range networkLoadBalancerChecks {
+		if check.running || now.Before(check.nextRun) {
+			continue
+		}
+
+		check.running = true
+		check.nextRun = now.Add(check.interval)
+
+		targets := map[string]string{}
+		for name, target := range check.targets {
+			targets[name] = target
+		}
+
+		go networkLoadBalancerHealthRun(s, key, check.network, targets, check.timeout)
+	}
+}
+
+// networkLoadBalancerHealthRun checks the backends of a load balancer and refreshes the firewall
+// rules of its network when any of them went online or offline.
+func networkLoadBalancerHealthRun(s *state.State, key string, network string, targets map[string]string, timeout time.Duration) {
+	results := map[string]bool{}
+	resultsLock := sync.Mutex{}
+
+	wg := sync.WaitGroup{}
+	for name, target := range targets {
+		wg.Add(1)
+		go func(name string, target string) {
+			defer wg.Done()
+
+			conn, err := net.DialTimeout("tcp", target, timeout)
+			if err == nil {
+				conn.Close()
+			}
+
+			resultsLock.Lock()
+			results[name] = err == nil
+			resultsLock.Unlock()
+		}(name, target)
+	}
+
+	wg.Wait()
+
+	changed := false
+
+	networkLoadBalancerChecksLock.Lock()
+	check, ok := networkLoadBalancerChecks[key]
+	if ok {
+		check.running = false
+
+		for name, success := range results {
+			health, ok := check.backends[name]
+			if !ok || check.targets[name] != targets[name] {
+				continue
+			}
+
+			online := health.online
+			if success {
+				health.failures = 0
+				health.successes++
+				if health.successes >= check.successCount {
+					health.online = true
+				}
+			} else {
+				health.successes = 0
+				health.failures++
+				if health.failures >= check.failureCount {
+					health.online = false
+				}
+			}
+
+			if health.online != online {
+				logger.Info("Network load balancer backend health changed", log.Ctx{"network": network, "listenAddress": check.listenAddress, "backend": name, "online": health.online})
+				changed = true
+			}
+		}
+	}
+	networkLoadBalancerChecksLock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	n, err := networkLoadByName(s, network)
+	if err != nil {
+		logger.Error("Failed to load network", log.Ctx{"network": network, "err": err})
+		return
+	}
+
+	err = n.loadBalancersApply()
+	if err != nil {
+		logger.Error("Failed to re-apply network load balancers", log.Ctx{"network": network, "err": err})
+	}
+}
+
+// networkLoadBalancerDuration returns the given number of seconds as a duration, or the default.
+func networkLoadBalancerDuration(value string, defaultSeconds int) time.Duration {
+	return time.Duration(networkLoadBalancerCount(value, defaultSeconds)) * time.Second
+}
+
+// networkLoadBalancerCount returns the given count, or the default.
+func networkLoadBalancerCount(value string, defaultCount int) int {
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		return defaultCount
+	}
+
+	return count
+}
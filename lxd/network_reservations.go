@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkReservationsCmd = APIEndpoint{
+	Path: "networks/{name}/reservations",
+
+	Get:  APIEndpointAction{Handler: networkReservationsGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: networkReservationsPost},
+}
+
+var networkReservationCmd = APIEndpoint{
+	Path: "networks/{name}/reservations/{hwaddr}",
+
+	Delete: APIEndpointAction{Handler: networkReservationDelete},
+	Get:    APIEndpointAction{Handler: networkReservationGet, AccessHandler: AllowAuthenticated},
+	Put:    APIEndpointAction{Handler: networkReservationPut},
+}
+
+// API endpoints
+func networkReservationsGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	recursion := util.IsRecursionRequest(r)
+
+	networkID, _, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	reservations, err := d.cluster.NetworkReservations(networkID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, reservation := range reservations {
+			urls = append(urls, fmt.Sprintf("/%s/networks/%s/reservations/%s", version.APIVersion, name, reservation.Hwaddr))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	return response.SyncResponse(true, reservations)
+}
+
+func networkReservationsPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	// Other nodes only need to refresh their dnsmasq configuration.
+	if isClusterNotification(r) {
+		err := networkUpdateStatic(d.State(), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	req := api.NetworkReservationsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	hwaddr, err := net.ParseMAC(req.Hwaddr)
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid MAC address %q", req.Hwaddr))
+	}
+	req.Hwaddr = hwaddr.String()
+
+	err = networkReservationValidate(d, n, req.Hwaddr, &req.NetworkReservationPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.NetworkReservationGet(n.id, req.Hwaddr)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("A reservation for %q already exists", req.Hwaddr))
+	}
+
+	_, err = d.cluster.NetworkReservationCreate(n.id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkUpdateStatic(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkReservationsNotify(d, func(client lxd.InstanceServer) error {
+		return client.CreateNetworkReservation(name, req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/networks/%s/reservations/%s", version.APIVersion, name, req.Hwaddr))
+}
+
+func networkReservationGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	hwaddr := strings.ToLower(mux.Vars(r)["hwaddr"])
+
+	networkID, _, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, reservation, err := d.cluster.NetworkReservationGet(networkID, hwaddr)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{reservation.Hwaddr, reservation.Description, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname}
+
+	return response.SyncResponseETag(true, reservation, etag)
+}
+
+func networkReservationPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	hwaddr := strings.ToLower(mux.Vars(r)["hwaddr"])
+
+	// Other nodes only need to refresh their dnsmasq configuration.
+	if isClusterNotification(r) {
+		err := networkUpdateStatic(d.State(), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	n, err := networkLoadByName(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	id, reservation, err := d.cluster.NetworkReservationGet(n.id, hwaddr)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{reservation.Hwaddr, reservation.Description, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkReservationPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = networkReservationValidate(d, n, reservation.Hwaddr, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.NetworkReservationUpdate(id, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkUpdateStatic(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkReservationsNotify(d, func(client lxd.InstanceServer) error {
+		return client.UpdateNetworkReservation(name, hwaddr, req, "")
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func networkReservationDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+	hwaddr := strings.ToLower(mux.Vars(r)["hwaddr"])
+
+	// Other nodes only need to refresh their dnsmasq configuration.
+	if isClusterNotification(r) {
+		err := networkUpdateStatic(d.State(), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	networkID, _, err := d.cluster.NetworkGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	id, _, err := d.cluster.NetworkReservationGet(networkID, hwaddr)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.NetworkReservationDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkUpdateStatic(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = networkReservationsNotify(d, func(client lxd.InstanceServer) error {
+		return client.DeleteNetworkReservation(name, hwaddr)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// networkReservationsNotify notifies all other nodes of a change to a network's DHCP reservations.
+func networkReservationsNotify(d *Daemon, hook func(client lxd.InstanceServer) error) error {
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
+	if err != nil {
+		return err
+	}
+
+	return notifier(hook)
+}
+
+// networkReservationValidate checks the addresses of a DHCP reservation on the given network.
+func networkReservationValidate(d *Daemon, n *network, hwaddr string, reservation *api.NetworkReservationPut) error {
+	if reservation.IPv4Address == "" && reservation.IPv6Address == "" {
+		return fmt.Errorf("A reservation requires an IPv4 or IPv6 address")
+	}
+
+	if reservation.Hostname != "" {
+		err := networkValidDNSZone(reservation.Hostname)
+		if err != nil {
+			return fmt.Errorf("Invalid hostname %q", reservation.Hostname)
+		}
+	}
+
+	validAddress := func(value string, key string) error {
+		if value == "" {
+			return nil
+		}
+
+		ip := net.ParseIP(value)
+		if ip == nil || (ip.To4() != nil) != (key == "ipv4.address") {
+			return fmt.Errorf("Invalid address %q", value)
+		}
+
+		_, subnet, err := net.ParseCIDR(n.config[key])
+		if err != nil || !subnet.Contains(ip) {
+			return fmt.Errorf("Address %q isn't within the network's subnet", value)
+		}
+
+		return nil
+	}
+
+	err := validAddress(reservation.IPv4Address, "ipv4.address")
+	if err != nil {
+		return err
+	}
+
+	err = validAddress(reservation.IPv6Address, "ipv6.address")
+	if err != nil {
+		return err
+	}
+
+	// Prevent the same address from being reserved twice.
+	reservations, err := d.cluster.NetworkReservations(n.id)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range reservations {
+		if entry.Hwaddr == hwaddr {
+			continue
+		}
+
+		if reservation.IPv4Address != "" && entry.IPv4Address == reservation.IPv4Address {
+			return fmt.Errorf("Address %q is already reserved for %s", reservation.IPv4Address, entry.Hwaddr)
+		}
+
+		if reservation.IPv6Address != "" && entry.IPv6Address == reservation.IPv6Address {
+			return fmt.Errorf("Address %q is already reserved for %s", reservation.IPv6Address, entry.Hwaddr)
+		}
+	}
+
+	return nil
+}
@@ -47,17 +47,17 @@ var networksCmd = APIEndpoint{
 	Path: "networks",
 
 	Get:  APIEndpointAction{Handler: networksGet, AccessHandler: AllowAuthenticated},
-	Post: APIEndpointAction{Handler: networksPost},
+	Post: APIEndpointAction{Handler: networksPost, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
 }
 
 var networkCmd = APIEndpoint{
 	Path: "networks/{name}",
 
-	Delete: APIEndpointAction{Handler: networkDelete},
+	Delete: APIEndpointAction{Handler: networkDelete, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
 	Get:    APIEndpointAction{Handler: networkGet, AccessHandler: AllowAuthenticated},
-	Patch:  APIEndpointAction{Handler: networkPatch},
-	Post:   APIEndpointAction{Handler: networkPost},
-	Put:    APIEndpointAction{Handler: networkPut},
+	Patch:  APIEndpointAction{Handler: networkPatch, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Post:   APIEndpointAction{Handler: networkPost, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
+	Put:    APIEndpointAction{Handler: networkPut, AccessHandler: AllowProjectPermission("networks", "manage-networks")},
 }
 
 var networkLeasesCmd = APIEndpoint{
@@ -132,13 +132,23 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		req.Config = map[string]string{}
 	}
 
+	// Networks created from a project other than the default one belong to it.
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	if project != "default" && !isClusterNotification(r) && queryParam(r, "target") == "" {
+		req.Config["volatile.project"] = project
+	}
+
 	err = networkValidateConfig(req.Name, req.Config)
 	if err != nil {
 		return response.BadRequest(err)
 	}
 
 	url := fmt.Sprintf("/%s/networks/%s", version.APIVersion, req.Name)
-	resp := response.SyncResponseLocation(true, nil, url)
+	resp = response.SyncResponseLocation(true, nil, url)
 
 	if isClusterNotification(r) {
 		// This is an internal request which triggers the actual
@@ -161,6 +171,12 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 				return response.SmartError(fmt.Errorf("Config key '%s' may not be used as node-specific key", key))
 			}
 		}
+
+		err = projectCheckNetworkConfig(d.cluster, project, nil, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			return tx.NetworkCreatePending(targetNode, req.Name, req.Config)
 		})
@@ -173,6 +189,11 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	err = projectCheckNetworkLimit(d.cluster, project)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Check if we're clustered
 	count, err := cluster.Count(d.State())
 	if err != nil {
@@ -180,7 +201,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if count > 1 {
-		err = networksPostCluster(d, req)
+		err = networksPostCluster(d, project, req)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -193,6 +214,11 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	err = projectCheckNetworkConfig(d.cluster, project, nil, req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// No targetNode was specified and we're either a single-node
 	// cluster or not clustered at all, so create the storage
 	// pool immediately.
@@ -219,7 +245,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func networksPostCluster(d *Daemon, req api.NetworksPost) error {
+func networksPostCluster(d *Daemon, project string, req api.NetworksPost) error {
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
@@ -246,6 +272,11 @@ func networksPostCluster(d *Daemon, req api.NetworksPost) error {
 		return err
 	}
 
+	err = projectCheckNetworkConfig(d.cluster, project, nil, req.Config)
+	if err != nil {
+		return err
+	}
+
 	// Check that the network is properly defined, fetch the node-specific
 	// configs and insert the global config.
 	var configs map[string]map[string]string
@@ -366,6 +397,13 @@ func doNetworksCreate(d *Daemon, req api.NetworksPost, withDatabase bool) error
 		return err
 	}
 
+	// Announce the routes of the network to its BGP peers
+	err = networkBGPServerUpdate(d.State())
+	if err != nil {
+		n.Delete(withDatabase)
+		return err
+	}
+
 	return nil
 }
 
@@ -485,6 +523,19 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
+
+	if !isClusterNotification(r) {
+		project, resp := projectNetworkRequestProject(d, r)
+		if resp != nil {
+			return resp
+		}
+
+		err = projectCheckNetworkConfig(d.cluster, project, network.Config, nil)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	if network.Status == "Pending" {
 		err := d.cluster.NetworkDelete(name)
 		if err != nil {
@@ -567,6 +618,16 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 		return response.NotFound(err)
 	}
 
+	project, resp := projectNetworkRequestProject(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	err = projectCheckNetworkConfig(d.cluster, project, n.config, nil)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Sanity checks
 	if req.Name == "" {
 		return response.BadRequest(fmt.Errorf("No name provided"))
@@ -597,6 +658,12 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 }
 
 func networkPut(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
 	name := mux.Vars(r)["name"]
 
 	// Get the existing network
@@ -611,6 +678,9 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Keep track of the node-specific fields, which are preserved unless targeting this node.
+	localConfig := util.CopyConfig(dbInfo.Config)
+
 	// If no target node is specified and the daemon is clustered, we omit
 	// the node-specific fields.
 	if targetNode == "" && clustered {
@@ -632,10 +702,39 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r))
+	if clustered {
+		req.Config, err = networkClusterFillConfig(localConfig, req.Config, targetNode != "", isClusterNotification(r))
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if targetNode != "" {
+			req.Description = dbInfo.Description
+		}
+	}
+
+	if !isClusterNotification(r) {
+		project, resp := projectNetworkRequestProject(d, r)
+		if resp != nil {
+			return resp
+		}
+
+		err = projectCheckNetworkConfig(d.cluster, project, dbInfo.Config, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r), clustered && targetNode != "")
 }
 
 func networkPatch(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
 	name := mux.Vars(r)["name"]
 
 	// Get the existing network
@@ -650,6 +749,9 @@ func networkPatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// Keep track of the node-specific fields, which are preserved unless targeting this node.
+	localConfig := util.CopyConfig(dbInfo.Config)
+
 	// If no target node is specified and the daemon is clustered, we omit
 	// the node-specific fields.
 	if targetNode == "" && clustered {
@@ -683,10 +785,89 @@ func networkPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
-	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r))
+	if clustered {
+		req.Config, err = networkClusterFillConfig(localConfig, req.Config, targetNode != "", isClusterNotification(r))
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if targetNode != "" {
+			req.Description = dbInfo.Description
+		}
+	}
+
+	if !isClusterNotification(r) {
+		project, resp := projectNetworkRequestProject(d, r)
+		if resp != nil {
+			return resp
+		}
+
+		err = projectCheckNetworkConfig(d.cluster, project, dbInfo.Config, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	return doNetworkUpdate(d, name, dbInfo.Config, req, isClusterNotification(r), clustered && targetNode != "")
 }
 
-func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req api.NetworkPut, notify bool) response.Response {
+// networkClusterFillConfig combines the configuration of a PUT/PATCH request with the node-specific
+// fields of the local node. When targeting the node, only its node-specific fields can be changed,
+// otherwise they're left untouched.
+func networkClusterFillConfig(localConfig map[string]string, reqConfig map[string]string, targeted bool, notification bool) (map[string]string, error) {
+	config := map[string]string{}
+
+	if targeted {
+		for key, value := range localConfig {
+			if !shared.StringInSlice(key, db.NetworkNodeConfigKeys) && reqConfig[key] != value {
+				return nil, fmt.Errorf("Config key %q isn't node-specific and can't be changed with a target", key)
+			}
+		}
+
+		for key, value := range reqConfig {
+			if !shared.StringInSlice(key, db.NetworkNodeConfigKeys) && localConfig[key] != value {
+				return nil, fmt.Errorf("Config key %q isn't node-specific and can't be changed with a target", key)
+			}
+
+			config[key] = value
+		}
+
+		return config, nil
+	}
+
+	for key, value := range reqConfig {
+		if shared.StringInSlice(key, db.NetworkNodeConfigKeys) {
+			// Notifications carry the fields of the node they originate from.
+			if !notification && localConfig[key] != value {
+				return nil, fmt.Errorf("Node-specific config key %q can only be changed with a target", key)
+			}
+
+			continue
+		}
+
+		config[key] = value
+	}
+
+	for _, key := range db.NetworkNodeConfigKeys {
+		if localConfig[key] != "" {
+			config[key] = localConfig[key]
+		}
+	}
+
+	return config, nil
+}
+
+func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req api.NetworkPut, notify bool, nodeOnly bool) response.Response {
+	// The project a network belongs to can't be changed
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	delete(req.Config, "volatile.project")
+	if oldConfig["volatile.project"] != "" {
+		req.Config["volatile.project"] = oldConfig["volatile.project"]
+	}
+
 	// Validate the configuration
 	err := networkValidateConfig(name, req.Config)
 	if err != nil {
@@ -706,11 +887,19 @@ func doNetworkUpdate(d *Daemon, name string, oldConfig map[string]string, req ap
 		return response.NotFound(err)
 	}
 
-	err = n.Update(req, notify)
+	// Changes of node-specific fields only apply to this node, so they aren't propagated.
+	err = n.Update(req, notify || nodeOnly)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	if nodeOnly {
+		err = d.cluster.NetworkUpdate(name, req.Description, req.Config)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.EmptySyncResponse
 }
 
@@ -780,6 +969,34 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 				}
 			}
 		}
+
+		// Get all the DHCP reservations (shared by all projects)
+		networkID, _, err := d.cluster.NetworkGet(name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		reservations, err := d.cluster.NetworkReservations(networkID)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		for _, reservation := range reservations {
+			projectMacs = append(projectMacs, reservation.Hwaddr)
+
+			for _, address := range []string{reservation.IPv4Address, reservation.IPv6Address} {
+				if address == "" {
+					continue
+				}
+
+				leases = append(leases, api.NetworkLease{
+					Hostname: reservation.Hostname,
+					Address:  address,
+					Hwaddr:   reservation.Hwaddr,
+					Type:     "static",
+				})
+			}
+		}
 	}
 
 	// Local server name
@@ -1084,6 +1301,18 @@ func (n *network) Delete(withDatabase bool) error {
 		return err
 	}
 
+	// Drop the DNS server peer keys of the network
+	err = networkDNSServerUpdate(n.state)
+	if err != nil {
+		return err
+	}
+
+	// Withdraw the routes of the network from its BGP peers
+	err = networkBGPServerUpdate(n.state)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1147,6 +1376,19 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		return nil
 	}
 
+	// Number the bridge from the prefix delegated by the uplink, leaving the stored config intact.
+	if n.config["ipv6.address"] == "delegated" {
+		config := n.config
+		defer func() { n.config = config }()
+
+		n.config = map[string]string{}
+		for k, v := range config {
+			n.config[k] = v
+		}
+
+		n.config["ipv6.address"] = device.NetworkIPv6Address(n.name, config)
+	}
+
 	// Create directory
 	if !shared.PathExists(shared.VarPath("networks", n.name)) {
 		err := os.MkdirAll(shared.VarPath("networks", n.name), 0711)
@@ -1175,6 +1417,54 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Configure VLAN filtering on native bridges (needed for VLAN aware instance NICs)
+	if n.config["bridge.driver"] != "openvswitch" && n.config["bridge.vlan_filtering"] != "" {
+		value := "0"
+		if shared.IsTrue(n.config["bridge.vlan_filtering"]) {
+			value = "1"
+		}
+
+		err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/vlan_filtering", n.name), []byte(value), 0)
+		if err != nil {
+			return fmt.Errorf("Failed to configure VLAN filtering on bridge: %v", err)
+		}
+	}
+
+	// Configure multicast snooping and the IGMP/MLD querier
+	if n.config["bridge.driver"] == "openvswitch" {
+		if n.config["bridge.multicast_snooping"] != "" {
+			_, err := shared.RunCommand("ovs-vsctl", "set", "bridge", n.name, fmt.Sprintf("mcast_snooping_enable=%v", shared.IsTrue(n.config["bridge.multicast_snooping"])))
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		multicast := map[string]string{}
+		if n.config["bridge.multicast_snooping"] != "" {
+			multicast["multicast_snooping"] = "0"
+			if shared.IsTrue(n.config["bridge.multicast_snooping"]) {
+				multicast["multicast_snooping"] = "1"
+			}
+		}
+
+		if n.config["bridge.multicast_querier"] != "" {
+			multicast["multicast_querier"] = "0"
+			if shared.IsTrue(n.config["bridge.multicast_querier"]) {
+				multicast["multicast_querier"] = "1"
+			}
+
+			// Send IGMP queries from the bridge address rather than 0.0.0.0, which some hosts ignore.
+			multicast["multicast_query_use_ifaddr"] = multicast["multicast_querier"]
+		}
+
+		for key, value := range multicast {
+			err := ioutil.WriteFile(fmt.Sprintf("/sys/class/net/%s/bridge/%s", n.name, key), []byte(value), 0)
+			if err != nil {
+				return fmt.Errorf("Failed to configure multicast on bridge: %v", err)
+			}
+		}
+	}
+
 	// Get a list of tunnels
 	tunnels := networkGetTunnels(n.config)
 
@@ -1217,6 +1507,8 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		mtu = n.config["bridge.mtu"]
 	} else if len(tunnels) > 0 {
 		mtu = "1400"
+	} else if n.config["bridge.mode"] == "wireguard" {
+		mtu = "1370"
 	} else if n.config["bridge.mode"] == "fan" {
 		if n.config["fan.type"] == "ipip" {
 			mtu = "1480"
@@ -1264,25 +1556,13 @@ func (n *network) Setup(oldConfig map[string]string) error {
 	if n.config["bridge.external_interfaces"] != "" {
 		for _, entry := range strings.Split(n.config["bridge.external_interfaces"], ",") {
 			entry = strings.TrimSpace(entry)
-			iface, err := net.InterfaceByName(entry)
-			if err != nil {
+			if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", entry)) {
 				continue
 			}
 
-			unused := true
-			addrs, err := iface.Addrs()
-			if err == nil {
-				for _, addr := range addrs {
-					ip, _, err := net.ParseCIDR(addr.String())
-					if ip != nil && err == nil && ip.IsGlobalUnicast() {
-						unused = false
-						break
-					}
-				}
-			}
-
-			if !unused {
-				return fmt.Errorf("Only unconfigured network interfaces can be bridged")
+			err = networkCheckUnconfigured(entry)
+			if err != nil {
+				return err
 			}
 
 			err = device.NetworkAttachInterface(n.name, entry)
@@ -1292,6 +1572,12 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Create the bond of uplink interfaces
+	err = n.setupBond()
+	if err != nil {
+		return err
+	}
+
 	// Remove any existing IPv4 iptables rules
 	if n.config["ipv4.firewall"] == "" || shared.IsTrue(n.config["ipv4.firewall"]) || (oldConfig != nil && (oldConfig["ipv4.firewall"] == "" || shared.IsTrue(oldConfig["ipv4.firewall"]))) {
 		err = n.state.Firewall.NetworkClear(firewallConsts.FamilyIPv4, firewallConsts.TableAll, n.name)
@@ -1411,6 +1697,12 @@ func (n *network) Setup(oldConfig map[string]string) error {
 				dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-option=3,%s", n.config["ipv4.dhcp.gateway"]))
 			}
 
+			// Network boot, setting both the legacy file and next server fields and option 67.
+			if n.config["ipv4.dhcp.filename"] != "" {
+				dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-boot=%s,,%s", n.config["ipv4.dhcp.filename"], n.config["ipv4.dhcp.next_server"]))
+				dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-option=option:bootfile-name,%s", n.config["ipv4.dhcp.filename"]))
+			}
+
 			expiry := "1h"
 			if n.config["ipv4.dhcp.expiry"] != "" {
 				expiry = n.config["ipv4.dhcp.expiry"]
@@ -1434,19 +1726,22 @@ func (n *network) Setup(oldConfig map[string]string) error {
 
 		// Configure NAT
 		if shared.IsTrue(n.config["ipv4.nat"]) {
-			//If a SNAT source address is specified, use that, otherwise default to using MASQUERADE mode.
-			args := []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "MASQUERADE"}
-			if n.config["ipv4.nat.address"] != "" {
-				args = []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "SNAT", "--to", n.config["ipv4.nat.address"]}
+			// If a SNAT source address is specified, use that, otherwise default to using MASQUERADE mode.
+			srcIP := net.ParseIP(n.config["ipv4.nat.address"])
+
+			// Destinations which are routed rather than NATed.
+			exclude, err := networkParseSubnets(n.config["ipv4.nat.exclude"])
+			if err != nil {
+				return err
 			}
 
 			if n.config["ipv4.nat.order"] == "after" {
-				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv4, n.name, firewallConsts.LocationAppend, args...)
+				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv4, n.name, firewallConsts.LocationAppend, subnet, srcIP, exclude)
 				if err != nil {
 					return err
 				}
 			} else {
-				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv4, n.name, firewallConsts.LocationPrepend, args...)
+				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv4, n.name, firewallConsts.LocationPrepend, subnet, srcIP, exclude)
 				if err != nil {
 					return err
 				}
@@ -1531,6 +1826,10 @@ func (n *network) Setup(oldConfig map[string]string) error {
 				dnsmasqCmd = append(dnsmasqCmd, []string{"--dhcp-no-override", "--dhcp-authoritative", fmt.Sprintf("--dhcp-leasefile=%s", shared.VarPath("networks", n.name, "dnsmasq.leases")), fmt.Sprintf("--dhcp-hostsfile=%s", shared.VarPath("networks", n.name, "dnsmasq.hosts"))}...)
 			}
 
+			if n.config["ipv6.dhcp.bootfile_url"] != "" {
+				dnsmasqCmd = append(dnsmasqCmd, fmt.Sprintf("--dhcp-option=option6:bootfile-url,%s", n.config["ipv6.dhcp.bootfile_url"]))
+			}
+
 			expiry := "1h"
 			if n.config["ipv6.dhcp.expiry"] != "" {
 				expiry = n.config["ipv6.dhcp.expiry"]
@@ -1605,18 +1904,21 @@ func (n *network) Setup(oldConfig map[string]string) error {
 
 		// Configure NAT
 		if shared.IsTrue(n.config["ipv6.nat"]) {
-			args := []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "MASQUERADE"}
-			if n.config["ipv6.nat.address"] != "" {
-				args = []string{"-s", subnet.String(), "!", "-d", subnet.String(), "-j", "SNAT", "--to", n.config["ipv6.nat.address"]}
+			srcIP := net.ParseIP(n.config["ipv6.nat.address"])
+
+			// Destinations which are routed rather than NATed.
+			exclude, err := networkParseSubnets(n.config["ipv6.nat.exclude"])
+			if err != nil {
+				return err
 			}
 
 			if n.config["ipv6.nat.order"] == "after" {
-				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv6, n.name, firewallConsts.LocationAppend, args...)
+				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv6, n.name, firewallConsts.LocationAppend, subnet, srcIP, exclude)
 				if err != nil {
 					return err
 				}
 			} else {
-				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv6, n.name, firewallConsts.LocationPrepend, args...)
+				err = n.state.Firewall.NetworkSetupNAT(firewallConsts.FamilyIPv6, n.name, firewallConsts.LocationPrepend, subnet, srcIP, exclude)
 				if err != nil {
 					return err
 				}
@@ -1816,6 +2118,14 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		tunRemote := getConfig("remote")
 		tunName := fmt.Sprintf("%s-%s", n.name, tunnel)
 
+		// Unicast VXLAN tunnels may have several remotes, flooded to using head-end replication.
+		tunRemotes := []string{}
+		if tunProtocol == "vxlan" && strings.Contains(tunRemote, ",") {
+			for _, remote := range strings.Split(tunRemote, ",") {
+				tunRemotes = append(tunRemotes, strings.TrimSpace(remote))
+			}
+		}
+
 		// Configure the tunnel
 		cmd := []string{"ip", "link", "add", "dev", tunName}
 		if tunProtocol == "gre" {
@@ -1825,6 +2135,29 @@ func (n *network) Setup(oldConfig map[string]string) error {
 			}
 
 			cmd = append(cmd, []string{"type", "gretap", "local", tunLocal, "remote", tunRemote}...)
+		} else if tunProtocol == "geneve" {
+			// Skip partial configs
+			if tunRemote == "" {
+				continue
+			}
+
+			cmd = append(cmd, []string{"type", "geneve", "remote", tunRemote}...)
+
+			tunPort := getConfig("port")
+			if tunPort != "" {
+				cmd = append(cmd, []string{"dstport", tunPort}...)
+			}
+
+			tunId := getConfig("id")
+			if tunId == "" {
+				tunId = "1"
+			}
+			cmd = append(cmd, []string{"id", tunId}...)
+
+			tunTtl := getConfig("ttl")
+			if tunTtl != "" {
+				cmd = append(cmd, []string{"ttl", tunTtl}...)
+			}
 		} else if tunProtocol == "vxlan" {
 			tunGroup := getConfig("group")
 			tunInterface := getConfig("interface")
@@ -1836,7 +2169,9 @@ func (n *network) Setup(oldConfig map[string]string) error {
 
 			cmd = append(cmd, []string{"type", "vxlan"}...)
 
-			if tunLocal != "" && tunRemote != "" {
+			if tunLocal != "" && len(tunRemotes) > 0 {
+				cmd = append(cmd, []string{"local", tunLocal}...)
+			} else if tunLocal != "" && tunRemote != "" {
 				cmd = append(cmd, []string{"local", tunLocal, "remote", tunRemote}...)
 			} else {
 				if tunGroup == "" {
@@ -1879,6 +2214,14 @@ func (n *network) Setup(oldConfig map[string]string) error {
 			return err
 		}
 
+		// Add the forwarding entries of the unicast remotes
+		for _, remote := range tunRemotes {
+			_, err = shared.RunCommand("bridge", "fdb", "append", "00:00:00:00:00:00", "dev", tunName, "dst", remote)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Bridge it and bring up
 		err = device.NetworkAttachInterface(n.name, tunName)
 		if err != nil {
@@ -1896,6 +2239,14 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Configure the WireGuard mesh
+	if n.config["bridge.mode"] == "wireguard" {
+		err = n.setupWireGuard(mtu)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Kill any existing dnsmasq and forkdns daemon for this network
 	err = dnsmasq.Kill(n.name, false)
 	if err != nil {
@@ -1996,6 +2347,24 @@ func (n *network) Setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Setup address forwards
+	err = n.forwardsSetup()
+	if err != nil {
+		return err
+	}
+
+	// Setup load balancers
+	err = n.loadBalancersSetup()
+	if err != nil {
+		return err
+	}
+
+	// Setup DHCPv6 prefix delegation
+	err = n.setupPrefixDelegation(n.config)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2004,6 +2373,9 @@ func (n *network) Stop() error {
 		return fmt.Errorf("The network is already stopped")
 	}
 
+	// Stop DHCPv6 prefix delegation
+	n.stopPrefixDelegation()
+
 	// Destroy the bridge interface
 	if n.config["bridge.driver"] == "openvswitch" {
 		_, err := shared.RunCommand("ovs-vsctl", "del-br", n.name)
@@ -2051,8 +2423,18 @@ func (n *network) Stop() error {
 		}
 	}
 
+	err := n.forwardsClear()
+	if err != nil {
+		return err
+	}
+
+	err = n.loadBalancersClear()
+	if err != nil {
+		return err
+	}
+
 	// Kill any existing dnsmasq and forkdns daemon for this network
-	err := dnsmasq.Kill(n.name, false)
+	err = dnsmasq.Kill(n.name, false)
 	if err != nil {
 		return err
 	}
@@ -2213,6 +2595,22 @@ func (n *network) Update(newNetwork api.NetworkPut, notify bool) error {
 		}
 	}
 
+	// Refresh the DNS server peer keys
+	if networkDNSConfigChanged(changedConfig) {
+		err = networkDNSServerUpdate(n.state)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Refresh the BGP peers and routes
+	if networkBGPConfigChanged(changedConfig) {
+		err = networkBGPServerUpdate(n.state)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Success, update the closure to mark that the changes should be kept.
 	undoChanges = false
 
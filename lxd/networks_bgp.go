@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/bgp"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/device"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// networkBGPLock serializes the updates of the BGP server, so that the last routes computed are
+// the ones announced.
+var networkBGPLock sync.Mutex
+
+func networkValidBGPASN(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	asn, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || asn == 0 {
+		return fmt.Errorf("Invalid ASN: %s", value)
+	}
+
+	return nil
+}
+
+// networkBGPServerUpdate (re)starts the BGP server with the current configuration and announces
+// the current routes of the networks to their peers.
+func networkBGPServerUpdate(s *state.State) error {
+	if s.BGP == nil {
+		return nil
+	}
+
+	networkBGPLock.Lock()
+	defer networkBGPLock.Unlock()
+
+	address, routerID, err := node.BGPAddress(s.Node)
+	if err != nil {
+		return err
+	}
+
+	// Don't bother computing the routes when the server is disabled.
+	if address == "" {
+		return s.BGP.Start("", 0, nil)
+	}
+
+	asn, err := cluster.ConfigGetInt64(s.Cluster, "core.bgp_asn")
+	if err != nil {
+		return err
+	}
+
+	peers, prefixes, err := networkBGPRoutes(s)
+	if err != nil {
+		return err
+	}
+
+	err = s.BGP.Update(peers, prefixes)
+	if err != nil {
+		return err
+	}
+
+	return s.BGP.Start(address, uint32(asn), networkBGPRouterID(address, routerID))
+}
+
+// networkBGPTask refreshes the routes announced through BGP, picking up the instances which started
+// or stopped without emitting a lifecycle event.
+func networkBGPTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		err := networkBGPServerUpdate(d.State())
+		if err != nil {
+			logger.Warn("Failed to refresh the BGP routes", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// networkBGPRouterID returns the router ID of the BGP server, defaulting to its address when it
+// listens on a specific IPv4 address.
+func networkBGPRouterID(address string, routerID string) net.IP {
+	if routerID != "" {
+		return net.ParseIP(routerID)
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() == nil || ip.IsUnspecified() {
+		return nil
+	}
+
+	return ip
+}
+
+// networkBGPRoutes returns the peers of the networks and the routes announced to them. Those are
+// the subnets of the networks not using NAT, their routes and the routes of the bridged NICs of the
+// instances running on this node, for every network having peers.
+func networkBGPRoutes(s *state.State) ([]bgp.Peer, []bgp.Prefix, error) {
+	peers := []bgp.Peer{}
+	prefixes := []bgp.Prefix{}
+
+	networks, err := s.Cluster.NetworksNotPending()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nics []map[string]string
+	for _, name := range networks {
+		_, network, err := s.Cluster.NetworkGet(name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		networkPeers := networkBGPPeers(network.Config)
+		if len(networkPeers) == 0 {
+			continue
+		}
+
+		peers = append(peers, networkPeers...)
+
+		// Only load the instances once some network needs them.
+		if nics == nil {
+			nics, err = networkBGPRunningNICs(s)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		subnets := []string{}
+		if !shared.IsTrue(network.Config["ipv4.nat"]) {
+			subnets = append(subnets, network.Config["ipv4.address"])
+		}
+
+		if !shared.IsTrue(network.Config["ipv6.nat"]) {
+			subnets = append(subnets, device.NetworkIPv6Address(network.Name, network.Config))
+		}
+
+		subnets = append(subnets, strings.Split(network.Config["ipv4.routes"], ",")...)
+		subnets = append(subnets, strings.Split(network.Config["ipv6.routes"], ",")...)
+
+		for _, nic := range nics {
+			if nic["parent"] != network.Name {
+				continue
+			}
+
+			subnets = append(subnets, strings.Split(nic["ipv4.routes"], ",")...)
+			subnets = append(subnets, strings.Split(nic["ipv6.routes"], ",")...)
+		}
+
+		for _, subnet := range subnets {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(subnet))
+			if err != nil {
+				continue
+			}
+
+			prefixes = append(prefixes, bgp.Prefix{
+				Subnet:  *ipNet,
+				NextHop: networkBGPNextHop(network.Config, ipNet.IP.To4() != nil),
+			})
+		}
+	}
+
+	return peers, prefixes, nil
+}
+
+// networkBGPRunningNICs returns the bridged NICs of the instances running on this node.
+func networkBGPRunningNICs(s *state.State) ([]map[string]string, error) {
+	insts, err := instanceLoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	nics := []map[string]string{}
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		for _, d := range inst.ExpandedDevices() {
+			if d["type"] == "nic" && d["nictype"] == "bridged" {
+				nics = append(nics, d)
+			}
+		}
+	}
+
+	return nics, nil
+}
+
+// networkBGPNextHop returns the next hop of the routes of a network, the unspecified address
+// making the BGP server use its local address of each session.
+func networkBGPNextHop(config map[string]string, ipv4 bool) net.IP {
+	if ipv4 {
+		if config["bgp.ipv4.nexthop"] != "" {
+			return net.ParseIP(config["bgp.ipv4.nexthop"])
+		}
+
+		return net.IPv4zero
+	}
+
+	if config["bgp.ipv6.nexthop"] != "" {
+		return net.ParseIP(config["bgp.ipv6.nexthop"])
+	}
+
+	return net.IPv6unspecified
+}
+
+// networkBGPPeers returns the BGP peers of a network.
+func networkBGPPeers(config map[string]string) []bgp.Peer {
+	peers := []bgp.Peer{}
+
+	for k, v := range config {
+		if !strings.HasPrefix(k, "bgp.peers.") || !strings.HasSuffix(k, ".address") {
+			continue
+		}
+
+		fields := strings.Split(k, ".")
+		if len(fields) != 4 {
+			continue
+		}
+
+		// Both the address and the ASN are checked when set.
+		asn, _ := strconv.ParseUint(config[fmt.Sprintf("bgp.peers.%s.asn", fields[2])], 10, 32)
+		peers = append(peers, bgp.Peer{
+			Address:  net.ParseIP(v),
+			ASN:      uint32(asn),
+			Password: config[fmt.Sprintf("bgp.peers.%s.password", fields[2])],
+		})
+	}
+
+	return peers
+}
+
+// networkBGPConfigChanged returns whether any of the BGP keys, or the subnets and routes announced
+// through BGP, are part of the changed keys.
+func networkBGPConfigChanged(changedKeys []string) bool {
+	for _, key := range changedKeys {
+		if strings.HasPrefix(key, "bgp.") || shared.StringInSlice(key, []string{"ipv4.address", "ipv6.address", "ipv4.nat", "ipv6.nat", "ipv4.routes", "ipv6.routes"}) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/lxc/lxd/lxd/device"
+	"github.com/lxc/lxd/shared"
+)
+
+// networkBondName returns the name of the bond LXD creates as the uplink of the network.
+func networkBondName(networkName string) string {
+	return fmt.Sprintf("%s-bond", networkName)
+}
+
+// networkBondMode returns the bonding mode of the network uplink.
+func networkBondMode(config map[string]string) string {
+	if config["bridge.bond.mode"] == "" {
+		return "active-backup"
+	}
+
+	return config["bridge.bond.mode"]
+}
+
+// setupBond creates the bond of the interfaces listed in bridge.bond.interfaces and attaches it
+// to the bridge. Any previous bond was removed along with the other "<network>-" devices.
+func (n *network) setupBond() error {
+	ifaces := networkParseInterfaces(n.config["bridge.bond.interfaces"])
+	if len(ifaces) == 0 {
+		return nil
+	}
+
+	for _, iface := range ifaces {
+		err := networkCheckUnconfigured(iface)
+		if err != nil {
+			return err
+		}
+	}
+
+	bondName := networkBondName(n.name)
+
+	miimon := "100"
+	if n.config["bridge.bond.miimon"] != "" {
+		miimon = n.config["bridge.bond.miimon"]
+	}
+
+	_, err := shared.RunCommand("ip", "link", "add", "dev", bondName, "type", "bond", "mode", networkBondMode(n.config), "miimon", miimon)
+	if err != nil {
+		return err
+	}
+
+	// Interfaces must be down to be enslaved.
+	for _, iface := range ifaces {
+		_, err = shared.RunCommand("ip", "link", "set", "dev", iface, "down")
+		if err != nil {
+			return err
+		}
+
+		_, err = shared.RunCommand("ip", "link", "set", "dev", iface, "master", bondName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if n.config["bridge.bond.primary"] != "" {
+		_, err = shared.RunCommand("ip", "link", "set", "dev", bondName, "type", "bond", "primary", n.config["bridge.bond.primary"])
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", bondName, "up")
+	if err != nil {
+		return err
+	}
+
+	return device.NetworkAttachInterface(n.name, bondName)
+}
+
+// networkCheckUnconfigured checks that an interface doesn't have any global address, which would
+// be lost when attaching it to a bridge or bond.
+func networkCheckUnconfigured(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if ip != nil && err == nil && ip.IsGlobalUnicast() {
+			return fmt.Errorf("Only unconfigured network interfaces can be bridged")
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -27,12 +28,25 @@ var networkConfigKeys = map[string]func(value string) error{
 
 		return nil
 	},
-	"bridge.hwaddr": shared.IsAny,
-	"bridge.mtu":    shared.IsInt64,
+	"bridge.bond.interfaces":    networkValidNameList,
+	"bridge.bond.miimon":        shared.IsUint32,
+	"bridge.bond.mode":          networkValidBondMode,
+	"bridge.bond.primary":       networkValidName,
+	"bridge.hwaddr":             shared.IsAny,
+	"bridge.mtu":                shared.IsInt64,
+	"bridge.multicast_querier":  shared.IsBool,
+	"bridge.multicast_snooping": shared.IsBool,
+	"bridge.vlan_filtering":     shared.IsBool,
 	"bridge.mode": func(value string) error {
-		return shared.IsOneOf(value, []string{"standard", "fan"})
+		return shared.IsOneOf(value, []string{"standard", "fan", "wireguard"})
 	},
 
+	"bgp.ipv4.nexthop":        device.NetworkValidAddressV4,
+	"bgp.ipv6.nexthop":        device.NetworkValidAddressV6,
+	"bgp.peers.PEER.address":  device.NetworkValidAddress,
+	"bgp.peers.PEER.asn":      networkValidBGPASN,
+	"bgp.peers.PEER.password": shared.IsAny,
+
 	"fan.overlay_subnet": device.NetworkValidNetworkV4,
 	"fan.underlay_subnet": func(value string) error {
 		if value == "auto" {
@@ -45,11 +59,17 @@ var networkConfigKeys = map[string]func(value string) error{
 		return shared.IsOneOf(value, []string{"vxlan", "ipip"})
 	},
 
+	"wireguard.subnet":                 device.NetworkValidNetworkV4,
+	"wireguard.port":                   networkValidPort,
+	"wireguard.peers.PEER.public_key":  networkValidWireGuardKey,
+	"wireguard.peers.PEER.endpoint":    networkValidWireGuardEndpoint,
+	"wireguard.peers.PEER.allowed_ips": networkValidWireGuardAllowedIPs,
+
 	"tunnel.TARGET.protocol": func(value string) error {
-		return shared.IsOneOf(value, []string{"gre", "vxlan"})
+		return shared.IsOneOf(value, []string{"gre", "vxlan", "geneve"})
 	},
 	"tunnel.TARGET.local":     device.NetworkValidAddress,
-	"tunnel.TARGET.remote":    device.NetworkValidAddress,
+	"tunnel.TARGET.remote":    networkValidAddressList,
 	"tunnel.TARGET.port":      networkValidPort,
 	"tunnel.TARGET.group":     device.NetworkValidAddress,
 	"tunnel.TARGET.id":        shared.IsInt64,
@@ -68,16 +88,19 @@ var networkConfigKeys = map[string]func(value string) error{
 	"ipv4.nat.order": func(value string) error {
 		return shared.IsOneOf(value, []string{"before", "after"})
 	},
-	"ipv4.nat.address":  device.NetworkValidAddressV4,
-	"ipv4.dhcp":         shared.IsBool,
-	"ipv4.dhcp.gateway": device.NetworkValidAddressV4,
-	"ipv4.dhcp.expiry":  shared.IsAny,
-	"ipv4.dhcp.ranges":  shared.IsAny,
-	"ipv4.routes":       shared.IsAny,
-	"ipv4.routing":      shared.IsBool,
+	"ipv4.nat.address":      device.NetworkValidAddressV4,
+	"ipv4.nat.exclude":      device.NetworkValidNetworkV4List,
+	"ipv4.dhcp":             shared.IsBool,
+	"ipv4.dhcp.gateway":     device.NetworkValidAddressV4,
+	"ipv4.dhcp.expiry":      shared.IsAny,
+	"ipv4.dhcp.ranges":      shared.IsAny,
+	"ipv4.dhcp.filename":    shared.IsAny,
+	"ipv4.dhcp.next_server": device.NetworkValidAddressV4,
+	"ipv4.routes":           shared.IsAny,
+	"ipv4.routing":          shared.IsBool,
 
 	"ipv6.address": func(value string) error {
-		if shared.IsOneOf(value, []string{"none", "auto"}) == nil {
+		if shared.IsOneOf(value, []string{"none", "auto", "delegated"}) == nil {
 			return nil
 		}
 
@@ -88,20 +111,59 @@ var networkConfigKeys = map[string]func(value string) error{
 	"ipv6.nat.order": func(value string) error {
 		return shared.IsOneOf(value, []string{"before", "after"})
 	},
-	"ipv6.nat.address":   device.NetworkValidAddressV6,
-	"ipv6.dhcp":          shared.IsBool,
-	"ipv6.dhcp.expiry":   shared.IsAny,
-	"ipv6.dhcp.stateful": shared.IsBool,
-	"ipv6.dhcp.ranges":   shared.IsAny,
-	"ipv6.routes":        shared.IsAny,
-	"ipv6.routing":       shared.IsBool,
+	"ipv6.nat.address":       device.NetworkValidAddressV6,
+	"ipv6.nat.exclude":       device.NetworkValidNetworkV6List,
+	"ipv6.dhcp":              shared.IsBool,
+	"ipv6.dhcp.expiry":       shared.IsAny,
+	"ipv6.dhcp.stateful":     shared.IsBool,
+	"ipv6.dhcp.ranges":       shared.IsAny,
+	"ipv6.dhcp.bootfile_url": shared.IsAny,
+	"ipv6.routes":            shared.IsAny,
+	"ipv6.routing":           shared.IsBool,
+
+	"ipv6.delegation.client_limit": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return fmt.Errorf("Invalid number of prefixes: %s", value)
+		}
+
+		return nil
+	},
+	"ipv6.delegation.prefix": device.NetworkValidNetworkV6,
+	"ipv6.delegation.size": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > 128 {
+			return fmt.Errorf("Invalid prefix length: %s", value)
+		}
+
+		return nil
+	},
+	"ipv6.delegation.uplink": networkValidName,
 
 	"dns.domain": shared.IsAny,
 	"dns.mode": func(value string) error {
 		return shared.IsOneOf(value, []string{"dynamic", "managed", "none"})
 	},
 
+	"dns.zone.allowed":            networkValidDNSAllowed,
+	"dns.zone.forward":            networkValidDNSZone,
+	"dns.zone.reverse.ipv4":       networkValidDNSReverseZone,
+	"dns.zone.reverse.ipv6":       networkValidDNSReverseZone,
+	"dns.zone.projects":           networkValidDNSProjects,
+	"dns.zone.peers.PEER.address": device.NetworkValidAddress,
+	"dns.zone.peers.PEER.key":     networkValidDNSKey,
+
 	"raw.dnsmasq": shared.IsAny,
+
+	"volatile.project": shared.IsAny,
 }
 
 func networkValidateConfig(name string, config map[string]string) error {
@@ -111,6 +173,84 @@ func networkValidateConfig(name string, config map[string]string) error {
 		return fmt.Errorf("Network name too long to use with the FAN (must be 11 characters or less)")
 	}
 
+	if bridgeMode == "wireguard" {
+		if len(name) > 12 {
+			return fmt.Errorf("Network name too long to use with WireGuard (must be 12 characters or less)")
+		}
+
+		if config["wireguard.subnet"] == "" {
+			return fmt.Errorf("WireGuard networks require wireguard.subnet to be set")
+		}
+	}
+
+	if config["bridge.bond.interfaces"] != "" {
+		if len(name) > 10 {
+			return fmt.Errorf("Network name too long to use with a bond (must be 10 characters or less)")
+		}
+
+		bondInterfaces := networkParseInterfaces(config["bridge.bond.interfaces"])
+		for _, iface := range networkParseInterfaces(config["bridge.external_interfaces"]) {
+			if shared.StringInSlice(iface, bondInterfaces) {
+				return fmt.Errorf("Interface %q can't be both in bridge.external_interfaces and bridge.bond.interfaces", iface)
+			}
+		}
+
+		if config["bridge.bond.primary"] != "" {
+			if !shared.StringInSlice(config["bridge.bond.primary"], bondInterfaces) {
+				return fmt.Errorf("bridge.bond.primary must be one of bridge.bond.interfaces")
+			}
+
+			if !shared.StringInSlice(networkBondMode(config), []string{"active-backup", "balance-tlb", "balance-alb"}) {
+				return fmt.Errorf("bridge.bond.primary isn't supported in bond mode %q", networkBondMode(config))
+			}
+		}
+	} else {
+		for _, key := range []string{"bridge.bond.miimon", "bridge.bond.mode", "bridge.bond.primary"} {
+			if config[key] != "" {
+				return fmt.Errorf("%s requires bridge.bond.interfaces to be set", key)
+			}
+		}
+	}
+
+	if shared.IsTrue(config["bridge.multicast_querier"]) {
+		if config["bridge.driver"] == "openvswitch" {
+			return fmt.Errorf("bridge.multicast_querier isn't supported on Open vSwitch bridges")
+		}
+
+		if config["bridge.multicast_snooping"] != "" && !shared.IsTrue(config["bridge.multicast_snooping"]) {
+			return fmt.Errorf("bridge.multicast_querier requires multicast snooping to be enabled")
+		}
+	}
+
+	if (config["ipv6.address"] == "delegated") != (config["ipv6.delegation.uplink"] != "") {
+		return fmt.Errorf("ipv6.address must be set to 'delegated' when using ipv6.delegation.uplink and the other way around")
+	}
+
+	if config["ipv4.dhcp.next_server"] != "" && config["ipv4.dhcp.filename"] == "" {
+		return fmt.Errorf("ipv4.dhcp.next_server requires ipv4.dhcp.filename to be set")
+	}
+
+	if config["ipv6.delegation.prefix"] != "" {
+		if shared.StringInSlice(config["ipv6.address"], []string{"", "none"}) {
+			return fmt.Errorf("Prefix delegation requires IPv6 to be enabled on the network")
+		}
+
+		// The DHCPv6 server of dnsmasq would also answer on port 547 of the bridge.
+		if config["ipv6.dhcp"] == "" || shared.IsTrue(config["ipv6.dhcp"]) {
+			return fmt.Errorf("Prefix delegation requires ipv6.dhcp to be disabled")
+		}
+
+		_, pool, err := net.ParseCIDR(config["ipv6.delegation.prefix"])
+		if err != nil {
+			return err
+		}
+
+		poolSize, _ := pool.Mask.Size()
+		if networkDelegationSize(config) < poolSize {
+			return fmt.Errorf("Delegated prefixes can't be larger than ipv6.delegation.prefix")
+		}
+	}
+
 	for k, v := range config {
 		key := k
 
@@ -131,6 +271,58 @@ func networkValidateConfig(name string, config map[string]string) error {
 			}
 
 			key = fmt.Sprintf("tunnel.TARGET.%s", fields[2])
+
+			// Only unicast VXLAN tunnels support several remotes
+			protocol := config[fmt.Sprintf("tunnel.%s.protocol", fields[1])]
+			if fields[2] == "remote" && strings.Contains(v, ",") {
+				if protocol != "vxlan" {
+					return fmt.Errorf("Multiple remotes are only supported on vxlan tunnels: %s", k)
+				}
+
+				if config[fmt.Sprintf("tunnel.%s.local", fields[1])] == "" {
+					return fmt.Errorf("Tunnels with multiple remotes require a local address: %s", k)
+				}
+			}
+
+			if protocol == "geneve" && shared.StringInSlice(fields[2], []string{"group", "interface", "local"}) && v != "" {
+				return fmt.Errorf("Key %s isn't supported on geneve tunnels", k)
+			}
+		}
+
+		// DNS zone peer keys have the peer name in their name, so extract the real key
+		if strings.HasPrefix(key, "dns.zone.peers.") {
+			fields := strings.Split(key, ".")
+			if len(fields) != 5 {
+				return fmt.Errorf("Invalid network configuration key: %s", k)
+			}
+
+			key = fmt.Sprintf("dns.zone.peers.PEER.%s", fields[4])
+		}
+
+		// BGP peer keys have the peer name in their name, so extract the real key
+		if strings.HasPrefix(key, "bgp.peers.") {
+			fields := strings.Split(key, ".")
+			if len(fields) != 4 {
+				return fmt.Errorf("Invalid network configuration key: %s", k)
+			}
+
+			peerAddress := config[fmt.Sprintf("bgp.peers.%s.address", fields[2])]
+			peerASN := config[fmt.Sprintf("bgp.peers.%s.asn", fields[2])]
+			if peerAddress == "" || peerASN == "" {
+				return fmt.Errorf("BGP peer %q requires both an address and an ASN", fields[2])
+			}
+
+			key = fmt.Sprintf("bgp.peers.PEER.%s", fields[3])
+		}
+
+		// WireGuard peer keys have the peer name in their name, so extract the real key
+		if strings.HasPrefix(key, "wireguard.peers.") {
+			fields := strings.Split(key, ".")
+			if len(fields) != 4 {
+				return fmt.Errorf("Invalid network configuration key: %s", k)
+			}
+
+			key = fmt.Sprintf("wireguard.peers.PEER.%s", fields[3])
 		}
 
 		// Then validate
@@ -157,6 +349,10 @@ func networkValidateConfig(name string, config map[string]string) error {
 			return fmt.Errorf("FAN configuration may only be set when in 'fan' mode")
 		}
 
+		if bridgeMode != "wireguard" && strings.HasPrefix(key, "wireguard.") && v != "" {
+			return fmt.Errorf("WireGuard configuration may only be set when in 'wireguard' mode")
+		}
+
 		// MTU checks
 		if key == "bridge.mtu" && v != "" {
 			mtu, err := strconv.ParseInt(v, 10, 64)
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/lxd/dhcpv6"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// networkDelegationServers holds the running DHCPv6 prefix delegation servers, by network.
+var networkDelegationServers = map[string]*dhcpv6.Server{}
+
+// networkDelegationClients holds the running DHCPv6 prefix delegation clients, by network.
+var networkDelegationClients = map[string]*dhcpv6.Client{}
+
+// networkDelegationUplinks holds the uplink interface each running client uses, by network.
+var networkDelegationUplinks = map[string]string{}
+
+var networkDelegationMutex sync.Mutex
+
+// networkDelegationSize returns the length of the prefixes delegated to instances.
+func networkDelegationSize(config map[string]string) int {
+	size, err := strconv.Atoi(config["ipv6.delegation.size"])
+	if err != nil {
+		return 64
+	}
+
+	return size
+}
+
+// networkDelegationClientLimit returns the maximum number of prefixes delegated to an instance.
+func networkDelegationClientLimit(config map[string]string) int {
+	limit, err := strconv.Atoi(config["ipv6.delegation.client_limit"])
+	if err != nil {
+		return 1
+	}
+
+	return limit
+}
+
+// networkDelegationLifetime returns the lifetime of the prefixes delegated to instances.
+func networkDelegationLifetime(config map[string]string) time.Duration {
+	lifetime, err := time.ParseDuration(config["ipv6.dhcp.expiry"])
+	if err != nil || lifetime < time.Minute {
+		return time.Hour
+	}
+
+	return lifetime
+}
+
+// setupPrefixDelegation (re)starts the DHCPv6 prefix delegation server of the network and makes
+// sure a client is requesting the prefix the network is numbered from.
+func (n *network) setupPrefixDelegation(config map[string]string) error {
+	networkDelegationMutex.Lock()
+	defer networkDelegationMutex.Unlock()
+
+	server := networkDelegationServers[n.name]
+	if server != nil {
+		server.Stop()
+		delete(networkDelegationServers, n.name)
+	}
+
+	client := networkDelegationClients[n.name]
+	if client != nil && networkDelegationUplinks[n.name] != config["ipv6.delegation.uplink"] {
+		client.Stop()
+		delete(networkDelegationClients, n.name)
+		delete(networkDelegationUplinks, n.name)
+		client = nil
+	}
+
+	if config["ipv6.delegation.uplink"] != "" && client == nil {
+		client = &dhcpv6.Client{
+			Interface: config["ipv6.delegation.uplink"],
+			IAID:      crc32.ChecksumIEEE([]byte(n.name)),
+			LeasePath: shared.VarPath("networks", n.name, "dhcpv6.uplink.lease"),
+			OnPrefix:  networkDelegationPrefixChanged(n.state, n.name),
+		}
+
+		err := client.Start()
+		if err != nil {
+			return err
+		}
+
+		networkDelegationClients[n.name] = client
+		networkDelegationUplinks[n.name] = config["ipv6.delegation.uplink"]
+	}
+
+	if config["ipv6.delegation.prefix"] != "" && !shared.StringInSlice(config["ipv6.address"], []string{"", "none"}) {
+		_, pool, err := net.ParseCIDR(config["ipv6.delegation.prefix"])
+		if err != nil {
+			return err
+		}
+
+		server = &dhcpv6.Server{
+			Interface:    n.name,
+			Pool:         pool,
+			PrefixLength: networkDelegationSize(config),
+			Lifetime:     networkDelegationLifetime(config),
+			LeasesPath:   shared.VarPath("networks", n.name, "dhcpv6.leases"),
+			ClientLimit:  networkDelegationClientLimit(config),
+			OnBind: func(prefix *net.IPNet, router net.IP) error {
+				_, err := shared.RunCommand("ip", "-6", "route", "replace", prefix.String(), "via", router.String(), "dev", n.name, "proto", "static")
+				return err
+			},
+			OnUnbind: func(prefix *net.IPNet) error {
+				_, err := shared.RunCommand("ip", "-6", "route", "del", prefix.String(), "dev", n.name)
+				return err
+			},
+		}
+
+		err = server.Start()
+		if err != nil {
+			return err
+		}
+
+		networkDelegationServers[n.name] = server
+	}
+
+	return nil
+}
+
+// stopPrefixDelegation stops the DHCPv6 prefix delegation server and client of the network.
+func (n *network) stopPrefixDelegation() {
+	networkDelegationMutex.Lock()
+	defer networkDelegationMutex.Unlock()
+
+	server := networkDelegationServers[n.name]
+	if server != nil {
+		server.Stop()
+		delete(networkDelegationServers, n.name)
+	}
+
+	client := networkDelegationClients[n.name]
+	if client != nil {
+		client.Stop()
+		delete(networkDelegationClients, n.name)
+		delete(networkDelegationUplinks, n.name)
+	}
+}
+
+// networkDelegationPrefixChanged returns the function renumbering the network when the prefix
+// delegated to it by the uplink changes.
+func networkDelegationPrefixChanged(s *state.State, name string) func(prefix *net.IPNet) {
+	return func(prefix *net.IPNet) {
+		// Run outside of the client's goroutine as restarting the network may need to stop it.
+		go func() {
+			logger.Info("Renumbering network from delegated prefix", log.Ctx{"network": name, "prefix": fmt.Sprintf("%v", prefix)})
+
+			n, err := networkLoadByName(s, name)
+			if err != nil {
+				logger.Error("Failed to load network", log.Ctx{"network": name, "err": err})
+				return
+			}
+
+			if !n.IsRunning() {
+				return
+			}
+
+			err = n.Start()
+			if err != nil {
+				logger.Error("Failed to renumber network from delegated prefix", log.Ctx{"network": name, "err": err})
+			}
+		}()
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/lxc/lxd/lxd/device"
+	lxddns "github.com/lxc/lxd/lxd/dns"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// networkDNSZoneKeys lists the network configuration keys holding the name of a DNS zone.
+var networkDNSZoneKeys = []string{"dns.zone.forward", "dns.zone.reverse.ipv4", "dns.zone.reverse.ipv6"}
+
+func networkValidDNSZone(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, ok := dns.IsDomainName(value)
+	if !ok {
+		return fmt.Errorf("Invalid DNS zone name: %s", value)
+	}
+
+	return nil
+}
+
+func networkValidDNSReverseZone(value string) error {
+	if value == "auto" {
+		return nil
+	}
+
+	return networkValidDNSZone(value)
+}
+
+func networkValidDNSProjects(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, project := range strings.Split(value, ",") {
+		if strings.TrimSpace(project) == "" {
+			return fmt.Errorf("Invalid project list: %s", value)
+		}
+	}
+
+	return nil
+}
+
+func networkValidDNSAllowed(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, subnet := range strings.Split(value, ",") {
+		_, _, err := net.ParseCIDR(strings.TrimSpace(subnet))
+		if err != nil {
+			return fmt.Errorf("Invalid subnet: %s", subnet)
+		}
+	}
+
+	return nil
+}
+
+func networkValidDNSKey(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("Invalid DNS TSIG key (must be base64 encoded): %v", err)
+	}
+
+	return nil
+}
+
+// networkDNSZone returns the DNS zone with the given fully qualified name, or nil if no managed
+// network uses it.
+func networkDNSZone(s *state.State, zoneName string) (*lxddns.Zone, error) {
+	networks, err := s.Cluster.Networks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range networks {
+		_, network, err := s.Cluster.NetworkGet(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range networkDNSZoneKeys {
+			name := networkDNSZoneName(network.Name, network.Config, key)
+			if name == "" || !strings.EqualFold(name, zoneName) {
+				continue
+			}
+
+			return networkDNSZoneBuild(s, network, key, name)
+		}
+	}
+
+	return nil, nil
+}
+
+// networkDNSZoneNames returns the fully qualified names of the DNS zones of all managed networks.
+func networkDNSZoneNames(s *state.State) ([]string, error) {
+	networks, err := s.Cluster.Networks()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, name := range networks {
+		_, network, err := s.Cluster.NetworkGet(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range networkDNSZoneKeys {
+			zoneName := networkDNSZoneName(network.Name, network.Config, key)
+			if zoneName != "" {
+				names = append(names, zoneName)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// networkDNSZoneName returns the fully qualified name of the zone held by the given key of a
+// network. Reverse zones set to "auto" are derived from the network subnet, rounded down to the
+// closest octet (IPv4) or nibble (IPv6) boundary.
+func networkDNSZoneName(networkName string, config map[string]string, key string) string {
+	value := config[key]
+	if value == "" {
+		return ""
+	}
+
+	if value != "auto" {
+		return dns.Fqdn(value)
+	}
+
+	address := config["ipv4.address"]
+	labelBits := 8
+	if key == "dns.zone.reverse.ipv6" {
+		address = device.NetworkIPv6Address(networkName, config)
+		labelBits = 4
+	}
+
+	_, subnet, err := net.ParseCIDR(address)
+	if err != nil {
+		return ""
+	}
+
+	size, total := subnet.Mask.Size()
+	size -= size % labelBits
+
+	reverse, err := dns.ReverseAddr(subnet.IP.String())
+	if err != nil {
+		return ""
+	}
+
+	labels := dns.SplitDomainName(reverse)
+	return dns.Fqdn(strings.Join(labels[(total-size)/labelBits:], "."))
+}
+
+// networkDNSZoneProjects returns the projects whose instances are published in the zones of a
+// network, or nil for all of them.
+func networkDNSZoneProjects(config map[string]string) []string {
+	if config["dns.zone.projects"] == "" {
+		return nil
+	}
+
+	projects := []string{}
+	for _, project := range strings.Split(config["dns.zone.projects"], ",") {
+		projects = append(projects, strings.TrimSpace(project))
+	}
+
+	return projects
+}
+
+// networkDNSZoneBuild generates the records of the given zone of a network from its instances.
+func networkDNSZoneBuild(s *state.State, network *api.Network, key string, zoneName string) (*lxddns.Zone, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	nameserver := dns.Fqdn(hostname)
+	header := func(name string, rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: 300}
+	}
+
+	zone := &lxddns.Zone{Name: zoneName}
+	zone.Records = append(zone.Records, &dns.SOA{
+		Hdr:     header(zoneName, dns.TypeSOA),
+		Ns:      nameserver,
+		Mbox:    fmt.Sprintf("hostmaster.%s", zoneName),
+		Refresh: 120,
+		Retry:   60,
+		Expire:  86400,
+		Minttl:  30,
+	})
+	zone.Records = append(zone.Records, &dns.NS{Hdr: header(zoneName, dns.TypeNS), Ns: nameserver})
+
+	insts, err := instanceLoadFromAllProjects(s)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := networkDNSZoneProjects(network.Config)
+
+	records := []dns.RR{}
+	for _, inst := range insts {
+		if projects != nil && !shared.StringInSlice(inst.Project(), projects) {
+			continue
+		}
+
+		hostName := inst.Name()
+		if inst.Project() != "default" {
+			hostName = fmt.Sprintf("%s.%s", inst.Name(), inst.Project())
+		}
+
+		for devName, d := range inst.ExpandedDevices() {
+			if d["type"] != "nic" || d["nictype"] != "bridged" || d["parent"] != network.Name {
+				continue
+			}
+
+			hwaddr := d["hwaddr"]
+			if hwaddr == "" {
+				hwaddr = inst.LocalConfig()[fmt.Sprintf("volatile.%s.hwaddr", devName)]
+			}
+
+			addresses := []net.IP{}
+			for _, address := range []string{d["ipv4.address"], d["ipv6.address"]} {
+				ip := net.ParseIP(address)
+				if ip != nil {
+					addresses = append(addresses, ip)
+				}
+			}
+
+			// Dynamic leases are only known to the node running the instance.
+			if hwaddr != "" {
+				leases, err := networkGetLeaseAddresses(s, network.Name, hwaddr)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, lease := range leases {
+					ip := net.ParseIP(lease.Address)
+					if ip != nil && !networkDNSHasIP(addresses, ip) {
+						addresses = append(addresses, ip)
+					}
+				}
+			}
+
+			for _, ip := range addresses {
+				if key == "dns.zone.forward" {
+					name := dns.Fqdn(fmt.Sprintf("%s.%s", hostName, zoneName))
+					if ip.To4() != nil {
+						records = append(records, &dns.A{Hdr: header(name, dns.TypeA), A: ip})
+					} else {
+						records = append(records, &dns.AAAA{Hdr: header(name, dns.TypeAAAA), AAAA: ip})
+					}
+
+					continue
+				}
+
+				if (key == "dns.zone.reverse.ipv4") != (ip.To4() != nil) {
+					continue
+				}
+
+				reverse, err := dns.ReverseAddr(ip.String())
+				if err != nil || !dns.IsSubDomain(zoneName, reverse) {
+					continue
+				}
+
+				target := hostName
+				if network.Config["dns.zone.forward"] != "" {
+					target = fmt.Sprintf("%s.%s", hostName, network.Config["dns.zone.forward"])
+				}
+
+				records = append(records, &dns.PTR{Hdr: header(reverse, dns.TypePTR), Ptr: dns.Fqdn(target)})
+			}
+		}
+	}
+
+	// Keep the records stable between queries.
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].String() < records[j].String()
+	})
+
+	zone.Records = append(zone.Records, records...)
+	zone.Peers = networkDNSPeers(network.Config, zoneName)
+	zone.Allowed = networkDNSAllowed(network.Name, network.Config)
+
+	return zone, nil
+}
+
+func networkDNSHasIP(list []net.IP, ip net.IP) bool {
+	for _, entry := range list {
+		if entry.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// networkDNSAllowed returns the subnets allowed to query the zones of a network, which are those of
+// the network itself and the ones listed in dns.zone.allowed.
+func networkDNSAllowed(networkName string, config map[string]string) []*net.IPNet {
+	allowed := []*net.IPNet{}
+
+	addresses := []string{config["ipv4.address"], device.NetworkIPv6Address(networkName, config)}
+	addresses = append(addresses, strings.Split(config["dns.zone.allowed"], ",")...)
+
+	for _, address := range addresses {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(address))
+		if err == nil {
+			allowed = append(allowed, subnet)
+		}
+	}
+
+	return allowed
+}
+
+// networkDNSPeers returns the peers allowed to transfer the given zone of a network.
+func networkDNSPeers(config map[string]string, zoneName string) []lxddns.Peer {
+	peers := []lxddns.Peer{}
+
+	for k, v := range config {
+		if !strings.HasPrefix(k, "dns.zone.peers.") || !strings.HasSuffix(k, ".address") {
+			continue
+		}
+
+		fields := strings.Split(k, ".")
+		if len(fields) != 5 {
+			continue
+		}
+
+		peer := lxddns.Peer{Address: v}
+		key := config[fmt.Sprintf("dns.zone.peers.%s.key", fields[3])]
+		if key != "" {
+			peer.KeyName = networkDNSKeyName(zoneName, fields[3])
+			peer.Key = key
+		}
+
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
+
+// networkDNSKeyName returns the TSIG key name a peer must use for a zone.
+func networkDNSKeyName(zoneName string, peer string) string {
+	return dns.Fqdn(fmt.Sprintf("%s_%s", strings.TrimSuffix(zoneName, "."), peer))
+}
+
+// networkDNSKeys returns all the TSIG keys used by the peers of the DNS zones.
+func networkDNSKeys(s *state.State) (map[string]string, error) {
+	keys := map[string]string{}
+
+	networks, err := s.Cluster.Networks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range networks {
+		_, network, err := s.Cluster.NetworkGet(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, zoneKey := range networkDNSZoneKeys {
+			zoneName := networkDNSZoneName(network.Name, network.Config, zoneKey)
+			if zoneName == "" {
+				continue
+			}
+
+			for _, peer := range networkDNSPeers(network.Config, zoneName) {
+				if peer.Key != "" {
+					keys[peer.KeyName] = peer.Key
+				}
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// networkDNSServerUpdate (re)starts the DNS server with the current address and peer keys.
+func networkDNSServerUpdate(s *state.State) error {
+	if s.DNS == nil {
+		return nil
+	}
+
+	address, err := node.DNSAddress(s.Node)
+	if err != nil {
+		return err
+	}
+
+	keys, err := networkDNSKeys(s)
+	if err != nil {
+		return err
+	}
+
+	// The zones may have changed along with the server configuration.
+	s.DNS.Invalidate()
+
+	return s.DNS.Start(address, keys)
+}
+
+// networkDNSConfigChanged returns whether any of the DNS zone keys, or the subnets automatic
+// reverse zones are derived from, are part of the changed keys.
+func networkDNSConfigChanged(changedKeys []string) bool {
+	for _, key := range changedKeys {
+		if strings.HasPrefix(key, "dns.zone.") || shared.StringInSlice(key, []string{"ipv4.address", "ipv6.address"}) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -456,6 +456,40 @@ func networkValidPort(value string) error {
 	return nil
 }
 
+func networkValidNameList(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		err := networkValidName(strings.TrimSpace(entry))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func networkValidBondMode(value string) error {
+	return shared.IsOneOf(value, []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"})
+}
+
+func networkValidAddressList(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		err := device.NetworkValidAddress(strings.TrimSpace(entry))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func networkValidAddressCIDRV6(value string) error {
 	if value == "" {
 		return nil
@@ -498,6 +532,41 @@ func networkValidAddressCIDRV4(value string) error {
 	return nil
 }
 
+// networkParseSubnets parses a comma separated list of subnets in CIDR notation.
+func networkParseSubnets(value string) ([]*net.IPNet, error) {
+	subnets := []*net.IPNet{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+// networkParseInterfaces parses a comma separated list of interface names.
+func networkParseInterfaces(value string) []string {
+	ifaces := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		ifaces = append(ifaces, entry)
+	}
+
+	return ifaces
+}
+
 func networkAddressForSubnet(subnet *net.IPNet) (net.IP, string, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -763,6 +832,32 @@ func networkUpdateStatic(s *state.State, networkName string) error {
 			}
 		}
 
+		// Apply the network's DHCP reservations (instance configuration takes precedence)
+		reservations, err := s.Cluster.NetworkReservations(n.id)
+		if err != nil {
+			return err
+		}
+
+		for _, reservation := range reservations {
+			used := false
+			for _, entry := range entries {
+				if strings.EqualFold(entry[0], reservation.Hwaddr) {
+					used = true
+					break
+				}
+			}
+
+			if used {
+				logger.Warnf("Ignoring DHCP reservation for %s on %s as it's configured on an instance", reservation.Hwaddr, network)
+				continue
+			}
+
+			err := dnsmasq.UpdateReservationEntry(network, config, reservation.Hwaddr, reservation.IPv4Address, reservation.IPv6Address, reservation.Hostname)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Signal dnsmasq
 		err = dnsmasq.Kill(network, true)
 		if err != nil {
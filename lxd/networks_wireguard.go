@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/device"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// networkWireGuardDefaultPort is the UDP port WireGuard listens on when wireguard.port isn't set.
+const networkWireGuardDefaultPort = "51820"
+
+// networkWireGuardMTU is the MTU of the WireGuard interface. The bridge MTU then also accounts for
+// the VXLAN encapsulation carried over it.
+const networkWireGuardMTU = 1420
+
+func networkValidWireGuardKey(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("Invalid WireGuard key (must be a base64 encoded 32 bytes key): %s", value)
+	}
+
+	return nil
+}
+
+func networkValidWireGuardEndpoint(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("Invalid WireGuard endpoint (must be host:port): %s", value)
+	}
+
+	return networkValidPort(port)
+}
+
+func networkValidWireGuardAllowedIPs(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		_, _, err := net.ParseCIDR(strings.TrimSpace(entry))
+		if err != nil {
+			return fmt.Errorf("Invalid network in allowed IPs: %s", entry)
+		}
+	}
+
+	return nil
+}
+
+// wireGuardDevices returns the names of the WireGuard and VXLAN interfaces of the network.
+func (n *network) wireGuardDevices() (string, string) {
+	return fmt.Sprintf("%s-wg", n.name), fmt.Sprintf("%s-vx", n.name)
+}
+
+// wireGuardPort returns the UDP port WireGuard listens on for the network.
+func (n *network) wireGuardPort() string {
+	if n.config["wireguard.port"] != "" {
+		return n.config["wireguard.port"]
+	}
+
+	return networkWireGuardDefaultPort
+}
+
+// wireGuardAddress returns the address of the given node on the WireGuard mesh subnet.
+func (n *network) wireGuardAddress(nodeID int64) (net.IP, *net.IPNet, error) {
+	_, subnet, err := net.ParseCIDR(n.config["wireguard.subnet"])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ip := networkGetIP(subnet, nodeID)
+	if !subnet.Contains(ip) {
+		return nil, nil, fmt.Errorf("WireGuard subnet %s is too small for node ID %d", subnet, nodeID)
+	}
+
+	return ip, subnet, nil
+}
+
+// wireGuardPublicKey generates the node's private key for the network if missing, records the
+// matching public key in the database and returns the path to the private key.
+func (n *network) wireGuardPublicKey() (string, error) {
+	keyPath := shared.VarPath("networks", n.name, "wireguard.key")
+
+	err := os.MkdirAll(shared.VarPath("networks", n.name), 0711)
+	if err != nil {
+		return "", err
+	}
+
+	if !shared.PathExists(keyPath) {
+		privateKey, err := shared.RunCommand("wg", "genkey")
+		if err != nil {
+			return "", err
+		}
+
+		err = ioutil.WriteFile(keyPath, []byte(strings.TrimSpace(privateKey)+"\n"), 0600)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	privateKey, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	var publicKey bytes.Buffer
+	err = shared.RunCommandWithFds(bytes.NewReader(privateKey), &publicKey, "wg", "pubkey")
+	if err != nil {
+		return "", err
+	}
+
+	err = n.state.Cluster.NetworkWireGuardPublicKeySet(n.id, strings.TrimSpace(publicKey.String()))
+	if err != nil {
+		return "", err
+	}
+
+	return keyPath, nil
+}
+
+// setupWireGuard creates the WireGuard interface of the network along with the VXLAN interface
+// that carries the bridge traffic over it, then configures the peers.
+func (n *network) setupWireGuard(mtu string) error {
+	_, err := exec.LookPath("wg")
+	if err != nil {
+		return fmt.Errorf("The wg tool is required for WireGuard networks")
+	}
+
+	wgName, vxName := n.wireGuardDevices()
+
+	keyPath, err := n.wireGuardPublicKey()
+	if err != nil {
+		return err
+	}
+
+	address, subnet, err := n.wireGuardAddress(n.state.Cluster.GetNodeID())
+	if err != nil {
+		return err
+	}
+
+	prefix, _ := subnet.Mask.Size()
+
+	_, err = shared.RunCommand("ip", "link", "add", "dev", wgName, "type", "wireguard")
+	if err != nil {
+		return fmt.Errorf("Failed to create the WireGuard interface: %v", err)
+	}
+
+	_, err = shared.RunCommand("wg", "set", wgName, "private-key", keyPath, "listen-port", n.wireGuardPort())
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "-4", "addr", "add", "dev", wgName, fmt.Sprintf("%s/%d", address, prefix))
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", wgName, "mtu", fmt.Sprintf("%d", networkWireGuardMTU), "up")
+	if err != nil {
+		return err
+	}
+
+	// Carry the bridge over the mesh using VXLAN with head-end replication to each peer.
+	_, err = shared.RunCommand("ip", "link", "add", "dev", vxName, "type", "vxlan", "id", "1", "local", address.String(), "dev", wgName, "dstport", "4789")
+	if err != nil {
+		return err
+	}
+
+	err = device.NetworkAttachInterface(n.name, vxName)
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", vxName, "mtu", mtu, "up")
+	if err != nil {
+		return err
+	}
+
+	_, err = shared.RunCommand("ip", "link", "set", "dev", n.name, "up")
+	if err != nil {
+		return err
+	}
+
+	return n.refreshWireGuardPeers()
+}
+
+// refreshWireGuardPeers configures the other cluster nodes and the external peers of the network
+// on the WireGuard interface, removing any peer which is no longer part of the mesh.
+func (n *network) refreshWireGuardPeers() error {
+	wgName, vxName := n.wireGuardDevices()
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", wgName)) {
+		return nil
+	}
+
+	nodes, err := n.state.Cluster.NetworkWireGuardPeers(n.id)
+	if err != nil {
+		return err
+	}
+
+	fdb, err := shared.RunCommand("bridge", "fdb", "show", "dev", vxName)
+	if err != nil {
+		return err
+	}
+
+	wanted := []string{}
+
+	// Cluster nodes, reached on their cluster address and bridged through VXLAN.
+	for _, node := range nodes {
+		if node.Local || node.PublicKey == "" {
+			continue
+		}
+
+		address, _, err := n.wireGuardAddress(node.NodeID)
+		if err != nil {
+			return err
+		}
+
+		host, _, err := net.SplitHostPort(node.Address)
+		if err != nil {
+			return err
+		}
+
+		_, err = shared.RunCommand("wg", "set", wgName, "peer", node.PublicKey, "endpoint", net.JoinHostPort(host, n.wireGuardPort()), "allowed-ips", fmt.Sprintf("%s/32", address), "persistent-keepalive", "25")
+		if err != nil {
+			return err
+		}
+
+		wanted = append(wanted, node.PublicKey)
+
+		if !strings.Contains(fdb, fmt.Sprintf("dst %s ", address)) {
+			_, err = shared.RunCommand("bridge", "fdb", "append", "00:00:00:00:00:00", "dev", vxName, "dst", address.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// External peers, routed through the WireGuard interface.
+	for _, peer := range networkWireGuardExternalPeers(n.config) {
+		getConfig := func(key string) string {
+			return n.config[fmt.Sprintf("wireguard.peers.%s.%s", peer, key)]
+		}
+
+		publicKey := getConfig("public_key")
+		if publicKey == "" {
+			continue
+		}
+
+		allowedIPs := []string{}
+		for _, entry := range strings.Split(getConfig("allowed_ips"), ",") {
+			entry = strings.TrimSpace(entry)
+			if entry != "" {
+				allowedIPs = append(allowedIPs, entry)
+			}
+		}
+
+		cmd := []string{"set", wgName, "peer", publicKey, "allowed-ips", strings.Join(allowedIPs, ",")}
+		if getConfig("endpoint") != "" {
+			cmd = append(cmd, "endpoint", getConfig("endpoint"), "persistent-keepalive", "25")
+		}
+
+		_, err = shared.RunCommand("wg", cmd...)
+		if err != nil {
+			return err
+		}
+
+		wanted = append(wanted, publicKey)
+
+		for _, allowedIP := range allowedIPs {
+			_, err = shared.RunCommand("ip", "route", "replace", allowedIP, "dev", wgName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Remove stale peers.
+	output, err := shared.RunCommand("wg", "show", wgName, "peers")
+	if err != nil {
+		return err
+	}
+
+	for _, publicKey := range strings.Fields(output) {
+		if shared.StringInSlice(publicKey, wanted) {
+			continue
+		}
+
+		_, err = shared.RunCommand("wg", "set", wgName, "peer", publicKey, "remove")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// networkWireGuardExternalPeers returns the sorted names of the external WireGuard peers of a network.
+func networkWireGuardExternalPeers(config map[string]string) []string {
+	peers := []string{}
+
+	for k := range config {
+		if !strings.HasPrefix(k, "wireguard.peers.") {
+			continue
+		}
+
+		fields := strings.Split(k, ".")
+		if len(fields) != 4 || shared.StringInSlice(fields[2], peers) {
+			continue
+		}
+
+		peers = append(peers, fields[2])
+	}
+
+	sort.Strings(peers)
+	return peers
+}
+
+// networkUpdateWireGuardPeersTask refreshes the cluster peers of the WireGuard networks.
+func networkUpdateWireGuardPeersTask(s *state.State) error {
+	networks, err := s.Cluster.NetworksNotPending()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range networks {
+		n, err := networkLoadByName(s, name)
+		if err != nil {
+			return err
+		}
+
+		if n.config["bridge.mode"] != "wireguard" || !n.IsRunning() {
+			continue
+		}
+
+		err = n.refreshWireGuardPeers()
+		if err != nil {
+			logger.Warnf("Failed to refresh WireGuard peers for %s: %v", n.name, err)
+		}
+	}
+
+	return nil
+}
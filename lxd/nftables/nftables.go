@@ -0,0 +1,490 @@
+package nftables
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
+	"github.com/lxc/lxd/shared"
+)
+
+// nftTable is the name of the tables holding the LXD rules in each of the nftables families.
+const nftTable = "lxd"
+
+// nftChain represents a base chain of the LXD tables.
+type nftChain struct {
+	chainType string
+	hook      string
+	priority  int
+}
+
+// nftChains maps the LXD base chains to their definitions.
+var nftChains = map[string]nftChain{
+	"in":      {chainType: "filter", hook: "input", priority: 0},
+	"fwd":     {chainType: "filter", hook: "forward", priority: 0},
+	"out":     {chainType: "filter", hook: "output", priority: 0},
+	"prert":   {chainType: "nat", hook: "prerouting", priority: -100},
+	"out_nat": {chainType: "nat", hook: "output", priority: -100},
+	"pstrt":   {chainType: "nat", hook: "postrouting", priority: 100},
+}
+
+// nftBridgeChains maps the LXD base chains of the bridge family to their definitions.
+var nftBridgeChains = map[string]nftChain{
+	"in":  {chainType: "filter", hook: "input", priority: -200},
+	"fwd": {chainType: "filter", hook: "forward", priority: -200},
+}
+
+// nftTableChains lists the chains used for the rules of each of the xtables style tables.
+var nftTableChains = map[firewallConsts.Table][]string{
+	firewallConsts.TableAll:    {"in", "fwd", "out"},
+	firewallConsts.TableFilter: {"in", "fwd", "out"},
+	firewallConsts.TableNat:    {"prert", "out_nat", "pstrt"},
+	firewallConsts.TableMangle: {},
+}
+
+// NFTables is an implementation of LXD firewall using nftables
+type NFTables struct{}
+
+// nftCommand runs the nft tool with the given arguments, returning its output.
+var nftCommand = func(args ...string) (string, error) {
+	return shared.RunCommand("nft", args...)
+}
+
+// Available returns whether nftables can be used on this system.
+func Available() error {
+	_, err := exec.LookPath("nft")
+	if err != nil {
+		return fmt.Errorf("The nft tool can't be found")
+	}
+
+	_, err = nftCommand("list", "tables")
+	if err != nil {
+		return fmt.Errorf("Failed to list nftables tables: %v", err)
+	}
+
+	return nil
+}
+
+// Lower-level Functions
+
+// NetworkClear removes network rules.
+func (nf NFTables) NetworkClear(family firewallConsts.Family, table firewallConsts.Table, comment string) error {
+	return nftClear(nftFamily(family), nftTableChains[table], fmt.Sprintf("LXD network %s", comment))
+}
+
+// InstanceClear removes rules all rules for the given instance.
+func (nf NFTables) InstanceClear(family firewallConsts.Family, table firewallConsts.Table, comment string) error {
+	return nftClear(nftFamily(family), nftTableChains[table], fmt.Sprintf("LXD container %s", comment))
+}
+
+// VerifyIPv6Module checks that IPv6 filtering can be used. The bridge family rules of nftables
+// match ICMPv6 natively and don't require br_netfilter.
+func (nf NFTables) VerifyIPv6Module() error {
+	return nil
+}
+
+// Proxy Functions
+
+// InstanceProxySetupNAT creates a default NAT setup.
+func (nf NFTables) InstanceProxySetupNAT(family firewallConsts.Family, connType, address, port string, destAddr net.IP, destPort string, comment string) error {
+	ipFamily := nftFamily(family)
+	toDest := fmt.Sprintf("%s:%s", destAddr, destPort)
+	if family == firewallConsts.FamilyIPv6 {
+		toDest = fmt.Sprintf("[%s]:%s", destAddr, destPort)
+	}
+
+	comment = fmt.Sprintf("LXD container %s", comment)
+
+	// outbound <-> container
+	err := nftInsert(ipFamily, "prert", comment, ipFamily, "daddr", address, connType, "dport", port, "dnat", "to", toDest)
+	if err != nil {
+		return err
+	}
+
+	// host <-> container
+	return nftInsert(ipFamily, "out_nat", comment, ipFamily, "daddr", address, connType, "dport", port, "dnat", "to", toDest)
+}
+
+// NIC Bridged Functions
+
+// InstanceNicBridgedRemoveFilters removes any non-standard rules from the nic instance.
+func (nf NFTables) InstanceNicBridgedRemoveFilters(m deviceConfig.Device, ipv4 net.IP, ipv6 net.IP) error {
+	err := nftClear("bridge", []string{"in", "fwd"}, nftNicComment(m))
+	if err != nil {
+		return fmt.Errorf("Failed to remove network filters for %s: %v", m["name"], err)
+	}
+
+	return nil
+}
+
+// InstanceNicBridgedSetFilters sets the nic rules to standard filtering.
+func (nf NFTables) InstanceNicBridgedSetFilters(m deviceConfig.Device, ipv4 net.IP, ipv6 net.IP, comment string) error {
+	mac, err := net.ParseMAC(m["hwaddr"])
+	if err != nil {
+		return err
+	}
+
+	hostName := m["host_name"]
+	hwaddr := mac.String()
+
+	// MAC source filtering rules. Blocks any packet coming from instance with an incorrect Ethernet source MAC.
+	rules := [][]string{
+		{"in", "iifname", hostName, "ether", "saddr", "!=", hwaddr, "drop"},
+		{"fwd", "iifname", hostName, "ether", "saddr", "!=", hwaddr, "drop"},
+	}
+
+	if shared.IsTrue(m["security.ipv4_filtering"]) && ipv4 != nil {
+		rules = append(rules,
+			// Prevent ARP MAC and IP spoofing.
+			[]string{"in", "iifname", hostName, "arp", "saddr", "ether", "!=", hwaddr, "drop"},
+			[]string{"fwd", "iifname", hostName, "arp", "saddr", "ether", "!=", hwaddr, "drop"},
+			[]string{"in", "iifname", hostName, "arp", "saddr", "ip", "!=", ipv4.String(), "drop"},
+			[]string{"fwd", "iifname", hostName, "arp", "saddr", "ip", "!=", ipv4.String(), "drop"},
+			// Allow DHCPv4 to the host only. This must come before the IP source filtering rules below.
+			[]string{"in", "iifname", hostName, "ether", "saddr", hwaddr, "ip", "saddr", "0.0.0.0", "ip", "daddr", "255.255.255.255", "udp", "dport", "67", "accept"},
+			// IP source filtering rules.
+			[]string{"in", "iifname", hostName, "ip", "saddr", "!=", ipv4.String(), "drop"},
+			[]string{"fwd", "iifname", hostName, "ip", "saddr", "!=", ipv4.String(), "drop"},
+		)
+	}
+
+	if shared.IsTrue(m["security.ipv6_filtering"]) && ipv6 != nil {
+		ipv6Hex := hex.EncodeToString(ipv6.To16())
+		macHex := hex.EncodeToString(mac)
+
+		rules = append(rules,
+			// Allow DHCPv6 and Router Solicitation to the host only. This must come before the IP source filtering rules below.
+			[]string{"in", "iifname", hostName, "ether", "saddr", hwaddr, "ip6", "saddr", "fe80::/10", "ip6", "daddr", "ff02::1:2", "udp", "dport", "547", "accept"},
+			[]string{"in", "iifname", hostName, "ether", "saddr", hwaddr, "ip6", "saddr", "fe80::/10", "ip6", "daddr", "ff02::2", "icmpv6", "type", "nd-router-solicit", "accept"},
+			// Prevent Neighbor Advertisement IP spoofing (target address of the advertisement).
+			[]string{"in", "iifname", hostName, "icmpv6", "type", "nd-neighbor-advert", "@th,64,128", "!=", fmt.Sprintf("0x%s", ipv6Hex), "drop"},
+			[]string{"fwd", "iifname", hostName, "icmpv6", "type", "nd-neighbor-advert", "@th,64,128", "!=", fmt.Sprintf("0x%s", ipv6Hex), "drop"},
+			// Prevent Neighbor Advertisement MAC spoofing (target link-layer address option).
+			[]string{"in", "iifname", hostName, "icmpv6", "type", "nd-neighbor-advert", "@th,208,48", "!=", fmt.Sprintf("0x%s", macHex), "drop"},
+			[]string{"fwd", "iifname", hostName, "icmpv6", "type", "nd-neighbor-advert", "@th,208,48", "!=", fmt.Sprintf("0x%s", macHex), "drop"},
+			// IP source filtering rules.
+			[]string{"in", "iifname", hostName, "ip6", "saddr", "!=", ipv6.String(), "drop"},
+			[]string{"fwd", "iifname", hostName, "ip6", "saddr", "!=", ipv6.String(), "drop"},
+		)
+	}
+
+	for _, rule := range rules {
+		err := nftAppend("bridge", rule[0], nftNicComment(m), rule[1:]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Network Functions
+
+// NetworkSetupAllowForwarding allows forwarding dependent on boolean argument
+func (nf NFTables) NetworkSetupAllowForwarding(family firewallConsts.Family, name string, actionType firewallConsts.Action) error {
+	action := "drop"
+	if actionType == firewallConsts.ActionAccept {
+		action = "accept"
+	} else if actionType == firewallConsts.ActionReject {
+		action = "reject"
+	}
+
+	comment := fmt.Sprintf("LXD network %s", name)
+
+	err := nftInsert(nftFamily(family), "fwd", comment, "iifname", name, action)
+	if err != nil {
+		return err
+	}
+
+	return nftInsert(nftFamily(family), "fwd", comment, "oifname", name, action)
+}
+
+// NetworkSetupNAT configures NAT
+func (nf NFTables) NetworkSetupNAT(family firewallConsts.Family, name string, location firewallConsts.Location, subnet *net.IPNet, srcIP net.IP, exclude []*net.IPNet) error {
+	ipFamily := nftFamily(family)
+
+	// Excluded destinations are matched along with the network subnet using an anonymous set.
+	daddr := subnet.String()
+	if len(exclude) > 0 {
+		dests := []string{subnet.String()}
+		for _, dest := range exclude {
+			dests = append(dests, dest.String())
+		}
+
+		daddr = fmt.Sprintf("{ %s }", strings.Join(dests, ", "))
+	}
+
+	rule := []string{ipFamily, "saddr", subnet.String(), ipFamily, "daddr", "!=", daddr, "masquerade"}
+	if srcIP != nil {
+		rule = []string{ipFamily, "saddr", subnet.String(), ipFamily, "daddr", "!=", daddr, "snat", "to", srcIP.String()}
+	}
+
+	return nftAdd(ipFamily, "pstrt", location, fmt.Sprintf("LXD network %s", name), rule...)
+}
+
+// NetworkSetupIPv4DNSOverrides sets up basic nftables overrides for DHCP/DNS
+func (nf NFTables) NetworkSetupIPv4DNSOverrides(name string) error {
+	return nftSetupDNSOverrides("ip", name, "67")
+}
+
+// NetworkSetupIPv4DHCPWorkaround attempts a workaround for broken DHCP clients. nftables doesn't
+// support filling in checksums so this is a no-op.
+func (nf NFTables) NetworkSetupIPv4DHCPWorkaround(name string) error {
+	return nil
+}
+
+// NetworkSetupIPv6DNSOverrides sets up basic nftables overrides for DHCP/DNS
+func (nf NFTables) NetworkSetupIPv6DNSOverrides(name string) error {
+	return nftSetupDNSOverrides("ip6", name, "547")
+}
+
+// NetworkSetupTunnelNAT configures tunnel NAT
+func (nf NFTables) NetworkSetupTunnelNAT(name string, location firewallConsts.Location, overlaySubnet net.IPNet) error {
+	return nftAdd("ip", "pstrt", location, fmt.Sprintf("LXD network %s", name), "ip", "saddr", overlaySubnet.String(), "ip", "daddr", "!=", overlaySubnet.String(), "masquerade")
+}
+
+// NetworkSetupForwardNAT adds the DNAT rules for a network address forward. If targetPort is empty
+// the destination port is kept as is.
+func (nf NFTables) NetworkSetupForwardNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddress net.IP, targetPort string) error {
+	ipFamily := nftFamily(family)
+	comment := fmt.Sprintf("LXD network %s forwards", name)
+
+	toDest := targetAddress.String()
+	if targetPort != "" {
+		toDest = fmt.Sprintf("%s:%s", targetAddress, targetPort)
+		if family == firewallConsts.FamilyIPv6 {
+			toDest = fmt.Sprintf("[%s]:%s", targetAddress, targetPort)
+		}
+	}
+
+	// outbound <-> instance and host <-> instance
+	for _, chain := range []string{"prert", "out_nat"} {
+		err := nftInsert(ipFamily, chain, comment, ipFamily, "daddr", listenAddress.String(), protocol, "dport", listenPort, "dnat", "to", toDest)
+		if err != nil {
+			return err
+		}
+	}
+
+	// instance <-> instance on the same network (hairpin)
+	hairpinPort := listenPort
+	if targetPort != "" {
+		hairpinPort = targetPort
+	}
+
+	return nftInsert(ipFamily, "pstrt", comment, ipFamily, "saddr", targetAddress.String(), ipFamily, "daddr", targetAddress.String(), protocol, "dport", hairpinPort, "masquerade")
+}
+
+// NetworkClearForwards removes the rules of all the address forwards of a network.
+func (nf NFTables) NetworkClearForwards(family firewallConsts.Family, name string) error {
+	return nftClear(nftFamily(family), nftTableChains[firewallConsts.TableNat], fmt.Sprintf("LXD network %s forwards", name))
+}
+
+// NetworkSetupLoadBalancerNAT adds the DNAT rules spreading the connections to a network load
+// balancer across its targets, targetPorts[i] being the port of targetAddresses[i] or empty to keep
+// the destination port. With session affinity the target is picked from a hash of the source
+// address, so that a client keeps reaching the same target.
+func (nf NFTables) NetworkSetupLoadBalancerNAT(family firewallConsts.Family, name string, protocol string, listenAddress net.IP, listenPort string, targetAddresses []net.IP, targetPorts []string, sessionAffinity bool) error {
+	ipFamily := nftFamily(family)
+	comment := fmt.Sprintf("LXD network %s load-balancers", name)
+	count := len(targetAddresses)
+
+	// Rules are inserted at the start of the chains, so the last target goes first.
+	for i := count - 1; i >= 0; i-- {
+		targetAddress := targetAddresses[i]
+		targetPort := targetPorts[i]
+
+		toDest := targetAddress.String()
+		if targetPort != "" {
+			toDest = fmt.Sprintf("%s:%s", targetAddress, targetPort)
+			if family == firewallConsts.FamilyIPv6 {
+				toDest = fmt.Sprintf("[%s]:%s", targetAddress, targetPort)
+			}
+		}
+
+		rule := []string{ipFamily, "daddr", listenAddress.String(), protocol, "dport", listenPort}
+		if sessionAffinity {
+			rule = append(rule, "jhash", ipFamily, "saddr", "mod", fmt.Sprintf("%d", count), "==", fmt.Sprintf("%d", i))
+		} else if i < count-1 {
+			// Each target gets an even share of the connections left over by the previous ones.
+			rule = append(rule, "numgen", "random", "mod", fmt.Sprintf("%d", count-i), "==", "0")
+		}
+
+		rule = append(rule, "dnat", "to", toDest)
+
+		// outbound <-> instance and host <-> instance
+		for _, chain := range []string{"prert", "out_nat"} {
+			err := nftInsert(ipFamily, chain, comment, rule...)
+			if err != nil {
+				return err
+			}
+		}
+
+		// instance <-> instance on the same network (hairpin)
+		hairpinPort := listenPort
+		if targetPort != "" {
+			hairpinPort = targetPort
+		}
+
+		err := nftInsert(ipFamily, "pstrt", comment, ipFamily, "saddr", targetAddress.String(), ipFamily, "daddr", targetAddress.String(), protocol, "dport", hairpinPort, "masquerade")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NetworkClearLoadBalancers removes the rules of all the load balancers of a network.
+func (nf NFTables) NetworkClearLoadBalancers(family firewallConsts.Family, name string) error {
+	return nftClear(nftFamily(family), nftTableChains[firewallConsts.TableNat], fmt.Sprintf("LXD network %s load-balancers", name))
+}
+
+// NetworkHasRules returns whether any rules of the network are present.
+func (nf NFTables) NetworkHasRules(family firewallConsts.Family, name string) (bool, error) {
+	output, err := nftCommand("list", "table", nftFamily(family), nftTable)
+	if err != nil {
+		// No rules if the table doesn't exist.
+		return false, nil
+	}
+
+	return strings.Contains(output, fmt.Sprintf("generated for LXD network %s\"", name)), nil
+}
+
+// Helper Functions
+
+// nftFamily returns the nftables family of the given firewall family.
+func nftFamily(family firewallConsts.Family) string {
+	if family == firewallConsts.FamilyIPv6 {
+		return "ip6"
+	}
+
+	return "ip"
+}
+
+// nftNicComment returns the comment used for the filtering rules of a bridged NIC.
+func nftNicComment(m deviceConfig.Device) string {
+	return fmt.Sprintf("LXD nic %s", m["host_name"])
+}
+
+// nftSetupDNSOverrides allows DHCP and DNS traffic between the host and a network.
+func nftSetupDNSOverrides(family string, name string, dhcpPort string) error {
+	comment := fmt.Sprintf("LXD network %s", name)
+	rules := [][]string{
+		{"in", "iifname", name, "udp", "dport", dhcpPort, "accept"},
+		{"in", "iifname", name, "udp", "dport", "53", "accept"},
+		{"in", "iifname", name, "tcp", "dport", "53", "accept"},
+		{"out", "oifname", name, "udp", "sport", dhcpPort, "accept"},
+		{"out", "oifname", name, "udp", "sport", "53", "accept"},
+		{"out", "oifname", name, "tcp", "sport", "53", "accept"},
+	}
+
+	for _, rule := range rules {
+		err := nftInsert(family, rule[0], comment, rule[1:]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nftEnsureChain creates the LXD table and the given base chain in the family if missing.
+func nftEnsureChain(family string, chain string) error {
+	chains := nftChains
+	if family == "bridge" {
+		chains = nftBridgeChains
+	}
+
+	def, ok := chains[chain]
+	if !ok {
+		return fmt.Errorf("Unknown nftables chain %q in family %q", chain, family)
+	}
+
+	_, err := nftCommand("add", "table", family, nftTable)
+	if err != nil {
+		return err
+	}
+
+	_, err = nftCommand("add", "chain", family, nftTable, chain, fmt.Sprintf("{ type %s hook %s priority %d; }", def.chainType, def.hook, def.priority))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func nftAdd(family string, chain string, location firewallConsts.Location, comment string, rule ...string) error {
+	if location == firewallConsts.LocationAppend {
+		return nftAppend(family, chain, comment, rule...)
+	}
+
+	return nftInsert(family, chain, comment, rule...)
+}
+
+func nftAppend(family string, chain string, comment string, rule ...string) error {
+	return nftConfig("add", family, chain, comment, rule...)
+}
+
+func nftInsert(family string, chain string, comment string, rule ...string) error {
+	return nftConfig("insert", family, chain, comment, rule...)
+}
+
+func nftConfig(method string, family string, chain string, comment string, rule ...string) error {
+	err := nftEnsureChain(family, chain)
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{method, "rule", family, nftTable, chain}, rule...)
+	args = append(args, "comment", fmt.Sprintf("\"generated for %s\"", comment))
+
+	_, err = nftCommand(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// nftClear removes the rules matching the comment from the given chains of the family's LXD table.
+func nftClear(family string, chains []string, comment string) error {
+	// Detect kernels that lack IPv6 support
+	if !shared.PathExists("/proc/sys/net/ipv6") && family == "ip6" {
+		return nil
+	}
+
+	output, err := nftCommand("-a", "list", "table", family, nftTable)
+	if err != nil {
+		// Nothing to clear if the table doesn't exist.
+		return nil
+	}
+
+	chain := ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "chain" {
+			chain = fields[1]
+			continue
+		}
+
+		if !shared.StringInSlice(chain, chains) || !strings.Contains(line, fmt.Sprintf("generated for %s", comment)) {
+			continue
+		}
+
+		// Rules are listed with a trailing "# handle <N>".
+		if len(fields) < 2 || fields[len(fields)-2] != "handle" {
+			continue
+		}
+
+		_, err = nftCommand("delete", "rule", family, nftTable, chain, "handle", fields[len(fields)-1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package nftables
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	firewallConsts "github.com/lxc/lxd/lxd/firewall/consts"
+)
+
+// nftRecord replaces the nft tool with a function recording the rules added and removed, and
+// answering listings with the given output. The returned function restores the nft tool.
+func nftRecord(listing string) (*[]string, func()) {
+	commands := []string{}
+	previous := nftCommand
+	nftCommand = func(args ...string) (string, error) {
+		command := strings.Join(args, " ")
+		if strings.Contains(command, "list") {
+			return listing, nil
+		}
+
+		if args[0] != "add" || args[1] == "rule" {
+			commands = append(commands, command)
+		}
+
+		return "", nil
+	}
+
+	return &commands, func() { nftCommand = previous }
+}
+
+func TestNetworkSetupNAT(t *testing.T) {
+	commands, restore := nftRecord("")
+	defer restore()
+
+	_, subnet, err := net.ParseCIDR("10.0.0.0/24")
+	require.NoError(t, err)
+
+	_, exclude, err := net.ParseCIDR("192.0.2.0/24")
+	require.NoError(t, err)
+
+	err = NFTables{}.NetworkSetupNAT(firewallConsts.FamilyIPv4, "lxdbr0", firewallConsts.LocationAppend, subnet, nil, []*net.IPNet{exclude})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`add rule ip lxd pstrt ip saddr 10.0.0.0/24 ip daddr != { 10.0.0.0/24, 192.0.2.0/24 } masquerade comment "generated for LXD network lxdbr0"`,
+	}, *commands)
+}
+
+func TestNetworkSetupForwardNAT(t *testing.T) {
+	commands, restore := nftRecord("")
+	defer restore()
+
+	err := NFTables{}.NetworkSetupForwardNAT(firewallConsts.FamilyIPv6, "lxdbr0", "tcp", net.ParseIP("2001:db8::1"), "80", net.ParseIP("fd42::2"), "8080")
+	require.NoError(t, err)
+
+	comment := `comment "generated for LXD network lxdbr0 forwards"`
+	assert.Equal(t, []string{
+		`insert rule ip6 lxd prert ip6 daddr 2001:db8::1 tcp dport 80 dnat to [fd42::2]:8080 ` + comment,
+		`insert rule ip6 lxd out_nat ip6 daddr 2001:db8::1 tcp dport 80 dnat to [fd42::2]:8080 ` + comment,
+		`insert rule ip6 lxd pstrt ip6 saddr fd42::2 ip6 daddr fd42::2 tcp dport 8080 masquerade ` + comment,
+	}, *commands)
+}
+
+func TestNetworkSetupLoadBalancerNAT(t *testing.T) {
+	commands, restore := nftRecord("")
+	defer restore()
+
+	targets := []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.4")}
+	err := NFTables{}.NetworkSetupLoadBalancerNAT(firewallConsts.FamilyIPv4, "lxdbr0", "tcp", net.ParseIP("192.0.2.1"), "80", targets, []string{"8080", "", ""}, false)
+	require.NoError(t, err)
+
+	// The rules are inserted from the last target, which takes whatever is left over.
+	comment := `comment "generated for LXD network lxdbr0 load-balancers"`
+	assert.Equal(t, []string{
+		`insert rule ip lxd prert ip daddr 192.0.2.1 tcp dport 80 dnat to 10.0.0.4 ` + comment,
+		`insert rule ip lxd out_nat ip daddr 192.0.2.1 tcp dport 80 dnat to 10.0.0.4 ` + comment,
+		`insert rule ip lxd pstrt ip saddr 10.0.0.4 ip daddr 10.0.0.4 tcp dport 80 masquerade ` + comment,
+		`insert rule ip lxd prert ip daddr 192.0.2.1 tcp dport 80 numgen random mod 2 == 0 dnat to 10.0.0.3 ` + comment,
+		`insert rule ip lxd out_nat ip daddr 192.0.2.1 tcp dport 80 numgen random mod 2 == 0 dnat to 10.0.0.3 ` + comment,
+		`insert rule ip lxd pstrt ip saddr 10.0.0.3 ip daddr 10.0.0.3 tcp dport 80 masquerade ` + comment,
+		`insert rule ip lxd prert ip daddr 192.0.2.1 tcp dport 80 numgen random mod 3 == 0 dnat to 10.0.0.2:8080 ` + comment,
+		`insert rule ip lxd out_nat ip daddr 192.0.2.1 tcp dport 80 numgen random mod 3 == 0 dnat to 10.0.0.2:8080 ` + comment,
+		`insert rule ip lxd pstrt ip saddr 10.0.0.2 ip daddr 10.0.0.2 tcp dport 8080 masquerade ` + comment,
+	}, *commands)
+}
+
+func TestNetworkSetupLoadBalancerNATSessionAffinity(t *testing.T) {
+	commands, restore := nftRecord("")
+	defer restore()
+
+	targets := []net.IP{net.ParseIP("fd42::2"), net.ParseIP("fd42::3")}
+	err := NFTables{}.NetworkSetupLoadBalancerNAT(firewallConsts.FamilyIPv6, "lxdbr0", "udp", net.ParseIP("2001:db8::1"), "53", targets, []string{"", ""}, true)
+	require.NoError(t, err)
+
+	// Every target matches its own share of the source address hashes.
+	assert.Contains(t, *commands, `insert rule ip6 lxd prert ip6 daddr 2001:db8::1 udp dport 53 jhash ip6 saddr mod 2 == 0 dnat to fd42::2 comment "generated for LXD network lxdbr0 load-balancers"`)
+	assert.Contains(t, *commands, `insert rule ip6 lxd prert ip6 daddr 2001:db8::1 udp dport 53 jhash ip6 saddr mod 2 == 1 dnat to fd42::3 comment "generated for LXD network lxdbr0 load-balancers"`)
+}
+
+func TestInstanceNicBridgedSetFilters(t *testing.T) {
+	commands, restore := nftRecord("")
+	defer restore()
+
+	m := deviceConfig.Device{
+		"host_name":               "veth1234",
+		"hwaddr":                  "00:16:3e:00:00:01",
+		"security.ipv4_filtering": "true",
+	}
+
+	err := NFTables{}.InstanceNicBridgedSetFilters(m, net.ParseIP("10.0.0.2"), nil, "c1")
+	require.NoError(t, err)
+
+	// IPv6 filtering isn't enabled, so only the MAC, ARP, DHCPv4 and IPv4 rules are added.
+	require.Len(t, *commands, 9)
+	for _, command := range *commands {
+		assert.True(t, strings.HasPrefix(command, "add rule bridge lxd "))
+		assert.Contains(t, command, "iifname veth1234")
+		assert.True(t, strings.HasSuffix(command, `comment "generated for LXD nic veth1234"`))
+	}
+
+	assert.Contains(t, *commands, `add rule bridge lxd fwd iifname veth1234 ip saddr != 10.0.0.2 drop comment "generated for LXD nic veth1234"`)
+}
+
+func TestNetworkClear(t *testing.T) {
+	listing := `table ip lxd {
+	chain fwd {
+		type filter hook forward priority 0; policy accept;
+		iifname "lxdbr0" accept comment "generated for LXD network lxdbr0" # handle 4
+		iifname "lxdbr1" accept comment "generated for LXD network lxdbr1" # handle 5
+	}
+
+	chain pstrt {
+		type nat hook postrouting priority 100; policy accept;
+		ip saddr 10.0.0.0/24 masquerade comment "generated for LXD network lxdbr0" # handle 7
+	}
+}`
+
+	commands, restore := nftRecord(listing)
+	defer restore()
+
+	err := NFTables{}.NetworkClear(firewallConsts.FamilyIPv4, firewallConsts.TableFilter, "lxdbr0")
+	require.NoError(t, err)
+
+	// Only the rules of the network in the chains of the table are removed.
+	assert.Equal(t, []string{"delete rule ip lxd fwd handle 4"}, *commands)
+}
+
+func TestNetworkHasRules(t *testing.T) {
+	_, restore := nftRecord(`iifname "lxdbr0" accept comment "generated for LXD network lxdbr0" # handle 4`)
+	defer restore()
+
+	has, err := NFTables{}.NetworkHasRules(firewallConsts.FamilyIPv4, "lxdbr0")
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	has, err = NFTables{}.NetworkHasRules(firewallConsts.FamilyIPv4, "lxdbr")
+	require.NoError(t, err)
+	assert.False(t, has)
+}
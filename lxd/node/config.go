@@ -2,9 +2,15 @@ package node
 
 import (
 	"fmt"
+	"net"
+	"runtime"
+	"strconv"
 
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/firewall"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/logging"
 )
 
 // Config holds node-local configuration values for a certain LXD instance.
@@ -43,11 +49,37 @@ func (c *Config) ClusterAddress() string {
 	return c.m.GetString("cluster.https_address")
 }
 
+// BGPAddress returns the address and port to setup the BGP server on
+func (c *Config) BGPAddress() string {
+	return c.m.GetString("core.bgp_address")
+}
+
+// BGPRouterID returns the router ID of the BGP server
+func (c *Config) BGPRouterID() string {
+	return c.m.GetString("core.bgp_routerid")
+}
+
 // DebugAddress returns the address and port to setup the pprof listener on
 func (c *Config) DebugAddress() string {
 	return c.m.GetString("core.debug_address")
 }
 
+// DNSAddress returns the address and port to setup the DNS server on
+func (c *Config) DNSAddress() string {
+	return c.m.GetString("core.dns_address")
+}
+
+// FirewallDriver returns the firewall driver to use on this node
+func (c *Config) FirewallDriver() string {
+	return c.m.GetString("core.firewall")
+}
+
+// StartupConcurrency returns the number of storage pools and instances to
+// start in parallel when the daemon starts, 0 meaning one per CPU.
+func (c *Config) StartupConcurrency() int64 {
+	return c.m.GetInt64("core.startup_concurrency")
+}
+
 // MAASMachine returns the MAAS machine this instance is associated with, if
 // any.
 func (c *Config) MAASMachine() string {
@@ -64,6 +96,16 @@ func (c *Config) StorageImagesVolume() string {
 	return c.m.GetString("storage.images_volume")
 }
 
+// LogLevels returns the log level of each subsystem which doesn't log at the default level.
+func (c *Config) LogLevels() map[string]string {
+	levels := map[string]string{}
+	for _, subsystem := range logging.Subsystems {
+		levels[subsystem] = c.m.GetString("core.log_level." + subsystem)
+	}
+
+	return levels
+}
+
 // Dump current configuration keys and their values. Keys with values matching
 // their defaults are omitted.
 func (c *Config) Dump() map[string]interface{} {
@@ -133,6 +175,76 @@ func DebugAddress(node *db.Node) (string, error) {
 	return config.DebugAddress(), nil
 }
 
+// BGPAddress is a convenience for loading the node configuration and
+// returning the values of core.bgp_address and core.bgp_routerid.
+func BGPAddress(node *db.Node) (string, string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return config.BGPAddress(), config.BGPRouterID(), nil
+}
+
+// DNSAddress is a convenience for loading the node configuration and
+// returning the value of core.dns_address.
+func DNSAddress(node *db.Node) (string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return config.DNSAddress(), nil
+}
+
+// FirewallDriver is a convenience for loading the node configuration and
+// returning the value of core.firewall.
+func FirewallDriver(node *db.Node) (string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return config.FirewallDriver(), nil
+}
+
+// StartupConcurrency is a convenience for loading the node configuration and
+// returning the number of storage pools and instances to start in parallel,
+// resolving the default of one per CPU.
+func StartupConcurrency(node *db.Node) (int, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := int(config.StartupConcurrency())
+	if concurrency == 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	return concurrency, nil
+}
+
 func (c *Config) update(values map[string]interface{}) (map[string]string, error) {
 	changed, err := c.m.Change(values)
 	if err != nil {
@@ -158,6 +270,25 @@ var ConfigSchema = config.Schema{
 	// Network address for the debug server
 	"core.debug_address": {},
 
+	// Network address for the DNS server
+	"core.dns_address": {},
+
+	// Network address and router ID of the BGP server
+	"core.bgp_address":  {},
+	"core.bgp_routerid": {Validator: validateBGPRouterID},
+
+	// Firewall driver (auto, xtables or nftables), applied on restart
+	"core.firewall": {Default: "auto", Validator: firewall.ValidateDriver},
+
+	// Number of storage pools and instances started in parallel (0 for one per CPU)
+	"core.startup_concurrency": {Type: config.Int64, Default: "0", Validator: validateStartupConcurrency},
+
+	// Log level of each subsystem, overriding the --debug and --verbose flags
+	"core.log_level.cluster":  {Validator: validateLogLevel},
+	"core.log_level.instance": {Validator: validateLogLevel},
+	"core.log_level.network":  {Validator: validateLogLevel},
+	"core.log_level.storage":  {Validator: validateLogLevel},
+
 	// MAAS machine this LXD instance is associated with
 	"maas.machine": {},
 
@@ -165,3 +296,41 @@ var ConfigSchema = config.Schema{
 	"storage.backups_volume": {},
 	"storage.images_volume":  {},
 }
+
+func validateStartupConcurrency(value string) error {
+	concurrency, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	if concurrency < 0 {
+		return fmt.Errorf("Startup concurrency can't be negative")
+	}
+
+	return nil
+}
+
+func validateBGPRouterID(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil || ip.To4() == nil {
+		return fmt.Errorf("Invalid router ID %q, must be an IPv4 address", value)
+	}
+
+	return nil
+}
+
+func validateLogLevel(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if !shared.StringInSlice(value, []string{"debug", "info", "warn", "error", "crit"}) {
+		return fmt.Errorf("Invalid log level %q, must be one of: debug, info, warn, error, crit", value)
+	}
+
+	return nil
+}
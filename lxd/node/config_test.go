@@ -1,6 +1,7 @@
 package node_test
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/lxc/lxd/lxd/db"
@@ -142,3 +143,29 @@ func TestClusterAddress(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "127.0.0.1:666", address)
 }
+
+// The core.startup_concurrency config key defaults to one per CPU and can't be
+// negative.
+func TestStartupConcurrency(t *testing.T) {
+	nodeDB, cleanup := db.NewTestNode(t)
+	defer cleanup()
+
+	concurrency, err := node.StartupConcurrency(nodeDB)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.NumCPU(), concurrency)
+
+	err = nodeDB.Transaction(func(tx *db.NodeTx) error {
+		config, err := node.ConfigLoad(tx)
+		require.NoError(t, err)
+		_, err = config.Replace(map[string]interface{}{"core.startup_concurrency": "-1"})
+		assert.Error(t, err)
+		_, err = config.Replace(map[string]interface{}{"core.startup_concurrency": "4"})
+		require.NoError(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	concurrency, err = node.StartupConcurrency(nodeDB)
+	require.NoError(t, err)
+	assert.Equal(t, 4, concurrency)
+}
@@ -3,19 +3,25 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 
+	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
 )
 
 var operationCmd = APIEndpoint{
@@ -52,6 +58,10 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAllowed(r, op.Project()) {
+			return response.Forbidden(nil)
+		}
+
 		_, body, err = op.Render()
 		if err != nil {
 			return response.SmartError(err)
@@ -62,6 +72,7 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var project string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
 		if err != nil {
@@ -69,12 +80,18 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		project = operation.Project
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// The other node can't check the project, as the request comes from this one.
+	if !operationAllowed(r, project) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -90,6 +107,10 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAllowed(r, op.Project()) {
+			return response.Forbidden(nil)
+		}
+
 		if op.Permission() != "" {
 			project := op.Project()
 			if project == "" {
@@ -111,6 +132,7 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var project string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
 		if err != nil {
@@ -118,12 +140,18 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		project = operation.Project
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// The other node can't check the project, as the request comes from this one.
+	if !operationAllowed(r, project) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
@@ -136,6 +164,46 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 func operationsGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	recursion := util.IsRecursionRequest(r)
+	allProjects := shared.IsTrue(queryParam(r, "all-projects"))
+	filterClass := queryParam(r, "class")
+	filterStatus := queryParam(r, "status")
+
+	if filterClass != "" && !shared.StringInSlice(filterClass, []string{"task", "websocket", "token"}) {
+		return response.BadRequest(fmt.Errorf("Invalid operation class %q", filterClass))
+	}
+
+	filter, err := filterParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Filtering, sorting and pagination need the full operations.
+	render := recursion || filter != nil
+
+	if !allProjects && !requestHasProject(r, project) {
+		return response.Forbidden(nil)
+	}
+
+	// Check whether an operation matches the requested project, class and status.
+	match := func(op *operations.Operation) bool {
+		if !allProjects && op.Project() != "" && op.Project() != project {
+			return false
+		}
+
+		if !operationAllowed(r, op.Project()) {
+			return false
+		}
+
+		if filterClass != "" && op.Class() != filterClass {
+			return false
+		}
+
+		if filterStatus != "" && !strings.EqualFold(op.Status().String(), filterStatus) {
+			return false
+		}
+
+		return true
+	}
 
 	localOperationURLs := func() (shared.Jmap, error) {
 		// Get all the operations
@@ -147,9 +215,10 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		body := shared.Jmap{}
 
 		for _, v := range localOps {
-			if v.Project() != "" && v.Project() != project {
+			if !match(v) {
 				continue
 			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -172,9 +241,10 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		body := shared.Jmap{}
 
 		for _, v := range localOps {
-			if v.Project() != "" && v.Project() != project {
+			if !match(v) {
 				continue
 			}
+
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -186,6 +256,15 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 				return nil, err
 			}
 
+			match, err := filter.Match(operationFilterValues(op))
+			if err != nil {
+				return nil, err
+			}
+
+			if !match {
+				continue
+			}
+
 			body[status] = append(body[status].([]*api.Operation), op)
 		}
 
@@ -195,11 +274,18 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 	// Check if called from a cluster node
 	if isClusterNotification(r) {
 		// Only return the local data
-		if recursion {
+		if render {
 			// Recursive queries
 			body, err := localOperations()
 			if err != nil {
-				return response.InternalError(err)
+				return response.SmartError(err)
+			}
+
+			if filter != nil {
+				body, err = operationsPage(body, filter, recursion)
+				if err != nil {
+					return response.SmartError(err)
+				}
 			}
 
 			return response.SyncResponse(true, body)
@@ -216,12 +302,11 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 
 	// Start with local operations
 	var md shared.Jmap
-	var err error
 
-	if recursion {
+	if render {
 		md, err = localOperations()
 		if err != nil {
-			return response.InternalError(err)
+			return response.SmartError(err)
 		}
 	} else {
 		md, err = localOperationURLs()
@@ -238,15 +323,26 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 
 	// Return now if not clustered
 	if !clustered {
+		if filter != nil {
+			md, err = operationsPage(md, filter, recursion)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+
 		return response.SyncResponse(true, md)
 	}
 
-	// Get all nodes with running operations in this project.
+	// Get all nodes with running operations in this project, or in any of them.
 	var nodes []string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		var err error
 
-		nodes, err = tx.OperationNodes(project)
+		if allProjects {
+			nodes, err = tx.OperationNodesAllProjects()
+		} else {
+			nodes, err = tx.OperationNodes(project)
+		}
 		if err != nil {
 			return err
 		}
@@ -275,26 +371,58 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		// Get operation data
-		ops, err := client.GetOperations()
-		if err != nil {
-			return response.SmartError(err)
+		// Get operation data, filtered the same way by the remote server
+		args := &lxd.OperationsArgs{
+			AllProjects: allProjects,
+			Class:       filterClass,
+			Status:      filterStatus,
+			Filter:      &lxd.FilterArgs{Filter: queryParam(r, "filter")},
+		}
+
+		// The other node can't leave out the projects of clients restricted to some of them, as
+		// the requests come from this one, so get the operations of each of those instead.
+		var ops []api.Operation
+		restrictedProjects := requestProjects(r)
+		if allProjects && len(restrictedProjects) > 0 {
+			args.AllProjects = false
+			seen := map[string]bool{}
+
+			for _, restrictedProject := range restrictedProjects {
+				projectOps, err := client.UseProject(restrictedProject).GetOperationsWithArgs(args)
+				if err != nil {
+					return response.SmartError(err)
+				}
+
+				// Operations which aren't tied to a project are listed in all of them.
+				for _, op := range projectOps {
+					if !seen[op.ID] {
+						seen[op.ID] = true
+						ops = append(ops, op)
+					}
+				}
+			}
+		} else {
+			ops, err = client.UseProject(project).GetOperationsWithArgs(args)
+			if err != nil {
+				return response.SmartError(err)
+			}
 		}
 
 		// Merge with existing data
 		for _, op := range ops {
+			op := op
 			status := strings.ToLower(op.Status)
 
 			_, ok := md[status]
 			if !ok {
-				if recursion {
+				if render {
 					md[status] = make([]*api.Operation, 0)
 				} else {
 					md[status] = make([]string, 0)
 				}
 			}
 
-			if recursion {
+			if render {
 				md[status] = append(md[status].([]*api.Operation), &op)
 			} else {
 				md[status] = append(md[status].([]string), fmt.Sprintf("/1.0/operations/%s", op.ID))
@@ -302,9 +430,82 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	if filter != nil {
+		md, err = operationsPage(md, filter, recursion)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
 	return response.SyncResponse(true, md)
 }
 
+// operationAllowed returns whether the restricted certificate or API token behind a request, if any,
+// grants access to the project of an operation. Operations which aren't tied to a project are
+// listed in all of them.
+func operationAllowed(r *http.Request, project string) bool {
+	return project == "" || requestHasProject(r, project)
+}
+
+// operationFilterValues returns the values of the fields operations can be filtered and sorted on.
+func operationFilterValues(op *api.Operation) map[string]string {
+	return map[string]string{
+		"id":          op.ID,
+		"class":       op.Class,
+		"description": op.Description,
+		"status":      op.Status,
+		"created_at":  op.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":  op.UpdatedAt.Format(time.RFC3339Nano),
+		"may_cancel":  strconv.FormatBool(op.MayCancel),
+		"location":    op.Location,
+		"err":         op.Err,
+	}
+}
+
+// operationsPage sorts and paginates the operations grouped by status, only returning their URLs
+// unless recursion is set.
+func operationsPage(md shared.Jmap, filter *query.Filter, recursion bool) (shared.Jmap, error) {
+	ops := []*api.Operation{}
+	for _, statusOps := range md {
+		ops = append(ops, statusOps.([]*api.Operation)...)
+	}
+
+	// Start from a stable order, the grouping by status doesn't have one.
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].CreatedAt.Equal(ops[j].CreatedAt) {
+			return ops[i].ID < ops[j].ID
+		}
+
+		return ops[i].CreatedAt.Before(ops[j].CreatedAt)
+	})
+
+	values := make([]map[string]string, len(ops))
+	for i, op := range ops {
+		values[i] = operationFilterValues(op)
+	}
+
+	indexes, err := filter.Sort(values)
+	if err != nil {
+		return nil, err
+	}
+
+	body := shared.Jmap{}
+	for _, i := range indexes {
+		op := ops[i]
+		status := strings.ToLower(op.Status)
+
+		if recursion {
+			statusOps, _ := body[status].([]*api.Operation)
+			body[status] = append(statusOps, op)
+		} else {
+			statusURLs, _ := body[status].([]string)
+			body[status] = append(statusURLs, fmt.Sprintf("/%s/operations/%s", version.APIVersion, op.ID))
+		}
+	}
+
+	return body, nil
+}
+
 func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	id := mux.Vars(r)["id"]
 
@@ -316,6 +517,10 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAllowed(r, op.Project()) {
+			return response.Forbidden(nil)
+		}
+
 		_, err = op.WaitFinal(timeout)
 		if err != nil {
 			return response.InternalError(err)
@@ -331,6 +536,7 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 
 	// Then check if the query is from an operation on another node, and, if so, forward it
 	var address string
+	var project string
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		operation, err := tx.OperationByUUID(id)
 		if err != nil {
@@ -338,12 +544,18 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		address = operation.NodeAddress
+		project = operation.Project
 		return nil
 	})
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	// The other node can't check the project, as the request comes from this one.
+	if !operationAllowed(r, project) {
+		return response.Forbidden(nil)
+	}
+
 	cert := d.endpoints.NetworkCert()
 	client, err := cluster.Connect(address, cert, false)
 	if err != nil {
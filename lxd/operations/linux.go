@@ -15,7 +15,15 @@ func registerDBOperation(op *Operation, opType db.OperationType) error {
 
 	err := op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
 		_, err := tx.OperationAdd(op.project, op.id, opType)
-		return err
+		if err != nil {
+			return err
+		}
+
+		if !opType.Persistent() {
+			return nil
+		}
+
+		return tx.OperationPersist(op.id, op.createdAt, op.resources)
 	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to add Operation %s to database", op.id)
@@ -36,6 +44,18 @@ func removeDBOperation(op *Operation) error {
 	return err
 }
 
+func unpersistDBOperation(op *Operation) error {
+	return op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.OperationUnpersist(op.id)
+	})
+}
+
+func setDBOperationResumeData(op *Operation, data string) error {
+	return op.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.OperationSetResumeData(op.id, data)
+	})
+}
+
 func getServerName(op *Operation) (string, error) {
 	if op.state == nil {
 		return "", nil
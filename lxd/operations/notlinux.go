@@ -24,6 +24,14 @@ func removeDBOperation(op *Operation) error {
 	return nil
 }
 
+func unpersistDBOperation(op *Operation) error {
+	return fmt.Errorf("unpersistDBOperation not supported on this platform")
+}
+
+func setDBOperationResumeData(op *Operation, data string) error {
+	return fmt.Errorf("setDBOperationResumeData not supported on this platform")
+}
+
 func getServerName(op *Operation) (string, error) {
 	if op.state != nil {
 		return "", fmt.Errorf("registerDBOperation not supported on this platform")
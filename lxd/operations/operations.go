@@ -1,6 +1,8 @@
 package operations
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
@@ -12,6 +14,7 @@ import (
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/trace"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/cancel"
@@ -82,6 +85,7 @@ type Operation struct {
 	project     string
 	id          string
 	class       operationClass
+	opType      db.OperationType
 	createdAt   time.Time
 	updatedAt   time.Time
 	status      api.StatusCode
@@ -107,6 +111,10 @@ type Operation struct {
 
 	state  *state.State
 	events *events.Server
+
+	// Context holding the trace span of the request which created the operation, then the
+	// span of the operation itself once running
+	traceCtx context.Context
 }
 
 // OperationCreate creates a new operation and returns it. If it cannot be
@@ -119,6 +127,7 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	op.description = opType.Description()
 	op.permission = opType.Permission()
 	op.class = opClass
+	op.opType = opType
 	op.createdAt = time.Now()
 	op.updatedAt = op.createdAt
 	op.status = api.Pending
@@ -175,6 +184,47 @@ func OperationCreate(s *state.State, project string, opClass operationClass, opT
 	return &op, nil
 }
 
+// SetResumeData records what's needed to resume or roll back the operation, should the daemon
+// stop before it's done. It's a no-op for the types of operations which aren't persistent.
+func (op *Operation) SetResumeData(data interface{}) error {
+	if !op.opType.Persistent() || op.state == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return setDBOperationResumeData(op, string(encoded))
+}
+
+// SetTraceContext sets the context holding the trace span of the request which created the
+// operation, for the operation's own span to be part of the same trace. It must be called before
+// the operation runs.
+func (op *Operation) SetTraceContext(ctx context.Context) {
+	op.lock.Lock()
+	op.traceCtx = ctx
+	op.lock.Unlock()
+}
+
+// TraceContext returns the context holding the trace span of the running operation, for the work
+// it does to be traced as part of it.
+func (op *Operation) TraceContext() context.Context {
+	if op == nil {
+		return context.Background()
+	}
+
+	op.lock.Lock()
+	defer op.lock.Unlock()
+
+	if op.traceCtx == nil {
+		return context.Background()
+	}
+
+	return op.traceCtx
+}
+
 // SetEventServer allows injection of event server.
 func (op *Operation) SetEventServer(events *events.Server) {
 	op.events = events
@@ -193,6 +243,13 @@ func (op *Operation) done() {
 	close(op.chanDone)
 	op.lock.Unlock()
 
+	if op.opType.Persistent() && op.state != nil {
+		err := unpersistDBOperation(op)
+		if err != nil {
+			logger.Warnf("Failed to clear the persisted state of operation %s: %s", op.id, err)
+		}
+	}
+
 	time.AfterFunc(time.Second*5, func() {
 		operationsLock.Lock()
 		_, ok := operations[op.id]
@@ -228,8 +285,17 @@ func (op *Operation) Run() (chan error, error) {
 	op.status = api.Running
 
 	if op.onRun != nil {
+		ctx, span := trace.Start(op.traceCtx, op.description)
+		span.SetAttribute("lxd.operation.id", op.id)
+		span.SetAttribute("lxd.operation.class", op.class.String())
+		if op.project != "" {
+			span.SetAttribute("lxd.project", op.project)
+		}
+		op.traceCtx = ctx
+
 		go func(op *Operation, chanRun chan error) {
 			err := op.onRun(op)
+			span.End(err)
 			if err != nil {
 				op.lock.Lock()
 				op.status = api.Failure
@@ -537,6 +603,11 @@ func (op *Operation) Permission() string {
 	return op.permission
 }
 
+// Class returns the name of the operation class.
+func (op *Operation) Class() string {
+	return op.class.String()
+}
+
 // Project returns the operation project.
 func (op *Operation) Project() string {
 	return op.project
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -45,6 +46,11 @@ func (r *operationResponse) Render(w http.ResponseWriter) error {
 	return util.WriteJSON(w, body, debug)
 }
 
+// SetTraceContext makes the operation part of the trace of the request it's the response to.
+func (r *operationResponse) SetTraceContext(ctx context.Context) {
+	r.op.SetTraceContext(ctx)
+}
+
 func (r *operationResponse) String() string {
 	_, md, err := r.op.Render()
 	if err != nil {
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// operationRollback is the resume data of the persistent operations which are rolled back when
+// interrupted, naming what they were creating when it can't be told from their resources.
+type operationRollback struct {
+	Instance string `json:"instance,omitempty"`
+	Pool     string `json:"pool,omitempty"`
+	Volume   string `json:"volume,omitempty"`
+}
+
+// recoverInterruptedOperations resumes or rolls back the persistent operations of this node which
+// were still running when the daemon stopped, then forgets about them.
+func recoverInterruptedOperations(d *Daemon) {
+	var ops []db.InterruptedOperation
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		ops, err = tx.OperationsInterrupted()
+		return err
+	})
+	if err != nil {
+		logger.Error("Failed to load the interrupted operations", log.Ctx{"err": err})
+		return
+	}
+
+	for _, op := range ops {
+		ctx := log.Ctx{"operation": op.UUID, "type": op.Type.Description(), "project": op.Project}
+
+		var err error
+		switch op.Type {
+		case db.OperationBackupCreate:
+			err = rollbackBackupCreate(d, op)
+		case db.OperationContainerCreate:
+			err = rollbackInstanceCreate(d, op)
+		case db.OperationVolumeCopy:
+			err = rollbackVolumeCopy(d, op)
+		case db.OperationImageDownload:
+			err = resumeImageDownload(d, op)
+		}
+		if err != nil {
+			ctx["err"] = err
+			logger.Error("Failed to recover interrupted operation", ctx)
+		} else {
+			logger.Info("Recovered interrupted operation", ctx)
+		}
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.OperationRemove(op.UUID)
+		})
+		if err != nil {
+			logger.Warn("Failed to delete interrupted operation", log.Ctx{"operation": op.UUID, "err": err})
+		}
+	}
+}
+
+// rollbackBackupCreate deletes the backup which an interrupted operation was creating.
+func rollbackBackupCreate(d *Daemon, op db.InterruptedOperation) error {
+	if len(op.Resources["instances"]) == 0 || len(op.Resources["backups"]) == 0 {
+		return fmt.Errorf("Missing instance or backup name")
+	}
+
+	instanceName := op.Resources["instances"][0]
+	name := instanceName + shared.SnapshotDelimiter + op.Resources["backups"][0]
+
+	args, err := d.cluster.ContainerGetBackup(op.Project, name)
+	if err != nil {
+		if errors.Cause(err) == db.ErrNoSuchObject {
+			return nil
+		}
+
+		return err
+	}
+
+	// Leave alone a backup which already existed.
+	if args.CreationDate.Before(op.CreatedAt) {
+		return nil
+	}
+
+	return backup.DoBackupDelete(d.State(), op.Project, name, instanceName)
+}
+
+// rollbackInstanceCreate deletes the instance which an interrupted operation was creating.
+func rollbackInstanceCreate(d *Daemon, op db.InterruptedOperation) error {
+	rollback := operationRollback{}
+	if op.ResumeData != "" {
+		err := json.Unmarshal([]byte(op.ResumeData), &rollback)
+		if err != nil {
+			return err
+		}
+	}
+
+	name := rollback.Instance
+	if name == "" {
+		if len(op.Resources["instances"]) == 0 {
+			return fmt.Errorf("Missing instance name")
+		}
+
+		name = op.Resources["instances"][0]
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), op.Project, name)
+	if err != nil {
+		if errors.Cause(err) == db.ErrNoSuchObject {
+			return nil
+		}
+
+		return err
+	}
+
+	// Leave alone an instance which already existed, unless the operation named it.
+	if rollback.Instance == "" && inst.CreationDate().Before(op.CreatedAt) {
+		return nil
+	}
+
+	return inst.Delete()
+}
+
+// rollbackVolumeCopy deletes the custom volume which an interrupted operation was creating.
+func rollbackVolumeCopy(d *Daemon, op db.InterruptedOperation) error {
+	rollback := operationRollback{}
+	err := json.Unmarshal([]byte(op.ResumeData), &rollback)
+	if err != nil {
+		return err
+	}
+
+	if rollback.Pool == "" || rollback.Volume == "" {
+		return fmt.Errorf("Missing pool or volume name")
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), rollback.Pool)
+	if err != nil {
+		return err
+	}
+
+	poolID, err := d.cluster.StoragePoolGetID(rollback.Pool)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.cluster.StoragePoolNodeVolumeGetTypeID(rollback.Volume, db.StoragePoolVolumeTypeCustom, poolID)
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return nil
+		}
+
+		return err
+	}
+
+	return pool.DeleteCustomVolume(rollback.Volume, nil)
+}
+
+// resumeImageDownload starts again the image download of an interrupted operation. Downloads of
+// uploaded or published images can't be resumed, their leftover files are pruned instead.
+func resumeImageDownload(d *Daemon, op db.InterruptedOperation) error {
+	if op.ResumeData == "" {
+		return nil
+	}
+
+	req := api.ImagesPost{}
+	err := json.Unmarshal([]byte(op.ResumeData), &req)
+	if err != nil {
+		return err
+	}
+
+	return imagesPostResume(d, op.Project, req)
+}
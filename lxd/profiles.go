@@ -116,6 +116,11 @@ func profilesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	err = projectCheckNetworkDevices(d.cluster, project, req.Devices)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Update DB entry
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		hasProfiles, err := tx.ProjectHasProfiles(project)
@@ -28,6 +28,11 @@ func doProfileUpdate(d *Daemon, project, name string, id int64, profile *api.Pro
 		return err
 	}
 
+	err = projectCheckNetworkDevices(d.cluster, project, req.Devices)
+	if err != nil {
+		return err
+	}
+
 	containers, err := getProfileContainersInfo(d.cluster, project, name)
 	if err != nil {
 		return errors.Wrapf(err, "failed to query containers associated with profile '%s'", name)
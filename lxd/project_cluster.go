@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// projectClusterGroups returns the cluster groups the project's instances are restricted to, or
+// nil if they may be placed on any cluster member.
+func projectClusterGroups(project *api.Project) []string {
+	if !shared.IsTrue(project.Config["restricted"]) {
+		return nil
+	}
+
+	groups := projectConfigList(project.Config["restricted.cluster.groups"])
+	if len(groups) == 0 {
+		return nil
+	}
+
+	return groups
+}
+
+// projectCheckClusterTarget checks the target of a new instance, either a cluster member name or
+// a cluster group name prefixed with "@", against the restrictions of its project. It returns the
+// cluster groups the instance should be placed in when the target isn't a specific member.
+func projectCheckClusterTarget(cluster *db.Cluster, projectName string, target string) ([]string, error) {
+	project, err := projectLoad(cluster, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := projectClusterGroups(project)
+
+	if strings.HasPrefix(target, "@") {
+		group := strings.TrimPrefix(target, "@")
+
+		_, _, err := cluster.ClusterGroupGet(group)
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				return nil, fmt.Errorf("No cluster group called %q", group)
+			}
+
+			return nil, err
+		}
+
+		if allowed != nil && !shared.StringInSlice(group, allowed) {
+			return nil, fmt.Errorf("Cluster group %q isn't allowed in project %q", group, projectName)
+		}
+
+		return []string{group}, nil
+	}
+
+	if target == "" || allowed == nil {
+		return allowed, nil
+	}
+
+	var members []string
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		members, err = tx.ClusterGroupsMembers(allowed)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !shared.StringInSlice(target, members) {
+		return nil, fmt.Errorf("Cluster member %q isn't allowed in project %q", target, projectName)
+	}
+
+	return nil, nil
+}
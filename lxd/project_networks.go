@@ -0,0 +1,378 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// projectNICTypes lists the NIC types which can be allowed through restricted.devices.nic.
+var projectNICTypes = []string{"bridged", "ipvlan", "macvlan", "p2p", "physical", "routed", "sriov"}
+
+func projectValidNICTypes(value string) error {
+	for _, nicType := range projectConfigList(value) {
+		if !shared.StringInSlice(nicType, projectNICTypes) {
+			return fmt.Errorf("Invalid NIC type: %s", nicType)
+		}
+	}
+
+	return nil
+}
+
+func projectValidSubnets(value string) error {
+	for _, subnet := range projectConfigList(value) {
+		_, _, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return fmt.Errorf("Invalid subnet: %s", subnet)
+		}
+	}
+
+	return nil
+}
+
+// projectConfigList splits a comma separated project configuration value.
+func projectConfigList(value string) []string {
+	values := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			values = append(values, entry)
+		}
+	}
+
+	return values
+}
+
+// projectLoad returns the project with the given name.
+func projectLoad(cluster *db.Cluster, name string) (*api.Project, error) {
+	var project *api.Project
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		project, err = tx.ProjectGet(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// projectNetworkRequestProject returns the project a network request is made from, which its
+// caller was authorized for. Callers which aren't administrators may only manage networks from
+// restricted projects, so that the project's restrictions always apply to them.
+func projectNetworkRequestProject(d *Daemon, r *http.Request) (string, response.Response) {
+	projectName := projectParam(r)
+	if d.userIsAdmin(r) {
+		return projectName, nil
+	}
+
+	project, err := projectLoad(d.cluster, projectName)
+	if err != nil {
+		return "", response.SmartError(err)
+	}
+
+	if !shared.IsTrue(project.Config["restricted"]) {
+		return "", response.Forbidden(fmt.Errorf("Networks can only be managed from restricted projects"))
+	}
+
+	return projectName, nil
+}
+
+// projectNetworkOwner returns the project a network was created from.
+func projectNetworkOwner(config map[string]string) string {
+	if config["volatile.project"] == "" {
+		return "default"
+	}
+
+	return config["volatile.project"]
+}
+
+// projectCheckNetworkLimit checks that the project can create another network.
+func projectCheckNetworkLimit(cluster *db.Cluster, projectName string) error {
+	project, err := projectLoad(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	if project.Config["limits.networks"] == "" {
+		return nil
+	}
+
+	limit, err := strconv.Atoi(project.Config["limits.networks"])
+	if err != nil {
+		return err
+	}
+
+	networks, err := cluster.Networks()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, name := range networks {
+		_, network, err := cluster.NetworkGet(name)
+		if err != nil {
+			return err
+		}
+
+		if projectNetworkOwner(network.Config) == projectName {
+			count++
+		}
+	}
+
+	if count >= limit {
+		return fmt.Errorf("Project %q has reached its limit of %d networks", projectName, limit)
+	}
+
+	return nil
+}
+
+// projectCheckNetworkConfig checks the configuration of a network against the restrictions of the
+// project it's managed from. Restricted projects may only manage their own networks.
+func projectCheckNetworkConfig(cluster *db.Cluster, projectName string, oldConfig map[string]string, config map[string]string) error {
+	project, err := projectLoad(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	if !shared.IsTrue(project.Config["restricted"]) {
+		return nil
+	}
+
+	if oldConfig != nil && projectNetworkOwner(oldConfig) != projectName {
+		return fmt.Errorf("Network isn't part of project %q", projectName)
+	}
+
+	uplinks := projectConfigList(project.Config["restricted.networks.uplinks"])
+	for key, value := range config {
+		if key != "bridge.external_interfaces" && key != "bridge.bond.interfaces" && key != "ipv6.delegation.uplink" && !(strings.HasPrefix(key, "tunnel.") && strings.HasSuffix(key, ".interface")) {
+			continue
+		}
+
+		for _, iface := range projectConfigList(value) {
+			if !shared.StringInSlice(iface, uplinks) {
+				return fmt.Errorf("Uplink %q isn't allowed in project %q", iface, projectName)
+			}
+		}
+	}
+
+	addresses, err := projectSubnets(project, "restricted.networks.addresses")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{"ipv4.nat.address", "ipv6.nat.address"} {
+		if config[key] != "" && !projectSubnetAllowed(addresses, config[key]) {
+			return fmt.Errorf("External address %q of %s isn't allowed in project %q", config[key], key, projectName)
+		}
+	}
+
+	subnets, err := projectSubnets(project, "restricted.networks.subnets")
+	if err != nil || subnets == nil {
+		return err
+	}
+
+	for _, key := range []string{"ipv4.address", "ipv6.address", "ipv4.routes", "ipv6.routes"} {
+		if shared.StringInSlice(config[key], []string{"", "none", "delegated"}) {
+			continue
+		}
+
+		for _, value := range projectConfigList(config[key]) {
+			if !projectSubnetAllowed(subnets, value) {
+				return fmt.Errorf("Subnet %q of %s isn't allowed in project %q", value, key, projectName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// projectCheckNetworkDevices checks the NIC devices of an instance or profile against the
+// restrictions of its project.
+func projectCheckNetworkDevices(cluster *db.Cluster, projectName string, devices map[string]map[string]string) error {
+	project, err := projectLoad(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	if !shared.IsTrue(project.Config["restricted"]) {
+		return nil
+	}
+
+	nicTypes := []string{"bridged"}
+	if project.Config["restricted.devices.nic"] != "" {
+		nicTypes = projectConfigList(project.Config["restricted.devices.nic"])
+	}
+
+	uplinks := projectConfigList(project.Config["restricted.networks.uplinks"])
+	mirrors := projectConfigList(project.Config["restricted.networks.mirrors"])
+
+	subnets, err := projectSubnets(project, "restricted.networks.subnets")
+	if err != nil {
+		return err
+	}
+
+	for name, d := range devices {
+		if d["type"] != "nic" {
+			continue
+		}
+
+		if !shared.StringInSlice(d["nictype"], nicTypes) {
+			return fmt.Errorf("NIC type %q of device %q isn't allowed in project %q", d["nictype"], name, projectName)
+		}
+
+		// Managed networks of the project can be used freely, anything else must be an allowed uplink.
+		if d["parent"] != "" && !shared.StringInSlice(d["parent"], uplinks) {
+			_, network, err := cluster.NetworkGet(d["parent"])
+			if err != nil && err != db.ErrNoSuchObject {
+				return err
+			}
+
+			if network == nil || projectNetworkOwner(network.Config) != projectName {
+				return fmt.Errorf("Parent %q of device %q isn't allowed in project %q", d["parent"], name, projectName)
+			}
+		}
+
+		// Traffic can be mirrored to the NICs of the project's instances (<instance>/<device>),
+		// while host interfaces must be one of the project's networks or an allowed mirror.
+		target := d["mirror.target"]
+		if target != "" && !strings.Contains(target, "/") && !shared.StringInSlice(target, mirrors) {
+			_, network, err := cluster.NetworkGet(target)
+			if err != nil && err != db.ErrNoSuchObject {
+				return err
+			}
+
+			if network == nil || projectNetworkOwner(network.Config) != projectName {
+				return fmt.Errorf("Mirror target %q of device %q isn't allowed in project %q", target, name, projectName)
+			}
+		}
+
+		if subnets == nil {
+			continue
+		}
+
+		for _, key := range []string{"ipv4.address", "ipv6.address", "ipv4.routes", "ipv6.routes"} {
+			for _, value := range projectConfigList(d[key]) {
+				if !projectSubnetAllowed(subnets, value) {
+					return fmt.Errorf("Address %q of device %q isn't allowed in project %q", value, name, projectName)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// projectCheckNetworkForward checks a forward or load balancer of a network against the
+// restrictions of the project it's managed from. Its listen address must be part of the project's
+// external address ranges.
+func projectCheckNetworkForward(cluster *db.Cluster, projectName string, networkConfig map[string]string, listenAddress string) error {
+	project, err := projectLoad(cluster, projectName)
+	if err != nil {
+		return err
+	}
+
+	if !shared.IsTrue(project.Config["restricted"]) {
+		return nil
+	}
+
+	if projectNetworkOwner(networkConfig) != projectName {
+		return fmt.Errorf("Network isn't part of project %q", projectName)
+	}
+
+	// Unlike the subnets, external addresses are denied altogether until ranges are allowed, as
+	// they usually are the host's own.
+	addresses, err := projectSubnets(project, "restricted.networks.addresses")
+	if err != nil {
+		return err
+	}
+
+	if addresses == nil {
+		return fmt.Errorf("Project %q isn't allowed any external address, see restricted.networks.addresses", projectName)
+	}
+
+	if !projectSubnetAllowed(addresses, listenAddress) {
+		return fmt.Errorf("Listen address %q isn't allowed in project %q", listenAddress, projectName)
+	}
+
+	return nil
+}
+
+// projectCheckInstanceNetworkDevices checks the NIC devices of an instance, including those
+// coming from its profiles, against the restrictions of its project.
+func projectCheckInstanceNetworkDevices(cluster *db.Cluster, projectName string, devices map[string]map[string]string, profileNames []string) error {
+	err := projectCheckNetworkDevices(cluster, projectName, devices)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := cluster.ProfilesGet(projectName, profileNames)
+	if err != nil {
+		return err
+	}
+
+	for _, profile := range profiles {
+		err := projectCheckNetworkDevices(cluster, projectName, profile.Devices)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// projectSubnets returns the subnets listed in a project configuration key, or nil if it's empty.
+func projectSubnets(project *api.Project, key string) ([]*net.IPNet, error) {
+	if project.Config[key] == "" {
+		return nil, nil
+	}
+
+	subnets := []*net.IPNet{}
+	for _, value := range projectConfigList(project.Config[key]) {
+		_, subnet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
+
+// projectSubnetAllowed checks whether an address or subnet is part of one of the allowed subnets.
+func projectSubnetAllowed(subnets []*net.IPNet, value string) bool {
+	_, subnet, err := net.ParseCIDR(value)
+	if err != nil {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return false
+		}
+
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+
+		subnet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+
+	size, bits := subnet.Mask.Size()
+	for _, allowed := range subnets {
+		allowedSize, allowedBits := allowed.Mask.Size()
+		if allowedBits == bits && size >= allowedSize && allowed.Contains(subnet.IP) {
+			return true
+		}
+	}
+
+	return false
+}
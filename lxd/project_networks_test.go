@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestProjectCheckNetworkForward(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		for name, config := range map[string]map[string]string{
+			"open":     {},
+			"noaddr":   {"restricted": "true"},
+			"restrict": {"restricted": "true", "restricted.networks.addresses": "198.51.100.0/28,2001:db8::/64"},
+		} {
+			project := api.ProjectsPost{}
+			project.Name = name
+			project.Config = config
+			_, err := tx.ProjectCreate(project)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Unrestricted projects can forward any address of any network.
+	assert.NoError(t, projectCheckNetworkForward(cluster, "open", map[string]string{}, "192.0.2.1"))
+
+	// Restricted projects without external address ranges can't forward any address.
+	network := map[string]string{"volatile.project": "noaddr"}
+	assert.Error(t, projectCheckNetworkForward(cluster, "noaddr", network, "198.51.100.1"))
+
+	// Restricted projects can only forward their external addresses, on their own networks.
+	network = map[string]string{"volatile.project": "restrict"}
+	assert.NoError(t, projectCheckNetworkForward(cluster, "restrict", network, "198.51.100.1"))
+	assert.NoError(t, projectCheckNetworkForward(cluster, "restrict", network, "2001:db8::1"))
+	assert.Error(t, projectCheckNetworkForward(cluster, "restrict", network, "198.51.100.16"))
+	assert.Error(t, projectCheckNetworkForward(cluster, "restrict", map[string]string{}, "198.51.100.1"))
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// projectUsageInterval is how often the resources consumed by projects are recorded.
+const projectUsageInterval = 5 * time.Minute
+
+// projectUsageRetention is how long the resources consumed by projects are kept for.
+const projectUsageRetention = 366 * 24 * time.Hour
+
+var projectUsageCmd = APIEndpoint{
+	Path: "projects/{name}/usage",
+
+	Get: APIEndpointAction{Handler: projectUsageGet, AccessHandler: AllowAuthenticated},
+}
+
+// /1.0/projects/{name}/usage
+// Get the resources consumed by a project over a time window
+func projectUsageGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	// Check user permissions
+	if !d.userHasPermission(r, name, "view") {
+		return response.Forbidden(nil)
+	}
+
+	end := time.Now()
+	if queryParam(r, "end") != "" {
+		var err error
+		end, err = time.Parse(time.RFC3339, queryParam(r, "end"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid end %q: %v", queryParam(r, "end"), err))
+		}
+	}
+
+	start := end.Add(-30 * 24 * time.Hour)
+	if queryParam(r, "start") != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, queryParam(r, "start"))
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid start %q: %v", queryParam(r, "start"), err))
+		}
+	}
+
+	if !start.Before(end) {
+		return response.BadRequest(fmt.Errorf("The start must be before the end"))
+	}
+
+	// Usage is recorded hour by hour, with the current hour being included.
+	start = start.UTC().Truncate(time.Hour)
+	if !end.UTC().Truncate(time.Hour).Equal(end.UTC()) {
+		end = end.UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+
+	var usage db.ProjectUsage
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.ProjectID(name)
+		if err != nil {
+			return err
+		}
+
+		usage, err = tx.ProjectUsageGet(name, start, end)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	hour := time.Hour.Seconds()
+	result := api.ProjectUsage{
+		Start:            start,
+		End:              end.UTC(),
+		InstanceHours:    usage.InstanceSeconds / hour,
+		CPUHours:         usage.CPUSeconds / hour,
+		MemoryByteHours:  usage.MemoryByteSeconds / hour,
+		StorageByteHours: usage.StorageByteSeconds / hour,
+		NetworkReceived:  usage.NetworkReceived,
+		NetworkSent:      usage.NetworkSent,
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// projectUsageAllocation returns the number of CPUs and the bytes of memory allocated to an
+// instance, which is the whole host when it's not limited.
+func projectUsageAllocation(inst instance.Instance) (float64, float64) {
+	config := inst.ExpandedConfig()
+
+	cpus := float64(runtime.NumCPU())
+	if config["limits.cpu"] != "" {
+		count, err := strconv.Atoi(config["limits.cpu"])
+		if err == nil {
+			cpus = float64(count)
+		} else {
+			set, err := parseCpuset(config["limits.cpu"])
+			if err == nil {
+				cpus = float64(len(set))
+			}
+		}
+	}
+
+	total, err := shared.DeviceTotalMemory()
+	if err != nil {
+		total = 0
+	}
+
+	memory := float64(total)
+	limit := config["limits.memory"]
+	if strings.HasSuffix(limit, "%") {
+		percent, err := strconv.ParseInt(strings.TrimSuffix(limit, "%"), 10, 64)
+		if err == nil {
+			memory = float64((total / 100) * percent)
+		}
+	} else if limit != "" {
+		bytes, err := units.ParseByteSizeString(limit)
+		if err == nil {
+			memory = float64(bytes)
+		}
+	}
+
+	return cpus, memory
+}
+
+// projectUsageLeader returns whether this member accounts for the cluster-wide resources, which
+// are the volumes of remote storage pools.
+func projectUsageLeader(d *Daemon) (bool, error) {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return false, err
+	}
+
+	if !clustered {
+		return true, nil
+	}
+
+	localAddress, err := node.ClusterAddress(d.db)
+	if err != nil {
+		return false, err
+	}
+
+	leader, err := d.gateway.LeaderAddress()
+	if err != nil {
+		return false, err
+	}
+
+	return localAddress == leader, nil
+}
+
+// projectUsageTask periodically adds the resources consumed by the instances and volumes of this
+// member since the last run to the usage of their projects.
+func projectUsageTask(d *Daemon) (task.Func, task.Schedule) {
+	var last time.Time
+
+	// Traffic accumulated by each instance at the last run, to record the traffic in between.
+	traffic := map[string][2]int64{}
+
+	f := func(ctx context.Context) {
+		now := time.Now()
+		elapsed := now.Sub(last).Seconds()
+		first := last.IsZero()
+		last = now
+
+		// Time during which the task didn't run, like while LXD was down, isn't accounted.
+		if elapsed > 2*projectUsageInterval.Seconds() {
+			elapsed = projectUsageInterval.Seconds()
+		}
+
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for project usage", log.Ctx{"err": err})
+			return
+		}
+
+		usages := map[string]*db.ProjectUsage{}
+		seen := map[string][2]int64{}
+
+		for _, inst := range instances {
+			usage, ok := usages[inst.Project()]
+			if !ok {
+				usage = &db.ProjectUsage{}
+				usages[inst.Project()] = usage
+			}
+
+			if inst.IsRunning() && !first {
+				cpus, memory := projectUsageAllocation(inst)
+				usage.InstanceSeconds += elapsed
+				usage.CPUSeconds += cpus * elapsed
+				usage.MemoryByteSeconds += memory * elapsed
+			}
+
+			counters := [2]int64{}
+			for _, devUsage := range instance.NetworkUsage(inst) {
+				counters[0] += devUsage.BytesReceived
+				counters[1] += devUsage.BytesSent
+			}
+
+			key := instanceUsageKey(inst.Project(), inst.Name())
+			seen[key] = counters
+
+			previous, ok := traffic[key]
+			if ok && counters[0] >= previous[0] && counters[1] >= previous[1] {
+				usage.NetworkReceived += counters[0] - previous[0]
+				usage.NetworkSent += counters[1] - previous[1]
+			}
+		}
+
+		// Forget about the deleted or renamed instances.
+		traffic = seen
+
+		if first {
+			return
+		}
+
+		var projects []string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			projects, err = tx.ProjectNames()
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed to load projects for project usage", log.Ctx{"err": err})
+			return
+		}
+
+		leader, err := projectUsageLeader(d)
+		if err != nil {
+			logger.Warn("Failed to check whether to account for remote storage pools", log.Ctx{"err": err})
+		}
+
+		for _, project := range projects {
+			used, err := storagePools.ProjectStorageUsage(d.State(), project, leader)
+			if err != nil {
+				logger.Warn("Failed to measure project storage usage", log.Ctx{"project": project, "err": err})
+				continue
+			}
+
+			usage, ok := usages[project]
+			if !ok {
+				usage = &db.ProjectUsage{}
+				usages[project] = usage
+			}
+
+			usage.StorageByteSeconds += float64(used) * elapsed
+		}
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			for project, usage := range usages {
+				if *usage == (db.ProjectUsage{}) {
+					continue
+				}
+
+				err := tx.ProjectUsageAdd(project, now, *usage)
+				if err != nil && err != db.ErrNoSuchObject {
+					return err
+				}
+			}
+
+			return tx.ProjectUsagePrune(now.Add(-projectUsageRetention))
+		})
+		if err != nil {
+			logger.Error("Failed to record project usage", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(projectUsageInterval)
+}
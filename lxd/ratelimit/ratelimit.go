@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// idleTimeout is how long the state of a client without requests is kept for.
+const idleTimeout = 10 * time.Minute
+
+// client tracks the requests of a client.
+type client struct {
+	// Requests the client can make right away, refilled over time.
+	tokens float64
+	last   time.Time
+
+	// Requests of the client being handled.
+	inFlight int
+}
+
+// Limiter limits the rate of the requests of each client and how many of them are handled at
+// the same time.
+type Limiter struct {
+	rate        float64
+	burst       int
+	concurrency int
+
+	clients   map[string]*client
+	lastSweep time.Time
+
+	lock sync.Mutex
+}
+
+// NewLimiter returns a new limiter, which doesn't limit anything until configured.
+func NewLimiter() *Limiter {
+	return &Limiter{
+		clients: map[string]*client{},
+	}
+}
+
+// Configure sets the number of requests per second each client can make, with bursts of up to
+// the given number of requests, and how many of its requests can be handled at the same time.
+// A zero rate or concurrency disables the matching limit, and a zero burst defaults to the rate.
+func (l *Limiter) Configure(rate float64, burst int, concurrency int) error {
+	if rate < 0 || burst < 0 || concurrency < 0 {
+		return fmt.Errorf("Rate limits can't be negative")
+	}
+
+	if burst == 0 {
+		burst = int(math.Ceil(rate))
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.rate = rate
+	l.burst = burst
+	l.concurrency = concurrency
+
+	// Start over with full buckets.
+	for _, c := range l.clients {
+		c.tokens = float64(l.burst)
+	}
+
+	return nil
+}
+
+// Enabled returns whether any limit is set.
+func (l *Limiter) Enabled() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.rate > 0 || l.concurrency > 0
+}
+
+// Acquire accounts for a new request of the given client. Unless the client is over its limits,
+// it returns a function to call once the request is handled. Otherwise it returns how long the
+// client should wait before retrying, along with the reason. Long-lived requests, like event
+// streams, are left out of the concurrency limit.
+func (l *Limiter) Acquire(key string, longLived bool) (func(), time.Duration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	noop := func() {}
+
+	if l.rate <= 0 && l.concurrency <= 0 {
+		return noop, 0, nil
+	}
+
+	now := time.Now()
+	l.sweep(now)
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{tokens: float64(l.burst), last: now}
+		l.clients[key] = c
+	}
+
+	if l.rate > 0 {
+		c.tokens = math.Min(float64(l.burst), c.tokens+now.Sub(c.last).Seconds()*l.rate)
+	}
+
+	c.last = now
+
+	if l.rate > 0 && c.tokens < 1 {
+		wait := time.Duration((1 - c.tokens) / l.rate * float64(time.Second))
+		return nil, wait, fmt.Errorf("Too many requests, limited to %g per second", l.rate)
+	}
+
+	if !longLived && l.concurrency > 0 && c.inFlight >= l.concurrency {
+		return nil, time.Second, fmt.Errorf("Too many concurrent requests, limited to %d", l.concurrency)
+	}
+
+	if l.rate > 0 {
+		c.tokens--
+	}
+
+	if longLived || l.concurrency <= 0 {
+		return noop, 0, nil
+	}
+
+	c.inFlight++
+
+	released := false
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		if released {
+			return
+		}
+
+		released = true
+		c.inFlight--
+	}, 0, nil
+}
+
+// sweep forgets about the clients which have been idle for a while.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, c := range l.clients {
+		if c.inFlight == 0 && now.Sub(c.last) > idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+}
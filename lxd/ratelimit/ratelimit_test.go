@@ -0,0 +1,71 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/ratelimit"
+)
+
+func TestLimiter_Rate(t *testing.T) {
+	limiter := ratelimit.NewLimiter()
+	require.NoError(t, limiter.Configure(1, 2, 0))
+
+	// The burst goes through.
+	for i := 0; i < 2; i++ {
+		release, _, err := limiter.Acquire("tls/abcd", false)
+		require.NoError(t, err)
+		release()
+	}
+
+	_, wait, err := limiter.Acquire("tls/abcd", false)
+	assert.EqualError(t, err, "Too many requests, limited to 1 per second")
+	assert.True(t, wait > 0 && wait <= time.Second)
+
+	// Other clients have their own limit.
+	_, _, err = limiter.Acquire("tls/efgh", false)
+	assert.NoError(t, err)
+
+	// Disabling the limits lets everything through.
+	require.NoError(t, limiter.Configure(0, 0, 0))
+	assert.False(t, limiter.Enabled())
+
+	_, _, err = limiter.Acquire("tls/abcd", false)
+	assert.NoError(t, err)
+}
+
+func TestLimiter_Concurrency(t *testing.T) {
+	limiter := ratelimit.NewLimiter()
+	require.NoError(t, limiter.Configure(0, 0, 1))
+	assert.True(t, limiter.Enabled())
+
+	release, _, err := limiter.Acquire("token/abcd", false)
+	require.NoError(t, err)
+
+	_, _, err = limiter.Acquire("token/abcd", false)
+	assert.EqualError(t, err, "Too many concurrent requests, limited to 1")
+
+	// Long-lived requests aren't counted.
+	_, _, err = limiter.Acquire("token/abcd", true)
+	assert.NoError(t, err)
+
+	// Releasing twice doesn't free an extra slot.
+	release()
+	release()
+
+	release, _, err = limiter.Acquire("token/abcd", false)
+	require.NoError(t, err)
+
+	_, _, err = limiter.Acquire("token/abcd", false)
+	assert.Error(t, err)
+	release()
+}
+
+func TestLimiter_Configure(t *testing.T) {
+	limiter := ratelimit.NewLimiter()
+	assert.False(t, limiter.Enabled())
+	assert.Error(t, limiter.Configure(-1, 0, 0))
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
 	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/shared/api"
@@ -53,35 +54,38 @@ func storagePoolResourcesGet(d *Daemon, r *http.Request) response.Response {
 
 	// Get the existing storage pool
 	poolName := mux.Vars(r)["name"]
-	var res *api.ResourcesStoragePool
 
+	res, err := storagePoolResources(d.State(), poolName)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, res)
+}
+
+// storagePoolResources returns the space and inodes used by the given storage pool on the local
+// node.
+func storagePoolResources(s *state.State, poolName string) (*api.ResourcesStoragePool, error) {
 	// Check if we can load new storage layer for pool driver type.
-	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	pool, err := storagePools.GetPoolByName(s, poolName)
 	if err != storageDrivers.ErrUnknownDriver {
 		if err != nil {
-			return response.InternalError(err)
+			return nil, err
 		}
 
-		res, err = pool.GetResources()
-		if err != nil {
-			return response.InternalError(err)
-		}
-	} else { // Fallback to old storage layer.
-		s, err := storagePoolInit(d.State(), poolName)
-		if err != nil {
-			return response.InternalError(err)
-		}
+		return pool.GetResources()
+	}
 
-		err = s.StoragePoolCheck()
-		if err != nil {
-			return response.InternalError(err)
-		}
+	// Fallback to old storage layer.
+	ps, err := storagePoolInit(s, poolName)
+	if err != nil {
+		return nil, err
+	}
 
-		res, err = s.StoragePoolResources()
-		if err != nil {
-			return response.InternalError(err)
-		}
+	err = ps.StoragePoolCheck()
+	if err != nil {
+		return nil, err
 	}
 
-	return response.SyncResponse(true, res)
+	return ps.StoragePoolResources()
 }
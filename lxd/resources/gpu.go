@@ -309,6 +309,78 @@ func gpuAddDeviceInfo(devicePath string, nvidiaCards map[string]*api.ResourcesGP
 		card.DRM = &drm
 	}
 
+	// Mediated device types
+	mdevPath := filepath.Join(devicePath, "mdev_supported_types")
+	if sysfsExists(mdevPath) {
+		card.Mdev = map[string]api.ResourcesGPUCardMdev{}
+
+		// List all the types
+		entries, err := ioutil.ReadDir(mdevPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to list \"%s\"", mdevPath)
+		}
+
+		// Fill in the struct
+		for _, entry := range entries {
+			entryName := entry.Name()
+			entryPath := filepath.Join(mdevPath, entryName)
+
+			mdev := api.ResourcesGPUCardMdev{}
+
+			// API
+			deviceAPI, err := ioutil.ReadFile(filepath.Join(entryPath, "device_api"))
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "device_api"))
+			}
+
+			mdev.API = strings.TrimSpace(string(deviceAPI))
+
+			// Available instances
+			available, err := readUint(filepath.Join(entryPath, "available_instances"))
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "available_instances"))
+			}
+
+			mdev.Available = available
+
+			// Name (optional)
+			if sysfsExists(filepath.Join(entryPath, "name")) {
+				name, err := ioutil.ReadFile(filepath.Join(entryPath, "name"))
+				if err != nil {
+					return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "name"))
+				}
+
+				mdev.Name = strings.TrimSpace(string(name))
+			}
+
+			// Description (optional)
+			if sysfsExists(filepath.Join(entryPath, "description")) {
+				description, err := ioutil.ReadFile(filepath.Join(entryPath, "description"))
+				if err != nil {
+					return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "description"))
+				}
+
+				// Some drivers describe the type over several lines
+				mdev.Description = strings.Join(strings.Split(strings.TrimSpace(string(description)), "\n"), ", ")
+			}
+
+			// Devices of this type
+			mdev.Devices = []string{}
+			if sysfsExists(filepath.Join(entryPath, "devices")) {
+				devices, err := ioutil.ReadDir(filepath.Join(entryPath, "devices"))
+				if err != nil {
+					return errors.Wrapf(err, "Failed to list \"%s\"", filepath.Join(entryPath, "devices"))
+				}
+
+				for _, device := range devices {
+					mdev.Devices = append(mdev.Devices, device.Name())
+				}
+			}
+
+			card.Mdev[entryName] = mdev
+		}
+	}
+
 	return nil
 }
 
@@ -194,6 +194,24 @@ func GetMemory() (*api.ResourcesMemory, error) {
 			node.Used = info.Used
 			node.Total = info.Total
 
+			// NUMA distances
+			if sysfsExists(filepath.Join(entryPath, "distance")) {
+				distances, err := ioutil.ReadFile(filepath.Join(entryPath, "distance"))
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "distance"))
+				}
+
+				node.Distances = []uint64{}
+				for _, field := range strings.Fields(string(distances)) {
+					distance, err := strconv.ParseUint(field, 10, 64)
+					if err != nil {
+						return nil, errors.Wrapf(err, "Failed to parse NUMA distance \"%s\"", field)
+					}
+
+					node.Distances = append(node.Distances, distance)
+				}
+			}
+
 			memory.Nodes = append(memory.Nodes, node)
 		}
 	}
@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
@@ -39,6 +40,23 @@ func networkAddDeviceInfo(devicePath string, pciDB *pcidb.PCIDB, uname unix.Utsn
 		sriov.MaximumVFs = vfMaximum
 		sriov.CurrentVFs = vfCurrent
 
+		// Count the VFs still having a host interface, the others being used by instances
+		for i := uint64(0); i < vfCurrent; i++ {
+			vfNetPath := filepath.Join(devicePath, fmt.Sprintf("virtfn%d", i), "net")
+			if !sysfsExists(vfNetPath) {
+				continue
+			}
+
+			entries, err := ioutil.ReadDir(vfNetPath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to list \"%s\"", vfNetPath)
+			}
+
+			if len(entries) > 0 {
+				sriov.AvailableVFs++
+			}
+		}
+
 		// Add the SRIOV data to the card
 		card.SRIOV = &sriov
 	}
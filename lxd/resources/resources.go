@@ -38,6 +38,12 @@ func GetResources() (*api.Resources, error) {
 		return nil, errors.Wrap(err, "Failed to retrieve storage information")
 	}
 
+	// Get USB information
+	usb, err := GetUSB()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to retrieve USB information")
+	}
+
 	// Build the final struct
 	resources := api.Resources{
 		CPU:     *cpu,
@@ -45,6 +51,7 @@ func GetResources() (*api.Resources, error) {
 		GPU:     *gpu,
 		Network: *network,
 		Storage: *storage,
+		USB:     *usb,
 	}
 
 	return &resources, nil
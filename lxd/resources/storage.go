@@ -98,6 +98,63 @@ func storageAddDriveInfo(devicePath string, disk *api.ResourcesStorageDisk) erro
 	return nil
 }
 
+func storageAddNVMeInfo(entryPath string, devicePath string, disk *api.ResourcesStorageDisk) error {
+	nvme := api.ResourcesStorageDiskNVMe{}
+
+	// Controller
+	controllerPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to track down \"%s\"", devicePath)
+	}
+
+	nvme.Controller = filepath.Base(controllerPath)
+
+	if sysfsExists(filepath.Join(devicePath, "cntlid")) {
+		controllerID, err := readUint(filepath.Join(devicePath, "cntlid"))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, "cntlid"))
+		}
+
+		nvme.ControllerID = controllerID
+	}
+
+	// Namespace, from the device name on older kernels
+	if sysfsExists(filepath.Join(entryPath, "nsid")) {
+		namespace, err := readUint(filepath.Join(entryPath, "nsid"))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(entryPath, "nsid"))
+		}
+
+		nvme.Namespace = namespace
+	} else {
+		name := filepath.Base(entryPath)
+		namespace, err := strconv.ParseUint(name[strings.LastIndex(name, "n")+1:], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse the namespace of \"%s\"", name)
+		}
+
+		nvme.Namespace = namespace
+	}
+
+	// Transport, address and subsystem
+	for file, value := range map[string]*string{"transport": &nvme.Transport, "address": &nvme.Address, "subsysnqn": &nvme.SubsystemNQN} {
+		if !sysfsExists(filepath.Join(devicePath, file)) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(devicePath, file))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, file))
+		}
+
+		*value = strings.TrimSpace(string(content))
+	}
+
+	disk.NVMe = &nvme
+
+	return nil
+}
+
 // GetStorage returns a filled api.ResourcesStorage struct ready for use by LXD
 func GetStorage() (*api.ResourcesStorage, error) {
 	storage := api.ResourcesStorage{}
@@ -174,6 +231,14 @@ func GetStorage() (*api.ResourcesStorage, error) {
 				disk.Type = filepath.Base(diskSubsystem)
 			}
 
+			// NVMe namespace
+			if disk.Type == "nvme" {
+				err := storageAddNVMeInfo(entryPath, devicePath, &disk)
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed to retrieve NVMe information for \"%s\"", entryName)
+				}
+			}
+
 			// Read-only
 			diskRo, err := readUint(filepath.Join(entryPath, "ro"))
 			if err != nil {
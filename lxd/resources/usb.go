@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+var sysBusUsbDevices = "/sys/bus/usb/devices"
+
+// usbParent returns the ID of the hub a USB device is plugged in, or an empty string for root hubs.
+func usbParent(id string) string {
+	if strings.HasPrefix(id, "usb") {
+		return ""
+	}
+
+	// Devices on a port of another device.
+	idx := strings.LastIndex(id, ".")
+	if idx > 0 {
+		return id[:idx]
+	}
+
+	// Devices on a port of a root hub.
+	return fmt.Sprintf("usb%s", strings.SplitN(id, "-", 2)[0])
+}
+
+// usbInterfaceDevice returns the ID of the USB device an interface belongs to.
+func usbInterfaceDevice(id string) string {
+	device := strings.SplitN(id, ":", 2)[0]
+
+	// Root hubs interfaces are named after port 0 of their bus.
+	if strings.HasSuffix(device, "-0") {
+		return fmt.Sprintf("usb%s", strings.TrimSuffix(device, "-0"))
+	}
+
+	return device
+}
+
+func usbAddInterfaceInfo(interfacePath string, uname unix.Utsname, device *api.ResourcesUSBDevice) error {
+	info := api.ResourcesUSBDeviceInterface{}
+
+	// Number and class
+	number, err := readHex(filepath.Join(interfacePath, "bInterfaceNumber"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(interfacePath, "bInterfaceNumber"))
+	}
+
+	info.Number = number
+
+	class, err := readHex(filepath.Join(interfacePath, "bInterfaceClass"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(interfacePath, "bInterfaceClass"))
+	}
+
+	info.ClassID = class
+
+	subClass, err := readHex(filepath.Join(interfacePath, "bInterfaceSubClass"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(interfacePath, "bInterfaceSubClass"))
+	}
+
+	info.SubClassID = subClass
+
+	// Driver information
+	driverPath := filepath.Join(interfacePath, "driver")
+	if sysfsExists(driverPath) {
+		linkTarget, err := filepath.EvalSymlinks(driverPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to track down \"%s\"", driverPath)
+		}
+
+		// Set the driver name
+		info.Driver = filepath.Base(linkTarget)
+
+		// Try to get the version, fallback to kernel version
+		out, err := ioutil.ReadFile(filepath.Join(driverPath, "module", "version"))
+		if err == nil {
+			info.DriverVersion = strings.TrimSpace(string(out))
+		} else {
+			info.DriverVersion = strings.TrimRight(string(uname.Release[:]), "\x00")
+		}
+	}
+
+	device.Interfaces = append(device.Interfaces, info)
+
+	return nil
+}
+
+func usbAddDeviceInfo(devicePath string, device *api.ResourcesUSBDevice) error {
+	// Bus and device addresses
+	busAddress, err := readUint(filepath.Join(devicePath, "busnum"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, "busnum"))
+	}
+
+	device.BusAddress = busAddress
+
+	deviceAddress, err := readUint(filepath.Join(devicePath, "devnum"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, "devnum"))
+	}
+
+	device.DeviceAddress = deviceAddress
+
+	// Speed in Mbit/s
+	speed, err := ioutil.ReadFile(filepath.Join(devicePath, "speed"))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, "speed"))
+	}
+
+	device.Speed, err = strconv.ParseFloat(strings.TrimSpace(string(speed)), 64)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse USB speed")
+	}
+
+	// Number of ports of hubs
+	if sysfsExists(filepath.Join(devicePath, "maxchild")) {
+		ports, err := readUint(filepath.Join(devicePath, "maxchild"))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, "maxchild"))
+		}
+
+		device.Ports = ports
+	}
+
+	// Vendor and product
+	for file, value := range map[string]*string{"idVendor": &device.VendorID, "idProduct": &device.ProductID, "manufacturer": &device.Vendor, "product": &device.Product, "serial": &device.Serial} {
+		if !sysfsExists(filepath.Join(devicePath, file)) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(devicePath, file))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read \"%s\"", filepath.Join(devicePath, file))
+		}
+
+		*value = strings.TrimSpace(string(content))
+	}
+
+	return nil
+}
+
+// GetUSB returns a filled api.ResourcesUSB struct ready for use by LXD
+func GetUSB() (*api.ResourcesUSB, error) {
+	usb := api.ResourcesUSB{}
+	usb.Devices = []api.ResourcesUSBDevice{}
+
+	if !sysfsExists(sysBusUsbDevices) {
+		return &usb, nil
+	}
+
+	// Get uname for driver version
+	uname := unix.Utsname{}
+	err := unix.Uname(&uname)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get uname")
+	}
+
+	entries, err := ioutil.ReadDir(sysBusUsbDevices)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list \"%s\"", sysBusUsbDevices)
+	}
+
+	// Add the devices, then their interfaces
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if strings.Contains(entryName, ":") {
+			continue
+		}
+
+		device := api.ResourcesUSBDevice{}
+		device.ID = entryName
+		device.Parent = usbParent(entryName)
+		device.Interfaces = []api.ResourcesUSBDeviceInterface{}
+
+		err := usbAddDeviceInfo(filepath.Join(sysBusUsbDevices, entryName), &device)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to add device information for \"%s\"", entryName)
+		}
+
+		usb.Devices = append(usb.Devices, device)
+	}
+
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if !strings.Contains(entryName, ":") {
+			continue
+		}
+
+		deviceID := usbInterfaceDevice(entryName)
+		for i := range usb.Devices {
+			if usb.Devices[i].ID != deviceID {
+				continue
+			}
+
+			err := usbAddInterfaceInfo(filepath.Join(sysBusUsbDevices, entryName), uname, &usb.Devices[i])
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to add interface information for \"%s\"", entryName)
+			}
+
+			break
+		}
+	}
+
+	usb.Total = uint64(len(usb.Devices))
+
+	return &usb, nil
+}
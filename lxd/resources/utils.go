@@ -24,6 +24,20 @@ func readUint(path string) (uint64, error) {
 	return value, nil
 }
 
+func readHex(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 16, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
 func readInt(path string) (int64, error) {
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -184,6 +184,17 @@ func PreconditionFailed(err error) Response {
 	return &errorResponse{http.StatusPreconditionFailed, err.Error()}
 }
 
+// TooManyRequests returns a too many requests response (429) with the given
+// error.
+func TooManyRequests(err error) Response {
+	message := "too many requests"
+	if err != nil {
+		message = err.Error()
+	}
+
+	return &errorResponse{http.StatusTooManyRequests, message}
+}
+
 // Unavailable return an unavailable response (503) with the given error.
 func Unavailable(err error) Response {
 	message := "unavailable"
@@ -394,3 +405,21 @@ func (r *forwardedResponse) Render(w http.ResponseWriter) error {
 func (r *forwardedResponse) String() string {
 	return fmt.Sprintf("request to %s", r.request.URL)
 }
+
+type manualResponse struct {
+	hook func(w http.ResponseWriter) error
+}
+
+// ManualResponse creates a new manual response responder, leaving the rendering of the response
+// to the provided hook.
+func ManualResponse(hook func(w http.ResponseWriter) error) Response {
+	return &manualResponse{hook: hook}
+}
+
+func (r *manualResponse) Render(w http.ResponseWriter) error {
+	return r.hook(w)
+}
+
+func (r *manualResponse) String() string {
+	return "unknown"
+}
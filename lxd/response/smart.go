@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 )
 
 // SmartError returns the right error message based on err.
@@ -36,6 +37,8 @@ func SmartError(err error) Response {
 		}
 
 		return Conflict(nil)
+	case query.ErrInvalidFilter:
+		return BadRequest(err)
 	default:
 		return InternalError(err)
 	}
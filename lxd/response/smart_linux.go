@@ -8,6 +8,7 @@ import (
 
 	"github.com/canonical/go-dqlite/driver"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
 )
@@ -37,6 +38,8 @@ func SmartError(err error) Response {
 		}
 
 		return Conflict(nil)
+	case query.ErrInvalidFilter:
+		return BadRequest(err)
 	case driver.ErrNoAvailableLeader:
 		return Unavailable(err)
 	default:
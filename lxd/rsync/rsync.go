@@ -18,8 +18,9 @@ import (
 	"github.com/lxc/lxd/shared/logger"
 )
 
-// LocalCopy copies a directory using rsync (with the --devices option).
-func LocalCopy(source string, dest string, bwlimit string, xattrs bool) (string, error) {
+// LocalCopy copies a directory using rsync (with the --devices option). Paths matching the excludes
+// patterns are neither copied nor deleted from the destination.
+func LocalCopy(source string, dest string, bwlimit string, xattrs bool, excludes ...string) (string, error) {
 	err := os.MkdirAll(dest, 0755)
 	if err != nil {
 		return "", err
@@ -52,6 +53,10 @@ func LocalCopy(source string, dest string, bwlimit string, xattrs bool) (string,
 		args = append(args, "--bwlimit", bwlimit)
 	}
 
+	for _, exclude := range excludes {
+		args = append(args, "--exclude", exclude)
+	}
+
 	args = append(args,
 		rsyncVerbosity,
 		shared.AddSlash(source),
@@ -0,0 +1,171 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the endpoint objects are uploaded to when none is given.
+const DefaultEndpoint = "https://s3.amazonaws.com"
+
+// DefaultRegion is the region requests are signed for when none is given.
+const DefaultRegion = "us-east-1"
+
+// Object identifies an S3 object along with the credentials to access it.
+type Object struct {
+	Endpoint  string
+	Bucket    string
+	Key       string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// Upload writes the content of a file to an S3 object, using a path-style URL for it to work
+// with S3 compatible servers too.
+func Upload(client *http.Client, object Object, path string) error {
+	if object.Bucket == "" || object.Key == "" {
+		return fmt.Errorf("Bucket and key are required")
+	}
+
+	endpoint := object.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	region := object.Region
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("Invalid endpoint %q: %v", endpoint, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Invalid endpoint %q, must be an HTTP or HTTPS URL", endpoint)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The payload is part of the signature, so hash it before sending it.
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	uri := strings.TrimSuffix(u.Path, "/") + "/" + escape(object.Bucket, false) + "/" + escape(object.Key, true)
+
+	req, err := http.NewRequest("PUT", u.Scheme+"://"+u.Host+uri, ioutil.NopCloser(f))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+	now := time.Now().UTC()
+
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+
+	for name, value := range headers {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	req.Header.Set("Authorization", sign("PUT", uri, headers, payloadHash, region, object.AccessKey, object.SecretKey, now))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Failed to upload to S3: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// sign returns the Authorization header of a request signed with AWS Signature Version 4, the
+// given headers with lowercase names being the signed ones.
+func sign(method string, uri string, headers map[string]string, payloadHash string, region string, accessKey string, secretKey string, now time.Time) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + strings.TrimSpace(headers[name]) + "\n"
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{method, uri, "", canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	date := now.UTC().Format("20060102")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.UTC().Format("20060102T150405Z"), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escape URI-encodes a path segment the way AWS expects it, leaving slashes alone if asked to.
+func escape(s string, keepSlashes bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlashes:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
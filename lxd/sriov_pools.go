@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/device"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var sriovPoolsCmd = APIEndpoint{
+	Path: "sriov-pools",
+
+	Get:  APIEndpointAction{Handler: sriovPoolsGet, AccessHandler: AllowAuthenticated},
+	Post: APIEndpointAction{Handler: sriovPoolsPost},
+}
+
+var sriovPoolCmd = APIEndpoint{
+	Path: "sriov-pools/{name}",
+
+	Delete: APIEndpointAction{Handler: sriovPoolDelete},
+	Get:    APIEndpointAction{Handler: sriovPoolGet, AccessHandler: AllowAuthenticated},
+	Put:    APIEndpointAction{Handler: sriovPoolPut},
+}
+
+// sriovPoolConfigKeys lists the supported SR-IOV pool configuration keys and their validators.
+var sriovPoolConfigKeys = map[string]func(value string) error{
+	"parent": func(value string) error {
+		for _, entry := range strings.Split(value, ",") {
+			err := networkValidName(strings.TrimSpace(entry))
+			if err != nil {
+				return fmt.Errorf("Invalid parent device '%s': %v", entry, err)
+			}
+		}
+
+		return nil
+	},
+	"vf_count":           shared.IsUint32,
+	"vlan":               device.NetworkValidVLAN,
+	"limits.max_tx_rate": shared.IsUint32,
+}
+
+// API endpoints
+func sriovPoolsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	names, err := d.cluster.SRIOVPools()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, name := range names {
+			urls = append(urls, fmt.Sprintf("/%s/sriov-pools/%s", version.APIVersion, name))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	pools := []*api.SRIOVPool{}
+	for _, name := range names {
+		pool, err := doSRIOVPoolGet(d.State(), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return response.SyncResponse(true, pools)
+}
+
+func sriovPoolsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.SRIOVPoolsPost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Other nodes only need to apply the pool which was added to the database.
+	if isClusterNotification(r) {
+		err = sriovPoolApply(d.State(), req.Name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	if strings.Contains(req.Name, "/") {
+		return response.BadRequest(fmt.Errorf("SR-IOV pool names may not contain slashes"))
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = sriovPoolValidateConfig(req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, _, err = d.cluster.SRIOVPoolGet(req.Name)
+	if err == nil {
+		return response.Conflict(fmt.Errorf("The SR-IOV pool already exists"))
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	id, err := d.cluster.SRIOVPoolCreate(req.Name, req.Description, req.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Add(func() { d.cluster.SRIOVPoolDelete(id) })
+
+	err = sriovPoolApply(d.State(), req.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = sriovPoolsNotify(d, func(client lxd.InstanceServer) error {
+		return client.CreateSRIOVPool(req)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/sriov-pools/%s", version.APIVersion, req.Name))
+}
+
+func sriovPoolGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	pool, err := doSRIOVPoolGet(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	etag := []interface{}{pool.Name, pool.Description, pool.Config}
+
+	return response.SyncResponseETag(true, pool, etag)
+}
+
+func sriovPoolPut(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	// Other nodes only need to apply the new configuration from the database.
+	if isClusterNotification(r) {
+		err := sriovPoolApply(d.State(), name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	id, pool, err := d.cluster.SRIOVPoolGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	etag := []interface{}{pool.Name, pool.Description, pool.Config}
+	err = util.EtagCheck(r, etag)
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.SRIOVPoolPut{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Config == nil {
+		req.Config = map[string]string{}
+	}
+
+	err = sriovPoolValidateConfig(req.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = d.cluster.SRIOVPoolUpdate(id, req.Description, req.Config)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Put back the previous configuration, also on the devices as it may have been partly applied.
+	revert.Add(func() {
+		err := d.cluster.SRIOVPoolUpdate(id, pool.Description, pool.Config)
+		if err != nil {
+			return
+		}
+
+		sriovPoolApply(d.State(), name)
+	})
+
+	err = sriovPoolApply(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = sriovPoolsNotify(d, func(client lxd.InstanceServer) error {
+		return client.UpdateSRIOVPool(name, req, "")
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revert.Success()
+	return response.EmptySyncResponse
+}
+
+func sriovPoolDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	id, _, err := d.cluster.SRIOVPoolGet(name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	usedBy, err := sriovPoolUsedBy(d.State(), name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(usedBy) > 0 {
+		return response.BadRequest(fmt.Errorf("The SR-IOV pool is currently in use"))
+	}
+
+	err = d.cluster.SRIOVPoolDelete(id)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func doSRIOVPoolGet(s *state.State, name string) (*api.SRIOVPool, error) {
+	_, pool, err := s.Cluster.SRIOVPoolGet(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.UsedBy, err = sriovPoolUsedBy(s, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// sriovPoolsNotify notifies all other nodes of a change to a SR-IOV pool.
+func sriovPoolsNotify(d *Daemon, hook func(client lxd.InstanceServer) error) error {
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
+	if err != nil {
+		return err
+	}
+
+	return notifier(hook)
+}
+
+func sriovPoolValidateConfig(config map[string]string) error {
+	for k, v := range config {
+		// User keys are free for all
+		if strings.HasPrefix(k, "user.") {
+			continue
+		}
+
+		validator, ok := sriovPoolConfigKeys[k]
+		if !ok {
+			return fmt.Errorf("Invalid SR-IOV pool configuration key: %s", k)
+		}
+
+		if v == "" {
+			continue
+		}
+
+		err := validator(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config["parent"] == "" {
+		return fmt.Errorf("SR-IOV pools require at least one parent device")
+	}
+
+	return nil
+}
+
+// sriovPoolUsedBy returns the URLs of the instances with NICs using the SR-IOV pool.
+func sriovPoolUsedBy(s *state.State, name string) ([]string, error) {
+	usedBy := []string{}
+
+	insts, err := instanceLoadFromAllProjects(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inst := range insts {
+		if len(sriovPoolDevices(inst, name)) == 0 {
+			continue
+		}
+
+		uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, inst.Name())
+		if inst.Project() != "default" {
+			uri += fmt.Sprintf("?project=%s", inst.Project())
+		}
+		usedBy = append(usedBy, uri)
+	}
+
+	return usedBy, nil
+}
+
+// sriovPoolDevices returns the names of the instance's NIC devices using the SR-IOV pool.
+func sriovPoolDevices(inst instance.Instance, name string) []string {
+	devices := []string{}
+
+	for devName, d := range inst.ExpandedDevices() {
+		if d["type"] == "nic" && d["nictype"] == "sriov" && d["pool"] == name {
+			devices = append(devices, devName)
+		}
+	}
+
+	return devices
+}
+
+// sriovPoolApply applies the configuration of a SR-IOV pool to the local parent devices and to the
+// VFs already allocated to running instances.
+func sriovPoolApply(s *state.State, name string) error {
+	_, pool, err := s.Cluster.SRIOVPoolGet(name)
+	if err != nil {
+		return err
+	}
+
+	insts, err := instanceLoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return err
+	}
+
+	// Live reconfiguration of the VFs in use.
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		for _, devName := range sriovPoolDevices(inst, name) {
+			localConfig := inst.LocalConfig()
+			parent := localConfig[fmt.Sprintf("volatile.%s.last_state.vf.parent", devName)]
+			vfID := localConfig[fmt.Sprintf("volatile.%s.last_state.vf.id", devName)]
+			if parent == "" || vfID == "" {
+				continue
+			}
+
+			vlan := inst.ExpandedDevices()[devName]["vlan"]
+			if vlan == "" {
+				vlan = pool.Config["vlan"]
+			}
+
+			if vlan == "" {
+				vlan = "0"
+			}
+
+			_, err := shared.RunCommand("ip", "link", "set", "dev", parent, "vf", vfID, "vlan", vlan)
+			if err != nil {
+				return err
+			}
+
+			maxTxRate := pool.Config["limits.max_tx_rate"]
+			if maxTxRate == "" {
+				maxTxRate = "0"
+			}
+
+			_, err = shared.RunCommand("ip", "link", "set", "dev", parent, "vf", vfID, "max_tx_rate", maxTxRate)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Set the number of VFs of the local parent devices.
+	if pool.Config["vf_count"] == "" {
+		return nil
+	}
+
+	for _, parent := range strings.Split(pool.Config["parent"], ",") {
+		parent = strings.TrimSpace(parent)
+		sriovNumVFs := fmt.Sprintf("/sys/class/net/%s/device/sriov_numvfs", parent)
+		if !shared.PathExists(sriovNumVFs) {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(sriovNumVFs)
+		if err != nil {
+			return err
+		}
+
+		current, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return err
+		}
+
+		if fmt.Sprintf("%d", current) == pool.Config["vf_count"] {
+			continue
+		}
+
+		// Changing the number of VFs of a device destroys all its existing VFs, including those
+		// used by NICs which aren't part of the pool.
+		if current > 0 {
+			users := sriovParentUsers(insts, parent)
+			if len(users) > 0 {
+				return fmt.Errorf("Can't change the number of virtual functions of '%s' while they are in use by: %s", parent, strings.Join(users, ", "))
+			}
+
+			err = ioutil.WriteFile(sriovNumVFs, []byte("0"), 0644)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = ioutil.WriteFile(sriovNumVFs, []byte(pool.Config["vf_count"]), 0644)
+		if err != nil {
+			return fmt.Errorf("Failed to set the number of virtual functions of '%s': %v", parent, err)
+		}
+	}
+
+	return nil
+}
+
+// sriovParentUsers returns the names of the running instances using a virtual function of the parent
+// device, through a SR-IOV pool, a sriov NIC or infiniband device on the parent, or a NIC whose
+// parent is one of its virtual functions.
+func sriovParentUsers(insts []instance.Instance, parent string) []string {
+	users := []string{}
+
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		for devName, dev := range inst.ExpandedDevices() {
+			if dev["type"] != "nic" && dev["type"] != "infiniband" {
+				continue
+			}
+
+			devParent := dev["parent"]
+			if dev["nictype"] == "sriov" {
+				if devParent == "" {
+					devParent = inst.LocalConfig()[fmt.Sprintf("volatile.%s.last_state.vf.parent", devName)]
+				}
+			} else if devParent != "" {
+				devParent = sriovPhysicalFunction(devParent)
+			}
+
+			if devParent == parent {
+				users = append(users, project.Prefix(inst.Project(), inst.Name()))
+				break
+			}
+		}
+	}
+
+	return users
+}
+
+// sriovPhysicalFunction returns the name of the physical function the network interface is a virtual
+// function of, or an empty string if it isn't one.
+func sriovPhysicalFunction(iface string) string {
+	physfn, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/class/net/%s/device/physfn", iface))
+	if err != nil {
+		return ""
+	}
+
+	ents, err := ioutil.ReadDir(filepath.Join(physfn, "net"))
+	if err != nil || len(ents) == 0 {
+		return ""
+	}
+
+	return ents[0].Name()
+}
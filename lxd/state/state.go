@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/lxc/lxd/lxd/bgp"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/dns"
 	"github.com/lxc/lxd/lxd/endpoints"
 	"github.com/lxc/lxd/lxd/events"
 	"github.com/lxc/lxd/lxd/firewall"
@@ -38,11 +40,17 @@ type State struct {
 
 	// Firewall instance
 	Firewall firewall.Firewall
+
+	// DNS server
+	DNS *dns.Server
+
+	// BGP server
+	BGP *bgp.Server
 }
 
 // NewState returns a new State object with the given database and operating
 // system components.
-func NewState(node *db.Node, cluster *db.Cluster, maas *maas.Controller, os *sys.OS, endpoints *endpoints.Endpoints, events *events.Server, devlxdEvents *events.Server, firewall firewall.Firewall, proxy func(req *http.Request) (*url.URL, error)) *State {
+func NewState(node *db.Node, cluster *db.Cluster, maas *maas.Controller, os *sys.OS, endpoints *endpoints.Endpoints, events *events.Server, devlxdEvents *events.Server, firewall firewall.Firewall, dns *dns.Server, bgp *bgp.Server, proxy func(req *http.Request) (*url.URL, error)) *State {
 	return &State{
 		Node:         node,
 		Cluster:      cluster,
@@ -52,6 +60,8 @@ func NewState(node *db.Node, cluster *db.Cluster, maas *maas.Controller, os *sys
 		DevlxdEvents: devlxdEvents,
 		Events:       events,
 		Firewall:     firewall,
+		DNS:          dns,
+		BGP:          bgp,
 		Proxy:        proxy,
 	}
 }
@@ -26,7 +26,8 @@ func NewTestState(t *testing.T) (*State, func()) {
 		osCleanup()
 	}
 
-	state := NewState(node, cluster, nil, os, nil, nil, nil, firewall.New(), nil)
+	fw, _ := firewall.New("xtables")
+	state := NewState(node, cluster, nil, os, nil, nil, nil, fw, nil, nil, nil)
 
 	return state, cleanup
 }
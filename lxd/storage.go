@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
@@ -25,6 +28,7 @@ import (
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 	"github.com/lxc/lxd/shared/ioprogress"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/units"
 	"github.com/lxc/lxd/shared/version"
@@ -741,31 +745,41 @@ func SetupStorageDriver(s *state.State, forceCheck bool) error {
 
 	}
 
-	for _, poolName := range pools {
-		logger.Debugf("Initializing and checking storage pool \"%s\"", poolName)
+	concurrency, err := node.StartupConcurrency(s.Node)
+	if err != nil {
+		return err
+	}
 
-		pool, err := storagePools.GetPoolByName(s, poolName)
-		if err != storageDrivers.ErrUnknownDriver {
-			if err != nil {
-				return err
-			}
+	// Pools are independent of each other, so mount them in parallel,
+	// bounded by the startup concurrency.
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var setupErr error
+	slots := make(chan struct{}, concurrency)
 
-			_, err = pool.Mount()
-			if err != nil {
-				return err
-			}
-		} else {
-			s, err := storagePoolInit(s, poolName)
-			if err != nil {
-				logger.Errorf("Error initializing storage pool \"%s\": %s, correct functionality of the storage pool cannot be guaranteed", pool, err)
-				continue
-			}
+	for _, poolName := range pools {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(poolName string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
 
-			err = s.StoragePoolCheck()
+			err := setupStoragePool(s, poolName)
 			if err != nil {
-				return err
+				errLock.Lock()
+				if setupErr == nil {
+					setupErr = err
+				}
+				errLock.Unlock()
 			}
-		}
+		}(poolName)
+	}
+
+	wg.Wait()
+	if setupErr != nil {
+		return setupErr
 	}
 
 	// Update the storage drivers cache in api_1.0.go.
@@ -773,6 +787,29 @@ func SetupStorageDriver(s *state.State, forceCheck bool) error {
 	return nil
 }
 
+// setupStoragePool initializes and mounts a storage pool on daemon startup.
+func setupStoragePool(s *state.State, poolName string) error {
+	logger.Debugf("Initializing and checking storage pool \"%s\"", poolName)
+
+	pool, err := storagePools.GetPoolByName(s, poolName)
+	if err != storageDrivers.ErrUnknownDriver {
+		if err != nil {
+			return err
+		}
+
+		_, err = pool.Mount()
+		return err
+	}
+
+	st, err := storagePoolInit(s, poolName)
+	if err != nil {
+		logger.Errorf("Error initializing storage pool \"%s\": %s, correct functionality of the storage pool cannot be guaranteed", poolName, err)
+		return nil
+	}
+
+	return st.StoragePoolCheck()
+}
+
 func storagePoolDriversCacheUpdate(s *state.State) {
 	// Get a list of all storage drivers currently in use
 	// on this LXD instance. Only do this when we do not already have done
@@ -839,21 +876,20 @@ func storageVolumeMount(state *state.State, poolName string, volumeName string,
 	pool, err := storagePools.GetPoolByName(state, poolName)
 	if err != storageDrivers.ErrUnknownDriver && err != storageDrivers.ErrNotImplemented {
 		// Mount the storage volume
-		ourMount, err := pool.MountCustomVolume(volumeName, nil)
+		_, err = pool.MountCustomVolume(volumeName, nil)
 		if err != nil {
 			return err
 		}
 
+		// Release the mount on failure, the volume is only unmounted if nothing else uses it.
 		revert := true
-		if ourMount {
-			defer func() {
-				if !revert {
-					return
-				}
+		defer func() {
+			if !revert {
+				return
+			}
 
-				pool.UnmountCustomVolume(volumeName, nil)
-			}()
-		}
+			pool.UnmountCustomVolume(volumeName, nil)
+		}()
 
 		// Custom storage volumes do not currently support projects, so hardcode "default" project.
 		err = storagePoolVolumeAttachPrepare(state, poolName, volumeName, volumeType, c)
@@ -922,6 +958,61 @@ func storageVolumeUmount(state *state.State, poolName string, volumeName string,
 	return nil
 }
 
+// storageMountRefsRestore rebuilds the references to the mounts of the volumes used by the running
+// instances, which are only kept in memory, so that they don't get unmounted from under the
+// instances after LXD restarted. It must run before any device of the instances is touched.
+func storageMountRefsRestore(s *state.State) error {
+	instances, err := instanceLoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load instances")
+	}
+
+	for _, inst := range instances {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		pool, err := storagePools.GetPoolByInstance(s, inst)
+		if err != storageDrivers.ErrUnknownDriver && err != storageDrivers.ErrNotImplemented {
+			if err == nil {
+				err = pool.HoldInstanceMount(inst, nil)
+			}
+
+			if err != nil {
+				logger.Warn("Failed to restore instance volume mount reference", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			}
+		}
+
+		// Custom volumes are only attached to containers, by storageVolumeMount.
+		if inst.Type() != instancetype.Container {
+			continue
+		}
+
+		for devName, dev := range inst.ExpandedDevices() {
+			if dev["type"] != "disk" || dev["pool"] == "" || dev["path"] == "/" {
+				continue
+			}
+
+			volumeName := strings.TrimPrefix(filepath.Clean(dev["source"]), fmt.Sprintf("%s/", db.StoragePoolVolumeTypeNameCustom))
+
+			pool, err := storagePools.GetPoolByName(s, dev["pool"])
+			if err == storageDrivers.ErrUnknownDriver || err == storageDrivers.ErrNotImplemented {
+				continue
+			}
+
+			if err == nil {
+				_, err = pool.MountCustomVolume(volumeName, nil)
+			}
+
+			if err != nil {
+				logger.Warn("Failed to restore custom volume mount reference", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "device": devName, "err": err})
+			}
+		}
+	}
+
+	return nil
+}
+
 // storageRootFSApplyQuota applies a quota to an instance if it can, if it cannot then it will
 // return false indicating that the quota needs to be stored in volatile to be applied on next boot.
 func storageRootFSApplyQuota(state *state.State, inst instance.Instance, size string) error {
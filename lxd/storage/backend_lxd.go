@@ -5,12 +5,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
@@ -30,6 +33,14 @@ import (
 	"github.com/lxc/lxd/shared/logging"
 )
 
+// quiesceSnapshotTimeout is how long the guest of a virtual machine stays quiesced at most while
+// it's being snapshotted.
+const quiesceSnapshotTimeout = time.Minute
+
+// quiesceBackupTimeout is how long the guest of a virtual machine stays quiesced at most while it's
+// being backed up.
+const quiesceBackupTimeout = 10 * time.Minute
+
 type lxdBackend struct {
 	driver drivers.Driver
 	id     int64
@@ -432,6 +443,11 @@ func (b *lxdBackend) CreateInstance(inst instance.Instance, op *operations.Opera
 		return err
 	}
 
+	err = b.checkInstanceDiskLimit(inst, rootDiskConf["size"])
+	if err != nil {
+		return err
+	}
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -465,6 +481,12 @@ func (b *lxdBackend) CreateInstanceFromBackup(srcBackup backup.Info, srcData io.
 	logger.Debug("CreateInstanceFromBackup started")
 	defer logger.Debug("CreateInstanceFromBackup finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(srcBackup.Project, srcBackup.Name)
 
@@ -569,6 +591,12 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 	logger.Debug("CreateInstanceFromCopy started")
 	defer logger.Debug("CreateInstanceFromCopy finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if inst.Type() != src.Type() {
 		return fmt.Errorf("Instance types must match")
 	}
@@ -591,6 +619,11 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 		return err
 	}
 
+	err = b.checkInstanceDiskLimit(inst, rootDiskConf["size"])
+	if err != nil {
+		return err
+	}
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -714,12 +747,19 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 
 // RefreshInstance synchronises one instance's volume (and optionally snapshots) over another.
 // Snapshots that are not present in the source but are in the destination are removed from the
-// destination if snapshots are included in the synchronisation.
-func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, op *operations.Operation) error {
+// destination if snapshots are included in the synchronisation. The excludes paths of the instance's
+// root filesystem are left out of the synchronisation.
+func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, excludes []string, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "src": src.Name(), "srcSnapshots": len(srcSnapshots)})
 	logger.Debug("RefreshInstance started")
 	defer logger.Debug("RefreshInstance finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if inst.Type() != src.Type() {
 		return fmt.Errorf("Instance types must match")
 	}
@@ -731,6 +771,16 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 
 	contentType := InstanceContentType(inst)
 
+	if len(excludes) > 0 && inst.Type() != instancetype.Container {
+		return fmt.Errorf("Path exclusions are only supported for containers")
+	}
+
+	// Anchor the excluded paths to the root filesystem of the container in its volume.
+	rsyncExcludes := make([]string, 0, len(excludes))
+	for _, exclude := range excludes {
+		rsyncExcludes = append(rsyncExcludes, filepath.Join("/rootfs", exclude))
+	}
+
 	// Get the root disk device config.
 	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
 	if err != nil {
@@ -770,11 +820,15 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 
 	if b.Name() == srcPool.Name() {
 		logger.Debug("RefreshInstance same-pool mode detected")
-		err = b.driver.RefreshVolume(vol, srcVol, srcSnapVols, op)
+		err = b.driver.RefreshVolume(vol, srcVol, srcSnapVols, rsyncExcludes, op)
 		if err != nil {
 			return err
 		}
 	} else {
+		if len(excludes) > 0 {
+			return fmt.Errorf("Path exclusions are only supported when refreshing within the same storage pool")
+		}
+
 		// We are copying volumes between storage pools so use migration system as it will
 		// be able to negotiate a common transfer method between pool types.
 		logger.Debug("RefreshInstance cross-pool mode detected")
@@ -853,6 +907,23 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 	return nil
 }
 
+// optimizedImages returns whether unpacked image volumes are kept on the pool. When the image store
+// is encrypted, they only are on pools encrypting their data, so that image contents aren't stored
+// in clear.
+func (b *lxdBackend) optimizedImages() bool {
+	if !b.driver.Info().OptimizedImages {
+		return false
+	}
+
+	encrypted, err := cluster.ConfigGetBool(b.state.Cluster, "images.encryption")
+	if err != nil {
+		b.logger.Warn("Failed to load the image encryption configuration", log.Ctx{"err": err})
+		return false
+	}
+
+	return !encrypted || b.driver.Encrypted()
+}
+
 // imageFiller returns a function that can be used as a filler function with CreateVolume().
 // The function returned will unpack the specified image archive into the specified mount path
 // provided, and for VM images, a raw root block path is required to unpack the qcow2 image into.
@@ -867,8 +938,13 @@ func (b *lxdBackend) imageFiller(fingerprint string, op *operations.Operation) f
 					op.UpdateMetadata(metadata)
 				}}
 		}
+		key, err := ImageEncryptionKey(b.state)
+		if err != nil {
+			return err
+		}
+
 		imageFile := shared.VarPath("images", fingerprint)
-		return ImageUnpack(imageFile, mountPath, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, tracker)
+		return ImageUnpack(imageFile, key, mountPath, rootBlockPath, b.driver.Info().BlockBacking, b.state.OS.RunningInUserNS, tracker)
 	}
 }
 
@@ -899,38 +975,122 @@ func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint
 		return err
 	}
 
+	err = b.checkInstanceDiskLimit(inst, rootDiskConf["size"])
+	if err != nil {
+		return err
+	}
+
 	// Get the volume name on storage.
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
 	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
 
+	err = b.createVolumeFromImage(vol, fingerprint, op)
+	if err != nil {
+		return err
+	}
+
+	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
+	if err != nil {
+		return err
+	}
+
+	err = inst.DeferTemplateApply("create")
+	if err != nil {
+		return err
+	}
+
+	revert = false
+	return nil
+}
+
+// createVolumeFromImage creates the supplied volume on storage populated with the image requested.
+func (b *lxdBackend) createVolumeFromImage(vol drivers.Volume, fingerprint string, op *operations.Operation) error {
 	// If the driver doesn't support optimized image volumes then create a new empty volume and
 	// populate it with the contents of the image archive.
-	if !b.driver.Info().OptimizedImages {
+	if !b.optimizedImages() {
 		volFiller := drivers.VolumeFiller{
 			Fingerprint: fingerprint,
 			Fill:        b.imageFiller(fingerprint, op),
 		}
 
-		err = b.driver.CreateVolume(vol, &volFiller, op)
-		if err != nil {
-			return err
-		}
-	} else {
-		// If the driver does support optimized images then ensure the optimized image
-		// volume has been created for the archive's fingerprint and then proceed to create
-		// a new volume by copying the optimized image volume.
-		err := b.EnsureImage(fingerprint, op)
-		if err != nil {
-			return err
-		}
+		return b.driver.CreateVolume(vol, &volFiller, op)
+	}
 
-		// No config for an image volume so set to nil.
-		imgVol := b.newVolume(drivers.VolumeTypeImage, contentType, fingerprint, nil)
-		err = b.driver.CreateVolumeFromCopy(vol, imgVol, false, op)
-		if err != nil {
-			return err
-		}
+	// If the driver does support optimized images then ensure the optimized image
+	// volume has been created for the archive's fingerprint and then proceed to create
+	// a new volume by copying the optimized image volume.
+	err := b.EnsureImage(fingerprint, op)
+	if err != nil {
+		return err
+	}
+
+	// No config for an image volume so set to nil.
+	imgVol := b.newVolume(drivers.VolumeTypeImage, vol.ContentType(), fingerprint, nil)
+	return b.driver.CreateVolumeFromCopy(vol, imgVol, false, op)
+}
+
+// ResetInstance replaces an existing instance's root volume with a fresh copy of the image requested.
+// The volume's database record (and so the instance's config) is left untouched.
+func (b *lxdBackend) ResetInstance(inst instance.Instance, fingerprint string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "fingerprint": fingerprint})
+	logger.Debug("ResetInstance started")
+	defer logger.Debug("ResetInstance finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance must not be a snapshot")
+	}
+
+	// Target instance must not be running.
+	if inst.IsRunning() {
+		return fmt.Errorf("Instance must not be running to reset")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	// Snapshots depend on the current volume on most drivers so it can't be replaced.
+	snapshots, err := b.state.Cluster.ContainerGetSnapshots(inst.Project(), inst.Name())
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		return fmt.Errorf("Cannot reset an instance volume that has snapshots to its image")
+	}
+
+	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	volStorageName := project.Prefix(inst.Project(), inst.Name())
+	vol := b.newVolume(volType, InstanceContentType(inst), volStorageName, rootDiskConf)
+
+	// Keep the current volume aside until the new one has been created so it can be put back
+	// on failure. Instance names can't contain dots so the temporary name can't be in use.
+	oldVolStorageName := fmt.Sprintf("%s.reset", volStorageName)
+	oldVol := b.newVolume(volType, InstanceContentType(inst), oldVolStorageName, rootDiskConf)
+
+	logger.Debug("Moving instance volume aside", log.Ctx{"volName": volStorageName, "tmpName": oldVolStorageName})
+	err = b.driver.RenameVolume(vol, oldVolStorageName, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.driver.DeleteVolume(vol, op)
+		b.driver.RenameVolume(oldVol, volStorageName, op)
+	})
+
+	err = b.createVolumeFromImage(vol, fingerprint, op)
+	if err != nil {
+		return err
 	}
 
 	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
@@ -943,7 +1103,14 @@ func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint
 		return err
 	}
 
-	revert = false
+	revert.Success()
+
+	logger.Debug("Deleting old instance volume", log.Ctx{"volName": oldVolStorageName})
+	err = b.driver.DeleteVolume(oldVol, op)
+	if err != nil {
+		logger.Error("Failed to delete old instance volume after reset", log.Ctx{"volName": oldVolStorageName, "err": err})
+	}
+
 	return nil
 }
 
@@ -967,6 +1134,13 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 		return err
 	}
 
+	if !args.Refresh {
+		err = b.checkInstanceDiskLimit(inst, rootDiskConf["size"])
+		if err != nil {
+			return err
+		}
+	}
+
 	// Override args.Name and args.Config to ensure volume is created based on instance.
 	args.Config = rootDiskConf
 	args.Name = inst.Name()
@@ -1169,6 +1343,88 @@ func (b *lxdBackend) RenameInstance(inst instance.Instance, newName string, op *
 	return nil
 }
 
+// MoveInstanceProject renames the volumes of an instance and of its snapshots on the storage device
+// after the instance got moved to another project in the database, from their names in the old
+// project to the ones in the instance's current project.
+func (b *lxdBackend) MoveInstanceProject(inst instance.Instance, oldProject string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "oldProject": oldProject})
+	logger.Debug("MoveInstanceProject started")
+	defer logger.Debug("MoveInstanceProject finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance cannot be a snapshot")
+	}
+
+	// Check we can convert the instance to the volume type needed.
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Get any snapshots the instance has in the format <instance name>/<snapshot name>.
+	snapshots, err := b.state.Cluster.ContainerGetSnapshots(inst.Project(), inst.Name())
+	if err != nil {
+		return err
+	}
+
+	// Rename the volume and its snapshots on the storage device.
+	oldVolStorageName := project.Prefix(oldProject, inst.Name())
+	volStorageName := project.Prefix(inst.Project(), inst.Name())
+	contentType := InstanceContentType(inst)
+
+	// There's no need to pass config as it's not needed when renaming a volume.
+	oldVol := b.newVolume(volType, contentType, oldVolStorageName, nil)
+
+	err = b.driver.RenameVolume(oldVol, volStorageName, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		// There's no need to pass config as it's not needed when renaming a volume.
+		vol := b.newVolume(volType, contentType, volStorageName, nil)
+		b.driver.RenameVolume(vol, oldVolStorageName, op)
+	})
+
+	// Remove old instance symlink and create new one.
+	err = b.removeInstanceSymlink(inst.Type(), oldProject, inst.Name())
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.ensureInstanceSymlink(inst.Type(), oldProject, inst.Name(), drivers.GetVolumeMountPath(b.name, volType, oldVolStorageName))
+	})
+
+	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), drivers.GetVolumeMountPath(b.name, volType, volStorageName))
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.removeInstanceSymlink(inst.Type(), inst.Project(), inst.Name())
+	})
+
+	// Remove old instance snapshot symlink and create a new one if needed.
+	err = b.removeInstanceSnapshotSymlinkIfUnused(inst.Type(), oldProject, inst.Name())
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		err = b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
 // DeleteInstance removes the instance's root volume (all snapshots need to be removed first).
 func (b *lxdBackend) DeleteInstance(inst instance.Instance, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
@@ -1208,12 +1464,15 @@ func (b *lxdBackend) DeleteInstance(inst instance.Instance, op *operations.Opera
 	// There's no need to pass config as it's not needed when deleting a volume.
 	vol := b.newVolume(volType, contentType, volStorageName, nil)
 
-	// Delete the volume from the storage device. Must come after snapshots are removed.
-	// Must come before DB StoragePoolVolumeDelete so that the volume ID is still available.
-	logger.Debug("Deleting instance volume", log.Ctx{"volName": volStorageName})
-	err = b.driver.DeleteVolume(vol, op)
-	if err != nil {
-		return err
+	// Delete the volume from the storage device, unless it was moved to the trash. Must come
+	// after snapshots are removed. Must come before DB StoragePoolVolumeDelete so that the
+	// volume ID is still available.
+	if b.driver.HasVolume(vol) {
+		logger.Debug("Deleting instance volume", log.Ctx{"volName": volStorageName})
+		err = b.driver.DeleteVolume(vol, op)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Remove symlinks.
@@ -1385,6 +1644,12 @@ func (b *lxdBackend) BackupInstance(inst instance.Instance, targetPath string, o
 	logger.Debug("BackupInstance started")
 	defer logger.Debug("BackupInstance finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
 		return err
@@ -1407,6 +1672,12 @@ func (b *lxdBackend) BackupInstance(inst instance.Instance, targetPath string, o
 		return err
 	}
 
+	thaw, err := b.quiesceInstance(inst, quiesceBackupTimeout)
+	if err != nil {
+		return err
+	}
+	defer thaw()
+
 	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
 	err = b.driver.BackupVolume(vol, targetPath, optimized, snapshots, op)
 	if err != nil {
@@ -1454,6 +1725,11 @@ func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, op *o
 		return err
 	}
 
+	err = b.checkInstanceDiskLimit(inst, size)
+	if err != nil {
+		return err
+	}
+
 	contentVolume := InstanceContentType(inst)
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -1464,58 +1740,87 @@ func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, op *o
 	return b.driver.SetVolumeQuota(vol, size, op)
 }
 
-// MountInstance mounts the instance's root volume.
+// MountInstance mounts the instance's root volume for an operation, returning whether it got mounted
+// by this call. Only then must the caller unmount it with UnmountInstance once done with it, which
+// leaves the volume of a running instance mounted.
 func (b *lxdBackend) MountInstance(inst instance.Instance, op *operations.Operation) (bool, error) {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
 	logger.Debug("MountInstance started")
 	defer logger.Debug("MountInstance finished")
 
-	// Check we can convert the instance to the volume type needed.
-	volType, err := InstanceTypeToVolumeType(inst.Type())
+	vol, err := b.instanceRootVolume(inst)
 	if err != nil {
 		return false, err
 	}
 
-	// Get the root disk device config.
-	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
+	return vol.Mount(op)
+}
+
+// HoldInstanceMount mounts the instance's root volume if needed and keeps it mounted while the
+// instance runs, whatever operations mount and unmount it in the meantime, until ReleaseInstanceMount
+// is called. This is also used to restore the mount references, which are only kept in memory, once
+// LXD restarted while the instance was running.
+func (b *lxdBackend) HoldInstanceMount(inst instance.Instance, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+	logger.Debug("HoldInstanceMount started")
+	defer logger.Debug("HoldInstanceMount finished")
+
+	vol, err := b.instanceRootVolume(inst)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	contentType := InstanceContentType(inst)
-	volStorageName := project.Prefix(inst.Project(), inst.Name())
+	return vol.MountAndHold(op)
+}
 
-	// Get the volume.
-	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
+// ReleaseInstanceMount releases the mount held by HoldInstanceMount once the instance stopped, and
+// unmounts the instance's root volume unless an operation is still using it.
+func (b *lxdBackend) ReleaseInstanceMount(inst instance.Instance, op *operations.Operation) (bool, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+	logger.Debug("ReleaseInstanceMount started")
+	defer logger.Debug("ReleaseInstanceMount finished")
+
+	vol, err := b.instanceRootVolume(inst)
+	if err != nil {
+		return false, err
+	}
 
-	return b.driver.MountVolume(vol, op)
+	return vol.ReleaseAndUnmount(op)
 }
 
-// UnmountInstance unmounts the instance's root volume.
+// UnmountInstance unmounts the instance's root volume mounted by MountInstance, unless the instance
+// is running or another operation is still using it.
 func (b *lxdBackend) UnmountInstance(inst instance.Instance, op *operations.Operation) (bool, error) {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
 	logger.Debug("UnmountInstance started")
 	defer logger.Debug("UnmountInstance finished")
 
+	vol, err := b.instanceRootVolume(inst)
+	if err != nil {
+		return false, err
+	}
+
+	return vol.Unmount(op)
+}
+
+// instanceRootVolume returns the root volume of the instance.
+func (b *lxdBackend) instanceRootVolume(inst instance.Instance) (drivers.Volume, error) {
 	// Check we can convert the instance to the volume type needed.
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
-		return false, err
+		return drivers.Volume{}, err
 	}
 
 	// Get the root disk device config.
 	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
 	if err != nil {
-		return false, err
+		return drivers.Volume{}, err
 	}
 
 	contentType := InstanceContentType(inst)
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
-	// Get the volume.
-	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
-
-	return b.driver.UnmountVolume(vol, op)
+	return b.newVolume(volType, contentType, volStorageName, rootDiskConf), nil
 }
 
 // GetInstanceDisk returns the location of the disk.
@@ -1572,7 +1877,8 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 	}
 
 	// Some driver backing stores require that running instances be frozen during snapshot.
-	if b.driver.Info().RunningSnapshotFreeze && src.IsRunning() {
+	// Instances already frozen by the caller, such as for snapshot groups, are left for it to thaw.
+	if b.driver.Info().RunningSnapshotFreeze && src.IsRunning() && !src.IsFrozen() {
 		err = src.Freeze()
 		if err != nil {
 			return err
@@ -1586,6 +1892,17 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 		return fmt.Errorf("Volume name must be a snapshot")
 	}
 
+	// Snapshots of thick pools take the size of the instance volume.
+	srcRootDiskConf, err := b.instanceRootVolumeConfig(src)
+	if err != nil {
+		return err
+	}
+
+	err = b.checkInstanceDiskLimit(inst, srcRootDiskConf["size"])
+	if err != nil {
+		return err
+	}
+
 	contentType := InstanceContentType(inst)
 	volStorageName := project.Prefix(inst.Project(), inst.Name())
 
@@ -1594,6 +1911,25 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 	// snapshots.
 	vol := b.newVolume(volType, contentType, volStorageName, nil)
 
+	// Freeze the filesystem of running containers if requested, so that the snapshot is consistent.
+	// Snapshots of other backing stores are atomic already.
+	if b.driver.Info().BlockBacking && src.Type() == instancetype.Container && src.IsRunning() && src.ExpandedConfig()["snapshots.consistency"] == "freeze" {
+		srcMountPath := b.newVolume(volType, contentType, project.Prefix(src.Project(), src.Name()), nil).MountPath()
+		if shared.IsMountPoint(srcMountPath) {
+			_, err = shared.RunCommand("fsfreeze", "--freeze", srcMountPath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to freeze filesystem of instance %q", src.Name())
+			}
+			defer shared.TryRunCommand("fsfreeze", "--unfreeze", srcMountPath)
+		}
+	}
+
+	thaw, err := b.quiesceInstance(src, quiesceSnapshotTimeout)
+	if err != nil {
+		return err
+	}
+	defer thaw()
+
 	err = b.driver.CreateVolumeSnapshot(vol, op)
 	if err != nil {
 		return err
@@ -1607,6 +1943,47 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 	return nil
 }
 
+// acquireOperationSlot waits for the number of heavy operations running on the pool to be below its
+// operations.concurrency before returning, returning a function to call once the operation is done.
+func (b *lxdBackend) acquireOperationSlot() (func(), error) {
+	limit := 0
+	if b.db.Config["operations.concurrency"] != "" {
+		var err error
+		limit, err = strconv.Atoi(b.db.Config["operations.concurrency"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid operations.concurrency")
+		}
+	}
+
+	return locking.AcquireSlot(b.name, limit), nil
+}
+
+// quiesceInstance asks the guest of a running virtual machine to quiesce its applications and to
+// freeze its filesystems when its snapshots.consistency is "freeze", returning a function thawing
+// them. The guest thaws them by itself after the timeout in case LXD doesn't.
+func (b *lxdBackend) quiesceInstance(inst instance.Instance, timeout time.Duration) (func(), error) {
+	if inst.Type() != instancetype.VM || !inst.IsRunning() || inst.ExpandedConfig()["snapshots.consistency"] != "freeze" {
+		return func() {}, nil
+	}
+
+	q, ok := inst.(instance.Quiescer)
+	if !ok {
+		return func() {}, nil
+	}
+
+	err := q.Quiesce(timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to quiesce instance %q", inst.Name())
+	}
+
+	return func() {
+		err := q.Thaw()
+		if err != nil {
+			b.logger.Error("Failed to thaw instance", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+		}
+	}, nil
+}
+
 // RenameInstanceSnapshot renames an instance snapshot.
 func (b *lxdBackend) RenameInstanceSnapshot(inst instance.Instance, newName string, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "newName": newName})
@@ -1685,6 +2062,12 @@ func (b *lxdBackend) DeleteInstanceSnapshot(inst instance.Instance, op *operatio
 	logger.Debug("DeleteInstanceSnapshot started")
 	defer logger.Debug("DeleteInstanceSnapshot finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	parentName, snapName, isSnap := shared.InstanceGetParentAndSnapshotName(inst.Name())
 	if !inst.IsSnapshot() || !isSnap {
 		return fmt.Errorf("Instance must be a snapshot")
@@ -1716,9 +2099,12 @@ func (b *lxdBackend) DeleteInstanceSnapshot(inst instance.Instance, op *operatio
 	// snapshot.
 	vol := b.newVolume(volType, contentType, snapVolName, nil)
 
-	err = b.driver.DeleteVolumeSnapshot(vol, op)
-	if err != nil {
-		return err
+	// The snapshot was moved along with its instance's volume when put in the trash.
+	if b.driver.HasVolume(vol) {
+		err = b.driver.DeleteVolumeSnapshot(vol, op)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Delete symlink if needed.
@@ -1887,6 +2273,82 @@ func (b *lxdBackend) UnmountInstanceSnapshot(inst instance.Instance, op *operati
 	return b.driver.UnmountVolumeSnapshot(vol, op)
 }
 
+// DiffInstanceSnapshot returns the files of the root filesystem changed between an instance
+// snapshot and target, a more recent snapshot of the same instance or the instance itself.
+func (b *lxdBackend) DiffInstanceSnapshot(inst instance.Instance, target instance.Instance, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "target": target.Name()})
+	logger.Debug("DiffInstanceSnapshot started")
+	defer logger.Debug("DiffInstanceSnapshot finished")
+
+	if !inst.IsSnapshot() {
+		return nil, fmt.Errorf("Instance must be a snapshot")
+	}
+
+	if inst.Type() != instancetype.Container {
+		return nil, fmt.Errorf("Snapshot diffs are only supported for containers")
+	}
+
+	// Check we can convert the instance to the volume type needed.
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the root disk device config.
+	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
+	if err != nil {
+		return nil, err
+	}
+
+	parentName, _, _ := shared.InstanceGetParentAndSnapshotName(inst.Name())
+	vol := b.newVolume(volType, drivers.ContentTypeFS, project.Prefix(inst.Project(), inst.Name()), rootDiskConf)
+	parentVol := b.newVolume(volType, drivers.ContentTypeFS, project.Prefix(inst.Project(), parentName), rootDiskConf)
+	targetVol := b.newVolume(volType, drivers.ContentTypeFS, project.Prefix(target.Project(), target.Name()), rootDiskConf)
+
+	// Mount the instance volume (some drivers need it to compare its snapshots) and both sides.
+	ourMount, err := b.driver.MountVolume(parentVol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	if ourMount {
+		defer b.driver.UnmountVolume(parentVol, op)
+	}
+
+	for _, snapVol := range []drivers.Volume{vol, targetVol} {
+		if !snapVol.IsSnapshot() {
+			continue
+		}
+
+		ourMount, err := b.driver.MountVolumeSnapshot(snapVol, op)
+		if err != nil {
+			return nil, err
+		}
+
+		if ourMount {
+			defer b.driver.UnmountVolumeSnapshot(snapVol, op)
+		}
+	}
+
+	entries, err := b.driver.DiffVolumeSnapshot(vol, targetVol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only report the changes to the root filesystem, with absolute paths.
+	result := []api.InstanceSnapshotDiff{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Path, "rootfs/") {
+			continue
+		}
+
+		entry.Path = strings.TrimPrefix(entry.Path, "rootfs")
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
 // poolBlockFilesystem returns the filesystem used for new block device filesystems.
 func (b *lxdBackend) poolBlockFilesystem() string {
 	if b.db.Config["volume.block.filesystem"] != "" {
@@ -1903,7 +2365,7 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation) e
 	logger.Debug("EnsureImage started")
 	defer logger.Debug("EnsureImage finished")
 
-	if !b.driver.Info().OptimizedImages {
+	if !b.optimizedImages() {
 		return nil // Nothing to do for drivers that don't support optimized images volumes.
 	}
 
@@ -1955,14 +2417,30 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation) e
 		return nil
 	}
 
-	volFiller := drivers.VolumeFiller{
-		Fingerprint: fingerprint,
-		Fill:        b.imageFiller(fingerprint, op),
+	// Image volumes are named after their fingerprint, so copy the volume from another pool using
+	// the same driver which has it already rather than unpacking the image again.
+	copied, err := b.ensureImageFromPool(imgVol, op)
+	if err != nil {
+		logger.Warn("Failed to copy image volume from another pool, unpacking it instead", log.Ctx{"err": err})
+
+		if b.driver.HasVolume(imgVol) {
+			err = b.driver.DeleteVolume(imgVol, op)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err = b.driver.CreateVolume(imgVol, &volFiller, op)
-	if err != nil {
-		return err
+	if !copied {
+		volFiller := drivers.VolumeFiller{
+			Fingerprint: fingerprint,
+			Fill:        b.imageFiller(fingerprint, op),
+		}
+
+		err = b.driver.CreateVolume(imgVol, &volFiller, op)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = VolumeDBCreate(b.state, "default", b.name, fingerprint, "", db.StoragePoolVolumeTypeNameImage, false, nil)
@@ -1973,6 +2451,113 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation) e
 	return nil
 }
 
+// ensureImageFromPool creates the image volume by copying it from another pool of this node using
+// the same driver and filesystem, which already has it. Returns whether the volume got copied.
+// This only saves downloading and unpacking the image again, each pool still storing a full copy
+// of the volume. The volume isn't cloned from the other pool, even where the driver could do it
+// (such as ZFS datasets of the same zpool), as that pool couldn't be deleted anymore.
+func (b *lxdBackend) ensureImageFromPool(imgVol drivers.Volume, op *operations.Operation) (bool, error) {
+	fingerprint := imgVol.Name()
+
+	poolIDs, err := b.state.Cluster.ImageGetPools(fingerprint)
+	if err != nil {
+		return false, err
+	}
+
+	poolNames, err := b.state.Cluster.ImageGetPoolNamesFromIDs(poolIDs)
+	if err != nil {
+		return false, err
+	}
+
+	var srcPool *lxdBackend
+	for _, poolName := range poolNames {
+		if poolName == b.name {
+			continue
+		}
+
+		pool, err := GetPoolByName(b.state, poolName)
+		if err != nil {
+			continue
+		}
+
+		backend, ok := pool.(*lxdBackend)
+		if !ok || backend.driver.Info().Name != b.driver.Info().Name {
+			continue
+		}
+
+		if imgVol.ContentType() == drivers.ContentTypeFS && backend.poolBlockFilesystem() != b.poolBlockFilesystem() {
+			continue
+		}
+
+		if !backend.driver.HasVolume(backend.newVolume(drivers.VolumeTypeImage, imgVol.ContentType(), fingerprint, nil)) {
+			continue
+		}
+
+		srcPool = backend
+		break
+	}
+
+	if srcPool == nil {
+		return false, nil
+	}
+
+	b.logger.Debug("Copying image volume from another pool", log.Ctx{"fingerprint": fingerprint, "srcPool": srcPool.name})
+
+	// Use in-memory pipe pair to simulate a connection between the sender and receiver.
+	aEnd, bEnd := memorypipe.NewPipePair()
+
+	// Negotiate the migration type to use, the pools using the same driver.
+	offeredTypes := srcPool.MigrationTypes(imgVol.ContentType(), false)
+	offerHeader := migration.TypesToHeader(offeredTypes...)
+	migrationType, err := migration.MatchTypes(offerHeader, migration.MigrationFSType_RSYNC, b.MigrationTypes(imgVol.ContentType(), false))
+	if err != nil {
+		return false, fmt.Errorf("Failed to negotiate copy migration type: %v", err)
+	}
+
+	// Run sender and receiver in separate go routines to prevent deadlocks.
+	aEndErrCh := make(chan error, 1)
+	bEndErrCh := make(chan error, 1)
+	go func() {
+		srcVol := srcPool.newVolume(drivers.VolumeTypeImage, imgVol.ContentType(), fingerprint, nil)
+		err := srcPool.driver.MigrateVolume(srcVol, aEnd, &migration.VolumeSourceArgs{
+			Name:          fingerprint,
+			MigrationType: migrationType,
+			TrackProgress: true, // Do use a progress tracker on sender.
+		}, op)
+
+		aEndErrCh <- err
+	}()
+
+	go func() {
+		err := b.driver.CreateVolumeFromMigration(imgVol, bEnd, migration.VolumeTargetArgs{
+			Name:          fingerprint,
+			MigrationType: migrationType,
+			TrackProgress: false, // Do not use a progress tracker on receiver.
+		}, nil, op)
+
+		bEndErrCh <- err
+	}()
+
+	// Capture errors from the sender and receiver from their result channels.
+	errs := []error{}
+	aEndErr := <-aEndErrCh
+	if aEndErr != nil {
+		aEnd.Close()
+		errs = append(errs, aEndErr)
+	}
+
+	bEndErr := <-bEndErrCh
+	if bEndErr != nil {
+		errs = append(errs, bEndErr)
+	}
+
+	if len(errs) > 0 {
+		return false, fmt.Errorf("Copy image volume from pool %q failed: %v", srcPool.name, errs)
+	}
+
+	return true, nil
+}
+
 // DeleteImage removes an image from the database and underlying storage device if needed.
 func (b *lxdBackend) DeleteImage(fingerprint string, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"fingerprint": fingerprint})
@@ -2066,6 +2651,11 @@ func (b *lxdBackend) CreateCustomVolume(volName, desc string, config map[string]
 		return err
 	}
 
+	err = b.checkProjectDiskLimit("default", db.StoragePoolVolumeTypeCustom, volName, false, vol.Config()["size"])
+	if err != nil {
+		return err
+	}
+
 	// Create database entry for new storage volume.
 	err = VolumeDBCreate(b.state, "default", b.name, volName, desc, db.StoragePoolVolumeTypeNameCustom, false, vol.Config())
 	if err != nil {
@@ -2096,6 +2686,12 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 	logger.Debug("CreateCustomVolumeFromCopy started")
 	defer logger.Debug("CreateCustomVolumeFromCopy finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// Setup the source pool backend instance.
 	var srcPool *lxdBackend
 	if b.name == srcPoolName {
@@ -2136,6 +2732,11 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(volName, desc string, config map
 		desc = srcVolRow.Description
 	}
 
+	err = b.checkProjectDiskLimit("default", db.StoragePoolVolumeTypeCustom, volName, false, config["size"])
+	if err != nil {
+		return err
+	}
+
 	// If we are copying snapshots, retrieve a list of snapshots from source volume.
 	snapshotNames := []string{}
 	if !srcVolOnly {
@@ -2305,6 +2906,11 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, ar
 		return err
 	}
 
+	err = b.checkProjectDiskLimit("default", db.StoragePoolVolumeTypeCustom, args.Name, false, vol.Config()["size"])
+	if err != nil {
+		return err
+	}
+
 	// Create database entry for new storage volume.
 	err = VolumeDBCreate(b.state, "default", b.name, args.Name, args.Description, db.StoragePoolVolumeTypeNameCustom, false, vol.Config())
 	if err != nil {
@@ -2473,6 +3079,14 @@ func (b *lxdBackend) UpdateCustomVolume(volName, newDesc string, newConfig map[s
 			return fmt.Errorf("Custom volume 'block.filesystem' property cannot be changed")
 		}
 
+		_, ok := changedConfig["size"]
+		if ok {
+			err = b.checkProjectDiskLimit("default", db.StoragePoolVolumeTypeCustom, volName, false, newConfig["size"])
+			if err != nil {
+				return err
+			}
+		}
+
 		curVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, curVol.Config)
 		if !userOnly {
 			err = b.driver.UpdateVolume(curVol, changedConfig)
@@ -2582,7 +3196,8 @@ func (b *lxdBackend) GetCustomVolumeUsage(volName string) (int64, error) {
 	return b.driver.GetVolumeUsage(vol)
 }
 
-// MountCustomVolume mounts a custom volume.
+// MountCustomVolume mounts a custom volume. Every successful call must be paired with a call to
+// UnmountCustomVolume, whether or not the volume was mounted by it.
 func (b *lxdBackend) MountCustomVolume(volName string, op *operations.Operation) (bool, error) {
 	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
 	logger.Debug("MountCustomVolume started")
@@ -2595,10 +3210,21 @@ func (b *lxdBackend) MountCustomVolume(volName string, op *operations.Operation)
 
 	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, volume.Config)
 
-	return b.driver.MountVolume(vol, op)
+	unlock := locking.Lock(b.name, string(vol.Type()), vol.Name())
+	defer unlock()
+
+	ourMount, err := b.driver.MountVolume(vol, op)
+	if err != nil {
+		return false, err
+	}
+
+	vol.MountRefCountIncrement()
+
+	return ourMount, nil
 }
 
-// UnmountCustomVolume unmounts a custom volume.
+// UnmountCustomVolume releases a custom volume mounted with MountCustomVolume, unmounting it once
+// nothing uses it anymore.
 func (b *lxdBackend) UnmountCustomVolume(volName string, op *operations.Operation) (bool, error) {
 	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
 	logger.Debug("UnmountCustomVolume started")
@@ -2611,6 +3237,15 @@ func (b *lxdBackend) UnmountCustomVolume(volName string, op *operations.Operatio
 
 	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, volume.Config)
 
+	unlock := locking.Lock(b.name, string(vol.Type()), vol.Name())
+	defer unlock()
+
+	vol.MountRefCountDecrement()
+	if vol.MountInUse() {
+		logger.Debug("Skipping unmount as the volume is in use")
+		return false, nil
+	}
+
 	return b.driver.UnmountVolume(vol, op)
 }
 
@@ -2650,6 +3285,12 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(volName string, newSnapshotName
 		return err
 	}
 
+	// Snapshots of thick pools take the size of their volume.
+	err = b.checkProjectDiskLimit("default", db.StoragePoolVolumeTypeCustom, fullSnapshotName, true, parentVol.Config["size"])
+	if err != nil {
+		return err
+	}
+
 	// Create database entry for new storage volume snapshot.
 	err = VolumeDBCreate(b.state, "default", b.name, fullSnapshotName, parentVol.Description, db.StoragePoolVolumeTypeNameCustom, true, parentVol.Config)
 	if err != nil {
@@ -2716,6 +3357,12 @@ func (b *lxdBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.O
 	logger.Debug("DeleteCustomVolumeSnapshot started")
 	defer logger.Debug("DeleteCustomVolumeSnapshot finished")
 
+	release, err := b.acquireOperationSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	isSnap := shared.IsSnapshot(volName)
 
 	if !isSnap {
@@ -2728,7 +3375,7 @@ func (b *lxdBackend) DeleteCustomVolumeSnapshot(volName string, op *operations.O
 
 	// Delete the snapshot from the storage device.
 	// Must come before DB StoragePoolVolumeDelete so that the volume ID is still available.
-	err := b.driver.DeleteVolumeSnapshot(vol, op)
+	err = b.driver.DeleteVolumeSnapshot(vol, op)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logging"
+)
+
+// ListUnknownVolumes returns the instance and custom volumes found on the pool which have no database
+// record, so that they can be recovered after the database was lost or corrupted. The instances are
+// returned through the backup.yaml file stored in their volume, keyed by project, its snapshots being
+// limited to the ones still existing on the pool. The custom volumes are returned by name.
+func (b *lxdBackend) ListUnknownVolumes(op *operations.Operation) (map[string][]*backup.InstanceConfig, []string, error) {
+	logger := logging.AddContext(b.logger, nil)
+	logger.Debug("ListUnknownVolumes started")
+	defer logger.Debug("ListUnknownVolumes finished")
+
+	// The pool's directories may be missing, such as when recovering it on a new system.
+	err := b.createStorageStructure(drivers.GetPoolMountPath(b.name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vols, err := b.driver.ListVolumes()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Failed to list the volumes of pool %q", b.name)
+	}
+
+	// Index the snapshots found on the pool by their parent volume.
+	snapshots := map[string][]string{}
+	for _, vol := range vols {
+		if vol.IsSnapshot() {
+			parentName, snapName, _ := shared.InstanceGetParentAndSnapshotName(vol.Name())
+			key := fmt.Sprintf("%s/%s", vol.Type(), parentName)
+			snapshots[key] = append(snapshots[key], snapName)
+		}
+	}
+
+	instances := map[string][]*backup.InstanceConfig{}
+	customVols := []string{}
+
+	for _, vol := range vols {
+		// Snapshots are recovered along with their parent volume.
+		if vol.IsSnapshot() {
+			continue
+		}
+
+		// Volumes in the trash are restored from their trash entry. Custom volumes created
+		// before such names were reserved may also take them, so only the ones of an existing
+		// trash entry are left alone.
+		if trashStorageNameRegexp.MatchString(vol.Name()) {
+			if vol.Type() != drivers.VolumeTypeCustom {
+				continue
+			}
+
+			id, err := strconv.ParseInt(strings.TrimPrefix(vol.Name(), "trash_"), 10, 64)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			_, err = b.trashEntry(id)
+			if err == nil {
+				continue
+			}
+
+			if err != db.ErrNoSuchObject {
+				return nil, nil, err
+			}
+		}
+
+		projectName, volName := recoverVolumeProject(vol)
+
+		known, err := b.volumeKnown(projectName, volName, vol.Type())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if known {
+			continue
+		}
+
+		if vol.Type() == drivers.VolumeTypeCustom {
+			customVols = append(customVols, volName)
+			continue
+		}
+
+		var config *backup.InstanceConfig
+		err = vol.EnsureMountPath()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+			config, err = backup.ParseInstanceConfigYamlFile(filepath.Join(mountPath, "backup.yaml"))
+			return err
+		}, op)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed to read the backup file of volume %q", vol.Name())
+		}
+
+		if config.Container == nil {
+			return nil, nil, fmt.Errorf("The backup file of volume %q has no instance", vol.Name())
+		}
+
+		// Only recover the snapshots which still exist on the pool.
+		onDiskSnapshots := snapshots[fmt.Sprintf("%s/%s", vol.Type(), vol.Name())]
+		existingSnapshots := []*api.InstanceSnapshot{}
+		for _, snap := range config.Snapshots {
+			if shared.StringInSlice(snap.Name, onDiskSnapshots) {
+				existingSnapshots = append(existingSnapshots, snap)
+			} else {
+				logger.Warn("Skipping snapshot missing from pool", log.Ctx{"volume": vol.Name(), "snapshot": snap.Name})
+			}
+		}
+
+		config.Snapshots = existingSnapshots
+		instances[projectName] = append(instances[projectName], config)
+	}
+
+	return instances, customVols, nil
+}
+
+// ImportInstance recreates the on-disk links of an instance whose database records were recreated
+// from the backup file stored in its volume, and restores the config of its volume.
+func (b *lxdBackend) ImportInstance(inst instance.Instance, volConfig map[string]string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+	logger.Debug("ImportInstance started")
+	defer logger.Debug("ImportInstance finished")
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	// Restore the volume config, the volume record having been created with the default config.
+	if volConfig != nil {
+		err = b.state.Cluster.StoragePoolVolumeUpdateByProject(inst.Project(), inst.Name(), volDBType, b.id, "", volConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	volStorageName := project.Prefix(inst.Project(), inst.Name())
+	vol := b.newVolume(volType, InstanceContentType(inst), volStorageName, volConfig)
+
+	err = vol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+
+	err = b.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), vol.MountPath())
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := inst.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	// The snapshots' mount paths are needed for some drivers to list them.
+	for _, snapshot := range snapshots {
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name())
+		snapVol, err := vol.NewSnapshot(snapName)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(snapVol.MountPath()), 0711)
+		if err != nil {
+			return err
+		}
+
+		err = snapVol.EnsureMountPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+}
+
+// ImportCustomVolume recreates the database records of a custom volume found on the pool, and of its
+// snapshots, with the given config. The volume's config isn't stored on the pool, so when recovering
+// it is nil and the pool's defaults are used.
+func (b *lxdBackend) ImportCustomVolume(volName string, volConfig map[string]string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+	logger.Debug("ImportCustomVolume started")
+	defer logger.Debug("ImportCustomVolume finished")
+
+	vols, err := b.driver.ListVolumes()
+	if err != nil {
+		return errors.Wrapf(err, "Failed to list the volumes of pool %q", b.name)
+	}
+
+	snapshots := []drivers.Volume{}
+	for _, vol := range vols {
+		if vol.Type() != drivers.VolumeTypeCustom || !vol.IsSnapshot() {
+			continue
+		}
+
+		parentName, _, _ := shared.InstanceGetParentAndSnapshotName(vol.Name())
+		if parentName == volName {
+			snapshots = append(snapshots, vol)
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = VolumeDBCreate(b.state, "default", b.name, volName, "", db.StoragePoolVolumeTypeNameCustom, false, volConfig)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.id)
+	})
+
+	for _, snapVol := range snapshots {
+		err = VolumeDBCreate(b.state, "default", b.name, snapVol.Name(), "", db.StoragePoolVolumeTypeNameCustom, true, volConfig)
+		if err != nil {
+			return err
+		}
+
+		snapName := snapVol.Name()
+		revert.Add(func() {
+			b.state.Cluster.StoragePoolVolumeDelete("default", snapName, db.StoragePoolVolumeTypeCustom, b.id)
+		})
+
+		err = os.MkdirAll(filepath.Dir(snapVol.MountPath()), 0711)
+		if err != nil {
+			return err
+		}
+
+		err = snapVol.EnsureMountPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// volumeKnown indicates whether a volume has a database record on the pool. Pools without database
+// record don't know any volume.
+func (b *lxdBackend) volumeKnown(projectName string, volName string, volType drivers.VolumeType) (bool, error) {
+	if b.id < 0 {
+		return false, nil
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return false, err
+	}
+
+	_, _, err = b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(projectName, volName, volDBType, b.id)
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// recoverVolumeProject returns the project and name of a volume from its storage name, instance volumes
+// being prefixed with their project when not in the default project.
+func recoverVolumeProject(vol drivers.Volume) (string, string) {
+	if vol.Type() == drivers.VolumeTypeContainer || vol.Type() == drivers.VolumeTypeVM {
+		volParts := strings.SplitN(vol.Name(), "_", 2)
+		if len(volParts) > 1 {
+			return volParts[0], volParts[1]
+		}
+	}
+
+	return "default", vol.Name()
+}
@@ -0,0 +1,372 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logging"
+)
+
+// trashStorageNameRegexp matches the names on storage of the volumes in the trash, which instance
+// names can't take as they can't start with a digit, and which ValidVolumeName rejects for custom
+// volumes.
+var trashStorageNameRegexp = regexp.MustCompile(`^trash_[0-9]+$`)
+
+// trashStorageName returns the name on storage of the volume of a trash entry.
+func trashStorageName(id int64) string {
+	return fmt.Sprintf("trash_%d", id)
+}
+
+// ValidateTrashExpiry validates a trash.expiry, which uses the snapshots.expiry syntax such as "7d".
+func ValidateTrashExpiry(value string) error {
+	_, err := shared.GetSnapshotExpiry(time.Now(), value)
+	return err
+}
+
+// TrashInstance moves the volume of an instance, along with its snapshots, to the trash of the pool
+// until the pool's trash.expiry. The instance must then be deleted, which leaves its volume alone.
+func (b *lxdBackend) TrashInstance(inst instance.Instance, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+	logger.Debug("TrashInstance started")
+	defer logger.Debug("TrashInstance finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance must not be a snapshot")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	_, volume, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject(inst.Project(), inst.Name(), volDBType, b.id)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the backup file is current, as the instance is restored from it.
+	err = b.UpdateInstanceBackupFile(inst, op)
+	if err != nil {
+		return err
+	}
+
+	vol := b.newVolume(volType, InstanceContentType(inst), project.Prefix(inst.Project(), inst.Name()), volume.Config)
+
+	return b.trashVolume(vol, inst.Project(), inst.Name(), volDBType, volume.Description, volume.Config, op)
+}
+
+// TrashCustomVolume moves a custom volume, along with its snapshots, to the trash of the pool until
+// the pool's trash.expiry, and removes its database records.
+func (b *lxdBackend) TrashCustomVolume(volName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"volName": volName})
+	logger.Debug("TrashCustomVolume started")
+	defer logger.Debug("TrashCustomVolume finished")
+
+	_, _, isSnap := shared.InstanceGetParentAndSnapshotName(volName)
+	if isSnap {
+		return fmt.Errorf("Volume name cannot be a snapshot")
+	}
+
+	_, volume, err := b.state.Cluster.StoragePoolNodeVolumeGetTypeByProject("default", volName, db.StoragePoolVolumeTypeCustom, b.id)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := VolumeSnapshotsGet(b.state, b.name, volName, db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volName, volume.Config)
+
+	err = b.trashVolume(vol, "default", volName, db.StoragePoolVolumeTypeCustom, volume.Description, volume.Config, op)
+	if err != nil {
+		return err
+	}
+
+	// The volume is restored from the trash entry, so its records can go.
+	for _, snapshot := range snapshots {
+		err = b.state.Cluster.StoragePoolVolumeDelete("default", snapshot.Name, db.StoragePoolVolumeTypeCustom, b.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.state.Cluster.StoragePoolVolumeDelete("default", volName, db.StoragePoolVolumeTypeCustom, b.id)
+}
+
+// trashVolume records a trash entry for a volume and renames it on storage to its trash name.
+func (b *lxdBackend) trashVolume(vol drivers.Volume, projectName string, name string, volDBType int, description string, config map[string]string, op *operations.Operation) error {
+	now := time.Now()
+	expiresAt, err := shared.GetSnapshotExpiry(now, b.db.Config["trash.expiry"])
+	if err != nil {
+		return err
+	}
+
+	if expiresAt.IsZero() {
+		return fmt.Errorf("Storage pool %q has no trash.expiry set", b.name)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	var id int64
+	err = b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		id, err = tx.StorageTrashCreate(db.StorageTrashEntry{
+			PoolID:      b.id,
+			Project:     projectName,
+			Name:        name,
+			Type:        volDBType,
+			Description: description,
+			Config:      config,
+			DeletedAt:   now,
+			ExpiresAt:   expiresAt,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { b.removeTrashEntry(id) })
+
+	err = b.driver.RenameVolume(vol, trashStorageName(id), op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to move volume %q to the trash", vol.Name())
+	}
+
+	revert.Success()
+	return nil
+}
+
+// GetTrashInstanceConfig returns the backup file of the instance of a trash entry, which its records
+// are recreated from when restoring it.
+func (b *lxdBackend) GetTrashInstanceConfig(id int64, op *operations.Operation) (*backup.InstanceConfig, error) {
+	entry, err := b.trashEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	vol, err := b.trashEntryVolume(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if vol.Type() == drivers.VolumeTypeCustom {
+		return nil, fmt.Errorf("Trash entry %d isn't an instance", id)
+	}
+
+	var config *backup.InstanceConfig
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		config, err = backup.ParseInstanceConfigYamlFile(filepath.Join(mountPath, "backup.yaml"))
+		return err
+	}, op)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the backup file of trash entry %d", id)
+	}
+
+	return config, nil
+}
+
+// RestoreInstanceFromTrash moves the volume of a trash entry back to the instance, whose records
+// were recreated from its backup file, and removes the trash entry.
+func (b *lxdBackend) RestoreInstanceFromTrash(inst instance.Instance, id int64, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "id": id})
+	logger.Debug("RestoreInstanceFromTrash started")
+	defer logger.Debug("RestoreInstanceFromTrash finished")
+
+	entry, err := b.trashEntry(id)
+	if err != nil {
+		return err
+	}
+
+	vol, err := b.trashEntryVolume(entry)
+	if err != nil {
+		return err
+	}
+
+	if vol.Type() == drivers.VolumeTypeCustom {
+		return fmt.Errorf("Trash entry %d isn't an instance", id)
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	volStorageName := project.Prefix(inst.Project(), inst.Name())
+	err = b.driver.RenameVolume(vol, volStorageName, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		restoredVol := b.newVolume(vol.Type(), vol.ContentType(), volStorageName, nil)
+		b.driver.RenameVolume(restoredVol, trashStorageName(id), op)
+	})
+
+	err = b.ImportInstance(inst, entry.Config, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	b.removeTrashEntry(id)
+	return nil
+}
+
+// RestoreCustomVolumeFromTrash moves the custom volume of a trash entry back, recreating its records,
+// and removes the trash entry.
+func (b *lxdBackend) RestoreCustomVolumeFromTrash(id int64, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"id": id})
+	logger.Debug("RestoreCustomVolumeFromTrash started")
+	defer logger.Debug("RestoreCustomVolumeFromTrash finished")
+
+	entry, err := b.trashEntry(id)
+	if err != nil {
+		return err
+	}
+
+	if entry.Type != db.StoragePoolVolumeTypeCustom {
+		return fmt.Errorf("Trash entry %d isn't a custom volume", id)
+	}
+
+	known, err := b.volumeKnown("default", entry.Name, drivers.VolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	if known {
+		return fmt.Errorf("A custom volume named %q already exists", entry.Name)
+	}
+
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, trashStorageName(id), nil)
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = b.driver.RenameVolume(vol, entry.Name, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		restoredVol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, entry.Name, nil)
+		b.driver.RenameVolume(restoredVol, trashStorageName(id), op)
+	})
+
+	err = b.ImportCustomVolume(entry.Name, entry.Config, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	b.removeTrashEntry(id)
+	return nil
+}
+
+// PurgeTrash deletes the volume of a trash entry, along with its snapshots, and removes the entry.
+func (b *lxdBackend) PurgeTrash(id int64, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"id": id})
+	logger.Debug("PurgeTrash started")
+	defer logger.Debug("PurgeTrash finished")
+
+	entry, err := b.trashEntry(id)
+	if err != nil {
+		return err
+	}
+
+	vol, err := b.trashEntryVolume(entry)
+	if err != nil {
+		return err
+	}
+
+	if b.driver.HasVolume(vol) {
+		snapshots, err := b.driver.VolumeSnapshots(vol, op)
+		if err != nil {
+			return err
+		}
+
+		for _, snapName := range snapshots {
+			snapVol, err := vol.NewSnapshot(snapName)
+			if err != nil {
+				return err
+			}
+
+			err = b.driver.DeleteVolumeSnapshot(snapVol, op)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = b.driver.DeleteVolume(vol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.StorageTrashDelete(id)
+	})
+}
+
+// trashEntry returns a trash entry of the pool.
+func (b *lxdBackend) trashEntry(id int64) (*db.StorageTrashEntry, error) {
+	var entry *db.StorageTrashEntry
+	err := b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		entry, err = tx.StorageTrashGet(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.PoolID != b.id {
+		return nil, db.ErrNoSuchObject
+	}
+
+	return entry, nil
+}
+
+// trashEntryVolume returns the volume of a trash entry under its trash name.
+func (b *lxdBackend) trashEntryVolume(entry *db.StorageTrashEntry) (drivers.Volume, error) {
+	volStorageName := trashStorageName(entry.ID)
+
+	switch entry.Type {
+	case db.StoragePoolVolumeTypeContainer:
+		return b.newVolume(drivers.VolumeTypeContainer, drivers.ContentTypeFS, volStorageName, entry.Config), nil
+	case db.StoragePoolVolumeTypeVM:
+		return b.newVolume(drivers.VolumeTypeVM, drivers.ContentTypeBlock, volStorageName, entry.Config), nil
+	case db.StoragePoolVolumeTypeCustom:
+		return b.newVolume(drivers.VolumeTypeCustom, drivers.ContentTypeFS, volStorageName, entry.Config), nil
+	}
+
+	return drivers.Volume{}, fmt.Errorf("Unsupported trash entry volume type %d", entry.Type)
+}
+
+// removeTrashEntry removes a trash entry, leaving its volume alone.
+func (b *lxdBackend) removeTrashEntry(id int64) {
+	err := b.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.StorageTrashDelete(id)
+	})
+	if err != nil {
+		b.logger.Warn("Failed to remove trash entry", log.Ctx{"id": id, "err": err})
+	}
+}
@@ -80,6 +80,10 @@ func (b *mockBackend) CreateInstanceFromImage(inst instance.Instance, fingerprin
 	return nil
 }
 
+func (b *mockBackend) ResetInstance(inst instance.Instance, fingerprint string, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
 	return nil
 }
@@ -88,6 +92,10 @@ func (b *mockBackend) RenameInstance(inst instance.Instance, newName string, op
 	return nil
 }
 
+func (b *mockBackend) MoveInstanceProject(inst instance.Instance, oldProject string, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) DeleteInstance(inst instance.Instance, op *operations.Operation) error {
 	return nil
 }
@@ -104,7 +112,7 @@ func (b *mockBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteC
 	return nil
 }
 
-func (b *mockBackend) RefreshInstance(i instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, op *operations.Operation) error {
+func (b *mockBackend) RefreshInstance(i instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, excludes []string, op *operations.Operation) error {
 	return nil
 }
 
@@ -124,6 +132,14 @@ func (b *mockBackend) MountInstance(inst instance.Instance, op *operations.Opera
 	return true, nil
 }
 
+func (b *mockBackend) HoldInstanceMount(inst instance.Instance, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) ReleaseInstanceMount(inst instance.Instance, op *operations.Operation) (bool, error) {
+	return true, nil
+}
+
 func (b *mockBackend) UnmountInstance(inst instance.Instance, op *operations.Operation) (bool, error) {
 	return true, nil
 }
@@ -160,6 +176,10 @@ func (b *mockBackend) UpdateInstanceSnapshot(inst instance.Instance, newDesc str
 	return nil
 }
 
+func (b *mockBackend) DiffInstanceSnapshot(inst instance.Instance, target instance.Instance, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	return nil, nil
+}
+
 func (b *mockBackend) EnsureImage(fingerprint string, op *operations.Operation) error {
 	return nil
 }
@@ -231,3 +251,39 @@ func (b *mockBackend) UpdateCustomVolumeSnapshot(volName, newDesc string, newCon
 func (b *mockBackend) RestoreCustomVolume(volName string, snapshotName string, op *operations.Operation) error {
 	return nil
 }
+
+func (b *mockBackend) ListUnknownVolumes(op *operations.Operation) (map[string][]*backup.InstanceConfig, []string, error) {
+	return nil, nil, nil
+}
+
+func (b *mockBackend) ImportInstance(inst instance.Instance, volConfig map[string]string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) ImportCustomVolume(volName string, volConfig map[string]string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) TrashInstance(inst instance.Instance, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) TrashCustomVolume(volName string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) GetTrashInstanceConfig(id int64, op *operations.Operation) (*backup.InstanceConfig, error) {
+	return nil, nil
+}
+
+func (b *mockBackend) RestoreInstanceFromTrash(inst instance.Instance, id int64, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) RestoreCustomVolumeFromTrash(id int64, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) PurgeTrash(id int64, op *operations.Operation) error {
+	return nil
+}
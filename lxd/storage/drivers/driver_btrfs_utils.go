@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -374,3 +375,43 @@ func (d *btrfs) receiveSubvolume(path string, targetPath string, conn io.ReadWri
 
 	return nil
 }
+
+// changedFiles returns the files of the subvolume at path with extents more recent than the
+// generation of the subvolume at since, as listed by btrfs find-new.
+func (d *btrfs) changedFiles(path string, since string) (map[string]bool, error) {
+	// Get the generation of the older subvolume (nothing is more recent than the highest one).
+	output, err := shared.RunCommand("btrfs", "subvolume", "find-new", since, strconv.FormatUint(math.MaxUint64, 10))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get the generation of %q", since)
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 || !strings.HasPrefix(strings.TrimSpace(output), "transid marker was") {
+		return nil, fmt.Errorf("Unexpected output of btrfs find-new: %q", output)
+	}
+
+	generation := fields[len(fields)-1]
+
+	output, err = shared.RunCommand("btrfs", "subvolume", "find-new", path, generation)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list the files of %q changed since %s", path, generation)
+	}
+
+	// Lines end with the flags of the extent and the path of the file.
+	changed := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		idx := strings.Index(line, " flags ")
+		if idx < 0 {
+			continue
+		}
+
+		fields := strings.SplitN(line[idx+len(" flags "):], " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		changed[fields[1]] = true
+	}
+
+	return changed, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/units"
 )
@@ -299,8 +300,8 @@ func (d *btrfs) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, v
 }
 
 // RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
-func (d *btrfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
-	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, op)
+func (d *btrfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
+	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, excludes, op)
 }
 
 // DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then
@@ -343,6 +344,11 @@ func (d *btrfs) HasVolume(vol Volume) bool {
 	return d.vfsHasVolume(vol)
 }
 
+// ListVolumes returns the volumes found on the storage pool.
+func (d *btrfs) ListVolumes() ([]Volume, error) {
+	return genericVFSListVolumes(d)
+}
+
 // ValidateVolume validates the supplied volume config.
 func (d *btrfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	return d.validateVolume(vol, nil, removeUnknownKeys)
@@ -720,6 +726,25 @@ func (d *btrfs) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string,
 	return d.vfsVolumeSnapshots(vol, op)
 }
 
+// DiffVolumeSnapshot returns the files changed between a volume snapshot and a more recent one or the volume.
+// The files with modified content are found through their extents being more recent than the snapshot.
+func (d *btrfs) DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	oldRoot := snapVol.MountPath()
+	newRoot := targetVol.MountPath()
+
+	changed, err := d.changedFiles(newRoot, oldRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := diffWalk(oldRoot, newRoot, changed)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffFillSizes(entries, oldRoot, newRoot), nil
+}
+
 // RestoreVolume restores a volume from a snapshot.
 func (d *btrfs) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
 	// Create a backup so we can revert.
@@ -13,6 +13,7 @@ import (
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/rsync"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/units"
@@ -225,7 +226,7 @@ func (d *cephfs) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser,
 }
 
 // RefreshVolume updates an existing volume to match the state of another.
-func (d *cephfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
+func (d *cephfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
 	return ErrNotImplemented
 }
 
@@ -270,6 +271,11 @@ func (d *cephfs) HasVolume(vol Volume) bool {
 	return d.vfsHasVolume(vol)
 }
 
+// ListVolumes returns the volumes found on the storage pool.
+func (d *cephfs) ListVolumes() ([]Volume, error) {
+	return genericVFSListVolumes(d)
+}
+
 // ValidateVolume validates the supplied volume config. Optionally removes invalid keys from the volume's config.
 func (d *cephfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	return d.validateVolume(vol, nil, removeUnknownKeys)
@@ -508,6 +514,11 @@ func (d *cephfs) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string
 	return d.vfsVolumeSnapshots(vol, op)
 }
 
+// DiffVolumeSnapshot returns the files changed between a volume snapshot and a more recent one or the volume.
+func (d *cephfs) DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	return d.vfsDiffVolumeSnapshot(snapVol, targetVol, op)
+}
+
 // RestoreVolume resets a volume to its snapshotted state.
 func (d *cephfs) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
 	sourcePath := GetVolumeMountPath(d.name, vol.volType, vol.name)
@@ -164,6 +164,12 @@ func (d *common) Config() map[string]string {
 	return confCopy
 }
 
+// Encrypted returns whether the data of the pool is encrypted at rest, which drivers don't do by
+// default.
+func (d *common) Encrypted() bool {
+	return false
+}
+
 // ApplyPatch looks for a suitable patch and runs it.
 func (d *common) ApplyPatch(name string) error {
 	if d.patches == nil {
@@ -287,6 +293,20 @@ func (d *common) vfsRenameVolumeSnapshot(snapVol Volume, newSnapshotName string,
 	return nil
 }
 
+// vfsDiffVolumeSnapshot is a generic DiffVolumeSnapshot implementation comparing the mounted
+// volumes with a dry run of rsync.
+func (d *common) vfsDiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	oldRoot := snapVol.MountPath()
+	newRoot := targetVol.MountPath()
+
+	output, err := shared.RunCommand("rsync", "-a", "-H", "--numeric-ids", "--dry-run", "--delete", "--out-format=%i %n", shared.AddSlash(newRoot), shared.AddSlash(oldRoot))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to compare %q with %q", oldRoot, newRoot)
+	}
+
+	return diffFillSizes(parseRsyncDiff(output), oldRoot, newRoot), nil
+}
+
 // vfsMigrateVolume is a generic MigrateVolume implementation for VFS-only drivers.
 func (d *common) vfsMigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
 	bwlimit := d.config["rsync.bwlimit"]
@@ -13,6 +13,7 @@ import (
 	"github.com/lxc/lxd/lxd/rsync"
 	"github.com/lxc/lxd/lxd/storage/quota"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
 )
 
@@ -115,7 +116,7 @@ func (d *dir) CreateVolumeFromCopy(vol Volume, srcVol Volume, copySnapshots bool
 	}
 
 	// Run the generic copy.
-	return genericCopyVolume(d, d.setupInitialQuota, vol, srcVol, srcSnapshots, false, op)
+	return genericCopyVolume(d, d.setupInitialQuota, vol, srcVol, srcSnapshots, false, nil, op)
 }
 
 // CreateVolumeFromMigration creates a volume being sent via a migration.
@@ -132,8 +133,8 @@ func (d *dir) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, vol
 }
 
 // RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
-func (d *dir) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
-	return genericCopyVolume(d, d.setupInitialQuota, vol, srcVol, srcSnapshots, true, op)
+func (d *dir) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
+	return genericCopyVolume(d, d.setupInitialQuota, vol, srcVol, srcSnapshots, true, excludes, op)
 }
 
 // DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then
@@ -188,6 +189,11 @@ func (d *dir) HasVolume(vol Volume) bool {
 	return d.vfsHasVolume(vol)
 }
 
+// ListVolumes returns the volumes found on the storage pool.
+func (d *dir) ListVolumes() ([]Volume, error) {
+	return genericVFSListVolumes(d)
+}
+
 // ValidateVolume validates the supplied volume config. Optionally removes invalid keys from the volume's config.
 func (d *dir) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	return d.validateVolume(vol, nil, removeUnknownKeys)
@@ -363,6 +369,11 @@ func (d *dir) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, e
 	return d.vfsVolumeSnapshots(vol, op)
 }
 
+// DiffVolumeSnapshot returns the files changed between a volume snapshot and a more recent one or the volume.
+func (d *dir) DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	return d.vfsDiffVolumeSnapshot(snapVol, targetVol, op)
+}
+
 // RestoreVolume restores a volume from a snapshot.
 func (d *dir) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
 	srcPath := GetVolumeMountPath(d.name, vol.volType, GetSnapshotVolumeName(vol.name, snapshotName))
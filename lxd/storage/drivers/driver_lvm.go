@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -27,6 +28,10 @@ var lvmAllowedFilesystems = []string{"btrfs", "ext4", "xfs"}
 
 type lvm struct {
 	common
+
+	// lvsReport caches the logical volumes of each volume group, see logicalVolumeReport.
+	lvsReport     map[string]map[string]lvmReportLV
+	lvsReportLock sync.Mutex
 }
 
 func (d *lvm) load() error {
@@ -361,6 +366,7 @@ func (d *lvm) Delete(op *operations.Operation) error {
 		// Remove volume group if needed.
 		if removeVg {
 			_, err := shared.TryRunCommand("vgremove", "-f", d.config["lvm.vg_name"])
+			d.logicalVolumeReportInvalidate()
 			if err != nil {
 				return errors.Wrapf(err, "Failed to delete the volume group for the lvm storage pool")
 			}
@@ -456,6 +462,7 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 
 	if changedConfig["lvm.vg_name"] != "" {
 		_, err := shared.TryRunCommand("vgrename", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"])
+		d.logicalVolumeReportInvalidate()
 		if err != nil {
 			return errors.Wrapf(err, "Error renaming LVM volume group from %q to %q", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"])
 		}
@@ -464,6 +471,7 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 
 	if changedConfig["lvm.thinpool_name"] != "" {
 		_, err := shared.TryRunCommand("lvrename", d.config["lvm.vg_name"], d.config["lvm.thinpool_name"], changedConfig["lvm.thinpool_name"])
+		d.logicalVolumeReportInvalidate()
 		if err != nil {
 			return errors.Wrapf(err, "Error renaming LVM thin pool from %q to %q", d.config["lvm.thinpool_name"], changedConfig["lvm.thinpool_name"])
 		}
@@ -476,8 +484,15 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 // Mount mounts the storage pool (this does nothing for external LVM pools, but for loopback image
 // LVM pools this creates a loop device).
 func (d *lvm) Mount() (bool, error) {
-	// Open the loop file if the LVM device doesn't exist yet and the source points to a file.
-	if !shared.IsDir(fmt.Sprintf("/dev/%s", d.config["lvm.vg_name"])) && filepath.IsAbs(d.config["source"]) && !shared.IsBlockdevPath(d.config["source"]) {
+	// Logical volumes are activated on demand when their volumes are mounted, so rather than activating
+	// the whole volume group (which is what creates its directory in /dev) just check that it's there.
+	vgExists, _, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+	if err != nil {
+		return false, err
+	}
+
+	// Open the loop file if the volume group doesn't exist yet and the source points to a file.
+	if !vgExists && filepath.IsAbs(d.config["source"]) && !shared.IsBlockdevPath(d.config["source"]) {
 		loopFile, err := d.openLoopFile(d.config["source"])
 		if err != nil {
 			return false, err
@@ -487,10 +502,8 @@ func (d *lvm) Mount() (bool, error) {
 		return true, nil
 	}
 
-	// Activate volume group so that it's device is added to /dev.
-	_, err := shared.TryRunCommand("vgchange", "-ay", d.config["lvm.vg_name"])
-	if err != nil {
-		return false, err
+	if !vgExists {
+		return false, fmt.Errorf("Volume group %q not found", d.config["lvm.vg_name"])
 	}
 
 	return false, nil
@@ -0,0 +1,307 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared"
+)
+
+// lvmDBusName is the D-Bus name of lvmdbusd.
+const lvmDBusName = "com.redhat.lvmdbus1"
+
+// lvmDBusOnce is used to check whether lvmdbusd is running once.
+var lvmDBusOnce sync.Once
+
+// lvmDBusRunning indicates whether lvmdbusd is running, the logical volumes being changed through it then.
+var lvmDBusRunning bool
+
+// lvmError is an error reported by LVM when changing a logical volume.
+type lvmError struct {
+	op      string
+	object  string
+	message string
+}
+
+// Error returns the error message.
+func (e *lvmError) Error() string {
+	return fmt.Sprintf("Failed to %s LVM logical volume %q: %s", e.op, e.object, e.message)
+}
+
+// lvmOps changes logical volumes, either through lvmdbusd or through the LVM commands.
+type lvmOps interface {
+	// createLV creates a logical volume in the volume group, or a thin volume in the thin pool if set.
+	// The options are passed as command line options to lvcreate, their value being omitted if empty.
+	createLV(vgName string, thinPoolName string, lvName string, sizeBytes int64, options map[string]string) error
+	resizeLV(vgName string, lvName string, sizeBytes int64) error
+	renameLV(vgName string, lvName string, newLVName string) error
+	removeLV(vgName string, lvName string) error
+}
+
+// lvmOps returns the lvmOps to use, using lvmdbusd if it's running.
+func (d *lvm) lvmOps() lvmOps {
+	lvmDBusOnce.Do(func() {
+		lvmDBusRunning = lvmDBusCheck()
+		if lvmDBusRunning {
+			d.logger.Debug("Using lvmdbusd to change logical volumes")
+		}
+	})
+
+	if lvmDBusRunning {
+		return &lvmDBus{}
+	}
+
+	jsonLog, _ := d.lvmVersionIsAtLeast(lvmVersion, "2.02.158")
+	return &lvmCLI{jsonLog: jsonLog}
+}
+
+// lvmCLI changes logical volumes through the LVM commands. Their output is locale independent, and the
+// errors are taken from their JSON log report when supported rather than from their error output.
+type lvmCLI struct {
+	jsonLog bool
+}
+
+func (o *lvmCLI) createLV(vgName string, thinPoolName string, lvName string, sizeBytes int64, options map[string]string) error {
+	args := []string{"--name", lvName}
+	for k, v := range options {
+		args = append(args, fmt.Sprintf("--%s", k))
+		if v != "" {
+			args = append(args, v)
+		}
+	}
+
+	if thinPoolName != "" {
+		args = append(args, "--thin", "--virtualsize", fmt.Sprintf("%db", sizeBytes), fmt.Sprintf("%s/%s", vgName, thinPoolName))
+	} else {
+		args = append(args, "--size", fmt.Sprintf("%db", sizeBytes), vgName)
+	}
+
+	return o.run("create", fmt.Sprintf("%s/%s", vgName, lvName), "lvcreate", args...)
+}
+
+func (o *lvmCLI) resizeLV(vgName string, lvName string, sizeBytes int64) error {
+	lvID := fmt.Sprintf("%s/%s", vgName, lvName)
+	return o.run("resize", lvID, "lvresize", "-L", fmt.Sprintf("%db", sizeBytes), "-f", lvID)
+}
+
+func (o *lvmCLI) renameLV(vgName string, lvName string, newLVName string) error {
+	return o.run("rename", fmt.Sprintf("%s/%s", vgName, lvName), "lvrename", vgName, lvName, newLVName)
+}
+
+func (o *lvmCLI) removeLV(vgName string, lvName string) error {
+	lvID := fmt.Sprintf("%s/%s", vgName, lvName)
+	return o.run("remove", lvID, "lvremove", "-f", lvID)
+}
+
+// run runs an LVM command, retrying it for a while if it fails as the logical volumes may be busy.
+func (o *lvmCLI) run(op string, object string, name string, args ...string) error {
+	if o.jsonLog {
+		args = append([]string{"--reportformat", "json", "--config", "log/report_command_log=1"}, args...)
+	}
+
+	env := append(os.Environ(), "LC_ALL=C")
+
+	var stdout, stderr string
+	var err error
+	for i := 0; i < 20; i++ {
+		stdout, stderr, err = shared.RunCommandSplit(env, name, args...)
+		if err == nil {
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	message := ""
+	if o.jsonLog {
+		message = lvmLogErrors(stdout)
+	}
+
+	if message == "" {
+		message = strings.TrimSpace(stderr)
+	}
+
+	if message == "" {
+		message = err.Error()
+	}
+
+	return &lvmError{op: op, object: object, message: message}
+}
+
+// lvmLogErrors returns the error messages of the JSON log report of an LVM command.
+func lvmLogErrors(output string) string {
+	report := struct {
+		Log []struct {
+			Type    string `json:"log_type"`
+			Message string `json:"log_message"`
+		} `json:"log"`
+	}{}
+
+	err := json.Unmarshal([]byte(output), &report)
+	if err != nil {
+		return ""
+	}
+
+	messages := []string{}
+	for _, entry := range report.Log {
+		if entry.Type == "error" && entry.Message != "" {
+			messages = append(messages, entry.Message)
+		}
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// busctlRun runs busctl with the given arguments, returning its standard output and error.
+var busctlRun = func(args ...string) (string, string, error) {
+	return shared.RunCommandSplit(append(os.Environ(), "LC_ALL=C"), "busctl", args...)
+}
+
+// lvmDBus changes logical volumes through the D-Bus API of lvmdbusd, using busctl.
+type lvmDBus struct{}
+
+func (o *lvmDBus) createLV(vgName string, thinPoolName string, lvName string, sizeBytes int64, options map[string]string) error {
+	lvID := fmt.Sprintf("%s/%s", vgName, lvName)
+
+	optionArgs := []string{fmt.Sprintf("%d", len(options))}
+	for k, v := range options {
+		optionArgs = append(optionArgs, k, "s", v)
+	}
+
+	if thinPoolName != "" {
+		path, err := o.lookUp(fmt.Sprintf("%s/%s", vgName, thinPoolName))
+		if err != nil {
+			return &lvmError{op: "create", object: lvID, message: err.Error()}
+		}
+
+		args := append([]string{lvName, fmt.Sprintf("%d", sizeBytes), "-1"}, optionArgs...)
+		_, err = o.call(path, "ThinPool", "LvCreate", "stia{sv}", args...)
+		if err != nil {
+			return &lvmError{op: "create", object: lvID, message: err.Error()}
+		}
+
+		return nil
+	}
+
+	path, err := o.lookUp(vgName)
+	if err != nil {
+		return &lvmError{op: "create", object: lvID, message: err.Error()}
+	}
+
+	// No physical volumes are specified for the logical volume to be allocated on.
+	args := append([]string{lvName, "0", fmt.Sprintf("%d", sizeBytes), "-1"}, optionArgs...)
+	_, err = o.call(path, "Vg", "LvCreate", "sa(ott)tia{sv}", args...)
+	if err != nil {
+		return &lvmError{op: "create", object: lvID, message: err.Error()}
+	}
+
+	return nil
+}
+
+func (o *lvmDBus) resizeLV(vgName string, lvName string, sizeBytes int64) error {
+	return o.callLV("resize", vgName, lvName, "Resize", "ta(sst)ia{sv}", fmt.Sprintf("%d", sizeBytes), "0", "-1", "0")
+}
+
+func (o *lvmDBus) renameLV(vgName string, lvName string, newLVName string) error {
+	return o.callLV("rename", vgName, lvName, "Rename", "sia{sv}", newLVName, "-1", "0")
+}
+
+func (o *lvmDBus) removeLV(vgName string, lvName string) error {
+	return o.callLV("remove", vgName, lvName, "Remove", "ia{sv}", "-1", "0")
+}
+
+// callLV calls a method of the logical volume, waiting for it to complete.
+func (o *lvmDBus) callLV(op string, vgName string, lvName string, method string, signature string, args ...string) error {
+	lvID := fmt.Sprintf("%s/%s", vgName, lvName)
+
+	path, err := o.lookUp(lvID)
+	if err != nil {
+		return &lvmError{op: op, object: lvID, message: err.Error()}
+	}
+
+	_, err = o.call(path, "Lv", method, signature, args...)
+	if err != nil {
+		return &lvmError{op: op, object: lvID, message: err.Error()}
+	}
+
+	return nil
+}
+
+// lookUp returns the object path of a volume group or logical volume, or errLVMNotFound if it doesn't exist.
+func (o *lvmDBus) lookUp(lvmID string) (string, error) {
+	data, err := o.call("/com/redhat/lvmdbus1/Manager", "Manager", "LookUpByLvmId", "s", lvmID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < 1 {
+		return "", fmt.Errorf("Unexpected reply from lvmdbusd")
+	}
+
+	var path string
+	err = json.Unmarshal(data[0], &path)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "/" {
+		return "", errLVMNotFound
+	}
+
+	return path, nil
+}
+
+// call calls a method of lvmdbusd, returning the values of the reply.
+func (o *lvmDBus) call(path string, iface string, method string, signature string, args ...string) ([]json.RawMessage, error) {
+	callArgs := append([]string{"--system", "--json=short", "call", lvmDBusName, path, fmt.Sprintf("%s.%s", lvmDBusName, iface), method, signature}, args...)
+
+	stdout, stderr, err := busctlRun(callArgs...)
+	if err != nil {
+		message := strings.TrimSpace(stderr)
+		if message == "" {
+			message = err.Error()
+		}
+
+		return nil, fmt.Errorf("%s", message)
+	}
+
+	reply := struct {
+		Data []json.RawMessage `json:"data"`
+	}{}
+
+	err = json.Unmarshal([]byte(stdout), &reply)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.Data, nil
+}
+
+// lvmDBusCheck checks whether lvmdbusd is running on the system bus.
+func lvmDBusCheck() bool {
+	_, err := exec.LookPath("busctl")
+	if err != nil {
+		return false
+	}
+
+	stdout, err := shared.RunCommand("busctl", "--system", "--json=short", "call", "org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "NameHasOwner", "s", lvmDBusName)
+	if err != nil {
+		return false
+	}
+
+	reply := struct {
+		Data []bool `json:"data"`
+	}{}
+
+	err = json.Unmarshal([]byte(stdout), &reply)
+	if err != nil || len(reply.Data) < 1 {
+		return false
+	}
+
+	return reply.Data[0]
+}
@@ -0,0 +1,101 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test lvmLogErrors
+func TestLVMLogErrors(t *testing.T) {
+	output := `{
+      "log": [
+          {"log_seq_num":"1", "log_type":"error", "log_context":"processing", "log_object_type":"lv", "log_object_name":"lxd/c1", "log_object_id":"", "log_object_group":"", "log_object_group_id":"", "log_message":"Logical Volume \"c1\" already exists in volume group \"lxd\"", "log_errno":"0", "log_ret_code":"0"},
+          {"log_seq_num":"2", "log_type":"status", "log_context":"processing", "log_object_type":"cmd", "log_object_name":"", "log_object_id":"", "log_object_group":"", "log_object_group_id":"", "log_message":"failure", "log_errno":"-1", "log_ret_code":"5"}
+      ]
+  }`
+
+	assert.Equal(t, `Logical Volume "c1" already exists in volume group "lxd"`, lvmLogErrors(output))
+
+	// Output which isn't a JSON report has no errors.
+	assert.Equal(t, "", lvmLogErrors("  Logical volume \"c1\" created."))
+}
+
+// Test the arguments passed to busctl for each call of lvmdbusd
+func TestLVMDBusCalls(t *testing.T) {
+	calls := [][]string{}
+
+	defer func(run func(args ...string) (string, string, error)) {
+		busctlRun = run
+	}(busctlRun)
+
+	busctlRun = func(args ...string) (string, string, error) {
+		calls = append(calls, args)
+
+		if args[6] == "LookUpByLvmId" {
+			return `{"type":"o","data":["/com/redhat/lvmdbus1/Object/1"]}`, "", nil
+		}
+
+		return `{"type":"oo","data":["/","/"]}`, "", nil
+	}
+
+	prefix := func(iface string, method string, signature string) []string {
+		path := "/com/redhat/lvmdbus1/Object/1"
+		if method == "LookUpByLvmId" {
+			path = "/com/redhat/lvmdbus1/Manager"
+		}
+
+		return []string{"--system", "--json=short", "call", "com.redhat.lvmdbus1", path, "com.redhat.lvmdbus1." + iface, method, signature}
+	}
+
+	o := &lvmDBus{}
+
+	tests := []struct {
+		name   string
+		run    func() error
+		lookUp string
+		call   []string
+	}{
+		{
+			name:   "createLV",
+			run:    func() error { return o.createLV("vg", "", "lv", 1024, map[string]string{"activate": "y"}) },
+			lookUp: "vg",
+			call:   append(prefix("Vg", "LvCreate", "sa(ott)tia{sv}"), "lv", "0", "1024", "-1", "1", "activate", "s", "y"),
+		},
+		{
+			name:   "createLV thin",
+			run:    func() error { return o.createLV("vg", "pool", "lv", 1024, nil) },
+			lookUp: "vg/pool",
+			call:   append(prefix("ThinPool", "LvCreate", "stia{sv}"), "lv", "1024", "-1", "0"),
+		},
+		{
+			name:   "resizeLV",
+			run:    func() error { return o.resizeLV("vg", "lv", 2048) },
+			lookUp: "vg/lv",
+			call:   append(prefix("Lv", "Resize", "ta(sst)ia{sv}"), "2048", "0", "-1", "0"),
+		},
+		{
+			name:   "renameLV",
+			run:    func() error { return o.renameLV("vg", "lv", "lv2") },
+			lookUp: "vg/lv",
+			call:   append(prefix("Lv", "Rename", "sia{sv}"), "lv2", "-1", "0"),
+		},
+		{
+			name:   "removeLV",
+			run:    func() error { return o.removeLV("vg", "lv") },
+			lookUp: "vg/lv",
+			call:   append(prefix("Lv", "Remove", "ia{sv}"), "-1", "0"),
+		},
+	}
+
+	for _, test := range tests {
+		calls = [][]string{}
+
+		err := test.run()
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, [][]string{
+			append(prefix("Manager", "LookUpByLvmId", "s"), test.lookUp),
+			test.call,
+		}, calls, test.name)
+	}
+}
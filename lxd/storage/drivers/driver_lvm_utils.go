@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -221,9 +222,9 @@ func (d *lvm) thinpoolExists(vgName string, poolName string) (bool, error) {
 
 // logicalVolumeExists checks whether the specified logical volume exists.
 func (d *lvm) logicalVolumeExists(volDevPath string) (bool, error) {
-	_, err := shared.RunCommand("lvs", "--noheadings", "-o", "lv_name", volDevPath)
+	_, err := d.logicalVolumeReport(volDevPath)
 	if err != nil {
-		if d.isLVMNotFoundExitError(err) {
+		if err == errLVMNotFound {
 			return false, nil
 		}
 
@@ -233,6 +234,104 @@ func (d *lvm) logicalVolumeExists(volDevPath string) (bool, error) {
 	return true, nil
 }
 
+// lvmReportLV is a logical volume as reported by lvs, with sizes in bytes.
+type lvmReportLV struct {
+	Name            string `json:"lv_name"`
+	Size            string `json:"lv_size"`
+	Attr            string `json:"lv_attr"`
+	DataPercent     string `json:"data_percent"`
+	MetadataPercent string `json:"metadata_percent"`
+}
+
+// lvmReportFields are the fields of lvmReportLV, in order.
+const lvmReportFields = "lv_name,lv_size,lv_attr,data_percent,metadata_percent"
+
+// logicalVolumeReport returns the lvs report of a logical volume, or errLVMNotFound if it doesn't exist.
+// All the logical volumes of its volume group are queried at once using the JSON report format, the result
+// being cached until the logical volumes are changed by the driver, so that operations dealing with many
+// logical volumes don't run lvs for each of them. LVM versions without the JSON report format get the
+// logical volume queried on its own.
+func (d *lvm) logicalVolumeReport(volDevPath string) (*lvmReportLV, error) {
+	vgName := filepath.Base(filepath.Dir(volDevPath))
+	lvName := filepath.Base(volDevPath)
+
+	jsonReport, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.158")
+	if err != nil {
+		return nil, err
+	}
+
+	if !jsonReport {
+		output, err := shared.RunCommand("lvs", "--noheadings", "--nosuffix", "--units", "b", "--separator", ",", "-o", lvmReportFields, volDevPath)
+		if err != nil {
+			if d.isLVMNotFoundExitError(err) {
+				return nil, errLVMNotFound
+			}
+
+			return nil, err
+		}
+
+		fields := strings.Split(strings.TrimSpace(output), ",")
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("Unexpected output from lvs command")
+		}
+
+		return &lvmReportLV{Name: fields[0], Size: fields[1], Attr: fields[2], DataPercent: fields[3], MetadataPercent: fields[4]}, nil
+	}
+
+	d.lvsReportLock.Lock()
+	defer d.lvsReportLock.Unlock()
+
+	if d.lvsReport == nil {
+		d.lvsReport = map[string]map[string]lvmReportLV{}
+	}
+
+	lvs, ok := d.lvsReport[vgName]
+	if !ok {
+		output, err := shared.RunCommand("lvs", "--reportformat", "json", "--nosuffix", "--units", "b", "-o", lvmReportFields, vgName)
+		if err != nil {
+			if d.isLVMNotFoundExitError(err) {
+				return nil, errLVMNotFound
+			}
+
+			return nil, err
+		}
+
+		report := struct {
+			Report []struct {
+				LV []lvmReportLV `json:"lv"`
+			} `json:"report"`
+		}{}
+
+		err = json.Unmarshal([]byte(output), &report)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse lvs report")
+		}
+
+		lvs = map[string]lvmReportLV{}
+		for _, r := range report.Report {
+			for _, lv := range r.LV {
+				lvs[lv.Name] = lv
+			}
+		}
+
+		d.lvsReport[vgName] = lvs
+	}
+
+	lv, ok := lvs[lvName]
+	if !ok {
+		return nil, errLVMNotFound
+	}
+
+	return &lv, nil
+}
+
+// logicalVolumeReportInvalidate discards the cached lvs reports, to be called when logical volumes change.
+func (d *lvm) logicalVolumeReportInvalidate() {
+	d.lvsReportLock.Lock()
+	d.lvsReport = nil
+	d.lvsReportLock.Unlock()
+}
+
 // createDefaultThinPool creates the default thinpool as 100% the size of the volume group with a 1G
 // meta data volume.
 func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) error {
@@ -273,6 +372,7 @@ func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) err
 
 	// Create the thin pool volume.
 	_, err = shared.TryRunCommand("lvcreate", args...)
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
 		return errors.Wrapf(err, "Error creating LVM thin pool named %q", thinPoolName)
 	}
@@ -280,6 +380,7 @@ func (d *lvm) createDefaultThinPool(lvmVersion, vgName, thinPoolName string) err
 	if !isRecent {
 		// Grow it to the maximum VG size (two step process required by old LVM).
 		_, err = shared.TryRunCommand("lvextend", "--alloc", "anywhere", "-l", "100%FREE", lvmThinPool)
+		d.logicalVolumeReportInvalidate()
 		if err != nil {
 			return errors.Wrapf(err, "Error growing LVM thin pool named %q", thinPoolName)
 		}
@@ -337,29 +438,30 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 
 	lvFullName := d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name)
 
-	args := []string{
-		"--name", lvFullName,
-		"--yes",
-		"--wipesignatures", "y",
+	options := map[string]string{
+		"yes":            "",
+		"wipesignatures": "y",
+	}
+
+	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
+	if err != nil {
+		return errors.Wrapf(err, "Error checking LVM version")
+	}
+
+	// Skip the logical volume when activating the whole volume group, such as on boot, as it gets
+	// activated on demand when used. It's still activated now to be formatted.
+	if isRecent {
+		options["setactivationskip"] = "y"
+		options["ignoreactivationskip"] = ""
 	}
 
 	if makeThinLv {
-		targetVg := fmt.Sprintf("%s/%s", vgName, thinPoolName)
-		args = append(args,
-			"--thin",
-			"--virtualsize", fmt.Sprintf("%db", lvSizeBytes),
-			targetVg,
-		)
+		err = d.lvmOps().createLV(vgName, thinPoolName, lvFullName, lvSizeBytes, options)
 	} else {
-		args = append(args,
-			"--size", fmt.Sprintf("%db", lvSizeBytes),
-			vgName,
-		)
-
 		// As we are creating a normal logical volume we can apply stripes settings if specified.
 		stripes := vol.ExpandedConfig("lvm.stripes")
 		if stripes != "" {
-			args = append(args, "--stripes", stripes)
+			options["stripes"] = stripes
 
 			stripeSize := vol.ExpandedConfig("lvm.stripes.size")
 			if stripeSize != "" {
@@ -368,14 +470,15 @@ func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeT
 					return errors.Wrapf(err, "Invalid volume stripe size %q", stripeSize)
 				}
 
-				args = append(args, "--stripesize", fmt.Sprintf("%db", stripSizeBytes))
+				options["stripesize"] = fmt.Sprintf("%db", stripSizeBytes)
 			}
 		}
-	}
 
-	_, err = shared.TryRunCommand("lvcreate", args...)
+		err = d.lvmOps().createLV(vgName, "", lvFullName, lvSizeBytes, options)
+	}
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
-		return errors.Wrapf(err, "Error creating LVM logical volume %q", lvFullName)
+		return err
 	}
 
 	volDevPath := d.lvmDevPath(vgName, vol.volType, vol.contentType, vol.name)
@@ -427,6 +530,7 @@ func (d *lvm) createLogicalVolumeSnapshot(vgName string, srcVol, snapVol Volume,
 	defer revert.Fail()
 
 	_, err = shared.TryRunCommand("lvcreate", args...)
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
 		return "", err
 	}
@@ -441,7 +545,7 @@ func (d *lvm) createLogicalVolumeSnapshot(vgName string, srcVol, snapVol Volume,
 		// Snapshots of thin logical volumes can be directly activated.
 		// Normal snapshots will complain about changing the origin (Which they never do.),
 		// so skip the activation since the logical volume will be automatically activated anyway.
-		_, err := shared.TryRunCommand("lvchange", "-ay", targetVolDevPath)
+		_, err := d.activateVolume(targetVolDevPath)
 		if err != nil {
 			return "", err
 		}
@@ -451,9 +555,45 @@ func (d *lvm) createLogicalVolumeSnapshot(vgName string, srcVol, snapVol Volume,
 	return targetVolDevPath, nil
 }
 
+// activateVolume activates a logical volume if its device isn't present yet, returning whether it was
+// activated. Logical volumes are activated on demand when used, rather than all at once with their volume
+// group, so that hosts with many of them don't need to activate them all on startup.
+func (d *lvm) activateVolume(volDevPath string) (bool, error) {
+	if shared.PathExists(volDevPath) {
+		return false, nil
+	}
+
+	_, err := shared.TryRunCommand("lvchange", "--activate", "y", "--ignoreactivationskip", volDevPath)
+	d.logicalVolumeReportInvalidate()
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to activate LVM logical volume %q", volDevPath)
+	}
+	d.logger.Debug("Activated logical volume", log.Ctx{"dev": volDevPath})
+
+	return true, nil
+}
+
+// deactivateVolume deactivates a logical volume if its device is present, returning whether it was
+// deactivated. This removes the device node of logical volumes which aren't in use.
+func (d *lvm) deactivateVolume(volDevPath string) (bool, error) {
+	if !shared.PathExists(volDevPath) {
+		return false, nil
+	}
+
+	_, err := shared.TryRunCommand("lvchange", "--activate", "n", volDevPath)
+	d.logicalVolumeReportInvalidate()
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to deactivate LVM logical volume %q", volDevPath)
+	}
+	d.logger.Debug("Deactivated logical volume", log.Ctx{"dev": volDevPath})
+
+	return true, nil
+}
+
 // removeLogicalVolume removes a logical volume.
 func (d *lvm) removeLogicalVolume(volDevPath string) error {
-	_, err := shared.TryRunCommand("lvremove", "-f", volDevPath)
+	err := d.lvmOps().removeLV(filepath.Base(filepath.Dir(volDevPath)), filepath.Base(volDevPath))
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
 		return err
 	}
@@ -464,7 +604,8 @@ func (d *lvm) removeLogicalVolume(volDevPath string) error {
 
 // renameLogicalVolume renames a logical volume.
 func (d *lvm) renameLogicalVolume(volDevPath string, newVolDevPath string) error {
-	_, err := shared.TryRunCommand("lvrename", volDevPath, newVolDevPath)
+	err := d.lvmOps().renameLV(filepath.Base(filepath.Dir(volDevPath)), filepath.Base(volDevPath), filepath.Base(newVolDevPath))
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
 		return err
 	}
@@ -511,6 +652,42 @@ func (d *lvm) lvmFullVolumeName(volType VolumeType, contentType ContentType, vol
 	return fmt.Sprintf("%s_%s%s", volTypePrefix, lvName, contentTypeSuffix)
 }
 
+// lvmVolumeFromLVName reverses lvmFullVolumeName, returning the volume type, content type and volume name of
+// a logical volume from its name. An empty volume type is returned if the logical volume isn't a volume.
+func (d *lvm) lvmVolumeFromLVName(lvName string) (VolumeType, ContentType, string) {
+	parts := strings.SplitN(lvName, "_", 2)
+	if len(parts) != 2 || parts[1] == "" || strings.HasSuffix(parts[1], tmpVolSuffix) {
+		return "", "", ""
+	}
+
+	volType := VolumeType(parts[0])
+	if volType != VolumeTypeContainer && volType != VolumeTypeVM && volType != VolumeTypeImage && volType != VolumeTypeCustom {
+		return "", "", ""
+	}
+
+	contentType := ContentTypeFS
+	escapedName := parts[1]
+	if strings.HasSuffix(escapedName, lvmBlockVolSuffix) {
+		contentType = ContentTypeBlock
+		escapedName = strings.TrimSuffix(escapedName, lvmBlockVolSuffix)
+	}
+
+	// Unescape the name, a single "-" being the snapshot delimiter and "--" an escaped "-".
+	var volName strings.Builder
+	for i := 0; i < len(escapedName); i++ {
+		if escapedName[i] != '-' {
+			volName.WriteByte(escapedName[i])
+		} else if i+1 < len(escapedName) && escapedName[i+1] == '-' {
+			volName.WriteByte('-')
+			i++
+		} else {
+			volName.WriteString(shared.SnapshotDelimiter)
+		}
+	}
+
+	return volType, contentType, volName.String()
+}
+
 // lvmDevPath returns the path to the LVM volume device. Empty string is returned if invalid volType supplied.
 func (d *lvm) lvmDevPath(vgName string, volType VolumeType, contentType ContentType, volName string) string {
 	fullVolName := d.lvmFullVolumeName(volType, contentType, volName)
@@ -523,7 +700,8 @@ func (d *lvm) lvmDevPath(vgName string, volType VolumeType, contentType ContentT
 
 // resizeLogicalVolume resizes an LVM logical volume. This function does not resize any filesystem inside the LV.
 func (d *lvm) resizeLogicalVolume(lvPath string, sizeBytes int64) error {
-	_, err := shared.TryRunCommand("lvresize", "-L", fmt.Sprintf("%db", sizeBytes), "-f", lvPath)
+	err := d.lvmOps().resizeLV(filepath.Base(filepath.Dir(lvPath)), filepath.Base(lvPath), sizeBytes)
+	d.logicalVolumeReportInvalidate()
 	if err != nil {
 		return err
 	}
@@ -656,47 +834,32 @@ func (d *lvm) copyThinpoolVolume(vol, srcVol Volume, srcSnapshots []Volume, refr
 
 // logicalVolumeSize gets the size in bytes of a logical volume.
 func (d *lvm) logicalVolumeSize(volDevPath string) (int64, error) {
-	output, err := shared.RunCommand("lvs", "--noheadings", "--nosuffix", "--units", "b", "-o", "lv_size", volDevPath)
+	lv, err := d.logicalVolumeReport(volDevPath)
 	if err != nil {
-		if d.isLVMNotFoundExitError(err) {
+		if err == errLVMNotFound {
 			return -1, errLVMNotFound
 		}
 
 		return -1, errors.Wrapf(err, "Error getting size of LVM volume %q", volDevPath)
 	}
 
-	output = strings.TrimSpace(output)
-	return strconv.ParseInt(output, 10, 64)
+	return strconv.ParseInt(lv.Size, 10, 64)
 }
 
 func (d *lvm) thinPoolVolumeUsage(volDevPath string) (uint64, uint64, error) {
-	args := []string{
-		volDevPath,
-		"--noheadings",
-		"--units", "b",
-		"--nosuffix",
-		"--separator", ",",
-		"-o", "lv_size,data_percent,metadata_percent",
-	}
-
-	out, err := shared.RunCommand("lvs", args...)
+	lv, err := d.logicalVolumeReport(volDevPath)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	parts := strings.Split(strings.TrimSpace(out), ",")
-	if len(parts) < 3 {
-		return 0, 0, fmt.Errorf("Unexpected output from lvs command")
-	}
-
-	total, err := strconv.ParseUint(parts[0], 10, 64)
+	total, err := strconv.ParseUint(lv.Size, 10, 64)
 	if err != nil {
 		return 0, 0, err
 	}
 
 	totalSize := total
 
-	dataPerc, err := strconv.ParseFloat(parts[1], 64)
+	dataPerc, err := strconv.ParseFloat(lv.DataPercent, 64)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -704,8 +867,8 @@ func (d *lvm) thinPoolVolumeUsage(volDevPath string) (uint64, uint64, error) {
 	metaPerc := float64(0)
 
 	// For thin volumes there is no meta data percentage. This is only for the thin pool volume itself.
-	if parts[2] != "" {
-		metaPerc, err = strconv.ParseFloat(parts[2], 64)
+	if lv.MetadataPercent != "" {
+		metaPerc, err = strconv.ParseFloat(lv.MetadataPercent, 64)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -0,0 +1,39 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test lvmVolumeFromLVName
+func TestLVMVolumeFromLVName(t *testing.T) {
+	d := &lvm{}
+
+	tests := []struct {
+		volType     VolumeType
+		contentType ContentType
+		volName     string
+	}{
+		{VolumeTypeContainer, ContentTypeFS, "c1"},
+		{VolumeTypeContainer, ContentTypeFS, "proj_c-1"},
+		{VolumeTypeContainer, ContentTypeFS, "c--1/snap-0"},
+		{VolumeTypeVM, ContentTypeBlock, "v1"},
+		{VolumeTypeVM, ContentTypeFS, "v1/snap0"},
+		{VolumeTypeCustom, ContentTypeFS, "vol-a"},
+	}
+
+	for _, test := range tests {
+		lvName := d.lvmFullVolumeName(test.volType, test.contentType, test.volName)
+		volType, contentType, volName := d.lvmVolumeFromLVName(lvName)
+		assert.Equal(t, test.volType, volType, lvName)
+		assert.Equal(t, test.contentType, contentType, lvName)
+		assert.Equal(t, test.volName, volName, lvName)
+	}
+
+	// Logical volumes which aren't volumes.
+	for _, lvName := range []string{"LXDThinPool", "containers_", "backups_c1", "containers_c1.lxdtmp"} {
+		volType, _, _ := d.lvmVolumeFromLVName(lvName)
+		assert.Equal(t, VolumeType(""), volType, lvName)
+	}
+}
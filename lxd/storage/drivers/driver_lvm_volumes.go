@@ -5,6 +5,7 @@ import (
 	"io"
 	"math"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
@@ -14,6 +15,7 @@ import (
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/rsync"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	log "github.com/lxc/lxd/shared/log15"
 )
 
@@ -123,7 +125,7 @@ func (d *lvm) CreateVolumeFromCopy(vol, srcVol Volume, copySnapshots bool, op *o
 	}
 
 	// Otherwise run the generic copy.
-	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, false, op)
+	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, false, nil, op)
 }
 
 // CreateVolumeFromMigration creates a volume being sent via a migration.
@@ -140,14 +142,15 @@ func (d *lvm) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, vol
 }
 
 // RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
-func (d *lvm) RefreshVolume(vol, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
-	// We can use optimised copying when the pool is backed by an LVM thinpool.
-	if d.usesThinpool() {
+func (d *lvm) RefreshVolume(vol, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
+	// We can use optimised copying when the pool is backed by an LVM thinpool, unless some paths
+	// must be left out which only the generic copy can do.
+	if d.usesThinpool() && len(excludes) == 0 {
 		return d.copyThinpoolVolume(vol, srcVol, srcSnapshots, true)
 	}
 
 	// Otherwise run the generic copy.
-	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, op)
+	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, excludes, op)
 }
 
 // DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then this function
@@ -221,6 +224,31 @@ func (d *lvm) HasVolume(vol Volume) bool {
 	return volExists
 }
 
+// ListVolumes returns the volumes found on the storage pool, from its logical volumes.
+func (d *lvm) ListVolumes() ([]Volume, error) {
+	output, err := shared.RunCommand("lvs", "--noheadings", "-o", "lv_name", d.config["lvm.vg_name"])
+	if err != nil {
+		return nil, err
+	}
+
+	vols := []Volume{}
+	for _, lvName := range strings.Fields(output) {
+		volType, contentType, volName := d.lvmVolumeFromLVName(lvName)
+		if volType == "" || volType == VolumeTypeImage {
+			continue
+		}
+
+		// Virtual machines are listed through their block volume, their filesystem volume being implied.
+		if volType == VolumeTypeVM && contentType != ContentTypeBlock {
+			continue
+		}
+
+		vols = append(vols, NewVolume(d, d.name, volType, contentType, volName, nil, d.config))
+	}
+
+	return vols, nil
+}
+
 // ValidateVolume validates the supplied volume config.
 func (d *lvm) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	rules := map[string]func(value string) error{
@@ -289,6 +317,12 @@ func (d *lvm) GetVolumeUsage(vol Volume) (int64, error) {
 		// For thin pool block volumes we can calculate an approximate usage using the space allocated to
 		// the volume from the thin pool.
 		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+		// The usage of thin volumes is only known while they are active.
+		if !shared.PathExists(volDevPath) {
+			return -1, ErrNotSupported
+		}
+
 		_, usedSize, err := d.thinPoolVolumeUsage(volDevPath)
 		if err != nil {
 			return -1, err
@@ -342,7 +376,25 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, op *operations.Operation)
 	if vol.contentType == ContentTypeFS {
 		if newSizeBytes < oldSizeBytes {
 			// Shrink filesystem to new size first, then shrink logical volume.
-			err = shrinkFileSystem(d.volumeFilesystem(vol), volDevPath, vol, newSizeBytes)
+			fsType := d.volumeFilesystem(vol)
+			if fsType == "" || fsType == "ext4" {
+				// Ext4 is checked while unmounted, which deactivates the logical volume, so
+				// activate it explicitly for the check.
+				err = vol.UnmountTask(func(op *operations.Operation) error {
+					activated, err := d.activateVolume(volDevPath)
+					if err != nil {
+						return err
+					}
+
+					if activated {
+						defer d.deactivateVolume(volDevPath)
+					}
+
+					return shrinkFileSystem(fsType, volDevPath, vol, newSizeBytes)
+				}, op)
+			} else {
+				err = shrinkFileSystem(fsType, volDevPath, vol, newSizeBytes)
+			}
 			if err != nil {
 				return err
 			}
@@ -384,23 +436,38 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, op *operations.Operation)
 func (d *lvm) GetVolumeDiskPath(vol Volume) (string, error) {
 	if vol.IsVMBlock() {
 		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+		// Make sure the device exists, it's deactivated again when the volume is unmounted.
+		_, err := d.activateVolume(volDevPath)
+		if err != nil {
+			return "", err
+		}
+
 		return volDevPath, nil
 	}
 
 	return "", ErrNotImplemented
 }
 
-// MountVolume simulates mounting a volume. As dir driver doesn't have volumes to mount it returns
-// false indicating that there is no need to issue an unmount.
+// MountVolume activates the volume's logical volume and mounts its filesystem if needed.
 func (d *lvm) MountVolume(vol Volume, op *operations.Operation) (bool, error) {
 	mountPath := vol.MountPath()
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
 
 	// Check if already mounted.
 	if vol.contentType == ContentTypeFS && !shared.IsMountPoint(mountPath) {
-		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+		activated, err := d.activateVolume(volDevPath)
+		if err != nil {
+			return false, err
+		}
+
 		mountFlags, mountOptions := resolveMountOptions(d.volumeMountOptions(vol))
-		err := TryMount(volDevPath, mountPath, d.volumeFilesystem(vol), mountFlags, mountOptions)
+		err = TryMount(volDevPath, mountPath, d.volumeFilesystem(vol), mountFlags, mountOptions)
 		if err != nil {
+			if activated {
+				d.deactivateVolume(volDevPath)
+			}
+
 			return false, errors.Wrapf(err, "Failed to mount LVM logical volume")
 		}
 		d.logger.Debug("Mounted logical volume", log.Ctx{"dev": volDevPath, "path": mountPath})
@@ -408,8 +475,13 @@ func (d *lvm) MountVolume(vol Volume, op *operations.Operation) (bool, error) {
 		return true, nil
 	}
 
-	// For VMs, mount the filesystem volume.
+	// For VMs, activate the block volume and mount the filesystem volume.
 	if vol.IsVMBlock() {
+		_, err := d.activateVolume(volDevPath)
+		if err != nil {
+			return false, err
+		}
+
 		fsVol := vol.NewVMBlockFilesystemVolume()
 		return d.MountVolume(fsVol, op)
 	}
@@ -417,10 +489,29 @@ func (d *lvm) MountVolume(vol Volume, op *operations.Operation) (bool, error) {
 	return false, nil
 }
 
-// UnmountVolume simulates unmounting a volume. As dir driver doesn't have volumes to unmount it
-// returns false indicating the volume was already unmounted.
+// UnmountVolume unmounts the volume's filesystem and deactivates its logical volume if it was mounted.
+// Volumes which weren't mounted by LXD are left active.
 func (d *lvm) UnmountVolume(vol Volume, op *operations.Operation) (bool, error) {
 	mountPath := vol.MountPath()
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+	// For VMs, unmount the filesystem volume and deactivate the block volume with it.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		ourUnmount, err := d.UnmountVolume(fsVol, op)
+		if err != nil {
+			return false, err
+		}
+
+		if ourUnmount {
+			_, err = d.deactivateVolume(volDevPath)
+			if err != nil {
+				return true, err
+			}
+		}
+
+		return ourUnmount, nil
+	}
 
 	// Check if already mounted.
 	if shared.IsMountPoint(mountPath) {
@@ -430,6 +521,11 @@ func (d *lvm) UnmountVolume(vol Volume, op *operations.Operation) (bool, error)
 		}
 		d.logger.Debug("Unmounted logical volume", log.Ctx{"path": mountPath})
 
+		_, err = d.deactivateVolume(volDevPath)
+		if err != nil {
+			return true, err
+		}
+
 		return true, nil
 	}
 
@@ -672,8 +768,13 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) (boo
 
 		// Finally attempt to mount the volume that needs mounting.
 		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], mountVol.volType, mountVol.contentType, mountVol.name)
+		_, err := d.activateVolume(volDevPath)
+		if err != nil {
+			return false, err
+		}
+
 		mountFlags, mountOptions := resolveMountOptions(d.volumeMountOptions(snapVol))
-		err := TryMount(volDevPath, mountPath, d.volumeFilesystem(mountVol), mountFlags|unix.MS_RDONLY, mountOptions)
+		err = TryMount(volDevPath, mountPath, d.volumeFilesystem(mountVol), mountFlags|unix.MS_RDONLY, mountOptions)
 		if err != nil {
 			return false, errors.Wrapf(err, "Failed to mount LVM snapshot volume")
 		}
@@ -705,6 +806,11 @@ func (d *lvm) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 		}
 		d.logger.Debug("Unmounted logical volume snapshot", log.Ctx{"path": mountPath})
 
+		_, err = d.deactivateVolume(d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name))
+		if err != nil {
+			return true, err
+		}
+
 		// Check if a temporary snapshot exists, and if so remove it.
 		tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
 		tmpVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, tmpVolName)
@@ -734,6 +840,11 @@ func (d *lvm) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, e
 	return d.vfsVolumeSnapshots(vol, op)
 }
 
+// DiffVolumeSnapshot returns the files changed between a volume snapshot and a more recent one or the volume.
+func (d *lvm) DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	return d.vfsDiffVolumeSnapshot(snapVol, targetVol, op)
+}
+
 // RestoreVolume restores a volume from a snapshot.
 func (d *lvm) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
 	// Instantiate snapshot volume from snapshot name.
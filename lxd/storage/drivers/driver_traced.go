@@ -0,0 +1,114 @@
+package drivers
+
+import (
+	"io"
+
+	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/trace"
+)
+
+// traced wraps a driver, tracing the volume functions which can take long as part of the
+// operation they're called for.
+type traced struct {
+	Driver
+}
+
+// start starts the span of a call to the driver.
+func (d *traced) start(name string, vol Volume, op *operations.Operation) *trace.Span {
+	_, span := trace.Start(op.TraceContext(), "storage."+name)
+	if span == nil {
+		return nil
+	}
+
+	span.SetAttribute("lxd.storage.driver", d.Info().Name)
+	span.SetAttribute("lxd.storage.pool", d.Name())
+	span.SetAttribute("lxd.storage.volume", vol.Name())
+	span.SetAttribute("lxd.storage.volume_type", string(vol.Type()))
+	span.SetAttribute("lxd.storage.content_type", string(vol.ContentType()))
+
+	return span
+}
+
+func (d *traced) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
+	span := d.start("CreateVolume", vol, op)
+	err := d.Driver.CreateVolume(vol, filler, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) CreateVolumeFromCopy(vol Volume, srcVol Volume, copySnapshots bool, op *operations.Operation) error {
+	span := d.start("CreateVolumeFromCopy", vol, op)
+	span.SetAttribute("lxd.storage.source_volume", srcVol.Name())
+	err := d.Driver.CreateVolumeFromCopy(vol, srcVol, copySnapshots, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
+	span := d.start("RefreshVolume", vol, op)
+	span.SetAttribute("lxd.storage.source_volume", srcVol.Name())
+	err := d.Driver.RefreshVolume(vol, srcVol, srcSnapshots, excludes, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) DeleteVolume(vol Volume, op *operations.Operation) error {
+	span := d.start("DeleteVolume", vol, op)
+	err := d.Driver.DeleteVolume(vol, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
+	span := d.start("CreateVolumeSnapshot", snapVol, op)
+	err := d.Driver.CreateVolumeSnapshot(snapVol, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
+	span := d.start("RestoreVolume", vol, op)
+	span.SetAttribute("lxd.storage.snapshot", snapshotName)
+	err := d.Driver.RestoreVolume(vol, snapshotName, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
+	span := d.start("MigrateVolume", vol, op)
+	err := d.Driver.MigrateVolume(vol, conn, volSrcArgs, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, volTargetArgs migration.VolumeTargetArgs, preFiller *VolumeFiller, op *operations.Operation) error {
+	span := d.start("CreateVolumeFromMigration", vol, op)
+	err := d.Driver.CreateVolumeFromMigration(vol, conn, volTargetArgs, preFiller, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) BackupVolume(vol Volume, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error {
+	span := d.start("BackupVolume", vol, op)
+	err := d.Driver.BackupVolume(vol, targetPath, optimized, snapshots, op)
+	span.End(err)
+
+	return err
+}
+
+func (d *traced) CreateVolumeFromBackup(vol Volume, snapshots []string, srcData io.ReadSeeker, optimizedStorage bool, op *operations.Operation) (func(vol Volume) error, func(), error) {
+	span := d.start("CreateVolumeFromBackup", vol, op)
+	postHook, revertHook, err := d.Driver.CreateVolumeFromBackup(vol, snapshots, srcData, optimizedStorage, op)
+	span.End(err)
+
+	return postHook, revertHook, err
+}
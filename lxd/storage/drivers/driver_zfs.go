@@ -88,6 +88,17 @@ func (d *zfs) Info() Info {
 	return info
 }
 
+// Encrypted returns whether the datasets of the pool are encrypted by ZFS native encryption, which
+// they inherit from the pool dataset.
+func (d *zfs) Encrypted() bool {
+	value, err := d.getDatasetProperty(d.config["zfs.pool_name"], "encryption")
+	if err != nil {
+		return false
+	}
+
+	return value != "off" && value != "-"
+}
+
 // Create is called during pool creation and is effectively using an empty driver struct.
 // WARNING: The Create() function cannot rely on any of the struct attributes being set.
 func (d *zfs) Create() error {
@@ -6,12 +6,14 @@ import (
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/pborman/uuid"
 
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
 )
 
@@ -316,3 +318,67 @@ func (d *zfs) receiveDataset(dataset string, conn io.ReadWriteCloser, writeWrapp
 
 	return nil
 }
+
+// parseZfsDiff returns the file changes listed by "zfs diff -H -F", with paths relative to root,
+// the mount path of the dataset. Renamed files are reported as deleted and added.
+func parseZfsDiff(output string, root string) []api.InstanceSnapshotDiff {
+	entries := []api.InstanceSnapshotDiff{}
+
+	add := func(path string, changeType string) {
+		name, err := filepath.Rel(root, zfsUnescape(path))
+		if err != nil || name == "." || strings.HasPrefix(name, "../") {
+			return
+		}
+
+		entries = append(entries, api.InstanceSnapshotDiff{Path: name, Type: changeType})
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+
+		switch fields[0] {
+		case "+":
+			add(fields[2], "added")
+		case "-":
+			add(fields[2], "deleted")
+		case "M":
+			// Skip the changes of directories, which follow the ones of their content.
+			if fields[1] != "/" {
+				add(fields[2], "modified")
+			}
+		case "R":
+			if len(fields) > 3 {
+				add(fields[2], "deleted")
+				add(fields[3], "added")
+			}
+		}
+	}
+
+	return entries
+}
+
+// zfsUnescape decodes the "\0ooo" octal escapes of the paths printed by zfs.
+func zfsUnescape(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 < len(path) {
+			value, err := strconv.ParseUint(path[i+1:i+5], 8, 8)
+			if err == nil {
+				b.WriteByte(byte(value))
+				i += 4
+				continue
+			}
+		}
+
+		b.WriteByte(path[i])
+	}
+
+	return b.String()
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/units"
 )
@@ -557,8 +558,8 @@ func (d *zfs) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, vol
 }
 
 // RefreshVolume updates an existing volume to match the state of another.
-func (d *zfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
-	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, op)
+func (d *zfs) RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error {
+	return genericCopyVolume(d, nil, vol, srcVol, srcSnapshots, true, excludes, op)
 }
 
 // DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then
@@ -653,6 +654,59 @@ func (d *zfs) HasVolume(vol Volume) bool {
 	return d.checkDataset(d.dataset(vol, false))
 }
 
+// ListVolumes returns the volumes found on the storage pool, from its datasets.
+func (d *zfs) ListVolumes() ([]Volume, error) {
+	poolName := d.config["zfs.pool_name"]
+
+	output, err := shared.RunCommand("zfs", "list", "-H", "-o", "name", "-t", "filesystem,volume,snapshot", "-r", poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	vols := []Volume{}
+	for _, dataset := range strings.Split(output, "\n") {
+		// Only keep the "<type>/<name>[@snapshot-<snapshot>]" datasets, skipping the deleted ones.
+		parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(dataset), poolName+"/"), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		volType := VolumeType(parts[0])
+		if volType != VolumeTypeContainer && volType != VolumeTypeVM && volType != VolumeTypeCustom {
+			continue
+		}
+
+		volName := parts[1]
+		if strings.Contains(volName, "@") {
+			fields := strings.SplitN(volName, "@", 2)
+			if !strings.HasPrefix(fields[1], "snapshot-") {
+				continue
+			}
+
+			volName = GetSnapshotVolumeName(fields[0], strings.TrimPrefix(fields[1], "snapshot-"))
+		}
+
+		// Virtual machines are listed through their block volume, their filesystem volume being implied.
+		contentType := ContentTypeFS
+		if volType == VolumeTypeVM {
+			parentName, snapName, isSnap := shared.InstanceGetParentAndSnapshotName(volName)
+			if !strings.HasSuffix(parentName, ".block") {
+				continue
+			}
+
+			contentType = ContentTypeBlock
+			volName = strings.TrimSuffix(parentName, ".block")
+			if isSnap {
+				volName = GetSnapshotVolumeName(volName, snapName)
+			}
+		}
+
+		vols = append(vols, NewVolume(d, d.name, volType, contentType, volName, nil, d.config))
+	}
+
+	return vols, nil
+}
+
 // ValidateVolume validates the supplied volume config.
 func (d *zfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 	rules := map[string]func(value string) error{
@@ -1277,6 +1331,20 @@ func (d *zfs) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, e
 	return snapshots, nil
 }
 
+// DiffVolumeSnapshot returns the files changed between a volume snapshot and a more recent one or the volume.
+func (d *zfs) DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error) {
+	output, err := shared.RunCommand("zfs", "diff", "-H", "-F", d.dataset(snapVol, false), d.dataset(targetVol, false))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to compare %q with %q", snapVol.name, targetVol.name)
+	}
+
+	// Paths are the ones of the files under the mount path of the volume.
+	parentName, _, _ := shared.InstanceGetParentAndSnapshotName(snapVol.name)
+	root := GetVolumeMountPath(d.name, snapVol.volType, parentName)
+
+	return diffFillSizes(parseZfsDiff(output, root), snapVol.MountPath(), targetVol.MountPath()), nil
+}
+
 // RestoreVolume restores a volume from a snapshot.
 func (d *zfs) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
 	snapVol := NewVolume(d, d.name, vol.volType, vol.contentType, fmt.Sprintf("%s/%s", vol.name, snapshotName), vol.config, vol.poolConfig)
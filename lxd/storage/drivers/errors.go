@@ -13,6 +13,9 @@ var ErrUnknownDriver = fmt.Errorf("Unknown driver")
 // ErrNotSupported is the "Not supported" error
 var ErrNotSupported = fmt.Errorf("Not supported")
 
+// ErrInUse is the "In use" error, returned when unmounting a volume still used by something else
+var ErrInUse = fmt.Errorf("In use")
+
 // ErrDeleteSnapshots is a special error used to tell the backend to delete more recent snapshots
 type ErrDeleteSnapshots struct {
 	Snapshots []string
@@ -3,6 +3,12 @@ package drivers
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/migration"
 	"github.com/lxc/lxd/lxd/operations"
@@ -15,7 +21,8 @@ import (
 
 // genericCopyVolume copies a volume and its snapshots using a non-optimized method.
 // initVolume is run against the main volume (not the snapshots) and is often used for quota initialization.
-func genericCopyVolume(d Driver, initVolume func(vol Volume) (func(), error), vol Volume, srcVol Volume, srcSnapshots []Volume, refresh bool, op *operations.Operation) error {
+// Paths matching the excludes rsync patterns are left out of the copy.
+func genericCopyVolume(d Driver, initVolume func(vol Volume) (func(), error), vol Volume, srcVol Volume, srcSnapshots []Volume, refresh bool, excludes []string, op *operations.Operation) error {
 	if vol.contentType != srcVol.contentType {
 		return fmt.Errorf("Content type of source and target must be the same")
 	}
@@ -45,7 +52,7 @@ func genericCopyVolume(d Driver, initVolume func(vol Volume) (func(), error), vo
 				// Mount the source snapshot.
 				err := srcSnapshot.MountTask(func(srcMountPath string, op *operations.Operation) error {
 					// Copy the snapshot.
-					_, err := rsync.LocalCopy(srcMountPath, mountPath, bwlimit, true)
+					_, err := rsync.LocalCopy(srcMountPath, mountPath, bwlimit, true, excludes...)
 					if err != nil {
 						return err
 					}
@@ -99,7 +106,7 @@ func genericCopyVolume(d Driver, initVolume func(vol Volume) (func(), error), vo
 
 		// Copy source to destination (mounting each volume if needed).
 		err := srcVol.MountTask(func(srcMountPath string, op *operations.Operation) error {
-			_, err := rsync.LocalCopy(srcMountPath, mountPath, bwlimit, true)
+			_, err := rsync.LocalCopy(srcMountPath, mountPath, bwlimit, true, excludes...)
 			if err != nil {
 				return err
 			}
@@ -357,3 +364,61 @@ func genericBackupUnpack(d Driver, vol Volume, snapshots []string, srcData io.Re
 	revert.Success()
 	return postHook, revertExternal.Fail, nil
 }
+
+// genericVFSListVolumes returns the instance and custom volumes, and their snapshots, found in the
+// directories of the pool's mount path. Virtual machine volumes are returned as block volumes.
+func genericVFSListVolumes(d Driver) ([]Volume, error) {
+	vols := []Volume{}
+
+	for _, volType := range []VolumeType{VolumeTypeContainer, VolumeTypeVM, VolumeTypeCustom} {
+		contentType := ContentTypeFS
+		if volType == VolumeTypeVM {
+			contentType = ContentTypeBlock
+		}
+
+		volsPath := filepath.Join(GetPoolMountPath(d.Name()), string(volType))
+		ents, err := ioutil.ReadDir(volsPath)
+		if err != nil {
+			// Pools only having some of the volume types don't have the others' directories.
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "Failed to list directory '%s'", volsPath)
+		}
+
+		for _, ent := range ents {
+			if !ent.IsDir() || strings.HasSuffix(ent.Name(), tmpVolSuffix) {
+				continue
+			}
+
+			vol := NewVolume(d, d.Name(), volType, contentType, ent.Name(), nil, d.Config())
+			vols = append(vols, vol)
+
+			snapshotDir := GetVolumeSnapshotDir(d.Name(), volType, ent.Name())
+			snapEnts, err := ioutil.ReadDir(snapshotDir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+
+				return nil, errors.Wrapf(err, "Failed to list directory '%s'", snapshotDir)
+			}
+
+			for _, snapEnt := range snapEnts {
+				if !snapEnt.IsDir() {
+					continue
+				}
+
+				snapVol, err := vol.NewSnapshot(snapEnt.Name())
+				if err != nil {
+					return nil, err
+				}
+
+				vols = append(vols, snapVol)
+			}
+		}
+	}
+
+	return vols, nil
+}
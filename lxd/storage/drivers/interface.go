@@ -24,6 +24,9 @@ type Driver interface {
 	Info() Info
 	HasVolume(vol Volume) bool
 
+	// Encrypted returns whether the data of the pool is encrypted at rest by the driver.
+	Encrypted() bool
+
 	// Export struct details.
 	Name() string
 	Config() map[string]string
@@ -43,10 +46,13 @@ type Driver interface {
 	ApplyPatch(name string) error
 
 	// Volumes.
+	// ListVolumes returns the instance and custom volumes found on the storage pool, including
+	// their snapshots, regardless of them being known to the database.
+	ListVolumes() ([]Volume, error)
 	ValidateVolume(vol Volume, removeUnknownKeys bool) error
 	CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error
 	CreateVolumeFromCopy(vol Volume, srcVol Volume, copySnapshots bool, op *operations.Operation) error
-	RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error
+	RefreshVolume(vol Volume, srcVol Volume, srcSnapshots []Volume, excludes []string, op *operations.Operation) error
 	DeleteVolume(vol Volume, op *operations.Operation) error
 	RenameVolume(vol Volume, newName string, op *operations.Operation) error
 	UpdateVolume(vol Volume, changedConfig map[string]string) error
@@ -76,6 +82,10 @@ type Driver interface {
 	VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, error)
 	RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error
 
+	// DiffVolumeSnapshot returns the files changed between a volume snapshot and targetVol, a more
+	// recent snapshot or the volume itself, with paths relative to the volume. Both must be mounted.
+	DiffVolumeSnapshot(snapVol Volume, targetVol Volume, op *operations.Operation) ([]api.InstanceSnapshotDiff, error)
+
 	// Migration.
 	MigrationTypes(contentType ContentType, refresh bool) []migration.Type
 	MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error
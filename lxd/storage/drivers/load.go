@@ -35,7 +35,7 @@ func Load(state *state.State, driverName string, name string, config map[string]
 		return nil, err
 	}
 
-	return d, nil
+	return &traced{Driver: d}, nil
 }
 
 // SupportedDrivers returns a list of supported storage drivers.
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/units"
 )
 
@@ -563,3 +565,96 @@ func copyDevice(inputPath, outputPath string) error {
 func loopFilePath(poolName string) string {
 	return filepath.Join(shared.VarPath("disks"), fmt.Sprintf("%s.img", poolName))
 }
+
+// parseRsyncDiff returns the file changes listed by a dry run of rsync with the "%i %n" output
+// format, syncing the more recent tree onto the older one.
+func parseRsyncDiff(output string) []api.InstanceSnapshotDiff {
+	entries := []api.InstanceSnapshotDiff{}
+	for _, line := range strings.Split(output, "\n") {
+		// Lines are made of the 11 characters of the itemized changes and the file name.
+		if len(line) < 13 {
+			continue
+		}
+
+		item := line[:11]
+		name := strings.TrimSuffix(line[12:], "/")
+		if name == "." {
+			continue
+		}
+
+		entry := api.InstanceSnapshotDiff{Path: name}
+		if strings.HasPrefix(item, "*deleting") {
+			entry.Type = "deleted"
+		} else if strings.Trim(item[2:], "+") == "" {
+			entry.Type = "added"
+		} else if item[1] == 'd' {
+			// Skip the attribute changes of directories.
+			continue
+		} else {
+			entry.Type = "modified"
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// diffWalk adds the files of newRoot missing from oldRoot to entries as added, and the ones of
+// oldRoot missing from newRoot as deleted. Files of newRoot in changed are added as modified.
+func diffWalk(oldRoot string, newRoot string, changed map[string]bool) ([]api.InstanceSnapshotDiff, error) {
+	entries := []api.InstanceSnapshotDiff{}
+
+	walk := func(root string, other string, missingType string, changed map[string]bool) error {
+		return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			name, err := filepath.Rel(root, path)
+			if err != nil || name == "." {
+				return err
+			}
+
+			if !shared.PathExists(filepath.Join(other, name)) {
+				entries = append(entries, api.InstanceSnapshotDiff{Path: name, Type: missingType})
+			} else if changed[name] {
+				entries = append(entries, api.InstanceSnapshotDiff{Path: name, Type: "modified"})
+			}
+
+			return nil
+		})
+	}
+
+	err := walk(newRoot, oldRoot, "added", changed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walk(oldRoot, newRoot, "deleted", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// diffFillSizes sets the size of the changed files, as found in newRoot or in oldRoot for deleted
+// ones, and sorts them by path.
+func diffFillSizes(entries []api.InstanceSnapshotDiff, oldRoot string, newRoot string) []api.InstanceSnapshotDiff {
+	for i := range entries {
+		root := newRoot
+		if entries[i].Type == "deleted" {
+			root = oldRoot
+		}
+
+		info, err := os.Lstat(filepath.Join(root, entries[i].Path))
+		if err == nil && !info.IsDir() {
+			entries[i].Size = info.Size()
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/shared/api"
 )
 
 // Test GetVolumeMountPath
@@ -35,3 +37,39 @@ func TestGetVolumeMountPath(t *testing.T) {
 	expected = GetPoolMountPath(poolName) + "/virtual-machines/testvol"
 	assert.Equal(t, expected, path)
 }
+
+func TestParseRsyncDiff(t *testing.T) {
+	output := `.d..t...... ./
+>f+++++++++ rootfs/root/new file
+cd+++++++++ rootfs/root/dir/
+>f.st...... rootfs/etc/hostname
+.d..t...... rootfs/etc/
+*deleting   rootfs/tmp/old
+`
+
+	entries := parseRsyncDiff(output)
+	assert.Equal(t, []api.InstanceSnapshotDiff{
+		{Path: "rootfs/root/new file", Type: "added"},
+		{Path: "rootfs/root/dir", Type: "added"},
+		{Path: "rootfs/etc/hostname", Type: "modified"},
+		{Path: "rootfs/tmp/old", Type: "deleted"},
+	}, entries)
+}
+
+func TestParseZfsDiff(t *testing.T) {
+	root := "/var/lib/lxd/storage-pools/default/containers/c1"
+	output := "M\t/\t" + root + "/rootfs/etc\n" +
+		"M\tF\t" + root + "/rootfs/etc/hostname\n" +
+		"+\tF\t" + root + "/rootfs/root/new\\0040file\n" +
+		"-\tF\t" + root + "/rootfs/tmp/old\n" +
+		"R\tF\t" + root + "/rootfs/a\t" + root + "/rootfs/b\n"
+
+	entries := parseZfsDiff(output, root)
+	assert.Equal(t, []api.InstanceSnapshotDiff{
+		{Path: "rootfs/etc/hostname", Type: "modified"},
+		{Path: "rootfs/root/new file", Type: "added"},
+		{Path: "rootfs/tmp/old", Type: "deleted"},
+		{Path: "rootfs/a", Type: "deleted"},
+		{Path: "rootfs/b", Type: "added"},
+	}, entries)
+}
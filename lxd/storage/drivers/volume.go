@@ -3,6 +3,7 @@ package drivers
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -59,6 +60,19 @@ var BaseDirectories = map[VolumeType][]string{
 	VolumeTypeVM:        {"virtual-machines", "virtual-machines-snapshots"},
 }
 
+// mountRef holds the users of a mounted volume.
+type mountRef struct {
+	users uint // Number of operations and devices currently using the mount.
+	held  bool // Whether the instance owning the volume keeps it mounted.
+}
+
+// mountRefs holds the users of the volumes mounted by LXD, keyed by pool, type and name.
+// Note that any access to this map must be done while holding mountRefsLock.
+var mountRefs = map[string]*mountRef{}
+
+// mountRefsLock is used to access mountRefs.
+var mountRefsLock sync.Mutex
+
 // Volume represents a storage volume, and provides functions to mount and unmount it.
 type Volume struct {
 	name        string
@@ -149,7 +163,8 @@ func (v Volume) EnsureMountPath() error {
 }
 
 // MountTask runs the supplied task after mounting the volume if needed. If the volume was mounted
-// for this then it is unmounted when the task finishes.
+// for this, or if its other users are gone by then, it is unmounted when the task finishes, unless
+// something else started using it in the meantime.
 func (v Volume) MountTask(task func(mountPath string, op *operations.Operation) error, op *operations.Operation) error {
 	isSnap := v.IsSnapshot()
 
@@ -182,22 +197,27 @@ func (v Volume) MountTask(task func(mountPath string, op *operations.Operation)
 			return err
 		}
 
+		// If the mount was in use, its users may all be gone by the end of the task, in which
+		// case they left the volume mounted for the task and it's up to it to unmount it.
+		wasInUse := v.MountInUse()
+		v.MountRefCountIncrement()
 		unlock()
 
-		if ourMount {
-			defer func() {
-				unlock := locking.Lock(v.pool, string(v.volType), v.name)
+		defer func() {
+			unlock := locking.Lock(v.pool, string(v.volType), v.name)
+			v.MountRefCountDecrement()
+			if (ourMount || wasInUse) && !v.MountInUse() {
 				v.driver.UnmountVolume(v, op)
-				unlock()
-			}()
-		}
+			}
+			unlock()
+		}()
 	}
 
 	return task(v.MountPath(), op)
 }
 
 // UnmountTask runs the supplied task after unmounting the volume if needed. If the volume was unmounted
-// for this then it is mounted when the task finishes.
+// for this then it is mounted when the task finishes. Fails with ErrInUse if the volume is in use.
 func (v Volume) UnmountTask(task func(op *operations.Operation) error, op *operations.Operation) error {
 	isSnap := v.IsSnapshot()
 
@@ -224,6 +244,12 @@ func (v Volume) UnmountTask(task func(op *operations.Operation) error, op *opera
 	} else {
 		unlock := locking.Lock(v.pool, string(v.volType), v.name)
 
+		// Don't pull the volume from under its users.
+		if v.MountInUse() {
+			unlock()
+			return ErrInUse
+		}
+
 		ourUnmount, err := v.driver.UnmountVolume(v, op)
 		if err != nil {
 			unlock()
@@ -244,6 +270,157 @@ func (v Volume) UnmountTask(task func(op *operations.Operation) error, op *opera
 	return task(op)
 }
 
+// Mount mounts the volume if needed and returns whether it got mounted by this call. In that case,
+// the caller is recorded as a user of the mount and must call Unmount once done with it. A volume
+// which is already mounted, such as the one of a running instance, is left as is.
+func (v Volume) Mount(op *operations.Operation) (bool, error) {
+	unlock := locking.Lock(v.pool, string(v.volType), v.name)
+	defer unlock()
+
+	ourMount, err := v.driver.MountVolume(v, op)
+	if err != nil {
+		return false, err
+	}
+
+	if ourMount {
+		v.MountRefCountIncrement()
+	}
+
+	return ourMount, nil
+}
+
+// Unmount releases a user of the volume's mount recorded by Mount and unmounts the volume, unless
+// something else still uses it or its instance holds it. Returns whether the volume was unmounted.
+func (v Volume) Unmount(op *operations.Operation) (bool, error) {
+	unlock := locking.Lock(v.pool, string(v.volType), v.name)
+	defer unlock()
+
+	v.MountRefCountDecrement()
+	if v.MountInUse() {
+		return false, nil
+	}
+
+	return v.driver.UnmountVolume(v, op)
+}
+
+// MountAndHold mounts the volume if needed and holds its mount for the instance owning it, until
+// ReleaseAndUnmount is called.
+func (v Volume) MountAndHold(op *operations.Operation) error {
+	unlock := locking.Lock(v.pool, string(v.volType), v.name)
+	defer unlock()
+
+	_, err := v.driver.MountVolume(v, op)
+	if err != nil {
+		return err
+	}
+
+	v.MountHold()
+
+	return nil
+}
+
+// ReleaseAndUnmount releases the hold of the instance owning the volume on its mount and unmounts
+// the volume, unless something else still uses it. Returns whether the volume was unmounted.
+func (v Volume) ReleaseAndUnmount(op *operations.Operation) (bool, error) {
+	unlock := locking.Lock(v.pool, string(v.volType), v.name)
+	defer unlock()
+
+	v.MountRelease()
+	if v.MountInUse() {
+		return false, nil
+	}
+
+	return v.driver.UnmountVolume(v, op)
+}
+
+// mountRefID returns the key of the volume in mountRefs.
+func (v Volume) mountRefID() string {
+	return fmt.Sprintf("%s/%s/%s", v.pool, v.volType, v.name)
+}
+
+// MountRefCountIncrement records a new user of the volume's mount, such as an operation or an
+// attached device, and returns the number of users.
+func (v Volume) MountRefCountIncrement() uint {
+	mountRefsLock.Lock()
+	defer mountRefsLock.Unlock()
+
+	ref, ok := mountRefs[v.mountRefID()]
+	if !ok {
+		ref = &mountRef{}
+		mountRefs[v.mountRefID()] = ref
+	}
+
+	ref.users++
+	return ref.users
+}
+
+// MountRefCountDecrement releases a user of the volume's mount and returns the number of
+// remaining users.
+func (v Volume) MountRefCountDecrement() uint {
+	mountRefsLock.Lock()
+	defer mountRefsLock.Unlock()
+
+	ref, ok := mountRefs[v.mountRefID()]
+	if !ok {
+		return 0
+	}
+
+	if ref.users > 0 {
+		ref.users--
+	}
+
+	users := ref.users
+	if users == 0 && !ref.held {
+		delete(mountRefs, v.mountRefID())
+	}
+
+	return users
+}
+
+// MountHold records that the instance owning the volume keeps it mounted, until MountRelease is
+// called. Holding is idempotent, so the instance can mount its volume several times.
+func (v Volume) MountHold() {
+	mountRefsLock.Lock()
+	defer mountRefsLock.Unlock()
+
+	ref, ok := mountRefs[v.mountRefID()]
+	if !ok {
+		ref = &mountRef{}
+		mountRefs[v.mountRefID()] = ref
+	}
+
+	ref.held = true
+}
+
+// MountRelease records that the instance owning the volume doesn't need it mounted anymore.
+func (v Volume) MountRelease() {
+	mountRefsLock.Lock()
+	defer mountRefsLock.Unlock()
+
+	ref, ok := mountRefs[v.mountRefID()]
+	if !ok {
+		return
+	}
+
+	ref.held = false
+	if ref.users == 0 {
+		delete(mountRefs, v.mountRefID())
+	}
+}
+
+// MountInUse returns whether the volume's mount is used by an operation, a device or its instance.
+func (v Volume) MountInUse() bool {
+	mountRefsLock.Lock()
+	defer mountRefsLock.Unlock()
+
+	ref, ok := mountRefs[v.mountRefID()]
+	if !ok {
+		return false
+	}
+
+	return ref.users > 0 || ref.held
+}
+
 // Snapshots returns a list of snapshots for the volume.
 func (v Volume) Snapshots(op *operations.Operation) ([]Volume, error) {
 	if v.IsSnapshot() {
@@ -0,0 +1,117 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/operations"
+)
+
+// Test the mount reference counting
+func TestVolumeMountRefs(t *testing.T) {
+	vol := NewVolume(nil, "testpool", VolumeTypeCustom, ContentTypeFS, "testvol", nil, nil)
+	other := NewVolume(nil, "testpool", VolumeTypeContainer, ContentTypeFS, "testvol", nil, nil)
+
+	assert.False(t, vol.MountInUse())
+
+	// Users are counted.
+	assert.Equal(t, uint(1), vol.MountRefCountIncrement())
+	assert.Equal(t, uint(2), vol.MountRefCountIncrement())
+	assert.True(t, vol.MountInUse())
+	assert.False(t, other.MountInUse())
+
+	assert.Equal(t, uint(1), vol.MountRefCountDecrement())
+	assert.True(t, vol.MountInUse())
+	assert.Equal(t, uint(0), vol.MountRefCountDecrement())
+	assert.False(t, vol.MountInUse())
+
+	// Releasing an unused volume doesn't underflow.
+	assert.Equal(t, uint(0), vol.MountRefCountDecrement())
+
+	// Holding is idempotent and independent from the users.
+	vol.MountHold()
+	vol.MountHold()
+	vol.MountRefCountIncrement()
+	vol.MountRelease()
+	assert.True(t, vol.MountInUse())
+	vol.MountRefCountDecrement()
+	assert.False(t, vol.MountInUse())
+	assert.Empty(t, mountRefs)
+}
+
+// mountDriver is a driver which only tracks whether its volume is mounted.
+type mountDriver struct {
+	Driver
+	mounted bool
+}
+
+func (d *mountDriver) MountVolume(vol Volume, op *operations.Operation) (bool, error) {
+	if d.mounted {
+		return false, nil
+	}
+
+	d.mounted = true
+	return true, nil
+}
+
+func (d *mountDriver) UnmountVolume(vol Volume, op *operations.Operation) (bool, error) {
+	if !d.mounted {
+		return false, nil
+	}
+
+	d.mounted = false
+	return true, nil
+}
+
+// Test that operations don't unmount the volume of a running instance
+func TestVolumeMountHeld(t *testing.T) {
+	driver := &mountDriver{}
+	vol := NewVolume(driver, "testpool", VolumeTypeContainer, ContentTypeFS, "testvol", nil, nil)
+
+	// The instance starts and holds its volume.
+	assert.NoError(t, vol.MountAndHold(nil))
+	assert.True(t, driver.mounted)
+
+	// An operation doesn't take over the existing mount.
+	ourMount, err := vol.Mount(nil)
+	assert.NoError(t, err)
+	assert.False(t, ourMount)
+
+	// Even if it unmounts the volume, it stays mounted for the instance.
+	unmounted, err := vol.Unmount(nil)
+	assert.NoError(t, err)
+	assert.False(t, unmounted)
+	assert.True(t, driver.mounted)
+
+	// The instance stops and its volume gets unmounted.
+	unmounted, err = vol.ReleaseAndUnmount(nil)
+	assert.NoError(t, err)
+	assert.True(t, unmounted)
+	assert.False(t, driver.mounted)
+
+	// The instance starts while an operation uses its volume, which stays mounted once the
+	// operation is done.
+	ourMount, err = vol.Mount(nil)
+	assert.NoError(t, err)
+	assert.True(t, ourMount)
+	assert.NoError(t, vol.MountAndHold(nil))
+
+	unmounted, err = vol.Unmount(nil)
+	assert.NoError(t, err)
+	assert.False(t, unmounted)
+	assert.True(t, driver.mounted)
+
+	// The instance stops while an operation uses its volume, which stays mounted until the
+	// operation is done.
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		unmounted, err := vol.ReleaseAndUnmount(nil)
+		assert.NoError(t, err)
+		assert.False(t, unmounted)
+		assert.True(t, driver.mounted)
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.False(t, driver.mounted)
+	assert.Empty(t, mountRefs)
+}
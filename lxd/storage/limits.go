@@ -0,0 +1,429 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/db"
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// ProjectDiskUsage returns the disk space accounted to the volumes of a project against its
+// limits.disk, across all pools. Instance and custom volumes account for their size, which they
+// must have. Snapshots account for the size of their volume, unless their pool thinly provisions
+// them in which case the space they use is measured, which can only be done for the snapshots of
+// this member and of remote pools.
+func ProjectDiskUsage(s *state.State, projectName string) (int64, error) {
+	return projectDiskUsage(s, projectName, "", -1, "")
+}
+
+// projectDiskUsage returns the disk space accounted to the volumes of a project, leaving out the
+// volume with the given pool, type and name.
+func projectDiskUsage(s *state.State, projectName string, skipPool string, skipType int, skipName string) (int64, error) {
+	var volumes []db.ProjectStorageVolume
+	var rootDisks map[string]map[string]string
+	var nodeName string
+
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+
+		volumes, err = tx.ProjectStorageVolumes(projectName)
+		if err != nil {
+			return err
+		}
+
+		rootDisks, err = projectRootDisks(tx, projectName)
+		if err != nil {
+			return err
+		}
+
+		nodeName, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	pools := map[string]*api.StoragePool{}
+	loadPool := func(name string) (*api.StoragePool, error) {
+		pool, ok := pools[name]
+		if !ok {
+			var err error
+			_, pool, err = s.Cluster.StoragePoolGet(name)
+			if err != nil {
+				return nil, err
+			}
+
+			pools[name] = pool
+		}
+
+		return pool, nil
+	}
+
+	key := func(pool string, volType int, name string) string {
+		return fmt.Sprintf("%s/%d/%s", pool, volType, name)
+	}
+
+	// Volumes of remote pools are listed once per member.
+	seen := map[string]bool{}
+
+	// The size of the volumes first, which the snapshots of thick pools take.
+	usage := int64(0)
+	sizes := map[string]int64{}
+	for _, vol := range volumes {
+		k := key(vol.Pool, vol.Type, vol.Name)
+		if vol.Snapshot || vol.Type == db.StoragePoolVolumeTypeImage || seen[k] {
+			continue
+		}
+
+		seen[k] = true
+
+		pool, err := loadPool(vol.Pool)
+		if err != nil {
+			return -1, err
+		}
+
+		size := projectVolumeSize(vol, rootDisks, pool)
+		if size == "" {
+			return -1, fmt.Errorf("Volume %q in pool %q has no size, which projects with limits.disk require", vol.Name, vol.Pool)
+		}
+
+		bytes, err := units.ParseByteSizeString(size)
+		if err != nil {
+			return -1, err
+		}
+
+		sizes[k] = bytes
+
+		if vol.Pool == skipPool && vol.Type == skipType && vol.Name == skipName {
+			continue
+		}
+
+		usage += bytes
+	}
+
+	for _, vol := range volumes {
+		k := key(vol.Pool, vol.Type, vol.Name)
+		if !vol.Snapshot || seen[k] {
+			continue
+		}
+
+		seen[k] = true
+
+		if vol.Pool == skipPool && vol.Type == skipType && vol.Name == skipName {
+			continue
+		}
+
+		parentName := strings.SplitN(vol.Name, shared.SnapshotDelimiter, 2)[0]
+		parentSize := sizes[key(vol.Pool, vol.Type, parentName)]
+
+		pool, err := loadPool(vol.Pool)
+		if err != nil {
+			return -1, err
+		}
+
+		if !thinSnapshots(pool) {
+			usage += parentSize
+			continue
+		}
+
+		if vol.Node != nodeName && !shared.StringInSlice(pool.Driver, []string{"ceph", "cephfs"}) {
+			continue
+		}
+
+		// Snapshots being created don't exist on storage yet.
+		used, err := projectVolumeUsage(s, projectName, pool.Name, vol)
+		if err != nil {
+			logger.Debug("Failed to measure snapshot usage", log.Ctx{"project": projectName, "pool": vol.Pool, "volume": vol.Name, "err": err})
+			continue
+		}
+
+		usage += used
+	}
+
+	return usage, nil
+}
+
+// projectDiskLimit returns the limits.disk of a project in bytes, or -1 if it has none.
+func projectDiskLimit(s *state.State, projectName string) (int64, error) {
+	var p *api.Project
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		p, err = tx.ProjectGet(projectName)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	if p.Config["limits.disk"] == "" {
+		return -1, nil
+	}
+
+	return units.ParseByteSizeString(p.Config["limits.disk"])
+}
+
+// projectRootDisks returns the root disk devices of the instances of a project, by instance name.
+func projectRootDisks(tx *db.ClusterTx, projectName string) (map[string]map[string]string, error) {
+	instances, err := tx.InstanceList(db.InstanceFilter{Project: projectName, Type: instancetype.Any})
+	if err != nil {
+		return nil, err
+	}
+
+	profilesProject := projectName
+	enabled, err := tx.ProjectHasProfiles(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		profilesProject = "default"
+	}
+
+	profiles, err := tx.ProfileList(db.ProfileFilter{Project: profilesProject})
+	if err != nil {
+		return nil, err
+	}
+
+	profilesByName := make(map[string]api.Profile, len(profiles))
+	for i := range profiles {
+		profilesByName[profiles[i].Name] = *db.ProfileToAPI(&profiles[i])
+	}
+
+	rootDisks := make(map[string]map[string]string, len(instances))
+	for _, inst := range instances {
+		instProfiles := make([]api.Profile, 0, len(inst.Profiles))
+		for _, name := range inst.Profiles {
+			profile, ok := profilesByName[name]
+			if ok {
+				instProfiles = append(instProfiles, profile)
+			}
+		}
+
+		devices := db.ProfilesExpandDevices(deviceConfig.NewDevices(inst.Devices), instProfiles)
+		_, rootDisk, err := shared.GetRootDiskDevice(devices.CloneNative())
+		if err != nil {
+			continue
+		}
+
+		rootDisks[inst.Name] = rootDisk
+	}
+
+	return rootDisks, nil
+}
+
+// projectVolumeSize returns the size of a volume: the one of the root disk for instance volumes,
+// the one in its config for the others, and the pool's default otherwise.
+func projectVolumeSize(vol db.ProjectStorageVolume, rootDisks map[string]map[string]string, pool *api.StoragePool) string {
+	size := ""
+
+	if vol.Type == db.StoragePoolVolumeTypeContainer || vol.Type == db.StoragePoolVolumeTypeVM {
+		rootDisk := rootDisks[vol.Name]
+		if rootDisk != nil && rootDisk["pool"] == vol.Pool {
+			size = rootDisk["size"]
+		}
+	}
+
+	if size == "" {
+		size = vol.Config["size"]
+	}
+
+	if size == "" {
+		size = pool.Config["volume.size"]
+	}
+
+	return size
+}
+
+// thinSnapshots returns whether the snapshots of a pool only use the space of the data they don't
+// share with their volume.
+func thinSnapshots(pool *api.StoragePool) bool {
+	switch pool.Driver {
+	case "btrfs", "ceph", "cephfs", "zfs":
+		return true
+	case "lvm":
+		return pool.Config["lvm.use_thinpool"] == "" || shared.IsTrue(pool.Config["lvm.use_thinpool"])
+	}
+
+	return false
+}
+
+// ProjectStorageUsage returns the space used by the volumes and snapshots of a project stored on
+// this member. Those of remote pools are only measured when remote is true, for a single member
+// to account for them. Volumes whose space can't be measured are left out. The usage.alerts
+// thresholds of the measured volumes are checked along the way.
+func ProjectStorageUsage(s *state.State, projectName string, remote bool) (int64, error) {
+	var volumes []db.ProjectStorageVolume
+	var rootDisks map[string]map[string]string
+	var nodeName string
+
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+
+		volumes, err = tx.ProjectStorageVolumes(projectName)
+		if err != nil {
+			return err
+		}
+
+		rootDisks, err = projectRootDisks(tx, projectName)
+		if err != nil {
+			return err
+		}
+
+		nodeName, err = tx.NodeName()
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	pools := map[string]*api.StoragePool{}
+	seen := map[string]bool{}
+	usage := int64(0)
+
+	for _, vol := range volumes {
+		if vol.Type == db.StoragePoolVolumeTypeImage {
+			continue
+		}
+
+		pool, ok := pools[vol.Pool]
+		if !ok {
+			_, pool, err = s.Cluster.StoragePoolGet(vol.Pool)
+			if err != nil {
+				return -1, err
+			}
+
+			pools[vol.Pool] = pool
+		}
+
+		if shared.StringInSlice(pool.Driver, []string{"ceph", "cephfs"}) {
+			// Volumes of remote pools are listed once per member.
+			key := fmt.Sprintf("%s/%d/%s", vol.Pool, vol.Type, vol.Name)
+			if !remote || seen[key] {
+				continue
+			}
+
+			seen[key] = true
+		} else if vol.Node != nodeName {
+			continue
+		}
+
+		used, err := projectVolumeUsage(s, projectName, pool.Name, vol)
+		if err != nil {
+			logger.Debug("Failed to measure volume usage", log.Ctx{"project": projectName, "pool": vol.Pool, "volume": vol.Name, "err": err})
+			continue
+		}
+
+		usage += used
+
+		if !vol.Snapshot {
+			b, err := GetPoolByName(s, vol.Pool)
+			if err == nil {
+				backend, ok := b.(*lxdBackend)
+				if ok {
+					checkVolumeUsageAlerts(s, projectName, backend, vol, projectVolumeSize(vol, rootDisks, pool), used)
+				}
+			}
+		}
+	}
+
+	return usage, nil
+}
+
+// projectVolumeUsage measures the space used by a volume or snapshot.
+func projectVolumeUsage(s *state.State, projectName string, poolName string, vol db.ProjectStorageVolume) (int64, error) {
+	pool, err := GetPoolByName(s, poolName)
+	if err != nil {
+		return -1, err
+	}
+
+	b, ok := pool.(*lxdBackend)
+	if !ok {
+		return -1, drivers.ErrNotSupported
+	}
+
+	var volType drivers.VolumeType
+	contentType := drivers.ContentTypeFS
+	switch vol.Type {
+	case db.StoragePoolVolumeTypeContainer:
+		volType = drivers.VolumeTypeContainer
+	case db.StoragePoolVolumeTypeVM:
+		volType = drivers.VolumeTypeVM
+		contentType = drivers.ContentTypeBlock
+	case db.StoragePoolVolumeTypeCustom:
+		volType = drivers.VolumeTypeCustom
+	default:
+		return -1, drivers.ErrNotSupported
+	}
+
+	volStorageName := project.Prefix(projectName, vol.Name)
+
+	return b.driver.GetVolumeUsage(b.newVolume(volType, contentType, volStorageName, vol.Config))
+}
+
+// checkProjectDiskLimit checks that a volume of a project fits in the project's limits.disk, on
+// top of its other volumes. Snapshots of thick pools take the given size of their volume, while
+// new ones of thin pools don't use any space yet. Volumes without a size take the pool's default.
+func (b *lxdBackend) checkProjectDiskLimit(projectName string, volType int, volName string, snapshot bool, size string) error {
+	limit, err := projectDiskLimit(b.state, projectName)
+	if err != nil {
+		return err
+	}
+
+	if limit < 0 {
+		return nil
+	}
+
+	added := int64(0)
+	if !snapshot || !thinSnapshots(&b.db) {
+		if size == "" {
+			size = b.db.Config["volume.size"]
+		}
+
+		if size == "" {
+			return fmt.Errorf("Volumes of project %q must have a size as it has limits.disk set", projectName)
+		}
+
+		added, err = units.ParseByteSizeString(size)
+		if err != nil {
+			return err
+		}
+	}
+
+	usage, err := projectDiskUsage(b.state, projectName, b.name, volType, volName)
+	if err != nil {
+		return err
+	}
+
+	if usage+added > limit {
+		return fmt.Errorf("Project %q would use %s out of its limits.disk of %s", projectName, units.GetByteSizeString(usage+added, 2), units.GetByteSizeString(limit, 2))
+	}
+
+	return nil
+}
+
+// checkInstanceDiskLimit checks that the root volume of an instance, or its snapshot, fits in the
+// limits.disk of its project with the given size.
+func (b *lxdBackend) checkInstanceDiskLimit(inst instance.Instance, size string) error {
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	return b.checkProjectDiskLimit(inst.Project(), volDBType, inst.Name(), inst.IsSnapshot(), size)
+}
@@ -163,6 +163,49 @@ func GetPoolByName(state *state.State, name string) (Pool, error) {
 	return &pool, nil
 }
 
+// GetPoolByInfo returns a Pool interface for an existing storage pool which has no database record, using
+// the supplied pool info instead. This is used to recover the volumes of a pool after the database was lost.
+// The pool's ID is -1, so the driver can't look up the volume IDs.
+// If the pool's driver is not recognised then drivers.ErrUnknownDriver is returned.
+func GetPoolByInfo(state *state.State, dbPool *api.StoragePoolsPost) (Pool, error) {
+	// Sanity checks.
+	if dbPool == nil {
+		return nil, ErrNilValue
+	}
+
+	// Ensure a config map exists.
+	if dbPool.Config == nil {
+		dbPool.Config = map[string]string{}
+	}
+
+	logger := logging.AddContext(logger.Log, log.Ctx{"driver": dbPool.Driver, "pool": dbPool.Name})
+
+	volIDFunc := func(volType drivers.VolumeType, volName string) (int64, error) {
+		return -1, fmt.Errorf("Failed to get volume ID for volume '%s', type '%s': Pool has no database record", volName, volType)
+	}
+
+	// Load the storage driver.
+	driver, err := drivers.Load(state, dbPool.Driver, dbPool.Name, dbPool.Config, logger, volIDFunc, commonRules())
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup the pool struct.
+	pool := lxdBackend{}
+	pool.driver = driver
+	pool.id = -1
+	pool.db = api.StoragePool{
+		StoragePoolPut: dbPool.StoragePoolPut,
+		Name:           dbPool.Name,
+		Driver:         dbPool.Driver,
+	}
+	pool.name = dbPool.Name
+	pool.state = state
+	pool.logger = logger
+
+	return &pool, nil
+}
+
 // GetPoolByInstance retrieves the pool from the database using the instance's pool.
 // If the pool's driver is not recognised then drivers.ErrUnknownDriver is returned. If the pool's
 // driver does not support the instance's type then drivers.ErrNotImplemented is returned.
@@ -0,0 +1,58 @@
+package locking
+
+import (
+	"sync"
+)
+
+// concurrencyLimiter counts the heavy operations running on a pool.
+type concurrencyLimiter struct {
+	running int
+	cond    *sync.Cond
+}
+
+// concurrencyLimiters holds the limiter of each pool.
+// Note that any access to this map or its limiters must be done while holding concurrencyLock.
+var concurrencyLimiters = map[string]*concurrencyLimiter{}
+
+// concurrencyLock is used to access concurrencyLimiters.
+var concurrencyLock sync.Mutex
+
+// AcquireSlot waits until less than limit heavy operations are running on the pool before
+// returning, so that the surplus operations are queued. On success, it returns a release function
+// which needs to be called once the operation is done. A limit of 0 means no limit.
+func AcquireSlot(poolName string, limit int) func() {
+	if limit <= 0 {
+		return func() {}
+	}
+
+	concurrencyLock.Lock()
+	defer concurrencyLock.Unlock()
+
+	limiter, ok := concurrencyLimiters[poolName]
+	if !ok {
+		limiter = &concurrencyLimiter{cond: sync.NewCond(&concurrencyLock)}
+		concurrencyLimiters[poolName] = limiter
+	}
+
+	for limiter.running >= limit {
+		limiter.cond.Wait()
+	}
+
+	limiter.running++
+
+	released := false
+	return func() {
+		concurrencyLock.Lock()
+		defer concurrencyLock.Unlock()
+
+		if released {
+			return
+		}
+
+		released = true
+		limiter.running--
+
+		// Wake all the waiting operations, as they may not all be waiting for the same limit.
+		limiter.cond.Broadcast()
+	}
+}
@@ -0,0 +1,58 @@
+package locking
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireSlot(t *testing.T) {
+	var lock sync.Mutex
+	running := 0
+	maxRunning := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release := AcquireSlot("pool1", 2)
+			defer release()
+
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			lock.Lock()
+			running--
+			lock.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 2, maxRunning)
+
+	// Other pools aren't limited by pool1's operations.
+	release := AcquireSlot("pool1", 1)
+	defer release()
+
+	done := make(chan struct{})
+	go func() {
+		AcquireSlot("pool2", 1)()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Operation on pool2 was queued behind pool1")
+	}
+}
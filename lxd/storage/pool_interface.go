@@ -34,18 +34,22 @@ type Pool interface {
 	CreateInstanceFromImage(inst instance.Instance, fingerprint string, op *operations.Operation) error
 	CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
 	RenameInstance(inst instance.Instance, newName string, op *operations.Operation) error
+	MoveInstanceProject(inst instance.Instance, oldProject string, op *operations.Operation) error
 	DeleteInstance(inst instance.Instance, op *operations.Operation) error
 	UpdateInstance(inst instance.Instance, newDesc string, newConfig map[string]string, op *operations.Operation) error
 	UpdateInstanceBackupFile(inst instance.Instance, op *operations.Operation) error
 
 	MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error
-	RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, op *operations.Operation) error
+	RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, excludes []string, op *operations.Operation) error
+	ResetInstance(inst instance.Instance, fingerprint string, op *operations.Operation) error
 	BackupInstance(inst instance.Instance, targetPath string, optimized bool, snapshots bool, op *operations.Operation) error
 
 	GetInstanceUsage(inst instance.Instance) (int64, error)
 	SetInstanceQuota(inst instance.Instance, size string, op *operations.Operation) error
 
 	MountInstance(inst instance.Instance, op *operations.Operation) (bool, error)
+	HoldInstanceMount(inst instance.Instance, op *operations.Operation) error
+	ReleaseInstanceMount(inst instance.Instance, op *operations.Operation) (bool, error)
 	UnmountInstance(inst instance.Instance, op *operations.Operation) (bool, error)
 	GetInstanceDisk(inst instance.Instance) (string, error)
 
@@ -57,6 +61,7 @@ type Pool interface {
 	MountInstanceSnapshot(inst instance.Instance, op *operations.Operation) (bool, error)
 	UnmountInstanceSnapshot(inst instance.Instance, op *operations.Operation) (bool, error)
 	UpdateInstanceSnapshot(inst instance.Instance, newDesc string, newConfig map[string]string, op *operations.Operation) error
+	DiffInstanceSnapshot(inst instance.Instance, target instance.Instance, op *operations.Operation) ([]api.InstanceSnapshotDiff, error)
 
 	// Images.
 	EnsureImage(fingerprint string, op *operations.Operation) error
@@ -84,4 +89,17 @@ type Pool interface {
 	MigrationTypes(contentType drivers.ContentType, refresh bool) []migration.Type
 	CreateCustomVolumeFromMigration(conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
 	MigrateCustomVolume(conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error
+
+	// Recovery.
+	ListUnknownVolumes(op *operations.Operation) (map[string][]*backup.InstanceConfig, []string, error)
+	ImportInstance(inst instance.Instance, volConfig map[string]string, op *operations.Operation) error
+	ImportCustomVolume(volName string, volConfig map[string]string, op *operations.Operation) error
+
+	// Trash.
+	TrashInstance(inst instance.Instance, op *operations.Operation) error
+	TrashCustomVolume(volName string, op *operations.Operation) error
+	GetTrashInstanceConfig(id int64, op *operations.Operation) (*backup.InstanceConfig, error)
+	RestoreInstanceFromTrash(inst instance.Instance, id int64, op *operations.Operation) error
+	RestoreCustomVolumeFromTrash(id int64, op *operations.Operation) error
+	PurgeTrash(id int64, op *operations.Operation) error
 }
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/state"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// usageAlertLevels records the highest usage.alerts threshold crossed by each volume, so that an
+// alert is only raised when a higher threshold gets crossed.
+var usageAlertLevels = map[string]int{}
+var usageAlertLevelsMu sync.Mutex
+
+// ValidateUsageAlerts validates a comma separated list of usage percentages, such as "80%,95%".
+func ValidateUsageAlerts(value string) error {
+	_, err := parseUsageAlerts(value)
+	return err
+}
+
+// parseUsageAlerts returns the sorted thresholds of a comma separated list of usage percentages.
+func parseUsageAlerts(value string) ([]int, error) {
+	thresholds := []int{}
+	if value == "" {
+		return thresholds, nil
+	}
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSuffix(strings.TrimSpace(field), "%")
+
+		threshold, err := strconv.Atoi(field)
+		if err != nil || threshold <= 0 || threshold > 100 {
+			return nil, fmt.Errorf("Invalid usage threshold %q, expected a percentage between 1%% and 100%%", field)
+		}
+
+		thresholds = append(thresholds, threshold)
+	}
+
+	sort.Ints(thresholds)
+
+	return thresholds, nil
+}
+
+// usageAlertThreshold returns the highest threshold crossed by the used space out of the total,
+// or 0 if none is.
+func usageAlertThreshold(thresholds []int, used int64, total int64) int {
+	if total <= 0 {
+		return 0
+	}
+
+	crossed := 0
+	for _, threshold := range thresholds {
+		if used*100 >= total*int64(threshold) {
+			crossed = threshold
+		}
+	}
+
+	return crossed
+}
+
+// checkVolumeUsageAlerts raises a warning and a lifecycle event when the space used by a volume
+// crosses one of its usage.alerts thresholds, which default to the volume.usage.alerts of its
+// pool. Usage is relative to the size of the volume, or to the pool's space when it has none.
+func checkVolumeUsageAlerts(s *state.State, projectName string, pool *lxdBackend, vol db.ProjectStorageVolume, size string, used int64) {
+	value, ok := vol.Config["usage.alerts"]
+	if !ok {
+		value = pool.db.Config["volume.usage.alerts"]
+	}
+
+	key := fmt.Sprintf("%s/%s/%d/%s", pool.name, projectName, vol.Type, vol.Name)
+
+	thresholds, err := parseUsageAlerts(value)
+	if err != nil || len(thresholds) == 0 {
+		usageAlertLevelsMu.Lock()
+		delete(usageAlertLevels, key)
+		usageAlertLevelsMu.Unlock()
+		return
+	}
+
+	total := int64(0)
+	if size != "" {
+		total, err = units.ParseByteSizeString(size)
+		if err != nil {
+			return
+		}
+	}
+
+	if total <= 0 {
+		res, err := pool.driver.GetResources()
+		if err != nil {
+			logger.Debug("Failed to get pool resources for usage alerts", log.Ctx{"pool": pool.name, "err": err})
+			return
+		}
+
+		total = int64(res.Space.Total)
+	}
+
+	crossed := usageAlertThreshold(thresholds, used, total)
+
+	usageAlertLevelsMu.Lock()
+	previous := usageAlertLevels[key]
+	usageAlertLevels[key] = crossed
+	usageAlertLevelsMu.Unlock()
+
+	// Only alert when going over a higher threshold, not while staying over it.
+	if crossed <= previous {
+		return
+	}
+
+	volTypeName, err := db.StoragePoolVolumeTypeToName(vol.Type)
+	if err != nil {
+		return
+	}
+
+	logger.Warn("Storage volume usage crossed alert threshold", log.Ctx{"project": projectName, "pool": pool.name, "volume": vol.Name, "type": volTypeName, "threshold": fmt.Sprintf("%d%%", crossed), "usage": units.GetByteSizeString(used, 2), "total": units.GetByteSizeString(total, 2)})
+
+	s.Events.SendLifecycle(projectName, "storage-volume-usage-alert",
+		fmt.Sprintf("/1.0/storage-pools/%s/volumes/%s/%s", pool.name, volTypeName, vol.Name),
+		map[string]interface{}{
+			"threshold": crossed,
+			"usage":     used,
+			"total":     total,
+		})
+}
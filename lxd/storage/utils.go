@@ -6,11 +6,15 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/ioprogress"
@@ -32,6 +36,21 @@ func ValidName(value string) error {
 	return nil
 }
 
+// ValidVolumeName validates the name of a custom volume, which can't take the names on storage of
+// the volumes in the trash.
+func ValidVolumeName(value string) error {
+	err := ValidName(value)
+	if err != nil {
+		return err
+	}
+
+	if trashStorageNameRegexp.MatchString(value) {
+		return fmt.Errorf("Invalid storage volume name \"%s\". Names of the form \"trash_<number>\" are reserved for the trash", value)
+	}
+
+	return nil
+}
+
 // ConfigDiff returns a diff of the provided configs. Additionally, it returns whether or not
 // only user properties have been changed.
 func ConfigDiff(oldConfig map[string]string, newConfig map[string]string) ([]string, bool) {
@@ -311,6 +330,15 @@ func VolumeDBCreate(s *state.State, project, poolName, volumeName, volumeDescrip
 		return err
 	}
 
+	// Snapshots and images don't take the project's defaults, the former having the config
+	// of their volume.
+	if !snapshot && volumeType != db.StoragePoolVolumeTypeImage {
+		err = VolumeFillProjectDefault(s, project, volumeConfig, poolStruct)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = VolumeFillDefault(poolName, volumeConfig, poolStruct)
 	if err != nil {
 		return err
@@ -361,6 +389,9 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 
 		return SupportedPoolTypes, nil
 	},
+	"usage.alerts": func(value string) ([]string, error) {
+		return SupportedPoolTypes, ValidateUsageAlerts(value)
+	},
 	"volatile.idmap.last": func(value string) ([]string, error) {
 		return SupportedPoolTypes, shared.IsAny(value)
 	},
@@ -439,6 +470,59 @@ func VolumeValidateConfig(s *state.State, name string, config map[string]string,
 	return nil
 }
 
+// ValidateVolumeDefault validates a volume.* default of a project, which must be a storage volume
+// configuration key applying to at least one driver.
+func ValidateVolumeDefault(key string, value string) error {
+	validator, ok := StorageVolumeConfigKeys[key]
+	if !ok || strings.HasPrefix(key, "volatile.") {
+		return fmt.Errorf("Invalid storage volume configuration key: %s", key)
+	}
+
+	_, err := validator(value)
+	return err
+}
+
+// VolumeFillProjectDefault fills the volume.* defaults of a project into a volume config, leaving
+// out the keys set on the volume and those which don't apply to the pool's driver. It must be
+// called before VolumeFillDefault as the project's defaults take precedence over the pool's ones.
+func VolumeFillProjectDefault(s *state.State, projectName string, config map[string]string, parentPool *api.StoragePool) error {
+	var p *api.Project
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		p, err = tx.ProjectGet(projectName)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, value := range p.Config {
+		if !strings.HasPrefix(key, "volume.") {
+			continue
+		}
+
+		volKey := strings.TrimPrefix(key, "volume.")
+		_, ok := config[volKey]
+		if ok {
+			continue
+		}
+
+		validator, ok := StorageVolumeConfigKeys[volKey]
+		if !ok {
+			continue
+		}
+
+		poolTypes, err := validator(value)
+		if err != nil || !shared.StringInSlice(parentPool.Driver, poolTypes) {
+			continue
+		}
+
+		config[volKey] = value
+	}
+
+	return nil
+}
+
 // VolumeFillDefault fills default settings into a volume config.
 func VolumeFillDefault(name string, config map[string]string, parentPool *api.StoragePool) error {
 	if parentPool.Driver == "lvm" || parentPool.Driver == "ceph" {
@@ -517,6 +601,9 @@ func validatePoolCommonRules() map[string]func(string) error {
 		"volume.size":             shared.IsSize,
 		"size":                    shared.IsSize,
 		"rsync.bwlimit":           shared.IsAny,
+		"operations.concurrency":  shared.IsUint32,
+		"volume.usage.alerts":     ValidateUsageAlerts,
+		"trash.expiry":            ValidateTrashExpiry,
 	}
 }
 
@@ -529,6 +616,8 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 		// Note: size should not be modifiable for non-custom volumes and should be checked
 		// in the relevant volume update functions.
 		"size": shared.IsSize,
+
+		"usage.alerts": ValidateUsageAlerts,
 	}
 
 	// block.mount_options is only relevant for drivers that are block backed and when there
@@ -550,6 +639,20 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 	return rules
 }
 
+// ImageEncryptionKey returns the key the image store is encrypted with, nil if none is configured.
+func ImageEncryptionKey(s *state.State) ([]byte, error) {
+	keyFile, err := cluster.ConfigGetString(s.Cluster, "images.encryption.key_file")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load cluster configuration")
+	}
+
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	return util.ImageEncryptionKey(keyFile)
+}
+
 // ImageUnpack unpacks a filesystem image into the destination path.
 // There are several formats that images can come in:
 // Container Format A: Separate metadata tarball and root squashfs file.
@@ -560,15 +663,36 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 // VM Format A: Separate metadata tarball and root qcow2 file.
 // 	- Unpack metadata tarball into mountPath.
 //	- Check rootBlockPath is a file and convert qcow2 file into raw format in rootBlockPath.
-func ImageUnpack(imageFile, destPath, destBlockFile string, blockBackend, runningInUserns bool, tracker *ioprogress.ProgressTracker) error {
+// Encrypted image files are decrypted in memory with the given key.
+func ImageUnpack(imageFile string, key []byte, destPath, destBlockFile string, blockBackend, runningInUserns bool, tracker *ioprogress.ProgressTracker) error {
+	imageRootfsFile := imageFile + ".rootfs"
+
+	if util.ImageIsEncrypted(imageFile) {
+		f, err := util.ImageDecryptMemfd(imageFile, key)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		imageFile = util.ImageMemfdPath(f)
+	}
+
+	if util.ImageIsEncrypted(imageRootfsFile) {
+		f, err := util.ImageDecryptMemfd(imageRootfsFile, key)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		imageRootfsFile = util.ImageMemfdPath(f)
+	}
+
 	// For all formats, first unpack the metadata (or combined) tarball into destPath.
 	err := shared.Unpack(imageFile, destPath, blockBackend, runningInUserns, tracker)
 	if err != nil {
 		return err
 	}
 
-	imageRootfsFile := imageFile + ".rootfs"
-
 	// If no destBlockFile supplied then this is a container image unpack.
 	if destBlockFile == "" {
 		rootfsPath := filepath.Join(destPath, "rootfs")
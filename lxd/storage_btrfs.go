@@ -2033,7 +2033,12 @@ func (s *storageBtrfs) ImageCreate(fingerprint string, tracker *ioprogress.Progr
 
 	// Unpack the image in imageMntPoint.
 	imagePath := shared.VarPath("images", fingerprint)
-	err = driver.ImageUnpack(imagePath, tmpImageSubvolumeName, "", false, s.s.OS.RunningInUserNS, tracker)
+	key, err := driver.ImageEncryptionKey(s.s)
+	if err != nil {
+		return err
+	}
+
+	err = driver.ImageUnpack(imagePath, key, tmpImageSubvolumeName, "", false, s.s.OS.RunningInUserNS, tracker)
 	if err != nil {
 		return err
 	}
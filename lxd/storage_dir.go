@@ -574,7 +574,12 @@ func (s *storageDir) ContainerCreateFromImage(container instance.Instance, image
 	}
 
 	imagePath := shared.VarPath("images", imageFingerprint)
-	err = driver.ImageUnpack(imagePath, containerMntPoint, "", false, s.s.OS.RunningInUserNS, nil)
+	key, err := driver.ImageEncryptionKey(s.s)
+	if err != nil {
+		return err
+	}
+
+	err = driver.ImageUnpack(imagePath, key, containerMntPoint, "", false, s.s.OS.RunningInUserNS, nil)
 	if err != nil {
 		return errors.Wrap(err, "Unpack image")
 	}
@@ -1954,7 +1954,12 @@ func (s *storageLvm) ImageCreate(fingerprint string, tracker *ioprogress.Progres
 		}
 
 		imagePath := shared.VarPath("images", fingerprint)
-		err = driver.ImageUnpack(imagePath, imageMntPoint, "", true, s.s.OS.RunningInUserNS, nil)
+		key, err := driver.ImageEncryptionKey(s.s)
+		if err != nil {
+			return err
+		}
+
+		err = driver.ImageUnpack(imagePath, key, imageMntPoint, "", true, s.s.OS.RunningInUserNS, nil)
 		if err != nil {
 			return err
 		}
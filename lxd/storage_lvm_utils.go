@@ -505,7 +505,12 @@ func (s *storageLvm) containerCreateFromImageLv(c instance.Instance, fp string)
 
 	imagePath := shared.VarPath("images", fp)
 	containerMntPoint := driver.GetContainerMountPoint(c.Project(), s.pool.Name, containerName)
-	err = driver.ImageUnpack(imagePath, containerMntPoint, "", true, s.s.OS.RunningInUserNS, nil)
+	key, err := driver.ImageEncryptionKey(s.s)
+	if err != nil {
+		return err
+	}
+
+	err = driver.ImageUnpack(imagePath, key, containerMntPoint, "", true, s.s.OS.RunningInUserNS, nil)
 	if err != nil {
 		logger.Errorf(`Failed to unpack image "%s" into non-thinpool LVM storage volume "%s" for container "%s" on storage pool "%s": %s`, imagePath, containerMntPoint, containerName, s.pool.Name, err)
 		return err
@@ -332,6 +332,12 @@ func storagePoolGet(d *Daemon, r *http.Request) response.Response {
 // /1.0/storage-pools/{name}
 // Replace pool properties.
 func storagePoolPut(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
 	poolName := mux.Vars(r)["name"]
 
 	// Get the existing storage pool.
@@ -350,8 +356,11 @@ func storagePoolPut(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// With a target, the node-specific keys of that node get updated.
+	targetNode := queryParam(r, "target")
+
 	config := dbInfo.Config
-	if clustered {
+	if clustered && targetNode == "" {
 		err := storagePoolValidateClusterConfig(req.Config)
 		if err != nil {
 			return response.BadRequest(err)
@@ -367,6 +376,10 @@ func storagePoolPut(d *Daemon, r *http.Request) response.Response {
 		return response.PreconditionFailed(err)
 	}
 
+	if clustered && targetNode != "" {
+		return storagePoolUpdateNode(d, poolName, dbInfo, req.Config)
+	}
+
 	// Validate the configuration
 	err = storagePoolValidateConfig(poolName, dbInfo.Driver, req.Config, dbInfo.Config)
 	if err != nil {
@@ -407,6 +420,12 @@ func storagePoolPut(d *Daemon, r *http.Request) response.Response {
 // /1.0/storage-pools/{name}
 // Change pool properties.
 func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
 	poolName := mux.Vars(r)["name"]
 
 	// Get the existing network
@@ -425,8 +444,11 @@ func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	// With a target, the node-specific keys of that node get updated.
+	targetNode := queryParam(r, "target")
+
 	config := dbInfo.Config
-	if clustered {
+	if clustered && targetNode == "" {
 		err := storagePoolValidateClusterConfig(req.Config)
 		if err != nil {
 			return response.BadRequest(err)
@@ -454,6 +476,10 @@ func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
 		}
 	}
 
+	if clustered && targetNode != "" {
+		return storagePoolUpdateNode(d, poolName, dbInfo, req.Config)
+	}
+
 	// Validate the configuration
 	err = storagePoolValidateConfig(poolName, dbInfo.Driver, req.Config, dbInfo.Config)
 	if err != nil {
@@ -492,20 +518,68 @@ func storagePoolPatch(d *Daemon, r *http.Request) response.Response {
 }
 
 // This helper makes sure that, when clustered, we're not changing
-// node-specific values.
-//
-// POSSIBLY TODO: for now we don't have any node-specific values that can be
-// modified. If we ever get some, we'll need to extend the PUT/PATCH APIs to
-// accept a targetNode query parameter.
+// node-specific values without a targetNode query parameter.
 func storagePoolValidateClusterConfig(reqConfig map[string]string) error {
 	for key := range reqConfig {
 		if shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
-			return fmt.Errorf("node-specific config key %s can't be changed", key)
+			return fmt.Errorf("node-specific config key %s can only be changed with --target", key)
 		}
 	}
 	return nil
 }
 
+// This helper makes sure that, when targeting a node, only node-specific
+// values are changed, and complements them with the cluster-wide values
+// taken from the db.
+func storagePoolNodeFillWithClusterConfig(dbConfig, reqConfig map[string]string) (map[string]string, error) {
+	config := map[string]string{}
+	for key, value := range dbConfig {
+		if shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
+			continue
+		}
+
+		if reqConfig[key] != value {
+			return nil, fmt.Errorf("config key %s isn't node-specific and can't be changed with --target", key)
+		}
+
+		config[key] = value
+	}
+
+	for key, value := range reqConfig {
+		if shared.StringInSlice(key, db.StoragePoolNodeConfigKeys) {
+			config[key] = value
+			continue
+		}
+
+		if dbConfig[key] != value {
+			return nil, fmt.Errorf("config key %s isn't node-specific and can't be changed with --target", key)
+		}
+	}
+
+	return config, nil
+}
+
+// storagePoolUpdateNode applies a change of the node-specific config keys of a
+// storage pool to the local node only.
+func storagePoolUpdateNode(d *Daemon, poolName string, dbInfo *api.StoragePool, reqConfig map[string]string) response.Response {
+	config, err := storagePoolNodeFillWithClusterConfig(dbInfo.Config, reqConfig)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = storagePoolValidateConfig(poolName, dbInfo.Driver, config, dbInfo.Config)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = storagePoolUpdate(d.State(), poolName, dbInfo.Description, config, true)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
 // This helper deletes any node-specific values from the config object, since
 // they should not be part of the calculated etag.
 func storagePoolClusterConfigForEtag(dbConfig map[string]string) map[string]string {
@@ -7,6 +7,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	storagePools "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/units"
 )
@@ -19,31 +20,49 @@ func updateStoragePoolError(unchangeable []string, driverName string) error {
 var changeableStoragePoolProperties = map[string][]string{
 	"btrfs": {
 		"rsync.bwlimit",
-		"btrfs.mount_options"},
+		"btrfs.mount_options",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 
 	"ceph": {
 		"volume.block.filesystem",
 		"volume.block.mount_options",
-		"volume.size"},
+		"volume.size",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 
 	"cephfs": {
-		"rsync.bwlimit"},
+		"rsync.bwlimit",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 
 	"dir": {
-		"rsync.bwlimit"},
+		"rsync.bwlimit",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 
 	"lvm": {
 		"lvm.thinpool_name",
 		"lvm.vg_name",
 		"volume.block.filesystem",
 		"volume.block.mount_options",
-		"volume.size"},
+		"volume.size",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 
 	"zfs": {
 		"rsync_bwlimit",
 		"volume.zfs.remove_snapshots",
 		"volume.zfs.use_refquota",
-		"zfs.clone_copy"},
+		"zfs.clone_copy",
+		"operations.concurrency",
+		"volume.usage.alerts",
+		"trash.expiry"},
 }
 
 var storagePoolConfigKeys = map[string]func(value string) error{
@@ -113,6 +132,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"zfs.clone_copy": shared.IsBool,
 	"zfs.pool_name":  shared.IsAny,
 	"rsync.bwlimit":  shared.IsAny,
+
+	// valid drivers: all
+	"operations.concurrency": shared.IsUint32,
+	"volume.usage.alerts":    storagePools.ValidateUsageAlerts,
+	"trash.expiry":           storagePools.ValidateTrashExpiry,
 }
 
 func storagePoolValidateConfig(name string, driver string, config map[string]string, oldConfig map[string]string) error {
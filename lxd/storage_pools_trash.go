@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var storagePoolTrashCmd = APIEndpoint{
+	Path: "storage-pools/{name}/trash",
+
+	Get: APIEndpointAction{Handler: storagePoolTrashGet},
+}
+
+var storagePoolTrashEntryCmd = APIEndpoint{
+	Path: "storage-pools/{name}/trash/{id}",
+
+	Delete: APIEndpointAction{Handler: storagePoolTrashEntryDelete},
+	Get:    APIEndpointAction{Handler: storagePoolTrashEntryGet},
+	Post:   APIEndpointAction{Handler: storagePoolTrashEntryPost},
+}
+
+// /1.0/storage-pools/{name}/trash
+// List the instances and custom volumes in the trash of a storage pool
+func storagePoolTrashGet(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["name"]
+	recursion := util.IsRecursionRequest(r)
+
+	poolID, err := d.cluster.StoragePoolGetID(poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var entries []db.StorageTrashEntry
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		entries, err = tx.StorageTrashList(poolID)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		urls := []string{}
+		for _, entry := range entries {
+			urls = append(urls, fmt.Sprintf("/%s/storage-pools/%s/trash/%d", version.APIVersion, poolName, entry.ID))
+		}
+
+		return response.SyncResponse(true, urls)
+	}
+
+	result := []api.StoragePoolTrashEntry{}
+	for _, entry := range entries {
+		apiEntry, err := storagePoolTrashEntryToAPI(entry)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		result = append(result, *apiEntry)
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// /1.0/storage-pools/{name}/trash/{id}
+// Get an instance or custom volume in the trash of a storage pool
+func storagePoolTrashEntryGet(d *Daemon, r *http.Request) response.Response {
+	entry, resp := storagePoolTrashEntryLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	apiEntry, err := storagePoolTrashEntryToAPI(*entry)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, apiEntry)
+}
+
+// /1.0/storage-pools/{name}/trash/{id}
+// Restore an instance or custom volume from the trash of a storage pool
+func storagePoolTrashEntryPost(d *Daemon, r *http.Request) response.Response {
+	entry, resp := storagePoolTrashEntryLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	// Forward the request to the member which has the volume.
+	resp = storagePoolTrashForward(d, r, entry)
+	if resp != nil {
+		return resp
+	}
+
+	pool, err := storagePoolTrashPool(d, entry.Pool)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		if entry.Type == db.StoragePoolVolumeTypeCustom {
+			return pool.RestoreCustomVolumeFromTrash(entry.ID, op)
+		}
+
+		config, err := pool.GetTrashInstanceConfig(entry.ID, op)
+		if err != nil {
+			return err
+		}
+
+		_, err = instance.LoadByProjectAndName(d.State(), entry.Project, config.Container.Name)
+		if err == nil {
+			return fmt.Errorf("Instance %q already exists in project %q", config.Container.Name, entry.Project)
+		}
+
+		return internalRecoverImportInstance(d, pool, entry.Project, config, func(inst instance.Instance, volConfig map[string]string) error {
+			return pool.RestoreInstanceFromTrash(inst, entry.ID, op)
+		})
+	}
+
+	resources := map[string][]string{}
+	resources["storage_pools"] = []string{entry.Pool}
+
+	op, err := operations.OperationCreate(d.State(), entry.Project, operations.OperationClassTask, db.OperationStorageTrashRestore, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// /1.0/storage-pools/{name}/trash/{id}
+// Purge an instance or custom volume from the trash of a storage pool
+func storagePoolTrashEntryDelete(d *Daemon, r *http.Request) response.Response {
+	entry, resp := storagePoolTrashEntryLoad(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	// Forward the request to the member which has the volume.
+	resp = storagePoolTrashForward(d, r, entry)
+	if resp != nil {
+		return resp
+	}
+
+	pool, err := storagePoolTrashPool(d, entry.Pool)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		return pool.PurgeTrash(entry.ID, op)
+	}
+
+	resources := map[string][]string{}
+	resources["storage_pools"] = []string{entry.Pool}
+
+	op, err := operations.OperationCreate(d.State(), entry.Project, operations.OperationClassTask, db.OperationStorageTrashPurge, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// storagePoolTrashEntryLoad returns the trash entry of the request, or an error response.
+func storagePoolTrashEntryLoad(d *Daemon, r *http.Request) (*db.StorageTrashEntry, response.Response) {
+	poolName := mux.Vars(r)["name"]
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return nil, response.BadRequest(fmt.Errorf("Invalid trash entry ID %q", mux.Vars(r)["id"]))
+	}
+
+	var entry *db.StorageTrashEntry
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		entry, err = tx.StorageTrashGet(id)
+		return err
+	})
+	if err != nil {
+		return nil, response.SmartError(err)
+	}
+
+	if entry.Pool != poolName {
+		return nil, response.NotFound(fmt.Errorf("Trash entry %d not found in storage pool %q", id, poolName))
+	}
+
+	return entry, nil
+}
+
+// storagePoolTrashForward forwards a request to the member which has the volume of a trash entry, if
+// it isn't the local one.
+func storagePoolTrashForward(d *Daemon, r *http.Request, entry *db.StorageTrashEntry) response.Response {
+	address, err := cluster.ResolveTarget(d.cluster, entry.Node)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address == "" {
+		return nil
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ForwardedResponse(client, r)
+}
+
+// storagePoolTrashPool returns a storage pool whose driver supports the trash.
+func storagePoolTrashPool(d *Daemon, poolName string) (storagePools.Pool, error) {
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err == storageDrivers.ErrUnknownDriver || err == storageDrivers.ErrNotImplemented {
+		return nil, fmt.Errorf("Storage pool %q doesn't support the trash", poolName)
+	}
+
+	return pool, err
+}
+
+// storagePoolTrashEntryToAPI converts a trash entry to its API representation.
+func storagePoolTrashEntryToAPI(entry db.StorageTrashEntry) (*api.StoragePoolTrashEntry, error) {
+	volTypeName, err := db.StoragePoolVolumeTypeToName(entry.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.StoragePoolTrashEntry{
+		ID:          entry.ID,
+		Project:     entry.Project,
+		Name:        entry.Name,
+		Type:        volTypeName,
+		Description: entry.Description,
+		Config:      entry.Config,
+		Location:    entry.Node,
+		DeletedAt:   entry.DeletedAt,
+		ExpiresAt:   entry.ExpiresAt,
+	}, nil
+}
+
+// storageTrashPurgeTask purges the volumes of this member which expired from the trash of their
+// storage pools.
+func storageTrashPurgeTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		var entries []db.StorageTrashEntry
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			entries, err = tx.StorageTrashExpired(time.Now())
+			return err
+		})
+		if err != nil {
+			logger.Error("Failed to get the expired storage trash", log.Ctx{"err": err})
+			return
+		}
+
+		if len(entries) == 0 {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			for _, entry := range entries {
+				pool, err := storagePoolTrashPool(d, entry.Pool)
+				if err != nil {
+					return err
+				}
+
+				err = pool.PurgeTrash(entry.ID, op)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to purge trash entry %d of storage pool %q", entry.ID, entry.Pool)
+				}
+			}
+
+			return nil
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationStorageTrashExpire, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start expired storage trash operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Purging expired storage trash")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to purge expired storage trash", log.Ctx{"err": err})
+		}
+		logger.Info("Done purging expired storage trash")
+	}
+
+	return f, task.Every(time.Hour)
+}
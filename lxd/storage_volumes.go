@@ -11,6 +11,8 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/operations"
@@ -88,6 +90,11 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 
 	recursion := util.IsRecursionRequest(r)
 
+	filter, err := filterParam(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Retrieve ID of the storage pool (and check if the storage pool
 	// exists).
 	poolID, err := d.cluster.StoragePoolGetID(poolName)
@@ -104,23 +111,32 @@ func storagePoolVolumesGet(d *Daemon, r *http.Request) response.Response {
 	// project. This means that we want to filter image volumes and return
 	// only the ones that have fingerprints matching images actually in use
 	// by the project.
-	volumes, err := d.cluster.StoragePoolVolumesGet(project, poolID, supportedVolumeTypesExceptImages)
-	if err != nil && err != db.ErrNoSuchObject {
-		return response.SmartError(err)
-	}
-
-	imageVolumes, err := d.cluster.StoragePoolVolumesGet("default", poolID, []int{storagePoolVolumeTypeImage})
-	if err != nil && err != db.ErrNoSuchObject {
-		return response.SmartError(err)
-	}
-
 	projectImages, err := d.cluster.ImagesGet(project, false)
 	if err != nil {
 		return response.SmartError(err)
 	}
-	for _, volume := range imageVolumes {
-		if shared.StringInSlice(volume.Name, projectImages) {
-			volumes = append(volumes, volume)
+
+	var volumes []*api.StorageVolume
+	if filter != nil {
+		volumes, err = d.cluster.StoragePoolVolumesGetFiltered(project, poolID, supportedVolumeTypesExceptImages, projectImages, filter)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	} else {
+		volumes, err = d.cluster.StoragePoolVolumesGet(project, poolID, supportedVolumeTypesExceptImages)
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
+
+		imageVolumes, err := d.cluster.StoragePoolVolumesGet("default", poolID, []int{storagePoolVolumeTypeImage})
+		if err != nil && err != db.ErrNoSuchObject {
+			return response.SmartError(err)
+		}
+
+		for _, volume := range imageVolumes {
+			if shared.StringInSlice(volume.Name, projectImages) {
+				volumes = append(volumes, volume)
+			}
 		}
 	}
 
@@ -270,6 +286,11 @@ func storagePoolVolumesTypePost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
 	}
 
+	err = storagePools.ValidVolumeName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	req.Type = mux.Vars(r)["type"]
 
 	// We currently only allow to create storage volumes of type
@@ -348,6 +369,12 @@ func doVolumeCreateOrCopy(d *Daemon, poolName string, req *api.StorageVolumesPos
 		return response.InternalError(err)
 	}
 
+	// The volume doesn't exist yet, so it's deleted if the copy is interrupted.
+	err = op.SetResumeData(operationRollback{Pool: poolName, Volume: req.Name})
+	if err != nil {
+		logger.Warn("Failed to persist the volume copy", log.Ctx{"err": err, "operation": op.ID()})
+	}
+
 	return operations.OperationResponse(op)
 }
 
@@ -376,6 +403,11 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
 	}
 
+	err = storagePools.ValidVolumeName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// Check that the user gave use a storage volume type for the storage
 	// volume we are about to create.
 	if req.Type == "" {
@@ -492,6 +524,12 @@ func doVolumeMigration(d *Daemon, poolName string, req *api.StorageVolumesPost)
 		if err != nil {
 			return response.InternalError(err)
 		}
+
+		// The volume doesn't exist yet, so it's deleted if the migration is interrupted.
+		err = op.SetResumeData(operationRollback{Pool: poolName, Volume: req.Name})
+		if err != nil {
+			logger.Warn("Failed to persist the volume migration", log.Ctx{"err": err, "operation": op.ID()})
+		}
 	}
 
 	return operations.OperationResponse(op)
@@ -529,6 +567,11 @@ func storagePoolVolumeTypePost(d *Daemon, r *http.Request, volumeTypeName string
 		return response.BadRequest(fmt.Errorf("Storage volume names may not contain slashes"))
 	}
 
+	err = storagePools.ValidVolumeName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	// We currently only allow to create storage volumes of type storagePoolVolumeTypeCustom.
 	// So check, that nothing else was requested.
 	if volumeTypeName != storagePoolVolumeTypeNameCustom {
@@ -606,6 +649,11 @@ func storagePoolVolumeTypePost(d *Daemon, r *http.Request, volumeTypeName string
 		return response.SmartError(fmt.Errorf("Volume is still in use by running containers"))
 	}
 
+	// Detect a move to another cluster member.
+	if req.TargetMember != "" {
+		return storagePoolVolumeTypePostMember(d, poolName, volumeName, volumeType, req)
+	}
+
 	// Detect a rename request.
 	if req.Pool == "" || req.Pool == poolName {
 		return storagePoolVolumeTypePostRename(d, poolName, volumeName, volumeType, req)
@@ -797,6 +845,143 @@ func storagePoolVolumeTypePostMove(d *Daemon, poolName string, volumeName string
 	return operations.OperationResponse(op)
 }
 
+// storagePoolVolumeTypePostMember handles requests moving a volume of a local storage pool to another
+// cluster member, which pulls it through the migration layer.
+func storagePoolVolumeTypePostMember(d *Daemon, poolName string, volumeName string, volumeType int, req api.StorageVolumePost) response.Response {
+	if req.Pool != "" && req.Pool != poolName {
+		return response.BadRequest(fmt.Errorf("Volumes can't be moved to another pool and cluster member at once"))
+	}
+
+	poolID, err := d.cluster.StoragePoolGetID(poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var driver string
+	var localAddress string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		driver, err = tx.StoragePoolDriver(poolID)
+		if err != nil {
+			return err
+		}
+
+		localAddress, err = tx.NodeAddress()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if driver == "ceph" || driver == "cephfs" {
+		return response.BadRequest(fmt.Errorf("Volumes of remote storage pools are available on all cluster members"))
+	}
+
+	address, err := cluster.ResolveTarget(d.cluster, req.TargetMember)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if address == "" {
+		return response.BadRequest(fmt.Errorf("The volume is already on cluster member %q", req.TargetMember))
+	}
+
+	run := func(op *operations.Operation) error {
+		// Notify users of the volume that it's name is changing.
+		if req.Name != volumeName {
+			err := storagePoolVolumeUpdateUsers(d, poolName, volumeName, poolName, req.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := storagePoolVolumeCopyToMember(d, poolName, volumeName, req.Name, localAddress, address)
+		if err != nil {
+			// Notify users of the volume that it's name is changing back.
+			if req.Name != volumeName {
+				storagePoolVolumeUpdateUsers(d, poolName, req.Name, poolName, volumeName)
+			}
+
+			return err
+		}
+
+		// Check if we can load new storage layer for pool driver type.
+		pool, err := storagePools.GetPoolByName(d.State(), poolName)
+		if err != storageDrivers.ErrUnknownDriver {
+			if err != nil {
+				return err
+			}
+
+			return pool.DeleteCustomVolume(volumeName, op)
+		}
+
+		snapshots, err := d.cluster.StoragePoolVolumeSnapshotsGetType(volumeName, volumeType, poolID)
+		if err != nil {
+			return err
+		}
+
+		// Delete snapshot volumes.
+		for _, snapshot := range snapshots {
+			s, err := storagePoolVolumeInit(d.State(), "default", poolName, snapshot.Name, volumeType)
+			if err != nil {
+				return err
+			}
+
+			err = s.StoragePoolVolumeSnapshotDelete()
+			if err != nil {
+				return err
+			}
+		}
+
+		s, err := storagePoolVolumeInit(d.State(), "default", poolName, volumeName, volumeType)
+		if err != nil {
+			return err
+		}
+
+		return s.StoragePoolVolumeDelete()
+	}
+
+	resources := map[string][]string{}
+	resources["storage_volumes"] = []string{fmt.Sprintf("%s/volumes/custom/%s", poolName, volumeName)}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationVolumeMove, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// storagePoolVolumeCopyToMember makes the member at the given address pull a copy of a local
+// custom volume, along with its snapshots.
+func storagePoolVolumeCopyToMember(d *Daemon, poolName string, volumeName string, newName string, localAddress string, address string) error {
+	source, err := cluster.Connect(localAddress, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	target, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return err
+	}
+
+	volume, _, err := source.GetStoragePoolVolume(poolName, storagePoolVolumeTypeNameCustom, volumeName)
+	if err != nil {
+		return err
+	}
+
+	args := &lxd.StoragePoolVolumeCopyArgs{
+		Name: newName,
+		Mode: "pull",
+	}
+
+	op, err := target.CopyStoragePoolVolume(poolName, source, poolName, *volume, args)
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
+
 func storagePoolVolumeTypeContainerPost(d *Daemon, r *http.Request) response.Response {
 	return storagePoolVolumeTypePost(d, r, "container")
 }
@@ -1257,7 +1442,12 @@ func storagePoolVolumeTypeDelete(d *Daemon, r *http.Request, volumeTypeName stri
 
 		switch volumeType {
 		case storagePoolVolumeTypeCustom:
-			err = pool.DeleteCustomVolume(volumeName, nil)
+			// Move the volume to the trash instead of deleting it when the pool has one.
+			if pool.Driver().Config()["trash.expiry"] != "" {
+				err = pool.TrashCustomVolume(volumeName, nil)
+			} else {
+				err = pool.DeleteCustomVolume(volumeName, nil)
+			}
 		case storagePoolVolumeTypeImage:
 			err = pool.DeleteImage(volumeName, nil)
 		default:
@@ -2431,7 +2431,12 @@ func (s *storageZfs) ImageCreate(fingerprint string, tracker *ioprogress.Progres
 	}
 
 	// Unpack the image into the temporary mountpoint.
-	err = driver.ImageUnpack(imagePath, tmpImageDir, "", false, s.s.OS.RunningInUserNS, nil)
+	key, err := driver.ImageEncryptionKey(s.s)
+	if err != nil {
+		return err
+	}
+
+	err = driver.ImageUnpack(imagePath, key, tmpImageDir, "", false, s.s.OS.RunningInUserNS, nil)
 	if err != nil {
 		return err
 	}
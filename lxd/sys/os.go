@@ -62,6 +62,7 @@ type OS struct {
 	CGInfo cgroup.Info
 
 	// Kernel features
+	CoreScheduling          bool
 	NetnsGetifaddrs         bool
 	SeccompListener         bool
 	SeccompListenerContinue bool
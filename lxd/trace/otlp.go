@@ -0,0 +1,226 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Spans are exported in batches of up to batchSize, at least every batchInterval. Spans are
+// dropped rather than blocking when more than queueSize are waiting for export.
+const (
+	batchSize     = 512
+	batchInterval = 5 * time.Second
+	queueSize     = 4096
+)
+
+// otlpExporter sends spans to an OTLP/HTTP endpoint, JSON encoded.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+
+	spans chan *Span
+	done  chan struct{}
+}
+
+func newOTLPExporter(url string) *otlpExporter {
+	e := &otlpExporter{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		spans:  make(chan *Span, queueSize),
+		done:   make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// queue queues a span for export, dropping it if the queue is full.
+func (e *otlpExporter) queue(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+	}
+}
+
+// stop exports the queued spans and stops the exporter.
+func (e *otlpExporter) stop() {
+	close(e.spans)
+	<-e.done
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := []*Span{}
+	for {
+		select {
+		case span, ok := <-e.spans:
+			if !ok {
+				e.export(batch)
+				return
+			}
+
+			batch = append(batch, span)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+
+		e.export(batch)
+		batch = []*Span{}
+	}
+}
+
+func (e *otlpExporter) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(otlpEncode(batch))
+	if err != nil {
+		logger.Warn("Failed to encode trace spans", log.Ctx{"err": err})
+		return
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		logger.Warn("Failed to export trace spans", log.Ctx{"url": e.url, "err": err})
+		return
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logger.Warn("Trace endpoint rejected spans", log.Ctx{"url": e.url, "status": resp.Status})
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpEncode returns the OTLP export request holding the given spans.
+func otlpEncode(batch []*Span) otlpRequest {
+	hostname, _ := os.Hostname()
+
+	resource := otlpResource{
+		Attributes: otlpAttributes(map[string]string{
+			"service.name":    "lxd",
+			"service.version": version.Version,
+			"host.name":       hostname,
+		}),
+	}
+
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.lock.Lock()
+
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attributes),
+		}
+
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+
+		if s.err != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.err}
+		}
+
+		s.lock.Unlock()
+
+		spans = append(spans, span)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/lxc/lxd", Version: version.Version},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		encoded = append(encoded, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+
+	return encoded
+}
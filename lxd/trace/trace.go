@@ -0,0 +1,186 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as defined by OTLP.
+const (
+	kindInternal = 1
+	kindServer   = 2
+)
+
+// Span records the time spent doing something, as part of a trace.
+//
+// All methods can be called on a nil Span, which is what's returned when tracing is disabled.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        string
+
+	lock sync.Mutex
+}
+
+type contextKey struct{}
+
+var exporter *otlpExporter
+var exporterLock sync.RWMutex
+
+// Configure sets the OTLP/HTTP endpoint spans are exported to, like "http://collector:4318".
+// Tracing is disabled when the endpoint is empty.
+func Configure(endpoint string) error {
+	var newExporter *otlpExporter
+
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Invalid trace endpoint %q, must be an http or https URL", endpoint)
+		}
+
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+
+		newExporter = newOTLPExporter(u.String())
+	}
+
+	exporterLock.Lock()
+	oldExporter := exporter
+	exporter = newExporter
+	exporterLock.Unlock()
+
+	// Flush the spans which were queued for the previous endpoint.
+	if oldExporter != nil {
+		oldExporter.stop()
+	}
+
+	return nil
+}
+
+// Enabled returns whether spans are being exported.
+func Enabled() bool {
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+
+	return exporter != nil
+}
+
+// Start starts a span as a child of the span held by the given context, if any. The returned
+// context holds the new span, for the work it covers to be traced as its children.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, kindInternal)
+}
+
+// StartServer starts the span of a request received from a client, continuing the trace set by
+// the W3C traceparent header of the request if any.
+func StartServer(ctx context.Context, name string, header http.Header) (context.Context, *Span) {
+	parent := parseTraceparent(header.Get("traceparent"))
+	if parent != nil {
+		ctx = context.WithValue(ctx, contextKey{}, parent)
+	}
+
+	return start(ctx, name, kindServer)
+}
+
+func start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	span := &Span{
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+
+	parent, ok := ctx.Value(contextKey{}).(*Span)
+	if ok {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+
+	rand.Read(span.spanID[:])
+
+	return context.WithValue(ctx, contextKey{}, span), span
+}
+
+// SetAttribute sets an attribute of the span.
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.attributes[key] = value
+	s.lock.Unlock()
+}
+
+// End ends the span, marking it as failed if the given error isn't nil, and queues it for export.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.lock.Unlock()
+
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+
+	if exporter != nil {
+		exporter.queue(s)
+	}
+}
+
+// parseTraceparent returns the remote parent span set by a W3C traceparent header, or nil if the
+// header isn't valid.
+func parseTraceparent(value string) *Span {
+	fields := strings.Split(value, "-")
+	if len(fields) != 4 || fields[0] != "00" || len(fields[1]) != 32 || len(fields[2]) != 16 {
+		return nil
+	}
+
+	parent := &Span{}
+
+	_, err := hex.Decode(parent.traceID[:], []byte(fields[1]))
+	if err != nil || parent.traceID == [16]byte{} {
+		return nil
+	}
+
+	_, err = hex.Decode(parent.spanID[:], []byte(fields[2]))
+	if err != nil || parent.spanID == [8]byte{} {
+		return nil
+	}
+
+	return parent
+}
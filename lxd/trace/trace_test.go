@@ -0,0 +1,93 @@
+package trace_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/trace"
+)
+
+func TestStart_Disabled(t *testing.T) {
+	require.NoError(t, trace.Configure(""))
+	assert.False(t, trace.Enabled())
+
+	ctx, span := trace.Start(context.Background(), "test")
+	assert.NotNil(t, ctx)
+	assert.Nil(t, span)
+
+	// A nil span can be used all the same.
+	span.SetAttribute("key", "value")
+	span.End(nil)
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	assert.EqualError(t, trace.Configure("collector:4318"), `Invalid trace endpoint "collector:4318", must be an http or https URL`)
+}
+
+func TestSpan_Export(t *testing.T) {
+	var lock sync.Mutex
+	paths := []string{}
+	spans := []map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []map[string]interface{} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}{}
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		require.NoError(t, err)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		paths = append(paths, r.URL.Path)
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, trace.Configure(server.URL))
+	assert.True(t, trace.Enabled())
+
+	header := http.Header{}
+	header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	ctx, parent := trace.StartServer(context.Background(), "GET /1.0/instances", header)
+	_, child := trace.Start(ctx, "storage.CreateVolumeFromCopy")
+	child.SetAttribute("lxd.storage.pool", "default")
+	child.End(fmt.Errorf("Copy failed"))
+	parent.End(nil)
+
+	// Disabling tracing flushes the queued spans.
+	require.NoError(t, trace.Configure(""))
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, []string{"/v1/traces"}, paths)
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "storage.CreateVolumeFromCopy", spans[0]["name"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0]["traceId"])
+	assert.Equal(t, spans[1]["spanId"], spans[0]["parentSpanId"])
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "Copy failed"}, spans[0]["status"])
+
+	assert.Equal(t, "GET /1.0/instances", spans[1]["name"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[1]["traceId"])
+	assert.Equal(t, "b7ad6b7169203331", spans[1]["parentSpanId"])
+}
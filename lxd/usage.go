@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/usage"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var instanceUsageCmd = APIEndpoint{
+	Name: "instanceUsage",
+	Path: "instances/{name}/usage",
+	Aliases: []APIEndpointAlias{
+		{Name: "containerUsage", Path: "containers/{name}/usage"},
+		{Name: "vmUsage", Path: "virtual-machines/{name}/usage"},
+	},
+
+	Get: APIEndpointAction{Handler: instanceUsageGet, AccessHandler: AllowProjectPermission("containers", "view")},
+}
+
+var storagePoolUsageCmd = APIEndpoint{
+	Path: "storage-pools/{name}/usage",
+
+	Get: APIEndpointAction{Handler: storagePoolUsageGet, AccessHandler: AllowAuthenticated},
+}
+
+// instanceUsageKey returns the key of the usage series of an instance.
+func instanceUsageKey(project string, name string) string {
+	return fmt.Sprintf("instance/%s/%s", project, name)
+}
+
+// storagePoolUsageKey returns the key of the usage series of a storage pool.
+func storagePoolUsageKey(name string) string {
+	return fmt.Sprintf("storage-pool/%s", name)
+}
+
+// usageQuery returns the usage recorded for the given series over the period and resolution of
+// the request.
+func usageQuery(d *Daemon, r *http.Request, key string) response.Response {
+	period := queryParam(r, "period")
+	if period == "" {
+		period = "hour"
+	}
+
+	resolution := usage.Interval
+	if queryParam(r, "resolution") != "" {
+		var err error
+		resolution, err = time.ParseDuration(queryParam(r, "resolution"))
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	history, err := d.usage.Query(key, period, resolution, time.Now())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	return response.SyncResponse(true, history)
+}
+
+// /1.0/instances/{name}/usage
+// Get the resource usage of an instance over the last hour or day
+func instanceUsageGet(d *Daemon, r *http.Request) response.Response {
+	instanceType, err := urlInstanceTypeDetect(r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	project := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	// Handle requests targeted to an instance on a different node
+	resp, err := ForwardedResponseIfContainerIsRemote(d, r, project, name, instanceType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+	if resp != nil {
+		return resp
+	}
+
+	_, err = instance.LoadByProjectAndName(d.State(), project, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return usageQuery(d, r, instanceUsageKey(project, name))
+}
+
+// /1.0/storage-pools/{name}/usage
+// Get the space used by a storage pool over the last hour or day
+func storagePoolUsageGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := ForwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	poolName := mux.Vars(r)["name"]
+
+	_, err := d.cluster.StoragePoolGetID(poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return usageQuery(d, r, storagePoolUsageKey(poolName))
+}
+
+// usageSampleTask records the resource usage of the running instances and storage pools of this
+// node every minute.
+func usageSampleTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		now := time.Now()
+		keys := []string{}
+
+		instances, err := instanceLoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for usage sampling", log.Ctx{"err": err})
+			return
+		}
+
+		for _, inst := range instances {
+			key := instanceUsageKey(inst.Project(), inst.Name())
+			keys = append(keys, key)
+
+			if !inst.IsRunning() {
+				continue
+			}
+
+			state, err := inst.RenderState()
+			if err != nil {
+				logger.Debug("Failed to get instance state for usage sampling", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+				continue
+			}
+
+			gauges := map[string]float64{
+				"memory": float64(state.Memory.Usage),
+			}
+
+			root, ok := state.Disk["root"]
+			if ok {
+				gauges["disk"] = float64(root.Usage)
+			}
+
+			counters := map[string]float64{
+				"cpu":              float64(state.CPU.Usage) / float64(time.Second),
+				"network_received": 0,
+				"network_sent":     0,
+			}
+
+			for devName, network := range state.Network {
+				if devName == "lo" {
+					continue
+				}
+
+				counters["network_received"] += float64(network.Counters.BytesReceived)
+				counters["network_sent"] += float64(network.Counters.BytesSent)
+			}
+
+			d.usage.Record(key, now, gauges, counters)
+		}
+
+		pools, err := d.cluster.StoragePoolsNotPending()
+		if err != nil && err != db.ErrNoSuchObject {
+			logger.Error("Failed to load storage pools for usage sampling", log.Ctx{"err": err})
+			return
+		}
+
+		for _, poolName := range pools {
+			key := storagePoolUsageKey(poolName)
+			keys = append(keys, key)
+
+			res, err := storagePoolResources(d.State(), poolName)
+			if err != nil {
+				logger.Debug("Failed to get storage pool resources for usage sampling", log.Ctx{"pool": poolName, "err": err})
+				continue
+			}
+
+			d.usage.Record(key, now, map[string]float64{
+				"space_used":   float64(res.Space.Used),
+				"space_total":  float64(res.Space.Total),
+				"inodes_used":  float64(res.Inodes.Used),
+				"inodes_total": float64(res.Inodes.Total),
+			}, nil)
+		}
+
+		// Forget about the deleted or renamed instances and storage pools.
+		d.usage.Forget(keys)
+	}
+
+	return f, task.Every(usage.Interval)
+}
@@ -0,0 +1,238 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Interval is how often the resource usage is sampled.
+const Interval = time.Minute
+
+// Retention is how long samples are kept for.
+const Retention = 24 * time.Hour
+
+// Periods maps the periods usage can be queried over to their duration.
+var Periods = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// series holds the samples of an instance or storage pool, oldest first.
+type series struct {
+	Samples []api.UsageSample `json:"samples"`
+
+	// Last values of the counters, to record their rate of change.
+	counters     map[string]float64
+	countersTime time.Time
+}
+
+// Store keeps the resource usage sampled over the last day in memory, saving it to disk for it
+// to survive restarts.
+type Store struct {
+	path   string
+	series map[string]*series
+
+	// Whether the samples saved to disk were loaded, for them not to be overwritten otherwise.
+	loaded bool
+
+	lock sync.Mutex
+}
+
+// NewStore returns a new store, saved to the given path.
+func NewStore(path string) *Store {
+	return &Store{
+		path:   path,
+		series: map[string]*series{},
+	}
+}
+
+// Load reads the samples saved to disk, if any.
+func (s *Store) Load() error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.lock.Lock()
+			s.loaded = true
+			s.lock.Unlock()
+			return nil
+		}
+
+		return err
+	}
+
+	saved := map[string]*series{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.series = saved
+	s.loaded = true
+	s.prune(time.Now())
+
+	return nil
+}
+
+// Save writes the samples to disk, unless they weren't loaded from there first.
+func (s *Store) Save() error {
+	s.lock.Lock()
+	if !s.loaded {
+		s.lock.Unlock()
+		return nil
+	}
+
+	data, err := json.Marshal(s.series)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, data, 0600)
+}
+
+// Record adds a sample to the series with the given key. Gauges are recorded as is and counters
+// as their rate of change per second since the last sample, which the first sample of a series
+// and counters which got reset don't have.
+func (s *Store) Record(key string, timestamp time.Time, gauges map[string]float64, counters map[string]float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.series[key]
+	if !ok {
+		entry = &series{}
+		s.series[key] = entry
+	}
+
+	values := make(map[string]float64, len(gauges)+len(counters))
+	for name, value := range gauges {
+		values[name] = value
+	}
+
+	elapsed := timestamp.Sub(entry.countersTime).Seconds()
+	for name, value := range counters {
+		last, ok := entry.counters[name]
+		if ok && elapsed > 0 && value >= last {
+			values[name] = (value - last) / elapsed
+		}
+	}
+
+	entry.counters = counters
+	entry.countersTime = timestamp
+
+	entry.Samples = append(entry.Samples, api.UsageSample{Timestamp: timestamp, Values: values})
+	s.prune(timestamp)
+}
+
+// Forget drops the series whose key isn't in the given list.
+func (s *Store) Forget(keep []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	kept := make(map[string]*series, len(keep))
+	for _, key := range keep {
+		entry, ok := s.series[key]
+		if ok {
+			kept[key] = entry
+		}
+	}
+
+	s.series = kept
+}
+
+// prune drops the samples past the retention, and the series left without any.
+func (s *Store) prune(now time.Time) {
+	cutoff := now.Add(-Retention)
+
+	for key, entry := range s.series {
+		i := sort.Search(len(entry.Samples), func(i int) bool {
+			return entry.Samples[i].Timestamp.After(cutoff)
+		})
+
+		if i == len(entry.Samples) {
+			delete(s.series, key)
+			continue
+		}
+
+		entry.Samples = entry.Samples[i:]
+	}
+}
+
+// Query returns the samples of the given series over the given period before now, averaged over
+// intervals of the given resolution. Intervals without any sample are left out.
+func (s *Store) Query(key string, period string, resolution time.Duration, now time.Time) (*api.UsageHistory, error) {
+	duration, ok := Periods[period]
+	if !ok {
+		return nil, fmt.Errorf("Invalid period %q, must be hour or day", period)
+	}
+
+	if resolution < Interval || resolution > duration {
+		return nil, fmt.Errorf("Invalid resolution %s, must be between %s and %s", resolution, Interval, duration)
+	}
+
+	history := &api.UsageHistory{
+		Period:     period,
+		Resolution: int64(resolution / time.Second),
+		Samples:    []api.UsageSample{},
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.series[key]
+	if !ok {
+		return history, nil
+	}
+
+	start := now.Add(-duration)
+
+	var bucket time.Time
+	sums := map[string]float64{}
+	counts := map[string]int{}
+
+	flush := func() {
+		if len(counts) == 0 {
+			return
+		}
+
+		values := make(map[string]float64, len(sums))
+		for name, sum := range sums {
+			values[name] = sum / float64(counts[name])
+		}
+
+		history.Samples = append(history.Samples, api.UsageSample{Timestamp: bucket, Values: values})
+		sums = map[string]float64{}
+		counts = map[string]int{}
+	}
+
+	for _, sample := range entry.Samples {
+		if !sample.Timestamp.After(start) || sample.Timestamp.After(now) {
+			continue
+		}
+
+		// Intervals are aligned on the resolution, for successive queries to match.
+		sampleBucket := sample.Timestamp.Truncate(resolution)
+		if !sampleBucket.Equal(bucket) {
+			flush()
+			bucket = sampleBucket
+		}
+
+		for name, value := range sample.Values {
+			sums[name] += value
+			counts[name]++
+		}
+	}
+
+	flush()
+
+	return history, nil
+}
@@ -0,0 +1,88 @@
+package usage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/usage"
+)
+
+func TestStore_Query(t *testing.T) {
+	store := usage.NewStore("")
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Samples every minute over the last two hours, the CPU time growing by 30 seconds each.
+	for i := 120; i > 0; i-- {
+		timestamp := now.Add(-time.Duration(i) * time.Minute)
+		store.Record("instance/default/c1", timestamp, map[string]float64{"memory": float64(i)}, map[string]float64{"cpu": float64(120-i) * 30})
+	}
+
+	history, err := store.Query("instance/default/c1", "hour", 30*time.Minute, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "hour", history.Period)
+	assert.Equal(t, int64(1800), history.Resolution)
+	require.Len(t, history.Samples, 2)
+
+	assert.Equal(t, now.Add(-time.Hour), history.Samples[0].Timestamp)
+	assert.Equal(t, 45.0, history.Samples[0].Values["memory"])
+	assert.Equal(t, 0.5, history.Samples[0].Values["cpu"])
+	assert.Equal(t, 15.5, history.Samples[1].Values["memory"])
+
+	// The first sample has no CPU rate.
+	history, err = store.Query("instance/default/c1", "day", time.Hour, now)
+	require.NoError(t, err)
+	require.Len(t, history.Samples, 2)
+	assert.Equal(t, 0.5, history.Samples[0].Values["cpu"])
+
+	history, err = store.Query("instance/default/c2", "hour", time.Minute, now)
+	require.NoError(t, err)
+	assert.Len(t, history.Samples, 0)
+
+	_, err = store.Query("instance/default/c1", "week", time.Hour, now)
+	assert.Error(t, err)
+
+	_, err = store.Query("instance/default/c1", "hour", time.Second, now)
+	assert.Error(t, err)
+}
+
+func TestStore_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-usage-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "usage.json")
+	now := time.Now().Truncate(time.Minute)
+
+	store := usage.NewStore(path)
+	store.Record("storage-pool/default", now.Add(-time.Minute), map[string]float64{"space_used": 10}, nil)
+
+	// Nothing is saved before loading, not to overwrite the history.
+	require.NoError(t, store.Save())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, store.Load())
+	store.Record("storage-pool/default", now.Add(-time.Minute), map[string]float64{"space_used": 10}, nil)
+	store.Record("storage-pool/old", now.Add(-time.Minute), map[string]float64{"space_used": 20}, nil)
+	store.Forget([]string{"storage-pool/default"})
+	require.NoError(t, store.Save())
+
+	store = usage.NewStore(path)
+	require.NoError(t, store.Load())
+
+	history, err := store.Query("storage-pool/default", "hour", time.Minute, now)
+	require.NoError(t, err)
+	require.Len(t, history.Samples, 1)
+	assert.Equal(t, 10.0, history.Samples[0].Values["space_used"])
+
+	history, err = store.Query("storage-pool/old", "hour", time.Minute, now)
+	require.NoError(t, err)
+	assert.Len(t, history.Samples, 0)
+}
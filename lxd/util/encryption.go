@@ -2,10 +2,18 @@ package util
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/lxc/lxd/shared"
 	"github.com/pkg/errors"
@@ -78,3 +86,252 @@ func WriteCert(dir, prefix string, cert, key, ca []byte) error {
 
 	return nil
 }
+
+// imageEncryptionMagic starts the image files encrypted by LXD, followed by the ID of the key, the
+// nonce prefix and the sealed chunks, each preceded by its length.
+var imageEncryptionMagic = []byte("LXDIMGENC1\n")
+
+// imageEncryptionKeyIDSize is the size of the key ID stored in the header of encrypted image files,
+// used to tell a wrong key from a corrupted file.
+const imageEncryptionKeyIDSize = 8
+
+// imageEncryptionChunkSize is the size of the chunks of image files sealed on their own, so that
+// files are encrypted and decrypted as streams.
+const imageEncryptionChunkSize = 1024 * 1024
+
+// ImageEncryptionKey reads the key encrypting the image store from the given file, made of either
+// 32 bytes or their hexadecimal encoding. The file is expected to be provided outside of the LXD
+// data, with the same content on all the members of a cluster.
+func ImageEncryptionKey(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the image encryption key")
+	}
+
+	if len(content) == 32 {
+		return content, nil
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("Invalid image encryption key %q, expected 32 bytes or their hexadecimal encoding", path)
+	}
+
+	return key, nil
+}
+
+// ImageEncryptionKeyFileValidate checks that the image encryption key file is an absolute path out
+// of the LXD data directory, so that the key isn't stored along the images it protects.
+func ImageEncryptionKeyFileValidate(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("Image encryption key file must be an absolute path")
+	}
+
+	path = filepath.Clean(path)
+	varDir := filepath.Clean(shared.VarPath())
+	if path == varDir || strings.HasPrefix(path, varDir+"/") {
+		return fmt.Errorf("Image encryption key file must be out of the LXD directory %q", varDir)
+	}
+
+	return nil
+}
+
+// imageEncryptionKeyID returns the ID of an image encryption key, stored in encrypted files.
+func imageEncryptionKeyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:imageEncryptionKeyIDSize]
+}
+
+// ImageIsEncrypted returns whether the given image file was encrypted by ImageEncrypt.
+func ImageIsEncrypted(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(imageEncryptionMagic))
+	_, err = io.ReadFull(f, header)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(header, imageEncryptionMagic)
+}
+
+// imageEncryptionAEAD returns the AES-GCM cipher of the given image encryption key.
+func imageEncryptionAEAD(key []byte) (cipher.AEAD, error) {
+	if key == nil {
+		return nil, fmt.Errorf("No image encryption key configured, see images.encryption.key_file")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// imageEncryptionNonce returns the nonce of a chunk, made of the nonce prefix of the file and the
+// chunk index.
+func imageEncryptionNonce(aead cipher.AEAD, prefix []byte, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+// ImageEncrypt encrypts the given image file in place with the given key, unless already encrypted.
+// Chunks are sealed with AES-GCM, the last one being marked as such so that truncated files are
+// detected.
+func ImageEncrypt(path string, key []byte) error {
+	if ImageIsEncrypted(path) {
+		return nil
+	}
+
+	aead, err := imageEncryptionAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".encrypt_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+
+	prefix := make([]byte, aead.NonceSize()-8)
+	_, err = rand.Read(prefix)
+	if err != nil {
+		return err
+	}
+
+	header := append(append([]byte{}, imageEncryptionMagic...), imageEncryptionKeyID(key)...)
+	_, err = dst.Write(append(header, prefix...))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, imageEncryptionChunkSize)
+	next := make([]byte, imageEncryptionChunkSize)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	for index := uint64(0); ; index++ {
+		// Read ahead to know whether this is the last chunk.
+		m, err := io.ReadFull(src, next)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		last := []byte{0}
+		if m == 0 {
+			last[0] = 1
+		}
+
+		sealed := aead.Seal(nil, imageEncryptionNonce(aead, prefix, index), buf[:n], last)
+
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(sealed)))
+		_, err = dst.Write(append(length, sealed...))
+		if err != nil {
+			return err
+		}
+
+		if m == 0 {
+			break
+		}
+
+		buf, next = next, buf
+		n = m
+	}
+
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(dst.Name(), path)
+}
+
+// ImageDecrypt writes the content of the given image file encrypted by ImageEncrypt to the writer,
+// returning its size.
+func ImageDecrypt(path string, key []byte, w io.Writer) (int64, error) {
+	aead, err := imageEncryptionAEAD(key)
+	if err != nil {
+		return 0, err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	header := make([]byte, len(imageEncryptionMagic)+imageEncryptionKeyIDSize+aead.NonceSize()-8)
+	_, err = io.ReadFull(src, header)
+	if err != nil || !bytes.Equal(header[:len(imageEncryptionMagic)], imageEncryptionMagic) {
+		return 0, fmt.Errorf("Image file %q isn't encrypted", path)
+	}
+
+	keyID := header[len(imageEncryptionMagic) : len(imageEncryptionMagic)+imageEncryptionKeyIDSize]
+	if !bytes.Equal(keyID, imageEncryptionKeyID(key)) {
+		return 0, fmt.Errorf("Image file %q is encrypted with another key", path)
+	}
+
+	prefix := header[len(imageEncryptionMagic)+imageEncryptionKeyIDSize:]
+	length := make([]byte, 4)
+	written := int64(0)
+	for index := uint64(0); ; index++ {
+		_, err = io.ReadFull(src, length)
+		if err != nil {
+			return written, fmt.Errorf("Encrypted image file %q is truncated", path)
+		}
+
+		size := binary.BigEndian.Uint32(length)
+		if size > imageEncryptionChunkSize+uint32(aead.Overhead()) {
+			return written, fmt.Errorf("Encrypted image file %q is corrupted", path)
+		}
+
+		sealed := make([]byte, size)
+		_, err = io.ReadFull(src, sealed)
+		if err != nil {
+			return written, fmt.Errorf("Encrypted image file %q is truncated", path)
+		}
+
+		// Try the chunk as the last one first, as marked when sealed.
+		last := true
+		chunk, err := aead.Open(nil, imageEncryptionNonce(aead, prefix, index), sealed, []byte{1})
+		if err != nil {
+			last = false
+			chunk, err = aead.Open(nil, imageEncryptionNonce(aead, prefix, index), sealed, []byte{0})
+			if err != nil {
+				return written, fmt.Errorf("Failed to decrypt image file %q: %v", path, err)
+			}
+		}
+
+		n, err := w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+
+		if last {
+			return written, nil
+		}
+	}
+}
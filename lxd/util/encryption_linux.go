@@ -0,0 +1,42 @@
+// +build linux
+
+package util
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ImageDecryptMemfd decrypts the given image file encrypted by ImageEncrypt to an anonymous memory
+// file, so that its content never reaches the disk. The file is to be closed by the caller, and
+// can be opened by path with ImageMemfdPath while open.
+func ImageDecryptMemfd(path string, key []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("lxd_image", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create memory file: %v", err)
+	}
+
+	f := os.NewFile(uintptr(fd), "lxd_image")
+
+	_, err = ImageDecrypt(path, key, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// ImageMemfdPath returns the path through which a memory file of LXD can be opened, including by
+// the commands it runs.
+func ImageMemfdPath(f *os.File) string {
+	return fmt.Sprintf("/proc/%d/fd/%d", os.Getpid(), f.Fd())
+}
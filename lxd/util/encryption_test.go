@@ -0,0 +1,106 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageEncrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-util-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	require.NoError(t, err)
+
+	// Spans a few chunks, the last one being partial.
+	content := make([]byte, 2*1024*1024+123)
+	_, err = rand.Read(content)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "image")
+	err = ioutil.WriteFile(path, content, 0600)
+	require.NoError(t, err)
+
+	assert.False(t, util.ImageIsEncrypted(path))
+
+	err = util.ImageEncrypt(path, key)
+	require.NoError(t, err)
+	assert.True(t, util.ImageIsEncrypted(path))
+
+	var buf bytes.Buffer
+	size, err := util.ImageDecrypt(path, key, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+	assert.Equal(t, content, buf.Bytes())
+
+	// Other keys don't decrypt.
+	otherKey := make([]byte, 32)
+	_, err = util.ImageDecrypt(path, otherKey, ioutil.Discard)
+	assert.Error(t, err)
+
+	// Truncated files don't decrypt.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	err = os.Truncate(path, info.Size()-100)
+	require.NoError(t, err)
+
+	_, err = util.ImageDecrypt(path, key, ioutil.Discard)
+	assert.Error(t, err)
+}
+
+func TestImageEncryptionKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-util-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	// Raw keys.
+	path := filepath.Join(dir, "raw.key")
+	err = ioutil.WriteFile(path, key, 0600)
+	require.NoError(t, err)
+
+	loaded, err := util.ImageEncryptionKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	// Hexadecimal keys.
+	path = filepath.Join(dir, "hex.key")
+	err = ioutil.WriteFile(path, []byte(strings.Repeat("42", 32)+"\n"), 0600)
+	require.NoError(t, err)
+
+	loaded, err = util.ImageEncryptionKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	// Short keys.
+	path = filepath.Join(dir, "short.key")
+	err = ioutil.WriteFile(path, []byte("4242"), 0600)
+	require.NoError(t, err)
+
+	_, err = util.ImageEncryptionKey(path)
+	assert.Error(t, err)
+}
+
+func TestImageEncryptionKeyFileValidate(t *testing.T) {
+	os.Setenv("LXD_DIR", "/var/lib/lxd")
+	defer os.Unsetenv("LXD_DIR")
+
+	assert.NoError(t, util.ImageEncryptionKeyFileValidate(""))
+	assert.NoError(t, util.ImageEncryptionKeyFileValidate("/run/lxd/images.key"))
+	assert.Error(t, util.ImageEncryptionKeyFileValidate("images.key"))
+	assert.Error(t, util.ImageEncryptionKeyFileValidate("/var/lib/lxd/images.key"))
+	assert.Error(t, util.ImageEncryptionKeyFileValidate("/var/lib/lxd/../lxd/keys/images.key"))
+}
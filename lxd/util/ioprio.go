@@ -0,0 +1,47 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const ioprioClassBestEffort = 2
+const ioprioClassShift = 13
+const ioprioWhoProcess = 1
+
+// SetIOPriority sets the best-effort I/O scheduling level, from 0 (highest) to 7 (lowest), of all
+// the threads of a process. It's honoured by the bfq and cfq I/O schedulers, and is inherited by the
+// threads created afterwards.
+func SetIOPriority(pid int, level int) error {
+	if level < 0 || level > 7 {
+		return fmt.Errorf("Invalid I/O priority level: %d", level)
+	}
+
+	tasks, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return err
+	}
+
+	ioprio := ioprioClassBestEffort<<ioprioClassShift | level
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+
+		_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(ioprio))
+		if errno != 0 {
+			// The thread may have exited since listing them.
+			if errno == unix.ESRCH {
+				continue
+			}
+
+			return errno
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 signature of the body of a delivery, when
+// a secret is set.
+const SignatureHeader = "X-LXD-Signature"
+
+// Deliveries are attempted up to maxAttempts times, waiting twice as long between each attempt,
+// starting with RetryDelay.
+const maxAttempts = 5
+
+// RetryDelay is how long to wait before retrying a failed delivery for the first time.
+var RetryDelay = time.Second
+
+// Sender posts the selected events to the configured webhook URLs.
+type Sender struct {
+	urls      []string
+	selectors []string
+	secret    string
+	location  string
+
+	client *http.Client
+	lock   sync.Mutex
+}
+
+// NewSender returns a new webhook sender, with no URL configured.
+func NewSender() *Sender {
+	return &Sender{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Configure sets the URLs events are posted to, the events to post and the secret to sign them
+// with. Selectors are either an event type (lifecycle or operation) or a pattern matched against
+// the action of lifecycle events, like "instance-*". Only lifecycle events are posted when no
+// selector is given. Webhooks are disabled when no URL is set.
+func (s *Sender) Configure(urls []string, selectors []string, secret string) error {
+	for _, rawURL := range urls {
+		err := ValidateURL(rawURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, selector := range selectors {
+		_, err := path.Match(selector, "")
+		if err != nil {
+			return fmt.Errorf("Invalid webhook event %q: %v", selector, err)
+		}
+	}
+
+	if len(selectors) == 0 {
+		selectors = []string{"lifecycle"}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.urls = urls
+	s.selectors = selectors
+	s.secret = secret
+
+	return nil
+}
+
+// SetLocation sets the name of the cluster member the events are posted from.
+func (s *Sender) SetLocation(location string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.location = location
+}
+
+// Send posts an event to the webhook URLs if it's selected. Failed deliveries are retried in the
+// background.
+func (s *Sender) Send(group string, event api.Event) {
+	s.lock.Lock()
+	urls := s.urls
+	secret := s.secret
+	selected := len(urls) > 0 && s.selects(event)
+	if event.Location == "" {
+		event.Location = s.location
+	}
+	s.lock.Unlock()
+
+	if !selected {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Warn("Failed to encode webhook event", log.Ctx{"err": err})
+		return
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-LXD-Event", event.Type)
+	header.Set("X-LXD-Delivery", uuid.NewRandom().String())
+
+	if group != "" {
+		header.Set("X-LXD-Project", group)
+	}
+
+	if secret != "" {
+		header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	for _, target := range urls {
+		go s.deliver(target, header, body)
+	}
+}
+
+// selects returns whether an event matches one of the selectors. It must be called with the lock
+// held.
+func (s *Sender) selects(event api.Event) bool {
+	switch event.Type {
+	case "lifecycle":
+		lifecycle := api.EventLifecycle{}
+		err := json.Unmarshal(event.Metadata, &lifecycle)
+		if err != nil {
+			return false
+		}
+
+		for _, selector := range s.selectors {
+			match, _ := path.Match(selector, lifecycle.Action)
+			if selector == "lifecycle" || match {
+				return true
+			}
+		}
+	case "operation":
+		// Operations are only posted once done, rather than on every progress update.
+		op := api.Operation{}
+		err := json.Unmarshal(event.Metadata, &op)
+		if err != nil || !op.StatusCode.IsFinal() {
+			return false
+		}
+
+		for _, selector := range s.selectors {
+			if selector == "operation" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// deliver posts an event to a webhook URL, retrying on connection failures and server errors.
+func (s *Sender) deliver(target string, header http.Header, body []byte) {
+	delay := RetryDelay
+
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(target, header, body)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt == maxAttempts {
+			logger.Warn("Failed to deliver webhook event", log.Ctx{"url": target, "attempts": attempt, "err": err})
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes a single delivery attempt, returning whether it's worth retrying on failure.
+func (s *Sender) post(target string, header http.Header, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header = header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("Webhook returned %s", resp.Status)
+	}
+
+	return false, nil
+}
+
+// Sign returns the signature of a body with the given secret, as set in the SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateURL checks that a webhook URL is an HTTP or HTTPS URL.
+func ValidateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Invalid webhook URL %q, must be an HTTP or HTTPS URL", value)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/webhook"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestSender_Send(t *testing.T) {
+	webhook.RetryDelay = time.Millisecond
+
+	deliveries := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	attempts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt, to check it's retried.
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		deliveries <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	sender := webhook.NewSender()
+	sender.SetLocation("node1")
+	require.NoError(t, sender.Configure([]string{server.URL}, []string{"instance-*"}, "s3cret"))
+
+	sender.Send("default", lifecycleEvent(t, "image-deleted"))
+	sender.Send("default", lifecycleEvent(t, "instance-started"))
+
+	select {
+	case r := <-deliveries:
+		body := <-bodies
+
+		assert.Equal(t, "lifecycle", r.Header.Get("X-LXD-Event"))
+		assert.Equal(t, "default", r.Header.Get("X-LXD-Project"))
+		assert.Equal(t, webhook.Sign("s3cret", body), r.Header.Get(webhook.SignatureHeader))
+
+		event := api.Event{}
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, "node1", event.Location)
+
+		lifecycle := api.EventLifecycle{}
+		require.NoError(t, json.Unmarshal(event.Metadata, &lifecycle))
+		assert.Equal(t, "instance-started", lifecycle.Action)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event wasn't delivered")
+	}
+
+	select {
+	case <-deliveries:
+		t.Fatal("Unselected event was delivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSender_ConfigureInvalid(t *testing.T) {
+	sender := webhook.NewSender()
+	assert.Error(t, sender.Configure([]string{"hooks.example.com"}, nil, ""))
+	assert.Error(t, sender.Configure([]string{"http://hooks.example.com"}, []string{"instance-["}, ""))
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", webhook.Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func lifecycleEvent(t *testing.T, action string) api.Event {
+	metadata, err := json.Marshal(api.EventLifecycle{Action: action, Source: "/1.0/instances/c1"})
+	require.NoError(t, err)
+
+	return api.Event{Type: "lifecycle", Timestamp: time.Now(), Metadata: metadata}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"time"
+)
+
+// AuditEntry represents a mutating API request recorded by the audit log
+//
+// API extension: audit_log
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+
+	// Cluster member which handled the request
+	Location string `json:"location" yaml:"location"`
+
+	// Authenticated identity (certificate fingerprint or external username) and how it was authenticated
+	Identity string `json:"identity" yaml:"identity"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	SourceAddress string `json:"source_address" yaml:"source_address"`
+	Method        string `json:"method" yaml:"method"`
+	Resource      string `json:"resource" yaml:"resource"`
+	Project       string `json:"project" yaml:"project"`
+
+	// HTTP status of the response and background operation started by the request, if any
+	StatusCode int    `json:"status_code" yaml:"status_code"`
+	Operation  string `json:"operation" yaml:"operation"`
+
+	// State of the resource before the request and request body, with secrets redacted
+	Before interface{} `json:"before" yaml:"before"`
+	After  interface{} `json:"after" yaml:"after"`
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"time"
+)
+
+// AuthPermission represents a role granted on the whole server, a project or an instance
+//
+// API extension: auth_builtin
+type AuthPermission struct {
+	// One of auditor, user, operator or admin
+	Role string `json:"role" yaml:"role"`
+
+	// Project the role is granted on, all projects if empty
+	Project string `json:"project" yaml:"project"`
+
+	// Instance of the project the role is granted on, all instances if empty
+	Instance string `json:"instance" yaml:"instance"`
+}
+
+// AuthGroupsPost represents the fields of a new LXD authorization group
+//
+// API extension: auth_builtin
+type AuthGroupsPost struct {
+	AuthGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// AuthGroupPost represents the fields required to rename a LXD authorization group
+//
+// API extension: auth_builtin
+type AuthGroupPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// AuthGroupPut represents the modifiable fields of a LXD authorization group
+//
+// API extension: auth_builtin
+type AuthGroupPut struct {
+	Description string `json:"description" yaml:"description"`
+
+	// Client certificate fingerprints or external usernames of the members of the group
+	Identities []string `json:"identities" yaml:"identities"`
+
+	Permissions []AuthPermission `json:"permissions" yaml:"permissions"`
+}
+
+// AuthGroup represents a LXD authorization group
+//
+// API extension: auth_builtin
+type AuthGroup struct {
+	AuthGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full AuthGroup struct into a AuthGroupPut struct (filters read-only fields)
+func (g *AuthGroup) Writable() AuthGroupPut {
+	return g.AuthGroupPut
+}
+
+// AuthTokensPost represents the fields of a new LXD API token
+//
+// API extension: auth_tokens
+type AuthTokensPost struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+
+	// Projects the token is restricted to, all projects if empty
+	Projects []string `json:"projects" yaml:"projects"`
+
+	// One of read-only, exec-only or full
+	Capability string `json:"capability" yaml:"capability"`
+
+	// When the token expires, never if unset
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// AuthToken represents a LXD API token
+//
+// API extension: auth_tokens
+type AuthToken struct {
+	AuthTokensPost `yaml:",inline"`
+
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// AuthTokenSecret represents the secret of a new LXD API token, which can't be retrieved later
+//
+// API extension: auth_tokens
+type AuthTokenSecret struct {
+	Secret string `json:"secret" yaml:"secret"`
+}
@@ -14,6 +14,9 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// API extension: certificate_roles
+	Projects []string `json:"projects" yaml:"projects"`
 }
 
 // Certificate represents a LXD certificate
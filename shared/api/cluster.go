@@ -1,5 +1,11 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
 // Cluster represents high-level information about a LXD cluster.
 //
 // API extension: clustering
@@ -41,6 +47,34 @@ type ClusterPut struct {
 	ClusterPassword string `json:"cluster_password" yaml:"cluster_password"`
 }
 
+// ClusterMembersPost represents the fields required to request a join token for a new member.
+//
+// API extension: clustering_join_token
+type ClusterMembersPost struct {
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// ClusterMemberJoinToken represents the fields contained within an encoded cluster member join token.
+//
+// API extension: clustering_join_token
+type ClusterMemberJoinToken struct {
+	ServerName  string    `json:"server_name" yaml:"server_name"`
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	Addresses   []string  `json:"addresses" yaml:"addresses"`
+	Secret      string    `json:"secret" yaml:"secret"`
+	ExpiresAt   time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// String encodes the cluster member join token as JSON and then base64.
+func (t *ClusterMemberJoinToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
+}
+
 // ClusterMemberPost represents the fields required to rename a LXD node.
 //
 // API extension: clustering
@@ -48,6 +82,42 @@ type ClusterMemberPost struct {
 	ServerName string `json:"server_name" yaml:"server_name"`
 }
 
+// ClusterMemberStatePost represents the fields required to evacuate or restore a cluster member.
+//
+// API extension: clustering_evacuation
+type ClusterMemberStatePost struct {
+	Action string `json:"action" yaml:"action"`
+}
+
+// ClusterMemberSysInfo represents the system load and memory of a cluster member, or their totals
+// across the cluster.
+//
+// API extension: clustering_member_state
+type ClusterMemberSysInfo struct {
+	LoadAverages []float64 `json:"load_averages" yaml:"load_averages"`
+	CPUs         uint64    `json:"cpus" yaml:"cpus"`
+	TotalRAM     uint64    `json:"total_ram" yaml:"total_ram"`
+	UsedRAM      uint64    `json:"used_ram" yaml:"used_ram"`
+}
+
+// ClusterMemberState represents the health and resource usage of a cluster member.
+//
+// API extension: clustering_member_state
+type ClusterMemberState struct {
+	SysInfo          ClusterMemberSysInfo            `json:"sysinfo" yaml:"sysinfo"`
+	StoragePools     map[string]ResourcesStoragePool `json:"storage_pools" yaml:"storage_pools"`
+	RunningInstances int                             `json:"running_instances" yaml:"running_instances"`
+}
+
+// ClusterState represents the state of the online cluster members along with their totals.
+//
+// API extension: clustering_member_state
+type ClusterState struct {
+	Members          map[string]ClusterMemberState `json:"members" yaml:"members"`
+	SysInfo          ClusterMemberSysInfo          `json:"sysinfo" yaml:"sysinfo"`
+	RunningInstances int                           `json:"running_instances" yaml:"running_instances"`
+}
+
 // ClusterMember represents the a LXD node in the cluster.
 //
 // API extension: clustering
@@ -63,4 +133,38 @@ type ClusterMember struct {
 
 	// API extension: clustering_architecture
 	Architecture string `json:"architecture" yaml:"architecture"`
+
+	// API extension: clustering_groups
+	Groups []string `json:"groups" yaml:"groups"`
+}
+
+// ClusterGroupsPost represents the fields of a new cluster group
+//
+// API extension: clustering_groups
+type ClusterGroupsPost struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// ClusterGroupPut represents the modifiable fields of a cluster group
+//
+// API extension: clustering_groups
+type ClusterGroupPut struct {
+	Description string   `json:"description" yaml:"description"`
+	Members     []string `json:"members" yaml:"members"`
+}
+
+// ClusterGroup represents a group of cluster members
+//
+// API extension: clustering_groups
+type ClusterGroup struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full ClusterGroup struct into a ClusterGroupPut struct (filters read-only fields)
+func (group *ClusterGroup) Writable() ClusterGroupPut {
+	return group.ClusterGroupPut
 }
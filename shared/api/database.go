@@ -0,0 +1,27 @@
+package api
+
+// DatabaseBackupsPost represents the fields of a new backup of the LXD databases
+//
+// API extension: database_backup
+type DatabaseBackupsPost struct {
+	// Where to write the backup to, either "file" or "s3"
+	Target string `json:"target" yaml:"target"`
+
+	// Absolute path of the file on the server, for the file target
+	Path string `json:"path" yaml:"path"`
+
+	// Object to upload the backup to, for the s3 target
+	S3 *DatabaseBackupS3 `json:"s3" yaml:"s3"`
+}
+
+// DatabaseBackupS3 represents an S3 object a backup of the LXD databases is uploaded to
+//
+// API extension: database_backup
+type DatabaseBackupS3 struct {
+	Endpoint  string `json:"endpoint" yaml:"endpoint"`
+	Bucket    string `json:"bucket" yaml:"bucket"`
+	Key       string `json:"key" yaml:"key"`
+	Region    string `json:"region" yaml:"region"`
+	AccessKey string `json:"access_key" yaml:"access_key"`
+	SecretKey string `json:"secret_key" yaml:"secret_key"`
+}
@@ -34,6 +34,42 @@ type ImagesPostSource struct {
 	// For type "image"
 	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
 	Secret      string `json:"secret" yaml:"secret"`
+
+	// For type "build"
+	// API extension: images_build
+	Build *ImageBuild `json:"build,omitempty" yaml:"build,omitempty"`
+}
+
+// ImageBuild represents the manifest of an image built by running actions in a throwaway instance
+//
+// API extension: images_build
+type ImageBuild struct {
+	// Image the build instance is created from
+	Source InstanceSource `json:"source" yaml:"source"`
+
+	// Type of the build instance (container or virtual-machine)
+	Type string `json:"type" yaml:"type"`
+
+	// Configuration and profiles of the build instance
+	Config   map[string]string `json:"config" yaml:"config"`
+	Profiles []string          `json:"profiles" yaml:"profiles"`
+
+	// Files written to the build instance before running the actions
+	Files []ImageBuildFile `json:"files" yaml:"files"`
+
+	// Shell commands run in order in the build instance
+	Actions []string `json:"actions" yaml:"actions"`
+}
+
+// ImageBuildFile represents a file written to the instance of an image build
+//
+// API extension: images_build
+type ImageBuildFile struct {
+	Path    string `json:"path" yaml:"path"`
+	Content string `json:"content" yaml:"content"`
+	Mode    string `json:"mode" yaml:"mode"`
+	UID     int64  `json:"uid" yaml:"uid"`
+	GID     int64  `json:"gid" yaml:"gid"`
 }
 
 // ImagePut represents the modifiable fields of a LXD image
@@ -47,6 +83,10 @@ type ImagePut struct {
 
 	// API extension: image_profiles
 	Profiles []string `json:"profiles" yaml:"profiles"`
+
+	// API extension: images_pinning
+	Pinned      bool  `json:"pinned" yaml:"pinned"`
+	CacheExpiry int64 `json:"cache_expiry" yaml:"cache_expiry"`
 }
 
 // Image represents a LXD image
@@ -74,6 +114,20 @@ func (img *Image) Writable() ImagePut {
 	return img.ImagePut
 }
 
+// ImageExportPost represents the target of a LXD image pushed to another server
+//
+// API extension: images_oci_artifacts
+type ImageExportPost struct {
+	// Protocol of the target (only "oci" is supported)
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Address of the target (e.g. https://registry.example.com)
+	Server string `json:"server" yaml:"server"`
+
+	// Name of the image on the target, with an optional tag
+	Alias string `json:"alias" yaml:"alias"`
+}
+
 // ImageAlias represents an alias from the alias list of a LXD image
 type ImageAlias struct {
 	Name        string `json:"name" yaml:"name"`
@@ -105,6 +159,9 @@ type ImageAliasesEntryPost struct {
 type ImageAliasesEntryPut struct {
 	Description string `json:"description" yaml:"description"`
 	Target      string `json:"target" yaml:"target"`
+
+	// API extension: image_aliases_auto_update
+	Config map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
 }
 
 // ImageAliasesEntry represents a LXD image alias
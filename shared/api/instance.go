@@ -38,6 +38,9 @@ type InstancePost struct {
 	InstanceOnly  bool                `json:"instance_only" yaml:"instance_only"`
 	ContainerOnly bool                `json:"container_only" yaml:"container_only"` // Deprecated, use InstanceOnly.
 	Target        *InstancePostTarget `json:"target" yaml:"target"`
+
+	// API extension: instance_project_move
+	Project string `json:"project" yaml:"project"`
 }
 
 // InstancePostTarget represents the migration target host and operation.
@@ -49,6 +52,13 @@ type InstancePostTarget struct {
 	Websockets  map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
 }
 
+// InstanceResetPost represents the fields required to reset a LXD instance's root filesystem.
+//
+// API extension: instance_reset
+type InstanceResetPost struct {
+	Snapshot string `json:"snapshot" yaml:"snapshot"`
+}
+
 // InstancePut represents the modifiable fields of a LXD instance.
 //
 // API extension: instances
@@ -134,4 +144,7 @@ type InstanceSource struct {
 	ContainerOnly bool              `json:"container_only,omitempty" yaml:"container_only,omitempty"` // Deprecated, use InstanceOnly.
 	Refresh       bool              `json:"refresh,omitempty" yaml:"refresh,omitempty"`
 	Project       string            `json:"project,omitempty" yaml:"project,omitempty"`
+
+	// API extension: instance_refresh_excludes
+	RefreshExcludes []string `json:"refresh_excludes,omitempty" yaml:"refresh_excludes,omitempty"`
 }
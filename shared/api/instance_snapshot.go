@@ -13,6 +13,28 @@ type InstanceSnapshotsPost struct {
 
 	// API extension: snapshot_expiry_creation
 	ExpiresAt *time.Time `json:"expires_at" yaml:"expires_at"`
+
+	// API extension: snapshot_groups
+	Volumes bool `json:"volumes" yaml:"volumes"`
+}
+
+// InstanceSnapshotGroup represents an instance snapshot taken together with snapshots of the
+// custom volumes attached to the instance.
+//
+// API extension: snapshot_groups
+type InstanceSnapshotGroup struct {
+	Name      string                        `json:"name" yaml:"name"`
+	CreatedAt time.Time                     `json:"created_at" yaml:"created_at"`
+	Volumes   []InstanceSnapshotGroupVolume `json:"volumes" yaml:"volumes"`
+}
+
+// InstanceSnapshotGroupVolume represents a custom volume snapshot of an instance snapshot group.
+//
+// API extension: snapshot_groups
+type InstanceSnapshotGroupVolume struct {
+	Pool     string `json:"pool" yaml:"pool"`
+	Volume   string `json:"volume" yaml:"volume"`
+	Snapshot string `json:"snapshot" yaml:"snapshot"`
 }
 
 // InstanceSnapshotPost represents the fields required to rename/move a LXD instance snapshot.
@@ -58,3 +80,18 @@ type InstanceSnapshot struct {
 func (c *InstanceSnapshot) Writable() InstanceSnapshotPut {
 	return c.InstanceSnapshotPut
 }
+
+// InstanceSnapshotDiff represents a file changed between an instance snapshot and a more recent
+// snapshot or the instance itself.
+//
+// API extension: instance_snapshot_diff
+type InstanceSnapshotDiff struct {
+	// Path of the file in the instance root filesystem
+	Path string `json:"path" yaml:"path"`
+
+	// Type of change (added, modified or deleted)
+	Type string `json:"type" yaml:"type"`
+
+	// Size of the file (as of the more recent side, or before deletion)
+	Size int64 `json:"size" yaml:"size"`
+}
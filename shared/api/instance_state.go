@@ -10,6 +10,30 @@ type InstanceStatePut struct {
 	Stateful bool   `json:"stateful" yaml:"stateful"`
 }
 
+// InstancesStatePut represents a state change of several LXD instances at once.
+//
+// API extension: instance_bulk_state_change
+type InstancesStatePut struct {
+	State InstanceStatePut `json:"state" yaml:"state"`
+
+	// Maximum number of instances changed at the same time (0 for the default of 10)
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+}
+
+// InstanceStateResult represents the outcome of a state change of several instances for one of
+// them.
+//
+// API extension: instance_bulk_state_change
+type InstanceStateResult struct {
+	Name     string `json:"name" yaml:"name"`
+	Project  string `json:"project" yaml:"project"`
+	Location string `json:"location" yaml:"location"`
+
+	// Success, Failure or Skipped (already in the requested state)
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
 // InstanceState represents a LXD instance's state.
 //
 // API extension: instances
@@ -22,6 +46,9 @@ type InstanceState struct {
 	Pid        int64                           `json:"pid" yaml:"pid"`
 	Processes  int64                           `json:"processes" yaml:"processes"`
 	CPU        InstanceStateCPU                `json:"cpu" yaml:"cpu"`
+
+	// API extension: network_usage
+	NetworkUsage map[string]InstanceStateNetworkUsage `json:"network_usage" yaml:"network_usage"`
 }
 
 // InstanceStateDisk represents the disk information section of a LXD instance's state.
@@ -82,3 +109,30 @@ type InstanceStateNetworkCounters struct {
 	PacketsReceived int64 `json:"packets_received" yaml:"packets_received"`
 	PacketsSent     int64 `json:"packets_sent" yaml:"packets_sent"`
 }
+
+// InstanceStateNetworkUsage represents the traffic accumulated by a NIC device of a LXD instance
+// across restarts and migrations.
+//
+// API extension: network_usage
+type InstanceStateNetworkUsage struct {
+	BytesReceived int64 `json:"bytes_received" yaml:"bytes_received"`
+	BytesSent     int64 `json:"bytes_sent" yaml:"bytes_sent"`
+}
+
+// InstanceNetworkDiagnostic represents the result of a check of the networking of a NIC device of
+// a LXD instance.
+//
+// API extension: instance_network_diagnostics
+type InstanceNetworkDiagnostic struct {
+	// Name of the NIC device
+	Device string `json:"device" yaml:"device"`
+
+	// Name of the check (bridge, dhcp, firewall, ovn or uplink)
+	Check string `json:"check" yaml:"check"`
+
+	// Result of the check (ok, failed or skipped)
+	Status string `json:"status" yaml:"status"`
+
+	// Details of the result
+	Message string `json:"message" yaml:"message"`
+}
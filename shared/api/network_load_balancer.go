@@ -0,0 +1,71 @@
+package api
+
+// NetworkLoadBalancerBackend represents a target backend of a network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerBackend struct {
+	Name          string `json:"name" yaml:"name"`
+	Description   string `json:"description" yaml:"description"`
+	TargetAddress string `json:"target_address" yaml:"target_address"`
+	TargetPort    string `json:"target_port" yaml:"target_port"`
+}
+
+// NetworkLoadBalancerPort represents a port specification in a network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPort struct {
+	Description   string   `json:"description" yaml:"description"`
+	Protocol      string   `json:"protocol" yaml:"protocol"`
+	ListenPort    string   `json:"listen_port" yaml:"listen_port"`
+	TargetBackend []string `json:"target_backend" yaml:"target_backend"`
+}
+
+// NetworkLoadBalancersPost represents the fields of a new LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancersPost struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// NetworkLoadBalancerPut represents the modifiable fields of a LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPut struct {
+	Description string                       `json:"description" yaml:"description"`
+	Config      map[string]string            `json:"config" yaml:"config"`
+	Backends    []NetworkLoadBalancerBackend `json:"backends" yaml:"backends"`
+	Ports       []NetworkLoadBalancerPort    `json:"ports" yaml:"ports"`
+}
+
+// NetworkLoadBalancer represents a LXD network load balancer
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancer struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// Writable converts a full NetworkLoadBalancer struct into a NetworkLoadBalancerPut struct (filters read-only fields)
+func (l *NetworkLoadBalancer) Writable() NetworkLoadBalancerPut {
+	return l.NetworkLoadBalancerPut
+}
+
+// NetworkLoadBalancerState represents the state of a network load balancer on a node
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerState struct {
+	BackendHealth map[string]NetworkLoadBalancerStateBackendHealth `json:"backend_health" yaml:"backend_health"`
+}
+
+// NetworkLoadBalancerStateBackendHealth represents the health of a network load balancer backend
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerStateBackendHealth struct {
+	Address string `json:"address" yaml:"address"`
+
+	// One of "online", "offline" or "unknown" when health checks are disabled
+	Status string `json:"status" yaml:"status"`
+}
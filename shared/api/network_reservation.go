@@ -0,0 +1,34 @@
+package api
+
+// NetworkReservationsPost represents the fields of a new LXD network DHCP reservation
+//
+// API extension: network_dhcp_reservations
+type NetworkReservationsPost struct {
+	NetworkReservationPut `yaml:",inline"`
+
+	Hwaddr string `json:"hwaddr" yaml:"hwaddr"`
+}
+
+// NetworkReservationPut represents the modifiable fields of a LXD network DHCP reservation
+//
+// API extension: network_dhcp_reservations
+type NetworkReservationPut struct {
+	Description string `json:"description" yaml:"description"`
+	IPv4Address string `json:"ipv4_address" yaml:"ipv4_address"`
+	IPv6Address string `json:"ipv6_address" yaml:"ipv6_address"`
+	Hostname    string `json:"hostname" yaml:"hostname"`
+}
+
+// NetworkReservation represents a LXD network DHCP reservation
+//
+// API extension: network_dhcp_reservations
+type NetworkReservation struct {
+	NetworkReservationPut `yaml:",inline"`
+
+	Hwaddr string `json:"hwaddr" yaml:"hwaddr"`
+}
+
+// Writable converts a full NetworkReservation struct into a NetworkReservationPut struct (filters read-only fields)
+func (r *NetworkReservation) Writable() NetworkReservationPut {
+	return r.NetworkReservationPut
+}
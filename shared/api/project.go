@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // ProjectsPost represents the fields of a new LXD project
 //
 // API extension: projects
@@ -7,6 +11,9 @@ type ProjectsPost struct {
 	ProjectPut `yaml:",inline"`
 
 	Name string `json:"name" yaml:"name"`
+
+	// API extension: projects_templates
+	Template string `json:"template" yaml:"template"`
 }
 
 // ProjectPost represents the fields required to rename a LXD project
@@ -40,3 +47,28 @@ type Project struct {
 func (project *Project) Writable() ProjectPut {
 	return project.ProjectPut
 }
+
+// ProjectUsage represents the resources consumed by a LXD project over a time window
+//
+// API extension: projects_usage
+type ProjectUsage struct {
+	// Start and end of the window, rounded to the hour
+	Start time.Time `json:"start" yaml:"start"`
+	End   time.Time `json:"end" yaml:"end"`
+
+	// Hours the instances of the project ran for
+	InstanceHours float64 `json:"instance_hours" yaml:"instance_hours"`
+
+	// CPUs allocated to the running instances, times hours
+	CPUHours float64 `json:"cpu_hours" yaml:"cpu_hours"`
+
+	// Bytes of memory allocated to the running instances, times hours
+	MemoryByteHours float64 `json:"memory_byte_hours" yaml:"memory_byte_hours"`
+
+	// Bytes used by the volumes of the project, times hours
+	StorageByteHours float64 `json:"storage_byte_hours" yaml:"storage_byte_hours"`
+
+	// Bytes received and sent by the instances
+	NetworkReceived int64 `json:"network_received" yaml:"network_received"`
+	NetworkSent     int64 `json:"network_sent" yaml:"network_sent"`
+}
@@ -12,6 +12,9 @@ type Resources struct {
 	// API extension: resources_v2
 	Network ResourcesNetwork `json:"network" yaml:"network"`
 	Storage ResourcesStorage `json:"storage" yaml:"storage"`
+
+	// API extension: resources_inventory
+	USB ResourcesUSB `json:"usb" yaml:"usb"`
 }
 
 // ResourcesCPU represents the cpu resources available on the system
@@ -90,6 +93,19 @@ type ResourcesGPUCard struct {
 	VendorID  string `json:"vendor_id,omitempty" yaml:"vendor_id,omitempty"`
 	Product   string `json:"product,omitempty" yaml:"product,omitempty"`
 	ProductID string `json:"product_id,omitempty" yaml:"product_id,omitempty"`
+
+	// API extension: resources_inventory
+	Mdev map[string]ResourcesGPUCardMdev `json:"mdev,omitempty" yaml:"mdev,omitempty"`
+}
+
+// ResourcesGPUCardMdev represents a mediated device type supported by the GPU
+// API extension: resources_inventory
+type ResourcesGPUCardMdev struct {
+	API         string   `json:"api" yaml:"api"`
+	Available   uint64   `json:"available" yaml:"available"`
+	Name        string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Devices     []string `json:"devices" yaml:"devices"`
 }
 
 // ResourcesGPUCardDRM represents the Linux DRM configuration of the GPU
@@ -202,6 +218,10 @@ type ResourcesNetworkCardSRIOV struct {
 	CurrentVFs uint64 `json:"current_vfs" yaml:"current_vfs"`
 	MaximumVFs uint64 `json:"maximum_vfs" yaml:"maximum_vfs"`
 
+	// Enabled VFs with a host interface, not passed to an instance yet
+	// API extension: resources_inventory
+	AvailableVFs uint64 `json:"available_vfs" yaml:"available_vfs"`
+
 	VFs []ResourcesNetworkCard `json:"vfs" yaml:"vfs"`
 }
 
@@ -237,6 +257,20 @@ type ResourcesStorageDisk struct {
 	DeviceID string `json:"device_id" yaml:"device_id"`
 
 	Partitions []ResourcesStorageDiskPartition `json:"partitions" yaml:"partitions"`
+
+	// API extension: resources_inventory
+	NVMe *ResourcesStorageDiskNVMe `json:"nvme,omitempty" yaml:"nvme,omitempty"`
+}
+
+// ResourcesStorageDiskNVMe represents the NVMe namespace backing a disk
+// API extension: resources_inventory
+type ResourcesStorageDiskNVMe struct {
+	Namespace    uint64 `json:"namespace" yaml:"namespace"`
+	Controller   string `json:"controller" yaml:"controller"`
+	ControllerID uint64 `json:"controller_id" yaml:"controller_id"`
+	Transport    string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	Address      string `json:"address,omitempty" yaml:"address,omitempty"`
+	SubsystemNQN string `json:"subsystem_nqn,omitempty" yaml:"subsystem_nqn,omitempty"`
 }
 
 // ResourcesStorageDiskPartition represents a partition on a disk
@@ -250,6 +284,45 @@ type ResourcesStorageDiskPartition struct {
 	Partition uint64 `json:"partition" yaml:"partition"`
 }
 
+// ResourcesUSB represents the USB devices available on the system
+// API extension: resources_inventory
+type ResourcesUSB struct {
+	Devices []ResourcesUSBDevice `json:"devices" yaml:"devices"`
+	Total   uint64               `json:"total" yaml:"total"`
+}
+
+// ResourcesUSBDevice represents a USB device, root hubs included
+// API extension: resources_inventory
+type ResourcesUSBDevice struct {
+	// Name of the device in sysfs (usb1 for a root hub, 1-2.3 for port 3 of the hub on port 2)
+	ID     string `json:"id" yaml:"id"`
+	Parent string `json:"parent,omitempty" yaml:"parent,omitempty"`
+
+	BusAddress    uint64  `json:"bus_address" yaml:"bus_address"`
+	DeviceAddress uint64  `json:"device_address" yaml:"device_address"`
+	Speed         float64 `json:"speed" yaml:"speed"`
+	Ports         uint64  `json:"ports,omitempty" yaml:"ports,omitempty"`
+
+	Vendor    string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	VendorID  string `json:"vendor_id" yaml:"vendor_id"`
+	Product   string `json:"product,omitempty" yaml:"product,omitempty"`
+	ProductID string `json:"product_id" yaml:"product_id"`
+	Serial    string `json:"serial,omitempty" yaml:"serial,omitempty"`
+
+	Interfaces []ResourcesUSBDeviceInterface `json:"interfaces" yaml:"interfaces"`
+}
+
+// ResourcesUSBDeviceInterface represents a USB device interface
+// API extension: resources_inventory
+type ResourcesUSBDeviceInterface struct {
+	Number     uint64 `json:"number" yaml:"number"`
+	ClassID    uint64 `json:"class_id" yaml:"class_id"`
+	SubClassID uint64 `json:"subclass_id" yaml:"subclass_id"`
+
+	Driver        string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	DriverVersion string `json:"driver_version,omitempty" yaml:"driver_version,omitempty"`
+}
+
 // ResourcesMemory represents the memory resources available on the system
 // API extension: resources
 type ResourcesMemory struct {
@@ -272,6 +345,10 @@ type ResourcesMemoryNode struct {
 
 	Used  uint64 `json:"used" yaml:"used"`
 	Total uint64 `json:"total" yaml:"total"`
+
+	// Relative distance to each NUMA node, indexed by node number
+	// API extension: resources_inventory
+	Distances []uint64 `json:"distances,omitempty" yaml:"distances,omitempty"`
 }
 
 // ResourcesStoragePool represents the resources available to a given storage pool
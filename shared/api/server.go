@@ -49,6 +49,11 @@ type ServerUntrusted struct {
 
 	// API extension: macaroon_authentication
 	AuthMethods []string `json:"auth_methods" yaml:"auth_methods"`
+
+	// API extension: auth_oidc
+	AuthOIDCIssuer   string `json:"auth_oidc_issuer,omitempty" yaml:"auth_oidc_issuer,omitempty"`
+	AuthOIDCClientID string `json:"auth_oidc_client_id,omitempty" yaml:"auth_oidc_client_id,omitempty"`
+	AuthOIDCAudience string `json:"auth_oidc_audience,omitempty" yaml:"auth_oidc_audience,omitempty"`
 }
 
 // Server represents a LXD server
@@ -0,0 +1,33 @@
+package api
+
+// SRIOVPoolsPost represents the fields of a new LXD SR-IOV pool
+//
+// API extension: sriov_pools
+type SRIOVPoolsPost struct {
+	SRIOVPoolPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// SRIOVPoolPut represents the modifiable fields of a LXD SR-IOV pool
+//
+// API extension: sriov_pools
+type SRIOVPoolPut struct {
+	Config      map[string]string `json:"config" yaml:"config"`
+	Description string            `json:"description" yaml:"description"`
+}
+
+// SRIOVPool represents a LXD SR-IOV pool
+//
+// API extension: sriov_pools
+type SRIOVPool struct {
+	SRIOVPoolPut `yaml:",inline"`
+
+	Name   string   `json:"name" yaml:"name"`
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// Writable converts a full SRIOVPool struct into a SRIOVPoolPut struct (filters read-only fields)
+func (pool *SRIOVPool) Writable() SRIOVPoolPut {
+	return pool.SRIOVPoolPut
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"time"
+)
+
+// StoragePoolTrashEntry represents an instance or custom volume in the trash of a LXD storage pool
+//
+// API extension: storage_trash
+type StoragePoolTrashEntry struct {
+	ID          int64             `json:"id" yaml:"id"`
+	Project     string            `json:"project" yaml:"project"`
+	Name        string            `json:"name" yaml:"name"`
+	Type        string            `json:"type" yaml:"type"`
+	Description string            `json:"description" yaml:"description"`
+	Config      map[string]string `json:"config" yaml:"config"`
+	Location    string            `json:"location" yaml:"location"`
+	DeletedAt   time.Time         `json:"deleted_at" yaml:"deleted_at"`
+	ExpiresAt   time.Time         `json:"expires_at" yaml:"expires_at"`
+}
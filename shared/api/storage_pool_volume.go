@@ -30,6 +30,9 @@ type StorageVolumePost struct {
 
 	// API extension: storage_api_remote_volume_snapshots
 	VolumeOnly bool `json:"volume_only" yaml:"volume_only"`
+
+	// API extension: clustering_volume_move
+	TargetMember string `json:"target_member,omitempty" yaml:"target_member,omitempty"`
 }
 
 // StorageVolumePostTarget represents the migration target host and operation
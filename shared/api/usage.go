@@ -0,0 +1,27 @@
+package api
+
+import (
+	"time"
+)
+
+// UsageHistory represents the resource usage of an instance or storage pool over a period.
+//
+// API extension: usage_history
+type UsageHistory struct {
+	// Period the usage covers (hour or day)
+	Period string `json:"period" yaml:"period"`
+
+	// Seconds each sample is the average of
+	Resolution int64 `json:"resolution" yaml:"resolution"`
+
+	// Samples, oldest first
+	Samples []UsageSample `json:"samples" yaml:"samples"`
+}
+
+// UsageSample represents the resource usage at a point in time.
+//
+// API extension: usage_history
+type UsageSample struct {
+	Timestamp time.Time          `json:"timestamp" yaml:"timestamp"`
+	Values    map[string]float64 `json:"values" yaml:"values"`
+}
@@ -147,6 +147,24 @@ func IsDeviceID(value string) error {
 	return nil
 }
 
+// IsTimeOffset validates a clock offset expressed as a signed integer followed by a unit (h, m, s, ms, us or ns).
+func IsTimeOffset(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	regexOffset, err := regexp.Compile("^-?[0-9]+(h|m|s|ms|us|ns)$")
+	if err != nil {
+		return err
+	}
+
+	if !regexOffset.MatchString(value) {
+		return fmt.Errorf("Invalid time offset %q, must be an integer followed by h, m, s, ms, us or ns", value)
+	}
+
+	return nil
+}
+
 // IsRootDiskDevice returns true if the given device representation is configured as root disk for
 // a container. It typically get passed a specific entry of api.Instance.Devices.
 func IsRootDiskDevice(device map[string]string) bool {
@@ -194,6 +212,11 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"boot.stop.priority":         IsInt64,
 	"boot.host_shutdown_timeout": IsInt64,
 
+	"cluster.evacuate": func(value string) error {
+		return IsOneOf(value, []string{"auto", "migrate", "stop"})
+	},
+	"cluster.rebalance": IsBool,
+
 	"limits.cpu": func(value string) error {
 		if value == "" {
 			return nil
@@ -288,6 +311,9 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 
 	"linux.kernel_modules": IsAny,
 
+	"linux.time.offset.boot":      IsTimeOffset,
+	"linux.time.offset.monotonic": IsTimeOffset,
+
 	"migration.incremental.memory":            IsBool,
 	"migration.incremental.memory.iterations": IsUint32,
 	"migration.incremental.memory.goal":       IsUint32,
@@ -297,6 +323,9 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"nvidia.require.cuda":        IsAny,
 	"nvidia.require.driver":      IsAny,
 
+	"security.apparmor.allow": IsAny,
+	"security.apparmor.deny":  IsAny,
+
 	"security.nesting":       IsBool,
 	"security.privileged":    IsBool,
 	"security.devlxd":        IsBool,
@@ -305,6 +334,8 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"security.protection.delete": IsBool,
 	"security.protection.shift":  IsBool,
 
+	"security.sched_core": IsBool,
+
 	"security.idmap.base":     IsUint32,
 	"security.idmap.isolated": IsBool,
 	"security.idmap.size":     IsUint32,
@@ -340,6 +371,13 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	},
 	"snapshots.schedule.stopped": IsBool,
 	"snapshots.pattern":          IsAny,
+	"snapshots.consistency": func(value string) error {
+		if value == "" {
+			return nil
+		}
+
+		return IsOneOf(value, []string{"none", "freeze"})
+	},
 	"snapshots.expiry": func(value string) error {
 		// Validate expression
 		_, err := GetSnapshotExpiry(time.Time{}, value)
@@ -361,6 +399,7 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 	"volatile.idmap.current":    IsAny,
 	"volatile.idmap.next":       IsAny,
 	"volatile.apply_quota":      IsAny,
+	"volatile.evacuate.origin":  IsAny,
 }
 
 // ConfigKeyChecker returns a function that will check whether or not
@@ -407,6 +446,18 @@ func ConfigKeyChecker(key string) (func(value string) error, error) {
 			return IsAny, nil
 		}
 
+		if strings.HasSuffix(key, ".parent") {
+			return IsAny, nil
+		}
+
+		if strings.HasSuffix(key, ".max_tx_rate") {
+			return IsAny, nil
+		}
+
+		if strings.HasSuffix(key, ".usage.bytes_received") || strings.HasSuffix(key, ".usage.bytes_sent") || strings.HasSuffix(key, ".usage.checkpoint") {
+			return IsAny, nil
+		}
+
 		if strings.HasSuffix(key, ".apply_quota") {
 			return IsAny, nil
 		}
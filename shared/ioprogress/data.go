@@ -13,4 +13,7 @@ type ProgressData struct {
 
 	// Total number of bytes (for files)
 	TotalBytes int64
+
+	// Transfer rate in bytes per second (for files)
+	Speed int64
 }
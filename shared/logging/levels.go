@@ -0,0 +1,187 @@
+package logging
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// Subsystems lists the subsystems which can log at their own level.
+var Subsystems = []string{"cluster", "instance", "network", "storage"}
+
+// subsystemKey is the context key records are tagged with the subsystem they come from.
+const subsystemKey = "subsystem"
+
+// subsystemPackages maps the packages of LXD to their subsystem.
+var subsystemPackages = map[string]string{
+	"lxd/cluster":  "cluster",
+	"lxd/db":       "cluster",
+	"lxd/device":   "instance",
+	"lxd/instance": "instance",
+	"lxd/seccomp":  "instance",
+	"lxd/bgp":      "network",
+	"lxd/dns":      "network",
+	"lxd/firewall": "network",
+	"lxd/network":  "network",
+	"lxd/storage":  "storage",
+}
+
+// subsystemFiles maps the prefixes of the file names of the main package to their subsystem, the
+// longest prefixes first.
+var subsystemFiles = []struct {
+	prefix    string
+	subsystem string
+}{
+	{"migrate_storage", "storage"},
+	{"daemon_storage", "storage"},
+	{"storage", "storage"},
+	{"project_networks", "network"},
+	{"network", "network"},
+	{"sriov", "network"},
+	{"api_cluster", "cluster"},
+	{"cluster", "cluster"},
+	{"container", "instance"},
+	{"instance", "instance"},
+	{"devices", "instance"},
+	{"devlxd", "instance"},
+	{"migrate", "instance"},
+}
+
+var subsystemLevels = map[string]log.Lvl{}
+var subsystemLevelsLock sync.RWMutex
+
+// SetSubsystemLevel sets the level a subsystem logs at, like "debug" or "warn", overriding the
+// level of the log handlers. An empty level makes the subsystem log at the level of the handlers
+// again.
+func SetSubsystemLevel(subsystem string, level string) error {
+	found := false
+	for _, name := range Subsystems {
+		if name == subsystem {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("Unknown log subsystem %q", subsystem)
+	}
+
+	subsystemLevelsLock.Lock()
+	defer subsystemLevelsLock.Unlock()
+
+	if level == "" {
+		delete(subsystemLevels, subsystem)
+		return nil
+	}
+
+	lvl, err := log.LvlFromString(level)
+	if err != nil {
+		return err
+	}
+
+	subsystemLevels[subsystem] = lvl
+
+	return nil
+}
+
+// subsystemHandler tags the records with the subsystem they come from, found from the package or
+// file of the function which logged them, unless they're already tagged.
+func subsystemHandler(h log.Handler) log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		if recordSubsystem(r) == "" {
+			subsystem := callerSubsystem()
+			if subsystem != "" {
+				r.Ctx = append(r.Ctx, subsystemKey, subsystem)
+			}
+		}
+
+		return h.Log(r)
+	})
+}
+
+// levelFilterHandler only passes the records at or above the level of their subsystem if it's set,
+// or above the given level otherwise.
+func levelFilterHandler(lvl log.Lvl, h log.Handler) log.Handler {
+	return log.FilterHandler(func(r *log.Record) bool {
+		subsystem := recordSubsystem(r)
+		if subsystem != "" {
+			subsystemLevelsLock.RLock()
+			subsystemLvl, ok := subsystemLevels[subsystem]
+			subsystemLevelsLock.RUnlock()
+
+			if ok {
+				return r.Lvl <= subsystemLvl
+			}
+		}
+
+		return r.Lvl <= lvl
+	}, h)
+}
+
+// recordSubsystem returns the subsystem a record is tagged with.
+func recordSubsystem(r *log.Record) string {
+	for i := 0; i+1 < len(r.Ctx); i += 2 {
+		if r.Ctx[i] == subsystemKey {
+			subsystem, _ := r.Ctx[i+1].(string)
+			return subsystem
+		}
+	}
+
+	return ""
+}
+
+// callerSubsystem returns the subsystem of the first function up the stack which isn't part of
+// the logging code.
+func callerSubsystem() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, "github.com/lxc/lxd/shared/log") {
+			return functionSubsystem(frame.Function, frame.File)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
+
+// functionSubsystem returns the subsystem of a function, given its full name and file.
+func functionSubsystem(function string, file string) string {
+	if strings.HasPrefix(function, "main.") {
+		name := filepath.Base(file)
+		for _, entry := range subsystemFiles {
+			if strings.HasPrefix(name, entry.prefix) {
+				return entry.subsystem
+			}
+		}
+
+		return ""
+	}
+
+	function = strings.TrimPrefix(function, "github.com/lxc/lxd/")
+
+	// Strip the function name from the package path.
+	pkg := function
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot >= 0 {
+		pkg = function[:slash+1+dot]
+	}
+
+	for prefix, subsystem := range subsystemPackages {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return subsystem
+		}
+	}
+
+	return ""
+}
@@ -13,14 +13,27 @@ import (
 )
 
 // GetLogger returns a logger suitable for using as logger.Log.
-func GetLogger(syslog string, logfile string, verbose bool, debug bool, customHandler log.Handler) (logger.Logger, error) {
+//
+// The format is either "text" or "json", the default being "text". Records are tagged with the
+// subsystem they come from, for subsystems to log at their own level.
+func GetLogger(syslog string, logfile string, format string, verbose bool, debug bool, customHandler log.Handler) (logger.Logger, error) {
 	Log := log.New()
 
 	var handlers []log.Handler
 	var syshandler log.Handler
 
+	var logFormat log.Format
+	switch format {
+	case "", "text":
+		logFormat = LogfmtFormat()
+	case "json":
+		logFormat = log.JsonFormat()
+	default:
+		return nil, fmt.Errorf("Invalid log format %q, must be text or json", format)
+	}
+
 	// System specific handler
-	syshandler = getSystemHandler(syslog, debug, LogfmtFormat())
+	syshandler = getSystemHandler(syslog, debug, logFormat)
 	if syshandler != nil {
 		handlers = append(handlers, syshandler)
 	}
@@ -34,40 +47,40 @@ func GetLogger(syslog string, logfile string, verbose bool, debug bool, customHa
 		if !debug {
 			handlers = append(
 				handlers,
-				log.LvlFilterHandler(
+				levelFilterHandler(
 					log.LvlInfo,
-					log.Must.FileHandler(logfile, LogfmtFormat()),
+					log.Must.FileHandler(logfile, logFormat),
 				),
 			)
 		} else {
-			handlers = append(handlers, log.Must.FileHandler(logfile, LogfmtFormat()))
+			handlers = append(handlers, levelFilterHandler(log.LvlDebug, log.Must.FileHandler(logfile, logFormat)))
 		}
 	}
 
 	// StderrHandler
-	format := LogfmtFormat()
-	if term.IsTty(os.Stderr.Fd()) {
-		format = TerminalFormat()
+	stderrFormat := logFormat
+	if format != "json" && term.IsTty(os.Stderr.Fd()) {
+		stderrFormat = TerminalFormat()
 	}
 
 	if verbose || debug {
 		if !debug {
 			handlers = append(
 				handlers,
-				log.LvlFilterHandler(
+				levelFilterHandler(
 					log.LvlInfo,
-					log.StreamHandler(os.Stderr, format),
+					log.StreamHandler(os.Stderr, stderrFormat),
 				),
 			)
 		} else {
-			handlers = append(handlers, log.StreamHandler(os.Stderr, format))
+			handlers = append(handlers, levelFilterHandler(log.LvlDebug, log.StreamHandler(os.Stderr, stderrFormat)))
 		}
 	} else {
 		handlers = append(
 			handlers,
-			log.LvlFilterHandler(
+			levelFilterHandler(
 				log.LvlWarn,
-				log.StreamHandler(os.Stderr, format),
+				log.StreamHandler(os.Stderr, stderrFormat),
 			),
 		)
 	}
@@ -76,7 +89,7 @@ func GetLogger(syslog string, logfile string, verbose bool, debug bool, customHa
 		handlers = append(handlers, customHandler)
 	}
 
-	Log.SetHandler(log.MultiHandler(handlers...))
+	Log.SetHandler(subsystemHandler(log.MultiHandler(handlers...)))
 
 	return Log, nil
 }
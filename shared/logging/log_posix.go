@@ -11,13 +11,13 @@ func getSystemHandler(syslog string, debug bool, format log.Format) log.Handler
 	// SyslogHandler
 	if syslog != "" {
 		if !debug {
-			return log.LvlFilterHandler(
+			return levelFilterHandler(
 				log.LvlInfo,
 				log.Must.SyslogHandler(syslog, format),
 			)
 		}
 
-		return log.Must.SyslogHandler(syslog, format)
+		return levelFilterHandler(log.LvlDebug, log.Must.SyslogHandler(syslog, format))
 	}
 
 	return nil
@@ -89,34 +89,20 @@ func (s *Products) ToLXD() ([]api.Image, map[string][][]string) {
 
 			// Image processing function
 			addImage := func(meta *ProductVersionItem, root *ProductVersionItem) error {
-				// Look for deltas (only on squashfs)
+				// Look for deltas of the root file
 				deltas := []ProductVersionItem{}
-				if root != nil && root.FileType == "squashfs" {
+				if root != nil {
 					for _, item := range version.Items {
-						if item.FileType == "squashfs.vcdiff" {
+						if item.FileType == fmt.Sprintf("%s.vcdiff", root.FileType) {
 							deltas = append(deltas, item)
 						}
 					}
 				}
 
 				// Figure out the fingerprint
-				fingerprint := ""
+				fingerprint := meta.HashSha256
 				if root != nil {
-					if root.FileType == "root.tar.xz" {
-						if meta.LXDHashSha256RootXz != "" {
-							fingerprint = meta.LXDHashSha256RootXz
-						} else {
-							fingerprint = meta.LXDHashSha256
-						}
-					} else if root.FileType == "squashfs" {
-						fingerprint = meta.LXDHashSha256SquashFs
-					} else if root.FileType == "disk-kvm.img" {
-						fingerprint = meta.LXDHashSha256DiskKvmImg
-					} else if root.FileType == "disk1.img" {
-						fingerprint = meta.LXDHashSha256DiskImg
-					}
-				} else {
-					fingerprint = meta.HashSha256
+					fingerprint = combinedFingerprint(meta, root.FileType)
 				}
 
 				if fingerprint == "" {
@@ -221,7 +207,7 @@ func (s *Products) ToLXD() ([]api.Image, map[string][][]string) {
 							continue
 						}
 
-						srcFingerprint = item.LXDHashSha256SquashFs
+						srcFingerprint = combinedFingerprint(&item, root.FileType)
 						break
 					}
 
@@ -271,3 +257,24 @@ func (s *Products) ToLXD() ([]api.Image, map[string][][]string) {
 
 	return images, downloads
 }
+
+// combinedFingerprint returns the fingerprint of the LXD image made of the given metadata and a
+// root file of the given type.
+func combinedFingerprint(meta *ProductVersionItem, rootType string) string {
+	switch rootType {
+	case "root.tar.xz":
+		if meta.LXDHashSha256RootXz != "" {
+			return meta.LXDHashSha256RootXz
+		}
+
+		return meta.LXDHashSha256
+	case "squashfs":
+		return meta.LXDHashSha256SquashFs
+	case "disk-kvm.img":
+		return meta.LXDHashSha256DiskKvmImg
+	case "disk1.img":
+		return meta.LXDHashSha256DiskImg
+	}
+
+	return ""
+}
@@ -183,6 +183,91 @@ var APIExtensions = []string{
 	"clustering_architecture",
 	"resources_disk_id",
 	"storage_lvm_stripes",
+	"instance_reset",
+	"oci_images",
+	"projects_idmap_isolated",
+	"container_time_namespace",
+	"container_live_update_limits",
+	"container_sched_core",
+	"container_apparmor_rules",
+	"network_forward",
+	"network_dns",
+	"network_dhcp_reservations",
+	"instance_nic_bridged_vlan",
+	"firewall_driver",
+	"network_wireguard",
+	"network_tunnel_geneve",
+	"sriov_pools",
+	"network_usage",
+	"network_dhcpv6_pd",
+	"network_dns_zones_auto",
+	"network_multicast",
+	"projects_networks",
+	"network_nat_exclude",
+	"vm_nic_hotplug",
+	"network_mirror",
+	"vm_boot_priority",
+	"network_bond",
+	"clustering_evacuation",
+	"clustering_groups",
+	"clustering_rebalance",
+	"clustering_config_per_member",
+	"clustering_join_token",
+	"clustering_heartbeat_config",
+	"clustering_rolling_upgrade",
+	"clustering_member_state",
+	"clustering_maintenance",
+	"clustering_volume_move",
+	"operations_filter",
+	"audit_log",
+	"auth_builtin",
+	"auth_oidc",
+	"auth_tokens",
+	"events_filter",
+	"collection_filter",
+	"trace_otlp",
+	"logging_subsystems",
+	"webhooks",
+	"log_forwarding",
+	"instance_bulk_state_change",
+	"usage_history",
+	"resources_inventory",
+	"rate_limiting",
+	"certificate_roles",
+	"database_metrics",
+	"database_backup",
+	"projects_limits_disk",
+	"projects_restricted_addresses",
+	"projects_templates",
+	"projects_usage",
+	"instance_project_move",
+	"images_signing",
+	"images_streams",
+	"images_build",
+	"image_aliases_auto_update",
+	"images_pinning",
+	"images_encryption",
+	"images_publish_vm",
+	"images_oci_artifacts",
+	"instance_file_sync",
+	"instance_metrics",
+	"instance_snapshot_diff",
+	"instance_network_diagnostics",
+	"migration_progress_bytes",
+	"startup_concurrency",
+	"snapshots_consistency",
+	"agent_quiesce",
+	"disk_priority_io_weight",
+	"storage_operations_concurrency",
+	"disaster_recovery",
+	"storage_volume_usage_alerts",
+	"instance_refresh_excludes",
+	"projects_volume_defaults",
+	"storage_trash",
+	"snapshot_groups",
+	"network_bgp",
+	"network_load_balancer",
+	"instance_nic_queue_tx_length",
 }
 
 // APIExtensionsCount returns the number of available API extensions.